@@ -0,0 +1,74 @@
+// Command gala runs the API server and the worker loop in one process,
+// sharing the Postgres pool, Redis client and storage provider. It is meant
+// for local development and small single-node deployments; production
+// setups that scale the API and workers independently use cmd/api and
+// cmd/worker.
+//
+// Configuration is the union of both: the API and worker environment
+// variables apply unchanged.
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+
+	"gala/internal/bootstrap"
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/metrics"
+)
+
+func main() {
+	mode := flag.String("mode", bootstrap.Env("GALA_MODE", "all"), "components to run: all, api or worker")
+	flag.Parse()
+
+	runAPI := *mode == "all" || *mode == "api"
+	runWorker := *mode == "all" || *mode == "worker"
+
+	// Initialize logger
+	log := bootstrap.NewLogger("gala")
+
+	if !runAPI && !runWorker {
+		log.Error("invalid mode", "mode", *mode)
+		os.Exit(2)
+	}
+
+	log.Info("starting GALA",
+		"version", "0.1.0",
+		"mode", *mode,
+	)
+
+	// Stack capture: all | errors (5xx only) | off
+	errors.SetStackMode(errors.ParseStackMode(bootstrap.Env("ERRORS_STACK_MODE", "all")))
+
+	ctx := context.Background()
+
+	// Initialize shutdown manager
+	shutdownMgr := bootstrap.NewShutdownManager(log)
+
+	// Config reload (SIGHUP, or POST /admin/config/reload with the API)
+	reloadMgr := bootstrap.NewReloadManager(log)
+
+	opt := bootstrap.ConnectOptions{}
+	if runAPI {
+		opt = bootstrap.ConnectOptions{Replicas: true, Metrics: metrics.NewRegistry()}
+	}
+	infra := bootstrap.Connect(ctx, log, shutdownMgr, opt)
+
+	accessCloser := io.Closer(io.NopCloser(nil))
+	if runAPI {
+		accessCloser = bootstrap.StartAPI(log, infra, reloadMgr, shutdownMgr)
+	}
+	if runWorker {
+		bootstrap.StartWorker(ctx, log, infra, reloadMgr, shutdownMgr)
+	}
+
+	go reloadMgr.Watch(shutdownMgr.Context())
+
+	// Wait for shutdown signal
+	shutdownMgr.Wait()
+
+	// Closed last: the HTTP server may log requests while draining.
+	_ = accessCloser.Close()
+}
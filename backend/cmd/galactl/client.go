@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+)
+
+// apiPrefix is the API version galactl speaks; request paths are given
+// without it.
+const apiPrefix = "/v1"
+
+// client calls the GALA API, over the network or (direct mode) through an
+// in-process router.
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// apiError is the API error envelope.
+type apiError struct {
+	Status  int            `json:"-"`
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+func (e *apiError) Error() string {
+	msg := fmt.Sprintf("%s (%d): %s", e.Code, e.Status, e.Message)
+	if len(e.Details) > 0 {
+		d, _ := json.Marshal(e.Details)
+		msg += " " + string(d)
+	}
+	return msg
+}
+
+// rawBody is a request body sent as is instead of encoded as JSON (e.g. a
+// multipart upload).
+type rawBody struct {
+	r           io.Reader
+	contentType string
+}
+
+// do sends a request with an optional JSON body and decodes a JSON
+// response into out (if not nil).
+func (c *client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s %s: decode response: %w", method, path, err)
+	}
+	return nil
+}
+
+// send returns the response of a successful request; the caller closes
+// its body.
+func (c *client) send(ctx context.Context, method, path string, query url.Values, body any) (*http.Response, error) {
+	u := strings.TrimRight(c.baseURL, "/") + apiPrefix + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var rd io.Reader
+	contentType := ""
+	switch b := body.(type) {
+	case nil:
+	case rawBody:
+		rd, contentType = b.r, b.contentType
+	default:
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd, contentType = bytes.NewReader(raw), "application/json"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 400 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var env struct {
+		Error apiError `json:"error"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(raw, &env); err != nil || env.Error.Code == "" {
+		return nil, fmt.Errorf("%s %s: HTTP %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	env.Error.Status = resp.StatusCode
+	if resp.StatusCode == http.StatusNotFound && env.Error.Code == "NOT_FOUND" && strings.HasPrefix(path, "/admin/") {
+		env.Error.Message += " (is ADMIN_TOKEN set on the API?)"
+	}
+	return nil, &env.Error
+}
+
+// handlerTransport serves requests with an in-process handler.
+type handlerTransport struct {
+	h http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.h.ServeHTTP(rec, req)
+	return rec.Result(), nil
+}
+
+func asAPIError(err error, target **apiError) bool {
+	return errors.As(err, target)
+}
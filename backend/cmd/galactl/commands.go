@@ -0,0 +1,635 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type cli struct {
+	c    *client
+	json bool
+	out  io.Writer
+}
+
+func (x *cli) run(ctx context.Context, group string, args []string) error {
+	if group == "seed" {
+		return x.seed(ctx, args)
+	}
+	if len(args) == 0 {
+		return errUsage
+	}
+	cmd, args := args[0], args[1:]
+	switch group + " " + cmd {
+	case "jobs inspect":
+		return x.jobsInspect(ctx, args)
+	case "jobs requeue":
+		return x.jobsAction(ctx, "requeue", args)
+	case "jobs cancel":
+		return x.jobsAction(ctx, "cancel", args)
+	case "queue stats":
+		return x.queueStats(ctx)
+	case "queue drain":
+		return x.queueDrain(ctx, args)
+	case "queue resume":
+		return x.queueResume(ctx)
+	case "assets gc":
+		return x.assetsGC(ctx, args)
+	case "storage audit":
+		return x.storageAudit(ctx, args)
+	case "templates export":
+		return x.templatesExport(ctx, args)
+	case "templates import":
+		return x.templatesImport(ctx, args)
+	case "workers list":
+		return x.workersList(ctx)
+	case "reports usage":
+		return x.reportsUsage(ctx, args)
+	}
+	return errUsage
+}
+
+func (x *cli) jobsInspect(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	var resp struct {
+		Job json.RawMessage `json:"job"`
+	}
+	if err := x.c.do(ctx, "GET", "/jobs/"+url.PathEscape(args[0]), nil, nil, &resp); err != nil {
+		return err
+	}
+	return x.printJSON(resp.Job)
+}
+
+// jobsAction requeues or cancels each job, reporting every failure and
+// carrying on with the rest.
+func (x *cli) jobsAction(ctx context.Context, action string, ids []string) error {
+	if len(ids) == 0 {
+		return errUsage
+	}
+	failed := 0
+	for _, id := range ids {
+		var resp struct {
+			Job struct {
+				ID     string `json:"id"`
+				Status string `json:"status"`
+			} `json:"job"`
+			// CancelRequested: a RUNNING job the worker will stop
+			CancelRequested bool `json:"cancel_requested"`
+		}
+		err := x.c.do(ctx, "POST", "/admin/jobs/"+url.PathEscape(id)+"/"+action, nil, nil, &resp)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "%s: %v\n", id, err)
+			continue
+		}
+		if resp.CancelRequested {
+			fmt.Fprintf(x.out, "%s\t%s (cancel requested)\n", resp.Job.ID, resp.Job.Status)
+			continue
+		}
+		fmt.Fprintf(x.out, "%s\t%s\n", resp.Job.ID, resp.Job.Status)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d jobs failed", failed, len(ids))
+	}
+	return nil
+}
+
+func (x *cli) queueStats(ctx context.Context) error {
+	var resp struct {
+		Queue struct {
+			Mode           string           `json:"mode"`
+			Pending        *int64           `json:"pending"`
+			Jobs           map[string]int64 `json:"jobs"`
+			OldestQueuedAt *time.Time       `json:"oldest_queued_at"`
+			PausedAt       *time.Time       `json:"paused_at"`
+		} `json:"queue"`
+	}
+	if x.json {
+		return x.printRaw(ctx, "GET", "/admin/queue/stats", nil)
+	}
+	if err := x.c.do(ctx, "GET", "/admin/queue/stats", nil, nil, &resp); err != nil {
+		return err
+	}
+	q := resp.Queue
+
+	tw := tabwriter.NewWriter(x.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "mode\t%s\n", q.Mode)
+	if q.Pending != nil {
+		fmt.Fprintf(tw, "pending in list\t%d\n", *q.Pending)
+	}
+	if q.PausedAt != nil {
+		fmt.Fprintf(tw, "paused since\t%s\n", q.PausedAt.Format(time.RFC3339))
+	}
+	if q.OldestQueuedAt != nil {
+		fmt.Fprintf(tw, "oldest queued\t%s (%s ago)\n", q.OldestQueuedAt.Format(time.RFC3339), time.Since(*q.OldestQueuedAt).Round(time.Second))
+	}
+	statuses := make([]string, 0, len(q.Jobs))
+	for s := range q.Jobs {
+		statuses = append(statuses, s)
+	}
+	sort.Strings(statuses)
+	for _, s := range statuses {
+		fmt.Fprintf(tw, "jobs %s\t%d\n", s, q.Jobs[s])
+	}
+	return tw.Flush()
+}
+
+func (x *cli) queueDrain(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("queue drain", flag.ContinueOnError)
+	cancel := fs.Bool("cancel", false, "cancel every QUEUED job instead (needs -yes)")
+	yes := fs.Bool("yes", false, "confirm -cancel")
+	fs.Bool("soft", true, "pause the queue and wait for the running jobs (the default)")
+	wait := fs.Duration("wait", 5*time.Minute, "how long to wait for the running jobs (cut to -timeout)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if !*cancel {
+		return x.queueSoftDrain(ctx, *wait)
+	}
+	if !*yes {
+		return fmt.Errorf("queue drain -cancel cancels every QUEUED job; pass -yes to confirm")
+	}
+	var resp struct {
+		Canceled int64 `json:"canceled"`
+	}
+	q := url.Values{"mode": {"cancel"}}
+	if err := x.c.do(ctx, "POST", "/admin/queue/drain", q, nil, &resp); err != nil {
+		return err
+	}
+	fmt.Fprintf(x.out, "canceled %d queued jobs\n", resp.Canceled)
+	return nil
+}
+
+// queueSoftDrain fails when jobs are still running at the end of wait, so
+// deploy scripts can stop there.
+func (x *cli) queueSoftDrain(ctx context.Context, wait time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		wait = max(min(wait, time.Until(deadline)-5*time.Second), 0)
+	}
+	q := url.Values{"mode": {"soft"}, "wait": {wait.String()}}
+	var resp struct {
+		Drain struct {
+			PausedAt time.Time `json:"paused_at"`
+			Drained  bool      `json:"drained"`
+			Running  []string  `json:"running"`
+			Queued   int64     `json:"queued"`
+		} `json:"drain"`
+	}
+	if err := x.c.do(ctx, "POST", "/admin/queue/drain", q, nil, &resp); err != nil {
+		return err
+	}
+	d := resp.Drain
+	if x.json {
+		return json.NewEncoder(x.out).Encode(d)
+	}
+	fmt.Fprintf(x.out, "queue paused since %s, %d queued jobs waiting\n", d.PausedAt.Format(time.RFC3339), d.Queued)
+	if !d.Drained {
+		return fmt.Errorf("%d jobs still running: %s", len(d.Running), strings.Join(d.Running, " "))
+	}
+	fmt.Fprintln(x.out, "no jobs running")
+	return nil
+}
+
+func (x *cli) queueResume(ctx context.Context) error {
+	if x.json {
+		return x.printRaw(ctx, "POST", "/admin/queue/resume", nil)
+	}
+	if err := x.c.do(ctx, "POST", "/admin/queue/resume", nil, nil, nil); err != nil {
+		return err
+	}
+	fmt.Fprintln(x.out, "queue resumed")
+	return nil
+}
+
+func (x *cli) assetsGC(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("assets gc", flag.ContinueOnError)
+	olderThan := fs.Duration("older-than", 30*24*time.Hour, "only assets created before this long ago")
+	limit := fs.Int("limit", 100, "maximum assets per run (up to 1000)")
+	apply := fs.Bool("apply", false, "delete the assets (default: only list them)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	q := url.Values{
+		"older_than": {olderThan.String()},
+		"limit":      {strconv.Itoa(*limit)},
+		"apply":      {strconv.FormatBool(*apply)},
+	}
+	if x.json {
+		return x.printRaw(ctx, "POST", "/admin/assets/gc", q)
+	}
+
+	var resp struct {
+		Assets []struct {
+			ID        string    `json:"id"`
+			Kind      string    `json:"kind"`
+			ObjectKey string    `json:"object_key"`
+			SizeBytes int64     `json:"size_bytes"`
+			CreatedAt time.Time `json:"created_at"`
+		} `json:"assets"`
+		Deleted int               `json:"deleted"`
+		Failed  map[string]string `json:"failed"`
+	}
+	if err := x.c.do(ctx, "POST", "/admin/assets/gc", q, nil, &resp); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(x.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tKIND\tSIZE\tCREATED\tOBJECT KEY")
+	var total int64
+	for _, a := range resp.Assets {
+		total += a.SizeBytes
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", a.ID, a.Kind, a.SizeBytes, a.CreatedAt.Format(time.RFC3339), a.ObjectKey)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if !*apply {
+		fmt.Fprintf(x.out, "%d unreferenced assets (%d bytes); run with -apply to delete them\n", len(resp.Assets), total)
+		return nil
+	}
+	fmt.Fprintf(x.out, "deleted %d of %d assets\n", resp.Deleted, len(resp.Assets))
+	for id, reason := range resp.Failed {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", id, reason)
+	}
+	if len(resp.Failed) > 0 {
+		return fmt.Errorf("%d assets could not be deleted", len(resp.Failed))
+	}
+	return nil
+}
+
+// listFlag is a flag that can be repeated.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// storageAudit cross-checks the assets table against the storage
+// provider's objects. -json prints the full report. It fails while any
+// issue is left unrepaired, so it can run from cron or CI.
+func (x *cli) storageAudit(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("storage audit", flag.ContinueOnError)
+	var prefixes listFlag
+	fs.Var(&prefixes, "prefix", "object name prefix that can hold orphans, repeatable (default assets/ and renders/)")
+	minAge := fs.Duration("min-age", time.Hour, "skip orphan objects modified more recently than this")
+	checksums := fs.Bool("checksums", false, "read objects the provider has no MD5 for to compare checksums")
+	repair := fs.String("repair", "", "comma-separated repairs to apply: delete-missing, delete-orphans, fix-sizes")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+	q := url.Values{
+		"prefix":    prefixes,
+		"min_age":   {minAge.String()},
+		"checksums": {strconv.FormatBool(*checksums)},
+	}
+	if *repair != "" {
+		q.Set("repair", *repair)
+	}
+
+	var resp struct {
+		Report json.RawMessage `json:"report"`
+	}
+	if err := x.c.do(ctx, "POST", "/admin/storage/audit", q, nil, &resp); err != nil {
+		return err
+	}
+	var report struct {
+		Provider string         `json:"provider"`
+		Assets   int            `json:"assets"`
+		Objects  int            `json:"objects"`
+		Counts   map[string]int `json:"counts"`
+		Issues   []struct {
+			Kind            string `json:"kind"`
+			AssetID         string `json:"asset_id"`
+			ObjectKey       string `json:"object_key"`
+			Name            string `json:"name"`
+			DBSize          int64  `json:"db_size"`
+			StorageSize     int64  `json:"storage_size"`
+			DBChecksum      string `json:"db_checksum"`
+			StorageChecksum string `json:"storage_checksum"`
+			Repair          string `json:"repair"`
+			Repaired        bool   `json:"repaired"`
+			Error           string `json:"error"`
+		} `json:"issues"`
+	}
+	if err := json.Unmarshal(resp.Report, &report); err != nil {
+		return err
+	}
+
+	unresolved := 0
+	for _, i := range report.Issues {
+		if !i.Repaired {
+			unresolved++
+		}
+	}
+	if x.json {
+		if err := x.printJSON(resp.Report); err != nil {
+			return err
+		}
+	} else {
+		tw := tabwriter.NewWriter(x.out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ISSUE	ASSET	OBJECT KEY	DETAIL	REPAIR")
+		for _, i := range report.Issues {
+			asset, detail := i.AssetID, ""
+			switch i.Kind {
+			case "missing_object":
+				detail = fmt.Sprintf("%d bytes in db", i.DBSize)
+			case "orphan_object":
+				asset, detail = "-", fmt.Sprintf("%s, %d bytes", i.Name, i.StorageSize)
+			case "size_mismatch":
+				detail = fmt.Sprintf("db %d, storage %d bytes", i.DBSize, i.StorageSize)
+			case "checksum_mismatch":
+				detail = fmt.Sprintf("db %s, storage %s", i.DBChecksum, i.StorageChecksum)
+			}
+			action := "-"
+			switch {
+			case i.Repaired:
+				action = i.Repair + " (done)"
+			case i.Error != "":
+				action = i.Repair + " failed: " + i.Error
+			case i.Repair != "":
+				action = i.Repair
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", i.Kind, asset, i.ObjectKey, detail, action)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(x.out, "%s: %d assets, %d objects, %d issues (%d unresolved)\n",
+			report.Provider, report.Assets, report.Objects, len(report.Issues), unresolved)
+	}
+	if unresolved > 0 {
+		return fmt.Errorf("%d storage issues unresolved", unresolved)
+	}
+	return nil
+}
+
+// exportedTemplate is one line of templates export/import.
+type exportedTemplate struct {
+	ID           string          `json:"id,omitempty"`
+	Type         string          `json:"type"`
+	Name         string          `json:"name"`
+	DurationMs   *int            `json:"duration_ms,omitempty"`
+	Format       json.RawMessage `json:"format,omitempty"`
+	ParamsSchema json.RawMessage `json:"params_schema,omitempty"`
+	Defaults     json.RawMessage `json:"defaults,omitempty"`
+	StrictParams bool            `json:"strict_params,omitempty"`
+}
+
+func (x *cli) templatesExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("templates export", flag.ContinueOnError)
+	outPath := fs.String("o", "-", "output file (- for stdout)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	out := x.out
+	if *outPath != "-" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+
+	n := 0
+	cursor := ""
+	for {
+		q := url.Values{"limit": {"200"}}
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+		var page struct {
+			Templates  []exportedTemplate `json:"items"`
+			NextCursor string             `json:"next_cursor"`
+		}
+		if err := x.c.do(ctx, "GET", "/templates", q, nil, &page); err != nil {
+			return err
+		}
+		for _, t := range page.Templates {
+			t.Format, t.ParamsSchema, t.Defaults = nonNull(t.Format), nonNull(t.ParamsSchema), nonNull(t.Defaults)
+			if err := enc.Encode(t); err != nil {
+				return err
+			}
+			n++
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	fmt.Fprintf(os.Stderr, "exported %d templates\n", n)
+	return nil
+}
+
+// nonNull drops JSON nulls so they are omitted on export.
+func nonNull(raw json.RawMessage) json.RawMessage {
+	if string(raw) == "null" {
+		return nil
+	}
+	return raw
+}
+
+// templatesImport creates each template of an export. Templates whose name
+// already exists are skipped, so an import can be re-run.
+func (x *cli) templatesImport(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return errUsage
+	}
+	var in io.Reader = os.Stdin
+	if len(args) == 1 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	created, skipped, failed, line := 0, 0, 0, 0
+	for sc.Scan() {
+		line++
+		raw := strings.TrimSpace(sc.Text())
+		if raw == "" {
+			continue
+		}
+		var t exportedTemplate
+		if err := json.Unmarshal([]byte(raw), &t); err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "line %d: %v\n", line, err)
+			continue
+		}
+		// The API assigns new ids
+		t.ID = ""
+
+		var resp struct {
+			Template struct {
+				ID string `json:"id"`
+			} `json:"template"`
+		}
+		err := x.c.do(ctx, "POST", "/templates", nil, t, &resp)
+		var apiErr *apiError
+		switch {
+		case err == nil:
+			created++
+			fmt.Fprintf(x.out, "%s\tcreated\t%s\n", t.Name, resp.Template.ID)
+		case asAPIError(err, &apiErr) && apiErr.Code == "TEMPLATE_NAME_EXISTS":
+			skipped++
+			fmt.Fprintf(x.out, "%s\tskipped (name exists)\n", t.Name)
+		default:
+			failed++
+			fmt.Fprintf(os.Stderr, "line %d (%s): %v\n", line, t.Name, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "created %d, skipped %d, failed %d\n", created, skipped, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d templates failed to import", failed)
+	}
+	return nil
+}
+
+func (x *cli) workersList(ctx context.Context) error {
+	if x.json {
+		return x.printRaw(ctx, "GET", "/admin/workers", nil)
+	}
+	var resp struct {
+		Workers []struct {
+			ID         string    `json:"id"`
+			Hostname   string    `json:"hostname"`
+			PID        int       `json:"pid"`
+			QueueMode  string    `json:"queue_mode"`
+			StartedAt  time.Time `json:"started_at"`
+			LastSeen   time.Time `json:"last_seen"`
+			CurrentJob string    `json:"current_job"`
+		} `json:"workers"`
+	}
+	if err := x.c.do(ctx, "GET", "/admin/workers", nil, nil, &resp); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(x.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tHOST\tPID\tQUEUE\tUP\tLAST SEEN\tJOB")
+	for _, w := range resp.Workers {
+		job := w.CurrentJob
+		if job == "" {
+			job = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s ago\t%s\n",
+			w.ID, w.Hostname, w.PID, w.QueueMode,
+			time.Since(w.StartedAt).Round(time.Second),
+			time.Since(w.LastSeen).Round(time.Second),
+			job,
+		)
+	}
+	return tw.Flush()
+}
+
+func (x *cli) reportsUsage(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("reports usage", flag.ContinueOnError)
+	month := fs.String("month", "", "month as YYYY-MM (default: the current one)")
+	asCSV := fs.Bool("csv", false, "write the API's CSV (one row per day plus a total row)")
+	outPath := fs.String("o", "-", "output file (- for stdout)")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	q := url.Values{}
+	if *month != "" {
+		q.Set("month", *month)
+	}
+
+	out := x.out
+	if *outPath != "-" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if *asCSV {
+		q.Set("format", "csv")
+		resp, err := x.c.send(ctx, "GET", "/admin/reports/usage", q, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, err = io.Copy(out, resp.Body)
+		return err
+	}
+	if x.json {
+		return x.printRaw(ctx, "GET", "/admin/reports/usage", q)
+	}
+
+	type totals struct {
+		Date              string  `json:"date"`
+		Renders           int64   `json:"renders"`
+		FailedRenders     int64   `json:"failed_renders"`
+		RenderMinutes     float64 `json:"render_minutes"`
+		StorageBytesAdded int64   `json:"storage_bytes_added"`
+		Publications      int64   `json:"publications"`
+	}
+	var resp struct {
+		Report struct {
+			Month        string   `json:"month"`
+			StorageBytes int64    `json:"storage_bytes"`
+			Totals       totals   `json:"totals"`
+			Days         []totals `json:"days"`
+		} `json:"report"`
+	}
+	if err := x.c.do(ctx, "GET", "/admin/reports/usage", q, nil, &resp); err != nil {
+		return err
+	}
+	rep := resp.Report
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE	RENDERS	FAILED	MINUTES	BYTES ADDED	PUBLICATIONS")
+	rep.Totals.Date = "total"
+	for _, d := range append(rep.Days, rep.Totals) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%d\t%d\n",
+			d.Date, d.Renders, d.FailedRenders, d.RenderMinutes, d.StorageBytesAdded, d.Publications)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s: %d bytes stored\n", rep.Month, rep.StorageBytes)
+	return nil
+}
+
+func (x *cli) printRaw(ctx context.Context, method, path string, q url.Values) error {
+	var raw json.RawMessage
+	if err := x.c.do(ctx, method, path, q, nil, &raw); err != nil {
+		return err
+	}
+	return x.printJSON(raw)
+}
+
+func (x *cli) printJSON(raw json.RawMessage) error {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(x.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"gala/internal/bootstrap"
+	contract "gala/internal/contracts/renderer"
+	"gala/internal/store"
+)
+
+// contractResult is the check of one spec or response.
+type contractResult struct {
+	Source string `json:"source"`
+	Kind   string `json:"kind"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+// contractsVerify checks renderer specs and responses against the
+// renderer contract built into galactl:
+//
+//   - files named <job_id>.<version>.json are specs as the renderer saves
+//     them under SPECS_DIR;
+//   - files named <version>.<status>[.<label>].json are recorded renderer
+//     responses;
+//   - any other file is a spec as stored with the job, {"version", "spec"}.
+//
+// -stored N also checks the specs of the last N jobs in DATABASE_URL, so a
+// contract change can be checked against what the worker really sends
+// before it is deployed. It fails when anything does not match.
+func contractsVerify(ctx context.Context, args []string, jsonOut bool, out io.Writer) error {
+	fs := flag.NewFlagSet("contracts verify", flag.ContinueOnError)
+	stored := fs.Int("stored", 0, "also check the render specs of the last N jobs in the database")
+	if err := fs.Parse(args); err != nil || *stored < 0 || (fs.NArg() == 0 && *stored == 0) {
+		return errUsage
+	}
+
+	var results []contractResult
+	add := func(source, kind string, err error) {
+		r := contractResult{Source: source, Kind: kind, OK: err == nil}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		results = append(results, r)
+	}
+
+	for _, name := range fs.Args() {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if v, ok := contract.SpecFileVersion(name); ok {
+			add(name, v+" spec", contract.ValidateSpecJSON(v, data))
+		} else if v, status, ok := contract.ResponseFileName(name); ok {
+			add(name, fmt.Sprintf("%s response %d", v, status), contract.ValidateResponse(v, status, data))
+		} else {
+			add(name, "stored spec", contract.ValidateStoredSpec(data))
+		}
+	}
+
+	if *stored > 0 {
+		log := stderrLogger("warn")
+		shutdownMgr := bootstrap.NewShutdownManager(log)
+		infra := bootstrap.Connect(ctx, log, shutdownMgr, bootstrap.ConnectOptions{})
+		defer shutdownMgr.Shutdown()
+
+		specs, err := store.RecentRenderSpecs(ctx, infra.Pool, *stored)
+		if err != nil {
+			return err
+		}
+		for _, s := range specs {
+			add("job "+s.JobID, "stored spec", contract.ValidateStoredSpec(s.Spec))
+		}
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.OK {
+			failed++
+		}
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(map[string]any{"results": results, "ok": failed == 0}); err != nil {
+			return err
+		}
+	} else {
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "SOURCE\tKIND\tRESULT\tDETAIL")
+		for _, r := range results {
+			result := "ok"
+			if !r.OK {
+				result = "FAIL"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Source, r.Kind, result, r.Error)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d do not match the renderer contract", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"gala/internal/bootstrap"
+)
+
+// doctor checks the environment of this process as the API and worker
+// would read it and prints a PASS/WARN/FAIL/SKIP report. It fails when
+// any check fails.
+func doctor(ctx context.Context, args []string, jsonOut bool, out io.Writer) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	component := fs.String("component", "all", "configuration to check: api, worker or all")
+	checkTimeout := fs.Duration("check-timeout", 10*time.Second, "timeout of each connectivity check")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	opt := bootstrap.DiagnoseOptions{Timeout: *checkTimeout}
+	switch *component {
+	case "api":
+		opt.API = true
+	case "worker":
+		opt.Worker = true
+	case "all":
+		opt.API, opt.Worker = true, true
+	default:
+		return errUsage
+	}
+
+	checks := bootstrap.Diagnose(ctx, stderrLogger("error"), opt)
+
+	failed := 0
+	for _, c := range checks {
+		if c.Status == bootstrap.CheckFail {
+			failed++
+		}
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(map[string]any{"checks": checks, "ok": failed == 0}); err != nil {
+			return err
+		}
+	} else {
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "CHECK\tRESULT\tTIME\tDETAIL")
+		for _, c := range checks {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Name, c.Status, c.Duration.Round(time.Millisecond), c.Detail)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
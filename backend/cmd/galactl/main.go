@@ -0,0 +1,160 @@
+// Command galactl is the operator CLI for GALA. It talks to the API
+// (GALA_API_URL, with GALA_ADMIN_TOKEN or ADMIN_TOKEN for /admin routes)
+// or, with -direct, runs the API handlers in-process against DATABASE_URL,
+// REDIS_ADDR and the storage configuration, so it works without a running
+// API and behaves exactly like it.
+//
+//	galactl jobs inspect <id>
+//	galactl jobs requeue <id>...
+//	galactl jobs cancel <id>...
+//	galactl jobs replay [-renderer url] [-keep] <id>
+//	galactl queue stats
+//	galactl queue drain [-wait 5m]
+//	galactl queue drain -cancel -yes
+//	galactl queue resume
+//	galactl assets gc [-older-than 720h] [-limit 100] [-apply]
+//	galactl storage audit [-prefix p]... [-min-age 1h] [-checksums] [-repair actions]
+//	galactl templates export [-o file]
+//	galactl templates import [file|-]
+//	galactl workers list
+//	galactl seed [-template name] [-avatar file] [-no-job] [-wait]
+//	galactl doctor [-component api|worker|all]
+//	galactl contracts verify [-stored n] [file...]
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"gala/internal/bootstrap"
+	"gala/internal/pkg/logger"
+)
+
+const usage = `usage: galactl [flags] <command> <subcommand> [args]
+
+commands:
+  jobs inspect <id>          show a job with its outputs
+  jobs requeue <id>...       requeue FAILED or CANCELED jobs
+  jobs cancel <id>...        cancel QUEUED jobs, stop RUNNING ones
+  jobs replay <id>           re-render a job's stored spec and diff the outputs (local)
+  queue stats                job counts by status and pending queue length
+  queue drain                stop taking new jobs and wait for the running ones (-wait 5m)
+  queue drain -cancel -yes   cancel every QUEUED job
+  queue resume               take new jobs again after a soft drain
+  assets gc                  list unreferenced assets (-apply deletes them)
+  storage audit              cross-check assets and storage objects (-repair fixes them)
+  templates export           write every template as NDJSON
+  templates import [file]    create templates from NDJSON (stdin by default)
+  workers list               live workers and their current job
+  reports usage              monthly usage by day (-month YYYY-MM, -csv)
+  seed                       create the demo templates and avatar and submit a demo job
+  doctor                     check this environment's configuration and dependencies
+  contracts verify [file...] check renderer specs and responses against the renderer contract (local)
+
+flags:
+`
+
+// errUsage makes main print the usage and exit 2.
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	fs := flag.NewFlagSet("galactl", flag.ExitOnError)
+	apiURL := fs.String("api", envOr("GALA_API_URL", "http://localhost:8080"), "API base URL")
+	token := fs.String("token", envOr("GALA_ADMIN_TOKEN", os.Getenv("ADMIN_TOKEN")), "admin bearer token")
+	direct := fs.Bool("direct", false, "run against the database, Redis and storage directly instead of the API")
+	timeout := fs.Duration("timeout", 2*time.Minute, "overall timeout")
+	jsonOut := fs.Bool("json", false, "print raw JSON responses")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(os.Args[1:])
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	// doctor, jobs replay and contracts verify run against the local
+	// environment, never through the API
+	switch {
+	case fs.Arg(0) == "doctor":
+		return exitCode(fs, doctor(ctx, fs.Args()[1:], *jsonOut, os.Stdout))
+	case fs.Arg(0) == "contracts" && fs.Arg(1) == "verify":
+		return exitCode(fs, contractsVerify(ctx, fs.Args()[2:], *jsonOut, os.Stdout))
+	case fs.Arg(0) == "jobs" && fs.Arg(1) == "replay":
+		return exitCode(fs, jobsReplay(ctx, fs.Args()[2:], *jsonOut, os.Stdout))
+	}
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return 2
+	}
+
+	c := &client{baseURL: *apiURL, token: *token, http: &http.Client{}}
+	if *direct {
+		closeDirect := connectDirect(ctx, c)
+		defer closeDirect()
+	}
+
+	cli := &cli{c: c, json: *jsonOut, out: os.Stdout}
+	return exitCode(fs, cli.run(ctx, fs.Arg(0), fs.Args()[1:]))
+}
+
+// exitCode reports err and returns the exit status for it: 2 (with the
+// usage) for errUsage, 1 for other errors.
+func exitCode(fs *flag.FlagSet, err error) int {
+	if errors.Is(err, errUsage) {
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "galactl:", err)
+		return 1
+	}
+	return 0
+}
+
+// connectDirect points c at an in-process API router and returns the
+// function that closes its connections.
+func connectDirect(ctx context.Context, c *client) func() {
+	log := stderrLogger("warn")
+	shutdownMgr := bootstrap.NewShutdownManager(log)
+	infra := bootstrap.Connect(ctx, log, shutdownMgr, bootstrap.ConnectOptions{})
+
+	// The in-process router gets its own throwaway admin token
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	c.token = hex.EncodeToString(buf)
+	c.baseURL = "http://galactl.local"
+	c.http = &http.Client{Transport: handlerTransport{h: bootstrap.NewAPIHandler(log, infra, c.token)}}
+
+	return shutdownMgr.Shutdown
+}
+
+// stderrLogger logs at LOG_LEVEL (default level) to stderr, so logs never
+// mix with command output.
+func stderrLogger(level string) *logger.Logger {
+	return logger.New(logger.Config{
+		Level:       envOr("LOG_LEVEL", level),
+		Format:      envOr("LOG_FORMAT", "text"),
+		Output:      os.Stderr,
+		ServiceName: "galactl",
+	})
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"gala/internal/bootstrap"
+	"gala/internal/worker/renderer"
+	"gala/internal/worker/replay"
+)
+
+// jobsReplay sends a job's stored renderer spec to the renderer again and
+// prints how the new outputs differ from the job's. Like the worker, it
+// needs the database, the storage provider and the storage root shared
+// with the renderer, so it runs where a worker would. It fails when any
+// output differs.
+func jobsReplay(ctx context.Context, args []string, jsonOut bool, out io.Writer) error {
+	fs := flag.NewFlagSet("jobs replay", flag.ContinueOnError)
+	rendererURL := fs.String("renderer", bootstrap.Env("RENDERER_HTTP_BASEURL", ""), "renderer base URL (e.g. a new renderer build)")
+	keep := fs.Bool("keep", false, "keep the replay inputs and outputs under the storage root")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+	if *rendererURL == "" {
+		return errors.New("no renderer: set RENDERER_HTTP_BASEURL or pass -renderer")
+	}
+
+	auth, err := renderer.NewAuthenticator(bootstrap.RendererAuthConfig())
+	if err != nil {
+		return fmt.Errorf("renderer auth: %w", err)
+	}
+
+	log := stderrLogger("warn")
+	shutdownMgr := bootstrap.NewShutdownManager(log)
+	infra := bootstrap.Connect(ctx, log, shutdownMgr, bootstrap.ConnectOptions{})
+	defer shutdownMgr.Shutdown()
+
+	res, err := replay.Run(ctx, replay.Deps{
+		Pool:        infra.Pool,
+		SP:          infra.SP,
+		Renderer:    renderer.NewHTTPClient(*rendererURL).WithAuth(auth),
+		StorageRoot: bootstrap.Env("STORAGE_LOCAL_ROOT", "/data"),
+		Keep:        *keep,
+	}, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(res); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(out, "replayed %s as %s (%s spec) on %s in %s\n",
+			res.JobID, res.ReplayID, res.Version, *rendererURL, res.Duration.Round(time.Millisecond))
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "OUTPUT\tRESULT\tSIZE\tSHA256\tDETAIL")
+		for _, o := range res.Outputs {
+			fmt.Fprintf(tw, "%s\t%s\t%d -> %d\t%s -> %s\t%s\n",
+				o.Name, o.Status, o.OriginalSize, o.ReplaySize, short(o.OriginalSHA256), short(o.ReplaySHA256), o.Detail)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if res.Dir != "" {
+			fmt.Fprintf(out, "replay outputs kept in %s\n", res.Dir)
+		}
+	}
+
+	if !res.Identical() {
+		return errors.New("replay outputs differ from the job's")
+	}
+	return nil
+}
+
+func short(sum string) string {
+	if sum == "" {
+		return "-"
+	}
+	return sum[:12]
+}
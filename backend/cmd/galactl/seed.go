@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// seedTemplates are the demo templates created by seed, in the
+// templates export format.
+//
+//go:embed seed/templates.ndjson
+var seedTemplates []byte
+
+const (
+	seedAvatarKind  = "avatar_input"
+	seedAvatarLabel = "gala-demo-avatar"
+	seedJobName     = "gala-demo"
+)
+
+// seed loads the demo templates and avatar and submits a demo job, so a
+// fresh environment shows the whole pipeline in one step. Templates and
+// the avatar are reused when they already exist; every run submits a new
+// job.
+func (x *cli) seed(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	tplName := fs.String("template", "demo-presenter", "seed template used for the demo job")
+	avatarPath := fs.String("avatar", "", "image uploaded as the demo avatar (default: a generated placeholder)")
+	text := fs.String("text", "", "params.text of the demo job (default: the template's)")
+	noJob := fs.Bool("no-job", false, "only create the templates and the avatar")
+	wait := fs.Bool("wait", false, "wait for the demo job to finish and print its outputs")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+
+	templates, err := x.seedTemplates(ctx)
+	if err != nil {
+		return err
+	}
+	avatarID, err := x.seedAvatar(ctx, *avatarPath)
+	if err != nil {
+		return err
+	}
+	if *noJob {
+		return nil
+	}
+
+	templateID, ok := templates[*tplName]
+	if !ok {
+		return fmt.Errorf("template %q is not one of the seed templates", *tplName)
+	}
+	body := map[string]any{
+		"name":        seedJobName,
+		"template_id": templateID,
+		"inputs":      map[string]string{"avatar_image_asset_id": avatarID},
+		"params":      map[string]any{},
+	}
+	if *text != "" {
+		body["params"] = map[string]any{"text": *text}
+	}
+	var resp struct {
+		Job struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"job"`
+	}
+	if err := x.c.do(ctx, "POST", "/jobs", nil, body, &resp); err != nil {
+		return fmt.Errorf("create demo job: %w", err)
+	}
+	fmt.Fprintf(x.out, "job\t%s\t%s\t%s\n", seedJobName, resp.Job.Status, resp.Job.ID)
+	if !*wait {
+		return nil
+	}
+	return x.waitJob(ctx, resp.Job.ID)
+}
+
+// seedTemplates creates the seed templates that do not exist yet and
+// returns the id of every seed template by name.
+func (x *cli) seedTemplates(ctx context.Context) (map[string]string, error) {
+	existing := map[string]string{}
+	cursor := ""
+	for {
+		q := url.Values{"limit": {"200"}}
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+		var page struct {
+			Templates  []exportedTemplate `json:"items"`
+			NextCursor string             `json:"next_cursor"`
+		}
+		if err := x.c.do(ctx, "GET", "/templates", q, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, t := range page.Templates {
+			existing[t.Name] = t.ID
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	ids := map[string]string{}
+	sc := bufio.NewScanner(bytes.NewReader(seedTemplates))
+	for sc.Scan() {
+		raw := strings.TrimSpace(sc.Text())
+		if raw == "" {
+			continue
+		}
+		var t exportedTemplate
+		if err := json.Unmarshal([]byte(raw), &t); err != nil {
+			return nil, fmt.Errorf("seed templates: %w", err)
+		}
+		if id, ok := existing[t.Name]; ok {
+			ids[t.Name] = id
+			fmt.Fprintf(x.out, "template\t%s\texists\t%s\n", t.Name, id)
+			continue
+		}
+
+		var resp struct {
+			Template struct {
+				ID string `json:"id"`
+			} `json:"template"`
+		}
+		if err := x.c.do(ctx, "POST", "/templates", nil, t, &resp); err != nil {
+			return nil, fmt.Errorf("create template %s: %w", t.Name, err)
+		}
+		ids[t.Name] = resp.Template.ID
+		fmt.Fprintf(x.out, "template\t%s\tcreated\t%s\n", t.Name, resp.Template.ID)
+	}
+	return ids, sc.Err()
+}
+
+// seedAvatar returns the demo avatar asset, uploading it if no asset with
+// its label exists. path replaces the generated placeholder image.
+func (x *cli) seedAvatar(ctx context.Context, path string) (string, error) {
+	q := url.Values{"kind": {seedAvatarKind}, "q": {seedAvatarLabel}, "limit": {"200"}}
+	var page struct {
+		Assets []struct {
+			ID    string `json:"id"`
+			Label string `json:"label"`
+		} `json:"items"`
+	}
+	if err := x.c.do(ctx, "GET", "/assets", q, nil, &page); err != nil {
+		return "", err
+	}
+	for _, a := range page.Assets {
+		if a.Label == seedAvatarLabel {
+			fmt.Fprintf(x.out, "asset\t%s\texists\t%s\n", seedAvatarLabel, a.ID)
+			return a.ID, nil
+		}
+	}
+
+	filename, contentType := "avatar.png", "image/png"
+	var img []byte
+	if path != "" {
+		var err error
+		if img, err = os.ReadFile(path); err != nil {
+			return "", err
+		}
+		filename = filepath.Base(path)
+		contentType = ""
+	} else {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, placeholderAvatar(512)); err != nil {
+			return "", err
+		}
+		img = buf.Bytes()
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("kind", seedAvatarKind)
+	_ = mw.WriteField("label", seedAvatarLabel)
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filename))
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(img); err != nil {
+		return "", err
+	}
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Asset struct {
+			ID string `json:"id"`
+		} `json:"asset"`
+	}
+	if err := x.c.do(ctx, "POST", "/assets", nil, rawBody{r: &body, contentType: mw.FormDataContentType()}, &resp); err != nil {
+		return "", fmt.Errorf("upload demo avatar: %w", err)
+	}
+	fmt.Fprintf(x.out, "asset\t%s\tcreated\t%s\n", seedAvatarLabel, resp.Asset.ID)
+	return resp.Asset.ID, nil
+}
+
+// waitJob polls a job until it finishes and prints its outputs.
+func (x *cli) waitJob(ctx context.Context, id string) error {
+	var resp struct {
+		Job struct {
+			Status  string `json:"status"`
+			Error   string `json:"error"`
+			Outputs []struct {
+				VideoAssetID     string `json:"video_asset_id"`
+				ThumbnailAssetID string `json:"thumbnail_asset_id"`
+				CaptionsAssetID  string `json:"captions_asset_id"`
+			} `json:"outputs"`
+		} `json:"job"`
+	}
+	status := ""
+	for {
+		if err := x.c.do(ctx, "GET", "/jobs/"+url.PathEscape(id), nil, nil, &resp); err != nil {
+			return err
+		}
+		if resp.Job.Status != status {
+			status = resp.Job.Status
+			fmt.Fprintf(os.Stderr, "%s: %s\n", id, status)
+		}
+		switch status {
+		case "DONE":
+			for _, o := range resp.Job.Outputs {
+				fmt.Fprintf(x.out, "output\tvideo\t%s\n", o.VideoAssetID)
+				if o.ThumbnailAssetID != "" {
+					fmt.Fprintf(x.out, "output\tthumbnail\t%s\n", o.ThumbnailAssetID)
+				}
+				if o.CaptionsAssetID != "" {
+					fmt.Fprintf(x.out, "output\tcaptions\t%s\n", o.CaptionsAssetID)
+				}
+			}
+			return nil
+		case "FAILED", "CANCELED":
+			return fmt.Errorf("demo job %s %s: %s", id, status, resp.Job.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("demo job %s still %s: %w", id, status, ctx.Err())
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// placeholderAvatar draws a simple face, enough for the renderer to build
+// a video and a thumbnail from.
+func placeholderAvatar(size int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	bg := color.RGBA{0x2b, 0x3a, 0x55, 0xff}
+	skin := color.RGBA{0xf2, 0xc6, 0x9b, 0xff}
+	dark := color.RGBA{0x33, 0x22, 0x1a, 0xff}
+
+	c := float64(size) / 2
+	in := func(x, y int, cx, cy, rx, ry float64) bool {
+		dx, dy := (float64(x)-cx)/rx, (float64(y)-cy)/ry
+		return dx*dx+dy*dy <= 1
+	}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			s := float64(size)
+			px := bg
+			switch {
+			case in(x, y, c-0.13*s, 0.42*s, 0.04*s, 0.05*s), in(x, y, c+0.13*s, 0.42*s, 0.04*s, 0.05*s):
+				px = dark // eyes
+			case in(x, y, c, 0.62*s, 0.12*s, 0.04*s) && float64(y) > 0.62*s:
+				px = dark // mouth
+			case in(x, y, c, 0.47*s, 0.3*s, 0.36*s):
+				px = skin
+			case in(x, y, c, 1.05*s, 0.42*s, 0.25*s):
+				px = dark // shoulders
+			}
+			img.SetRGBA(x, y, px)
+		}
+	}
+	return img
+}
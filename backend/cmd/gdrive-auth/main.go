@@ -1,142 +1,283 @@
-package main
-
-import (
-	"context"
-	"crypto/rand"
-	"encoding/base64"
-	"fmt"
-	"log"
-	"net"
-	"net/http"
-	"net/url"
-	"os"
-	"strings"
-	"time"
-
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
-	drive "google.golang.org/api/drive/v3"
-)
-
-func main() {
-	ctx := context.Background()
-
-	clientID := mustEnv("GDRIVE_CLIENT_ID")
-	clientSecret := mustEnv("GDRIVE_CLIENT_SECRET")
-
-	// 1) Levanta un callback local en un puerto libre
-	ln, err := net.Listen("tcp", "127.0.0.1:0")
-	if err != nil {
-		panic(err)
-	}
-	defer ln.Close()
-
-	port := ln.Addr().(*net.TCPAddr).Port
-	redirectURL := fmt.Sprintf("http://127.0.0.1:%d/callback", port)
-
-	conf := &oauth2.Config{
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
-		Endpoint:     google.Endpoint,
-		Scopes:       []string{drive.DriveFileScope}, // <-- SOLO lo que necesitamos
-		RedirectURL:  redirectURL,
-	}
-
-	state := randomState()
-
-	codeCh := make(chan string, 1)
-	errCh := make(chan error, 1)
-
-	mux := http.NewServeMux()
-	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
-		q := r.URL.Query()
-		if q.Get("state") != state {
-			http.Error(w, "invalid state", http.StatusBadRequest)
-			errCh <- fmt.Errorf("invalid state")
-			return
-		}
-		if e := q.Get("error"); e != "" {
-			http.Error(w, "auth error: "+e, http.StatusBadRequest)
-			errCh <- fmt.Errorf("auth error: %s", e)
-			return
-		}
-		code := q.Get("code")
-		if code == "" {
-			http.Error(w, "missing code", http.StatusBadRequest)
-			errCh <- fmt.Errorf("missing code")
-			return
-		}
-
-		fmt.Fprintln(w, "OK. Ya puedes cerrar esta ventana y volver a la terminal.")
-		codeCh <- code
-	})
-
-	srv := &http.Server{
-		Handler:      mux,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-	}
-	go func() {
-		_ = srv.Serve(ln)
-	}()
-
-	// 2) Genera URL de autorización (offline => refresh token)
-	authURL := conf.AuthCodeURL(
-		state,
-		oauth2.AccessTypeOffline,
-		oauth2.SetAuthURLParam("prompt", "consent"),
-	)
-
-	fmt.Println("\nAbre esta URL en tu navegador:")
-	fmt.Println(authURL)
-	fmt.Println("\nEsperando autorización en:", redirectURL)
-
-	// 3) Espera code o error
-	var code string
-	select {
-	case code = <-codeCh:
-	case err := <-errCh:
-		_ = srv.Close()
-		log.Fatal(err)
-	case <-time.After(3 * time.Minute):
-		_ = srv.Close()
-		log.Fatal("timeout esperando autorización")
-	}
-
-	_ = srv.Close()
-
-	// 4) Intercambia code por tokens
-	tok, err := conf.Exchange(ctx, code)
-	if err != nil {
-		panic(err)
-	}
-
-	// Nota: refresh token puede venir vacío si ya autorizaste antes sin prompt=consent.
-	// Con prompt=consent normalmente se fuerza a entregarlo.
-	if strings.TrimSpace(tok.RefreshToken) == "" {
-		fmt.Println("\n⚠️ No llegó refresh_token.")
-		fmt.Println("Solución: revoca acceso previo de la app en tu Google Account y vuelve a correr este comando.")
-		fmt.Println("https://myaccount.google.com/permissions")
-		return
-	}
-
-	fmt.Println("\n✅ REFRESH TOKEN:")
-	fmt.Println(tok.RefreshToken)
-}
-
-func mustEnv(k string) string {
-	v := strings.TrimSpace(os.Getenv(k))
-	if v == "" {
-		panic("missing env: " + k)
-	}
-	return v
-}
-
-func randomState() string {
-	b := make([]byte, 18)
-	_, _ = rand.Read(b)
-	return base64.RawURLEncoding.EncodeToString(b)
-}
-
-// (opcional) por si quieres depurar URLs
-var _ = url.URL{}
+// Command gdrive-auth obtiene el refresh token de Google Drive que usa el
+// storage provider gdrive (GDRIVE_REFRESH_TOKEN o GDRIVE_REFRESH_TOKEN_FILE).
+//
+//	gdrive-auth                         # navegador en esta máquina (callback local)
+//	gdrive-auth -device                 # sin navegador: el código se ingresa en otro dispositivo
+//	gdrive-auth -out /run/secrets/gdrive_refresh_token -verify
+//	gdrive-auth -youtube                # token de subida a YouTube (YOUTUBE_REFRESH_TOKEN)
+//
+// Lee GDRIVE_CLIENT_ID y GDRIVE_CLIENT_SECRET (YOUTUBE_CLIENT_ID y
+// YOUTUBE_CLIENT_SECRET con -youtube); -verify usa además GDRIVE_FOLDER_ID.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	drive "google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+
+	"gala/internal/adapters/publish/youtube"
+	"gala/internal/adapters/storage/gdrive"
+	"gala/internal/ports"
+)
+
+func main() {
+	device := flag.Bool("device", false, "use the device-code flow (headless servers: authorize from any other device)")
+	out := flag.String("out", "", "write the refresh token to this file (mode 0600) instead of printing it")
+	verify := flag.Bool("verify", false, "upload and delete a test file in GDRIVE_FOLDER_ID with the new token")
+	timeout := flag.Duration("timeout", 5*time.Minute, "how long to wait for the authorization")
+	yt := flag.Bool("youtube", false, "authorize the YouTube upload scope for the publish targets instead of Drive")
+	flag.Parse()
+	if *yt && *verify {
+		log.Fatal("-verify only checks Drive tokens")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	envPrefix, scope := "GDRIVE", drive.DriveFileScope // <-- SOLO lo que necesitamos
+	if *yt {
+		envPrefix, scope = "YOUTUBE", youtube.UploadScope
+	}
+	conf := &oauth2.Config{
+		ClientID:     mustEnv(envPrefix + "_CLIENT_ID"),
+		ClientSecret: mustEnv(envPrefix + "_CLIENT_SECRET"),
+		Endpoint:     google.Endpoint,
+		Scopes:       []string{scope},
+	}
+
+	var (
+		tok *oauth2.Token
+		err error
+	)
+	if *device {
+		tok, err = deviceFlow(ctx, conf)
+	} else {
+		tok, err = browserFlow(ctx, conf)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Nota: refresh token puede venir vacío si ya autorizaste antes sin prompt=consent.
+	// Con prompt=consent normalmente se fuerza a entregarlo.
+	if strings.TrimSpace(tok.RefreshToken) == "" {
+		fmt.Fprintln(os.Stderr, "\n⚠️ No llegó refresh_token.")
+		fmt.Fprintln(os.Stderr, "Solución: revoca acceso previo de la app en tu Google Account y vuelve a correr este comando.")
+		fmt.Fprintln(os.Stderr, "https://myaccount.google.com/permissions")
+		os.Exit(1)
+	}
+
+	// Guardar antes de verificar: si la verificación falla el token no se pierde
+	if *out != "" {
+		if err := writeSecret(*out, tok.RefreshToken); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(os.Stderr, "\n✅ Refresh token guardado en %s (0600). Usa %s_REFRESH_TOKEN_FILE=%s\n", *out, envPrefix, *out)
+	} else {
+		fmt.Println("\n✅ REFRESH TOKEN:")
+		fmt.Println(tok.RefreshToken)
+	}
+
+	if *verify {
+		folderID := strings.TrimSpace(os.Getenv("GDRIVE_FOLDER_ID"))
+		if err := verifyUpload(ctx, conf, tok, folderID); err != nil {
+			log.Fatalf("verificación fallida: %v", err)
+		}
+		where := "la raíz de My Drive"
+		if folderID != "" {
+			where = "la carpeta " + folderID
+		}
+		fmt.Fprintf(os.Stderr, "✅ Verificado: se subió y borró un archivo de prueba en %s\n", where)
+	}
+}
+
+// browserFlow autoriza con un navegador en esta máquina, recibiendo el
+// code en un callback local.
+func browserFlow(ctx context.Context, conf *oauth2.Config) (*oauth2.Token, error) {
+	// 1) Levanta un callback local en un puerto libre
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	redirectURL := fmt.Sprintf("http://127.0.0.1:%d/callback", port)
+	c := *conf
+	c.RedirectURL = redirectURL
+
+	state := randomState()
+
+	codeCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			http.Error(w, "invalid state", http.StatusBadRequest)
+			errCh <- fmt.Errorf("invalid state")
+			return
+		}
+		if e := q.Get("error"); e != "" {
+			http.Error(w, "auth error: "+e, http.StatusBadRequest)
+			errCh <- fmt.Errorf("auth error: %s", e)
+			return
+		}
+		code := q.Get("code")
+		if code == "" {
+			http.Error(w, "missing code", http.StatusBadRequest)
+			errCh <- fmt.Errorf("missing code")
+			return
+		}
+
+		fmt.Fprintln(w, "OK. Ya puedes cerrar esta ventana y volver a la terminal.")
+		codeCh <- code
+	})
+
+	srv := &http.Server{
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	defer srv.Close()
+
+	// 2) Genera URL de autorización (offline => refresh token)
+	authURL := c.AuthCodeURL(
+		state,
+		oauth2.AccessTypeOffline,
+		oauth2.SetAuthURLParam("prompt", "consent"),
+	)
+
+	fmt.Fprintln(os.Stderr, "\nAbre esta URL en tu navegador:")
+	fmt.Fprintln(os.Stderr, authURL)
+	fmt.Fprintln(os.Stderr, "\nEsperando autorización en:", redirectURL)
+
+	// 3) Espera code o error
+	var code string
+	select {
+	case code = <-codeCh:
+	case err := <-errCh:
+		return nil, err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("timeout esperando autorización")
+	}
+
+	// 4) Intercambia code por tokens
+	return c.Exchange(ctx, code)
+}
+
+// deviceFlow autoriza sin navegador local (OAuth device-code flow): el
+// usuario abre la URL en cualquier otro dispositivo e ingresa el código.
+// El client OAuth tiene que ser de tipo "TVs and Limited Input devices".
+func deviceFlow(ctx context.Context, conf *oauth2.Config) (*oauth2.Token, error) {
+	da, err := conf.DeviceAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("device auth: %w", err)
+	}
+
+	url := da.VerificationURIComplete
+	if url == "" {
+		url = da.VerificationURI
+	}
+	fmt.Fprintln(os.Stderr, "\nEn cualquier dispositivo abre:")
+	fmt.Fprintln(os.Stderr, url)
+	fmt.Fprintln(os.Stderr, "\ne ingresa el código:", da.UserCode)
+	if !da.Expiry.IsZero() {
+		fmt.Fprintf(os.Stderr, "\nEsperando autorización (el código vence en %s)...\n", time.Until(da.Expiry).Round(time.Second))
+	}
+
+	tok, err := conf.DeviceAccessToken(ctx, da)
+	if err != nil {
+		return nil, fmt.Errorf("device token: %w", err)
+	}
+	return tok, nil
+}
+
+// verifyUpload sube y borra un archivo de prueba en folderID usando tok,
+// igual que lo hace el storage provider gdrive.
+func verifyUpload(ctx context.Context, conf *oauth2.Config, tok *oauth2.Token, folderID string) error {
+	srv, err := drive.NewService(ctx, option.WithHTTPClient(conf.Client(ctx, tok)))
+	if err != nil {
+		return err
+	}
+	client := gdrive.NewClient(srv, folderID)
+
+	payload := []byte("gala gdrive-auth verify " + time.Now().UTC().Format(time.RFC3339))
+	put, err := client.PutObject(ctx, ports.PutObjectInput{
+		ObjectKey:   "gdrive-auth/verify-" + randomState() + ".txt",
+		ContentType: "text/plain",
+		Reader:      bytes.NewReader(payload),
+		Size:        int64(len(payload)),
+	})
+	if err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+	if err := client.DeleteObject(ctx, put.ObjectKey); err != nil {
+		return fmt.Errorf("delete test file %s: %w", put.ObjectKey, err)
+	}
+	return nil
+}
+
+// writeSecret escribe value en path con permisos 0600, reemplazándolo de
+// forma atómica para que nadie lea un archivo a medias.
+func writeSecret(path, value string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.WriteString(value + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func mustEnv(k string) string {
+	v := strings.TrimSpace(os.Getenv(k))
+	if v == "" {
+		log.Fatal("missing env: " + k)
+	}
+	return v
+}
+
+func randomState() string {
+	b := make([]byte, 18)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,93 @@
+// Command migrate manages the database schema (DATABASE_URL) using the
+// migrations embedded from backend/migrations.
+//
+//	migrate up              apply every pending migration
+//	migrate down [-steps N] revert the last N migrations (default 1)
+//	migrate status          list migrations and when they were applied
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"gala/internal/bootstrap"
+	"gala/internal/pkg/dbpool"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/migrate"
+	"gala/migrations"
+)
+
+func main() {
+	steps := flag.Int("steps", 1, "migrations to revert with down")
+	timeout := flag.Duration("timeout", 10*time.Minute, "overall timeout")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: migrate [-steps N] [-timeout D] up|down|status\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	log := bootstrap.NewLogger("gala-migrate")
+
+	dbURL := bootstrap.Env("DATABASE_URL", "")
+	if dbURL == "" {
+		log.LogFatal("missing required environment variable", nil, "key", "DATABASE_URL")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	pool, err := dbpool.New(ctx, dbURL, dbpool.Config{MaxConns: 2})
+	if err != nil {
+		log.LogFatal("failed to connect to PostgreSQL", err)
+	}
+	defer pool.Close()
+	// LogFatal skips deferred calls
+	logger.SetExitHook(pool.Close)
+
+	m, err := migrate.New(pool, migrations.FS, log)
+	if err != nil {
+		log.LogFatal("invalid migrations", err)
+	}
+
+	switch cmd := flag.Arg(0); cmd {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			log.LogFatal("migrate up failed", err, "applied", n)
+		}
+		log.Info("database up to date", "applied", n)
+	case "down":
+		n, err := m.Down(ctx, *steps)
+		if err != nil {
+			log.LogFatal("migrate down failed", err, "reverted", n)
+		}
+		log.Info("migrations reverted", "reverted", n)
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			log.LogFatal("migrate status failed", err)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			at := "pending"
+			if s.AppliedAt != nil {
+				at = s.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%03d\t%s\t%s\n", s.Version, s.Name, at)
+		}
+		_ = tw.Flush()
+	default:
+		log.Error("unknown command", "command", cmd)
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gala/internal/mockrenderer"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/shutdown"
+	"gala/internal/worker/renderer"
+)
+
+func main() {
+	// Initialize logger
+	log := logger.New(logger.Config{
+		Level:       getEnv("LOG_LEVEL", "info"),
+		Format:      getEnv("LOG_FORMAT", "json"),
+		ServiceName: "gala-mock-renderer",
+		AddSource:   getEnv("LOG_SOURCE", "false") == "true",
+	})
+
+	// Flags default to env vars so the binary works the same in compose and locally
+	port := flag.String("port", getEnv("RENDERER_PORT", "9000"), "HTTP port")
+	storageRoot := flag.String("storage-root", getEnv("STORAGE_LOCAL_ROOT", "/data"), "shared storage root")
+	delay := flag.Duration("delay", durationEnv("MOCK_RENDER_DELAY", 0), "delay before answering each render")
+	failRate := flag.Float64("fail-rate", floatEnv("MOCK_RENDER_FAIL_RATE", 0), "probability (0..1) of injected failures")
+	failStatus := flag.Int("fail-status", intEnv("MOCK_RENDER_FAIL_STATUS", http.StatusInternalServerError), "HTTP status for injected failures")
+	failJobs := flag.String("fail-jobs", getEnv("MOCK_RENDER_FAIL_JOBS", ""), "comma-separated job IDs that always fail")
+	skipOutputs := flag.Bool("skip-outputs", getEnv("MOCK_RENDER_SKIP_OUTPUTS", "false") == "true", "answer OK without writing outputs")
+	authSecrets := flag.String("auth-secrets", getEnv("MOCK_RENDER_AUTH_SECRETS", ""), "comma-separated accepted secrets (value or id:value)")
+	skipCaptions := flag.Bool("skip-captions", getEnv("MOCK_RENDER_SKIP_CAPTIONS", "false") == "true", "never write captions files")
+	flag.Parse()
+
+	log.Info("starting GALA mock renderer",
+		"port", *port,
+		"storage_root", *storageRoot,
+		"delay", delay.String(),
+		"fail_rate", *failRate,
+		"fail_status", *failStatus,
+		"skip_outputs", *skipOutputs,
+		"skip_captions", *skipCaptions,
+		"auth", *authSecrets != "",
+	)
+
+	srv := mockrenderer.New(mockrenderer.Options{
+		StorageRoot:  *storageRoot,
+		Delay:        *delay,
+		FailRate:     *failRate,
+		FailStatus:   *failStatus,
+		FailJobs:     splitCSV(*failJobs),
+		SkipOutputs:  *skipOutputs,
+		SkipCaptions: *skipCaptions,
+		AuthSecrets:  parseSecrets(*authSecrets),
+		Log:          log,
+	})
+
+	server := &http.Server{
+		Addr:              "0.0.0.0:" + *port,
+		Handler:           srv,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	shutdownMgr := shutdown.NewManager(log, 10*time.Second)
+	shutdownMgr.Register("http-server", func(ctx context.Context) error {
+		return server.Shutdown(ctx)
+	})
+
+	go func() {
+		log.Info("mock renderer listening", "addr", server.Addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.LogFatal("mock renderer failed", err)
+		}
+	}()
+
+	shutdownMgr.Wait()
+}
+
+// getEnv gets an environment variable with a default value.
+func getEnv(key, defaultValue string) string {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return defaultValue
+	}
+	return v
+}
+
+// durationEnv gets a duration environment variable.
+func durationEnv(key string, defaultValue time.Duration) time.Duration {
+	d, err := time.ParseDuration(getEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return d
+}
+
+// floatEnv gets a float environment variable.
+func floatEnv(key string, defaultValue float64) float64 {
+	f, err := strconv.ParseFloat(getEnv(key, ""), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return f
+}
+
+// intEnv gets an integer environment variable.
+func intEnv(key string, defaultValue int) int {
+	n, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return n
+}
+
+func splitCSV(raw string) []string {
+	out := []string{}
+	for _, p := range strings.Split(raw, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func parseSecrets(raw string) []renderer.Secret {
+	out := []renderer.Secret{}
+	for _, p := range splitCSV(raw) {
+		if id, value, ok := strings.Cut(p, ":"); ok && id != "" && value != "" {
+			out = append(out, renderer.Secret{ID: id, Value: value})
+			continue
+		}
+		out = append(out, renderer.Secret{Value: p})
+	}
+	return out
+}
@@ -0,0 +1,123 @@
+// Package cdn invalidates cached paths on the CDN in front of the s3
+// publish target, so a republished render replaces the old one right away.
+package cdn
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gala/internal/pkg/awsv4"
+)
+
+// Invalidator purges paths (URL paths, e.g. "/renders/job_1/1.mp4") from a
+// CDN cache.
+type Invalidator interface {
+	Invalidate(ctx context.Context, paths []string) error
+}
+
+// CloudFront invalidates paths of a CloudFront distribution.
+type CloudFront struct {
+	http           *http.Client
+	creds          awsv4.Credentials
+	distributionID string
+}
+
+// NewCloudFront creates a CloudFront invalidator; nil httpClient uses
+// http.DefaultClient.
+func NewCloudFront(httpClient *http.Client, creds awsv4.Credentials, distributionID string) *CloudFront {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &CloudFront{http: httpClient, creds: creds, distributionID: distributionID}
+}
+
+type invalidationBatch struct {
+	XMLName xml.Name `xml:"http://cloudfront.amazonaws.com/doc/2020-05-31/ InvalidationBatch"`
+	Paths   struct {
+		Quantity int      `xml:"Quantity"`
+		Items    []string `xml:"Items>Path"`
+	} `xml:"Paths"`
+	CallerReference string `xml:"CallerReference"`
+}
+
+// Invalidate creates an invalidation; CloudFront applies it in the
+// background, usually within a minute.
+func (c *CloudFront) Invalidate(ctx context.Context, paths []string) error {
+	var batch invalidationBatch
+	batch.Paths.Quantity = len(paths)
+	batch.Paths.Items = paths
+	batch.CallerReference = fmt.Sprintf("gala-%d", time.Now().UnixNano())
+	body, err := xml.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	u := "https://cloudfront.amazonaws.com/2020-05-31/distribution/" + url.PathEscape(c.distributionID) + "/invalidation"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/xml")
+	// CloudFront is global; its requests are signed for us-east-1
+	awsv4.Sign(req, c.creds, "us-east-1", "cloudfront", awsv4.HashPayload(body), time.Now())
+	return do(c.http, req, "cloudfront invalidation")
+}
+
+// Fastly purges single URLs of a Fastly service.
+type Fastly struct {
+	http  *http.Client
+	token string
+	// base is the public URL the paths are relative to.
+	base *url.URL
+}
+
+// NewFastly creates a Fastly purger for the URLs under publicBase; nil
+// httpClient uses http.DefaultClient.
+func NewFastly(httpClient *http.Client, token, publicBase string) (*Fastly, error) {
+	base, err := url.Parse(publicBase)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid public base URL %q", publicBase)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Fastly{http: httpClient, token: token, base: base}, nil
+}
+
+// Invalidate purges each path's URL.
+func (f *Fastly) Invalidate(ctx context.Context, paths []string) error {
+	for _, p := range paths {
+		u := "https://api.fastly.com/purge/" + f.base.Host + "/" + strings.TrimPrefix(p, "/")
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Fastly-Key", f.token)
+		req.Header.Set("Accept", "application/json")
+		if err := do(f.http, req, "fastly purge"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// do sends req and turns a non-2xx response into an error.
+func do(client *http.Client, req *http.Request, op string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s: %s", op, resp.Status, strings.TrimSpace(string(raw)))
+	}
+	return nil
+}
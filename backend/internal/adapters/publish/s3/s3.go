@@ -0,0 +1,131 @@
+// Package s3 publishes renders to a public S3 (or S3-compatible) bucket
+// under deterministic names, and invalidates them on the CDN in front of
+// it, so the public URL of an output never changes.
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"gala/internal/adapters/publish/cdn"
+	"gala/internal/pkg/awsv4"
+	"gala/internal/ports"
+)
+
+// Config configures the target.
+type Config struct {
+	Bucket string
+	Region string
+	// Endpoint is the bucket URL; empty uses
+	// https://<bucket>.s3.<region>.amazonaws.com. S3-compatible stores
+	// (MinIO, R2, ...) set their own, path-style included
+	// (https://minio:9000/<bucket>).
+	Endpoint string
+	// Prefix is prepended to every object name, e.g. "renders".
+	Prefix string
+	// PublicBaseURL is where the bucket is served (the CDN); empty uses
+	// Endpoint.
+	PublicBaseURL string
+	// CacheControl is set on the uploaded objects.
+	CacheControl string
+	Credentials  awsv4.Credentials
+	// Invalidator purges republished objects from the CDN; nil skips it.
+	Invalidator cdn.Invalidator
+}
+
+// Client implements ports.PublishTarget on S3.
+type Client struct {
+	http     *http.Client
+	cfg      Config
+	endpoint *url.URL
+	public   *url.URL
+}
+
+// NewClient validates cfg and creates the client; nil httpClient uses
+// http.DefaultClient.
+func NewClient(httpClient *http.Client, cfg Config) (*Client, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("bucket is required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://" + cfg.Bucket + ".s3." + cfg.Region + ".amazonaws.com"
+	}
+	if cfg.PublicBaseURL == "" {
+		cfg.PublicBaseURL = cfg.Endpoint
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q", cfg.Endpoint)
+	}
+	public, err := url.Parse(strings.TrimRight(cfg.PublicBaseURL, "/"))
+	if err != nil || public.Host == "" {
+		return nil, fmt.Errorf("invalid public base URL %q", cfg.PublicBaseURL)
+	}
+	if cfg.Credentials.AccessKeyID == "" || cfg.Credentials.SecretAccessKey == "" {
+		return nil, fmt.Errorf("access key and secret are required")
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{http: httpClient, cfg: cfg, endpoint: endpoint, public: public}, nil
+}
+
+func (c *Client) Target() string { return "s3" }
+
+// Publish uploads the render as <prefix>/<in.Name>, replacing any previous
+// upload, invalidates that path on the CDN and returns the object key and
+// public URL.
+func (c *Client) Publish(ctx context.Context, in ports.PublishInput) (ports.PublishOutput, error) {
+	if in.Name == "" {
+		return ports.PublishOutput{}, fmt.Errorf("object name is required")
+	}
+	if in.Size <= 0 {
+		return ports.PublishOutput{}, fmt.Errorf("video size is unknown")
+	}
+	key := path.Join(c.cfg.Prefix, in.Name)
+
+	u := *c.endpoint
+	u.Path = path.Join(u.Path, key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), in.Reader)
+	if err != nil {
+		return ports.PublishOutput{}, err
+	}
+	req.ContentLength = in.Size
+	if in.ContentType != "" {
+		req.Header.Set("Content-Type", in.ContentType)
+	}
+	if c.cfg.CacheControl != "" {
+		req.Header.Set("Cache-Control", c.cfg.CacheControl)
+	}
+	// Streamed, so the body is not hashed (allowed over HTTPS)
+	req.Header.Set("X-Amz-Content-Sha256", awsv4.UnsignedPayload)
+	awsv4.Sign(req, c.cfg.Credentials, c.cfg.Region, "s3", awsv4.UnsignedPayload, time.Now())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return ports.PublishOutput{}, fmt.Errorf("s3 upload: %w", err)
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return ports.PublishOutput{}, fmt.Errorf("s3 upload: %s: %s", resp.Status, strings.TrimSpace(string(raw)))
+	}
+
+	pub := *c.public
+	pub.Path = path.Join("/", pub.Path, key)
+	if c.cfg.Invalidator != nil {
+		if err := c.cfg.Invalidator.Invalidate(ctx, []string{pub.EscapedPath()}); err != nil {
+			return ports.PublishOutput{}, err
+		}
+	}
+	return ports.PublishOutput{ExternalID: key, URL: pub.String()}, nil
+}
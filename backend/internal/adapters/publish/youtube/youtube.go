@@ -0,0 +1,151 @@
+// Package youtube publishes videos to a YouTube channel with the Data API
+// v3 resumable upload, over an OAuth2 HTTP client (see NewClient).
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gala/internal/ports"
+)
+
+// UploadScope is the OAuth2 scope needed to upload videos.
+const UploadScope = "https://www.googleapis.com/auth/youtube.upload"
+
+const uploadURL = "https://www.googleapis.com/upload/youtube/v3/videos?uploadType=resumable&part=snippet,status"
+
+// Client implements ports.PublishTarget for YouTube.
+type Client struct {
+	http *http.Client
+	// privacy is used when the input does not set one.
+	privacy string
+	// categoryID is the YouTube video category ("22" People & Blogs).
+	categoryID string
+}
+
+// NewClient creates a client; httpClient must add the OAuth2 token with
+// UploadScope (oauth2.Config.Client). privacy defaults to "private" and
+// categoryID to "22".
+func NewClient(httpClient *http.Client, privacy, categoryID string) *Client {
+	if privacy == "" {
+		privacy = "private"
+	}
+	if categoryID == "" {
+		categoryID = "22"
+	}
+	return &Client{http: httpClient, privacy: privacy, categoryID: categoryID}
+}
+
+func (c *Client) Target() string { return "youtube" }
+
+type snippet struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	CategoryID  string   `json:"categoryId"`
+}
+
+type videoStatus struct {
+	PrivacyStatus           string `json:"privacyStatus"`
+	SelfDeclaredMadeForKids bool   `json:"selfDeclaredMadeForKids"`
+}
+
+type video struct {
+	ID      string      `json:"id,omitempty"`
+	Snippet snippet     `json:"snippet"`
+	Status  videoStatus `json:"status"`
+}
+
+// Publish uploads the video: a first request sends the metadata and opens
+// an upload session, a second one sends the content to it.
+func (c *Client) Publish(ctx context.Context, in ports.PublishInput) (ports.PublishOutput, error) {
+	privacy := in.Privacy
+	if privacy == "" {
+		privacy = c.privacy
+	}
+	contentType := in.ContentType
+	if contentType == "" {
+		contentType = "video/mp4"
+	}
+	meta, err := json.Marshal(video{
+		Snippet: snippet{Title: in.Title, Description: in.Description, Tags: in.Tags, CategoryID: c.categoryID},
+		Status:  videoStatus{PrivacyStatus: privacy},
+	})
+	if err != nil {
+		return ports.PublishOutput{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(meta))
+	if err != nil {
+		return ports.PublishOutput{}, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("X-Upload-Content-Type", contentType)
+	if in.Size > 0 {
+		req.Header.Set("X-Upload-Content-Length", strconv.FormatInt(in.Size, 10))
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return ports.PublishOutput{}, fmt.Errorf("youtube upload session: %w", err)
+	}
+	session := resp.Header.Get("Location")
+	err = checkResponse(resp, "upload session")
+	resp.Body.Close()
+	if err != nil {
+		return ports.PublishOutput{}, err
+	}
+	if session == "" {
+		return ports.PublishOutput{}, fmt.Errorf("youtube upload session: no Location header")
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodPut, session, in.Reader)
+	if err != nil {
+		return ports.PublishOutput{}, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if in.Size > 0 {
+		req.ContentLength = in.Size
+	}
+	resp, err = c.http.Do(req)
+	if err != nil {
+		return ports.PublishOutput{}, fmt.Errorf("youtube upload: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, "upload"); err != nil {
+		return ports.PublishOutput{}, err
+	}
+
+	var v video
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return ports.PublishOutput{}, fmt.Errorf("youtube upload: invalid response: %w", err)
+	}
+	if v.ID == "" {
+		return ports.PublishOutput{}, fmt.Errorf("youtube upload: response has no video id")
+	}
+	return ports.PublishOutput{ExternalID: v.ID, URL: "https://www.youtube.com/watch?v=" + v.ID}, nil
+}
+
+// checkResponse turns a non-2xx response into an error with the API's
+// message.
+func checkResponse(resp *http.Response, op string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	msg := strings.TrimSpace(string(raw))
+	if json.Unmarshal(raw, &body) == nil && body.Error.Message != "" {
+		msg = body.Error.Message
+	}
+	return fmt.Errorf("youtube %s: %s: %s", op, resp.Status, msg)
+}
@@ -4,6 +4,8 @@ import (
     "context"
     "fmt"
     "io"
+    "slices"
+    "strings"
     "time"
 
     "gala/internal/ports"
@@ -18,12 +20,80 @@ import (
 type Client struct {
     srv      *drive.Service
     folderID string
+    // folders maps a Category to its folder; categories without one use
+    // folderID
+    folders map[string]string
+    // driveID is the Shared Drive holding the folders, if any
+    driveID string
 }
 
+// Categories of objects that can go to their own folder (WithFolders).
+const (
+    CategoryInputs     = "inputs"     // uploaded and imported assets
+    CategoryRenders    = "renders"    // videos, captions and HLS of renders
+    CategoryThumbnails = "thumbnails" // render thumbnails
+)
+
+// Categories lists the categories WithFolders accepts.
+var Categories = []string{CategoryInputs, CategoryRenders, CategoryThumbnails}
+
 func NewClient(srv *drive.Service, folderID string) *Client {
     return &Client{srv: srv, folderID: folderID}
 }
 
+// WithFolders uploads each category of objects to its own folder; the
+// categories missing from folders keep going to the default folder.
+func (c *Client) WithFolders(folders map[string]string) *Client {
+    c.folders = folders
+    return c
+}
+
+// WithSharedDrive stores the objects in the Shared Drive driveID: the
+// folders must belong to it, and without any folder files go to its root.
+func (c *Client) WithSharedDrive(driveID string) *Client {
+    c.driveID = driveID
+    return c
+}
+
+// category classifies an upload: thumbnails by asset kind, the rest of
+// renders/ as renders, anything else (assets/) as inputs.
+func category(in ports.PutObjectInput) string {
+    switch {
+    case in.Kind == "thumbnail":
+        return CategoryThumbnails
+    case strings.HasPrefix(in.ObjectKey, "renders/"):
+        return CategoryRenders
+    default:
+        return CategoryInputs
+    }
+}
+
+// parent is the folder an upload goes to; empty means My Drive's root.
+func (c *Client) parent(in ports.PutObjectInput) string {
+    if id := c.folders[category(in)]; id != "" {
+        return id
+    }
+    if c.folderID != "" {
+        return c.folderID
+    }
+    return c.driveID
+}
+
+// parents are all the folders objects are uploaded to, for listing.
+func (c *Client) parents() []string {
+    ids := []string{c.folderID}
+    for _, cat := range Categories {
+        ids = append(ids, c.folders[cat])
+    }
+    var out []string
+    for _, id := range ids {
+        if id != "" && !slices.Contains(out, id) {
+            out = append(out, id)
+        }
+    }
+    return out
+}
+
 func (c *Client) Provider() string { return "gdrive" }
 
 func (c *Client) PutObject(ctx context.Context, in ports.PutObjectInput) (ports.PutObjectOutput, error) {
@@ -32,11 +102,11 @@ func (c *Client) PutObject(ctx context.Context, in ports.PutObjectInput) (ports.
     }
 
     file := &drive.File{Name: in.ObjectKey}
-    if c.folderID != "" {
-        file.Parents = []string{c.folderID}
+    if parent := c.parent(in); parent != "" {
+        file.Parents = []string{parent}
     }
 
-    call := c.srv.Files.Create(file)
+    call := c.srv.Files.Create(file).SupportsAllDrives(true)
     if in.ContentType != "" {
         call = call.Media(in.Reader, googleapi.ContentType(in.ContentType))
     } else {
@@ -52,9 +122,28 @@ func (c *Client) PutObject(ctx context.Context, in ports.PutObjectInput) (ports.
     return ports.PutObjectOutput{ObjectKey: created.Id, Size: in.Size}, nil
 }
 
+// GetObject downloads a file. Drive's download responses often lack
+// Content-Length and carry a generic Content-Type, so the file's size and
+// mimeType are read from its metadata at the same time; the response
+// headers are only a fallback if that lookup fails.
 func (c *Client) GetObject(ctx context.Context, objectKey string) (rc io.ReadCloser, contentType string, size int64, err error) {
+    type result struct {
+        f   *drive.File
+        err error
+    }
+    meta := make(chan result, 1)
+    go func() {
+        f, err := c.srv.Files.Get(objectKey).
+            SupportsAllDrives(true).
+            Fields("size, mimeType").
+            Context(ctx).
+            Do()
+        meta <- result{f, err}
+    }()
+
     resp, err := c.srv.Files.Get(objectKey).
         SupportsAllDrives(true).
+        Context(ctx).
         Download()
     if err != nil {
         return nil, "", 0, err
@@ -62,6 +151,15 @@ func (c *Client) GetObject(ctx context.Context, objectKey string) (rc io.ReadClo
 
     contentType = resp.Header.Get("Content-Type")
     size = resp.ContentLength
+    if m := <-meta; m.err == nil && m.f != nil {
+        if m.f.MimeType != "" {
+            contentType = m.f.MimeType
+        }
+        // Native Google files (Docs, Sheets) have no size
+        if m.f.Size > 0 {
+            size = m.f.Size
+        }
+    }
     return resp.Body, contentType, size, nil
 }
 
@@ -76,3 +174,47 @@ func (c *Client) GetSignedURL(ctx context.Context, objectKey string, expiresIn t
     // v0: we don't generate signed URLs for Drive in this iteration.
     return ports.SignedURLOutput{URL: "", ExpiresAt: time.Now().UTC().Add(expiresIn)}, nil
 }
+
+// ListObjects lists the (non-trashed) files in the folders whose name
+// starts with prefix. ObjectKey is the fileId and MD5 Drive's md5Checksum. With
+// the drive.file scope only files created by GALA are visible.
+func (c *Client) ListObjects(ctx context.Context, prefix string, fn func(ports.ObjectInfo) error) error {
+    q := "trashed = false and mimeType != 'application/vnd.google-apps.folder'"
+    if parents := c.parents(); len(parents) > 0 {
+        conds := make([]string, len(parents))
+        for i, id := range parents {
+            conds[i] = fmt.Sprintf("'%s' in parents", strings.ReplaceAll(id, "'", `\'`))
+        }
+        q += " and (" + strings.Join(conds, " or ") + ")"
+    }
+
+    call := c.srv.Files.List()
+    if c.driveID != "" {
+        call = call.Corpora("drive").DriveId(c.driveID)
+    }
+    return call.
+        Q(q).
+        PageSize(1000).
+        Fields("nextPageToken, files(id, name, size, md5Checksum, modifiedTime)").
+        SupportsAllDrives(true).
+        IncludeItemsFromAllDrives(true).
+        Pages(ctx, func(page *drive.FileList) error {
+            for _, f := range page.Files {
+                if !strings.HasPrefix(f.Name, prefix) {
+                    continue
+                }
+                mod, _ := time.Parse(time.RFC3339, f.ModifiedTime)
+                err := fn(ports.ObjectInfo{
+                    ObjectKey: f.Id,
+                    Name:      f.Name,
+                    Size:      f.Size,
+                    ModTime:   mod.UTC(),
+                    MD5:       f.Md5Checksum,
+                })
+                if err != nil {
+                    return err
+                }
+            }
+            return nil
+        })
+}
@@ -2,12 +2,15 @@ package localfs
 
 import (
     "context"
+    "errors"
     "fmt"
     "io"
+    "io/fs"
     "mime"
     "net/http"
     "os"
     "path/filepath"
+    "strings"
     "time"
 
     "gala/internal/ports"
@@ -73,6 +76,12 @@ func (l *LocalFS) GetObject(ctx context.Context, objectKey string) (rc io.ReadCl
     return f, contentType, size, nil
 }
 
+// Path returns the file that holds objectKey, for servers that send files
+// themselves (X-Sendfile).
+func (l *LocalFS) Path(objectKey string) string {
+    return filepath.Join(l.root, filepath.FromSlash(objectKey))
+}
+
 func (l *LocalFS) DeleteObject(ctx context.Context, objectKey string) error {
     p := filepath.Join(l.root, filepath.FromSlash(objectKey))
     return os.Remove(p)
@@ -82,3 +91,49 @@ func (l *LocalFS) GetSignedURL(ctx context.Context, objectKey string, expiresIn
     // v0: local provider has no real signed URLs; API currently serves /assets/{id}/content.
     return ports.SignedURLOutput{URL: "", ExpiresAt: time.Now().UTC().Add(expiresIn)}, nil
 }
+
+// ListObjects walks the files under root whose key starts with prefix.
+func (l *LocalFS) ListObjects(ctx context.Context, prefix string, fn func(ports.ObjectInfo) error) error {
+    // Walk only the directory that can contain the prefix
+    dir := l.root
+    if i := strings.LastIndex(prefix, "/"); i >= 0 {
+        dir = filepath.Join(l.root, filepath.FromSlash(prefix[:i]))
+    }
+
+    return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+        // Files removed during the walk (or a missing dir) are skipped
+        if errors.Is(err, fs.ErrNotExist) {
+            return nil
+        }
+        if err != nil {
+            return err
+        }
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+        if d.IsDir() {
+            return nil
+        }
+        rel, err := filepath.Rel(l.root, p)
+        if err != nil {
+            return err
+        }
+        key := filepath.ToSlash(rel)
+        if !strings.HasPrefix(key, prefix) {
+            return nil
+        }
+        info, err := d.Info()
+        if errors.Is(err, fs.ErrNotExist) {
+            return nil
+        }
+        if err != nil {
+            return err
+        }
+        return fn(ports.ObjectInfo{
+            ObjectKey: key,
+            Name:      key,
+            Size:      info.Size(),
+            ModTime:   info.ModTime().UTC(),
+        })
+    })
+}
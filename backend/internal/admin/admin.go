@@ -0,0 +1,521 @@
+// Package admin implements the operator actions behind the /admin API
+// routes and cmd/galactl: requeueing and canceling jobs, queue stats and
+// drain, asset deletion and garbage collection, the worker list and the
+// usage report.
+package admin
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+
+	"gala/internal/events"
+	"gala/internal/jobs"
+	"gala/internal/pkg/db"
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/ports"
+	"gala/internal/store"
+	"gala/internal/worker/queue"
+	"gala/internal/worker/registry"
+)
+
+var (
+	ErrJobNotFound   = stderrors.New("job not found")
+	ErrJobState      = stderrors.New("job state does not allow this action")
+	ErrAssetNotFound = stderrors.New("asset not found")
+	ErrAssetInUse    = stderrors.New("asset in use")
+	ErrStorage       = stderrors.New("storage delete failed")
+	// ErrQueue: the Redis job queue is unreachable.
+	ErrQueue = stderrors.New("job queue unavailable")
+	// ErrJobModified: the job changed since the updated_at the caller saw.
+	ErrJobModified = stderrors.New("job was modified")
+)
+
+// JobStateError is returned (matching ErrJobState) when the job's status
+// does not allow the action.
+type JobStateError struct {
+	Status string
+}
+
+func (e *JobStateError) Error() string { return "job is " + e.Status }
+
+func (e *JobStateError) Is(target error) bool { return target == ErrJobState }
+
+// Service runs the admin actions.
+type Service struct {
+	pool *pgxpool.Pool
+	rdb  redis.UniversalClient
+	sp   ports.StorageProvider
+	ev   *events.Bus
+
+	queueName string
+	// pushJobs is false in postgres queue mode, where workers claim QUEUED
+	// rows directly.
+	pushJobs bool
+}
+
+// New creates the service. ev receives the job and asset events (nil
+// publishes none); queueName is the Redis list the workers pop from;
+// pushJobs is false in postgres queue mode.
+func New(pool *pgxpool.Pool, rdb redis.UniversalClient, sp ports.StorageProvider, ev *events.Bus, queueName string, pushJobs bool) *Service {
+	return &Service{pool: pool, rdb: rdb, sp: sp, ev: ev, queueName: queueName, pushJobs: pushJobs}
+}
+
+// RequeueJob puts a FAILED or CANCELED job back in the queue. If the id
+// cannot be pushed, the job is set back as it was, status and error
+// included, and ErrQueue returned.
+func (s *Service) RequeueJob(ctx context.Context, id string) (store.Job, error) {
+	var (
+		job  store.Job
+		prev store.JobRun
+	)
+	err := db.WithTx(ctx, s.pool, func(tx pgx.Tx) error {
+		var err error
+		if prev, err = store.LockJobRun(ctx, tx, id); err != nil {
+			return err
+		}
+		job, err = store.RequeueJob(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return job, s.jobStateErr(ctx, id, err)
+	}
+	// A cancel request that arrived as the last run ended must not stop
+	// the next one
+	_ = queue.ClearCancel(ctx, s.rdb, id)
+	if s.pushJobs {
+		// A copy left in a list (by a cancel that could not remove it),
+		// or in the scheduled set by a cancel while SCHEDULED, would run
+		// the job on a second worker
+		pipe := s.rdb.TxPipeline()
+		for _, list := range queue.Lists(s.queueName) {
+			pipe.LRem(ctx, list, 0, id)
+		}
+		pipe.ZRem(ctx, queue.ScheduledSet(s.queueName), id)
+		pipe.LPush(ctx, queue.ListFor(s.queueName, job.Priority), id)
+		if _, err := pipe.Exec(ctx); err != nil {
+			// Not QUEUED with no worker to pop it: back to how it was,
+			// so it can be retried again
+			if _, rerr := store.RestoreJobRun(context.WithoutCancel(ctx), s.pool, prev); rerr != nil {
+				return store.Job{}, fmt.Errorf("%w: %v; job left QUEUED: %v", ErrQueue, err, rerr)
+			}
+			return store.Job{}, fmt.Errorf("%w: %v", ErrQueue, err)
+		}
+	}
+	s.ev.Publish(ctx, events.JobRequeued, id, map[string]any{"status": job.Status})
+	return job, nil
+}
+
+// CancelJob cancels a QUEUED or SCHEDULED job at once. A RUNNING job is
+// asked to cancel instead: its worker stops it between stages, or aborts
+// the render, and sets it CANCELED; the job is returned still RUNNING.
+func (s *Service) CancelJob(ctx context.Context, id string) (store.Job, error) {
+	job, err := store.CancelQueuedJob(ctx, s.pool, id)
+	if err == nil {
+		if s.pushJobs {
+			// Best effort: a copy left behind is skipped by the worker, and
+			// RequeueJob removes it before pushing the id again. The
+			// scheduler drops the id of a canceled scheduled job.
+			pipe := s.rdb.Pipeline()
+			for _, list := range queue.Lists(s.queueName) {
+				pipe.LRem(ctx, list, 0, id)
+			}
+			_, _ = pipe.Exec(ctx)
+		}
+		s.ev.Publish(ctx, events.JobCanceled, id, map[string]any{"status": job.Status})
+		return job, nil
+	}
+	if !pgerr.IsNoRows(err) {
+		return job, err
+	}
+	job, err = store.GetJob(ctx, s.pool, id)
+	if pgerr.IsNoRows(err) {
+		return job, ErrJobNotFound
+	}
+	if err != nil {
+		return job, err
+	}
+	if job.Status != store.JobRunning {
+		return job, &JobStateError{Status: job.Status}
+	}
+	if err := queue.RequestCancel(ctx, s.rdb, id); err != nil {
+		return store.Job{}, fmt.Errorf("%w: %v", ErrQueue, err)
+	}
+	s.ev.Publish(ctx, events.JobCancelRequested, id, map[string]any{"status": job.Status})
+	return job, nil
+}
+
+// EditJobParams replaces the params of a QUEUED job, keeping its place in
+// the queue. updatedAt is the job's updated_at as the caller last saw it:
+// if the job changed since, it returns ErrJobModified. The params are
+// checked as on creation: params.text for a legacy job, the params_schema
+// of a strict template. The worker runs the job with the params it finds
+// when it marks it RUNNING, so an edit racing the worker still applies or
+// fails with a state error.
+func (s *Service) EditJobParams(ctx context.Context, id string, params map[string]any, updatedAt time.Time) (store.Job, error) {
+	job, err := store.GetJob(ctx, s.pool, id)
+	if err != nil {
+		return store.Job{}, s.jobStateErr(ctx, id, err)
+	}
+	if job.Status != store.JobQueued {
+		return store.Job{}, &JobStateError{Status: job.Status}
+	}
+	if !job.UpdatedAt.Equal(updatedAt) {
+		return store.Job{}, ErrJobModified
+	}
+
+	spec := jobs.ParseSpec(job.ParamsJSON)
+	spec.Params = params
+	if spec.TemplateID == "" {
+		if _, ok := params["text"]; !ok {
+			return store.Job{}, errors.ValidationField("params.text", "params.text is required")
+		}
+	} else {
+		t, err := store.GetTemplate(ctx, s.pool, spec.TemplateID)
+		if err != nil && !pgerr.IsNoRows(err) {
+			return store.Job{}, err
+		}
+		// A deleted template fails the job anyway; nothing to check against
+		if err == nil {
+			if err := jobs.CheckParams(t, params); err != nil {
+				return store.Job{}, err
+			}
+		}
+	}
+	paramsJSON, paramsHash, err := jobs.Encode(spec)
+	if err != nil {
+		return store.Job{}, err
+	}
+
+	job, err = store.UpdateQueuedJobParams(ctx, s.pool, id, string(paramsJSON), paramsHash, updatedAt)
+	if pgerr.IsNoRows(err) {
+		// Started, canceled or edited since the read above
+		err = s.jobStateErr(ctx, id, err)
+		var se *JobStateError
+		if errors.As(err, &se) && se.Status == store.JobQueued {
+			err = ErrJobModified
+		}
+	}
+	if err != nil {
+		return store.Job{}, err
+	}
+	s.ev.Publish(ctx, events.JobEdited, id, map[string]any{"status": job.Status})
+	return job, nil
+}
+
+// jobStateErr explains why a conditional job update matched no row.
+func (s *Service) jobStateErr(ctx context.Context, id string, err error) error {
+	if !pgerr.IsNoRows(err) {
+		return err
+	}
+	job, err := store.GetJob(ctx, s.pool, id)
+	if pgerr.IsNoRows(err) {
+		return ErrJobNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return &JobStateError{Status: job.Status}
+}
+
+// QueueStats describes the job queue.
+type QueueStats struct {
+	Mode string `json:"mode"`
+	// Pending is the length of the Redis list (redis mode only).
+	Pending *int64 `json:"pending,omitempty"`
+	// PendingError says why Pending is missing in redis mode.
+	PendingError string `json:"pending_error,omitempty"`
+	// Jobs counts jobs by status.
+	Jobs           map[string]int64 `json:"jobs"`
+	OldestQueuedAt *time.Time       `json:"oldest_queued_at,omitempty"`
+	// PausedAt is set while the workers take no new jobs (soft drain).
+	PausedAt *time.Time `json:"paused_at,omitempty"`
+}
+
+// QueueStats returns the job counts and, in redis mode, the list length.
+// With Redis down the counts are still returned, without Pending.
+func (s *Service) QueueStats(ctx context.Context) (QueueStats, error) {
+	st := QueueStats{Mode: "postgres"}
+	var err error
+	if st.Jobs, err = store.CountJobsByStatus(ctx, s.pool, store.JobFilter{}); err != nil {
+		return st, err
+	}
+	if st.OldestQueuedAt, err = store.OldestQueuedJob(ctx, s.pool); err != nil {
+		return st, err
+	}
+	if since, err := queue.PausedSince(ctx, s.rdb); err == nil && !since.IsZero() {
+		st.PausedAt = &since
+	}
+	if s.pushJobs {
+		st.Mode = "redis"
+		var pending int64
+		for _, list := range queue.Lists(s.queueName) {
+			n, err := s.rdb.LLen(ctx, list).Result()
+			if err != nil {
+				st.PendingError = "queue length unavailable: " + err.Error()
+				return st, nil
+			}
+			pending += n
+		}
+		st.Pending = &pending
+	}
+	return st, nil
+}
+
+// DrainQueue cancels every QUEUED job and empties the Redis lists, and
+// returns how many jobs it canceled. Running jobs are not affected, nor
+// SCHEDULED ones: they are queued at their run_at as usual.
+func (s *Service) DrainQueue(ctx context.Context) (int64, error) {
+	// The list goes first: ids pushed after it was emptied belong either to
+	// jobs canceled below (workers skip them) or to jobs created afterwards.
+	if s.pushJobs {
+		if err := s.rdb.Del(ctx, queue.Lists(s.queueName)...).Err(); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrQueue, err)
+		}
+	}
+	return store.CancelQueuedJobs(ctx, s.pool)
+}
+
+// drainRunningLimit caps the running job ids a DrainStatus lists.
+const drainRunningLimit = 100
+
+// DrainStatus is the state of a soft drain.
+type DrainStatus struct {
+	PausedAt time.Time `json:"paused_at"`
+	// Drained is true once no job is RUNNING and no worker holds one
+	// (a job popped but not yet RUNNING, or waiting for its template's
+	// slot).
+	Drained bool `json:"drained"`
+	// Running are the ids of the jobs still in flight (the 100 newest
+	// RUNNING ones if there are more, oldest first), then those held by a
+	// worker that are not RUNNING yet.
+	Running []string `json:"running"`
+	// Queued jobs stay queued until the queue is resumed.
+	Queued int64 `json:"queued"`
+}
+
+// SoftDrain pauses the queue, so workers take no new jobs and finish the
+// ones they have, and waits up to wait (checking every poll) for no job to
+// be in flight. It returns the state when that happens or when wait or ctx
+// end; calling it again keeps waiting on the same drain.
+func (s *Service) SoftDrain(ctx context.Context, wait, poll time.Duration) (DrainStatus, error) {
+	if err := queue.Pause(ctx, s.rdb); err != nil {
+		return DrainStatus{}, fmt.Errorf("%w: %v", ErrQueue, err)
+	}
+	since, err := queue.PausedSince(ctx, s.rdb)
+	if err != nil {
+		return DrainStatus{}, fmt.Errorf("%w: %v", ErrQueue, err)
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		st, err := s.drainStatus(ctx)
+		if err != nil {
+			return st, err
+		}
+		st.PausedAt = since
+		if st.Drained || time.Until(deadline) <= 0 {
+			return st, nil
+		}
+		select {
+		case <-ctx.Done():
+			return st, nil
+		case <-time.After(min(poll, time.Until(deadline))):
+		}
+	}
+}
+
+func (s *Service) drainStatus(ctx context.Context) (DrainStatus, error) {
+	counts, err := store.CountJobsByStatus(ctx, s.pool, store.JobFilter{})
+	if err != nil {
+		return DrainStatus{}, err
+	}
+	// Workers publish the job they took before checking the pause again,
+	// so a job popped as the queue was paused shows here until it runs or
+	// goes back to the queue
+	workers, err := registry.List(ctx, s.rdb)
+	if err != nil {
+		return DrainStatus{}, fmt.Errorf("%w: %v", ErrQueue, err)
+	}
+	st := DrainStatus{Queued: counts[store.JobQueued], Running: []string{}}
+	if counts[store.JobRunning] > 0 {
+		running, err := store.ListJobs(ctx, s.pool, store.JobFilter{Status: store.JobRunning}, nil, drainRunningLimit)
+		if err != nil {
+			return DrainStatus{}, err
+		}
+		// ListJobs is newest first
+		for i := len(running) - 1; i >= 0; i-- {
+			st.Running = append(st.Running, running[i].ID)
+		}
+	}
+	for _, w := range workers {
+		if w.CurrentJob != "" && !slices.Contains(st.Running, w.CurrentJob) {
+			st.Running = append(st.Running, w.CurrentJob)
+		}
+	}
+	st.Drained = counts[store.JobRunning] == 0 && len(st.Running) == 0
+	return st, nil
+}
+
+// ResumeQueue ends a soft drain: the workers take jobs again.
+func (s *Service) ResumeQueue(ctx context.Context) error {
+	if err := queue.Resume(ctx, s.rdb); err != nil {
+		return fmt.Errorf("%w: %v", ErrQueue, err)
+	}
+	return nil
+}
+
+// DeleteAsset deletes an asset and its storage object unless a job output
+// references it. The row is locked and deleted first; the storage object
+// goes last and the transaction only commits if that worked, so a failed
+// storage delete keeps the asset instead of leaving a row without its
+// object. The lock also holds off job outputs that would reference it.
+func (s *Service) DeleteAsset(ctx context.Context, id string) (store.Asset, error) {
+	var asset store.Asset
+	err := db.WithTx(ctx, s.pool, func(tx pgx.Tx) error {
+		a, err := store.LockAsset(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		asset = a
+
+		refs, err := store.CountAssetRefs(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if refs > 0 {
+			return ErrAssetInUse
+		}
+
+		if err := store.DeleteAsset(ctx, tx, id); err != nil {
+			return err
+		}
+
+		if err := s.sp.DeleteObject(ctx, a.ObjectKey); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %v", ErrStorage, err)
+		}
+		return nil
+	})
+	switch {
+	case pgerr.IsNoRows(err):
+		return asset, ErrAssetNotFound
+	case pgerr.IsForeignKeyViolation(err):
+		return asset, ErrAssetInUse
+	case err == nil:
+		s.ev.Publish(ctx, events.AssetDeleted, id, map[string]any{"kind": asset.Kind})
+	}
+	return asset, err
+}
+
+// GCResult is the outcome of GCAssets.
+type GCResult struct {
+	// Assets are the unreferenced assets found.
+	Assets []store.Asset
+	// Deleted counts the ones deleted (0 on a dry run).
+	Deleted int
+	// Failed maps asset ids that could not be deleted to the reason.
+	Failed map[string]string
+}
+
+// GCAssets finds up to limit assets older than olderThan that nothing
+// references (see store.UnreferencedAssets) and, if apply is set, deletes
+// them with their storage objects.
+func (s *Service) GCAssets(ctx context.Context, olderThan time.Duration, limit int, apply bool) (GCResult, error) {
+	res := GCResult{Failed: map[string]string{}}
+	assets, err := store.UnreferencedAssets(ctx, s.pool, time.Now().Add(-olderThan), limit)
+	if err != nil {
+		return res, err
+	}
+	res.Assets = assets
+	if !apply {
+		return res, nil
+	}
+
+	for _, a := range assets {
+		if _, err := s.DeleteAsset(ctx, a.ID); err != nil {
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+			res.Failed[a.ID] = err.Error()
+			continue
+		}
+		res.Deleted++
+	}
+	return res, nil
+}
+
+// Workers lists the live workers.
+func (s *Service) Workers(ctx context.Context) ([]registry.Worker, error) {
+	return registry.List(ctx, s.rdb)
+}
+
+// UsageReport is the usage of the platform in a calendar month (UTC), for
+// internal chargeback.
+type UsageReport struct {
+	// Month is the YYYY-MM of the report.
+	Month string    `json:"month"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	// StorageBytes is the size of the stored assets created before To.
+	StorageBytes int64         `json:"storage_bytes"`
+	Totals       UsageTotals   `json:"totals"`
+	Days         []UsageTotals `json:"days"`
+}
+
+// UsageTotals is the usage of a day or of the whole report.
+type UsageTotals struct {
+	// Date is set on days only.
+	Date              string  `json:"date,omitempty"`
+	Renders           int64   `json:"renders"`
+	FailedRenders     int64   `json:"failed_renders"`
+	RenderMinutes     float64 `json:"render_minutes"`
+	StorageBytesAdded int64   `json:"storage_bytes_added"`
+	Publications      int64   `json:"publications"`
+}
+
+// UsageReport aggregates renders, render time, storage and publications
+// of month (any time within it) by UTC day.
+func (s *Service) UsageReport(ctx context.Context, month time.Time) (UsageReport, error) {
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	rep := UsageReport{Month: from.Format("2006-01"), From: from, To: from.AddDate(0, 1, 0)}
+
+	days, err := store.UsageByDay(ctx, s.pool, rep.From, rep.To)
+	if err != nil {
+		return rep, err
+	}
+	var secs float64
+	rep.Days = make([]UsageTotals, 0, len(days))
+	for _, d := range days {
+		rep.Days = append(rep.Days, UsageTotals{
+			Date:              d.Date.Format(time.DateOnly),
+			Renders:           d.Renders,
+			FailedRenders:     d.FailedRenders,
+			RenderMinutes:     minutes(d.RenderSeconds),
+			StorageBytesAdded: d.StorageBytesAdded,
+			Publications:      d.Publications,
+		})
+		secs += d.RenderSeconds
+		rep.Totals.Renders += d.Renders
+		rep.Totals.FailedRenders += d.FailedRenders
+		rep.Totals.StorageBytesAdded += d.StorageBytesAdded
+		rep.Totals.Publications += d.Publications
+	}
+	rep.Totals.RenderMinutes = minutes(secs)
+
+	rep.StorageBytes, err = store.StoredBytes(ctx, s.pool, rep.To)
+	return rep, err
+}
+
+// minutes converts seconds to minutes rounded to hundredths.
+func minutes(secs float64) float64 {
+	return math.Round(secs/60*100) / 100
+}
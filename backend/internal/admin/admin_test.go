@@ -0,0 +1,202 @@
+package admin_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"gala/internal/admin"
+	"gala/internal/store"
+	"gala/internal/testinfra"
+	"gala/internal/worker/queue"
+	"gala/internal/worker/registry"
+)
+
+func TestMain(m *testing.M) { testinfra.Main(m) }
+
+func insertJob(t *testing.T, env *testinfra.Env, id string) {
+	t.Helper()
+	err := store.InsertJob(context.Background(), env.Pool, store.Job{
+		ID:         id,
+		ParamsJSON: `{"text":"hola"}`,
+		CreatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// copies counts the copies of id in the lists of the default queue.
+func copies(t *testing.T, env *testinfra.Env, id string) int {
+	t.Helper()
+	n := 0
+	for _, list := range queue.Lists(queue.DefaultName) {
+		ids, err := env.RDB.LRange(context.Background(), list, 0, -1).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, got := range ids {
+			if got == id {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestCancelThenRequeueLeavesOneCopy(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	s := admin.New(env.Pool, env.RDB, env.SP, env.Events, queue.DefaultName, true)
+	insertJob(t, env, "job_1")
+	if err := env.RDB.LPush(ctx, queue.DefaultName, "job_1").Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.CancelJob(ctx, "job_1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := copies(t, env, "job_1"); n != 0 {
+		t.Errorf("after cancel the lists hold %d copies, want 0", n)
+	}
+
+	// A copy the cancel could not remove
+	if err := env.RDB.LPush(ctx, queue.DefaultName, "job_1").Err(); err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.RequeueJob(ctx, "job_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != store.JobQueued {
+		t.Errorf("requeued job is %s, want QUEUED", job.Status)
+	}
+	if n := copies(t, env, "job_1"); n != 1 {
+		t.Errorf("after requeue the lists hold %d copies, want 1", n)
+	}
+}
+
+// A job canceled while SCHEDULED runs now when requeued, from its list
+// only: its id is gone from the scheduled set.
+func TestRequeueScheduledJobRunsNow(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	s := admin.New(env.Pool, env.RDB, env.SP, env.Events, queue.DefaultName, true)
+	runAt := time.Now().UTC().Add(time.Hour)
+	err := store.InsertJob(ctx, env.Pool, store.Job{
+		ID:         "job_1",
+		Status:     store.JobScheduled,
+		ParamsJSON: `{"text":"hola"}`,
+		RunAt:      &runAt,
+		CreatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.RDB.ZAdd(ctx, queue.ScheduledSet(queue.DefaultName), queue.Scheduled("job_1", runAt)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CancelJob(ctx, "job_1"); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := s.RequeueJob(ctx, "job_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != store.JobQueued || job.RunAt != nil {
+		t.Errorf("requeued job is %s with run_at %v, want QUEUED without run_at", job.Status, job.RunAt)
+	}
+	if n := copies(t, env, "job_1"); n != 1 {
+		t.Errorf("after requeue the lists hold %d copies, want 1", n)
+	}
+	if n, _ := env.RDB.ZCard(ctx, queue.ScheduledSet(queue.DefaultName)).Result(); n != 0 {
+		t.Errorf("scheduled set holds %d ids after requeue, want none", n)
+	}
+}
+
+// A soft drain waits for the running jobs and leaves the queued ones.
+func TestSoftDrain(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	s := admin.New(env.Pool, env.RDB, env.SP, env.Events, queue.DefaultName, true)
+	insertJob(t, env, "job_1")
+	insertJob(t, env, "job_2")
+	if _, _, err := store.MarkJobRunning(ctx, env.Pool, "job_2", false); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := s.SoftDrain(ctx, 0, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Drained || len(st.Running) != 1 || st.Running[0] != "job_2" || st.Queued != 1 || st.PausedAt.IsZero() {
+		t.Fatalf("drain without waiting = %+v, want job_2 running and job_1 queued", st)
+	}
+
+	// job_2 finishes while the drain waits
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = store.MarkJobFailed(ctx, env.Pool, "job_2", "boom", store.JobError{Code: "INTERNAL_ERROR", Message: "boom"})
+	}()
+	again, err := s.SoftDrain(ctx, 10*time.Second, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.Drained || len(again.Running) != 0 || again.Queued != 1 {
+		t.Errorf("drain = %+v, want drained with job_1 still queued", again)
+	}
+	if !again.PausedAt.Equal(st.PausedAt) {
+		t.Errorf("paused_at = %v, want the first drain's %v", again.PausedAt, st.PausedAt)
+	}
+
+	if err := s.ResumeQueue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if since, _ := queue.PausedSince(ctx, env.RDB); !since.IsZero() {
+		t.Errorf("queue still paused since %v after ResumeQueue", since)
+	}
+}
+
+func TestSoftDrainQueueDown(t *testing.T) {
+	env := testinfra.New(t)
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	t.Cleanup(func() { _ = rdb.Close() })
+	s := admin.New(env.Pool, rdb, env.SP, env.Events, queue.DefaultName, true)
+
+	if _, err := s.SoftDrain(context.Background(), time.Second, time.Millisecond); !errors.Is(err, admin.ErrQueue) {
+		t.Errorf("SoftDrain = %v, want ErrQueue", err)
+	}
+	if err := s.ResumeQueue(context.Background()); !errors.Is(err, admin.ErrQueue) {
+		t.Errorf("ResumeQueue = %v, want ErrQueue", err)
+	}
+}
+
+// A job a worker took but has not set RUNNING keeps the drain open.
+func TestSoftDrainWaitsForHeldJobs(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	s := admin.New(env.Pool, env.RDB, env.SP, env.Events, queue.DefaultName, true)
+	insertJob(t, env, "job_1")
+
+	hb := registry.NewHeartbeat(env.RDB, env.Log, registry.Worker{ID: "w1"}, time.Minute)
+	hb.SetJob(ctx, "job_1")
+	st, err := s.SoftDrain(ctx, 0, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Drained || len(st.Running) != 1 || st.Running[0] != "job_1" {
+		t.Errorf("drain with job_1 held by a worker = %+v, want job_1 in flight", st)
+	}
+
+	hb.SetJob(ctx, "")
+	if st, err = s.SoftDrain(ctx, 0, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if !st.Drained || len(st.Running) != 0 {
+		t.Errorf("drain once the worker let go = %+v, want drained", st)
+	}
+}
@@ -0,0 +1,285 @@
+package admin
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"gala/internal/events"
+	"gala/internal/pkg/db"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/ports"
+	"gala/internal/store"
+)
+
+// ErrAuditUnsupported is returned by AuditStorage when the storage
+// provider cannot list its objects.
+var ErrAuditUnsupported = errors.New("storage provider cannot list objects")
+
+// Storage audit issue kinds.
+const (
+	// IssueMissing: the asset row exists but its object does not.
+	IssueMissing = "missing_object"
+	// IssueOrphan: the object exists but no asset row points at it.
+	IssueOrphan = "orphan_object"
+	// IssueSize: the object's size differs from the row's size_bytes.
+	IssueSize = "size_mismatch"
+	// IssueChecksum: the object's MD5 differs from the row's checksum.
+	IssueChecksum = "checksum_mismatch"
+)
+
+// Storage audit repair actions. Checksum mismatches have none: there is
+// no telling which side is right.
+const (
+	// RepairDeleteMissing deletes the rows of missing objects that no job
+	// output references.
+	RepairDeleteMissing = "delete-missing"
+	// RepairDeleteOrphans deletes orphan objects.
+	RepairDeleteOrphans = "delete-orphans"
+	// RepairFixSizes sets size_bytes to the object's size.
+	RepairFixSizes = "fix-sizes"
+)
+
+// RepairActions lists the valid AuditOptions.Repair values.
+var RepairActions = []string{RepairDeleteMissing, RepairDeleteOrphans, RepairFixSizes}
+
+// DefaultAuditPrefixes are the object names GALA writes (uploads and
+// render outputs); the rest of the storage root is staging.
+var DefaultAuditPrefixes = []string{"assets/", "renders/"}
+
+// AuditOptions configures AuditStorage.
+type AuditOptions struct {
+	// Prefixes limit which objects can be reported as orphans
+	// (default DefaultAuditPrefixes).
+	Prefixes []string
+	// MinAge skips orphans modified more recently, which may be uploads
+	// whose row is not committed yet.
+	MinAge time.Duration
+	// Checksums reads objects whose provider does not report an MD5
+	// (localfs) to compare them with the row's checksum.
+	Checksums bool
+	// Repair lists the repair actions to apply (see RepairActions).
+	Repair []string
+}
+
+// AuditIssue is an inconsistency between the assets table and storage.
+type AuditIssue struct {
+	Kind      string `json:"kind"`
+	AssetID   string `json:"asset_id,omitempty"`
+	ObjectKey string `json:"object_key"`
+	// Name is the object's name in storage (orphans only).
+	Name            string `json:"name,omitempty"`
+	DBSize          int64  `json:"db_size,omitempty"`
+	StorageSize     int64  `json:"storage_size,omitempty"`
+	DBChecksum      string `json:"db_checksum,omitempty"`
+	StorageChecksum string `json:"storage_checksum,omitempty"`
+	// Repair is the action that fixes the issue, if any; Repaired tells
+	// whether it was applied and Error why it failed.
+	Repair   string `json:"repair,omitempty"`
+	Repaired bool   `json:"repaired"`
+	Error    string `json:"error,omitempty"`
+}
+
+// AuditReport is the outcome of AuditStorage.
+type AuditReport struct {
+	Provider  string        `json:"provider"`
+	Prefixes  []string      `json:"prefixes"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration_ns"`
+	Assets    int           `json:"assets"`
+	Objects   int           `json:"objects"`
+	// Counts has the number of issues of each kind.
+	Counts map[string]int `json:"counts"`
+	Issues []AuditIssue   `json:"issues"`
+}
+
+// Unresolved counts the issues left unrepaired.
+func (r *AuditReport) Unresolved() int {
+	n := 0
+	for _, i := range r.Issues {
+		if !i.Repaired {
+			n++
+		}
+	}
+	return n
+}
+
+// AuditStorage cross-checks the assets table against the objects the
+// storage provider lists: rows whose object is missing, objects no row
+// points at, and size or checksum mismatches. Repair actions in opts are
+// applied as issues are found. Assets created after the listing started
+// are skipped, so the audit can run while jobs are uploading.
+func (s *Service) AuditStorage(ctx context.Context, opts AuditOptions) (*AuditReport, error) {
+	lister, ok := s.sp.(ports.ObjectLister)
+	if !ok {
+		return nil, ErrAuditUnsupported
+	}
+	prefixes := opts.Prefixes
+	if len(prefixes) == 0 {
+		prefixes = DefaultAuditPrefixes
+	}
+	repair := map[string]bool{}
+	for _, a := range opts.Repair {
+		repair[a] = true
+	}
+
+	rep := &AuditReport{
+		Provider:  s.sp.Provider(),
+		Prefixes:  prefixes,
+		StartedAt: time.Now().UTC(),
+		Counts:    map[string]int{},
+		Issues:    []AuditIssue{},
+	}
+	defer func() { rep.Duration = time.Since(rep.StartedAt) }()
+
+	// Every object is listed so that missing objects are never reported
+	// for keys outside the prefixes
+	objects := map[string]ports.ObjectInfo{}
+	err := lister.ListObjects(ctx, "", func(o ports.ObjectInfo) error {
+		objects[o.ObjectKey] = o
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list objects: %w", err)
+	}
+	rep.Objects = len(objects)
+
+	assets, err := store.StreamAssets(ctx, s.pool, store.AssetFilter{Provider: rep.Provider})
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var found []AuditIssue
+	for a, err := range assets {
+		if err != nil {
+			return nil, err
+		}
+		seen[a.ObjectKey] = true
+		if a.CreatedAt.After(rep.StartedAt) {
+			continue
+		}
+		rep.Assets++
+
+		o, ok := objects[a.ObjectKey]
+		if !ok {
+			found = append(found, AuditIssue{Kind: IssueMissing, AssetID: a.ID, ObjectKey: a.ObjectKey, DBSize: a.SizeBytes, Repair: RepairDeleteMissing})
+			continue
+		}
+		if o.Size != a.SizeBytes {
+			found = append(found, AuditIssue{Kind: IssueSize, AssetID: a.ID, ObjectKey: a.ObjectKey, DBSize: a.SizeBytes, StorageSize: o.Size, Repair: RepairFixSizes})
+		}
+		if a.Checksum == "" {
+			continue
+		}
+		sum := o.MD5
+		if sum == "" && opts.Checksums {
+			if sum, err = s.objectMD5(ctx, a.ObjectKey); err != nil {
+				return nil, err
+			}
+		}
+		if sum != "" && !strings.EqualFold(sum, a.Checksum) {
+			found = append(found, AuditIssue{Kind: IssueChecksum, AssetID: a.ID, ObjectKey: a.ObjectKey, DBChecksum: a.Checksum, StorageChecksum: sum})
+		}
+	}
+
+	minModTime := rep.StartedAt.Add(-opts.MinAge)
+	var orphans []AuditIssue
+	for key, o := range objects {
+		if seen[key] || o.ModTime.After(minModTime) || !hasAnyPrefix(o.Name, prefixes) {
+			continue
+		}
+		orphans = append(orphans, AuditIssue{Kind: IssueOrphan, ObjectKey: key, Name: o.Name, StorageSize: o.Size, StorageChecksum: o.MD5, Repair: RepairDeleteOrphans})
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].Name < orphans[j].Name })
+
+	for _, issue := range append(found, orphans...) {
+		if repair[issue.Repair] {
+			if err := s.repairIssue(ctx, issue); err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				issue.Error = err.Error()
+			} else {
+				issue.Repaired = true
+			}
+		}
+		rep.Counts[issue.Kind]++
+		rep.Issues = append(rep.Issues, issue)
+	}
+	return rep, nil
+}
+
+func (s *Service) repairIssue(ctx context.Context, issue AuditIssue) error {
+	switch issue.Kind {
+	case IssueMissing:
+		return s.deleteAssetRow(ctx, issue.AssetID)
+	case IssueOrphan:
+		if err := s.sp.DeleteObject(ctx, issue.ObjectKey); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	case IssueSize:
+		return store.SetAssetSize(ctx, s.pool, issue.AssetID, issue.StorageSize)
+	}
+	return nil
+}
+
+// deleteAssetRow deletes an asset row (not its object) unless a job
+// output references it.
+func (s *Service) deleteAssetRow(ctx context.Context, id string) error {
+	err := db.WithTx(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := store.LockAsset(ctx, tx, id); err != nil {
+			return err
+		}
+		refs, err := store.CountAssetRefs(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if refs > 0 {
+			return ErrAssetInUse
+		}
+		return store.DeleteAsset(ctx, tx, id)
+	})
+	switch {
+	case pgerr.IsNoRows(err):
+		// Deleted since it was read
+		return nil
+	case pgerr.IsForeignKeyViolation(err):
+		return ErrAssetInUse
+	case err == nil:
+		s.ev.Publish(ctx, events.AssetDeleted, id, nil)
+	}
+	return err
+}
+
+// objectMD5 reads an object to compute its hex MD5.
+func (s *Service) objectMD5(ctx context.Context, objectKey string) (string, error) {
+	rc, _, _, err := s.sp.GetObject(ctx, objectKey)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", objectKey, err)
+	}
+	defer rc.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", fmt.Errorf("read %s: %w", objectKey, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package admin
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gala/internal/events"
+	"gala/internal/pkg/logger"
+	"gala/internal/storage"
+	"gala/internal/store"
+)
+
+// VerifyOptions configures VerifyOutputs.
+type VerifyOptions struct {
+	// Sample is how many output assets a run checks.
+	Sample int
+	// Window limits the sample to outputs of jobs finished within it.
+	Window time.Duration
+	// Checksums reads each object to compare its MD5 with the row's
+	// checksum; otherwise the object is only opened, which checks that it
+	// exists and its size.
+	Checksums bool
+}
+
+// VerifyReport is the outcome of VerifyOutputs.
+type VerifyReport struct {
+	Checked int `json:"checked"`
+	// Failed are the assets with an issue (same kinds as AuditStorage).
+	Failed []AuditIssue `json:"failed"`
+	// Skipped could not be checked: storage errors other than a missing
+	// object, which are not held against the asset.
+	Skipped int `json:"skipped"`
+}
+
+// VerifyOutputs checks a sample of recent job outputs against storage,
+// so missing or corrupted objects are found before users hit dead links:
+// the object must exist and match the row's size and, with
+// opts.Checksums, its checksum. Each outcome is recorded in
+// asset_integrity, and a failure is logged and published as
+// asset.integrity_failed. Assets checked longest ago go first.
+func (s *Service) VerifyOutputs(ctx context.Context, log *logger.Logger, opts VerifyOptions) (*VerifyReport, error) {
+	assets, err := store.SampleOutputAssets(ctx, s.pool, s.sp.Provider(), time.Now().Add(-opts.Window), opts.Sample)
+	if err != nil {
+		return nil, fmt.Errorf("sample outputs: %w", err)
+	}
+
+	rep := &VerifyReport{Failed: []AuditIssue{}}
+	for _, a := range assets {
+		issue, err := s.verifyAsset(ctx, a, opts.Checksums)
+		if err != nil {
+			if ctx.Err() != nil {
+				return rep, ctx.Err()
+			}
+			rep.Skipped++
+			log.Warn("could not verify output", "asset_id", a.ID, "object_key", a.ObjectKey, "error", err.Error())
+			continue
+		}
+		rep.Checked++
+
+		msg := ""
+		if issue != nil {
+			msg = issueMessage(*issue)
+			rep.Failed = append(rep.Failed, *issue)
+			log.Error("output failed integrity check",
+				"asset_id", a.ID, "object_key", a.ObjectKey, "kind", issue.Kind, "error", msg)
+			s.ev.Publish(ctx, events.AssetIntegrityFailed, a.ID, map[string]any{
+				"kind": a.Kind, "object_key": a.ObjectKey, "issue": issue.Kind, "error": msg,
+			})
+		}
+		if err := store.SetAssetIntegrity(ctx, s.pool, a.ID, msg); err != nil {
+			return rep, fmt.Errorf("record integrity of %s: %w", a.ID, err)
+		}
+	}
+	return rep, nil
+}
+
+// verifyAsset returns the issue of a, or nil if its object is intact. An
+// error means the object could not be checked.
+func (s *Service) verifyAsset(ctx context.Context, a store.Asset, checksums bool) (*AuditIssue, error) {
+	rc, _, size, err := s.sp.GetObject(ctx, a.ObjectKey)
+	if storage.IsNotFound(err) {
+		return &AuditIssue{Kind: IssueMissing, AssetID: a.ID, ObjectKey: a.ObjectKey, DBSize: a.SizeBytes}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// Providers report a negative size when they do not know it
+	known := size >= 0
+	sum := ""
+	if checksums && a.Checksum != "" {
+		h := md5.New()
+		n, err := io.Copy(h, rc)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", a.ObjectKey, err)
+		}
+		size, known, sum = n, true, hex.EncodeToString(h.Sum(nil))
+	}
+	if known && size != a.SizeBytes {
+		return &AuditIssue{Kind: IssueSize, AssetID: a.ID, ObjectKey: a.ObjectKey, DBSize: a.SizeBytes, StorageSize: size}, nil
+	}
+	if sum != "" && !strings.EqualFold(sum, a.Checksum) {
+		return &AuditIssue{Kind: IssueChecksum, AssetID: a.ID, ObjectKey: a.ObjectKey, DBChecksum: a.Checksum, StorageChecksum: sum}, nil
+	}
+	return nil, nil
+}
+
+// issueMessage describes issue for asset_integrity.error and the logs.
+func issueMessage(issue AuditIssue) string {
+	switch issue.Kind {
+	case IssueMissing:
+		return "object not found in storage"
+	case IssueSize:
+		return fmt.Sprintf("object is %d bytes, asset records %d", issue.StorageSize, issue.DBSize)
+	case IssueChecksum:
+		return fmt.Sprintf("object MD5 is %s, asset records %s", issue.StorageChecksum, issue.DBChecksum)
+	}
+	return issue.Kind
+}
@@ -0,0 +1,120 @@
+package admin_test
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gala/internal/admin"
+	"gala/internal/ports"
+	"gala/internal/store"
+	"gala/internal/testinfra"
+	"gala/internal/testsupport"
+	"gala/internal/worker/queue"
+)
+
+// putAsset stores data under key in sp and records an asset of it with
+// size and the MD5 of sum.
+func putAsset(t *testing.T, env *testinfra.Env, sp *testsupport.Storage, id, data string, size int64, sum string) {
+	t.Helper()
+	ctx := context.Background()
+	key := "outputs/" + id
+	if data != "" {
+		if _, err := sp.PutObject(ctx, ports.PutObjectInput{ObjectKey: key, Reader: strings.NewReader(data)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := md5.Sum([]byte(sum))
+	err := store.InsertAsset(ctx, env.Pool, store.Asset{
+		ID:        id,
+		Kind:      "video",
+		Provider:  sp.Provider(),
+		ObjectKey: key,
+		Mime:      "video/mp4",
+		SizeBytes: size,
+		Checksum:  hex.EncodeToString(h[:]),
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyOutputs(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	sp := testsupport.NewStorage()
+	sp.Fail = testsupport.FailFirst(-1, testsupport.OpGet, "outputs/a_flaky")
+	s := admin.New(env.Pool, env.RDB, sp, env.Events, queue.DefaultName, true)
+
+	putAsset(t, env, sp, "a_ok", "video", 5, "video")
+	putAsset(t, env, sp, "a_size", "video", 9, "video")
+	putAsset(t, env, sp, "a_sum", "video", 5, "other")
+	putAsset(t, env, sp, "a_missing", "", 5, "video")
+	putAsset(t, env, sp, "a_flaky", "video", 5, "video")
+	insertJob(t, env, "job_1")
+	insertJob(t, env, "job_2")
+	for _, o := range []store.JobOutput{
+		{ID: "out_1", JobID: "job_1", VideoAssetID: "a_ok", ThumbnailAssetID: "a_size", CaptionsAssetID: "a_sum"},
+		{ID: "out_2", JobID: "job_2", VideoAssetID: "a_missing", ThumbnailAssetID: "a_flaky"},
+	} {
+		if err := store.InsertJobOutput(ctx, env.Pool, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rep, err := s.VerifyOutputs(ctx, env.Log, admin.VerifyOptions{Sample: 10, Window: time.Hour, Checksums: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Checked != 4 || rep.Skipped != 1 {
+		t.Errorf("checked %d and skipped %d, want 4 and 1", rep.Checked, rep.Skipped)
+	}
+	got := map[string]string{}
+	for _, issue := range rep.Failed {
+		got[issue.AssetID] = issue.Kind
+	}
+	want := map[string]string{"a_size": admin.IssueSize, "a_sum": admin.IssueChecksum, "a_missing": admin.IssueMissing}
+	if len(got) != len(want) {
+		t.Errorf("failed = %v, want %v", got, want)
+	}
+	for id, kind := range want {
+		if got[id] != kind {
+			t.Errorf("%s: issue %q, want %q", id, got[id], kind)
+		}
+	}
+
+	// Every checked asset has its outcome recorded; the skipped one does not
+	rows, err := env.Pool.Query(ctx, `SELECT asset_id, error IS NOT NULL FROM asset_integrity`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recorded, failed []string
+	for rows.Next() {
+		var id string
+		var bad bool
+		if err := rows.Scan(&id, &bad); err != nil {
+			t.Fatal(err)
+		}
+		recorded = append(recorded, id)
+		if bad {
+			failed = append(failed, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(recorded)
+	sort.Strings(failed)
+	if want := []string{"a_missing", "a_ok", "a_size", "a_sum"}; !slices.Equal(recorded, want) {
+		t.Errorf("asset_integrity holds %v, want %v", recorded, want)
+	}
+	if want := []string{"a_missing", "a_size", "a_sum"}; !slices.Equal(failed, want) {
+		t.Errorf("asset_integrity failures = %v, want %v", failed, want)
+	}
+}
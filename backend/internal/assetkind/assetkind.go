@@ -0,0 +1,206 @@
+// Package assetkind is the registry of asset kinds: the values of an
+// asset's kind, with the MIME types and size each one accepts. POST
+// /assets validates uploads against it and GET /assets/kinds lists it.
+//
+// Default has the kinds the platform uses; deployments can add their own
+// or tighten the defaults with Register or LoadFile (ASSET_KINDS_FILE).
+package assetkind
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"gala/internal/pkg/errors"
+)
+
+const mib = 1 << 20
+
+// Kind describes an asset kind.
+type Kind struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// MIMETypes are the accepted content types; "image/*" matches any
+	// image. Empty accepts any type.
+	MIMETypes []string `json:"mime_types,omitempty"`
+	// MaxBytes is the largest accepted file; 0 means no limit.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// Kind names are lowercase snake_case.
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// Accepts reports whether a file of content type contentType is allowed.
+// Parameters such as "; charset=utf-8" are ignored.
+func (k Kind) Accepts(contentType string) bool {
+	if len(k.MIMETypes) == 0 {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	for _, pattern := range k.MIMETypes {
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if strings.HasPrefix(mt, prefix+"/") {
+				return true
+			}
+		} else if mt == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry holds the known kinds. It is safe for concurrent use.
+type Registry struct {
+	mu    sync.RWMutex
+	kinds map[string]Kind
+	order []string
+}
+
+// NewRegistry creates a registry with kinds.
+func NewRegistry(kinds ...Kind) (*Registry, error) {
+	r := &Registry{kinds: map[string]Kind{}}
+	for _, k := range kinds {
+		if err := r.Register(k); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Default returns a new registry with the kinds used by the platform.
+func Default() *Registry {
+	r, err := NewRegistry(defaults...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+var (
+	images   = []string{"image/*"}
+	audio    = []string{"audio/*"}
+	videos   = []string{"video/*"}
+	captions = []string{"text/vtt", "application/x-subrip", "text/plain"}
+)
+
+var defaults = []Kind{
+	{Name: "avatar_image", Description: "Image of the avatar to animate.", MIMETypes: images, MaxBytes: 20 * mib},
+	{Name: "avatar_input", Description: "Avatar image; older name of avatar_image.", MIMETypes: images, MaxBytes: 20 * mib},
+	{Name: "audio_voiceover", Description: "Voice track the avatar speaks.", MIMETypes: audio, MaxBytes: 200 * mib},
+	{Name: "music", Description: "Background music.", MIMETypes: audio, MaxBytes: 200 * mib},
+	{Name: "source_video", Description: "Video to use as source footage.", MIMETypes: videos, MaxBytes: 2048 * mib},
+	{Name: "overlay", Description: "Image or video drawn over the render.", MIMETypes: []string{"image/*", "video/*"}, MaxBytes: 500 * mib},
+	{Name: "background", Description: "Image or video behind the avatar.", MIMETypes: []string{"image/*", "video/*"}, MaxBytes: 500 * mib},
+	{Name: "captions", Description: "Subtitles (WebVTT or SubRip).", MIMETypes: captions, MaxBytes: 5 * mib},
+	{Name: "url_input", Description: "File downloaded from a URL given as a job input."},
+	{Name: "render_output", Description: "Video rendered by a job.", MIMETypes: videos},
+	{Name: "thumbnail", Description: "Thumbnail of a rendered video.", MIMETypes: images, MaxBytes: 20 * mib},
+	{Name: "hls_segment", Description: "Playlist or segment of the HLS rendition of a video.",
+		MIMETypes: []string{"video/mp2t", "application/vnd.apple.mpegurl"}},
+}
+
+// Register adds k, or replaces the kind with the same name.
+func (r *Registry) Register(k Kind) error {
+	if !namePattern.MatchString(k.Name) {
+		return fmt.Errorf("invalid asset kind name %q (want lowercase snake_case)", k.Name)
+	}
+	if k.MaxBytes < 0 {
+		return fmt.Errorf("asset kind %s: max_bytes must not be negative", k.Name)
+	}
+	k.MIMETypes = slices.Clone(k.MIMETypes)
+	for i, m := range k.MIMETypes {
+		m = strings.ToLower(strings.TrimSpace(m))
+		if !strings.Contains(m, "/") {
+			return fmt.Errorf("asset kind %s: invalid mime type %q", k.Name, k.MIMETypes[i])
+		}
+		k.MIMETypes[i] = m
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.kinds[k.Name]; !ok {
+		r.order = append(r.order, k.Name)
+	}
+	r.kinds[k.Name] = k
+	return nil
+}
+
+// LoadFile registers the kinds of a JSON file holding an array of Kind.
+func (r *Registry) LoadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var kinds []Kind
+	if err := json.Unmarshal(b, &kinds); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, k := range kinds {
+		if err := r.Register(k); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Lookup returns the kind called name.
+func (r *Registry) Lookup(name string) (Kind, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	k, ok := r.kinds[name]
+	return k, ok
+}
+
+// Kinds returns every kind, in registration order.
+func (r *Registry) Kinds() []Kind {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Kind, 0, len(r.order))
+	for _, name := range r.order {
+		out = append(out, r.kinds[name])
+	}
+	return out
+}
+
+// Names returns the kind names, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := slices.Clone(r.order)
+	slices.Sort(names)
+	return names
+}
+
+// Check validates a new asset of kind name: the kind must exist and the
+// file (contentType, size bytes) must meet its rules. The error is a
+// validation error on the "kind" or "file" field.
+func (r *Registry) Check(name, contentType string, size int64) error {
+	k, ok := r.Lookup(name)
+	if !ok {
+		return errors.ValidationField("kind", fmt.Sprintf("unknown asset kind %q", name)).
+			WithField("allowed", r.Names())
+	}
+	var errs []error
+	if !k.Accepts(contentType) {
+		errs = append(errs, errors.ValidationField("file",
+			fmt.Sprintf("kind %s does not accept %s files", k.Name, contentType)).
+			WithField("allowed_mime_types", k.MIMETypes))
+	}
+	if k.MaxBytes > 0 && size > k.MaxBytes {
+		errs = append(errs, errors.ValidationField("file",
+			fmt.Sprintf("kind %s accepts files up to %d bytes, got %d", k.Name, k.MaxBytes, size)).
+			WithField("max_bytes", k.MaxBytes))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.Aggregate(errs...)
+}
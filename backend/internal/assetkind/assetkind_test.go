@@ -0,0 +1,100 @@
+package assetkind
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gala/internal/pkg/errors"
+)
+
+func TestCheck(t *testing.T) {
+	r := Default()
+	tests := []struct {
+		name, kind, mime string
+		size             int64
+		wantField        string // "" means valid
+	}{
+		{"avatar jpeg", "avatar_image", "image/jpeg", 1 << 20, ""},
+		{"legacy avatar", "avatar_input", "image/png", 1 << 20, ""},
+		{"mime params", "captions", "text/vtt; charset=utf-8", 100, ""},
+		{"any mime", "url_input", "application/pdf", 1 << 30, ""},
+		{"typo", "avartar", "image/jpeg", 1, "kind"},
+		{"wrong mime", "audio_voiceover", "image/png", 1, "file"},
+		{"too big", "avatar_image", "image/jpeg", 21 << 20, "file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Check(tt.kind, tt.mime, tt.size)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("Check() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.IsValidation(err) {
+				t.Fatalf("Check() = %v, want a validation error", err)
+			}
+			var e *errors.Error
+			errors.As(err, &e)
+			if e.Fields["field"] != tt.wantField {
+				t.Errorf("field = %v, want %s", e.Fields["field"], tt.wantField)
+			}
+		})
+	}
+}
+
+func TestCheckReportsEveryRule(t *testing.T) {
+	err := Default().Check("captions", "video/mp4", 6<<20)
+	var e *errors.Error
+	if !errors.As(err, &e) || len(e.Causes) != 2 {
+		t.Fatalf("Check() = %v, want the mime and size errors", err)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kinds.json")
+	data := `[
+		{"name": "logo", "mime_types": ["image/PNG", "image/svg+xml"], "max_bytes": 1024},
+		{"name": "music", "mime_types": ["audio/mpeg"]}
+	]`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := Default()
+	n := len(r.Kinds())
+	if err := r.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(r.Kinds()); got != n+1 {
+		t.Errorf("len(Kinds()) = %d, want %d (one added, one replaced)", got, n+1)
+	}
+	if err := r.Check("logo", "image/png", 512); err != nil {
+		t.Errorf("Check(logo) = %v", err)
+	}
+	if err := r.Check("music", "audio/ogg", 1); err == nil {
+		t.Error("expected the replaced music kind to refuse audio/ogg")
+	}
+	if names := r.Names(); names[len(names)-1] != "url_input" {
+		t.Errorf("Names() = %v, want it sorted", names)
+	}
+}
+
+func TestRegisterRejectsInvalidKinds(t *testing.T) {
+	r, _ := NewRegistry()
+	for _, k := range []Kind{
+		{Name: "Avatar"},
+		{Name: ""},
+		{Name: "logo", MIMETypes: []string{"png"}},
+		{Name: "logo", MaxBytes: -1},
+	} {
+		if err := r.Register(k); err == nil {
+			t.Errorf("Register(%+v) = nil, want an error", k)
+		}
+	}
+	if err := r.Register(Kind{Name: "logo"}); err != nil || !strings.Contains(strings.Join(r.Names(), ","), "logo") {
+		t.Errorf("Register(logo) = %v, names %v", err, r.Names())
+	}
+}
@@ -0,0 +1,124 @@
+// Package assetslots is the asset library of templates: named slots, such
+// as the background music or a logo, bound to a default asset. A job input
+// of the same name that the job leaves unset takes the slot's asset, so
+// jobs only send the inputs that change between renders.
+package assetslots
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gala/internal/pkg/db"
+	"gala/internal/pkg/errors"
+	"gala/internal/store"
+)
+
+// namePattern is what a slot name may be: the name of the job input it
+// fills.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// Slot is an asset slot of a template.
+type Slot struct {
+	// AssetID is the default asset of the slot.
+	AssetID string `json:"asset_id"`
+	// Kind, if set, is the kind the slot's asset must have.
+	Kind        string `json:"kind,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Slots are a template's slots by name.
+type Slots map[string]Slot
+
+// Normalize trims the fields of every slot.
+func (s Slots) Normalize() {
+	for name, slot := range s {
+		slot.AssetID = strings.TrimSpace(slot.AssetID)
+		slot.Kind = strings.TrimSpace(slot.Kind)
+		slot.Description = strings.TrimSpace(slot.Description)
+		s[name] = slot
+	}
+}
+
+// Check reports the field errors of s through check, with field names under
+// prefix (e.g. "assets").
+func (s Slots) Check(prefix string, check func(ok bool, field, message string)) {
+	for _, name := range s.Names() {
+		field := prefix + "." + name
+		check(namePattern.MatchString(name), field, "asset slot name is invalid")
+		check(s[name].AssetID != "", field+".asset_id", "asset slot asset_id is required")
+	}
+}
+
+// Names returns the slot names, sorted.
+func (s Slots) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Fill sets the inputs the job left unset to the asset of the slot with
+// their name, and returns the names it set.
+func (s Slots) Fill(inputs map[string]string) []string {
+	var filled []string
+	for _, name := range s.Names() {
+		if strings.TrimSpace(inputs[name]) == "" {
+			inputs[name] = s[name].AssetID
+			filled = append(filled, name)
+		}
+	}
+	return filled
+}
+
+// Parse decodes stored slots (templates.assets); nil or JSON null is no
+// slots.
+func Parse(raw []byte) (Slots, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var s Slots
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("invalid template assets: %w", err)
+	}
+	return s, nil
+}
+
+// CheckAssets verifies that the asset of every slot exists and has the
+// slot's kind. Its failures are validation errors on the slot's field
+// under prefix, so handlers can return them as they are.
+func CheckAssets(ctx context.Context, q db.Querier, prefix string, s Slots) error {
+	if len(s) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(s))
+	for _, slot := range s {
+		ids = append(ids, slot.AssetID)
+	}
+	assets, err := store.GetAssets(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]store.Asset, len(assets))
+	for _, a := range assets {
+		byID[a.ID] = a
+	}
+	for _, name := range s.Names() {
+		slot := s[name]
+		field := prefix + "." + name + ".asset_id"
+		a, ok := byID[slot.AssetID]
+		if !ok {
+			return errors.ValidationField(field, "asset slot asset not found").WithField("asset_id", slot.AssetID)
+		}
+		if slot.Kind != "" && a.Kind != slot.Kind {
+			return errors.ValidationField(field, "asset slot asset has another kind").
+				WithFields(map[string]any{"asset_id": slot.AssetID, "kind": a.Kind, "want_kind": slot.Kind})
+		}
+	}
+	return nil
+}
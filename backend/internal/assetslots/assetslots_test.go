@@ -0,0 +1,63 @@
+package assetslots
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFill(t *testing.T) {
+	s := Slots{
+		"background_music": {AssetID: "ast_music"},
+		"logo":             {AssetID: "ast_logo"},
+	}
+	inputs := map[string]string{"logo": "ast_job_logo", "avatar_image_asset_id": "ast_avatar"}
+
+	filled := s.Fill(inputs)
+	if !slices.Equal(filled, []string{"background_music"}) {
+		t.Errorf("Fill() = %v, want [background_music]", filled)
+	}
+	want := map[string]string{"background_music": "ast_music", "logo": "ast_job_logo", "avatar_image_asset_id": "ast_avatar"}
+	for k, v := range want {
+		if inputs[k] != v {
+			t.Errorf("inputs[%q] = %q, want %q", k, inputs[k], v)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s := Slots{
+		"logo":      {AssetID: " ast_logo "},
+		"bad name!": {AssetID: "ast_x"},
+		"music":     {},
+	}
+	s.Normalize()
+	if s["logo"].AssetID != "ast_logo" {
+		t.Errorf("Normalize() left asset_id %q", s["logo"].AssetID)
+	}
+
+	var fields []string
+	s.Check("assets", func(ok bool, field, _ string) {
+		if !ok {
+			fields = append(fields, field)
+		}
+	})
+	want := []string{"assets.bad name!", "assets.music.asset_id"}
+	if !slices.Equal(fields, want) {
+		t.Errorf("Check() fields = %v, want %v", fields, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, raw := range []string{"", "null"} {
+		if s, err := Parse([]byte(raw)); err != nil || s != nil {
+			t.Errorf("Parse(%q) = %v, %v; want no slots", raw, s, err)
+		}
+	}
+	s, err := Parse([]byte(`{"logo":{"asset_id":"ast_logo","kind":"overlay"}}`))
+	if err != nil || s["logo"].AssetID != "ast_logo" || s["logo"].Kind != "overlay" {
+		t.Errorf("Parse() = %v, %v", s, err)
+	}
+	if _, err := Parse([]byte(`[]`)); err == nil {
+		t.Error("Parse([]) did not fail")
+	}
+}
@@ -0,0 +1,143 @@
+package bootstrap
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"gala/internal/health"
+	"gala/internal/httpapi"
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/fetch"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/reload"
+	"gala/internal/pkg/shutdown"
+)
+
+// StartAPI starts the HTTP server on HTTP_PORT in the background and
+// registers its graceful shutdown. The returned closer flushes the access
+// log; close it after shutdownMgr.Wait, since the server may log requests
+// while draining.
+func StartAPI(log *logger.Logger, infra *Infra, reloadMgr *reload.Manager, shutdownMgr *shutdown.Manager) io.Closer {
+	// Include stack traces in error responses only when explicitly enabled
+	errors.SetDebug(Env("API_DEBUG_ERRORS", "false") == "true")
+
+	httpPort := Env("HTTP_PORT", "8080")
+
+	// Access log: request lines go to their own sink when configured,
+	// otherwise to the application log.
+	accessLog := log
+	accessCloser := io.Closer(io.NopCloser(nil))
+	if dest := Env("ACCESS_LOG_OUTPUT", ""); dest != "" {
+		out, closer, err := logger.OpenOutput(dest,
+			int64(intEnv("ACCESS_LOG_MAX_SIZE_MB", 100))<<20,
+			intEnv("ACCESS_LOG_MAX_BACKUPS", 5),
+		)
+		if err != nil {
+			log.LogFatal("failed to open access log", err, "output", dest)
+		}
+		accessCloser = closer
+		accessLog = logger.New(logger.Config{
+			Level:       Env("ACCESS_LOG_LEVEL", "info"),
+			Format:      Env("ACCESS_LOG_FORMAT", "json"),
+			Output:      out,
+			ServiceName: "gala-api-access",
+		})
+		log.Info("access log enabled", "output", dest)
+
+		reloadMgr.Register("access-log", func(ctx context.Context, v reload.Values) error {
+			accessLog.SetLevel(v.Get("ACCESS_LOG_LEVEL", "info"))
+			return nil
+		})
+	}
+
+	ev := eventBus(log, infra)
+	pub := startPublisher(log, infra, ev, shutdownMgr)
+
+	// Create HTTP router
+	router := httpapi.NewRouter(httpapi.Deps{
+		Pool:      infra.Pool,
+		DB:        infra.DB,
+		RDB:       infra.RDB,
+		SP:        infra.SP,
+		Log:       log,
+		AccessLog: accessLog,
+		Metrics:   infra.Metrics,
+		Reload:    reloadMgr,
+		Ready:     func() bool { return !shutdownMgr.Draining() },
+		QueueMode: queueMode(log),
+		Events:    ev,
+		Publisher: pub,
+		Fetch:     fetch.New(fetchConfig()),
+
+		AssetStream:  assetStreamConfig(log),
+		Health:       startHealthMonitor(log, infra, shutdownMgr),
+		AssetKinds:   assetKinds(log),
+		DedupWindow:  durationEnv("JOB_DEDUP_WINDOW", 0),
+		RendererAuth: rendererSecret(log),
+	})
+
+	// Create HTTP server
+	server := &http.Server{
+		Addr:         "0.0.0.0:" + httpPort,
+		Handler:      router,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 60 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+
+	// Register server shutdown
+	shutdownMgr.Register("http-server", func(ctx context.Context) error {
+		log.Info("shutting down HTTP server")
+		return server.Shutdown(ctx)
+	})
+
+	// Start server in goroutine
+	go func() {
+		log.Info("HTTP server listening",
+			"addr", server.Addr,
+			"port", httpPort,
+		)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.LogFatal("HTTP server failed", err)
+		}
+	}()
+
+	// gRPC services, only when GRPC_PORT is set
+	if grpcPort := Env("GRPC_PORT", ""); grpcPort != "" {
+		startGRPC(log, infra, ev, grpcPort, shutdownMgr)
+	}
+
+	return accessCloser
+}
+
+// NewAPIHandler builds the API routes on infra without serving them, for
+// tools that run the API in-process (galactl -direct). adminToken guards
+// the /admin routes as ADMIN_TOKEN does for the server.
+func NewAPIHandler(log *logger.Logger, infra *Infra, adminToken string) http.Handler {
+	return httpapi.NewRouter(httpapi.Deps{
+		Pool:       infra.Pool,
+		DB:         infra.DB,
+		RDB:        infra.RDB,
+		SP:         infra.SP,
+		Log:        log,
+		QueueMode:  queueMode(log),
+		AdminToken: adminToken,
+		Events:     eventBus(log, infra),
+	})
+}
+
+// startHealthMonitor refreshes the dependency checks of GET
+// /health?deep=true every HEALTH_CHECK_INTERVAL (default 10s) until
+// shutdown; 0 turns it off and the checks run on every request.
+func startHealthMonitor(log *logger.Logger, infra *Infra, shutdownMgr *shutdown.Manager) *health.Monitor {
+	interval := durationEnv("HEALTH_CHECK_INTERVAL", health.DefaultInterval)
+	if interval <= 0 {
+		log.Info("health monitor disabled", "reason", "HEALTH_CHECK_INTERVAL is 0")
+		return nil
+	}
+	m := health.NewMonitor(health.Checks(infra.Pool, infra.RDB, infra.SP), interval)
+	m.Start(shutdownMgr.Context())
+	return m
+}
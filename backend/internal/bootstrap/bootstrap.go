@@ -0,0 +1,219 @@
+// Package bootstrap starts the services from environment configuration:
+// the shared infrastructure (Postgres, Redis, storage), the API server and
+// the worker loop. cmd/api, cmd/worker and the combined cmd/gala use it so
+// each component starts the same way whichever binary runs it.
+//
+// Startup failures are fatal (logged and exit 1, after running whatever
+// shutdown handlers were registered so far), as there is nothing a caller
+// could do to recover from them.
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+
+	"gala/internal/pkg/chaos"
+	"gala/internal/pkg/dbpool"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/metrics"
+	"gala/internal/pkg/migrate"
+	"gala/internal/pkg/redisconn"
+	"gala/internal/pkg/reload"
+	"gala/internal/pkg/shutdown"
+	"gala/internal/pkg/startup"
+	"gala/internal/ports"
+	"gala/internal/storage"
+	"gala/migrations"
+)
+
+// NewLogger creates the application logger from LOG_LEVEL, LOG_FORMAT and
+// LOG_SOURCE.
+func NewLogger(service string) *logger.Logger {
+	return logger.New(logger.Config{
+		Level:       Env("LOG_LEVEL", "info"),
+		Format:      Env("LOG_FORMAT", "json"),
+		ServiceName: service,
+		AddSource:   Env("LOG_SOURCE", "false") == "true",
+	})
+}
+
+// NewShutdownManager creates the shutdown manager. SHUTDOWN_TIMEOUT bounds
+// draining and closing (default 30s; keep it below the Kubernetes
+// terminationGracePeriodSeconds minus the pre-stop delay).
+// SHUTDOWN_PRE_STOP_DELAY is how long /readyz fails before draining
+// starts (default 0; a few seconds under Kubernetes).
+//
+// The manager also becomes the logger exit hook, so LogFatal runs the
+// registered handlers within SHUTDOWN_FATAL_TIMEOUT (default 5s) before
+// exiting.
+func NewShutdownManager(log *logger.Logger) *shutdown.Manager {
+	m := shutdown.NewManager(log, durationEnv("SHUTDOWN_TIMEOUT", 30*time.Second))
+	m.SetPreStopDelay(durationEnv("SHUTDOWN_PRE_STOP_DELAY", 0))
+
+	fatalTimeout := durationEnv("SHUTDOWN_FATAL_TIMEOUT", 5*time.Second)
+	logger.SetExitHook(func() { m.Fatal(fatalTimeout) })
+	return m
+}
+
+// NewReloadManager creates the config reload manager (CONFIG_FILE) with
+// the application log level registered. Components add their own
+// handlers; call Watch once everything is started.
+func NewReloadManager(log *logger.Logger) *reload.Manager {
+	m := reload.NewManager(log, Env("CONFIG_FILE", ""))
+	m.Register("log", func(ctx context.Context, v reload.Values) error {
+		log.SetLevel(v.Get("LOG_LEVEL", "info"))
+		return nil
+	})
+	return m
+}
+
+// Infra is the infrastructure shared by the API and the worker.
+type Infra struct {
+	Pool *pgxpool.Pool
+	// DB routes API list/export reads to read replicas, if any.
+	DB  *dbpool.Router
+	RDB redis.UniversalClient
+	SP  ports.StorageProvider
+	// Metrics is served by the API on /metrics; nil when no API runs in
+	// this process.
+	Metrics *metrics.Registry
+	// Chaos injects faults when CHAOS_ENABLED is set; nil otherwise. RDB
+	// and SP already go through it; the worker adds it to the renderer.
+	Chaos *chaos.Injector
+}
+
+// ConnectOptions selects what Connect sets up beyond the primary pool,
+// Redis and storage.
+type ConnectOptions struct {
+	// Replicas connects DATABASE_REPLICA_URLS for API reads.
+	Replicas bool
+	// Metrics, if set, receives the pool stats gauges and the storage
+	// provider metrics.
+	Metrics *metrics.Registry
+}
+
+// Connect opens Postgres (DATABASE_URL), Redis (REDIS_ADDR) and the
+// storage provider, waiting up to STARTUP_MAX_WAIT for the databases on
+// cold starts. Connections are closed by shutdownMgr. With CHAOS_ENABLED,
+// Redis and the storage fail at the rates of chaosConfig; never set it in
+// production.
+func Connect(ctx context.Context, log *logger.Logger, shutdownMgr *shutdown.Manager, opt ConnectOptions) *Infra {
+	dbURL := mustEnv(log, "DATABASE_URL")
+	redisAddr := mustEnv(log, "REDIS_ADDR")
+
+	// How long to wait for Postgres/Redis before giving up (0 = fail fast)
+	startupWait := durationEnv("STARTUP_MAX_WAIT", 60*time.Second)
+
+	// Connect to PostgreSQL
+	log.Info("connecting to PostgreSQL")
+	pool, err := dbpool.New(ctx, dbURL, dbPoolConfig())
+	if err != nil {
+		log.LogFatal("failed to connect to PostgreSQL", err)
+	}
+	shutdownMgr.RegisterStage(shutdown.StageClose, "postgres", func(ctx context.Context) error {
+		pool.Close()
+		return nil
+	})
+
+	// Verify PostgreSQL connection, waiting for it on cold starts
+	if err := startup.WaitFor(ctx, log, "postgres", startupWait, startup.Backoff{}, pingWithTimeout(pool.Ping)); err != nil {
+		log.LogFatal("failed to ping PostgreSQL", err)
+	}
+	log.Info("PostgreSQL connected")
+
+	// Apply pending schema migrations (DB_AUTO_MIGRATE); concurrent
+	// instances serialize on an advisory lock
+	if boolEnv("DB_AUTO_MIGRATE", false) {
+		m, err := migrate.New(pool, migrations.FS, log)
+		if err != nil {
+			log.LogFatal("invalid migrations", err)
+		}
+		n, err := m.Up(ctx)
+		if err != nil {
+			log.LogFatal("failed to migrate database", err, "applied", n)
+		}
+		log.Info("database schema up to date", "applied", n)
+	}
+
+	// Read replicas (optional): list and export endpoints read from them
+	var replicas []*pgxpool.Pool
+	if opt.Replicas {
+		for i, url := range replicaURLs() {
+			rp, err := dbpool.New(ctx, url, dbPoolConfig())
+			if err != nil {
+				log.LogFatal("failed to configure read replica", err, "replica", i)
+			}
+			shutdownMgr.RegisterStage(shutdown.StageClose, fmt.Sprintf("postgres-replica-%d", i), func(ctx context.Context) error {
+				rp.Close()
+				return nil
+			})
+			if err := startup.WaitFor(ctx, log, fmt.Sprintf("postgres-replica-%d", i), startupWait, startup.Backoff{}, pingWithTimeout(rp.Ping)); err != nil {
+				log.LogFatal("failed to ping read replica", err, "replica", i)
+			}
+			replicas = append(replicas, rp)
+		}
+	}
+	db := dbpool.NewRouter(pool, replicas...)
+	go db.Watch(shutdownMgr.Context(), log, durationEnv("DB_REPLICA_CHECK_INTERVAL", 10*time.Second))
+	if len(replicas) > 0 {
+		log.Info("read replicas connected", "count", len(replicas))
+	}
+
+	// Pool stats are exported when there is a registry and logged when
+	// saturated
+	go dbpool.Monitor(shutdownMgr.Context(), pool, log, opt.Metrics, durationEnv("DB_STATS_INTERVAL", 15*time.Second))
+
+	// Connect to Redis
+	log.Info("connecting to Redis")
+	rdb, err := redisconn.New(redisConfig(redisAddr))
+	if err != nil {
+		log.LogFatal("invalid Redis configuration", err)
+	}
+	shutdownMgr.RegisterStage(shutdown.StageClose, "redis", func(ctx context.Context) error {
+		return rdb.Close()
+	})
+
+	// Verify Redis connection
+	if err := startup.WaitFor(ctx, log, "redis", startupWait, startup.Backoff{}, pingWithTimeout(func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	})); err != nil {
+		log.LogFatal("failed to ping Redis", err)
+	}
+	log.Info("Redis connected")
+
+	// Fault injection (staging only), after the startup checks so that
+	// they do not fail on it
+	var inj *chaos.Injector
+	if boolEnv("CHAOS_ENABLED", false) {
+		cfg := chaosConfig()
+		if err := cfg.Validate(); err != nil {
+			log.LogFatal("invalid chaos configuration", err)
+		}
+		inj = chaos.New(cfg, log)
+		rdb.AddHook(inj.RedisHook())
+		log.Warn("fault injection enabled, do not run in production",
+			"storage_error_rate", cfg.StorageErrorRate,
+			"renderer_error_rate", cfg.RendererErrorRate,
+			"renderer_timeout_rate", cfg.RendererTimeoutRate,
+			"renderer_hang", cfg.RendererHang.String(),
+			"redis_drop_rate", cfg.RedisDropRate,
+		)
+	}
+
+	// Initialize storage provider
+	log.Info("initializing storage provider")
+	sp, err := storage.NewProvider()
+	if err != nil {
+		log.LogFatal("failed to initialize storage provider", err)
+	}
+	// Calls are timed into opt.Metrics (if any) and logged when slower
+	// than STORAGE_SLOW_OP_THRESHOLD, injected faults included
+	sp = storage.Instrument(storage.WithChaos(sp, inj), opt.Metrics, log, durationEnv("STORAGE_SLOW_OP_THRESHOLD", 10*time.Second))
+	log.Info("storage provider initialized", "provider", sp.Provider())
+
+	return &Infra{Pool: pool, DB: db, RDB: rdb, SP: sp, Metrics: opt.Metrics, Chaos: inj}
+}
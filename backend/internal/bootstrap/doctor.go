@@ -0,0 +1,352 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gala/internal/assetkind"
+	"gala/internal/httpapi/handlers"
+	"gala/internal/pkg/dbpool"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/migrate"
+	"gala/internal/pkg/redisconn"
+	"gala/internal/ports"
+	"gala/internal/storage"
+	"gala/internal/worker/processor"
+	"gala/internal/worker/queue"
+	"gala/internal/worker/renderer"
+	"gala/migrations"
+)
+
+// Check results reported by Diagnose.
+const (
+	CheckPass = "PASS"
+	CheckWarn = "WARN"
+	CheckFail = "FAIL"
+	CheckSkip = "SKIP"
+)
+
+// Check is the outcome of one Diagnose step.
+type Check struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Detail   string        `json:"detail,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// DiagnoseOptions selects which component's configuration Diagnose checks.
+type DiagnoseOptions struct {
+	// API and Worker enable the checks for that component (RENDERER_* is
+	// only required by the worker).
+	API, Worker bool
+	// Timeout bounds each connectivity check (default 10s).
+	Timeout time.Duration
+}
+
+// Diagnose checks the environment the way Connect, StartAPI and
+// StartWorker read it, then connects to every dependency: Postgres
+// (including pending migrations), Redis, the storage provider (with a
+// write/read/delete probe) and the renderer. Unlike Connect it never
+// exits; every problem becomes a failed Check, and checks that depend on
+// a failed one are skipped.
+func Diagnose(ctx context.Context, log *logger.Logger, opt DiagnoseOptions) []Check {
+	if opt.Timeout <= 0 {
+		opt.Timeout = 10 * time.Second
+	}
+	d := &diagnosis{ctx: ctx, timeout: opt.Timeout}
+
+	// Configuration
+	d.run("env", func(ctx context.Context) (string, error) {
+		return checkEnv(opt)
+	})
+
+	// Postgres
+	dbURL := Env("DATABASE_URL", "")
+	d.runIf(dbURL != "", "postgres", func(ctx context.Context) (string, error) {
+		pool, err := dbpool.New(ctx, dbURL, dbPoolConfig())
+		if err != nil {
+			return "", err
+		}
+		defer pool.Close()
+		if err := pool.Ping(ctx); err != nil {
+			return "", err
+		}
+		var version string
+		if err := pool.QueryRow(ctx, "SHOW server_version").Scan(&version); err != nil {
+			return "", err
+		}
+
+		m, err := migrate.New(pool, migrations.FS, log)
+		if err != nil {
+			return "", err
+		}
+		status, err := m.Status(ctx)
+		if err != nil {
+			return "", fmt.Errorf("migration status: %w", err)
+		}
+		pending := 0
+		for _, s := range status {
+			if s.AppliedAt == nil {
+				pending++
+			}
+		}
+		if pending > 0 && !boolEnv("DB_AUTO_MIGRATE", false) {
+			return "", warning(fmt.Sprintf("server %s; %d migrations pending (run migrate up or set DB_AUTO_MIGRATE=true)", version, pending))
+		}
+		return fmt.Sprintf("server %s; %d migrations, %d pending", version, len(status), pending), nil
+	})
+
+	// Redis
+	redisAddr := Env("REDIS_ADDR", "")
+	d.runIf(redisAddr != "", "redis", func(ctx context.Context) (string, error) {
+		rdb, err := redisconn.New(redisConfig(redisAddr))
+		if err != nil {
+			return "", err
+		}
+		defer rdb.Close()
+		if err := rdb.Ping(ctx).Err(); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s (%s)", redisAddr, Env("REDIS_MODE", redisconn.ModeStandalone)), nil
+	})
+
+	// Storage: the provider is built exactly as Connect builds it
+	var sp ports.StorageProvider
+	storageOK := d.run("storage", func(ctx context.Context) (string, error) {
+		var err error
+		sp, err = newStorageProvider()
+		if err != nil {
+			return "", err
+		}
+		return sp.Provider(), nil
+	})
+	d.runIf(storageOK, "storage-probe", func(ctx context.Context) (string, error) {
+		return probeStorage(ctx, sp)
+	})
+
+	// Renderer
+	if opt.Worker {
+		baseURL := Env("RENDERER_HTTP_BASEURL", "")
+		d.runIf(baseURL != "", "renderer", func(ctx context.Context) (string, error) {
+			return probeRenderer(ctx, baseURL)
+		})
+	}
+
+	return d.checks
+}
+
+// warning marks a check result that works but needs attention.
+type warning string
+
+func (w warning) Error() string { return string(w) }
+
+type diagnosis struct {
+	ctx     context.Context
+	timeout time.Duration
+	checks  []Check
+}
+
+// run records the result of fn and reports whether it passed (or only
+// warned).
+func (d *diagnosis) run(name string, fn func(ctx context.Context) (string, error)) bool {
+	ctx, cancel := context.WithTimeout(d.ctx, d.timeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := fn(ctx)
+	c := Check{Name: name, Status: CheckPass, Detail: detail, Duration: time.Since(start)}
+	switch w, ok := err.(warning); {
+	case ok:
+		c.Status, c.Detail = CheckWarn, string(w)
+	case err != nil:
+		c.Status, c.Detail = CheckFail, err.Error()
+	}
+	// pgx joins one line per attempted host
+	c.Detail = strings.Join(strings.Fields(c.Detail), " ")
+	d.checks = append(d.checks, c)
+	return c.Status != CheckFail
+}
+
+func (d *diagnosis) runIf(ok bool, name string, fn func(ctx context.Context) (string, error)) {
+	if !ok {
+		d.checks = append(d.checks, Check{Name: name, Status: CheckSkip, Detail: "missing configuration or failed dependency"})
+		return
+	}
+	d.run(name, fn)
+}
+
+// checkEnv validates the variables the services read at startup, so
+// Diagnose can report them all at once instead of failing on the first.
+func checkEnv(opt DiagnoseOptions) (string, error) {
+	var problems, warnings []string
+	require := func(keys ...string) {
+		for _, k := range keys {
+			if Env(k, "") == "" {
+				problems = append(problems, k+" is not set")
+			}
+		}
+	}
+
+	require("DATABASE_URL", "REDIS_ADDR")
+
+	switch p := Env("STORAGE_PROVIDER", "localfs"); p {
+	case "localfs":
+		require("STORAGE_LOCAL_ROOT")
+		if root := Env("STORAGE_LOCAL_ROOT", ""); root != "" {
+			if st, err := os.Stat(root); err != nil || !st.IsDir() {
+				problems = append(problems, "STORAGE_LOCAL_ROOT "+root+" is not a directory")
+			}
+		}
+	case "gdrive":
+		require("GDRIVE_CLIENT_ID", "GDRIVE_CLIENT_SECRET")
+		if Env("GDRIVE_REFRESH_TOKEN", "") == "" && Env("GDRIVE_REFRESH_TOKEN_FILE", "") == "" {
+			problems = append(problems, "GDRIVE_REFRESH_TOKEN or GDRIVE_REFRESH_TOKEN_FILE is not set")
+		}
+	default:
+		problems = append(problems, "STORAGE_PROVIDER "+p+" is not localfs or gdrive")
+	}
+
+	if mode := Env("QUEUE_MODE", queue.ModeRedis); mode != queue.ModeRedis && mode != queue.ModePostgres {
+		problems = append(problems, "QUEUE_MODE "+mode+" is not redis or postgres")
+	}
+
+	if opt.API && Env("ADMIN_TOKEN", "") == "" {
+		warnings = append(warnings, "ADMIN_TOKEN is not set (the /admin routes are disabled)")
+	}
+
+	if opt.API {
+		switch mode := Env("ASSET_STREAM_MODE", handlers.StreamProxy); mode {
+		case handlers.StreamProxy:
+		case handlers.StreamAccel, handlers.StreamSendfile:
+			if Env("STORAGE_PROVIDER", "localfs") != "localfs" {
+				warnings = append(warnings, "ASSET_STREAM_MODE "+mode+" only applies to localfs storage")
+			}
+		default:
+			problems = append(problems, "ASSET_STREAM_MODE "+mode+" is not proxy, accel or sendfile")
+		}
+		if path := Env("ASSET_KINDS_FILE", ""); path != "" {
+			if err := assetkind.Default().LoadFile(path); err != nil {
+				problems = append(problems, "ASSET_KINDS_FILE: "+err.Error())
+			}
+		}
+	}
+
+	if opt.API && Env("YOUTUBE_CLIENT_ID", "") != "" {
+		require("YOUTUBE_CLIENT_SECRET")
+		if Env("YOUTUBE_REFRESH_TOKEN", "") == "" && Env("YOUTUBE_REFRESH_TOKEN_FILE", "") == "" {
+			problems = append(problems, "YOUTUBE_REFRESH_TOKEN or YOUTUBE_REFRESH_TOKEN_FILE is not set")
+		}
+	}
+	if opt.API && Env("PUBLISH_S3_BUCKET", "") != "" {
+		if _, err := s3Target(); err != nil {
+			problems = append(problems, "s3 publish target: "+err.Error())
+		}
+	}
+
+	if opt.Worker {
+		require("RENDERER_HTTP_BASEURL")
+		_, err := renderer.NewAuthenticator(RendererAuthConfig())
+		if err != nil {
+			problems = append(problems, "renderer auth: "+err.Error())
+		}
+		if boolEnv("HLS_ENABLED", false) {
+			if _, err := exec.LookPath(Env("HLS_FFMPEG_PATH", "ffmpeg")); err != nil {
+				problems = append(problems, "HLS_ENABLED is set but ffmpeg was not found: "+err.Error())
+			}
+		}
+		switch fc := formatCheckConfig(); fc.Mode {
+		case processor.FormatCheckOff:
+		case processor.FormatCheckWarn, processor.FormatCheckFail:
+			if _, err := exec.LookPath(fc.FFprobePath); err != nil {
+				msg := "RENDER_FORMAT_CHECK=" + fc.Mode + " but ffprobe was not found: " + err.Error()
+				if fc.Mode == processor.FormatCheckFail {
+					problems = append(problems, msg)
+				} else {
+					warnings = append(warnings, msg)
+				}
+			}
+		default:
+			problems = append(problems, "invalid RENDER_FORMAT_CHECK "+fc.Mode+" (want off, warn or fail)")
+		}
+	}
+
+	if len(problems) > 0 {
+		return "", fmt.Errorf("%s", strings.Join(append(problems, warnings...), "; "))
+	}
+	if len(warnings) > 0 {
+		return "", warning(strings.Join(warnings, "; "))
+	}
+	return "all required variables set", nil
+}
+
+// newStorageProvider wraps storage.NewProvider, which panics on missing
+// variables.
+func newStorageProvider() (sp ports.StorageProvider, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return storage.NewProvider()
+}
+
+// probeStorage writes a small object, reads it back and deletes it.
+func probeStorage(ctx context.Context, sp ports.StorageProvider) (string, error) {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	payload := []byte("gala doctor probe " + hex.EncodeToString(buf))
+
+	put, err := sp.PutObject(ctx, ports.PutObjectInput{
+		ObjectKey:   "doctor/probe-" + hex.EncodeToString(buf) + ".txt",
+		ContentType: "text/plain",
+		Reader:      bytes.NewReader(payload),
+		Size:        int64(len(payload)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("write: %w", err)
+	}
+
+	rc, _, _, err := sp.GetObject(ctx, put.ObjectKey)
+	if err != nil {
+		_ = sp.DeleteObject(ctx, put.ObjectKey)
+		return "", fmt.Errorf("read: %w", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err == nil && !bytes.Equal(got, payload) {
+		err = fmt.Errorf("content mismatch (%d bytes written, %d read)", len(payload), len(got))
+	}
+	if err != nil {
+		_ = sp.DeleteObject(ctx, put.ObjectKey)
+		return "", fmt.Errorf("read: %w", err)
+	}
+
+	if err := sp.DeleteObject(ctx, put.ObjectKey); err != nil {
+		return "", fmt.Errorf("delete %s: %w", put.ObjectKey, err)
+	}
+	return "write/read/delete ok", nil
+}
+
+// probeRenderer checks that the renderer answers HTTP. The renderer has no
+// health route, so any response counts as reachable.
+func probeRenderer(ctx context.Context, baseURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(baseURL, "/")+"/health", nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+	return fmt.Sprintf("%s reachable (HTTP %d)", baseURL, res.StatusCode), nil
+}
@@ -0,0 +1,233 @@
+package bootstrap
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gala/internal/assetkind"
+	"gala/internal/callback"
+	"gala/internal/events"
+	"gala/internal/httpapi/handlers"
+	"gala/internal/pkg/chaos"
+	"gala/internal/pkg/db"
+	"gala/internal/pkg/dbpool"
+	"gala/internal/pkg/fetch"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/redisconn"
+	"gala/internal/worker/renderer"
+)
+
+// Env gets an environment variable with a default value.
+func Env(key, defaultValue string) string {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return defaultValue
+	}
+	return v
+}
+
+// intEnv gets an integer environment variable with a default value.
+func intEnv(key string, defaultValue int) int {
+	v, err := strconv.Atoi(Env(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return v
+}
+
+// boolEnv gets a boolean environment variable.
+func boolEnv(key string, defaultValue bool) bool {
+	v := strings.ToLower(Env(key, ""))
+	if v == "" {
+		return defaultValue
+	}
+	return v == "1" || v == "true" || v == "yes" || v == "on"
+}
+
+// floatEnv gets a float environment variable with a default value.
+func floatEnv(key string, defaultValue float64) float64 {
+	v, err := strconv.ParseFloat(Env(key, ""), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return v
+}
+
+// durationEnv gets a duration environment variable (e.g. "15m").
+func durationEnv(key string, defaultValue time.Duration) time.Duration {
+	d, err := time.ParseDuration(Env(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return d
+}
+
+// mustEnv gets a required environment variable or exits.
+func mustEnv(log *logger.Logger, key string) string {
+	v := Env(key, "")
+	if v == "" {
+		log.LogFatal("missing required environment variable", nil, "key", key)
+	}
+	return v
+}
+
+// eventBus publishes the lifecycle events on infra's Redis, keeping about
+// EVENTS_STREAM_MAXLEN of them for clients resuming GET /v1/events.
+func eventBus(log *logger.Logger, infra *Infra) *events.Bus {
+	return events.New(infra.RDB, log, int64(intEnv("EVENTS_STREAM_MAXLEN", events.DefaultMaxLen)))
+}
+
+// fetchConfig reads the limits for downloading inputs given by URL:
+// INPUT_FETCH_MAX_BYTES, INPUT_FETCH_ALLOWED_TYPES (comma-separated, e.g.
+// "image/*,video/mp4"), INPUT_FETCH_TIMEOUT and INPUT_FETCH_ALLOW_PRIVATE.
+func fetchConfig() fetch.Config {
+	var types []string
+	for _, t := range strings.Split(Env("INPUT_FETCH_ALLOWED_TYPES", ""), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return fetch.Config{
+		MaxBytes:     int64(intEnv("INPUT_FETCH_MAX_BYTES", fetch.DefaultMaxBytes)),
+		AllowedTypes: types,
+		Timeout:      durationEnv("INPUT_FETCH_TIMEOUT", fetch.DefaultTimeout),
+		AllowPrivate: boolEnv("INPUT_FETCH_ALLOW_PRIVATE", false),
+	}
+}
+
+// callbackConfig reads how the worker posts job callbacks:
+// CALLBACK_TIMEOUT (per attempt), CALLBACK_MAX_ATTEMPTS and
+// CALLBACK_ALLOW_PRIVATE.
+func callbackConfig() callback.Config {
+	return callback.Config{
+		Timeout:      durationEnv("CALLBACK_TIMEOUT", callback.DefaultTimeout),
+		MaxAttempts:  intEnv("CALLBACK_MAX_ATTEMPTS", callback.DefaultMaxAttempts),
+		AllowPrivate: boolEnv("CALLBACK_ALLOW_PRIVATE", false),
+	}
+}
+
+// assetStreamConfig reads how the API serves asset files:
+// ASSET_STREAM_MODE (proxy, accel or sendfile) and ASSET_ACCEL_PREFIX, the
+// nginx internal location of accel mode.
+func assetStreamConfig(log *logger.Logger) handlers.StreamConfig {
+	mode := Env("ASSET_STREAM_MODE", handlers.StreamProxy)
+	switch mode {
+	case handlers.StreamProxy, handlers.StreamAccel, handlers.StreamSendfile:
+	default:
+		log.LogFatal("invalid ASSET_STREAM_MODE", nil, "mode", mode)
+	}
+	if mode != handlers.StreamProxy && Env("STORAGE_PROVIDER", "localfs") != "localfs" {
+		log.Warn("ASSET_STREAM_MODE only applies to localfs storage; assets are proxied", "mode", mode)
+	}
+	return handlers.StreamConfig{
+		Mode:        mode,
+		AccelPrefix: Env("ASSET_ACCEL_PREFIX", handlers.DefaultAccelPrefix),
+	}
+}
+
+// assetKinds builds the asset kind registry: the defaults plus the kinds
+// of ASSET_KINDS_FILE, a JSON array that adds kinds or replaces defaults.
+func assetKinds(log *logger.Logger) *assetkind.Registry {
+	kinds := assetkind.Default()
+	if path := Env("ASSET_KINDS_FILE", ""); path != "" {
+		if err := kinds.LoadFile(path); err != nil {
+			log.LogFatal("invalid ASSET_KINDS_FILE", err, "path", path)
+		}
+	}
+	return kinds
+}
+
+// RendererAuthConfig reads how requests to the renderer are authenticated
+// (RENDERER_AUTH_MODE, RENDERER_AUTH_SECRET, RENDERER_AUTH_SECRET_FILE).
+func RendererAuthConfig() renderer.AuthConfig {
+	return renderer.AuthConfig{
+		Mode:       Env("RENDERER_AUTH_MODE", "none"),
+		Secret:     os.Getenv("RENDERER_AUTH_SECRET"),
+		SecretFile: Env("RENDERER_AUTH_SECRET_FILE", ""),
+	}
+}
+
+// rendererSecret is the renderer's shared secret, with which the API
+// verifies the progress the renderer reports; nil with RENDERER_AUTH_MODE
+// none.
+func rendererSecret(log *logger.Logger) renderer.SecretSource {
+	src, err := renderer.NewSecretSource(RendererAuthConfig())
+	if err != nil {
+		log.LogFatal("invalid renderer auth configuration", err)
+	}
+	return src
+}
+
+// dbPoolConfig reads the connection pool settings; unset values keep the
+// pgxpool defaults. DB_QUERY_TIMEOUT bounds each statement (0 disables
+// it).
+func dbPoolConfig() dbpool.Config {
+	return dbpool.Config{
+		MaxConns:          int32(intEnv("DB_MAX_CONNS", 0)),
+		MinConns:          int32(intEnv("DB_MIN_CONNS", 0)),
+		MaxConnLifetime:   durationEnv("DB_MAX_CONN_LIFETIME", 0),
+		MaxConnIdleTime:   durationEnv("DB_MAX_CONN_IDLE_TIME", 0),
+		HealthCheckPeriod: durationEnv("DB_HEALTH_CHECK_PERIOD", 0),
+		QueryTimeout:      durationEnv("DB_QUERY_TIMEOUT", db.DefaultQueryTimeout),
+	}
+}
+
+// replicaURLs returns the read replica DSNs from DATABASE_REPLICA_URLS
+// (comma-separated) or DATABASE_REPLICA_URL.
+func replicaURLs() []string {
+	var out []string
+	for _, u := range strings.Split(Env("DATABASE_REPLICA_URLS", Env("DATABASE_REPLICA_URL", "")), ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// chaosConfig reads the fault rates of the chaos mode (CHAOS_ENABLED):
+// CHAOS_STORAGE_ERROR_RATE, CHAOS_RENDERER_ERROR_RATE,
+// CHAOS_RENDERER_TIMEOUT_RATE (hanging up to CHAOS_RENDERER_HANG) and
+// CHAOS_REDIS_DROP_RATE.
+func chaosConfig() chaos.Config {
+	return chaos.Config{
+		StorageErrorRate:    floatEnv("CHAOS_STORAGE_ERROR_RATE", 0),
+		RendererErrorRate:   floatEnv("CHAOS_RENDERER_ERROR_RATE", 0),
+		RendererTimeoutRate: floatEnv("CHAOS_RENDERER_TIMEOUT_RATE", 0),
+		RendererHang:        durationEnv("CHAOS_RENDERER_HANG", chaos.DefaultRendererHang),
+		RedisDropRate:       floatEnv("CHAOS_REDIS_DROP_RATE", 0),
+	}
+}
+
+// redisConfig reads the Redis connection settings. REDIS_ADDR holds the
+// server, the sentinels or the cluster seed nodes (comma-separated)
+// depending on REDIS_MODE.
+func redisConfig(addrs string) redisconn.Config {
+	return redisconn.Config{
+		Mode:                  Env("REDIS_MODE", redisconn.ModeStandalone),
+		Addrs:                 strings.Split(addrs, ","),
+		Username:              Env("REDIS_USERNAME", ""),
+		Password:              os.Getenv("REDIS_PASSWORD"),
+		PasswordFile:          Env("REDIS_PASSWORD_FILE", ""),
+		DB:                    intEnv("REDIS_DB", 0),
+		MasterName:            Env("REDIS_SENTINEL_MASTER", ""),
+		SentinelUsername:      Env("REDIS_SENTINEL_USERNAME", ""),
+		SentinelPassword:      os.Getenv("REDIS_SENTINEL_PASSWORD"),
+		TLS:                   Env("REDIS_TLS", "false") == "true",
+		TLSServerName:         Env("REDIS_TLS_SERVER_NAME", ""),
+		TLSCAFile:             Env("REDIS_TLS_CA_FILE", ""),
+		TLSInsecureSkipVerify: Env("REDIS_TLS_INSECURE_SKIP_VERIFY", "false") == "true",
+	}
+}
+
+// pingWithTimeout bounds each connectivity check so a dependency that
+// accepts connections but never answers still counts as a failed attempt.
+func pingWithTimeout(ping func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		return ping(ctx)
+	}
+}
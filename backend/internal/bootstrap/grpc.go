@@ -0,0 +1,47 @@
+package bootstrap
+
+import (
+	"context"
+	"net"
+
+	"gala/internal/events"
+	"gala/internal/grpcapi"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/shutdown"
+)
+
+// startGRPC serves the gRPC services on port in the background, next to
+// the HTTP server, and registers their graceful shutdown.
+func startGRPC(log *logger.Logger, infra *Infra, ev *events.Bus, port string, shutdownMgr *shutdown.Manager) {
+	ln, err := net.Listen("tcp", "0.0.0.0:"+port)
+	if err != nil {
+		log.LogFatal("gRPC listen failed", err, "port", port)
+	}
+
+	server := grpcapi.NewServer(grpcapi.Deps{
+		Pool:          infra.Pool,
+		DB:            infra.DB,
+		RDB:           infra.RDB,
+		SP:            infra.SP,
+		Log:           log,
+		QueueMode:     queueMode(log),
+		WatchInterval: durationEnv("GRPC_WATCH_INTERVAL", grpcapi.DefaultWatchInterval),
+		Events:        ev,
+		DedupWindow:   durationEnv("JOB_DEDUP_WINDOW", 0),
+	})
+
+	// WatchJob streams only end with their job, so the hard stop at the
+	// deadline is what closes them
+	shutdownMgr.Register("grpc-server", func(ctx context.Context) error {
+		log.Info("shutting down gRPC server")
+		server.SetNotServing()
+		return server.GracefulStopContext(ctx)
+	})
+
+	go func() {
+		log.Info("gRPC server listening", "addr", ln.Addr().String(), "port", port)
+		if err := server.Serve(ln); err != nil {
+			log.LogFatal("gRPC server failed", err)
+		}
+	}()
+}
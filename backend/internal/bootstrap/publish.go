@@ -0,0 +1,148 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+
+	"gala/internal/adapters/publish/cdn"
+	"gala/internal/adapters/publish/s3"
+	"gala/internal/adapters/publish/youtube"
+	"gala/internal/events"
+	"gala/internal/pkg/awsv4"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/shutdown"
+	"gala/internal/ports"
+	"gala/internal/publish"
+)
+
+// startPublisher creates the job publication service with the targets
+// that have credentials, resumes the publications left pending by the
+// previous run and registers its shutdown (interrupted uploads go back to
+// PENDING).
+//
+// YouTube is enabled by YOUTUBE_CLIENT_ID, YOUTUBE_CLIENT_SECRET and
+// YOUTUBE_REFRESH_TOKEN (or YOUTUBE_REFRESH_TOKEN_FILE), the OAuth
+// credentials of the one channel every job is published to. The s3 target
+// is enabled by PUBLISH_S3_BUCKET (see s3Target).
+func startPublisher(log *logger.Logger, infra *Infra, ev *events.Bus, shutdownMgr *shutdown.Manager) *publish.Service {
+	var targets []ports.PublishTarget
+	if Env("YOUTUBE_CLIENT_ID", "") != "" {
+		yt, err := youtubeTarget()
+		if err != nil {
+			log.LogFatal("invalid YouTube configuration", err)
+		}
+		targets = append(targets, yt)
+	}
+	if Env("PUBLISH_S3_BUCKET", "") != "" {
+		t, err := s3Target()
+		if err != nil {
+			log.LogFatal("invalid S3 publish configuration", err)
+		}
+		targets = append(targets, t)
+	}
+
+	svc := publish.New(publish.Deps{
+		Pool:          infra.Pool,
+		SP:            infra.SP,
+		Targets:       targets,
+		Events:        ev,
+		Log:           log,
+		UploadTimeout: durationEnv("PUBLISH_UPLOAD_TIMEOUT", publish.DefaultUploadTimeout),
+		Concurrency:   intEnv("PUBLISH_CONCURRENCY", publish.DefaultConcurrency),
+	})
+	if len(targets) > 0 {
+		log.Info("publish targets enabled", "targets", strings.Join(svc.Targets(), ","))
+	}
+
+	shutdownMgr.Register("publisher", func(ctx context.Context) error {
+		return svc.Shutdown(ctx)
+	})
+	if err := svc.Resume(shutdownMgr.Context()); err != nil {
+		// Not fatal: they are resumed on the next start
+		log.Error("failed to resume publications", "error", err.Error())
+	}
+	return svc
+}
+
+// youtubeTarget builds the YouTube client from the YOUTUBE_* variables.
+func youtubeTarget() (*youtube.Client, error) {
+	secret := Env("YOUTUBE_CLIENT_SECRET", "")
+	if secret == "" {
+		return nil, fmt.Errorf("YOUTUBE_CLIENT_SECRET is required")
+	}
+	refresh := Env("YOUTUBE_REFRESH_TOKEN", "")
+	if refresh == "" {
+		path := Env("YOUTUBE_REFRESH_TOKEN_FILE", "")
+		if path == "" {
+			return nil, fmt.Errorf("YOUTUBE_REFRESH_TOKEN or YOUTUBE_REFRESH_TOKEN_FILE is required")
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("youtube refresh token file: %w", err)
+		}
+		if refresh = strings.TrimSpace(string(b)); refresh == "" {
+			return nil, fmt.Errorf("youtube refresh token file is empty: %s", path)
+		}
+	}
+
+	conf := &oauth2.Config{
+		ClientID:     Env("YOUTUBE_CLIENT_ID", ""),
+		ClientSecret: secret,
+		Endpoint:     google.Endpoint,
+		Scopes:       []string{youtube.UploadScope},
+	}
+	httpClient := conf.Client(context.Background(), &oauth2.Token{RefreshToken: refresh})
+	return youtube.NewClient(httpClient, Env("YOUTUBE_PRIVACY", "private"), Env("YOUTUBE_CATEGORY_ID", "")), nil
+}
+
+// s3Target builds the s3 target: renders go to PUBLISH_S3_BUCKET (in
+// PUBLISH_S3_REGION, or at PUBLISH_S3_ENDPOINT for S3-compatible stores)
+// under PUBLISH_S3_PREFIX, are served from PUBLISH_PUBLIC_BASE_URL, and
+// are invalidated on the CDN set by PUBLISH_CDN (cloudfront with
+// CLOUDFRONT_DISTRIBUTION_ID, fastly with FASTLY_API_TOKEN, or none).
+// Credentials are the usual AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN.
+func s3Target() (*s3.Client, error) {
+	creds := awsv4.Credentials{
+		AccessKeyID:     Env("AWS_ACCESS_KEY_ID", ""),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	cfg := s3.Config{
+		Bucket:        Env("PUBLISH_S3_BUCKET", ""),
+		Region:        Env("PUBLISH_S3_REGION", Env("AWS_REGION", "us-east-1")),
+		Endpoint:      Env("PUBLISH_S3_ENDPOINT", ""),
+		Prefix:        Env("PUBLISH_S3_PREFIX", "renders"),
+		PublicBaseURL: Env("PUBLISH_PUBLIC_BASE_URL", ""),
+		CacheControl:  Env("PUBLISH_S3_CACHE_CONTROL", "public, max-age=86400"),
+		Credentials:   creds,
+	}
+
+	switch mode := Env("PUBLISH_CDN", "none"); mode {
+	case "none":
+	case "cloudfront":
+		id := Env("CLOUDFRONT_DISTRIBUTION_ID", "")
+		if id == "" {
+			return nil, fmt.Errorf("CLOUDFRONT_DISTRIBUTION_ID is required with PUBLISH_CDN=cloudfront")
+		}
+		cfg.Invalidator = cdn.NewCloudFront(nil, creds, id)
+	case "fastly":
+		token := Env("FASTLY_API_TOKEN", "")
+		if token == "" || cfg.PublicBaseURL == "" {
+			return nil, fmt.Errorf("FASTLY_API_TOKEN and PUBLISH_PUBLIC_BASE_URL are required with PUBLISH_CDN=fastly")
+		}
+		f, err := cdn.NewFastly(nil, token, cfg.PublicBaseURL)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Invalidator = f
+	default:
+		return nil, fmt.Errorf("PUBLISH_CDN %s is not none, cloudfront or fastly", mode)
+	}
+	return s3.NewClient(nil, cfg)
+}
@@ -0,0 +1,312 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gala/internal/admin"
+	"gala/internal/callback"
+	"gala/internal/pkg/fetch"
+	"gala/internal/pkg/leader"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/reload"
+	"gala/internal/pkg/shutdown"
+	"gala/internal/worker"
+	"gala/internal/worker/processor"
+	"gala/internal/worker/queue"
+	"gala/internal/worker/renderer"
+	"gala/internal/worker/staging"
+)
+
+// StartWorker starts the job loop in the background and registers its
+// shutdown.
+func StartWorker(ctx context.Context, log *logger.Logger, infra *Infra, reloadMgr *reload.Manager, shutdownMgr *shutdown.Manager) {
+	// Load configuration
+	rendererBaseURL := mustEnv(log, "RENDERER_HTTP_BASEURL")
+	storageRoot := Env("STORAGE_LOCAL_ROOT", "/data")
+	queueName := Env("JOB_QUEUE_NAME", queue.DefaultName)
+	queueMode := queueMode(log)
+	// Postgres mode fallback only: LISTEN/NOTIFY wakes the worker on new jobs
+	queuePoll := durationEnv("QUEUE_POLL_INTERVAL", 5*time.Second)
+	queuePopTimeout := durationEnv("QUEUE_POP_TIMEOUT", queue.DefaultPopTimeout)
+	if queuePopTimeout < time.Second {
+		// BRPOP counts its timeout in whole seconds (0 would block forever)
+		log.LogFatal("invalid QUEUE_POP_TIMEOUT, must be at least 1s", nil, "value", queuePopTimeout.String())
+	}
+	cleanupLocal := boolEnv("WORKER_CLEANUP_LOCAL", false)
+	jobTimeout := durationEnv("WORKER_JOB_TIMEOUT", 0)
+	workerID := Env("WORKER_ID", "")
+	inputConcurrency := intEnv("WORKER_INPUT_CONCURRENCY", processor.DefaultInputConcurrency)
+	if inputConcurrency < 1 {
+		log.LogFatal("invalid WORKER_INPUT_CONCURRENCY", nil, "value", inputConcurrency)
+	}
+	uploadConcurrency := intEnv("WORKER_UPLOAD_CONCURRENCY", processor.DefaultUploadConcurrency)
+	uploadAttempts := intEnv("WORKER_UPLOAD_ATTEMPTS", processor.DefaultUploadAttempts)
+	if uploadConcurrency < 1 || uploadAttempts < 1 {
+		log.LogFatal("invalid WORKER_UPLOAD_CONCURRENCY or WORKER_UPLOAD_ATTEMPTS", nil,
+			"concurrency", uploadConcurrency, "attempts", uploadAttempts)
+	}
+	templateCacheTTL := durationEnv("WORKER_TEMPLATE_CACHE_TTL", processor.DefaultTemplateCacheTTL)
+	// Staging area checks; its gauges are only exported where an API
+	// serves /metrics (infra.Metrics is nil otherwise)
+	stagingConfig := staging.Config{
+		Root:         storageRoot,
+		MinFreeBytes: int64(intEnv("WORKER_STAGING_MIN_FREE_MB", 512)) << 20,
+		MaxBytes:     int64(intEnv("WORKER_STAGING_MAX_MB", 0)) << 20,
+		MinAge:       durationEnv("WORKER_STAGING_MIN_AGE", staging.DefaultMinAge),
+	}
+	if stagingConfig.MinFreeBytes < 0 || stagingConfig.MaxBytes < 0 {
+		log.LogFatal("invalid WORKER_STAGING_MIN_FREE_MB or WORKER_STAGING_MAX_MB", nil)
+	}
+	stagingSweep := durationEnv("WORKER_STAGING_SWEEP_INTERVAL", staging.DefaultInterval)
+	rendererAuthConfig := RendererAuthConfig()
+	breakerThreshold := intEnv("RENDERER_BREAKER_THRESHOLD", renderer.DefaultBreakerThreshold)
+	if breakerThreshold < 1 {
+		log.LogFatal("invalid RENDERER_BREAKER_THRESHOLD, must be at least 1", nil, "value", breakerThreshold)
+	}
+	readyInterval := durationEnv("WORKER_READY_INTERVAL", worker.DefaultReadyInterval)
+	hls := processor.HLSConfig{
+		Enabled:        boolEnv("HLS_ENABLED", false),
+		FFmpegPath:     Env("HLS_FFMPEG_PATH", "ffmpeg"),
+		SegmentSeconds: intEnv("HLS_SEGMENT_SECONDS", 6),
+		Transcode:      boolEnv("HLS_TRANSCODE", false),
+		Timeout:        durationEnv("HLS_TIMEOUT", 10*time.Minute),
+	}
+	formatCheck := formatCheckConfig()
+	switch formatCheck.Mode {
+	case processor.FormatCheckOff, processor.FormatCheckWarn, processor.FormatCheckFail:
+	default:
+		log.LogFatal("invalid RENDER_FORMAT_CHECK (want off, warn or fail)", nil, "value", formatCheck.Mode)
+	}
+
+	rendererAuth, err := renderer.NewAuthenticator(rendererAuthConfig)
+	if err != nil {
+		log.LogFatal("invalid renderer auth configuration", err)
+	}
+
+	// Create worker dependencies
+	deps := worker.Deps{
+		Pool:              infra.Pool,
+		RDB:               infra.RDB,
+		RendererBaseURL:   rendererBaseURL,
+		RendererAuth:      rendererAuth,
+		ProgressBaseURL:   Env("RENDERER_PROGRESS_BASEURL", ""),
+		StorageRoot:       storageRoot,
+		QueueName:         queueName,
+		QueueMode:         queueMode,
+		QueuePollInterval: queuePoll,
+		QueuePopTimeout:   queuePopTimeout,
+		QueueIdleBackoff:  durationEnv("QUEUE_IDLE_BACKOFF", worker.DefaultIdleBackoff),
+		WorkerID:          workerID,
+		HeartbeatInterval: durationEnv("WORKER_HEARTBEAT_INTERVAL", 0),
+		CleanupLocal:      cleanupLocal,
+		JobTimeout:        jobTimeout,
+		HLS:               hls,
+		FormatCheck:       formatCheck,
+		Fetch:             fetch.New(fetchConfig()),
+		Callbacks:         callback.New(callbackConfig()),
+		InputConcurrency:  inputConcurrency,
+		UploadConcurrency: uploadConcurrency,
+		UploadAttempts:    uploadAttempts,
+		TemplateCacheTTL:  templateCacheTTL,
+		Staging:           staging.New(stagingConfig, log, infra.Metrics),
+		StagingSweep:      stagingSweep,
+		Reload:            reloadMgr,
+		SP:                infra.SP,
+		Events:            eventBus(log, infra),
+		Log:               log,
+
+		// Readiness gate on the renderer and the storage
+		RendererBreakerThreshold: breakerThreshold,
+		ReadyInterval:            readyInterval,
+
+		// Renderer faults of the chaos mode, if enabled
+		Chaos: infra.Chaos,
+	}
+
+	log.Info("worker configuration",
+		"queue", queueName,
+		"queue_mode", queueMode,
+		"queue_pop_timeout", queuePopTimeout.String(),
+		"renderer_url", rendererBaseURL,
+		"renderer_auth", rendererAuthConfig.Mode,
+		"renderer_breaker_threshold", breakerThreshold,
+		"ready_interval", readyInterval.String(),
+		"storage_root", storageRoot,
+		"cleanup_local", cleanupLocal,
+		"job_timeout", jobTimeout.String(),
+		"hls", hls.Enabled,
+		"input_concurrency", inputConcurrency,
+		"upload_concurrency", uploadConcurrency,
+		"upload_attempts", uploadAttempts,
+		"template_cache_ttl", templateCacheTTL.String(),
+		"staging_min_free_mb", stagingConfig.MinFreeBytes>>20,
+		"staging_max_mb", stagingConfig.MaxBytes>>20,
+		"staging_sweep_interval", stagingSweep.String(),
+	)
+
+	startReaper(log, infra, jobTimeout, shutdownMgr)
+	startPartitionMaintainer(log, infra, shutdownMgr)
+	startOutputVerifier(log, infra, admin.New(infra.Pool, infra.RDB, infra.SP, deps.Events, queueName, queueMode == queue.ModeRedis), shutdownMgr)
+	startScheduler(log, infra, deps, shutdownMgr)
+
+	// stop ends queue pops; canceling jobCtx abandons the job in flight
+	stop := make(chan struct{})
+	jobCtx, abandonJob := context.WithCancel(ctx)
+	finished := make(chan struct{})
+
+	// Register worker shutdown: finish the current job before the pools
+	// close, abandoning it only if the shutdown timeout runs out
+	shutdownMgr.Register("worker", func(ctx context.Context) error {
+		log.Info("stopping worker, finishing job in flight")
+		close(stop)
+		select {
+		case <-finished:
+			return nil
+		case <-ctx.Done():
+			abandonJob()
+			return fmt.Errorf("job in flight abandoned: %w", ctx.Err())
+		}
+	})
+
+	// Start worker in goroutine
+	go func() {
+		defer close(finished)
+		log.Info("worker started, waiting for jobs")
+		if err := worker.Run(jobCtx, stop, deps); err != nil {
+			if err != context.Canceled {
+				log.Error("worker error", "error", err.Error())
+			}
+		}
+	}()
+}
+
+// queueMode reads QUEUE_MODE (redis or postgres). The API and the workers
+// must agree on it: in postgres mode the API no longer pushes to Redis.
+func queueMode(log *logger.Logger) string {
+	mode := Env("QUEUE_MODE", queue.ModeRedis)
+	if mode != queue.ModeRedis && mode != queue.ModePostgres {
+		log.LogFatal("invalid QUEUE_MODE", nil, "mode", mode)
+	}
+	return mode
+}
+
+// startReaper runs the stale job reaper on one worker replica (elected via
+// advisory lock). WORKER_STALE_JOB_AFTER sets when a RUNNING job counts as
+// abandoned; it defaults to WORKER_JOB_TIMEOUT plus a margin, and the
+// reaper is off when neither is set, since jobs may then run forever.
+func startReaper(log *logger.Logger, infra *Infra, jobTimeout time.Duration, shutdownMgr *shutdown.Manager) {
+	staleAfter := durationEnv("WORKER_STALE_JOB_AFTER", 0)
+	if staleAfter <= 0 && jobTimeout > 0 {
+		staleAfter = jobTimeout + 5*time.Minute
+	}
+	if staleAfter <= 0 {
+		log.Info("stale job reaper disabled", "reason", "no WORKER_STALE_JOB_AFTER or WORKER_JOB_TIMEOUT")
+		return
+	}
+	interval := durationEnv("WORKER_REAPER_INTERVAL", time.Minute)
+
+	reap := leader.Every(log, interval, func(ctx context.Context) error {
+		_, err := worker.ReapStaleJobs(ctx, infra.Pool, log, staleAfter)
+		return err
+	})
+	startSingleton(log, infra, shutdownMgr, "stale-job-reaper", reap)
+
+	log.Info("stale job reaper enabled", "stale_after", staleAfter.String(), "interval", interval.String())
+}
+
+// startPartitionMaintainer keeps the monthly jobs partitions created
+// WORKER_PARTITION_MONTHS_AHEAD months ahead, checking every
+// WORKER_PARTITION_INTERVAL.
+func startPartitionMaintainer(log *logger.Logger, infra *Infra, shutdownMgr *shutdown.Manager) {
+	monthsAhead := intEnv("WORKER_PARTITION_MONTHS_AHEAD", 3)
+	interval := durationEnv("WORKER_PARTITION_INTERVAL", 24*time.Hour)
+
+	maintain := leader.Every(log, interval, func(ctx context.Context) error {
+		_, err := worker.EnsureJobPartitions(ctx, infra.Pool, log, monthsAhead)
+		return err
+	})
+	startSingleton(log, infra, shutdownMgr, "job-partitions", maintain)
+}
+
+// startOutputVerifier checks, every WORKER_VERIFY_INTERVAL (0 = off) on one
+// replica, WORKER_VERIFY_SAMPLE outputs of the jobs finished within
+// WORKER_VERIFY_WINDOW against storage; WORKER_VERIFY_CHECKSUMS also reads
+// them to compare their checksums.
+func startOutputVerifier(log *logger.Logger, infra *Infra, svc *admin.Service, shutdownMgr *shutdown.Manager) {
+	interval := durationEnv("WORKER_VERIFY_INTERVAL", time.Hour)
+	if interval <= 0 {
+		log.Info("output verifier disabled")
+		return
+	}
+	opts := admin.VerifyOptions{
+		Sample:    intEnv("WORKER_VERIFY_SAMPLE", 20),
+		Window:    durationEnv("WORKER_VERIFY_WINDOW", 7*24*time.Hour),
+		Checksums: boolEnv("WORKER_VERIFY_CHECKSUMS", false),
+	}
+	if opts.Sample < 1 || opts.Window <= 0 {
+		log.LogFatal("invalid WORKER_VERIFY_SAMPLE or WORKER_VERIFY_WINDOW", nil,
+			"sample", opts.Sample, "window", opts.Window.String())
+	}
+
+	verify := leader.Every(log, interval, func(ctx context.Context) error {
+		rep, err := svc.VerifyOutputs(ctx, log, opts)
+		if err != nil {
+			return err
+		}
+		if len(rep.Failed) > 0 || rep.Skipped > 0 {
+			log.Warn("output verification found issues",
+				"checked", rep.Checked, "failed", len(rep.Failed), "skipped", rep.Skipped)
+		} else {
+			log.Debug("outputs verified", "checked", rep.Checked)
+		}
+		return nil
+	})
+	startSingleton(log, infra, shutdownMgr, "output-verifier", verify)
+
+	log.Info("output verifier enabled", "interval", interval.String(),
+		"sample", opts.Sample, "window", opts.Window.String(), "checksums", opts.Checksums)
+}
+
+// startScheduler queues, on one worker replica, the SCHEDULED jobs that
+// are due and the jobs put off while their template had no free slot,
+// checking every JOB_SCHEDULE_INTERVAL.
+func startScheduler(log *logger.Logger, infra *Infra, d worker.Deps, shutdownMgr *shutdown.Manager) {
+	interval := durationEnv("JOB_SCHEDULE_INTERVAL", queue.DefaultScheduleInterval)
+	s := queue.NewScheduler(infra.Pool, infra.RDB, d.Events, log, d.QueueName, d.QueueMode, interval)
+	startSingleton(log, infra, shutdownMgr, "job-scheduler", s.Run)
+}
+
+// startSingleton runs fn on one replica via leader.Run, stepping down
+// (releasing the lock) while draining, before the pool closes.
+func startSingleton(log *logger.Logger, infra *Infra, shutdownMgr *shutdown.Manager, name string, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		leader.Run(ctx, infra.Pool, log, name, durationEnv("LEADER_RETRY_INTERVAL", leader.DefaultRetry), fn)
+	}()
+
+	shutdownMgr.Register(name, func(ctx context.Context) error {
+		cancel()
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// formatCheckConfig reads the render format check settings.
+func formatCheckConfig() processor.FormatCheckConfig {
+	return processor.FormatCheckConfig{
+		Mode:              strings.ToLower(Env("RENDER_FORMAT_CHECK", processor.FormatCheckWarn)),
+		FFprobePath:       Env("FFPROBE_PATH", "ffprobe"),
+		SizeTolerance:     intEnv("RENDER_FORMAT_SIZE_TOLERANCE", 0),
+		DurationTolerance: durationEnv("RENDER_FORMAT_DURATION_TOLERANCE", time.Second),
+		Timeout:           durationEnv("RENDER_FORMAT_PROBE_TIMEOUT", 30*time.Second),
+	}
+}
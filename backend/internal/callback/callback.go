@@ -0,0 +1,200 @@
+// Package callback posts the completion of a job to the callback_url given
+// when it was created. The payload is JSON and, when the job has a
+// callback_secret, is signed like the worker signs renderer requests:
+//
+//	X-Gala-Timestamp: <unix seconds>
+//	X-Gala-Signature: hex(HMAC-SHA256(secret, timestamp + "." + body))
+//
+// Receivers recompute the signature over the raw body and should reject
+// old timestamps to stop replays.
+package callback
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gala/internal/pkg/fetch"
+)
+
+// Headers of a callback request.
+const (
+	HeaderEvent     = "X-Gala-Event"
+	HeaderTimestamp = "X-Gala-Timestamp"
+	HeaderSignature = "X-Gala-Signature"
+)
+
+// Defaults of Config.
+const (
+	DefaultTimeout     = 10 * time.Second
+	DefaultMaxAttempts = 3
+)
+
+// ErrStatus: the receiver did not answer 2xx.
+var ErrStatus = errors.New("unexpected response status")
+
+// Config sets how a Sender delivers.
+type Config struct {
+	// Timeout bounds each attempt (0 = DefaultTimeout).
+	Timeout time.Duration
+	// MaxAttempts is how many times a callback is tried before giving up
+	// (0 = DefaultMaxAttempts). Attempts are 1s, 2s, 4s... apart.
+	MaxAttempts int
+	// AllowPrivate lets callbacks reach private and loopback addresses,
+	// for development setups.
+	AllowPrivate bool
+}
+
+// Payload is the body posted when a job finishes.
+type Payload struct {
+	Event      string     `json:"event"`
+	JobID      string     `json:"job_id"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	Outputs    []Output   `json:"outputs"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Output is a rendered variant of the job, as GET /jobs/{id} lists it.
+type Output struct {
+	Variant          int    `json:"variant"`
+	VideoAssetID     string `json:"video_asset_id"`
+	ThumbnailAssetID string `json:"thumbnail_asset_id,omitempty"`
+	CaptionsAssetID  string `json:"captions_asset_id,omitempty"`
+}
+
+// Attempt is the outcome of a delivery attempt.
+type Attempt struct {
+	// N counts the attempts of a Send from 1.
+	N int
+	// StatusCode is the receiver's answer, 0 if none came.
+	StatusCode int
+	Duration   time.Duration
+	// Err is nil when the attempt delivered the callback.
+	Err error
+}
+
+// Sender posts callbacks.
+type Sender struct {
+	cfg  Config
+	http *http.Client
+	// sleep waits between attempts; tests replace it.
+	sleep func(context.Context, time.Duration) error
+}
+
+// New creates a sender. Callback URLs are checked like job inputs given by
+// URL: https only and, unless cfg.AllowPrivate, no internal addresses.
+func New(cfg Config) *Sender {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	return &Sender{
+		cfg: cfg,
+		http: &http.Client{
+			Transport: fetch.Transport(cfg.AllowPrivate),
+			// A redirect could lead anywhere; receivers answer directly
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		sleep: sleep,
+	}
+}
+
+// MaxDuration is the longest a Send can take: every attempt timing out,
+// plus the waits between them.
+func (s *Sender) MaxDuration() time.Duration {
+	n := s.cfg.MaxAttempts
+	return time.Duration(n)*s.cfg.Timeout + time.Duration(1<<(n-1)-1)*time.Second
+}
+
+// Sign computes the hex HMAC-SHA256 signature of a callback body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send posts body to url, retrying failed attempts up to MaxAttempts.
+// attempt is called after each one, so the caller can record them. It
+// returns the last error.
+func (s *Sender) Send(ctx context.Context, url, secret, event string, body []byte, attempt func(Attempt)) error {
+	if _, err := fetch.ParseURL(url); err != nil {
+		attempt(Attempt{N: 1, Err: err})
+		return err
+	}
+
+	var err error
+	for i := 0; i < s.cfg.MaxAttempts; i++ {
+		if i > 0 {
+			if err := s.sleep(ctx, time.Duration(1<<(i-1))*time.Second); err != nil {
+				return err
+			}
+		}
+		start := time.Now()
+		var code int
+		code, err = s.post(ctx, url, secret, event, body)
+		attempt(Attempt{N: i + 1, StatusCode: code, Duration: time.Since(start), Err: err})
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// post makes one attempt and returns the receiver's status code, 0 if it
+// did not answer.
+func (s *Sender) post(ctx context.Context, url, secret, event string, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event)
+	if secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderTimestamp, ts)
+		req.Header.Set(HeaderSignature, Sign(secret, ts, body))
+	}
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		if errors.Is(err, fetch.ErrAddress) {
+			return 0, fetch.ErrAddress
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("%w: %d", ErrStatus, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
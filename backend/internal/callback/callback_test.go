@@ -0,0 +1,135 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gala/internal/pkg/fetch"
+)
+
+// newTestSender returns a sender that trusts srv, may reach it on the
+// loopback address and does not wait between attempts.
+func newTestSender(srv *httptest.Server, cfg Config) *Sender {
+	cfg.AllowPrivate = true
+	s := New(cfg)
+	s.http.Transport.(*http.Transport).TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+	s.sleep = func(context.Context, time.Duration) error { return nil }
+	return s
+}
+
+func TestSendSigns(t *testing.T) {
+	body := []byte(`{"event":"job.done","job_id":"job_1"}`)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		if string(got) != string(body) {
+			t.Errorf("body = %s", got)
+		}
+		if e := r.Header.Get(HeaderEvent); e != "job.done" {
+			t.Errorf("event = %q", e)
+		}
+		ts := r.Header.Get(HeaderTimestamp)
+		if want := Sign("s3cret", ts, got); r.Header.Get(HeaderSignature) != want {
+			t.Errorf("signature = %q, want %q", r.Header.Get(HeaderSignature), want)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var attempts []Attempt
+	err := newTestSender(srv, Config{}).Send(context.Background(), srv.URL, "s3cret", "job.done", body, func(a Attempt) {
+		attempts = append(attempts, a)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 1 || attempts[0].Err != nil || attempts[0].StatusCode != http.StatusNoContent {
+		t.Errorf("attempts = %v, want one successful", attempts)
+	}
+}
+
+func TestSendUnsignedWithoutSecret(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderSignature) != "" || r.Header.Get(HeaderTimestamp) != "" {
+			t.Error("unsigned callback has signature headers")
+		}
+	}))
+	defer srv.Close()
+
+	if err := newTestSender(srv, Config{}).Send(context.Background(), srv.URL, "", "job.failed", []byte(`{}`), func(Attempt) {}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	var attempts []Attempt
+	err := newTestSender(srv, Config{MaxAttempts: 3}).Send(context.Background(), srv.URL, "", "job.done", []byte(`{}`), func(a Attempt) {
+		attempts = append(attempts, a)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 3 || !errors.Is(attempts[0].Err, ErrStatus) || attempts[2].Err != nil {
+		t.Errorf("attempts = %v", attempts)
+	}
+	if a := attempts[0]; a.N != 1 || a.StatusCode != http.StatusBadGateway {
+		t.Errorf("first attempt = %+v, want N 1 with 502", a)
+	}
+	if a := attempts[2]; a.N != 3 || a.StatusCode != http.StatusOK {
+		t.Errorf("last attempt = %+v, want N 3 with 200", a)
+	}
+
+	calls.Store(-10)
+	attempts = nil
+	err = newTestSender(srv, Config{MaxAttempts: 2}).Send(context.Background(), srv.URL, "", "job.done", []byte(`{}`), func(a Attempt) {
+		attempts = append(attempts, a)
+	})
+	if !errors.Is(err, ErrStatus) || len(attempts) != 2 {
+		t.Errorf("err = %v, attempts = %v; want ErrStatus after 2", err, attempts)
+	}
+}
+
+func TestSendRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("private address was called")
+	}))
+	defer srv.Close()
+
+	s := New(Config{MaxAttempts: 1})
+	err := s.Send(context.Background(), srv.URL, "", "job.done", []byte(`{}`), func(Attempt) {})
+	if !errors.Is(err, fetch.ErrAddress) {
+		t.Errorf("err = %v, want fetch.ErrAddress", err)
+	}
+	err = s.Send(context.Background(), "http://example.com/hook", "", "job.done", []byte(`{}`), func(Attempt) {})
+	if !errors.Is(err, fetch.ErrURL) {
+		t.Errorf("err = %v, want fetch.ErrURL", err)
+	}
+}
+
+func TestMaxDuration(t *testing.T) {
+	for _, tt := range []struct {
+		cfg  Config
+		want time.Duration
+	}{
+		{Config{}, 33 * time.Second},
+		{Config{MaxAttempts: 1, Timeout: 5 * time.Second}, 5 * time.Second},
+		{Config{MaxAttempts: 4, Timeout: time.Second}, 11 * time.Second},
+	} {
+		if got := New(tt.cfg).MaxDuration(); got != tt.want {
+			t.Errorf("MaxDuration(%+v) = %v, want %v", tt.cfg, got, tt.want)
+		}
+	}
+}
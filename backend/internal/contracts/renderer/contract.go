@@ -0,0 +1,133 @@
+// Package renderer holds the published contract between the worker and the
+// renderer: JSON Schemas of the spec each endpoint version takes (v0:
+// POST /render, v1: POST /render/v1) and of the responses it answers with.
+// The worker's specs and recorded renderer responses are checked against
+// them in tests and with galactl contracts verify, so a change on either
+// side that breaks the other fails before it is deployed.
+package renderer
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gala/internal/pkg/jsonschema"
+)
+
+// Versions of the renderer endpoints.
+const (
+	V0 = "v0"
+	V1 = "v1"
+)
+
+// Versions are the versions with a published contract.
+var Versions = []string{V0, V1}
+
+//go:embed schemas/*.json
+var schemaFS embed.FS
+
+var (
+	requests = map[string]*jsonschema.Schema{
+		V0: mustLoad("schemas/v0.request.json"),
+		V1: mustLoad("schemas/v1.request.json"),
+	}
+	responses = map[string]*jsonschema.Schema{
+		V0: mustLoad("schemas/v0.response.json"),
+		V1: mustLoad("schemas/v1.response.json"),
+	}
+	errorResponse = mustLoad("schemas/error.response.json")
+)
+
+func mustLoad(name string) *jsonschema.Schema {
+	data, err := schemaFS.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	return jsonschema.MustParse(data)
+}
+
+// ValidateSpec checks a spec of version against its schema. spec is any
+// value that encodes to the spec's JSON, e.g. a processor.RenderSpec.Spec.
+func ValidateSpec(version string, spec any) error {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("encode spec: %w", err)
+	}
+	return ValidateSpecJSON(version, data)
+}
+
+// ValidateSpecJSON is ValidateSpec for an encoded spec.
+func ValidateSpecJSON(version string, data []byte) error {
+	s, ok := requests[version]
+	if !ok {
+		return fmt.Errorf("unknown renderer contract version %q", version)
+	}
+	errs, err := s.ValidateJSON(data)
+	if err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+	return jsonschema.Join(errs)
+}
+
+// ValidateResponse checks a renderer response body of version with the
+// given status: the version's success schema for 200, the error schema for
+// 4xx and 5xx.
+func ValidateResponse(version string, status int, body []byte) error {
+	s, ok := responses[version]
+	if !ok {
+		return fmt.Errorf("unknown renderer contract version %q", version)
+	}
+	switch {
+	case status == http.StatusOK:
+	case status >= 400 && status <= 599:
+		s = errorResponse
+	default:
+		return fmt.Errorf("unexpected status %d", status)
+	}
+	errs, err := s.ValidateJSON(body)
+	if err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+	return jsonschema.Join(errs)
+}
+
+// ValidateStoredSpec checks a spec as the worker stores it with the job
+// (jobs.render_spec): {"version": "v1", "spec": {...}}.
+func ValidateStoredSpec(data []byte) error {
+	var stored struct {
+		Version string          `json:"version"`
+		Spec    json.RawMessage `json:"spec"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+	return ValidateSpecJSON(stored.Version, stored.Spec)
+}
+
+// SpecFileVersion returns the version of a spec file as the renderer
+// saves them under SPECS_DIR, "<job_id>.<version>.json".
+func SpecFileVersion(name string) (string, bool) {
+	base := strings.TrimSuffix(filepath.Base(name), ".json")
+	v := base[strings.LastIndex(base, ".")+1:]
+	return v, v != base && slices.Contains(Versions, v)
+}
+
+// ResponseFileName parses the name of a recorded renderer response,
+// "<version>.<status>[.<label>].json", e.g. "v1.400.json" or
+// "v1.200.watermark.json".
+func ResponseFileName(name string) (version string, status int, ok bool) {
+	parts := strings.Split(strings.TrimSuffix(filepath.Base(name), ".json"), ".")
+	if len(parts) < 2 || !slices.Contains(Versions, parts[0]) {
+		return "", 0, false
+	}
+	status, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, false
+	}
+	return parts[0], status, true
+}
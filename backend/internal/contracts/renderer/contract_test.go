@@ -0,0 +1,172 @@
+package renderer_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	contract "gala/internal/contracts/renderer"
+	"gala/internal/watermark"
+	"gala/internal/worker/processor"
+)
+
+// specs are the specs the worker builds for each kind of job.
+func specs() map[string]processor.RenderSpec {
+	adapter := processor.NewRendererAdapter(nil)
+	opacity := 0.5
+
+	legacy := &processor.ParsedJob{MergedParams: map[string]any{"text": "hola"}}
+	v1 := &processor.ParsedJob{
+		TemplateID:   "tpl-1",
+		Inputs:       map[string]string{"avatar_image_asset_id": "asset-1"},
+		Params:       map[string]any{"text": "hola"},
+		MergedParams: map[string]any{"text": "hola", "duration": 7.0},
+		HasEnvelope:  true,
+	}
+	full := &processor.ParsedJob{
+		TemplateID: "tpl-1",
+		Inputs: map[string]string{
+			"avatar_image_asset_id": "asset-1",
+			"voice_audio_asset_id":  "asset-2",
+			"captions_asset_id":     "asset-3",
+		},
+		MergedParams: map[string]any{"text": "hola", "captions": true},
+		HasEnvelope:  true,
+		Watermark:    &watermark.Config{AssetID: "asset-4", Position: "top-left", Opacity: &opacity},
+	}
+
+	req := func(id string, j *processor.ParsedJob, inputs map[string]string, wm string) processor.RenderRequest {
+		return processor.RenderRequest{
+			JobID:         id,
+			ParsedJob:     j,
+			InputPaths:    inputs,
+			OutputKeys:    processor.GenerateOutputKeys(id, j.CaptionsEnabled()),
+			WatermarkPath: wm,
+		}
+	}
+	progress := func(r processor.RenderRequest) processor.RenderRequest {
+		r.ProgressURL = "http://api:8080/v1/jobs/" + r.JobID + "/progress"
+		return r
+	}
+	return map[string]processor.RenderSpec{
+		"v0 legacy":            adapter.Spec(req("job-0", legacy, nil, "")),
+		"v0 with progress url": adapter.Spec(progress(req("job-3", legacy, nil, ""))),
+		"v1 with progress url": adapter.Spec(progress(req("job-4", v1, map[string]string{
+			"avatar_image_asset_id": "/data/jobs/job-4/inputs/avatar.png",
+		}, ""))),
+		"v1": adapter.Spec(req("job-1", v1, map[string]string{
+			"avatar_image_asset_id": "/data/jobs/job-1/inputs/avatar.png",
+		}, "")),
+		"v1 audio captions watermark": adapter.Spec(req("job-2", full, map[string]string{
+			"avatar_image_asset_id": "/data/jobs/job-2/inputs/avatar.png",
+			"voice_audio_asset_id":  "/data/jobs/job-2/inputs/voice.wav",
+			"captions_asset_id":     "/data/jobs/job-2/inputs/captions.vtt",
+		}, "/data/jobs/job-2/inputs/watermark.png")),
+	}
+}
+
+func TestAdapterSpecsMatchContract(t *testing.T) {
+	for name, s := range specs() {
+		t.Run(name, func(t *testing.T) {
+			if want := strings.Fields(name)[0]; s.Version != want {
+				t.Fatalf("version = %s, want %s", s.Version, want)
+			}
+			if err := contract.ValidateSpec(s.Version, s.Spec); err != nil {
+				t.Errorf("spec does not match the %s contract: %v\nspec: %+v", s.Version, err, s.Spec)
+			}
+		})
+	}
+}
+
+func TestContractRejectsDrift(t *testing.T) {
+	tests := []struct {
+		version, spec, want string
+	}{
+		{"v0", `{"job_id": "j", "params": {}, "output": {"video_object_key": "a.mp4"}}`, "output.thumb_object_key: is required"},
+		{"v0", `{"job_id": "j", "params": {}, "output": {"video_object_key": "a", "thumb_object_key": "b"}, "inputs": {}}`, "inputs: is not allowed"},
+		{"v1", `{"job_id": "j", "template_id": "t", "inputs": {}, "params": {}, "output": {"video_object_key": "a", "thumb_object_key": "b"}}`, "inputs.avatar_image_asset_id: is required"},
+		{"v1", `{"job_id": "j", "template_id": "t", "inputs": {"avatar_image_asset_id": "/a"}, "params": {}, "output": {"video_object_key": "a", "thumb_object_key": "b"}, "watermark": {"path": "/w", "position": "middle", "opacity": 2}}`, "watermark.opacity: must be at most 1"},
+	}
+	for _, tt := range tests {
+		err := contract.ValidateSpecJSON(tt.version, []byte(tt.spec))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s %s: error = %v, want %q", tt.version, tt.spec, err, tt.want)
+		}
+	}
+	if err := contract.ValidateSpecJSON("v9", []byte(`{}`)); err == nil {
+		t.Error("unknown version accepted")
+	}
+}
+
+func TestStoredSpec(t *testing.T) {
+	ok := `{"version": "v0", "spec": {"job_id": "j", "params": {"text": "x"}, "output": {"video_object_key": "a", "thumb_object_key": "b"}}}`
+	if err := contract.ValidateStoredSpec([]byte(ok)); err != nil {
+		t.Errorf("stored spec: %v", err)
+	}
+	if err := contract.ValidateStoredSpec([]byte(`{"version": "v1", "spec": {}}`)); err == nil {
+		t.Error("empty v1 spec accepted")
+	}
+}
+
+// TestRecordedResponses checks the renderer's responses recorded under
+// testdata/responses, named <version>.<status>[.<label>].json. Record new
+// ones when the renderer's responses change.
+func TestRecordedResponses(t *testing.T) {
+	files, err := filepath.Glob("testdata/responses/*.json")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no recorded responses: %v", err)
+	}
+	for _, f := range files {
+		t.Run(filepath.Base(f), func(t *testing.T) {
+			version, status, ok := contract.ResponseFileName(f)
+			if !ok {
+				t.Fatalf("name is not <version>.<status>[.<label>].json")
+			}
+			body, err := os.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := contract.ValidateResponse(version, status, body); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestResponseDrift(t *testing.T) {
+	tests := []struct {
+		version string
+		status  int
+		body    string
+	}{
+		{"v0", 200, `{"ok": false, "spec": "s", "job_id": "j"}`},
+		{"v1", 200, `{"ok": true, "spec": "s", "job_id": "j"}`},
+		{"v1", 200, `{"ok": true, "spec": "s", "job_id": "j", "duration": "7", "has_audio": false, "has_captions": false, "used_transcription": false, "used_external_captions": false, "used_animation": false, "has_watermark": false}`},
+		{"v1", 500, `{"message": "boom"}`},
+		{"v1", 302, `{}`},
+		{"v1", 200, `not json`},
+	}
+	for _, tt := range tests {
+		if err := contract.ValidateResponse(tt.version, tt.status, []byte(tt.body)); err == nil {
+			t.Errorf("%s %d %s accepted", tt.version, tt.status, tt.body)
+		}
+	}
+}
+
+func TestFileNames(t *testing.T) {
+	if v, ok := contract.SpecFileVersion("/data/specs/job-1.v1.json"); !ok || v != "v1" {
+		t.Errorf("SpecFileVersion = %q, %v", v, ok)
+	}
+	for _, name := range []string{"v1.json", "job.v7.json", "v1.200.json"} {
+		if _, ok := contract.SpecFileVersion(name); ok {
+			t.Errorf("SpecFileVersion(%q) ok", name)
+		}
+	}
+	if v, s, ok := contract.ResponseFileName("v1.200.watermark.json"); !ok || v != "v1" || s != 200 {
+		t.Errorf("ResponseFileName = %q, %d, %v", v, s, ok)
+	}
+	if _, _, ok := contract.ResponseFileName("job-1.v1.json"); ok {
+		t.Error("spec file parsed as a response")
+	}
+}
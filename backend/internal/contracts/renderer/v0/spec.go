@@ -4,6 +4,7 @@ package v0
 // - job_id: identificador del job
 // - params: parámetros libres (Hello Render usa params.text)
 // - output: rutas (object keys) donde el renderer debe escribir en el storage compartido
+// - progress_url: opcional, dónde reportar el progreso del render
 type RendererSpec struct {
 	JobID  string         `json:"job_id"`
 	Params map[string]any `json:"params"`
@@ -11,4 +12,5 @@ type RendererSpec struct {
 		VideoObjectKey string `json:"video_object_key"`
 		ThumbObjectKey string `json:"thumb_object_key"`
 	} `json:"output"`
+	ProgressURL string `json:"progress_url,omitempty"`
 }
@@ -0,0 +1,257 @@
+// Package events publishes job, asset, template and publication lifecycle
+// events to a Redis stream and reads them back for GET /v1/events. Stream
+// entry IDs are the event IDs, so a client that reconnects with Last-Event-ID
+// resumes right after the last event it got, as long as the stream still
+// holds it (the stream is trimmed to about maxLen events, see New).
+//
+// The job.* events are also published on a pub/sub channel per job (see
+// JobChannel), so GET /v1/jobs/{jobId}/events gets the events of one job
+// without reading everyone else's.
+//
+// Publishing is best effort: the database is the source of truth and a
+// lost event only delays a dashboard until its next refresh.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"gala/internal/pkg/logger"
+)
+
+// StreamKey is the Redis stream holding the events.
+const StreamKey = "gala:events"
+
+// JobChannelPrefix prefixes the pub/sub channel of each job.
+const JobChannelPrefix = "gala:job:events:"
+
+// DefaultMaxLen is about how many events the stream keeps for resuming.
+const DefaultMaxLen = 10000
+
+// publishTimeout bounds a publish; it runs after the change committed, so
+// it does not use the caller's (maybe canceled) deadline.
+const publishTimeout = 2 * time.Second
+
+// Event types. The subject of job.*, asset.*, template.* and
+// publication.* events is the job, asset, template or publication ID.
+const (
+	JobCreated  = "job.created"
+	JobRunning  = "job.running"
+	JobDone     = "job.done"
+	JobFailed   = "job.failed"
+	JobCanceled = "job.canceled"
+	// JobCancelRequested reports that a RUNNING job was asked to cancel;
+	// job.canceled follows once its worker stops it.
+	JobCancelRequested = "job.cancel_requested"
+	JobRequeued        = "job.requeued"
+	// JobEdited reports that an admin replaced the params of a QUEUED job.
+	JobEdited = "job.edited"
+	// JobQueued reports that a SCHEDULED job reached its run_at and was
+	// queued.
+	JobQueued = "job.queued"
+	// JobOutput reports the upload of each output of a job to storage.
+	JobOutput = "job.output"
+	// JobProgress reports the stage a RUNNING job entered ("parse",
+	// "inputs", "render", "hls", "upload" or "register") with its progress,
+	// and the progress the renderer reports during "render".
+	JobProgress = "job.progress"
+
+	AssetCreated = "asset.created"
+	AssetDeleted = "asset.deleted"
+	// AssetIntegrityFailed reports a job output whose stored object is
+	// missing or differs from its asset row (see admin.VerifyOutputs).
+	AssetIntegrityFailed = "asset.integrity_failed"
+
+	TemplateCreated = "template.created"
+	TemplateUpdated = "template.updated"
+	TemplateDeleted = "template.deleted"
+
+	PublicationDone   = "publication.done"
+	PublicationFailed = "publication.failed"
+)
+
+// Event is a lifecycle event.
+type Event struct {
+	// ID is the stream entry ID, e.g. "1717000000000-0".
+	ID      string         `json:"id"`
+	Type    string         `json:"type"`
+	Subject string         `json:"subject"`
+	Time    time.Time      `json:"time"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// Bus publishes and reads events. A nil *Bus publishes nothing, so
+// components built without Redis (tests, tools) need no checks.
+type Bus struct {
+	rdb    redis.UniversalClient
+	log    *logger.Logger
+	maxLen int64
+}
+
+// New creates a bus on rdb; maxLen <= 0 uses DefaultMaxLen.
+func New(rdb redis.UniversalClient, log *logger.Logger, maxLen int64) *Bus {
+	if log == nil {
+		log = logger.NewDefault()
+	}
+	if maxLen <= 0 {
+		maxLen = DefaultMaxLen
+	}
+	return &Bus{rdb: rdb, log: log.WithComponent("events"), maxLen: maxLen}
+}
+
+// Publish appends an event; failures are logged, not returned.
+func (b *Bus) Publish(ctx context.Context, typ, subject string, data map[string]any) {
+	if b == nil || b.rdb == nil {
+		return
+	}
+	log := b.log.FromContext(ctx)
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Warn("event publish failed", "type", typ, "subject", subject, "error", err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), publishTimeout)
+	defer cancel()
+	id, err := b.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: StreamKey,
+		MaxLen: b.maxLen,
+		Approx: true,
+		Values: map[string]any{
+			"type":    typ,
+			"subject": subject,
+			"time":    now.Format(time.RFC3339Nano),
+			"data":    string(raw),
+		},
+	}).Result()
+	if err != nil {
+		log.Warn("event publish failed", "type", typ, "subject", subject, "error", err.Error())
+		return
+	}
+
+	if !strings.HasPrefix(typ, "job.") {
+		return
+	}
+	msg, err := json.Marshal(Event{ID: id, Type: typ, Subject: subject, Time: now, Data: data})
+	if err == nil {
+		err = b.rdb.Publish(ctx, JobChannel(subject), msg).Err()
+	}
+	if err != nil {
+		log.Warn("job event publish failed", "type", typ, "subject", subject, "error", err.Error())
+	}
+}
+
+// JobChannel is the pub/sub channel of the job.* events of jobID.
+func JobChannel(jobID string) string {
+	return JobChannelPrefix + jobID
+}
+
+// Terminal reports whether an event of type typ ends a run of its job.
+func Terminal(typ string) bool {
+	return typ == JobDone || typ == JobFailed || typ == JobCanceled
+}
+
+// SubscribeJob subscribes to the events of jobID published from now on;
+// they arrive on the returned channel until ctx ends or stop is called,
+// which closes it. Pub/sub keeps no history: callers read the job after
+// subscribing to know where it stands.
+func (b *Bus) SubscribeJob(ctx context.Context, jobID string) (<-chan Event, func(), error) {
+	ps := b.rdb.Subscribe(ctx, JobChannel(jobID))
+	// Wait for the confirmation, so no event published after this returns
+	// is missed
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, nil, err
+	}
+
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		msgs := ps.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-msgs:
+				if !ok {
+					return
+				}
+				var e Event
+				if err := json.Unmarshal([]byte(m.Payload), &e); err != nil {
+					continue
+				}
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, func() { _ = ps.Close() }, nil
+}
+
+// Read returns up to count events after the one with ID after, waiting up
+// to block for the first; it returns no events and no error on timeout.
+func (b *Bus) Read(ctx context.Context, after string, count int64, block time.Duration) ([]Event, error) {
+	res, err := b.rdb.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{StreamKey, after},
+		Count:   count,
+		Block:   block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []Event
+	for _, s := range res {
+		for _, m := range s.Messages {
+			out = append(out, decode(m))
+		}
+	}
+	return out, nil
+}
+
+// LastID returns the ID of the newest event, or "0-0" if there is none;
+// reading after it gets only events published from then on.
+func (b *Bus) LastID(ctx context.Context) (string, error) {
+	msgs, err := b.rdb.XRevRangeN(ctx, StreamKey, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(msgs) == 0 {
+		return "0-0", nil
+	}
+	return msgs[0].ID, nil
+}
+
+var idPattern = regexp.MustCompile(`^\d+-\d+$`)
+
+// ValidID reports whether id has the form of an event ID.
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// decode turns a stream entry into an Event; malformed fields are left
+// zero rather than dropping the event.
+func decode(m redis.XMessage) Event {
+	e := Event{ID: m.ID}
+	e.Type, _ = m.Values["type"].(string)
+	e.Subject, _ = m.Values["subject"].(string)
+	if s, ok := m.Values["time"].(string); ok {
+		e.Time, _ = time.Parse(time.RFC3339Nano, s)
+	}
+	if s, ok := m.Values["data"].(string); ok {
+		_ = json.Unmarshal([]byte(s), &e.Data)
+	}
+	return e
+}
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestValidID(t *testing.T) {
+	for id, want := range map[string]bool{
+		"1717000000000-0": true,
+		"0-0":             true,
+		"1717000000000":   false,
+		"$":               false,
+		"abc-1":           false,
+		"":                false,
+	} {
+		if got := ValidID(id); got != want {
+			t.Errorf("ValidID(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	e := decode(redis.XMessage{ID: "1-0", Values: map[string]any{
+		"type":    JobDone,
+		"subject": "job_1",
+		"time":    "2024-05-29T16:26:40.5Z",
+		"data":    `{"status":"DONE"}`,
+	}})
+	if e.ID != "1-0" || e.Type != JobDone || e.Subject != "job_1" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if !e.Time.Equal(time.Date(2024, 5, 29, 16, 26, 40, 5e8, time.UTC)) {
+		t.Errorf("unexpected time %v", e.Time)
+	}
+	if e.Data["status"] != "DONE" {
+		t.Errorf("unexpected data %v", e.Data)
+	}
+
+	// Malformed fields are left zero
+	e = decode(redis.XMessage{ID: "2-0", Values: map[string]any{"type": JobFailed, "data": "{"}})
+	if e.ID != "2-0" || e.Type != JobFailed || e.Data != nil || !e.Time.IsZero() {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
+
+func TestNilBusPublishes(t *testing.T) {
+	var b *Bus
+	// Must not panic
+	b.Publish(context.Background(), JobCreated, "job_1", nil)
+}
+
+func TestTerminal(t *testing.T) {
+	for typ, want := range map[string]bool{
+		JobDone:            true,
+		JobFailed:          true,
+		JobCanceled:        true,
+		JobRunning:         false,
+		JobProgress:        false,
+		JobCancelRequested: false,
+		AssetCreated:       false,
+	} {
+		if got := Terminal(typ); got != want {
+			t.Errorf("Terminal(%q) = %v, want %v", typ, got, want)
+		}
+	}
+	if c := JobChannel("job_1"); c != "gala:job:events:job_1" {
+		t.Errorf("JobChannel = %q", c)
+	}
+}
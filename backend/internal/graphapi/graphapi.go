@@ -0,0 +1,264 @@
+// Package graphapi serves a read-only GraphQL endpoint for the dashboard,
+// so a job page (job, outputs, their assets and the template) takes one
+// round trip. Nested fields are resolved through per-request Loaders that
+// batch their lookups into one query per type.
+package graphapi
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"gala/internal/httpkit"
+	"gala/internal/pkg/dbpool"
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/i18n"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/store"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+const (
+	// maxDepth bounds query nesting; the deepest useful path is
+	// jobs.nodes.outputs.video.
+	maxDepth = 8
+	// maxQueryLength bounds the query text in bytes.
+	maxQueryLength = 16 << 10
+	// loaderWait is how long a loader collects keys before querying.
+	loaderWait = 2 * time.Millisecond
+)
+
+type Deps struct {
+	Pool *pgxpool.Pool
+	// DB routes list queries to read replicas; nil uses Pool only.
+	DB  *dbpool.Router
+	Log *logger.Logger
+}
+
+// Handler executes GraphQL queries sent as POST {"query": ...}.
+type Handler struct {
+	schema *graphql.Schema
+	svc    *service
+}
+
+// NewHandler parses the schema; it panics if it does not match the
+// resolvers.
+func NewHandler(d Deps) *Handler {
+	log := d.Log
+	if log == nil {
+		log = logger.NewDefault()
+	}
+	log = log.WithComponent("graphql")
+
+	db := d.DB
+	if db == nil {
+		db = dbpool.NewRouter(d.Pool)
+	}
+	svc := &service{pool: d.Pool, db: db, log: log}
+
+	schema := graphql.MustParseSchema(schemaSDL, &queryResolver{svc},
+		graphql.MaxDepth(maxDepth),
+		graphql.MaxQueryLength(maxQueryLength),
+		// List items are resolved concurrently up to this limit; it must
+		// cover a full page for the loaders to batch the whole page.
+		graphql.MaxParallelism(httpkit.MaxLimit),
+		graphql.Logger(panicLogger{log}),
+	)
+	return &Handler{schema: schema, svc: svc}
+}
+
+// Request is a GraphQL request body.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	// Extensions is accepted for client compatibility and ignored.
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (req *Request) Validate() error {
+	var v httpkit.Validator
+	v.Required("query", req.Query)
+	return v.Err()
+}
+
+// ServeHTTP answers 200 with the GraphQL response, including when it has
+// errors; only a malformed body gets the REST error envelope.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if !httpkit.DecodeAndValidate(w, r, &req) {
+		return
+	}
+	ctx := withLoaders(r.Context(), h.svc)
+	resp := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	httpkit.WriteJSON(w, http.StatusOK, resp)
+}
+
+// service holds what the resolvers share.
+type service struct {
+	pool *pgxpool.Pool
+	db   *dbpool.Router
+	log  *logger.Logger
+}
+
+// reader returns the pool for list queries, which may lag the primary.
+func (s *service) reader() *pgxpool.Pool {
+	return s.db.Reader()
+}
+
+// ---- LOADERS ----
+
+type loaders struct {
+	templates *Loader[string, store.Template]
+	assets    *Loader[string, store.Asset]
+	outputs   *Loader[string, []store.JobOutput]
+}
+
+type loadersKey struct{}
+
+// withLoaders returns ctx with a fresh set of loaders, so nothing is
+// cached across requests.
+func withLoaders(ctx context.Context, s *service) context.Context {
+	l := &loaders{
+		templates: NewLoader(ctx, loaderWait, httpkit.MaxLimit, s.fetchTemplates),
+		assets:    NewLoader(ctx, loaderWait, httpkit.MaxLimit, s.fetchAssets),
+		outputs:   NewLoader(ctx, loaderWait, httpkit.MaxLimit, s.fetchOutputs),
+	}
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+func (s *service) fetchTemplates(ctx context.Context, ids []string) (map[string]store.Template, error) {
+	ts, err := store.GetTemplates(ctx, s.pool, ids)
+	if err != nil {
+		return nil, s.dbError(ctx, err, "graphql.templates", "db query failed")
+	}
+	m := make(map[string]store.Template, len(ts))
+	for _, t := range ts {
+		m[t.ID] = t
+	}
+	return m, nil
+}
+
+func (s *service) fetchAssets(ctx context.Context, ids []string) (map[string]store.Asset, error) {
+	as, err := store.GetAssets(ctx, s.pool, ids)
+	if err != nil {
+		return nil, s.dbError(ctx, err, "graphql.assets", "db query failed")
+	}
+	m := make(map[string]store.Asset, len(as))
+	for _, a := range as {
+		m[a.ID] = a
+	}
+	return m, nil
+}
+
+// fetchOutputs groups the outputs by job; jobs without outputs are left
+// out of the map.
+func (s *service) fetchOutputs(ctx context.Context, jobIDs []string) (map[string][]store.JobOutput, error) {
+	outs, err := store.ListOutputsOfJobs(ctx, s.pool, jobIDs)
+	if err != nil && !pgerr.IsUndefinedTable(err) {
+		return nil, s.dbError(ctx, err, "graphql.outputs", "db outputs query failed")
+	}
+	m := map[string][]store.JobOutput{}
+	for _, o := range outs {
+		m[o.JobID] = append(m[o.JobID], o)
+	}
+	return m, nil
+}
+
+// ---- ERRORS ----
+
+// apiError is a resolver error; its code (the REST API's, e.g.
+// VALIDATION_ERROR) and details go to the error's extensions.
+type apiError struct {
+	code    errors.Code
+	message string
+	details map[string]any
+}
+
+func (e *apiError) Error() string { return e.message }
+
+func (e *apiError) Extensions() map[string]any {
+	ext := map[string]any{"code": string(e.code)}
+	for k, v := range e.details {
+		ext[k] = v
+	}
+	return ext
+}
+
+// newError returns an apiError with msg translated to the request
+// language.
+func newError(ctx context.Context, code errors.Code, msg string, details map[string]any) error {
+	return &apiError{code: code, message: i18n.Localize(ctx, code, msg, details), details: details}
+}
+
+func invalidArgument(ctx context.Context, field, msg string) error {
+	return newError(ctx, errors.CodeValidation, msg, map[string]any{"field": field})
+}
+
+// dbError translates a database error (see pgerr) like the REST
+// handlers' writeDBErr and logs it.
+func (s *service) dbError(ctx context.Context, err error, op, msg string) error {
+	e := pgerr.Wrap(err, op, msg)
+	s.log.FromContext(ctx).Error("database error",
+		"op", op,
+		"code", string(e.Code),
+		"error", err.Error(),
+	)
+	return newError(ctx, e.Code, msg, nil)
+}
+
+// panicLogger logs panics recovered in resolvers; the query gets a
+// generic error for the field.
+type panicLogger struct {
+	log *logger.Logger
+}
+
+func (l panicLogger) LogPanic(ctx context.Context, value any) {
+	l.log.FromContext(ctx).Error("panic recovered", "panic", value)
+}
+
+// ---- PAGINATION ----
+
+// parsePage reads first/after with the REST API's bounds and cursor
+// format.
+func parsePage(ctx context.Context, first *int32, after *string) (limit int, keyset *store.Keyset, err error) {
+	limit = httpkit.DefaultLimit
+	if first != nil {
+		if *first < 1 {
+			return 0, nil, invalidArgument(ctx, "first", "first must be a positive integer")
+		}
+		limit = min(int(*first), httpkit.MaxLimit)
+	}
+	if after == nil || strings.TrimSpace(*after) == "" {
+		return limit, nil, nil
+	}
+	var k store.Keyset
+	if err := httpkit.DecodeCursor(*after, &k.CreatedAt, &k.ID); err != nil {
+		return 0, nil, invalidArgument(ctx, "after", "invalid cursor")
+	}
+	return limit, &k, nil
+}
+
+// nextPage trims items fetched with limit+1 to one page and returns the
+// cursor of the next page, or nil if this is the last one.
+func nextPage[T any](items []T, limit int, key func(T) (time.Time, string)) ([]T, *string) {
+	if len(items) <= limit {
+		return items, nil
+	}
+	items = items[:limit]
+	createdAt, id := key(items[len(items)-1])
+	next := httpkit.EncodeCursor(createdAt, id)
+	return items, &next
+}
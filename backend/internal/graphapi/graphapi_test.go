@@ -0,0 +1,90 @@
+package graphapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gala/internal/pkg/logger"
+)
+
+// newTestHandler returns a Handler without database; only queries that
+// fail before touching the store work.
+func newTestHandler() *Handler {
+	return NewHandler(Deps{Log: logger.New(logger.Config{Output: io.Discard})})
+}
+
+type gqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func post(t *testing.T, h http.Handler, body string) (*httptest.ResponseRecorder, gqlResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/graphql", strings.NewReader(body)))
+	var resp gqlResponse
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+		}
+	}
+	return rec, resp
+}
+
+func TestIntrospection(t *testing.T) {
+	rec, resp := post(t, newTestHandler(), `{"query":"{ __type(name: \"Job\") { fields { name } } }"}`)
+	if rec.Code != http.StatusOK || len(resp.Errors) > 0 {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	for _, f := range []string{`"outputs"`, `"template"`, `"createdAt"`} {
+		if !strings.Contains(string(resp.Data), f) {
+			t.Errorf("Job type has no field %s: %s", f, resp.Data)
+		}
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	h := newTestHandler()
+	for _, body := range []string{``, `{}`, `{"query":"  "}`, `{"query":"{ job }","unknown":1}`} {
+		if rec, _ := post(t, h, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("body %q: expected 400, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	h := newTestHandler()
+	tests := []struct {
+		name  string
+		query string
+		code  string
+		field string
+	}{
+		{"invalid first", `{ jobs(first: 0) { nodes { id } } }`, "VALIDATION_ERROR", "first"},
+		{"invalid cursor", `{ assets(after: \"nope\") { nodes { id } } }`, "VALIDATION_ERROR", "after"},
+		{"mutation", `mutation { deleteJob(id: \"x\") }`, "", ""},
+		{"unknown field", `{ jobs { nodes { secret } } }`, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, resp := post(t, h, `{"query":"`+tt.query+`"}`)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d", rec.Code)
+			}
+			if len(resp.Errors) == 0 {
+				t.Fatalf("expected errors, got %s", rec.Body.String())
+			}
+			ext := resp.Errors[0].Extensions
+			if tt.code != "" && (ext["code"] != tt.code || ext["field"] != tt.field) {
+				t.Errorf("expected %s on %s, got %v", tt.code, tt.field, ext)
+			}
+		})
+	}
+}
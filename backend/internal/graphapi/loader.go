@@ -0,0 +1,102 @@
+package graphapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Loader batches and caches the loads of one request: keys asked for
+// within wait of the first one (or until maxBatch keys) are fetched with
+// a single call, so resolving a field over a list of N items runs one
+// query instead of N.
+type Loader[K comparable, V any] struct {
+	ctx      context.Context
+	fetch    func(ctx context.Context, keys []K) (map[K]V, error)
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	cache   map[K]*loadResult[V]
+	pending []K
+	timer   *time.Timer
+}
+
+type loadResult[V any] struct {
+	done  chan struct{}
+	value V
+	found bool
+	err   error
+}
+
+// NewLoader creates a loader whose fetches run with ctx (the request's).
+// fetch returns the values it found; keys missing from its map load as
+// not found.
+func NewLoader[K comparable, V any](ctx context.Context, wait time.Duration, maxBatch int, fetch func(ctx context.Context, keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{
+		ctx:      ctx,
+		fetch:    fetch,
+		wait:     wait,
+		maxBatch: maxBatch,
+		cache:    map[K]*loadResult[V]{},
+	}
+}
+
+// Load returns the value of key and whether it exists.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, bool, error) {
+	l.mu.Lock()
+	r, ok := l.cache[key]
+	if !ok {
+		r = &loadResult[V]{done: make(chan struct{})}
+		l.cache[key] = r
+		l.pending = append(l.pending, key)
+		switch {
+		case len(l.pending) >= l.maxBatch:
+			l.dispatchLocked()
+		case len(l.pending) == 1:
+			l.timer = time.AfterFunc(l.wait, l.dispatch)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return r.value, r.found, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, false, ctx.Err()
+	}
+}
+
+func (l *Loader[K, V]) dispatch() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.dispatchLocked()
+}
+
+// dispatchLocked starts fetching the pending keys; l.mu is held.
+func (l *Loader[K, V]) dispatchLocked() {
+	if len(l.pending) == 0 {
+		return
+	}
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	keys := l.pending
+	l.pending = nil
+	results := make([]*loadResult[V], len(keys))
+	for i, k := range keys {
+		results[i] = l.cache[k]
+	}
+
+	go func() {
+		values, err := l.fetch(l.ctx, keys)
+		for i, k := range keys {
+			r := results[i]
+			r.value, r.found = values[k]
+			r.err = err
+			close(r.done)
+		}
+	}()
+}
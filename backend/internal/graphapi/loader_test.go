@@ -0,0 +1,115 @@
+package graphapi
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a fetch function that records its batches and finds every
+// key but "missing".
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]string
+	err     error
+}
+
+func (r *recorder) fetch(_ context.Context, keys []string) (map[string]string, error) {
+	r.mu.Lock()
+	r.batches = append(r.batches, append([]string(nil), keys...))
+	r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	m := map[string]string{}
+	for _, k := range keys {
+		if k != "missing" {
+			m[k] = "v:" + k
+		}
+	}
+	return m, nil
+}
+
+// loadAll loads keys concurrently and fails the test on errors.
+func loadAll(t *testing.T, l *Loader[string, string], keys ...string) {
+	t.Helper()
+	var wg sync.WaitGroup
+	for _, k := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := l.Load(context.Background(), k); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLoaderBatches(t *testing.T) {
+	rec := &recorder{}
+	l := NewLoader(context.Background(), 20*time.Millisecond, 100, rec.fetch)
+
+	loadAll(t, l, "a", "b", "c", "b")
+	if len(rec.batches) != 1 {
+		t.Fatalf("expected one batch, got %v", rec.batches)
+	}
+	got := rec.batches[0]
+	sort.Strings(got)
+	if len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("expected each key once, got %v", got)
+	}
+
+	// Cached keys are not fetched again
+	v, ok, err := l.Load(context.Background(), "a")
+	if err != nil || !ok || v != "v:a" {
+		t.Errorf("unexpected load: %q %v %v", v, ok, err)
+	}
+	if len(rec.batches) != 1 {
+		t.Errorf("expected cached load, got batches %v", rec.batches)
+	}
+}
+
+func TestLoaderMaxBatch(t *testing.T) {
+	rec := &recorder{}
+	// The wait is long enough that only maxBatch can dispatch in time
+	l := NewLoader(context.Background(), time.Minute, 2, rec.fetch)
+
+	done := make(chan struct{})
+	go func() {
+		loadAll(t, l, "a", "b")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("full batch was not dispatched")
+	}
+}
+
+func TestLoaderMissingAndErrors(t *testing.T) {
+	rec := &recorder{}
+	l := NewLoader(context.Background(), time.Millisecond, 10, rec.fetch)
+	if _, ok, err := l.Load(context.Background(), "missing"); ok || err != nil {
+		t.Errorf("expected not found, got ok=%v err=%v", ok, err)
+	}
+
+	boom := errors.New("boom")
+	l = NewLoader(context.Background(), time.Millisecond, 10, (&recorder{err: boom}).fetch)
+	if _, _, err := l.Load(context.Background(), "a"); !errors.Is(err, boom) {
+		t.Errorf("expected fetch error, got %v", err)
+	}
+}
+
+func TestLoaderCanceled(t *testing.T) {
+	rec := &recorder{}
+	l := NewLoader(context.Background(), time.Minute, 10, rec.fetch)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := l.Load(ctx, "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
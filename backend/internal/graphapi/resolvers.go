@@ -0,0 +1,339 @@
+package graphapi
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"gala/internal/httpkit"
+	"gala/internal/jobs"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/store"
+)
+
+// JSON is the JSON scalar: any JSON value, passed through as is.
+type JSON struct {
+	Value any
+}
+
+func (JSON) ImplementsGraphQLType(name string) bool { return name == "JSON" }
+
+func (j *JSON) UnmarshalGraphQL(input any) error {
+	j.Value = input
+	return nil
+}
+
+func (j JSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Value)
+}
+
+// rawJSON wraps a JSONB column; NULL gives nil.
+func rawJSON(raw []byte) *JSON {
+	if raw == nil {
+		return nil
+	}
+	return &JSON{Value: json.RawMessage(raw)}
+}
+
+func timePtr(t *time.Time) *graphql.Time {
+	if t == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
+
+func idPtr(id string) *graphql.ID {
+	if id == "" {
+		return nil
+	}
+	gid := graphql.ID(id)
+	return &gid
+}
+
+// ---- QUERY ----
+
+type queryResolver struct {
+	*service
+}
+
+func (q *queryResolver) Job(ctx context.Context, args struct{ ID graphql.ID }) (*jobResolver, error) {
+	j, err := store.GetJob(ctx, q.pool, string(args.ID))
+	if err != nil {
+		if pgerr.IsNoRows(err) {
+			return nil, nil
+		}
+		return nil, q.dbError(ctx, err, "graphql.job", "db query failed")
+	}
+	return newJobResolver(j), nil
+}
+
+type jobConnection struct {
+	nodes      []*jobResolver
+	nextCursor *string
+}
+
+func (c *jobConnection) Nodes() []*jobResolver { return c.nodes }
+func (c *jobConnection) NextCursor() *string   { return c.nextCursor }
+
+func (q *queryResolver) Jobs(ctx context.Context, args struct {
+	Status *string
+	First  *int32
+	After  *string
+}) (*jobConnection, error) {
+	limit, after, err := parsePage(ctx, args.First, args.After)
+	if err != nil {
+		return nil, err
+	}
+	f := store.JobFilter{}
+	if args.Status != nil {
+		f.Status = *args.Status
+	}
+	list, err := store.ListJobs(ctx, q.reader(), f, after, limit+1)
+	if err != nil {
+		return nil, q.dbError(ctx, err, "graphql.jobs", "db query failed")
+	}
+	list, next := nextPage(list, limit, func(j store.Job) (time.Time, string) { return j.CreatedAt, j.ID })
+	c := &jobConnection{nodes: make([]*jobResolver, 0, len(list)), nextCursor: next}
+	for _, j := range list {
+		c.nodes = append(c.nodes, newJobResolver(j))
+	}
+	return c, nil
+}
+
+func (q *queryResolver) Template(ctx context.Context, args struct{ ID graphql.ID }) (*templateResolver, error) {
+	t, err := store.GetTemplate(ctx, q.pool, string(args.ID))
+	if err != nil {
+		if pgerr.IsNoRows(err) {
+			return nil, nil
+		}
+		return nil, q.dbError(ctx, err, "graphql.template", "db query failed")
+	}
+	return &templateResolver{t}, nil
+}
+
+type templateConnection struct {
+	nodes      []*templateResolver
+	nextCursor *string
+}
+
+func (c *templateConnection) Nodes() []*templateResolver { return c.nodes }
+func (c *templateConnection) NextCursor() *string        { return c.nextCursor }
+
+func (q *queryResolver) Templates(ctx context.Context, args struct {
+	First *int32
+	After *string
+}) (*templateConnection, error) {
+	limit, after, err := parsePage(ctx, args.First, args.After)
+	if err != nil {
+		return nil, err
+	}
+	list, err := store.ListTemplates(ctx, q.reader(), after, limit+1)
+	if err != nil {
+		return nil, q.dbError(ctx, err, "graphql.templates", "db query failed")
+	}
+	list, next := nextPage(list, limit, func(t store.Template) (time.Time, string) { return t.CreatedAt, t.ID })
+	c := &templateConnection{nodes: make([]*templateResolver, 0, len(list)), nextCursor: next}
+	for _, t := range list {
+		c.nodes = append(c.nodes, &templateResolver{t})
+	}
+	return c, nil
+}
+
+func (q *queryResolver) Asset(ctx context.Context, args struct{ ID graphql.ID }) (*assetResolver, error) {
+	a, err := store.GetAsset(ctx, q.pool, string(args.ID))
+	if err != nil {
+		if pgerr.IsNoRows(err) {
+			return nil, nil
+		}
+		return nil, q.dbError(ctx, err, "graphql.asset", "db query failed")
+	}
+	return &assetResolver{a}, nil
+}
+
+type assetConnection struct {
+	nodes      []*assetResolver
+	nextCursor *string
+}
+
+func (c *assetConnection) Nodes() []*assetResolver { return c.nodes }
+func (c *assetConnection) NextCursor() *string     { return c.nextCursor }
+
+func (q *queryResolver) Assets(ctx context.Context, args struct {
+	Kind   *string
+	Search *string
+	First  *int32
+	After  *string
+}) (*assetConnection, error) {
+	limit, after, err := parsePage(ctx, args.First, args.After)
+	if err != nil {
+		return nil, err
+	}
+	var f store.AssetFilter
+	if args.Kind != nil {
+		f.Kind = strings.TrimSpace(*args.Kind)
+	}
+	if args.Search != nil {
+		f.Search = strings.TrimSpace(*args.Search)
+	}
+	list, err := store.ListAssets(ctx, q.reader(), f, after, limit+1)
+	if err != nil {
+		return nil, q.dbError(ctx, err, "graphql.assets", "db query failed")
+	}
+	list, next := nextPage(list, limit, func(a store.Asset) (time.Time, string) { return a.CreatedAt, a.ID })
+	c := &assetConnection{nodes: make([]*assetResolver, 0, len(list)), nextCursor: next}
+	for _, a := range list {
+		c.nodes = append(c.nodes, &assetResolver{a})
+	}
+	return c, nil
+}
+
+// ---- JOB ----
+
+type jobResolver struct {
+	j    store.Job
+	spec jobs.Spec
+}
+
+func newJobResolver(j store.Job) *jobResolver {
+	return &jobResolver{j: j, spec: jobs.ParseSpec(j.ParamsJSON)}
+}
+
+func (r *jobResolver) ID() graphql.ID            { return graphql.ID(r.j.ID) }
+func (r *jobResolver) Name() string              { return r.j.Name }
+func (r *jobResolver) Status() string            { return r.j.Status }
+func (r *jobResolver) TemplateID() *graphql.ID   { return idPtr(r.spec.TemplateID) }
+func (r *jobResolver) Inputs() JSON              { return JSON{Value: r.spec.Inputs} }
+func (r *jobResolver) Params() JSON              { return JSON{Value: r.spec.Params} }
+func (r *jobResolver) CreatedAt() graphql.Time   { return graphql.Time{Time: r.j.CreatedAt} }
+func (r *jobResolver) UpdatedAt() graphql.Time   { return graphql.Time{Time: r.j.UpdatedAt} }
+func (r *jobResolver) RunAt() *graphql.Time      { return timePtr(r.j.RunAt) }
+func (r *jobResolver) StartedAt() *graphql.Time  { return timePtr(r.j.StartedAt) }
+func (r *jobResolver) FinishedAt() *graphql.Time { return timePtr(r.j.FinishedAt) }
+
+func (r *jobResolver) Progress() int32 { return int32(r.j.Progress) }
+
+func (r *jobResolver) Stage() *string {
+	if r.j.Stage == "" {
+		return nil
+	}
+	return &r.j.Stage
+}
+
+func (r *jobResolver) Error() *string {
+	if r.j.ErrorText == "" {
+		return nil
+	}
+	return &r.j.ErrorText
+}
+
+func (r *jobResolver) Template(ctx context.Context) (*templateResolver, error) {
+	if r.spec.TemplateID == "" {
+		return nil, nil
+	}
+	t, ok, err := loadersFrom(ctx).templates.Load(ctx, r.spec.TemplateID)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &templateResolver{t}, nil
+}
+
+func (r *jobResolver) Outputs(ctx context.Context) ([]*outputResolver, error) {
+	outs, _, err := loadersFrom(ctx).outputs.Load(ctx, r.j.ID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*outputResolver, 0, len(outs))
+	for _, o := range outs {
+		res = append(res, &outputResolver{o})
+	}
+	return res, nil
+}
+
+// ---- JOB OUTPUT ----
+
+type outputResolver struct {
+	o store.JobOutput
+}
+
+func (r *outputResolver) Variant() int32                { return int32(r.o.Variant) }
+func (r *outputResolver) VideoAssetID() graphql.ID      { return graphql.ID(r.o.VideoAssetID) }
+func (r *outputResolver) ThumbnailAssetID() *graphql.ID { return idPtr(r.o.ThumbnailAssetID) }
+func (r *outputResolver) CaptionsAssetID() *graphql.ID  { return idPtr(r.o.CaptionsAssetID) }
+
+func (r *outputResolver) Video(ctx context.Context) (*assetResolver, error) {
+	return loadAsset(ctx, r.o.VideoAssetID)
+}
+
+func (r *outputResolver) Thumbnail(ctx context.Context) (*assetResolver, error) {
+	return loadAsset(ctx, r.o.ThumbnailAssetID)
+}
+
+func (r *outputResolver) Captions(ctx context.Context) (*assetResolver, error) {
+	return loadAsset(ctx, r.o.CaptionsAssetID)
+}
+
+// loadAsset loads an asset through the request's loader; an empty id or
+// a missing asset gives nil.
+func loadAsset(ctx context.Context, id string) (*assetResolver, error) {
+	if id == "" {
+		return nil, nil
+	}
+	a, ok, err := loadersFrom(ctx).assets.Load(ctx, id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &assetResolver{a}, nil
+}
+
+// ---- TEMPLATE ----
+
+type templateResolver struct {
+	t store.Template
+}
+
+func (r *templateResolver) ID() graphql.ID          { return graphql.ID(r.t.ID) }
+func (r *templateResolver) Type() string            { return r.t.Type }
+func (r *templateResolver) Name() string            { return r.t.Name }
+func (r *templateResolver) Format() *JSON           { return rawJSON(r.t.Format) }
+func (r *templateResolver) ParamsSchema() *JSON     { return rawJSON(r.t.ParamsSchema) }
+func (r *templateResolver) Defaults() *JSON         { return rawJSON(r.t.Defaults) }
+func (r *templateResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.t.CreatedAt} }
+func (r *templateResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.t.UpdatedAt} }
+
+func (r *templateResolver) DurationMs() *int32 {
+	if r.t.DurationMs == nil {
+		return nil
+	}
+	d := int32(*r.t.DurationMs)
+	return &d
+}
+
+// ---- ASSET ----
+
+type assetResolver struct {
+	a store.Asset
+}
+
+func (r *assetResolver) ID() graphql.ID          { return graphql.ID(r.a.ID) }
+func (r *assetResolver) Kind() string            { return r.a.Kind }
+func (r *assetResolver) Provider() string        { return r.a.Provider }
+func (r *assetResolver) ObjectKey() string       { return r.a.ObjectKey }
+func (r *assetResolver) Mime() string            { return r.a.Mime }
+func (r *assetResolver) SizeBytes() float64      { return float64(r.a.SizeBytes) }
+func (r *assetResolver) Label() string           { return r.a.Label }
+func (r *assetResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.a.CreatedAt} }
+func (r *assetResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.a.UpdatedAt} }
+
+func (r *assetResolver) Checksum() *string {
+	if r.a.Checksum == "" {
+		return nil
+	}
+	return &r.a.Checksum
+}
+
+func (r *assetResolver) ContentURL(ctx context.Context) string {
+	return httpkit.VersionedPath(ctx, "/assets/"+r.a.ID+"/content")
+}
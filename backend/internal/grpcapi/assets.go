@@ -0,0 +1,141 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"gala/internal/admin"
+	"gala/internal/grpcapi/galav1"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/storage"
+	"gala/internal/store"
+)
+
+// chunkSize is the data size of the AssetChunk messages, well under the
+// default 4 MiB message limit.
+const chunkSize = 256 << 10
+
+func assetNotFound(id string) error {
+	return apiError(codes.NotFound, "ASSET_NOT_FOUND", "asset not found", map[string]string{"asset_id": id})
+}
+
+func (s assetsServer) GetAsset(ctx context.Context, req *galav1.GetAssetRequest) (*galav1.Asset, error) {
+	a, err := store.GetAsset(ctx, s.pool, req.GetId())
+	if err != nil {
+		if pgerr.IsNoRows(err) {
+			return nil, assetNotFound(req.GetId())
+		}
+		return nil, s.dbError(ctx, err, "assets.get", "db query failed")
+	}
+	return toAsset(a), nil
+}
+
+func (s assetsServer) ListAssets(ctx context.Context, req *galav1.ListAssetsRequest) (*galav1.ListAssetsResponse, error) {
+	limit, after, err := parsePage(req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	f := store.AssetFilter{
+		Kind:   strings.TrimSpace(req.GetKind()),
+		Search: strings.TrimSpace(req.GetQuery()),
+	}
+
+	// Fetch one extra row to know whether there is a next page.
+	rows, err := store.ListAssets(ctx, s.reader(), f, after, limit+1)
+	if err != nil {
+		return nil, s.dbError(ctx, err, "assets.list", "db query failed")
+	}
+	rows, next := nextPage(rows, limit, func(a store.Asset) (time.Time, string) { return a.CreatedAt, a.ID })
+
+	resp := &galav1.ListAssetsResponse{NextPageToken: next}
+	for _, a := range rows {
+		resp.Assets = append(resp.Assets, toAsset(a))
+	}
+	return resp, nil
+}
+
+func (s assetsServer) ReadAssetContent(req *galav1.ReadAssetContentRequest, stream grpc.ServerStreamingServer[galav1.AssetChunk]) error {
+	ctx := stream.Context()
+	a, err := store.GetAsset(ctx, s.pool, req.GetId())
+	if err != nil {
+		if pgerr.IsNoRows(err) {
+			return assetNotFound(req.GetId())
+		}
+		return s.dbError(ctx, err, "assets.stream", "db query failed")
+	}
+
+	rc, ct, _, err := s.sp.GetObject(ctx, a.ObjectKey)
+	if err != nil {
+		return apiError(codes.NotFound, "ASSET_FILE_MISSING", "asset file missing", map[string]string{"object_key": a.ObjectKey})
+	}
+	defer rc.Close()
+	if ct == "" {
+		ct = a.Mime
+	}
+
+	first := &galav1.AssetChunk{ContentType: ct, SizeBytes: a.SizeBytes}
+	// Reads stop once the client cancels the stream
+	src := storage.WithContext(ctx, rc)
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(src, buf)
+		if n > 0 || first != nil {
+			chunk := first
+			if chunk == nil {
+				chunk = &galav1.AssetChunk{}
+			}
+			first = nil
+			chunk.Data = buf[:n]
+			if err := stream.Send(chunk); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if cerr := ctx.Err(); cerr != nil {
+			return cerr
+		}
+		if err != nil {
+			s.log.FromContext(ctx).Warn("asset stream aborted", "asset_id", a.ID, "error", err.Error())
+			return apiError(codes.Internal, "INTERNAL_ERROR", "storage read failed", nil)
+		}
+	}
+}
+
+func (s assetsServer) DeleteAsset(ctx context.Context, req *galav1.DeleteAssetRequest) (*emptypb.Empty, error) {
+	a, err := s.admin.DeleteAsset(ctx, req.GetId())
+	switch {
+	case err == nil:
+		return &emptypb.Empty{}, nil
+	case errors.Is(err, admin.ErrAssetNotFound):
+		return nil, assetNotFound(req.GetId())
+	case errors.Is(err, admin.ErrAssetInUse):
+		return nil, apiError(codes.FailedPrecondition, "ASSET_IN_USE", "asset is referenced by job outputs", map[string]string{"asset_id": req.GetId()})
+	case errors.Is(err, admin.ErrStorage):
+		return nil, apiError(codes.Internal, "INTERNAL_ERROR", "storage delete failed", map[string]string{"object_key": a.ObjectKey})
+	}
+	return nil, s.dbError(ctx, err, "assets.delete", "db delete failed")
+}
+
+func toAsset(a store.Asset) *galav1.Asset {
+	return &galav1.Asset{
+		Id:        a.ID,
+		Kind:      a.Kind,
+		Provider:  a.Provider,
+		ObjectKey: a.ObjectKey,
+		Mime:      a.Mime,
+		SizeBytes: a.SizeBytes,
+		Label:     a.Label,
+		CreatedAt: timestamppb.New(a.CreatedAt),
+		UpdatedAt: timestamppb.New(a.UpdatedAt),
+	}
+}
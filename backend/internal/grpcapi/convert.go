@@ -0,0 +1,73 @@
+package grpcapi
+
+import (
+	"encoding/json"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"gala/internal/httpkit"
+	"gala/internal/store"
+)
+
+// parsePage reads a page size and token with the REST API's bounds and
+// cursor format.
+func parsePage(size int32, token string) (limit int, after *store.Keyset, err error) {
+	switch {
+	case size < 0:
+		return 0, nil, invalidArgument("page_size", "page_size must not be negative")
+	case size == 0:
+		limit = httpkit.DefaultLimit
+	default:
+		limit = min(int(size), httpkit.MaxLimit)
+	}
+	if token == "" {
+		return limit, nil, nil
+	}
+	var k store.Keyset
+	if err := httpkit.DecodeCursor(token, &k.CreatedAt, &k.ID); err != nil {
+		return 0, nil, invalidArgument("page_token", "invalid page token")
+	}
+	return limit, &k, nil
+}
+
+// nextPage trims items fetched with limit+1 to one page and returns the
+// token of the next page, or "" if this is the last one.
+func nextPage[T any](items []T, limit int, key func(T) (time.Time, string)) ([]T, string) {
+	if len(items) <= limit {
+		return items, ""
+	}
+	items = items[:limit]
+	createdAt, id := key(items[len(items)-1])
+	return items, httpkit.EncodeCursor(createdAt, id)
+}
+
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// jsonStruct decodes a JSON object column; anything else gives nil.
+func jsonStruct(raw []byte) *structpb.Struct {
+	var m map[string]any
+	if json.Unmarshal(raw, &m) != nil || m == nil {
+		return nil
+	}
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		return nil
+	}
+	return s
+}
+
+// structJSON encodes a Struct for a JSONB column; nil stays nil (NULL).
+func structJSON(s *structpb.Struct) []byte {
+	if s == nil {
+		return nil
+	}
+	b, _ := json.Marshal(s.AsMap())
+	return b
+}
@@ -0,0 +1,92 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/pgerr"
+)
+
+// errorDomain is the ErrorInfo domain of GALA errors.
+const errorDomain = "gala"
+
+// apiError returns a status with code and an ErrorInfo detail whose reason
+// is the REST API error code (e.g. JOB_NOT_FOUND), so clients can switch
+// on the same codes over both transports.
+func apiError(code codes.Code, apiCode, msg string, metadata map[string]string) error {
+	st := status.New(code, msg)
+	if d, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   apiCode,
+		Domain:   errorDomain,
+		Metadata: metadata,
+	}); err == nil {
+		st = d
+	}
+	return st.Err()
+}
+
+func invalidArgument(field, msg string) error {
+	return apiError(codes.InvalidArgument, string(errors.CodeValidation), msg, map[string]string{"field": field})
+}
+
+// isValidation reports whether err is a validation error from a shared
+// service (e.g. the job watermark checks); validationError converts it.
+func isValidation(err error) bool {
+	return errors.IsValidation(err)
+}
+
+func validationError(err error) error {
+	e := errors.AsError(err)
+	field, _ := e.Fields["field"].(string)
+	return invalidArgument(field, e.Message)
+}
+
+// grpcCodes maps the generic error codes to gRPC codes.
+var grpcCodes = map[errors.Code]codes.Code{
+	errors.CodeValidation:      codes.InvalidArgument,
+	errors.CodeBadRequest:      codes.InvalidArgument,
+	errors.CodeNotFound:        codes.NotFound,
+	errors.CodeConflict:        codes.Aborted,
+	errors.CodeAlreadyExists:   codes.AlreadyExists,
+	errors.CodeFailedPrecond:   codes.FailedPrecondition,
+	errors.CodeUnauthorized:    codes.Unauthenticated,
+	errors.CodeForbidden:       codes.PermissionDenied,
+	errors.CodeTimeout:         codes.DeadlineExceeded,
+	errors.CodeUnavailable:     codes.Unavailable,
+	errors.CodeResourceExhaust: codes.ResourceExhausted,
+	errors.CodePayloadTooLarge: codes.ResourceExhausted,
+}
+
+// dbError translates a database error (see pgerr) like the REST
+// handlers' writeDBErr and logs it. Not-found is left to callers, which
+// use resource-specific codes.
+func (s *service) dbError(ctx context.Context, err error, op, msg string) error {
+	if ctx.Err() != nil {
+		// Canceled or timed out by the caller
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	e := pgerr.Wrap(err, op, msg)
+	s.log.FromContext(ctx).Error("database error",
+		"op", op,
+		"code", string(e.Code),
+		"error", err.Error(),
+	)
+	code, ok := grpcCodes[e.Code]
+	if !ok {
+		code = codes.Internal
+	}
+	return apiError(code, string(e.Code), msg, nil)
+}
+
+// newRequestID returns a random request ID, like the REST middleware's.
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,254 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: gala/v1/assets.proto
+
+package galav1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	AssetsService_GetAsset_FullMethodName         = "/gala.v1.AssetsService/GetAsset"
+	AssetsService_ListAssets_FullMethodName       = "/gala.v1.AssetsService/ListAssets"
+	AssetsService_ReadAssetContent_FullMethodName = "/gala.v1.AssetsService/ReadAssetContent"
+	AssetsService_DeleteAsset_FullMethodName      = "/gala.v1.AssetsService/DeleteAsset"
+)
+
+// AssetsServiceClient is the client API for AssetsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AssetsService reads and deletes stored assets. Uploads go through the
+// REST API (POST /v1/assets).
+type AssetsServiceClient interface {
+	GetAsset(ctx context.Context, in *GetAssetRequest, opts ...grpc.CallOption) (*Asset, error)
+	// ListAssets lists assets newest first.
+	ListAssets(ctx context.Context, in *ListAssetsRequest, opts ...grpc.CallOption) (*ListAssetsResponse, error)
+	// ReadAssetContent streams the asset's file in chunks; the first one
+	// carries the content type and size.
+	ReadAssetContent(ctx context.Context, in *ReadAssetContentRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AssetChunk], error)
+	// DeleteAsset deletes an asset no job output references.
+	DeleteAsset(ctx context.Context, in *DeleteAssetRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type assetsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAssetsServiceClient(cc grpc.ClientConnInterface) AssetsServiceClient {
+	return &assetsServiceClient{cc}
+}
+
+func (c *assetsServiceClient) GetAsset(ctx context.Context, in *GetAssetRequest, opts ...grpc.CallOption) (*Asset, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Asset)
+	err := c.cc.Invoke(ctx, AssetsService_GetAsset_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *assetsServiceClient) ListAssets(ctx context.Context, in *ListAssetsRequest, opts ...grpc.CallOption) (*ListAssetsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAssetsResponse)
+	err := c.cc.Invoke(ctx, AssetsService_ListAssets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *assetsServiceClient) ReadAssetContent(ctx context.Context, in *ReadAssetContentRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AssetChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AssetsService_ServiceDesc.Streams[0], AssetsService_ReadAssetContent_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ReadAssetContentRequest, AssetChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AssetsService_ReadAssetContentClient = grpc.ServerStreamingClient[AssetChunk]
+
+func (c *assetsServiceClient) DeleteAsset(ctx context.Context, in *DeleteAssetRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, AssetsService_DeleteAsset_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AssetsServiceServer is the server API for AssetsService service.
+// All implementations must embed UnimplementedAssetsServiceServer
+// for forward compatibility.
+//
+// AssetsService reads and deletes stored assets. Uploads go through the
+// REST API (POST /v1/assets).
+type AssetsServiceServer interface {
+	GetAsset(context.Context, *GetAssetRequest) (*Asset, error)
+	// ListAssets lists assets newest first.
+	ListAssets(context.Context, *ListAssetsRequest) (*ListAssetsResponse, error)
+	// ReadAssetContent streams the asset's file in chunks; the first one
+	// carries the content type and size.
+	ReadAssetContent(*ReadAssetContentRequest, grpc.ServerStreamingServer[AssetChunk]) error
+	// DeleteAsset deletes an asset no job output references.
+	DeleteAsset(context.Context, *DeleteAssetRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedAssetsServiceServer()
+}
+
+// UnimplementedAssetsServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAssetsServiceServer struct{}
+
+func (UnimplementedAssetsServiceServer) GetAsset(context.Context, *GetAssetRequest) (*Asset, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAsset not implemented")
+}
+func (UnimplementedAssetsServiceServer) ListAssets(context.Context, *ListAssetsRequest) (*ListAssetsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAssets not implemented")
+}
+func (UnimplementedAssetsServiceServer) ReadAssetContent(*ReadAssetContentRequest, grpc.ServerStreamingServer[AssetChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method ReadAssetContent not implemented")
+}
+func (UnimplementedAssetsServiceServer) DeleteAsset(context.Context, *DeleteAssetRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAsset not implemented")
+}
+func (UnimplementedAssetsServiceServer) mustEmbedUnimplementedAssetsServiceServer() {}
+func (UnimplementedAssetsServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeAssetsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AssetsServiceServer will
+// result in compilation errors.
+type UnsafeAssetsServiceServer interface {
+	mustEmbedUnimplementedAssetsServiceServer()
+}
+
+func RegisterAssetsServiceServer(s grpc.ServiceRegistrar, srv AssetsServiceServer) {
+	// If the following call pancis, it indicates UnimplementedAssetsServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AssetsService_ServiceDesc, srv)
+}
+
+func _AssetsService_GetAsset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAssetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AssetsServiceServer).GetAsset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AssetsService_GetAsset_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AssetsServiceServer).GetAsset(ctx, req.(*GetAssetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AssetsService_ListAssets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAssetsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AssetsServiceServer).ListAssets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AssetsService_ListAssets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AssetsServiceServer).ListAssets(ctx, req.(*ListAssetsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AssetsService_ReadAssetContent_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReadAssetContentRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AssetsServiceServer).ReadAssetContent(m, &grpc.GenericServerStream[ReadAssetContentRequest, AssetChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AssetsService_ReadAssetContentServer = grpc.ServerStreamingServer[AssetChunk]
+
+func _AssetsService_DeleteAsset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAssetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AssetsServiceServer).DeleteAsset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AssetsService_DeleteAsset_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AssetsServiceServer).DeleteAsset(ctx, req.(*DeleteAssetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AssetsService_ServiceDesc is the grpc.ServiceDesc for AssetsService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AssetsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gala.v1.AssetsService",
+	HandlerType: (*AssetsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetAsset",
+			Handler:    _AssetsService_GetAsset_Handler,
+		},
+		{
+			MethodName: "ListAssets",
+			Handler:    _AssetsService_ListAssets_Handler,
+		},
+		{
+			MethodName: "DeleteAsset",
+			Handler:    _AssetsService_DeleteAsset_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ReadAssetContent",
+			Handler:       _AssetsService_ReadAssetContent_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gala/v1/assets.proto",
+}
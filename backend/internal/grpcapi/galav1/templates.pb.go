@@ -0,0 +1,708 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        v5.29.3
+// source: gala/v1/templates.proto
+
+package galav1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TemplateFormat struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Width         int32                  `protobuf:"varint,1,opt,name=width,proto3" json:"width,omitempty"`
+	Height        int32                  `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Fps           int32                  `protobuf:"varint,3,opt,name=fps,proto3" json:"fps,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TemplateFormat) Reset() {
+	*x = TemplateFormat{}
+	mi := &file_gala_v1_templates_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TemplateFormat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TemplateFormat) ProtoMessage() {}
+
+func (x *TemplateFormat) ProtoReflect() protoreflect.Message {
+	mi := &file_gala_v1_templates_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TemplateFormat.ProtoReflect.Descriptor instead.
+func (*TemplateFormat) Descriptor() ([]byte, []int) {
+	return file_gala_v1_templates_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *TemplateFormat) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *TemplateFormat) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *TemplateFormat) GetFps() int32 {
+	if x != nil {
+		return x.Fps
+	}
+	return 0
+}
+
+type Template struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	DurationMs    *int32                 `protobuf:"varint,4,opt,name=duration_ms,json=durationMs,proto3,oneof" json:"duration_ms,omitempty"`
+	Format        *TemplateFormat        `protobuf:"bytes,5,opt,name=format,proto3" json:"format,omitempty"`
+	ParamsSchema  *structpb.Struct       `protobuf:"bytes,6,opt,name=params_schema,json=paramsSchema,proto3" json:"params_schema,omitempty"`
+	Defaults      *structpb.Struct       `protobuf:"bytes,7,opt,name=defaults,proto3" json:"defaults,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Template) Reset() {
+	*x = Template{}
+	mi := &file_gala_v1_templates_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Template) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Template) ProtoMessage() {}
+
+func (x *Template) ProtoReflect() protoreflect.Message {
+	mi := &file_gala_v1_templates_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Template.ProtoReflect.Descriptor instead.
+func (*Template) Descriptor() ([]byte, []int) {
+	return file_gala_v1_templates_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Template) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Template) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Template) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Template) GetDurationMs() int32 {
+	if x != nil && x.DurationMs != nil {
+		return *x.DurationMs
+	}
+	return 0
+}
+
+func (x *Template) GetFormat() *TemplateFormat {
+	if x != nil {
+		return x.Format
+	}
+	return nil
+}
+
+func (x *Template) GetParamsSchema() *structpb.Struct {
+	if x != nil {
+		return x.ParamsSchema
+	}
+	return nil
+}
+
+func (x *Template) GetDefaults() *structpb.Struct {
+	if x != nil {
+		return x.Defaults
+	}
+	return nil
+}
+
+func (x *Template) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Template) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type CreateTemplateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	DurationMs    *int32                 `protobuf:"varint,3,opt,name=duration_ms,json=durationMs,proto3,oneof" json:"duration_ms,omitempty"`
+	Format        *TemplateFormat        `protobuf:"bytes,4,opt,name=format,proto3" json:"format,omitempty"`
+	ParamsSchema  *structpb.Struct       `protobuf:"bytes,5,opt,name=params_schema,json=paramsSchema,proto3" json:"params_schema,omitempty"`
+	Defaults      *structpb.Struct       `protobuf:"bytes,6,opt,name=defaults,proto3" json:"defaults,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateTemplateRequest) Reset() {
+	*x = CreateTemplateRequest{}
+	mi := &file_gala_v1_templates_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTemplateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTemplateRequest) ProtoMessage() {}
+
+func (x *CreateTemplateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gala_v1_templates_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTemplateRequest.ProtoReflect.Descriptor instead.
+func (*CreateTemplateRequest) Descriptor() ([]byte, []int) {
+	return file_gala_v1_templates_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreateTemplateRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *CreateTemplateRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateTemplateRequest) GetDurationMs() int32 {
+	if x != nil && x.DurationMs != nil {
+		return *x.DurationMs
+	}
+	return 0
+}
+
+func (x *CreateTemplateRequest) GetFormat() *TemplateFormat {
+	if x != nil {
+		return x.Format
+	}
+	return nil
+}
+
+func (x *CreateTemplateRequest) GetParamsSchema() *structpb.Struct {
+	if x != nil {
+		return x.ParamsSchema
+	}
+	return nil
+}
+
+func (x *CreateTemplateRequest) GetDefaults() *structpb.Struct {
+	if x != nil {
+		return x.Defaults
+	}
+	return nil
+}
+
+type GetTemplateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTemplateRequest) Reset() {
+	*x = GetTemplateRequest{}
+	mi := &file_gala_v1_templates_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTemplateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTemplateRequest) ProtoMessage() {}
+
+func (x *GetTemplateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gala_v1_templates_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTemplateRequest.ProtoReflect.Descriptor instead.
+func (*GetTemplateRequest) Descriptor() ([]byte, []int) {
+	return file_gala_v1_templates_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetTemplateRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ListTemplatesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// page_size defaults to 50, up to 200.
+	PageSize      int32  `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken     string `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTemplatesRequest) Reset() {
+	*x = ListTemplatesRequest{}
+	mi := &file_gala_v1_templates_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTemplatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTemplatesRequest) ProtoMessage() {}
+
+func (x *ListTemplatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gala_v1_templates_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTemplatesRequest.ProtoReflect.Descriptor instead.
+func (*ListTemplatesRequest) Descriptor() ([]byte, []int) {
+	return file_gala_v1_templates_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListTemplatesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListTemplatesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListTemplatesResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Templates []*Template            `protobuf:"bytes,1,rep,name=templates,proto3" json:"templates,omitempty"`
+	// next_page_token is empty on the last page.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTemplatesResponse) Reset() {
+	*x = ListTemplatesResponse{}
+	mi := &file_gala_v1_templates_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTemplatesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTemplatesResponse) ProtoMessage() {}
+
+func (x *ListTemplatesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gala_v1_templates_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTemplatesResponse.ProtoReflect.Descriptor instead.
+func (*ListTemplatesResponse) Descriptor() ([]byte, []int) {
+	return file_gala_v1_templates_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListTemplatesResponse) GetTemplates() []*Template {
+	if x != nil {
+		return x.Templates
+	}
+	return nil
+}
+
+func (x *ListTemplatesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type UpdateTemplateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          *string                `protobuf:"bytes,2,opt,name=type,proto3,oneof" json:"type,omitempty"`
+	Name          *string                `protobuf:"bytes,3,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	DurationMs    *int32                 `protobuf:"varint,4,opt,name=duration_ms,json=durationMs,proto3,oneof" json:"duration_ms,omitempty"`
+	Format        *TemplateFormat        `protobuf:"bytes,5,opt,name=format,proto3" json:"format,omitempty"`
+	ParamsSchema  *structpb.Struct       `protobuf:"bytes,6,opt,name=params_schema,json=paramsSchema,proto3" json:"params_schema,omitempty"`
+	Defaults      *structpb.Struct       `protobuf:"bytes,7,opt,name=defaults,proto3" json:"defaults,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateTemplateRequest) Reset() {
+	*x = UpdateTemplateRequest{}
+	mi := &file_gala_v1_templates_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateTemplateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateTemplateRequest) ProtoMessage() {}
+
+func (x *UpdateTemplateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gala_v1_templates_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateTemplateRequest.ProtoReflect.Descriptor instead.
+func (*UpdateTemplateRequest) Descriptor() ([]byte, []int) {
+	return file_gala_v1_templates_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateTemplateRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdateTemplateRequest) GetType() string {
+	if x != nil && x.Type != nil {
+		return *x.Type
+	}
+	return ""
+}
+
+func (x *UpdateTemplateRequest) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *UpdateTemplateRequest) GetDurationMs() int32 {
+	if x != nil && x.DurationMs != nil {
+		return *x.DurationMs
+	}
+	return 0
+}
+
+func (x *UpdateTemplateRequest) GetFormat() *TemplateFormat {
+	if x != nil {
+		return x.Format
+	}
+	return nil
+}
+
+func (x *UpdateTemplateRequest) GetParamsSchema() *structpb.Struct {
+	if x != nil {
+		return x.ParamsSchema
+	}
+	return nil
+}
+
+func (x *UpdateTemplateRequest) GetDefaults() *structpb.Struct {
+	if x != nil {
+		return x.Defaults
+	}
+	return nil
+}
+
+type DeleteTemplateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteTemplateRequest) Reset() {
+	*x = DeleteTemplateRequest{}
+	mi := &file_gala_v1_templates_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteTemplateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteTemplateRequest) ProtoMessage() {}
+
+func (x *DeleteTemplateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gala_v1_templates_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteTemplateRequest.ProtoReflect.Descriptor instead.
+func (*DeleteTemplateRequest) Descriptor() ([]byte, []int) {
+	return file_gala_v1_templates_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeleteTemplateRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+var File_gala_v1_templates_proto protoreflect.FileDescriptor
+
+const file_gala_v1_templates_proto_rawDesc = "" +
+	"\n" +
+	"\x17gala/v1/templates.proto\x12\agala.v1\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"P\n" +
+	"\x0eTemplateFormat\x12\x14\n" +
+	"\x05width\x18\x01 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x02 \x01(\x05R\x06height\x12\x10\n" +
+	"\x03fps\x18\x03 \x01(\x05R\x03fps\"\x92\x03\n" +
+	"\bTemplate\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12$\n" +
+	"\vduration_ms\x18\x04 \x01(\x05H\x00R\n" +
+	"durationMs\x88\x01\x01\x12/\n" +
+	"\x06format\x18\x05 \x01(\v2\x17.gala.v1.TemplateFormatR\x06format\x12<\n" +
+	"\rparams_schema\x18\x06 \x01(\v2\x17.google.protobuf.StructR\fparamsSchema\x123\n" +
+	"\bdefaults\x18\a \x01(\v2\x17.google.protobuf.StructR\bdefaults\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\x0e\n" +
+	"\f_duration_ms\"\x99\x02\n" +
+	"\x15CreateTemplateRequest\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12$\n" +
+	"\vduration_ms\x18\x03 \x01(\x05H\x00R\n" +
+	"durationMs\x88\x01\x01\x12/\n" +
+	"\x06format\x18\x04 \x01(\v2\x17.gala.v1.TemplateFormatR\x06format\x12<\n" +
+	"\rparams_schema\x18\x05 \x01(\v2\x17.google.protobuf.StructR\fparamsSchema\x123\n" +
+	"\bdefaults\x18\x06 \x01(\v2\x17.google.protobuf.StructR\bdefaultsB\x0e\n" +
+	"\f_duration_ms\"$\n" +
+	"\x12GetTemplateRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"R\n" +
+	"\x14ListTemplatesRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\"p\n" +
+	"\x15ListTemplatesResponse\x12/\n" +
+	"\ttemplates\x18\x01 \x03(\v2\x11.gala.v1.TemplateR\ttemplates\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"\xc5\x02\n" +
+	"\x15UpdateTemplateRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\x04type\x18\x02 \x01(\tH\x00R\x04type\x88\x01\x01\x12\x17\n" +
+	"\x04name\x18\x03 \x01(\tH\x01R\x04name\x88\x01\x01\x12$\n" +
+	"\vduration_ms\x18\x04 \x01(\x05H\x02R\n" +
+	"durationMs\x88\x01\x01\x12/\n" +
+	"\x06format\x18\x05 \x01(\v2\x17.gala.v1.TemplateFormatR\x06format\x12<\n" +
+	"\rparams_schema\x18\x06 \x01(\v2\x17.google.protobuf.StructR\fparamsSchema\x123\n" +
+	"\bdefaults\x18\a \x01(\v2\x17.google.protobuf.StructR\bdefaultsB\a\n" +
+	"\x05_typeB\a\n" +
+	"\x05_nameB\x0e\n" +
+	"\f_duration_ms\"'\n" +
+	"\x15DeleteTemplateRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id2\xf5\x02\n" +
+	"\x10TemplatesService\x12C\n" +
+	"\x0eCreateTemplate\x12\x1e.gala.v1.CreateTemplateRequest\x1a\x11.gala.v1.Template\x12=\n" +
+	"\vGetTemplate\x12\x1b.gala.v1.GetTemplateRequest\x1a\x11.gala.v1.Template\x12N\n" +
+	"\rListTemplates\x12\x1d.gala.v1.ListTemplatesRequest\x1a\x1e.gala.v1.ListTemplatesResponse\x12C\n" +
+	"\x0eUpdateTemplate\x12\x1e.gala.v1.UpdateTemplateRequest\x1a\x11.gala.v1.Template\x12H\n" +
+	"\x0eDeleteTemplate\x12\x1e.gala.v1.DeleteTemplateRequest\x1a\x16.google.protobuf.EmptyB\x1eZ\x1cgala/internal/grpcapi/galav1b\x06proto3"
+
+var (
+	file_gala_v1_templates_proto_rawDescOnce sync.Once
+	file_gala_v1_templates_proto_rawDescData []byte
+)
+
+func file_gala_v1_templates_proto_rawDescGZIP() []byte {
+	file_gala_v1_templates_proto_rawDescOnce.Do(func() {
+		file_gala_v1_templates_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_gala_v1_templates_proto_rawDesc), len(file_gala_v1_templates_proto_rawDesc)))
+	})
+	return file_gala_v1_templates_proto_rawDescData
+}
+
+var file_gala_v1_templates_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_gala_v1_templates_proto_goTypes = []any{
+	(*TemplateFormat)(nil),        // 0: gala.v1.TemplateFormat
+	(*Template)(nil),              // 1: gala.v1.Template
+	(*CreateTemplateRequest)(nil), // 2: gala.v1.CreateTemplateRequest
+	(*GetTemplateRequest)(nil),    // 3: gala.v1.GetTemplateRequest
+	(*ListTemplatesRequest)(nil),  // 4: gala.v1.ListTemplatesRequest
+	(*ListTemplatesResponse)(nil), // 5: gala.v1.ListTemplatesResponse
+	(*UpdateTemplateRequest)(nil), // 6: gala.v1.UpdateTemplateRequest
+	(*DeleteTemplateRequest)(nil), // 7: gala.v1.DeleteTemplateRequest
+	(*structpb.Struct)(nil),       // 8: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil), // 9: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),         // 10: google.protobuf.Empty
+}
+var file_gala_v1_templates_proto_depIdxs = []int32{
+	0,  // 0: gala.v1.Template.format:type_name -> gala.v1.TemplateFormat
+	8,  // 1: gala.v1.Template.params_schema:type_name -> google.protobuf.Struct
+	8,  // 2: gala.v1.Template.defaults:type_name -> google.protobuf.Struct
+	9,  // 3: gala.v1.Template.created_at:type_name -> google.protobuf.Timestamp
+	9,  // 4: gala.v1.Template.updated_at:type_name -> google.protobuf.Timestamp
+	0,  // 5: gala.v1.CreateTemplateRequest.format:type_name -> gala.v1.TemplateFormat
+	8,  // 6: gala.v1.CreateTemplateRequest.params_schema:type_name -> google.protobuf.Struct
+	8,  // 7: gala.v1.CreateTemplateRequest.defaults:type_name -> google.protobuf.Struct
+	1,  // 8: gala.v1.ListTemplatesResponse.templates:type_name -> gala.v1.Template
+	0,  // 9: gala.v1.UpdateTemplateRequest.format:type_name -> gala.v1.TemplateFormat
+	8,  // 10: gala.v1.UpdateTemplateRequest.params_schema:type_name -> google.protobuf.Struct
+	8,  // 11: gala.v1.UpdateTemplateRequest.defaults:type_name -> google.protobuf.Struct
+	2,  // 12: gala.v1.TemplatesService.CreateTemplate:input_type -> gala.v1.CreateTemplateRequest
+	3,  // 13: gala.v1.TemplatesService.GetTemplate:input_type -> gala.v1.GetTemplateRequest
+	4,  // 14: gala.v1.TemplatesService.ListTemplates:input_type -> gala.v1.ListTemplatesRequest
+	6,  // 15: gala.v1.TemplatesService.UpdateTemplate:input_type -> gala.v1.UpdateTemplateRequest
+	7,  // 16: gala.v1.TemplatesService.DeleteTemplate:input_type -> gala.v1.DeleteTemplateRequest
+	1,  // 17: gala.v1.TemplatesService.CreateTemplate:output_type -> gala.v1.Template
+	1,  // 18: gala.v1.TemplatesService.GetTemplate:output_type -> gala.v1.Template
+	5,  // 19: gala.v1.TemplatesService.ListTemplates:output_type -> gala.v1.ListTemplatesResponse
+	1,  // 20: gala.v1.TemplatesService.UpdateTemplate:output_type -> gala.v1.Template
+	10, // 21: gala.v1.TemplatesService.DeleteTemplate:output_type -> google.protobuf.Empty
+	17, // [17:22] is the sub-list for method output_type
+	12, // [12:17] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
+}
+
+func init() { file_gala_v1_templates_proto_init() }
+func file_gala_v1_templates_proto_init() {
+	if File_gala_v1_templates_proto != nil {
+		return
+	}
+	file_gala_v1_templates_proto_msgTypes[1].OneofWrappers = []any{}
+	file_gala_v1_templates_proto_msgTypes[2].OneofWrappers = []any{}
+	file_gala_v1_templates_proto_msgTypes[6].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_gala_v1_templates_proto_rawDesc), len(file_gala_v1_templates_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_gala_v1_templates_proto_goTypes,
+		DependencyIndexes: file_gala_v1_templates_proto_depIdxs,
+		MessageInfos:      file_gala_v1_templates_proto_msgTypes,
+	}.Build()
+	File_gala_v1_templates_proto = out.File
+	file_gala_v1_templates_proto_goTypes = nil
+	file_gala_v1_templates_proto_depIdxs = nil
+}
@@ -0,0 +1,159 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"gala/internal/grpcapi/galav1"
+	"gala/internal/jobs"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/store"
+)
+
+var jobStatuses = map[string]galav1.JobStatus{
+	// The proto has no scheduled status yet: a scheduled job waits like a
+	// queued one.
+	store.JobScheduled: galav1.JobStatus_JOB_STATUS_QUEUED,
+	store.JobQueued:    galav1.JobStatus_JOB_STATUS_QUEUED,
+	store.JobRunning:   galav1.JobStatus_JOB_STATUS_RUNNING,
+	store.JobDone:      galav1.JobStatus_JOB_STATUS_DONE,
+	store.JobFailed:    galav1.JobStatus_JOB_STATUS_FAILED,
+	store.JobCanceled:  galav1.JobStatus_JOB_STATUS_CANCELED,
+}
+
+func jobNotFound(id string) error {
+	return apiError(codes.NotFound, "JOB_NOT_FOUND", "job not found", map[string]string{"job_id": id})
+}
+
+func (s jobsServer) CreateJob(ctx context.Context, req *galav1.CreateJobRequest) (*galav1.Job, error) {
+	spec := jobs.Spec{
+		TemplateID: strings.TrimSpace(req.GetTemplateId()),
+		Inputs:     req.GetInputs(),
+		Params:     req.GetParams().AsMap(),
+	}
+	if spec.Inputs == nil {
+		spec.Inputs = map[string]string{}
+	}
+	// Legacy path stays stable
+	if _, ok := spec.Params["text"]; !ok && spec.TemplateID == "" {
+		return nil, invalidArgument("params.text", "params.text is required")
+	}
+
+	j, _, err := s.jobs.Create(ctx, strings.TrimSpace(req.GetName()), spec)
+	switch {
+	case errors.Is(err, jobs.ErrTemplateNotFound):
+		return nil, apiError(codes.NotFound, "TEMPLATE_NOT_FOUND", "template not found", map[string]string{"template_id": spec.TemplateID})
+	case errors.Is(err, jobs.ErrQueuePush):
+		return nil, apiError(codes.Unavailable, "UNAVAILABLE", "job queue unavailable", nil)
+	case isValidation(err):
+		return nil, validationError(err)
+	case err != nil:
+		return nil, s.dbError(ctx, err, "jobs.create", "db insert failed")
+	}
+	return toJob(j), nil
+}
+
+func (s jobsServer) GetJob(ctx context.Context, req *galav1.GetJobRequest) (*galav1.Job, error) {
+	j, err := store.GetJob(ctx, s.pool, req.GetId())
+	if err != nil {
+		if pgerr.IsNoRows(err) {
+			return nil, jobNotFound(req.GetId())
+		}
+		return nil, s.dbError(ctx, err, "jobs.get", "db query failed")
+	}
+
+	outputs, err := store.ListJobOutputs(ctx, s.pool, j.ID)
+	if err != nil && !pgerr.IsUndefinedTable(err) {
+		return nil, s.dbError(ctx, err, "jobs.get", "db outputs query failed")
+	}
+	out := toJob(j)
+	for _, o := range outputs {
+		out.Outputs = append(out.Outputs, &galav1.JobOutput{
+			Variant:          int32(o.Variant),
+			VideoAssetId:     o.VideoAssetID,
+			ThumbnailAssetId: o.ThumbnailAssetID,
+			CaptionsAssetId:  o.CaptionsAssetID,
+		})
+	}
+	return out, nil
+}
+
+func (s jobsServer) ListJobs(ctx context.Context, req *galav1.ListJobsRequest) (*galav1.ListJobsResponse, error) {
+	limit, after, err := parsePage(req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	var f store.JobFilter
+	if st := req.GetStatus(); st != galav1.JobStatus_JOB_STATUS_UNSPECIFIED {
+		f.Status = strings.TrimPrefix(st.String(), "JOB_STATUS_")
+	}
+
+	// Fetch one extra row to know whether there is a next page.
+	rows, err := store.ListJobs(ctx, s.reader(), f, after, limit+1)
+	if err != nil {
+		return nil, s.dbError(ctx, err, "jobs.list", "db query failed")
+	}
+	rows, next := nextPage(rows, limit, func(j store.Job) (time.Time, string) { return j.CreatedAt, j.ID })
+
+	resp := &galav1.ListJobsResponse{NextPageToken: next}
+	for _, j := range rows {
+		resp.Jobs = append(resp.Jobs, toJob(j))
+	}
+	return resp, nil
+}
+
+func (s jobsServer) WatchJob(req *galav1.WatchJobRequest, stream grpc.ServerStreamingServer[galav1.JobStatusEvent]) error {
+	ctx := stream.Context()
+	err := s.jobs.Watch(ctx, req.GetId(), s.watch, func(j store.Job) error {
+		ev := &galav1.JobStatusEvent{
+			JobId:     j.ID,
+			Status:    jobStatuses[j.Status],
+			UpdatedAt: timestamppb.New(j.UpdatedAt),
+			Error:     j.ErrorText,
+		}
+		return stream.Send(ev)
+	})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, jobs.ErrJobNotFound):
+		return jobNotFound(req.GetId())
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	case status.Code(err) != codes.Unknown:
+		// Send failed
+		return err
+	}
+	return s.dbError(ctx, err, "jobs.watch", "db query failed")
+}
+
+// toJob converts a job row; outputs are added by GetJob.
+func toJob(j store.Job) *galav1.Job {
+	spec := jobs.ParseSpec(j.ParamsJSON)
+	out := &galav1.Job{
+		Id:         j.ID,
+		Name:       j.Name,
+		Status:     jobStatuses[j.Status],
+		TemplateId: spec.TemplateID,
+		CreatedAt:  timestamppb.New(j.CreatedAt),
+		UpdatedAt:  timestamppb.New(j.UpdatedAt),
+		StartedAt:  timestamp(j.StartedAt),
+		FinishedAt: timestamp(j.FinishedAt),
+		Error:      j.ErrorText,
+	}
+	if len(spec.Inputs) > 0 {
+		out.Inputs = spec.Inputs
+	}
+	if p, err := structpb.NewStruct(spec.Params); err == nil {
+		out.Params = p
+	}
+	return out
+}
@@ -0,0 +1,266 @@
+// Package grpcapi serves the Jobs, Templates and Assets services of
+// proto/gala/v1 over gRPC, for internal services that prefer it to the
+// REST API. It shares the store, jobs and admin layers with the REST
+// handlers.
+package grpcapi
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"gala/internal/admin"
+	"gala/internal/events"
+	"gala/internal/grpcapi/galav1"
+	"gala/internal/jobs"
+	"gala/internal/pkg/dbpool"
+	"gala/internal/pkg/logger"
+	"gala/internal/ports"
+	"gala/internal/worker/queue"
+)
+
+// requestIDKey is the metadata key carrying the request ID, like the
+// REST API's X-Request-ID header.
+const requestIDKey = "x-request-id"
+
+// DefaultWatchInterval is how often WatchJob polls the job.
+const DefaultWatchInterval = time.Second
+
+type Deps struct {
+	Pool *pgxpool.Pool
+	// DB routes list reads to read replicas; nil uses Pool only.
+	DB  *dbpool.Router
+	RDB redis.UniversalClient
+	SP  ports.StorageProvider
+	Log *logger.Logger
+	// QueueMode is the worker job source; in "postgres" mode new jobs are
+	// not pushed to Redis (see queue.ModePostgres).
+	QueueMode string
+	// WatchInterval is how often WatchJob polls; default
+	// DefaultWatchInterval.
+	WatchInterval time.Duration
+	// Events receives the lifecycle events; nil publishes none.
+	Events *events.Bus
+	// DedupWindow reuses DONE jobs identical to a new one for this long;
+	// 0 renders every job.
+	DedupWindow time.Duration
+}
+
+// Server is a gRPC server with the GALA services, health and reflection
+// registered.
+type Server struct {
+	*grpc.Server
+	health *health.Server
+}
+
+// NewServer creates the server. Health reports SERVING until
+// SetNotServing is called.
+func NewServer(d Deps) *Server {
+	log := d.Log
+	if log == nil {
+		log = logger.NewDefault()
+	}
+	log = log.WithComponent("grpc")
+
+	db := d.DB
+	if db == nil {
+		db = dbpool.NewRouter(d.Pool)
+	}
+	watch := d.WatchInterval
+	if watch <= 0 {
+		watch = DefaultWatchInterval
+	}
+	pushJobs := d.QueueMode != queue.ModePostgres
+
+	gs := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryRequestID, unaryLogging(log), unaryRecovery(log)),
+		grpc.ChainStreamInterceptor(streamRequestID, streamLogging(log), streamRecovery(log)),
+	)
+	s := &service{
+		pool:  d.Pool,
+		db:    db,
+		sp:    d.SP,
+		log:   log,
+		ev:    d.Events,
+		jobs:  jobs.New(d.Pool, d.RDB, d.Events, queue.DefaultName, pushJobs).WithDedup(d.DedupWindow),
+		admin: admin.New(d.Pool, d.RDB, d.SP, d.Events, queue.DefaultName, pushJobs),
+		watch: watch,
+	}
+	galav1.RegisterJobsServiceServer(gs, jobsServer{service: s})
+	galav1.RegisterTemplatesServiceServer(gs, templatesServer{service: s})
+	galav1.RegisterAssetsServiceServer(gs, assetsServer{service: s})
+
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(gs, hs)
+	reflection.Register(gs)
+
+	return &Server{Server: gs, health: hs}
+}
+
+// SetNotServing makes health checks fail, e.g. while draining before a
+// shutdown.
+func (s *Server) SetNotServing() {
+	s.health.Shutdown()
+}
+
+// GracefulStopContext stops accepting RPCs and waits for the running
+// ones, or stops hard when ctx is done.
+func (s *Server) GracefulStopContext(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.Server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.Server.Stop()
+		return ctx.Err()
+	}
+}
+
+// service holds what the three services share.
+type service struct {
+	pool  *pgxpool.Pool
+	db    *dbpool.Router
+	sp    ports.StorageProvider
+	log   *logger.Logger
+	ev    *events.Bus
+	jobs  *jobs.Service
+	admin *admin.Service
+	watch time.Duration
+}
+
+// reader returns the pool for list queries, which may lag the primary.
+func (s *service) reader() *pgxpool.Pool {
+	return s.db.Reader()
+}
+
+type jobsServer struct {
+	galav1.UnimplementedJobsServiceServer
+	*service
+}
+
+type templatesServer struct {
+	galav1.UnimplementedTemplatesServiceServer
+	*service
+}
+
+type assetsServer struct {
+	galav1.UnimplementedAssetsServiceServer
+	*service
+}
+
+// ---- INTERCEPTORS ----
+
+// withRequestID puts the caller's x-request-id (or a new one) in the
+// context and sends it back in the response header.
+func withRequestID(ctx context.Context) context.Context {
+	id := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(requestIDKey); len(v) > 0 {
+			id = v[0]
+		}
+	}
+	if id == "" {
+		id = newRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+	return logger.ContextWithRequestID(ctx, id)
+}
+
+func unaryRequestID(ctx context.Context, req any, _ *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+	return next(withRequestID(ctx), req)
+}
+
+func streamRequestID(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, next grpc.StreamHandler) error {
+	return next(srv, &ctxStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+}
+
+// ctxStream replaces a stream's context.
+type ctxStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *ctxStream) Context() context.Context { return s.ctx }
+
+func unaryLogging(log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := next(ctx, req)
+		logRPC(log, ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func streamLogging(log *logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, next grpc.StreamHandler) error {
+		start := time.Now()
+		err := next(srv, ss)
+		logRPC(log, ss.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
+
+// logRPC logs a completed RPC like the REST access log: errors for
+// server-side codes, warnings for the rest of the failures.
+func logRPC(log *logger.Logger, ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	l := log.FromContext(ctx)
+	logFn := l.Info
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss, codes.Unimplemented:
+		logFn = l.Error
+	default:
+		logFn = l.Warn
+	}
+	logFn("rpc completed",
+		"method", method,
+		"code", code.String(),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+}
+
+func unaryRecovery(log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = recovered(log, ctx, info.FullMethod, rec)
+			}
+		}()
+		return next(ctx, req)
+	}
+}
+
+func streamRecovery(log *logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, next grpc.StreamHandler) (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = recovered(log, ss.Context(), info.FullMethod, rec)
+			}
+		}()
+		return next(srv, ss)
+	}
+}
+
+func recovered(log *logger.Logger, ctx context.Context, method string, rec any) error {
+	log.FromContext(ctx).Error("panic recovered",
+		"method", method,
+		"panic", rec,
+		"stack", string(debug.Stack()),
+	)
+	return status.Error(codes.Internal, "internal error")
+}
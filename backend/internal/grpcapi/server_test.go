@@ -0,0 +1,150 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"gala/internal/grpcapi/galav1"
+	"gala/internal/pkg/logger"
+)
+
+// newTestClient serves a Server without database over an in-memory
+// listener; only the paths that fail before touching the store work.
+func newTestClient(t *testing.T) (*Server, *grpc.ClientConn) {
+	t.Helper()
+	srv := NewServer(Deps{Log: logger.New(logger.Config{Output: io.Discard})})
+	ln := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return srv, conn
+}
+
+// errorInfo returns the code and ErrorInfo detail of err.
+func errorInfo(t *testing.T, err error) (codes.Code, *errdetails.ErrorInfo) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	if !ok {
+		t.Fatalf("expected a status error, got %v", err)
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return st.Code(), info
+		}
+	}
+	t.Fatalf("status %v has no ErrorInfo", st)
+	return 0, nil
+}
+
+func TestValidationErrors(t *testing.T) {
+	_, conn := newTestClient(t)
+	ctx := context.Background()
+	jobs := galav1.NewJobsServiceClient(conn)
+	templates := galav1.NewTemplatesServiceClient(conn)
+
+	params, _ := structpb.NewStruct(map[string]any{"color": "red"})
+	blank := " "
+
+	tests := []struct {
+		name  string
+		call  func() error
+		field string
+	}{
+		{"job without text", func() error {
+			_, err := jobs.CreateJob(ctx, &galav1.CreateJobRequest{Params: params})
+			return err
+		}, "params.text"},
+		{"negative page size", func() error {
+			_, err := jobs.ListJobs(ctx, &galav1.ListJobsRequest{PageSize: -1})
+			return err
+		}, "page_size"},
+		{"bad page token", func() error {
+			_, err := templates.ListTemplates(ctx, &galav1.ListTemplatesRequest{PageToken: "nope"})
+			return err
+		}, "page_token"},
+		{"template without type", func() error {
+			_, err := templates.CreateTemplate(ctx, &galav1.CreateTemplateRequest{Name: "n"})
+			return err
+		}, "type"},
+		{"template blank name", func() error {
+			_, err := templates.UpdateTemplate(ctx, &galav1.UpdateTemplateRequest{Id: "tpl_1", Name: &blank})
+			return err
+		}, "name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, info := errorInfo(t, tt.call())
+			if code != codes.InvalidArgument {
+				t.Errorf("expected InvalidArgument, got %v", code)
+			}
+			if info.Reason != "VALIDATION_ERROR" || info.Domain != errorDomain {
+				t.Errorf("unexpected ErrorInfo %s/%s", info.Domain, info.Reason)
+			}
+			if info.Metadata["field"] != tt.field {
+				t.Errorf("expected field %q, got %q", tt.field, info.Metadata["field"])
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	_, conn := newTestClient(t)
+	jobs := galav1.NewJobsServiceClient(conn)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), requestIDKey, "req-123")
+	var header metadata.MD
+	_, _ = jobs.CreateJob(ctx, &galav1.CreateJobRequest{}, grpc.Header(&header))
+	if got := header.Get(requestIDKey); len(got) != 1 || got[0] != "req-123" {
+		t.Errorf("expected request ID echoed, got %v", got)
+	}
+
+	header = nil
+	_, _ = jobs.CreateJob(context.Background(), &galav1.CreateJobRequest{}, grpc.Header(&header))
+	if got := header.Get(requestIDKey); len(got) != 1 || len(got[0]) != 32 {
+		t.Errorf("expected a generated request ID, got %v", got)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	srv, conn := newTestClient(t)
+	client := healthpb.NewHealthClient(conn)
+	ctx := context.Background()
+
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected SERVING, got %v", resp.Status)
+	}
+
+	srv.SetNotServing()
+	resp, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("expected NOT_SERVING after SetNotServing, got %v", resp.Status)
+	}
+}
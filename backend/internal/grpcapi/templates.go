@@ -0,0 +1,200 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"gala/internal/events"
+	"gala/internal/grpcapi/galav1"
+	"gala/internal/httpapi/util"
+	"gala/internal/pkg/db"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/settings"
+	"gala/internal/store"
+)
+
+// templateFormat is how the format column is stored (as the REST API's
+// TemplateFormat).
+type templateFormat struct {
+	Width  int32 `json:"width"`
+	Height int32 `json:"height"`
+	FPS    int32 `json:"fps"`
+}
+
+func templateNotFound(id string) error {
+	return apiError(codes.NotFound, "TEMPLATE_NOT_FOUND", "template not found", map[string]string{"template_id": id})
+}
+
+func templateNameExists() error {
+	return apiError(codes.AlreadyExists, "TEMPLATE_NAME_EXISTS", "template name already exists", map[string]string{"field": "name"})
+}
+
+func (s templatesServer) CreateTemplate(ctx context.Context, req *galav1.CreateTemplateRequest) (*galav1.Template, error) {
+	t := store.Template{
+		ID:           util.NewID("tpl"),
+		Type:         strings.TrimSpace(req.GetType()),
+		Name:         strings.TrimSpace(req.GetName()),
+		Format:       formatJSON(req.GetFormat()),
+		ParamsSchema: structJSON(req.GetParamsSchema()),
+		Defaults:     structJSON(req.GetDefaults()),
+		CreatedAt:    time.Now().UTC().Truncate(time.Microsecond),
+	}
+	t.UpdatedAt = t.CreatedAt
+	if req.DurationMs != nil {
+		d := int(req.GetDurationMs())
+		t.DurationMs = &d
+	}
+	switch {
+	case t.Type == "":
+		return nil, invalidArgument("type", "type is required")
+	case t.Name == "":
+		return nil, invalidArgument("name", "name is required")
+	}
+	ws, err := settings.Load(ctx, s.pool)
+	if err != nil {
+		return nil, s.dbError(ctx, err, "templates.create", "db query failed")
+	}
+	t.Format = ws.FormatJSON(t.Format)
+
+	if err := store.InsertTemplate(ctx, s.pool, t); err != nil {
+		if pgerr.IsUniqueViolation(err) {
+			return nil, templateNameExists()
+		}
+		return nil, s.dbError(ctx, err, "templates.create", "db insert failed")
+	}
+	s.ev.Publish(ctx, events.TemplateCreated, t.ID, map[string]any{"name": t.Name})
+	return toTemplate(t), nil
+}
+
+func (s templatesServer) GetTemplate(ctx context.Context, req *galav1.GetTemplateRequest) (*galav1.Template, error) {
+	t, err := store.GetTemplate(ctx, s.pool, req.GetId())
+	if err != nil {
+		if pgerr.IsNoRows(err) {
+			return nil, templateNotFound(req.GetId())
+		}
+		return nil, s.dbError(ctx, err, "templates.get", "db query failed")
+	}
+	return toTemplate(t), nil
+}
+
+func (s templatesServer) ListTemplates(ctx context.Context, req *galav1.ListTemplatesRequest) (*galav1.ListTemplatesResponse, error) {
+	limit, after, err := parsePage(req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra row to know whether there is a next page.
+	rows, err := store.ListTemplates(ctx, s.pool, after, limit+1)
+	if err != nil {
+		return nil, s.dbError(ctx, err, "templates.list", "db query failed")
+	}
+	rows, next := nextPage(rows, limit, func(t store.Template) (time.Time, string) { return t.CreatedAt, t.ID })
+
+	resp := &galav1.ListTemplatesResponse{NextPageToken: next}
+	for _, t := range rows {
+		resp.Templates = append(resp.Templates, toTemplate(t))
+	}
+	return resp, nil
+}
+
+func (s templatesServer) UpdateTemplate(ctx context.Context, req *galav1.UpdateTemplateRequest) (*galav1.Template, error) {
+	switch {
+	case req.Type != nil && strings.TrimSpace(req.GetType()) == "":
+		return nil, invalidArgument("type", "type must not be blank")
+	case req.Name != nil && strings.TrimSpace(req.GetName()) == "":
+		return nil, invalidArgument("name", "name must not be blank")
+	}
+
+	// Locked until the update commits, as in the REST PATCH
+	err := db.WithTx(ctx, s.pool, func(tx pgx.Tx) error {
+		t, err := store.LockTemplate(ctx, tx, req.GetId())
+		if err != nil {
+			return err
+		}
+		if req.Type != nil {
+			t.Type = strings.TrimSpace(req.GetType())
+		}
+		if req.Name != nil {
+			t.Name = strings.TrimSpace(req.GetName())
+		}
+		if req.DurationMs != nil {
+			d := int(req.GetDurationMs())
+			t.DurationMs = &d
+		}
+		if req.Format != nil {
+			t.Format = formatJSON(req.Format)
+		}
+		if req.ParamsSchema != nil {
+			t.ParamsSchema = structJSON(req.ParamsSchema)
+		}
+		if req.Defaults != nil {
+			t.Defaults = structJSON(req.Defaults)
+		}
+		return store.UpdateTemplate(ctx, tx, t)
+	})
+	if err != nil {
+		if pgerr.IsNoRows(err) {
+			return nil, templateNotFound(req.GetId())
+		}
+		if pgerr.IsUniqueViolation(err) {
+			return nil, templateNameExists()
+		}
+		return nil, s.dbError(ctx, err, "templates.update", "db update failed")
+	}
+	s.ev.Publish(ctx, events.TemplateUpdated, req.GetId(), nil)
+
+	// Fresh read for the timestamps set by the update
+	t, err := store.GetTemplate(ctx, s.pool, req.GetId())
+	if err != nil {
+		return nil, s.dbError(ctx, err, "templates.get", "db query failed")
+	}
+	return toTemplate(t), nil
+}
+
+func (s templatesServer) DeleteTemplate(ctx context.Context, req *galav1.DeleteTemplateRequest) (*emptypb.Empty, error) {
+	deleted, err := store.SoftDeleteTemplate(ctx, s.pool, req.GetId())
+	if err != nil {
+		return nil, s.dbError(ctx, err, "templates.delete", "db delete failed")
+	}
+	if !deleted {
+		return nil, templateNotFound(req.GetId())
+	}
+	s.ev.Publish(ctx, events.TemplateDeleted, req.GetId(), nil)
+	return &emptypb.Empty{}, nil
+}
+
+func formatJSON(f *galav1.TemplateFormat) []byte {
+	if f == nil {
+		return nil
+	}
+	b, _ := json.Marshal(templateFormat{Width: f.GetWidth(), Height: f.GetHeight(), FPS: f.GetFps()})
+	return b
+}
+
+func toTemplate(t store.Template) *galav1.Template {
+	out := &galav1.Template{
+		Id:           t.ID,
+		Type:         t.Type,
+		Name:         t.Name,
+		ParamsSchema: jsonStruct(t.ParamsSchema),
+		Defaults:     jsonStruct(t.Defaults),
+		CreatedAt:    timestamppb.New(t.CreatedAt),
+		UpdatedAt:    timestamppb.New(t.UpdatedAt),
+	}
+	if t.DurationMs != nil {
+		d := int32(*t.DurationMs)
+		out.DurationMs = &d
+	}
+	var f templateFormat
+	if len(t.Format) > 0 && json.Unmarshal(t.Format, &f) == nil {
+		out.Format = &galav1.TemplateFormat{Width: f.Width, Height: f.Height, Fps: f.FPS}
+	}
+	return out
+}
@@ -0,0 +1,172 @@
+// Package health checks the API's dependencies (Postgres, Redis, storage)
+// in the background, so GET /health?deep=true serves the last snapshot
+// instead of pinging them on every load balancer probe.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+
+	"gala/internal/ports"
+)
+
+// DefaultInterval is how often a Monitor refreshes its snapshot.
+const DefaultInterval = 10 * time.Second
+
+// checkTimeout bounds each check.
+const checkTimeout = 5 * time.Second
+
+// Check reports the state of one dependency: a map with "status" ("ok"
+// or "error") and check-specific fields.
+type Check func(ctx context.Context) map[string]any
+
+// Checks returns the checks of the API's dependencies.
+func Checks(pool *pgxpool.Pool, rdb redis.UniversalClient, sp ports.StorageProvider) map[string]Check {
+	return map[string]Check{
+		"postgres": Postgres(pool),
+		"redis":    Redis(rdb),
+		"storage":  Storage(sp),
+	}
+}
+
+// Postgres pings the pool and reports its connection stats.
+func Postgres(pool *pgxpool.Pool) Check {
+	return func(ctx context.Context) map[string]any {
+		start := time.Now()
+		result := map[string]any{"status": "ok"}
+		if err := pool.Ping(ctx); err != nil {
+			result["status"] = "error"
+			result["error"] = err.Error()
+		} else {
+			stats := pool.Stat()
+			result["total_conns"] = stats.TotalConns()
+			result["idle_conns"] = stats.IdleConns()
+			result["acquired_conns"] = stats.AcquiredConns()
+		}
+		result["latency_ms"] = time.Since(start).Milliseconds()
+		return result
+	}
+}
+
+// Redis pings rdb.
+func Redis(rdb redis.UniversalClient) Check {
+	return func(ctx context.Context) map[string]any {
+		start := time.Now()
+		result := map[string]any{"status": "ok"}
+		if err := rdb.Ping(ctx).Err(); err != nil {
+			result["status"] = "error"
+			result["error"] = err.Error()
+		}
+		result["latency_ms"] = time.Since(start).Milliseconds()
+		return result
+	}
+}
+
+// Storage reports the provider; there is no connectivity check yet.
+func Storage(sp ports.StorageProvider) Check {
+	return func(context.Context) map[string]any {
+		return map[string]any{"status": "ok", "provider": sp.Provider()}
+	}
+}
+
+// Snapshot is the result of running every check once.
+type Snapshot struct {
+	Checks    map[string]any
+	CheckedAt time.Time
+}
+
+// OK reports whether every check passed.
+func (s Snapshot) OK() bool {
+	for _, c := range s.Checks {
+		if m, ok := c.(map[string]any); !ok || m["status"] != "ok" {
+			return false
+		}
+	}
+	return true
+}
+
+// Run runs checks concurrently, each with its own timeout.
+func Run(ctx context.Context, checks map[string]Check) Snapshot {
+	s := Snapshot{Checks: make(map[string]any, len(checks)), CheckedAt: time.Now().UTC()}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			res := check(cctx)
+			mu.Lock()
+			s.Checks[name] = res
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return s
+}
+
+// Monitor keeps a Snapshot of checks refreshed every interval.
+type Monitor struct {
+	checks   map[string]Check
+	interval time.Duration
+
+	mu   sync.RWMutex
+	last Snapshot
+}
+
+// NewMonitor creates a monitor of checks; interval <= 0 uses
+// DefaultInterval. Nothing runs until Start.
+func NewMonitor(checks map[string]Check, interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Monitor{checks: checks, interval: interval}
+}
+
+// Interval is how often the snapshot is refreshed.
+func (m *Monitor) Interval() time.Duration { return m.interval }
+
+// Start runs the checks once, so Snapshot has a result on return, and then
+// every interval until ctx is done.
+func (m *Monitor) Start(ctx context.Context) {
+	m.refresh(ctx)
+	go func() {
+		t := time.NewTicker(m.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				m.refresh(ctx)
+			}
+		}
+	}()
+}
+
+func (m *Monitor) refresh(ctx context.Context) {
+	s := Run(ctx, m.checks)
+	m.mu.Lock()
+	m.last = s
+	m.mu.Unlock()
+}
+
+// Snapshot returns the last result; its CheckedAt is zero before Start.
+func (m *Monitor) Snapshot() Snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.last
+}
+
+// Stale reports whether s is older than it should be at now: the monitor
+// missed two refreshes (or never ran).
+func (m *Monitor) Stale(s Snapshot, now time.Time) bool {
+	return s.CheckedAt.IsZero() || now.Sub(s.CheckedAt) > 3*m.interval
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func ok(context.Context) map[string]any { return map[string]any{"status": "ok"} }
+
+func failing(context.Context) map[string]any {
+	return map[string]any{"status": "error", "error": "down"}
+}
+
+func TestRun(t *testing.T) {
+	s := Run(context.Background(), map[string]Check{"a": ok, "b": ok})
+	if !s.OK() || len(s.Checks) != 2 || s.CheckedAt.IsZero() {
+		t.Fatalf("snapshot = %+v, want both ok", s)
+	}
+
+	s = Run(context.Background(), map[string]Check{"a": ok, "b": failing})
+	if s.OK() {
+		t.Error("OK with a failing check")
+	}
+}
+
+func TestRunTimesOutChecks(t *testing.T) {
+	slow := func(ctx context.Context) map[string]any {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("check has no deadline")
+		}
+		return map[string]any{"status": "ok"}
+	}
+	Run(context.Background(), map[string]Check{"slow": slow})
+}
+
+func TestMonitor(t *testing.T) {
+	var calls atomic.Int32
+	counted := func(context.Context) map[string]any {
+		calls.Add(1)
+		return map[string]any{"status": "ok"}
+	}
+	m := NewMonitor(map[string]Check{"c": counted}, 10*time.Millisecond)
+	if !m.Stale(m.Snapshot(), time.Now()) {
+		t.Error("snapshot before Start is not stale")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+	first := m.Snapshot()
+	if !first.OK() || calls.Load() != 1 {
+		t.Fatalf("after Start: snapshot %+v, %d calls", first, calls.Load())
+	}
+	if m.Stale(first, time.Now()) {
+		t.Error("fresh snapshot is stale")
+	}
+	if !m.Stale(first, first.CheckedAt.Add(time.Second)) {
+		t.Error("old snapshot is not stale")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for calls.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if calls.Load() < 3 {
+		t.Fatalf("monitor ran %d times, want refreshes", calls.Load())
+	}
+	if !m.Snapshot().CheckedAt.After(first.CheckedAt) {
+		t.Error("snapshot was not refreshed")
+	}
+}
@@ -0,0 +1,449 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"gala/internal/httpapi/openapi"
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/logger"
+)
+
+// Contract tests: the router, the error catalog and the responses handlers
+// send must match internal/httpapi/openapi/openapi.json.
+
+const testAdminToken = "contract-test-token"
+
+func loadSpec(t *testing.T) map[string]any {
+	t.Helper()
+	var doc map[string]any
+	if err := json.Unmarshal(openapi.Spec(), &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	if v, _ := doc["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		t.Fatalf("openapi version = %q, want 3.x", v)
+	}
+	return doc
+}
+
+// newTestRouter builds the router with every route mounted and no
+// backends; only requests that never reach Postgres, Redis or storage can
+// be served.
+func newTestRouter() http.Handler {
+	return NewRouter(Deps{
+		Log:        logger.New(logger.Config{Output: io.Discard}),
+		AdminToken: testAdminToken,
+	})
+}
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	doc := loadSpec(t)
+
+	inSpec := map[string]bool{}
+	for path, item := range doc["paths"].(map[string]any) {
+		for method := range item.(map[string]any) {
+			if slices.Contains(httpMethods, method) {
+				inSpec[strings.ToUpper(method)+" "+path] = true
+			}
+		}
+	}
+
+	routed := map[string]bool{}
+	err := chi.Walk(newTestRouter().(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routed[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range sortedKeys(routed) {
+		method, route, _ := strings.Cut(r, " ")
+		if !strings.HasPrefix(route, "/v1/") && routed[method+" /v1"+route] {
+			// Deprecated root alias of a v1 route
+			continue
+		}
+		if !inSpec[r] {
+			t.Errorf("route %s is not in openapi.json", r)
+		}
+	}
+	for _, r := range sortedKeys(inSpec) {
+		if !routed[r] {
+			t.Errorf("openapi.json has %s but the router does not", r)
+		}
+	}
+}
+
+func TestSpecErrorCodes(t *testing.T) {
+	doc := loadSpec(t)
+	schema := resolve(doc, map[string]any{"$ref": "#/components/schemas/ErrorCode"})
+
+	var inSpec []string
+	for _, c := range schema["enum"].([]any) {
+		inSpec = append(inSpec, c.(string))
+	}
+	var catalog []string
+	for _, info := range errors.Catalog() {
+		catalog = append(catalog, string(info.Code))
+	}
+	sort.Strings(inSpec)
+	if !slices.Equal(inSpec, catalog) {
+		t.Errorf("ErrorCode enum does not match errors.Catalog()\n spec:    %v\n catalog: %v", inSpec, catalog)
+	}
+}
+
+// TestSpecOperations checks the document itself: unique operation ids,
+// refs that resolve and shared error responses using ErrorEnvelope. Inline
+// error responses (the draining body of /readyz) are deliberate exceptions.
+func TestSpecOperations(t *testing.T) {
+	doc := loadSpec(t)
+
+	walkRefs(doc, func(ref string) {
+		if lookupRef(doc, ref) == nil {
+			t.Errorf("unresolved $ref %s", ref)
+		}
+	})
+
+	ids := map[string]string{}
+	for path, item := range doc["paths"].(map[string]any) {
+		for method, o := range item.(map[string]any) {
+			if !slices.Contains(httpMethods, method) {
+				continue
+			}
+			name := strings.ToUpper(method) + " " + path
+			op := o.(map[string]any)
+			id, _ := op["operationId"].(string)
+			if id == "" {
+				t.Errorf("%s has no operationId", name)
+			} else if other, ok := ids[id]; ok {
+				t.Errorf("operationId %s is used by %s and %s", id, other, name)
+			}
+			ids[id] = name
+
+			for status, resp := range op["responses"].(map[string]any) {
+				code, err := strconv.Atoi(status)
+				if _, shared := resp.(map[string]any)["$ref"]; err != nil || code < 400 || !shared {
+					continue
+				}
+				media := mediaSchema(resolve(doc, resp.(map[string]any)), "application/json")
+				if ref, _ := media["$ref"].(string); ref != "#/components/schemas/ErrorEnvelope" {
+					t.Errorf("%s %s response does not use ErrorEnvelope", name, status)
+				}
+			}
+		}
+	}
+}
+
+// TestResponsesMatchSpec sends requests that the handlers answer without
+// touching any backend and validates status and body against the spec.
+func TestLegacyRoutesDeprecated(t *testing.T) {
+	router := newTestRouter()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/errors/catalog?lang=es", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("legacy status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("Deprecation = %q, want true", got)
+	}
+	want := `</v1/errors/catalog?lang=es>; rel="successor-version"`
+	if got := rec.Header().Values("Link"); !slices.Contains(got, want) {
+		t.Errorf("Link = %q, want %s", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/errors/catalog", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("v1 status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Deprecation"); got != "" {
+		t.Errorf("v1 Deprecation = %q, want none", got)
+	}
+}
+
+func TestResponsesMatchSpec(t *testing.T) {
+	doc := loadSpec(t)
+	router := newTestRouter()
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		// path is the spec path template of url
+		path        string
+		body        string
+		contentType string
+		admin       bool
+		want        int
+	}{
+		{name: "health", method: "GET", url: "/health", path: "/health", want: 200},
+		{name: "readyz", method: "GET", url: "/readyz", path: "/readyz", want: 200},
+		{name: "error catalog", method: "GET", url: "/v1/errors/catalog", path: "/v1/errors/catalog", want: 200},
+		{name: "openapi", method: "GET", url: "/openapi.json", path: "/openapi.json", want: 200},
+		{name: "swagger ui", method: "GET", url: "/docs", path: "/docs", want: 200},
+		{name: "upload without form", method: "POST", url: "/v1/assets", path: "/v1/assets", body: "x", contentType: "text/plain", want: 400},
+		{name: "assets bad cursor", method: "GET", url: "/v1/assets?cursor=nope", path: "/v1/assets", want: 400},
+		{name: "template invalid json", method: "POST", url: "/v1/templates", path: "/v1/templates", body: "{", want: 400},
+		{name: "template missing fields", method: "POST", url: "/v1/templates", path: "/v1/templates", body: "{}", want: 400},
+		{name: "template unknown field", method: "POST", url: "/v1/templates", path: "/v1/templates", body: `{"type":"t","name":"n","color":1}`, want: 400},
+		{name: "template blank name", method: "PATCH", url: "/v1/templates/tpl_1", path: "/v1/templates/{templateId}", body: `{"name":" "}`, want: 400},
+		{name: "templates bad limit", method: "GET", url: "/v1/templates?limit=0", path: "/v1/templates", want: 400},
+		{name: "template watermark without asset", method: "POST", url: "/v1/templates", path: "/v1/templates", body: `{"type":"t","name":"n","watermark":{"position":"top-left"}}`, want: 400},
+		{name: "template watermark bad opacity", method: "PATCH", url: "/v1/templates/tpl_1", path: "/v1/templates/{templateId}", body: `{"watermark":{"asset_id":"ast_1","opacity":2}}`, want: 400},
+		{name: "strict template without schema", method: "POST", url: "/v1/templates", path: "/v1/templates", body: `{"type":"avatar_v1","name":"t","strict_params":true}`, want: 400},
+		{name: "strict template bad defaults", method: "POST", url: "/v1/templates", path: "/v1/templates", body: `{"type":"avatar_v1","name":"t","strict_params":true,"params_schema":{"text":{"type":"string"}},"defaults":{"text":1}}`, want: 400},
+		{name: "legacy job with watermark", method: "POST", url: "/v1/jobs", path: "/v1/jobs", body: `{"params":{"text":"hi"},"watermark":{"asset_id":"ast_1"}}`, want: 400},
+		{name: "legacy job without text", method: "POST", url: "/v1/jobs", path: "/v1/jobs", body: `{"params":{}}`, want: 400},
+		{name: "job http input", method: "POST", url: "/v1/jobs", path: "/v1/jobs", body: `{"template_id":"tpl_1","inputs":{"avatar_image_asset_id":"http://example.com/a.png"}}`, want: 400},
+		{name: "job http callback", method: "POST", url: "/v1/jobs", path: "/v1/jobs", body: `{"params":{"text":"hi"},"callback_url":"http://example.com/hook"}`, want: 400},
+		{name: "job callback secret without url", method: "POST", url: "/v1/jobs", path: "/v1/jobs", body: `{"params":{"text":"hi"},"callback_secret":"s"}`, want: 400},
+		{name: "job run_at too far", method: "POST", url: "/v1/jobs", path: "/v1/jobs", body: `{"params":{"text":"hi"},"run_at":"2999-01-01T00:00:00Z"}`, want: 400},
+		{name: "job progress out of range", method: "POST", url: "/v1/jobs/job_1/progress", path: "/v1/jobs/{jobId}/progress", body: `{"progress":150}`, want: 400},
+		{name: "job progress missing", method: "POST", url: "/v1/jobs/job_1/progress", path: "/v1/jobs/{jobId}/progress", body: `{}`, want: 400},
+		{name: "job batch empty", method: "POST", url: "/v1/jobs/batch", path: "/v1/jobs/batch", body: `{"jobs":[]}`, want: 400},
+		{name: "job batch invalid item", method: "POST", url: "/v1/jobs/batch", path: "/v1/jobs/batch", body: `{"jobs":[{"params":{"text":"hi"}},{"params":{}}]}`, want: 400},
+		{name: "quick render without template", method: "POST", url: "/v1/quick-render", path: "/v1/quick-render", body: `{"params":{"text":"hi"}}`, want: 400},
+		{name: "quick render http input", method: "POST", url: "/v1/quick-render", path: "/v1/quick-render", body: `{"template":"demo","inputs":{"avatar":"http://example.com/a.png"}}`, want: 400},
+		{name: "jobs unknown include", method: "GET", url: "/v1/jobs?include=everything", path: "/v1/jobs", want: 400},
+		{name: "jobs bad limit", method: "GET", url: "/v1/jobs?limit=x", path: "/v1/jobs", want: 400},
+		{name: "export bad since", method: "GET", url: "/v1/jobs/export?since=yesterday", path: "/v1/jobs/export", want: 400},
+		{name: "publish without target", method: "POST", url: "/v1/jobs/job_1/publish", path: "/v1/jobs/{jobId}/publish", body: `{}`, want: 400},
+		{name: "publish bad privacy", method: "POST", url: "/v1/jobs/job_1/publish", path: "/v1/jobs/{jobId}/publish", body: `{"target":"youtube","privacy":"friends"}`, want: 400},
+		{name: "publish unconfigured target", method: "POST", url: "/v1/jobs/job_1/publish", path: "/v1/jobs/{jobId}/publish", body: `{"target":"youtube"}`, want: 501},
+		{name: "share bad expiry", method: "POST", url: "/v1/jobs/job_1/shares", path: "/v1/jobs/{jobId}/shares", body: `{"expires_in":-1}`, want: 400},
+		{name: "revoke malformed share", method: "DELETE", url: "/v1/shares/nope", path: "/v1/shares/{token}", want: 404},
+		{name: "embed malformed token", method: "GET", url: "/embed/nope", path: "/embed/{token}", want: 404},
+		{name: "embed metadata malformed token", method: "GET", url: "/embed/nope.json", path: "/embed/{token}.json", want: 404},
+		{name: "settings bad priority", method: "PUT", url: "/v1/settings", path: "/v1/settings", body: `{"priority":"urgent"}`, want: 400},
+		{name: "events bad last id", method: "GET", url: "/v1/events?last_event_id=nope", path: "/v1/events", want: 400},
+		{name: "admin without token", method: "POST", url: "/v1/admin/queue/drain", path: "/v1/admin/queue/drain", want: 401},
+		{name: "gc bad duration", method: "POST", url: "/v1/admin/assets/gc?older_than=soon", path: "/v1/admin/assets/gc", admin: true, want: 400},
+		{name: "usage report bad month", method: "GET", url: "/v1/admin/reports/usage?month=June", path: "/v1/admin/reports/usage", admin: true, want: 400},
+		{name: "audit bad repair", method: "POST", url: "/v1/admin/storage/audit?repair=everything", path: "/v1/admin/storage/audit", admin: true, want: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.body != "" {
+				ct := tt.contentType
+				if ct == "" {
+					ct = "application/json"
+				}
+				req.Header.Set("Content-Type", ct)
+			}
+			if tt.admin {
+				req.Header.Set("Authorization", "Bearer "+testAdminToken)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.want, rec.Body)
+			}
+
+			item, _ := doc["paths"].(map[string]any)[tt.path].(map[string]any)
+			op, _ := item[strings.ToLower(tt.method)].(map[string]any)
+			if op == nil {
+				t.Fatalf("openapi.json has no %s %s", tt.method, tt.path)
+			}
+			resp, _ := op["responses"].(map[string]any)[strconv.Itoa(rec.Code)].(map[string]any)
+			if resp == nil {
+				t.Fatalf("openapi.json does not declare status %d for %s %s", rec.Code, tt.method, tt.path)
+			}
+
+			mediaType, _, _ := strings.Cut(rec.Header().Get("Content-Type"), ";")
+			schema := mediaSchema(resolve(doc, resp), mediaType)
+			if schema == nil {
+				t.Fatalf("openapi.json declares no %s body for status %d", mediaType, rec.Code)
+			}
+			if mediaType != "application/json" {
+				return
+			}
+			var body any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid JSON body: %v", err)
+			}
+			for _, err := range validate(doc, schema, body, "body") {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// resolve follows $ref until it reaches an inline object.
+func resolve(doc, node map[string]any) map[string]any {
+	for node != nil {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		node = lookupRef(doc, ref)
+	}
+	return nil
+}
+
+func lookupRef(doc map[string]any, ref string) map[string]any {
+	var cur any = doc
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[strings.NewReplacer("~1", "/", "~0", "~").Replace(part)]
+	}
+	m, _ := cur.(map[string]any)
+	return m
+}
+
+func walkRefs(node any, fn func(string)) {
+	switch n := node.(type) {
+	case map[string]any:
+		if ref, ok := n["$ref"].(string); ok {
+			fn(ref)
+		}
+		for _, v := range n {
+			walkRefs(v, fn)
+		}
+	case []any:
+		for _, v := range n {
+			walkRefs(v, fn)
+		}
+	}
+}
+
+// mediaSchema returns the (unresolved) schema of a response for a media
+// type, or nil if the response does not declare it.
+func mediaSchema(resp map[string]any, mediaType string) map[string]any {
+	content, _ := resp["content"].(map[string]any)
+	media, _ := content[mediaType].(map[string]any)
+	if media == nil {
+		return nil
+	}
+	schema, _ := media["schema"].(map[string]any)
+	if schema == nil {
+		return map[string]any{}
+	}
+	return schema
+}
+
+// validate checks v against the subset of JSON Schema the spec uses.
+func validate(doc, schema map[string]any, v any, at string) []error {
+	schema = resolve(doc, schema)
+	if schema == nil {
+		return []error{fmt.Errorf("%s: unresolved schema", at)}
+	}
+
+	if alts, ok := schema["oneOf"].([]any); ok {
+		for _, alt := range alts {
+			if len(validate(doc, alt.(map[string]any), v, at)) == 0 {
+				return nil
+			}
+		}
+		return []error{fmt.Errorf("%s: matches no oneOf alternative", at)}
+	}
+
+	if t, ok := schema["type"]; ok {
+		var types []string
+		switch t := t.(type) {
+		case string:
+			types = []string{t}
+		case []any:
+			for _, s := range t {
+				types = append(types, s.(string))
+			}
+		}
+		if !slices.ContainsFunc(types, func(t string) bool { return hasType(v, t) }) {
+			return []error{fmt.Errorf("%s: %v is not of type %v", at, v, types)}
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, v) {
+		return []error{fmt.Errorf("%s: %v is not one of %v", at, v, enum)}
+	}
+
+	var errs []error
+	switch v := v.(type) {
+	case map[string]any:
+		for _, r := range asSlice(schema["required"]) {
+			if _, ok := v[r.(string)]; !ok {
+				errs = append(errs, fmt.Errorf("%s: missing required %q", at, r))
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for k, pv := range v {
+			if ps, ok := props[k].(map[string]any); ok {
+				errs = append(errs, validate(doc, ps, pv, at+"."+k)...)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					errs = append(errs, fmt.Errorf("%s: unexpected property %q", at, k))
+				}
+			case map[string]any:
+				errs = append(errs, validate(doc, extra, pv, at+"."+k)...)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, iv := range v {
+				errs = append(errs, validate(doc, items, iv, fmt.Sprintf("%s[%d]", at, i))...)
+			}
+		}
+	}
+	return errs
+}
+
+func hasType(v any, t string) bool {
+	switch t {
+	case "null":
+		return v == nil
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	}
+	return false
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"gala/internal/admin"
+	"gala/internal/httpkit"
+	"gala/internal/pkg/errors"
+	"gala/internal/store"
+)
+
+// ReloadConfig re-applies the reloadable configuration, same as SIGHUP.
+// Handlers that fail keep their previous configuration and are listed in
+// the error details.
+func (h *Handler) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if h.reload == nil {
+		httpkit.WriteErr(w, r, 404, "NOT_FOUND", "config reload is not enabled", nil)
+		return
+	}
+	applied, err := h.reload.Reload(r.Context())
+	if err != nil {
+		httpkit.WriteError(w, r, err)
+		return
+	}
+	httpkit.WriteJSON(w, 200, map[string]any{"reloaded": applied})
+}
+
+// RequeueJob puts a FAILED or CANCELED job back in the queue. Served on
+// /jobs/{jobId}/retry and /admin/jobs/{jobId}/requeue.
+func (h *Handler) RequeueJob(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobId")
+	job, err := h.admin.RequeueJob(r.Context(), jobID)
+	h.writeJobAction(w, r, "jobs.requeue", jobID, job, err)
+}
+
+// CancelJob cancels a QUEUED or SCHEDULED job, or asks the worker of a
+// RUNNING one to stop it (202, the job still RUNNING). Served on /jobs and
+// /admin/jobs.
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobId")
+	job, err := h.admin.CancelJob(r.Context(), jobID)
+	if err == nil && job.Status == store.JobRunning {
+		httpkit.WriteJSON(w, 202, map[string]any{"job": job, "cancel_requested": true})
+		return
+	}
+	h.writeJobAction(w, r, "jobs.cancel", jobID, job, err)
+}
+
+// EditJobParamsRequest replaces the params of a QUEUED job. UpdatedAt is
+// the job's updated_at as last read, for optimistic locking.
+type EditJobParamsRequest struct {
+	Params    map[string]any `json:"params"`
+	UpdatedAt *time.Time     `json:"updated_at"`
+}
+
+func (req *EditJobParamsRequest) Validate() error {
+	var v httpkit.Validator
+	v.Check(req.Params != nil, "params", "params is required")
+	v.Check(req.UpdatedAt != nil, "updated_at", "updated_at is required")
+	return v.Err()
+}
+
+// EditJobParams replaces the params of a QUEUED job without moving it in
+// the queue.
+func (h *Handler) EditJobParams(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobId")
+	var req EditJobParamsRequest
+	if !httpkit.DecodeAndValidate(w, r, &req) {
+		return
+	}
+	job, err := h.admin.EditJobParams(r.Context(), jobID, req.Params, *req.UpdatedAt)
+	h.writeJobAction(w, r, "admin.edit_params", jobID, job, err)
+}
+
+func (h *Handler) writeJobAction(w http.ResponseWriter, r *http.Request, op, jobID string, job store.Job, err error) {
+	switch {
+	case err == nil:
+		httpkit.WriteJSON(w, 200, map[string]any{"job": job})
+	case errors.Is(err, admin.ErrJobNotFound):
+		httpkit.WriteErr(w, r, 404, "JOB_NOT_FOUND", "job not found", map[string]any{"job_id": jobID})
+	case errors.Is(err, admin.ErrQueue):
+		h.writeQueueErr(w, r, err, op)
+	case errors.Is(err, admin.ErrJobState):
+		details := map[string]any{"job_id": jobID}
+		var se *admin.JobStateError
+		if errors.As(err, &se) {
+			details["status"] = se.Status
+		}
+		httpkit.WriteErr(w, r, 409, "JOB_INVALID_STATE", "job status does not allow this action", details)
+	case errors.Is(err, admin.ErrJobModified):
+		httpkit.WriteErr(w, r, 409, string(CodeJobModified), "job was modified since it was read", map[string]any{"job_id": jobID})
+	case errors.IsValidation(err):
+		httpkit.WriteError(w, r, err)
+	default:
+		h.writeDBErr(w, r, err, op, "job update failed")
+	}
+}
+
+// QueueStats reports job counts by status and the pending queue length.
+func (h *Handler) QueueStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.admin.QueueStats(r.Context())
+	if err != nil {
+		h.writeDBErr(w, r, err, "admin.queue_stats", "queue stats failed")
+		return
+	}
+	httpkit.WriteJSON(w, 200, map[string]any{"queue": stats})
+}
+
+// Modes of POST /admin/queue/drain.
+const (
+	// DrainSoft pauses the queue and waits for the running jobs (the
+	// default).
+	DrainSoft = "soft"
+	// DrainCancel cancels every QUEUED job; it has to be asked for.
+	DrainCancel = "cancel"
+)
+
+const (
+	defaultDrainWait = 5 * time.Minute
+	drainPoll        = 2 * time.Second
+	// drainMargin is how long before the route's timeout a soft drain
+	// answers
+	drainMargin = 5 * time.Second
+)
+
+// DrainQueue pauses the queue and waits up to wait (default 5m) for no
+// job to be RUNNING or, with mode=cancel, cancels every QUEUED job.
+func (h *Handler) DrainQueue(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var v httpkit.Validator
+	mode := strings.TrimSpace(q.Get("mode"))
+	if mode == "" {
+		mode = DrainSoft
+	}
+	v.Check(mode == DrainSoft || mode == DrainCancel, "mode", "mode must be soft or cancel")
+	wait := defaultDrainWait
+	if raw := strings.TrimSpace(q.Get("wait")); raw != "" {
+		d, err := time.ParseDuration(raw)
+		v.Check(err == nil && d >= 0, "wait", "wait must be a duration like 5m")
+		wait = d
+	}
+	if err := v.Err(); err != nil {
+		httpkit.WriteError(w, r, err)
+		return
+	}
+	if mode == DrainSoft {
+		h.softDrain(w, r, wait)
+		return
+	}
+
+	n, err := h.admin.DrainQueue(r.Context())
+	if errors.Is(err, admin.ErrQueue) {
+		h.writeQueueErr(w, r, err, "admin.queue_drain")
+		return
+	}
+	if err != nil {
+		h.writeDBErr(w, r, err, "admin.queue_drain", "queue drain failed")
+		return
+	}
+	httpkit.WriteJSON(w, 200, map[string]any{"canceled": n})
+}
+
+// softDrain answers when the running jobs are done, or with drained=false
+// when wait (cut to the route's timeout) ends first.
+func (h *Handler) softDrain(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	ctx := r.Context()
+	if deadline, ok := ctx.Deadline(); ok {
+		wait = max(min(wait, time.Until(deadline)-drainMargin), 0)
+	}
+	st, err := h.admin.SoftDrain(ctx, wait, drainPoll)
+	if errors.Is(err, admin.ErrQueue) {
+		h.writeQueueErr(w, r, err, "admin.queue_drain")
+		return
+	}
+	if err != nil {
+		h.writeDBErr(w, r, err, "admin.queue_drain", "queue drain failed")
+		return
+	}
+	httpkit.WriteJSON(w, 200, map[string]any{"drain": st})
+}
+
+// ResumeQueue ends a soft drain: the workers take jobs again.
+func (h *Handler) ResumeQueue(w http.ResponseWriter, r *http.Request) {
+	if err := h.admin.ResumeQueue(r.Context()); err != nil {
+		h.writeQueueErr(w, r, err, "admin.queue_resume")
+		return
+	}
+	httpkit.WriteJSON(w, 200, map[string]any{"resumed": true})
+}
+
+// GCAssets lists (and with apply=true deletes) unreferenced assets older
+// than older_than (default 720h), at most limit (default 100, max 1000)
+// per call.
+func (h *Handler) GCAssets(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var v httpkit.Validator
+	olderThan := 30 * 24 * time.Hour
+	if raw := strings.TrimSpace(q.Get("older_than")); raw != "" {
+		d, err := time.ParseDuration(raw)
+		v.Check(err == nil && d >= 0, "older_than", "older_than must be a duration like 720h")
+		olderThan = d
+	}
+	limit, err := httpkit.ParseLimit(q.Get("limit"), 100, 1000)
+	v.Check(err == nil, "limit", "limit must be a positive integer")
+	apply, _ := strconv.ParseBool(q.Get("apply"))
+	if err := v.Err(); err != nil {
+		httpkit.WriteError(w, r, err)
+		return
+	}
+
+	res, err := h.admin.GCAssets(r.Context(), olderThan, limit, apply)
+	if err != nil {
+		h.writeDBErr(w, r, err, "admin.assets_gc", "asset gc failed")
+		return
+	}
+
+	assets := res.Assets
+	if assets == nil {
+		assets = []store.Asset{}
+	}
+	httpkit.WriteJSON(w, 200, map[string]any{
+		"assets":  assets,
+		"applied": apply,
+		"deleted": res.Deleted,
+		"failed":  res.Failed,
+	})
+}
+
+// ListWorkers lists the live workers from their heartbeats.
+func (h *Handler) ListWorkers(w http.ResponseWriter, r *http.Request) {
+	workers, err := h.admin.Workers(r.Context())
+	if err != nil {
+		httpkit.WriteErr(w, r, 503, "UNAVAILABLE", "worker registry unavailable", nil)
+		return
+	}
+	httpkit.WriteJSON(w, 200, map[string]any{"workers": workers})
+}
+
+// AuditStorage cross-checks the assets table against the storage
+// provider's objects. Query: prefix (repeatable; objects that can be
+// orphans, default assets/ and renders/), min_age (default 1h; newer
+// orphans are skipped), checksums=true (read objects the provider has no
+// MD5 for) and repair (comma-separated admin.RepairActions).
+func (h *Handler) AuditStorage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var v httpkit.Validator
+	opts := admin.AuditOptions{Prefixes: q["prefix"], MinAge: time.Hour}
+	if raw := strings.TrimSpace(q.Get("min_age")); raw != "" {
+		d, err := time.ParseDuration(raw)
+		v.Check(err == nil && d >= 0, "min_age", "min_age must be a duration like 1h")
+		opts.MinAge = d
+	}
+	opts.Checksums, _ = strconv.ParseBool(q.Get("checksums"))
+	for _, a := range strings.Split(q.Get("repair"), ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			v.Check(slices.Contains(admin.RepairActions, a), "repair", "unknown repair action")
+			opts.Repair = append(opts.Repair, a)
+		}
+	}
+	if err := v.Err(); err != nil {
+		httpkit.WriteError(w, r, err)
+		return
+	}
+
+	report, err := h.admin.AuditStorage(r.Context(), opts)
+	switch {
+	case err == nil:
+		httpkit.WriteJSON(w, 200, map[string]any{"report": report})
+	case errors.Is(err, admin.ErrAuditUnsupported):
+		httpkit.WriteErr(w, r, 501, "STORAGE_AUDIT_UNSUPPORTED", "storage provider cannot list objects", map[string]any{"provider": h.sp.Provider()})
+	default:
+		h.writeDBErr(w, r, err, "admin.storage_audit", "storage audit failed")
+	}
+}
+
+// UsageReport reports the usage of a month (month=YYYY-MM, default the
+// current one) as JSON or, with format=csv, as a CSV of one row per day
+// plus a total row.
+func (h *Handler) UsageReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var v httpkit.Validator
+	month := time.Now().UTC()
+	if raw := strings.TrimSpace(q.Get("month")); raw != "" {
+		t, err := time.Parse("2006-01", raw)
+		v.Check(err == nil, "month", "month must be YYYY-MM")
+		month = t
+	}
+	format := strings.ToLower(strings.TrimSpace(q.Get("format")))
+	v.Check(format == "" || format == "json" || format == "csv", "format", "format must be json or csv")
+	if err := v.Err(); err != nil {
+		httpkit.WriteError(w, r, err)
+		return
+	}
+
+	rep, err := h.admin.UsageReport(r.Context(), month)
+	if err != nil {
+		h.writeDBErr(w, r, err, "admin.usage_report", "usage report failed")
+		return
+	}
+	if format != "csv" {
+		httpkit.WriteJSON(w, 200, map[string]any{"report": rep})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="usage-`+rep.Month+`.csv"`)
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"date", "renders", "failed_renders", "render_minutes", "storage_bytes_added", "publications"})
+	for _, d := range append(rep.Days, rep.Totals) {
+		date := d.Date
+		if date == "" {
+			date = "total"
+		}
+		_ = cw.Write([]string{
+			date,
+			strconv.FormatInt(d.Renders, 10),
+			strconv.FormatInt(d.FailedRenders, 10),
+			strconv.FormatFloat(d.RenderMinutes, 'f', 2, 64),
+			strconv.FormatInt(d.StorageBytesAdded, 10),
+			strconv.FormatInt(d.Publications, 10),
+		})
+	}
+	cw.Flush()
+}
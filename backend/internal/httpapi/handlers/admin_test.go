@@ -0,0 +1,214 @@
+package handlers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"gala/internal/admin"
+	"gala/internal/httpapi/handlers"
+	"gala/internal/store"
+	"gala/internal/testinfra"
+	"gala/internal/worker/queue"
+)
+
+func retryRouter(d handlers.Deps) chi.Router {
+	h := handlers.New(d)
+	r := chi.NewRouter()
+	r.Post("/jobs/{jobId}/retry", h.RequeueJob)
+	return r
+}
+
+func TestRetryJob(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	r := retryRouter(env.HandlerDeps())
+	failJob(t, env, "job_1", "boom")
+
+	var body struct {
+		Job store.Job `json:"job"`
+	}
+	if rec := serve(t, r, "POST", "/jobs/job_1/retry", &body); rec.Code != 200 {
+		t.Fatalf("retry = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+	if body.Job.Status != store.JobQueued || body.Job.ErrorText != "" || body.Job.Attempts != 1 {
+		t.Errorf("job = %s, error %q, %d attempts; want QUEUED, no error, 1 attempt",
+			body.Job.Status, body.Job.ErrorText, body.Job.Attempts)
+	}
+	ids, err := env.RDB.LRange(ctx, queue.DefaultName, 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "job_1" {
+		t.Errorf("queue = %v, want [job_1]", ids)
+	}
+}
+
+func TestRetryJobInvalidState(t *testing.T) {
+	env := testinfra.New(t)
+	r := retryRouter(env.HandlerDeps())
+	insertJob(t, env, "job_1")
+
+	var body errorBody
+	if rec := serve(t, r, "POST", "/jobs/job_1/retry", &body); rec.Code != 409 {
+		t.Fatalf("retry = %d, want 409; body: %s", rec.Code, rec.Body)
+	}
+	if body.Error.Code != "JOB_INVALID_STATE" || body.Error.Details["status"] != store.JobQueued {
+		t.Errorf("error = %s %v, want JOB_INVALID_STATE with status QUEUED", body.Error.Code, body.Error.Details)
+	}
+	if rec := serve(t, r, "POST", "/jobs/job_2/retry", nil); rec.Code != 404 {
+		t.Errorf("retry of a missing job = %d, want 404", rec.Code)
+	}
+}
+
+// A retry whose push fails leaves the job as it was, error included.
+func TestRetryJobQueueDown(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	d := env.HandlerDeps()
+	d.RDB = deadRedis(t)
+	r := retryRouter(d)
+	failJob(t, env, "job_1", "boom")
+	before, err := store.GetJob(ctx, env.Pool, "job_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body errorBody
+	if rec := serve(t, r, "POST", "/jobs/job_1/retry", &body); rec.Code != 503 {
+		t.Fatalf("retry = %d, want 503; body: %s", rec.Code, rec.Body)
+	}
+	if body.Error.Code != "UNAVAILABLE" {
+		t.Errorf("error code = %s, want UNAVAILABLE", body.Error.Code)
+	}
+	after, err := store.GetJob(ctx, env.Pool, "job_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Status != store.JobFailed || after.ErrorText != "boom" || after.Error == nil || *after.Error != *before.Error {
+		t.Errorf("job after a failed retry = %s, %q, %+v; want FAILED, boom, %+v",
+			after.Status, after.ErrorText, after.Error, before.Error)
+	}
+	if !after.FinishedAt.Equal(*before.FinishedAt) {
+		t.Errorf("finished_at = %v, want %v", after.FinishedAt, before.FinishedAt)
+	}
+}
+
+func drainRouter(d handlers.Deps) chi.Router {
+	h := handlers.New(d)
+	r := chi.NewRouter()
+	r.Post("/admin/queue/drain", h.DrainQueue)
+	return r
+}
+
+// queueDrainJobs leaves job_1 QUEUED, in the list, and job_2 RUNNING.
+func queueDrainJobs(t *testing.T, env *testinfra.Env) {
+	t.Helper()
+	ctx := context.Background()
+	insertJob(t, env, "job_1")
+	if err := env.RDB.LPush(ctx, queue.DefaultName, "job_1").Err(); err != nil {
+		t.Fatal(err)
+	}
+	insertJob(t, env, "job_2")
+	if _, _, err := store.MarkJobRunning(ctx, env.Pool, "job_2", false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Without mode the drain is soft: nothing queued is canceled.
+func TestDrainQueueDefaultsToSoft(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	r := drainRouter(env.HandlerDeps())
+	queueDrainJobs(t, env)
+
+	var body struct {
+		Drain admin.DrainStatus `json:"drain"`
+	}
+	if rec := serve(t, r, "POST", "/admin/queue/drain?wait=0s", &body); rec.Code != 200 {
+		t.Fatalf("drain = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+	d := body.Drain
+	if d.Drained || len(d.Running) != 1 || d.Running[0] != "job_2" || d.Queued != 1 {
+		t.Errorf("drain = %+v, want not drained, running [job_2], 1 queued", d)
+	}
+	if since, err := queue.PausedSince(ctx, env.RDB); err != nil || since.IsZero() {
+		t.Errorf("PausedSince = %v, %v; want the queue paused", since, err)
+	}
+	job, err := store.GetJob(ctx, env.Pool, "job_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != store.JobQueued {
+		t.Errorf("queued job is %s after a soft drain, want QUEUED", job.Status)
+	}
+	if n, err := env.RDB.LLen(ctx, queue.DefaultName).Result(); err != nil || n != 1 {
+		t.Errorf("queue length = %d, %v; want 1", n, err)
+	}
+
+	// Once the running job ends the drain resolves
+	if ok, err := store.MarkJobDone(ctx, env.Pool, "job_2"); err != nil || !ok {
+		t.Fatalf("MarkJobDone = %v, %v", ok, err)
+	}
+	body.Drain = admin.DrainStatus{}
+	if rec := serve(t, r, "POST", "/admin/queue/drain?mode=soft&wait=0s", &body); rec.Code != 200 {
+		t.Fatalf("second drain = %d, want 200", rec.Code)
+	}
+	if !body.Drain.Drained || len(body.Drain.Running) != 0 {
+		t.Errorf("drain = %+v, want drained", body.Drain)
+	}
+	if !body.Drain.PausedAt.Equal(d.PausedAt) {
+		t.Errorf("paused_at = %v, want the first drain's %v", body.Drain.PausedAt, d.PausedAt)
+	}
+}
+
+func TestDrainQueueCancel(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	r := drainRouter(env.HandlerDeps())
+	queueDrainJobs(t, env)
+
+	var body struct {
+		Canceled int64 `json:"canceled"`
+	}
+	if rec := serve(t, r, "POST", "/admin/queue/drain?mode=cancel", &body); rec.Code != 200 {
+		t.Fatalf("drain = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+	if body.Canceled != 1 {
+		t.Errorf("canceled = %d, want 1", body.Canceled)
+	}
+	for id, want := range map[string]string{"job_1": store.JobCanceled, "job_2": store.JobRunning} {
+		job, err := store.GetJob(ctx, env.Pool, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != want {
+			t.Errorf("%s is %s, want %s", id, job.Status, want)
+		}
+	}
+	if n, err := env.RDB.LLen(ctx, queue.DefaultName).Result(); err != nil || n != 0 {
+		t.Errorf("queue length = %d, %v; want 0", n, err)
+	}
+}
+
+func TestDrainQueueInvalidMode(t *testing.T) {
+	env := testinfra.New(t)
+	r := drainRouter(env.HandlerDeps())
+	insertJob(t, env, "job_1")
+
+	var body errorBody
+	if rec := serve(t, r, "POST", "/admin/queue/drain?mode=all", &body); rec.Code != 400 {
+		t.Fatalf("drain = %d, want 400", rec.Code)
+	}
+	if body.Error.Code != "VALIDATION_ERROR" {
+		t.Errorf("error code = %s, want VALIDATION_ERROR", body.Error.Code)
+	}
+	job, err := store.GetJob(context.Background(), env.Pool, "job_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != store.JobQueued {
+		t.Errorf("job is %s after a rejected drain, want QUEUED", job.Status)
+	}
+}
@@ -1,13 +1,14 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"mime"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -15,32 +16,62 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"gala/internal/admin"
+	"gala/internal/events"
 	"gala/internal/httpapi/util"
 	"gala/internal/httpkit"
+	"gala/internal/pkg/pgerr"
 	"gala/internal/ports"
+	"gala/internal/storage"
+	"gala/internal/store"
+	"gala/internal/uploads"
 )
 
+// PostAsset uploads an asset. With ?upload_id (from POST /assets/uploads)
+// the upload's progress is recorded in that session as the body is read
+// and the file stored.
 func (h *Handler) PostAsset(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	uploadID := strings.TrimSpace(r.URL.Query().Get("upload_id"))
+	// failure is what the session records if the upload ends without an
+	// asset; assetDone is set once it has one.
+	failure, assetDone := "upload failed", ""
+	if uploadID != "" {
+		if err := h.uploads.Start(ctx, uploadID, r.ContentLength); err != nil {
+			h.writeUploadErr(w, r, err, uploadID)
+			return
+		}
+		defer func() {
+			if err := h.uploads.Finish(context.WithoutCancel(ctx), uploadID, assetDone, failure); err != nil && h.log != nil {
+				h.log.FromContext(ctx).Warn("could not finish upload", "upload_id", uploadID, "error", err.Error())
+			}
+		}()
+		r.Body = h.uploads.Receive(ctx, uploadID, r.Body)
+	}
+
 	if err := r.ParseMultipartForm(512 << 20); err != nil {
-		httpkit.WriteErr(w, 400, "VALIDATION_ERROR", "invalid multipart form", nil)
+		failure = "invalid multipart form"
+		httpkit.WriteErr(w, r, 400, "VALIDATION_ERROR", "invalid multipart form", nil)
 		return
 	}
 
 	kind := strings.TrimSpace(r.FormValue("kind"))
-	if kind == "" {
-		httpkit.WriteErr(w, 400, "VALIDATION_ERROR", "kind is required", map[string]any{"field": "kind"})
-		return
-	}
 	label := strings.TrimSpace(r.FormValue("label"))
 
-	file, header, err := r.FormFile("file")
-	if err != nil {
-		httpkit.WriteErr(w, 400, "VALIDATION_ERROR", "file is required", map[string]any{"field": "file"})
+	file, header, fileErr := r.FormFile("file")
+	if fileErr == nil {
+		defer file.Close()
+	}
+
+	var v httpkit.Validator
+	v.Required("kind", kind)
+	v.Check(fileErr == nil, "file", "file is required")
+	if err := v.Err(); err != nil {
+		failure = err.Error()
+		httpkit.WriteError(w, r, err)
 		return
 	}
-	defer file.Close()
 
 	assetID := util.NewID("ast")
 	ext := filepath.Ext(header.Filename)
@@ -60,75 +91,159 @@ func (h *Handler) PostAsset(w http.ResponseWriter, r *http.Request) {
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
+	if err := h.kinds.Check(kind, contentType, header.Size); err != nil {
+		failure = err.Error()
+		httpkit.WriteError(w, r, err)
+		return
+	}
 
+	var src io.Reader = file
+	if uploadID != "" {
+		src = h.uploads.Store(ctx, uploadID, header.Size, file)
+	}
+	sum := md5.New()
 	out, err := h.sp.PutObject(ctx, ports.PutObjectInput{
 		ObjectKey:   objectKey,
 		ContentType: contentType,
-		Reader:      file,
+		Reader:      storage.WithContext(ctx, io.TeeReader(src, sum)),
 		Size:        header.Size,
+		Kind:        kind,
 	})
 	if err != nil {
-		httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "storage put failed", nil)
+		failure = "storage put failed"
+		if ctx.Err() != nil {
+			// The client went away mid-upload; drop what the provider
+			// may have kept of the object (localfs leaves a partial file)
+			_ = h.sp.DeleteObject(context.WithoutCancel(ctx), objectKey)
+		}
+		httpkit.WriteErr(w, r, 500, "INTERNAL_ERROR", "storage put failed", nil)
+		return
+	}
+
+	createdAt := now()
+	asset := store.Asset{
+		ID:        assetID,
+		Kind:      kind,
+		Provider:  h.sp.Provider(),
+		ObjectKey: out.ObjectKey,
+		Mime:      contentType,
+		SizeBytes: out.Size,
+		Checksum:  hex.EncodeToString(sum.Sum(nil)),
+		Label:     label,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+	if err := store.InsertAsset(ctx, h.pool, asset); err != nil {
+		failure = "db insert asset failed"
+		h.writeDBErr(w, r, err, "assets.create", "db insert asset failed")
+		return
+	}
+	assetDone = assetID
+	h.ev.Publish(ctx, events.AssetCreated, assetID, map[string]any{"kind": kind, "mime": contentType})
+
+	httpkit.WriteJSON(w, 201, map[string]any{"asset": asset})
+}
+
+// CreateUpload opens an upload session to pass to POST /assets as
+// upload_id, whose progress GET /assets/uploads/{uploadId} reports.
+func (h *Handler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	u, err := h.uploads.Create(r.Context())
+	if err != nil {
+		h.writeUploadErr(w, r, err, "")
 		return
 	}
+	httpkit.WriteJSON(w, 201, map[string]any{"upload": u})
+}
 
-	createdAt := time.Now().UTC()
-	provider := h.sp.Provider()
-	_, err = h.pool.Exec(ctx,
-		`INSERT INTO assets (id, kind, provider, object_key, mime, size_bytes, label, created_at)
-		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`,
-		assetID, kind, provider, out.ObjectKey, contentType, out.Size, nullIfEmpty(label), createdAt,
-	)
+// GetUpload returns the progress of an upload session.
+func (h *Handler) GetUpload(w http.ResponseWriter, r *http.Request) {
+	uploadID := chi.URLParam(r, "uploadId")
+	u, err := h.uploads.Get(r.Context(), uploadID)
 	if err != nil {
-		httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "db insert asset failed", nil)
+		h.writeUploadErr(w, r, err, uploadID)
 		return
 	}
+	httpkit.WriteJSON(w, 200, map[string]any{"upload": u})
+}
 
-	httpkit.WriteJSON(w, 201, map[string]any{
-		"asset": map[string]any{
-			"id":         assetID,
-			"kind":       kind,
-			"provider":   provider,
-			"object_key": out.ObjectKey,
-			"mime":       contentType,
-			"size_bytes": out.Size,
-			"label":      label,
-			"created_at": createdAt,
-		},
-	})
+// writeUploadErr writes an error of the upload sessions.
+func (h *Handler) writeUploadErr(w http.ResponseWriter, r *http.Request, err error, uploadID string) {
+	switch {
+	case errors.Is(err, uploads.ErrNotFound):
+		httpkit.WriteErr(w, r, 404, "UPLOAD_NOT_FOUND", "upload not found", map[string]any{"upload_id": uploadID})
+	case errors.Is(err, uploads.ErrStarted):
+		httpkit.WriteErr(w, r, 409, "UPLOAD_ALREADY_STARTED", "upload already started", map[string]any{"upload_id": uploadID})
+	default:
+		if h.log != nil {
+			h.log.FromContext(r.Context()).Error("upload tracking unavailable", "upload_id", uploadID, "error", err.Error())
+		}
+		httpkit.WriteErr(w, r, 503, "UNAVAILABLE", "upload tracking unavailable", nil)
+	}
+}
+
+// ListAssetKinds lists the asset kinds POST /assets accepts, with their
+// MIME and size rules.
+func (h *Handler) ListAssetKinds(w http.ResponseWriter, r *http.Request) {
+	httpkit.WriteJSON(w, 200, map[string]any{"kinds": h.kinds.Kinds()})
 }
 
 func (h *Handler) GetAsset(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	assetID := chi.URLParam(r, "assetId")
 
-	var (
-		id, kind, provider, objectKey, mimeType string
-		sizeBytes                               int64
-		label                                   sql.NullString
-		createdAt                               time.Time
-	)
-
-	err := h.pool.QueryRow(ctx,
-		`SELECT id, kind, provider, object_key, mime, size_bytes, label, created_at
-		 FROM assets WHERE id=$1`, assetID,
-	).Scan(&id, &kind, &provider, &objectKey, &mimeType, &sizeBytes, &label, &createdAt)
+	a, err := store.GetAsset(ctx, h.pool, assetID)
 	if err != nil {
-		httpkit.WriteErr(w, 404, "ASSET_NOT_FOUND", "asset not found", map[string]any{"asset_id": assetID})
+		if pgerr.IsNoRows(err) {
+			httpkit.WriteErr(w, r, 404, "ASSET_NOT_FOUND", "asset not found", map[string]any{"asset_id": assetID})
+			return
+		}
+		h.writeDBErr(w, r, err, "assets.get", "db query failed")
 		return
 	}
 
-	httpkit.WriteJSON(w, 200, map[string]any{
-		"asset": map[string]any{
-			"id":         id,
-			"kind":       kind,
-			"provider":   provider,
-			"object_key": objectKey,
-			"mime":       mimeType,
-			"size_bytes": sizeBytes,
-			"label":      label.String,
-			"created_at": createdAt,
-		},
+	httpkit.WriteJSON(w, 200, map[string]any{"asset": a})
+}
+
+// ListAssets lists assets newest first, filtered by kind and a free-text
+// match on label. With Accept: application/x-ndjson every match is
+// streamed one per line; otherwise results are paginated.
+func (h *Handler) ListAssets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+	f := store.AssetFilter{
+		Kind:   strings.TrimSpace(q.Get("kind")),
+		Search: strings.TrimSpace(q.Get("q")),
+	}
+
+	if httpkit.WantsNDJSON(r) {
+		assets, err := store.StreamAssets(ctx, h.reader(), f)
+		if err != nil {
+			h.writeDBErr(w, r, err, "assets.list", "db query failed")
+			return
+		}
+		if err := httpkit.StreamNDJSON(w, r, assets); err != nil && h.log != nil {
+			h.log.FromContext(ctx).Warn("assets stream aborted", "error", err.Error())
+		}
+		return
+	}
+
+	page, after, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	// Fetch one extra row to know whether there is a next page.
+	assets, err := store.ListAssets(ctx, h.reader(), f, after, page.Limit+1)
+	if err != nil {
+		h.writeDBErr(w, r, err, "assets.list", "db query failed")
+		return
+	}
+
+	assets, next := nextCursor(assets, page.Limit, func(a store.Asset) (time.Time, string) { return a.CreatedAt, a.ID })
+	httpkit.WriteList(w, r, httpkit.List{
+		Items:      assets,
+		NextCursor: next,
+		Filters:    map[string]string{"kind": f.Kind, "q": f.Search},
+		Legacy:     "assets",
 	})
 }
 
@@ -138,7 +253,7 @@ func (h *Handler) GetAssetURL(w http.ResponseWriter, r *http.Request) {
 
 	httpkit.WriteJSON(w, 200, map[string]any{
 		"asset_id":   assetID,
-		"url":        fmt.Sprintf("http://localhost:%s/assets/%s/content", util.Env("HTTP_PORT", "8080"), assetID),
+		"url":        fmt.Sprintf("http://localhost:%s%s", util.Env("HTTP_PORT", "8080"), httpkit.VersionedPath(r.Context(), "/assets/"+assetID+"/content")),
 		"expires_at": expiresAt,
 	})
 }
@@ -147,83 +262,99 @@ func (h *Handler) StreamAsset(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	assetID := chi.URLParam(r, "assetId")
 
-	var objectKey, mimeType string
-	var sizeBytes int64
-
-	err := h.pool.QueryRow(ctx,
-		`SELECT object_key, mime, size_bytes FROM assets WHERE id=$1`, assetID,
-	).Scan(&objectKey, &mimeType, &sizeBytes)
+	a, err := store.GetAsset(ctx, h.pool, assetID)
 	if err != nil {
-		httpkit.WriteErr(w, 404, "ASSET_NOT_FOUND", "asset not found", map[string]any{"asset_id": assetID})
+		if pgerr.IsNoRows(err) {
+			httpkit.WriteErr(w, r, 404, "ASSET_NOT_FOUND", "asset not found", map[string]any{"asset_id": assetID})
+			return
+		}
+		h.writeDBErr(w, r, err, "assets.stream", "db query failed")
 		return
 	}
 
-	rc, ct, _, err := h.sp.GetObject(ctx, objectKey)
+	if h.offload(w, a.ObjectKey, a.Mime) {
+		return
+	}
+	rc, ct, size, err := h.sp.GetObject(ctx, a.ObjectKey)
 	if err != nil {
-		httpkit.WriteErr(w, 404, "ASSET_FILE_MISSING", "asset file missing", map[string]any{"object_key": objectKey})
+		httpkit.WriteErr(w, r, 404, "ASSET_FILE_MISSING", "asset file missing", map[string]any{"object_key": a.ObjectKey})
 		return
 	}
 	defer rc.Close()
 
-	if ct == "" {
-		ct = mimeType
+	// The provider knows the stored object; the asset row is the fallback
+	if ct == "" || (ct == "application/octet-stream" && a.Mime != "") {
+		ct = a.Mime
+	}
+	if size <= 0 {
+		size = a.SizeBytes
 	}
 	w.Header().Set("Content-Type", ct)
-	if sizeBytes > 0 {
-		w.Header().Set("Content-Length", strconv.FormatInt(sizeBytes, 10))
+	if size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
 	}
-	_, _ = io.Copy(w, rc)
+	_, _ = storage.Copy(ctx, w, rc)
 }
 
-func (h *Handler) DeleteAsset(w http.ResponseWriter, r *http.Request) {
+// StreamHLS serves a file of the HLS rendition of a video asset:
+// /assets/{id}/hls/index.m3u8 is the playlist and its relative segment URIs
+// resolve to the sibling /assets/{id}/hls/{segment} routes. The files never
+// change once packaged, so they are cacheable.
+func (h *Handler) StreamHLS(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	assetID := chi.URLParam(r, "assetId")
+	name := chi.URLParam(r, "name")
 
-	var objectKey string
-	err := h.pool.QueryRow(ctx, `SELECT object_key FROM assets WHERE id=$1`, assetID).Scan(&objectKey)
+	a, err := store.GetHLSFile(ctx, h.pool, assetID, name)
 	if err != nil {
-		httpkit.WriteErr(w, 404, "ASSET_NOT_FOUND", "asset not found", map[string]any{"asset_id": assetID})
-		return
-	}
-
-	var cnt int
-	if err := h.pool.QueryRow(ctx,
-		`SELECT COUNT(1)
-		 FROM job_outputs
-		 WHERE video_asset_id=$1 OR thumbnail_asset_id=$1 OR captions_asset_id=$1`,
-		assetID,
-	).Scan(&cnt); err != nil {
-		if !httpkit.IsUndefinedTable(err) {
-			httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "db query failed", nil)
+		if pgerr.IsNoRows(err) || pgerr.IsUndefinedTable(err) {
+			httpkit.WriteErr(w, r, 404, string(CodeHLSNotFound), "hls file not found",
+				map[string]any{"asset_id": assetID, "name": name})
 			return
 		}
-		cnt = 0
-	}
-
-	if cnt > 0 {
-		httpkit.WriteErr(w, 409, "ASSET_IN_USE", "asset is referenced by job outputs", map[string]any{"asset_id": assetID})
+		h.writeDBErr(w, r, err, "assets.hls", "db query failed")
 		return
 	}
 
-	if err := h.sp.DeleteObject(ctx, objectKey); err != nil && !errors.Is(err, os.ErrNotExist) {
-		httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "storage delete failed", map[string]any{"object_key": objectKey})
+	w.Header().Set("Cache-Control", "public, max-age=86400, immutable")
+	if h.offload(w, a.ObjectKey, a.Mime) {
 		return
 	}
-
-	_, err = h.pool.Exec(ctx, `DELETE FROM assets WHERE id=$1`, assetID)
+	rc, _, _, err := h.sp.GetObject(ctx, a.ObjectKey)
 	if err != nil {
-		httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "db delete failed", nil)
+		w.Header().Del("Cache-Control")
+		httpkit.WriteErr(w, r, 404, "ASSET_FILE_MISSING", "asset file missing", map[string]any{"object_key": a.ObjectKey})
 		return
 	}
+	defer rc.Close()
 
-	w.WriteHeader(204)
+	w.Header().Set("Content-Type", a.Mime)
+	if a.SizeBytes > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(a.SizeBytes, 10))
+	}
+	_, _ = storage.Copy(ctx, w, rc)
 }
 
-func nullIfEmpty(s string) any {
-	if strings.TrimSpace(s) == "" {
-		return nil
+func (h *Handler) DeleteAsset(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	assetID := chi.URLParam(r, "assetId")
+
+	a, err := h.admin.DeleteAsset(ctx, assetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, admin.ErrAssetNotFound):
+			httpkit.WriteErr(w, r, 404, "ASSET_NOT_FOUND", "asset not found", map[string]any{"asset_id": assetID})
+		case errors.Is(err, admin.ErrAssetInUse):
+			httpkit.WriteErr(w, r, 409, "ASSET_IN_USE", "asset is referenced by job outputs", map[string]any{"asset_id": assetID})
+		case errors.Is(err, admin.ErrStorage):
+			httpkit.WriteErr(w, r, 500, "INTERNAL_ERROR", "storage delete failed", map[string]any{"object_key": a.ObjectKey})
+		default:
+			h.writeDBErr(w, r, err, "assets.delete", "db delete failed")
+		}
+		return
 	}
-	return s
+
+	w.WriteHeader(204)
 }
 
 func guessExt(contentType string) string {
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gala/internal/httpkit"
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/i18n"
+)
+
+// Resource-specific error codes returned by the API.
+const (
+	CodeAssetNotFound              errors.Code = "ASSET_NOT_FOUND"
+	CodeAssetFileMissing           errors.Code = "ASSET_FILE_MISSING"
+	CodeAssetInUse                 errors.Code = "ASSET_IN_USE"
+	CodeTemplateNotFound           errors.Code = "TEMPLATE_NOT_FOUND"
+	CodeTemplateNameExists         errors.Code = "TEMPLATE_NAME_EXISTS"
+	CodeJobNotFound                errors.Code = "JOB_NOT_FOUND"
+	CodeJobInvalidState            errors.Code = "JOB_INVALID_STATE"
+	CodeJobModified                errors.Code = "JOB_MODIFIED"
+	CodeStorageAuditUnsupported    errors.Code = "STORAGE_AUDIT_UNSUPPORTED"
+	CodeOutputNotFound             errors.Code = "OUTPUT_NOT_FOUND"
+	CodePublicationExists          errors.Code = "PUBLICATION_EXISTS"
+	CodePublishTargetNotConfigured errors.Code = "PUBLISH_TARGET_NOT_CONFIGURED"
+	CodeHLSNotFound                errors.Code = "HLS_NOT_FOUND"
+	CodeShareNotFound              errors.Code = "SHARE_NOT_FOUND"
+	CodeInputFetchFailed           errors.Code = "INPUT_FETCH_FAILED"
+	CodeUploadNotFound             errors.Code = "UPLOAD_NOT_FOUND"
+	CodeUploadStarted              errors.Code = "UPLOAD_ALREADY_STARTED"
+)
+
+func init() {
+	for _, info := range []errors.CodeInfo{
+		{Code: CodeAssetNotFound, HTTPStatus: 404, Description: "The asset does not exist."},
+		{Code: CodeAssetFileMissing, HTTPStatus: 404, Description: "The asset record exists but its file is missing from storage."},
+		{Code: CodeAssetInUse, HTTPStatus: 409, Description: "The asset is referenced by job outputs and cannot be deleted."},
+		{Code: CodeTemplateNotFound, HTTPStatus: 404, Description: "The template does not exist or was deleted."},
+		{Code: CodeTemplateNameExists, HTTPStatus: 409, Description: "Another template already uses this name."},
+		{Code: CodeJobNotFound, HTTPStatus: 404, Description: "The job does not exist."},
+		{Code: CodeJobInvalidState, HTTPStatus: 409, Description: "The job's status does not allow this action (e.g. canceling a running job)."},
+		{Code: CodeJobModified, HTTPStatus: 409, Description: "The job changed since the updated_at sent with the edit; read it again and retry."},
+		{Code: CodeStorageAuditUnsupported, HTTPStatus: 501, Description: "The active storage provider cannot list its objects, so it cannot be audited."},
+		{Code: CodeOutputNotFound, HTTPStatus: 404, Description: "The job has no output with that variant."},
+		{Code: CodePublicationExists, HTTPStatus: 409, Description: "The output is already published, or being published, to that target."},
+		{Code: CodePublishTargetNotConfigured, HTTPStatus: 501, Description: "The publish target is unknown or has no credentials in this deployment."},
+		{Code: CodeHLSNotFound, HTTPStatus: 404, Description: "The video asset has no HLS rendition, or the rendition has no file with that name."},
+		{Code: CodeShareNotFound, HTTPStatus: 404, Description: "The share link does not exist, was revoked or has expired."},
+		{Code: CodeInputFetchFailed, HTTPStatus: 422, Description: "An input URL could not be downloaded, or its file was refused (size, content type or address)."},
+		{Code: CodeUploadNotFound, HTTPStatus: 404, Description: "The upload session does not exist or has expired."},
+		{Code: CodeUploadStarted, HTTPStatus: 409, Description: "The upload session already received its file; create a new one."},
+	} {
+		errors.Register(info)
+	}
+
+	es := i18n.Default()
+	for code, t := range map[errors.Code]string{
+		CodeAssetNotFound:              "No se encontró el recurso multimedia.",
+		CodeAssetFileMissing:           "Falta el archivo del recurso multimedia.",
+		CodeAssetInUse:                 "El recurso multimedia está en uso por resultados de trabajos.",
+		CodeTemplateNotFound:           "No se encontró la plantilla.",
+		CodeTemplateNameExists:         "Ya existe una plantilla con ese nombre.",
+		CodeJobNotFound:                "No se encontró el trabajo.",
+		CodeJobInvalidState:            "El estado del trabajo no permite esta acción.",
+		CodeJobModified:                "El trabajo cambió desde que se leyó; vuelve a leerlo y reintenta.",
+		CodeStorageAuditUnsupported:    "El proveedor de almacenamiento activo no permite auditarlo.",
+		CodeOutputNotFound:             "El trabajo no tiene un resultado con esa variante.",
+		CodePublicationExists:          "El resultado ya está publicado, o publicándose, en ese destino.",
+		CodePublishTargetNotConfigured: "El destino de publicación no existe o no está configurado.",
+		CodeHLSNotFound:                "El video no tiene una versión HLS con ese archivo.",
+		CodeShareNotFound:              "El enlace compartido no existe, fue revocado o expiró.",
+		CodeInputFetchFailed:           "No se pudo descargar un input por URL, o su archivo fue rechazado.",
+		CodeUploadNotFound:             "La sesión de subida no existe o expiró.",
+		CodeUploadStarted:              "La sesión de subida ya recibió su archivo; crea otra.",
+	} {
+		es.AddCode("es", code, t)
+	}
+	for msg, t := range map[string]string{
+		"invalid json body":                           "El cuerpo JSON no es válido.",
+		"invalid multipart form":                      "El formulario multipart no es válido.",
+		"kind is required":                            "El campo kind es obligatorio.",
+		"file is required":                            "El archivo es obligatorio.",
+		"type is required":                            "El campo type es obligatorio.",
+		"name is required":                            "El campo name es obligatorio.",
+		"type cannot be empty":                        "El campo type no puede estar vacío.",
+		"name cannot be empty":                        "El campo name no puede estar vacío.",
+		"params.text is required":                     "El campo params.text es obligatorio.",
+		"limit must be a positive integer":            "El campo limit debe ser un entero positivo.",
+		"invalid cursor":                              "El cursor no es válido.",
+		"config reload is not enabled":                "La recarga de configuración no está habilitada.",
+		"job status does not allow this action":       "El estado del trabajo no permite esta acción.",
+		"older_than must be a duration like 720h":     "El campo older_than debe ser una duración como 720h.",
+		"worker registry unavailable":                 "El registro de workers no está disponible.",
+		"min_age must be a duration like 1h":          "El campo min_age debe ser una duración como 1h.",
+		"unknown repair action":                       "Acción de reparación desconocida.",
+		"storage provider cannot list objects":        "El proveedor de almacenamiento no puede listar sus objetos.",
+		"invalid Last-Event-ID":                       "El Last-Event-ID no es válido.",
+		"event stream unavailable":                    "El stream de eventos no está disponible.",
+		"target is required":                          "El campo target es obligatorio.",
+		"variant must not be negative":                "El campo variant no puede ser negativo.",
+		"title is too long":                           "El título es demasiado largo.",
+		"description is too long":                     "La descripción es demasiado larga.",
+		"privacy must be private, unlisted or public": "El campo privacy debe ser private, unlisted o public.",
+		"publish target is not configured":            "El destino de publicación no está configurado.",
+		"job output not found":                        "No se encontró el resultado del trabajo.",
+		"output is already published to this target":  "El resultado ya está publicado en ese destino.",
+		"publication failed":                          "La publicación falló.",
+		"hls file not found":                          "No se encontró el archivo HLS.",
+		"watermark.asset_id is required":              "El campo watermark.asset_id es obligatorio.",
+		"watermark.position is invalid":               "El campo watermark.position no es válido.",
+		"watermark.opacity must be in (0, 1]":         "El campo watermark.opacity debe estar en (0, 1].",
+		"watermark.disabled is not valid here":        "El campo watermark.disabled no se admite aquí.",
+		"the template has no watermark":               "El template no tiene watermark.",
+		"watermark requires template_id":              "El watermark requiere template_id.",
+		"watermark asset not found":                   "No se encontró el recurso del watermark.",
+		"watermark asset must be an image":            "El recurso del watermark debe ser una imagen.",
+		"expires_in is out of range":                  "El campo expires_in está fuera de rango.",
+		"share token generation failed":               "No se pudo generar el token del enlace.",
+		"share not found":                             "No se encontró el enlace compartido.",
+		"month must be YYYY-MM":                       "El campo month debe tener el formato YYYY-MM.",
+		"format must be json or csv":                  "El campo format debe ser json o csv.",
+		"usage report failed":                         "No se pudo generar el reporte de uso.",
+		"template is required":                        "El campo template es obligatorio.",
+		"too many inputs":                             "Hay demasiados inputs.",
+		"input must be an https url":                  "El input debe ser una URL https.",
+		"input could not be downloaded":               "No se pudo descargar el input.",
+		"input import failed":                         "No se pudo importar el input.",
+		"asset slot name is invalid":                  "El nombre del slot de asset no es válido.",
+		"asset slot asset_id is required":             "El asset_id del slot es obligatorio.",
+		"asset slot asset not found":                  "No se encontró el asset del slot.",
+		"asset slot asset has another kind":           "El asset del slot es de otro kind.",
+		"priority must be normal or high":             "El campo priority debe ser normal o high.",
+		"jobs is required":                            "El campo jobs es obligatorio.",
+		"too many jobs":                               "Hay demasiados jobs.",
+		"template not found":                          "No se encontró la plantilla.",
+		"run_at is too far in the future":             "El campo run_at está demasiado lejos en el futuro.",
+		"progress must be between 0 and 100":          "El campo progress debe estar entre 0 y 100.",
+		"renderer auth unavailable":                   "La autenticación del renderer no está disponible.",
+		"upload not found":                            "No se encontró la subida.",
+		"upload already started":                      "La subida ya comenzó.",
+		"upload tracking unavailable":                 "El seguimiento de subidas no está disponible.",
+	} {
+		es.AddMessage("es", msg, t)
+	}
+}
+
+// ErrorCatalog lists every error code the API can return.
+func (h *Handler) ErrorCatalog(w http.ResponseWriter, r *http.Request) {
+	httpkit.WriteJSON(w, 200, map[string]any{"codes": errors.Catalog()})
+}
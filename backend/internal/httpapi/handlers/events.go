@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"gala/internal/events"
+	"gala/internal/httpkit"
+	"gala/internal/jobs"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/store"
+)
+
+const (
+	// eventsHeartbeat is how long the stream waits for events before
+	// sending a comment, so proxies keep the connection open and closed
+	// clients are noticed.
+	eventsHeartbeat = 15 * time.Second
+	// eventsBatch is how many events are read from Redis at once.
+	eventsBatch = 100
+	// eventsRetry is the reconnection delay suggested to EventSource.
+	eventsRetry = 3 * time.Second
+)
+
+// StreamEvents streams the job, asset, template and publication events as
+// Server-Sent Events. Last-Event-ID (or the last_event_id query param)
+// resumes after that event; without it only new events are sent. types
+// keeps only the listed types or categories, e.g. types=job,asset.created.
+func (h *Handler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := h.getLogger(nil).FromContext(ctx)
+
+	last := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if last == "" {
+		last = strings.TrimSpace(r.URL.Query().Get("last_event_id"))
+	}
+	if last != "" && !events.ValidID(last) {
+		httpkit.WriteErr(w, r, 400, "VALIDATION_ERROR", "invalid Last-Event-ID", map[string]any{"field": "last_event_id"})
+		return
+	}
+	match := eventFilter(r.URL.Query().Get("types"))
+
+	// Also checks Redis when resuming, so an outage answers 503 instead of
+	// a stream that ends at once
+	id, err := h.ev.LastID(ctx)
+	if err != nil {
+		log.Error("event stream unavailable", "error", err.Error())
+		httpkit.WriteErr(w, r, 503, "UNAVAILABLE", "event stream unavailable", nil)
+		return
+	}
+	if last == "" {
+		last = id
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Tell nginx-style proxies not to buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", eventsRetry.Milliseconds())
+	_ = rc.Flush()
+
+	for {
+		evs, err := h.ev.Read(ctx, last, eventsBatch, eventsHeartbeat)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn("event stream read failed", "error", err.Error())
+			}
+			// The client reconnects with the last ID it got
+			return
+		}
+		// Draining: end the stream so the client reconnects elsewhere
+		if h.ready != nil && !h.ready() {
+			return
+		}
+
+		if len(evs) == 0 {
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		for _, e := range evs {
+			last = e.ID
+			if !match(e.Type) {
+				continue
+			}
+			data, _ := json.Marshal(e)
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// jobStatusEvent is the first event of GET /jobs/{jobId}/events: the job
+// as it stands when the stream starts.
+const jobStatusEvent = "job.status"
+
+// StreamJobEvents streams the events of one job as Server-Sent Events:
+// first a job.status event with the job, then its status transitions and
+// progress as the worker publishes them. The stream ends after the job
+// ends (job.done, job.failed or job.canceled); a client that reconnects
+// gets the current job again, so missed events need no replay.
+func (h *Handler) StreamJobEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := h.getLogger(nil).FromContext(ctx)
+	jobID := chi.URLParam(r, "jobId")
+
+	// Subscribe before reading the job: a transition in between shows in
+	// one or the other
+	evs, stop, err := h.ev.SubscribeJob(ctx, jobID)
+	if err != nil {
+		log.Error("job event stream unavailable", "error", err.Error())
+		httpkit.WriteErr(w, r, 503, "UNAVAILABLE", "event stream unavailable", nil)
+		return
+	}
+	defer stop()
+
+	job, err := store.GetJob(ctx, h.pool, jobID)
+	if err != nil {
+		if pgerr.IsNoRows(err) {
+			httpkit.WriteErr(w, r, 404, "JOB_NOT_FOUND", "job not found", map[string]any{"job_id": jobID})
+			return
+		}
+		h.writeDBErr(w, r, err, "jobs.events", "db query failed")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", eventsRetry.Milliseconds())
+
+	first := events.Event{
+		Type:    jobStatusEvent,
+		Subject: job.ID,
+		Time:    time.Now().UTC(),
+		Data:    map[string]any{"status": job.Status, "job": job},
+	}
+	data, _ := json.Marshal(first)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", first.Type, data)
+	if err := rc.Flush(); err != nil || jobs.Final(job.Status) {
+		return
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			// Draining: end the stream so the client reconnects elsewhere
+			if h.ready != nil && !h.ready() {
+				return
+			}
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-evs:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(e)
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+				return
+			}
+			if events.Terminal(e.Type) {
+				_ = rc.Flush()
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// eventFilter matches event types against a comma-separated list of types
+// ("job.done") and categories ("job"); an empty list matches everything.
+func eventFilter(list string) func(typ string) bool {
+	var want []string
+	for _, t := range strings.Split(list, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			want = append(want, t)
+		}
+	}
+	return func(typ string) bool {
+		if len(want) == 0 {
+			return true
+		}
+		for _, w := range want {
+			if typ == w || strings.HasPrefix(typ, w+".") {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -1,25 +1,91 @@
 package handlers
 
 import (
+	"iter"
+	"net/http"
+	"time"
+
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 
+	"gala/internal/admin"
+	"gala/internal/assetkind"
+	"gala/internal/events"
+	"gala/internal/health"
+	"gala/internal/httpkit"
+	"gala/internal/inputs"
+	"gala/internal/jobs"
+	"gala/internal/pkg/dbpool"
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/fetch"
 	"gala/internal/pkg/logger"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/pkg/reload"
 	"gala/internal/ports"
+	"gala/internal/publish"
+	"gala/internal/store"
+	"gala/internal/uploads"
+	"gala/internal/worker/queue"
+	"gala/internal/worker/renderer"
 )
 
 type Deps struct {
 	Pool *pgxpool.Pool
-	RDB  *redis.Client
-	SP   ports.StorageProvider
-	Log  *logger.Logger
+	// DB routes heavy reads to replicas; nil sends everything to Pool.
+	DB     *dbpool.Router
+	RDB    redis.UniversalClient
+	SP     ports.StorageProvider
+	Log    *logger.Logger
+	Reload *reload.Manager
+	// Ready backs /readyz; nil means always ready.
+	Ready func() bool
+	// QueueMode "postgres" skips the Redis push on job creation: workers
+	// claim QUEUED rows directly.
+	QueueMode string
+	// Events receives the lifecycle events and backs GET /v1/events; nil
+	// publishes none.
+	Events *events.Bus
+	// Publisher runs the job publications; nil uses one without targets.
+	Publisher *publish.Service
+	// Fetch downloads the inputs given by URL; nil uses the fetch
+	// defaults.
+	Fetch *fetch.Client
+	// Stream sets how asset files are served; the zero value proxies them.
+	Stream StreamConfig
+	// Health serves the deep checks of GET /health from its snapshot; nil
+	// runs them on every request.
+	Health *health.Monitor
+	// AssetKinds validates the kind of uploaded assets; nil uses
+	// assetkind.Default.
+	AssetKinds *assetkind.Registry
+	// DedupWindow reuses DONE jobs identical to a new one for this long
+	// (JOB_DEDUP_WINDOW); 0 renders every job.
+	DedupWindow time.Duration
+	// RendererAuth holds the renderer's shared secret (RENDERER_AUTH_*),
+	// which signs the progress it reports; nil accepts the reports
+	// unauthenticated.
+	RendererAuth renderer.SecretSource
 }
 
 type Handler struct {
-	pool *pgxpool.Pool
-	rdb  *redis.Client
-	sp   ports.StorageProvider
-	log  *logger.Logger
+	pool    *pgxpool.Pool
+	db      *dbpool.Router
+	rdb     redis.UniversalClient
+	sp      ports.StorageProvider
+	log     *logger.Logger
+	reload  *reload.Manager
+	ready   func() bool
+	admin   *admin.Service
+	jobs    *jobs.Service
+	ev      *events.Bus
+	publish *publish.Service
+	inputs  *inputs.Importer
+	stream  StreamConfig
+	health  *health.Monitor
+	kinds   *assetkind.Registry
+	uploads *uploads.Tracker
+	// rendererAuth verifies POST /jobs/{jobId}/progress; nil skips it.
+	rendererAuth renderer.SecretSource
 }
 
 func New(d Deps) *Handler {
@@ -29,11 +95,43 @@ func New(d Deps) *Handler {
 		handlerLog = handlerLog.WithComponent("handlers")
 	}
 
+	db := d.DB
+	if db == nil {
+		db = dbpool.NewRouter(d.Pool)
+	}
+
+	pushJobs := d.QueueMode != queue.ModePostgres
+	pub := d.Publisher
+	if pub == nil {
+		pub = publish.New(publish.Deps{Pool: d.Pool, SP: d.SP, Events: d.Events, Log: d.Log})
+	}
+	fc := d.Fetch
+	if fc == nil {
+		fc = fetch.New(fetch.Config{})
+	}
+	kinds := d.AssetKinds
+	if kinds == nil {
+		kinds = assetkind.Default()
+	}
 	return &Handler{
-		pool: d.Pool,
-		rdb:  d.RDB,
-		sp:   d.SP,
-		log:  handlerLog,
+		pool:    d.Pool,
+		db:      db,
+		rdb:     d.RDB,
+		sp:      d.SP,
+		log:     handlerLog,
+		reload:  d.Reload,
+		ready:   d.Ready,
+		admin:   admin.New(d.Pool, d.RDB, d.SP, d.Events, queue.DefaultName, pushJobs),
+		jobs:    jobs.New(d.Pool, d.RDB, d.Events, queue.DefaultName, pushJobs).WithDedup(d.DedupWindow),
+		ev:      d.Events,
+		publish: pub,
+		inputs:  inputs.NewImporter(d.Pool, d.SP, d.Events, fc),
+		stream:  d.Stream,
+		health:  d.Health,
+		kinds:   kinds,
+		uploads: uploads.New(d.RDB, 0),
+
+		rendererAuth: d.RendererAuth,
 	}
 }
 
@@ -44,3 +142,102 @@ func (h *Handler) getLogger(_ interface{ Context() interface{} }) *logger.Logger
 	}
 	return h.log
 }
+
+// reader returns the pool for list and export queries, which may lag the
+// primary by the replication delay. Anything that writes, or that a client
+// reads right after writing it, must use h.pool.
+func (h *Handler) reader() *pgxpool.Pool {
+	return h.db.Reader()
+}
+
+// writeDBErr translates a database error (see pgerr) and writes the
+// matching response: 503 when the database is unreachable, 504 on timeouts,
+// 500 otherwise. Not-found is left to callers because the API contract uses
+// resource-specific codes (ASSET_NOT_FOUND, JOB_NOT_FOUND, ...).
+func (h *Handler) writeDBErr(w http.ResponseWriter, r *http.Request, err error, op, msg string) {
+	e := pgerr.Wrap(err, op, msg)
+	if h.log != nil {
+		h.log.FromContext(r.Context()).Error("database error",
+			"op", op,
+			"code", string(e.Code),
+			"error", err.Error(),
+		)
+	}
+	httpkit.WriteErr(w, r, e.HTTPStatus(), string(e.Code), msg, nil)
+}
+
+// writeQueueErr writes a 503 for a failure to reach the Redis job queue
+// (jobs.ErrQueuePush, admin.ErrQueue): the action was not applied and can
+// be retried once Redis is back.
+func (h *Handler) writeQueueErr(w http.ResponseWriter, r *http.Request, err error, op string) {
+	if h.log != nil {
+		h.log.FromContext(r.Context()).Error("job queue unavailable", "op", op, "error", err.Error())
+	}
+	httpkit.WriteErr(w, r, 503, string(errors.CodeUnavailable), "job queue unavailable", nil)
+}
+
+// writeCheckErr writes the validation errors of DB-backed checks (such as
+// watermark.CheckAsset) as they are, and anything else as a database error.
+func (h *Handler) writeCheckErr(w http.ResponseWriter, r *http.Request, err error, op string) {
+	if errors.IsValidation(err) {
+		httpkit.WriteError(w, r, err)
+		return
+	}
+	h.writeDBErr(w, r, err, op, "db query failed")
+}
+
+// parsePage reads limit/cursor and decodes the (created_at, id) keyset used
+// by list endpoints. On invalid input it writes a 400 and returns false.
+func parsePage(w http.ResponseWriter, r *http.Request) (page httpkit.Page, after *store.Keyset, ok bool) {
+	page, err := httpkit.ParsePage(r, httpkit.DefaultLimit, httpkit.MaxLimit)
+	if err != nil {
+		httpkit.WriteErr(w, r, 400, "VALIDATION_ERROR", "limit must be a positive integer", map[string]any{"field": "limit"})
+		return page, nil, false
+	}
+	if page.Cursor == "" {
+		return page, nil, true
+	}
+	var k store.Keyset
+	if err := httpkit.DecodeCursor(page.Cursor, &k.CreatedAt, &k.ID); err != nil {
+		httpkit.WriteErr(w, r, 400, "VALIDATION_ERROR", "invalid cursor", map[string]any{"field": "cursor"})
+		return page, nil, false
+	}
+	return page, &k, true
+}
+
+// nextCursor trims items fetched with limit+1 to one page and returns the
+// cursor of the next page, or "" if this is the last one. key gives the
+// keyset of an item. The page is never nil, so an empty one encodes as [].
+func nextCursor[T any](items []T, limit int, key func(T) (time.Time, string)) ([]T, string) {
+	if items == nil {
+		items = []T{}
+	}
+	if len(items) <= limit {
+		return items, ""
+	}
+	items = items[:limit]
+	createdAt, id := key(items[len(items)-1])
+	return items, httpkit.EncodeCursor(createdAt, id)
+}
+
+// mapSeq converts the items of a store iterator for httpkit.StreamNDJSON.
+func mapSeq[T, U any](seq iter.Seq2[T, error], f func(T) U) iter.Seq2[U, error] {
+	return func(yield func(U, error) bool) {
+		for item, err := range seq {
+			var out U
+			if err == nil {
+				out = f(item)
+			}
+			if !yield(out, err) {
+				return
+			}
+		}
+	}
+}
+
+// now returns the current time as Postgres stores it (UTC, microsecond
+// precision), so a create response carries the same timestamps a later
+// read returns.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,73 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"gala/internal/store"
+	"gala/internal/testinfra"
+)
+
+func TestMain(m *testing.M) { testinfra.Main(m) }
+
+// insertJob inserts a QUEUED job with id.
+func insertJob(t *testing.T, env *testinfra.Env, id string) {
+	t.Helper()
+	err := store.InsertJob(context.Background(), env.Pool, store.Job{
+		ID:         id,
+		ParamsJSON: `{"text":"hola"}`,
+		CreatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// failJob inserts a job with id that ran once and failed with msg.
+func failJob(t *testing.T, env *testinfra.Env, id, msg string) {
+	t.Helper()
+	ctx := context.Background()
+	insertJob(t, env, id)
+	if _, _, err := store.MarkJobRunning(ctx, env.Pool, id, false); err != nil {
+		t.Fatal(err)
+	}
+	err := store.MarkJobFailed(ctx, env.Pool, id, msg, store.JobError{Code: "INTERNAL_ERROR", Message: msg})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// deadRedis is a client of a Redis that is not there.
+func deadRedis(t *testing.T) redis.UniversalClient {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return rdb
+}
+
+// serve sends method path to h and decodes the JSON answer into out (nil
+// skips it).
+func serve(t *testing.T, h http.Handler, method, path string, out any) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: %v; body: %s", method, path, err, rec.Body)
+		}
+	}
+	return rec
+}
+
+// errorBody is the error envelope of the API.
+type errorBody struct {
+	Error struct {
+		Code    string         `json:"code"`
+		Details map[string]any `json:"details"`
+	} `json:"error"`
+}
@@ -5,10 +5,13 @@ import (
 	"net/http"
 	"time"
 
+	"gala/internal/health"
 	"gala/internal/httpkit"
 )
 
-// Health performs a health check of the service.
+// Health performs a health check of the service. With deep=true it adds
+// the dependency checks: the monitor's last snapshot when the API runs one
+// (with checked_at telling how fresh it is), or else checks run inline.
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := h.log.FromContext(ctx)
@@ -22,93 +25,40 @@ func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 
 	// Check if deep health check is requested
 	if r.URL.Query().Get("deep") == "true" {
-		checks := h.deepHealthCheck(ctx)
-		health["checks"] = checks
-
-		// If any check failed, change status
-		for _, check := range checks {
-			if checkMap, ok := check.(map[string]any); ok {
-				if checkMap["status"] != "ok" {
-					health["status"] = "degraded"
-					log.Warn("health check degraded", "checks", checks)
-					break
-				}
-			}
+		snap, stale := h.deepHealthCheck(ctx)
+		health["checks"] = snap.Checks
+		health["checked_at"] = snap.CheckedAt
+		health["age_ms"] = time.Since(snap.CheckedAt).Milliseconds()
+
+		if stale {
+			health["status"] = "degraded"
+			health["stale"] = true
+			log.Warn("health snapshot is stale", "checked_at", snap.CheckedAt)
+		} else if !snap.OK() {
+			health["status"] = "degraded"
+			log.Warn("health check degraded", "checks", snap.Checks)
 		}
 	}
 
 	httpkit.WriteJSON(w, 200, health)
 }
 
-// deepHealthCheck performs detailed health checks on dependencies.
-func (h *Handler) deepHealthCheck(ctx context.Context) map[string]any {
-	checks := make(map[string]any)
-
-	// PostgreSQL check
-	checks["postgres"] = h.checkPostgres(ctx)
-
-	// Redis check
-	checks["redis"] = h.checkRedis(ctx)
-
-	// Storage check
-	checks["storage"] = h.checkStorage(ctx)
-
-	return checks
-}
-
-func (h *Handler) checkPostgres(ctx context.Context) map[string]any {
-	start := time.Now()
-	result := map[string]any{
-		"status": "ok",
-	}
-
-	// Create a context with timeout
-	checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	// Ping the database
-	if err := h.pool.Ping(checkCtx); err != nil {
-		result["status"] = "error"
-		result["error"] = err.Error()
-	} else {
-		// Get pool stats
-		stats := h.pool.Stat()
-		result["total_conns"] = stats.TotalConns()
-		result["idle_conns"] = stats.IdleConns()
-		result["acquired_conns"] = stats.AcquiredConns()
+// Ready reports whether the instance should receive traffic. Unlike
+// Health it does not look at dependencies: it only fails once shutdown has
+// started, so load balancers stop routing here before the server drains.
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.ready != nil && !h.ready() {
+		httpkit.WriteJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "draining"})
+		return
 	}
-
-	result["latency_ms"] = time.Since(start).Milliseconds()
-	return result
+	httpkit.WriteJSON(w, http.StatusOK, map[string]any{"status": "ready"})
 }
 
-func (h *Handler) checkRedis(ctx context.Context) map[string]any {
-	start := time.Now()
-	result := map[string]any{
-		"status": "ok",
+// deepHealthCheck returns the dependency checks and whether they are stale.
+func (h *Handler) deepHealthCheck(ctx context.Context) (health.Snapshot, bool) {
+	if h.health != nil {
+		snap := h.health.Snapshot()
+		return snap, h.health.Stale(snap, time.Now())
 	}
-
-	// Create a context with timeout
-	checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	// Ping Redis
-	if err := h.rdb.Ping(checkCtx).Err(); err != nil {
-		result["status"] = "error"
-		result["error"] = err.Error()
-	}
-
-	result["latency_ms"] = time.Since(start).Milliseconds()
-	return result
-}
-
-func (h *Handler) checkStorage(_ context.Context) map[string]any {
-	result := map[string]any{
-		"status":   "ok",
-		"provider": h.sp.Provider(),
-	}
-
-	// For now, just report the provider type
-	// In the future, we could add actual connectivity checks
-	return result
+	return health.Run(ctx, health.Checks(h.pool, h.rdb, h.sp)), false
 }
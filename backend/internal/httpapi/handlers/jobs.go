@@ -1,18 +1,27 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 
-	"gala/internal/httpapi/util"
+	"gala/internal/events"
 	"gala/internal/httpkit"
+	"gala/internal/jobs"
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/fetch"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/store"
+	"gala/internal/watermark"
+	"gala/internal/worker/renderer"
 )
 
 type CreateJobRequest struct {
@@ -20,19 +29,32 @@ type CreateJobRequest struct {
 	TemplateID string            `json:"template_id,omitempty"`
 	Inputs     map[string]string `json:"inputs,omitempty"`
 	Params     map[string]any    `json:"params"`
+	// Watermark overrides the template's watermark field by field;
+	// {"disabled": true} renders without it.
+	Watermark *watermark.Config `json:"watermark,omitempty"`
+	// CallbackURL receives a POST when the job finishes; CallbackSecret,
+	// if set, signs it (see package callback).
+	CallbackURL    string `json:"callback_url,omitempty"`
+	CallbackSecret string `json:"callback_secret,omitempty"`
+	// Priority is normal (default) or high: high jobs are taken before
+	// every normal one.
+	Priority string `json:"priority,omitempty"`
+	// RunAt, if in the future, schedules the job: it stays SCHEDULED
+	// until then. A past or missing run_at queues it at once.
+	RunAt *time.Time `json:"run_at,omitempty"`
 }
 
-func (h *Handler) PostJob(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+// MaxCallbackSecret bounds callback_secret.
+const MaxCallbackSecret = 256
 
-	var req CreateJobRequest
-	if err := httpkit.DecodeJSON(r, &req); err != nil {
-		httpkit.WriteErr(w, 400, "VALIDATION_ERROR", "invalid json body", nil)
-		return
-	}
+// MaxScheduleAhead bounds how far in the future run_at can be.
+const MaxScheduleAhead = 365 * 24 * time.Hour
 
+func (req *CreateJobRequest) Validate() error {
 	req.Name = strings.TrimSpace(req.Name)
 	req.TemplateID = strings.TrimSpace(req.TemplateID)
+	req.CallbackURL = strings.TrimSpace(req.CallbackURL)
+	req.Priority = strings.ToLower(strings.TrimSpace(req.Priority))
 
 	if req.Params == nil {
 		req.Params = map[string]any{}
@@ -41,253 +63,534 @@ func (h *Handler) PostJob(w http.ResponseWriter, r *http.Request) {
 		req.Inputs = map[string]string{}
 	}
 
+	var v httpkit.Validator
 	// Legacy path stays stable
 	if req.TemplateID == "" {
-		if _, ok := req.Params["text"]; !ok {
-			httpkit.WriteErr(w, 400, "VALIDATION_ERROR", "params.text is required", map[string]any{"field": "params.text"})
-			return
-		}
-	} else {
-		var tmp string
-		err := h.pool.QueryRow(ctx, `SELECT id FROM templates WHERE id=$1 AND deleted_at IS NULL`, req.TemplateID).Scan(&tmp)
-		if err != nil {
-			httpkit.WriteErr(w, 404, "TEMPLATE_NOT_FOUND", "template not found", map[string]any{"template_id": req.TemplateID})
+		_, ok := req.Params["text"]
+		v.Check(ok, "params.text", "params.text is required")
+		v.Check(req.Watermark == nil, "watermark", "watermark requires template_id")
+	} else if req.Watermark != nil {
+		req.Watermark.Normalize()
+		req.Watermark.Check("watermark", false, v.Check)
+	}
+	if req.CallbackURL != "" {
+		_, err := fetch.ParseURL(req.CallbackURL)
+		v.Check(err == nil, "callback_url", "callback_url must be an https url")
+	}
+	v.Check(req.CallbackSecret == "" || req.CallbackURL != "", "callback_secret", "callback_secret requires callback_url")
+	v.Check(len(req.CallbackSecret) <= MaxCallbackSecret, "callback_secret", "callback_secret is too long")
+	v.Check(req.Priority == "" || slices.Contains(store.JobPriorities, req.Priority), "priority", "priority must be normal or high")
+	v.Check(req.RunAt == nil || time.Until(*req.RunAt) <= MaxScheduleAhead, "run_at", "run_at is too far in the future")
+	return v.Err()
+}
+
+// spec is the jobs.Spec of a validated request.
+func (req *CreateJobRequest) spec() jobs.Spec {
+	spec := jobs.Spec{
+		TemplateID: req.TemplateID,
+		Inputs:     req.Inputs,
+		Params:     req.Params,
+		Watermark:  req.Watermark,
+		Priority:   req.Priority,
+	}
+	if req.RunAt != nil {
+		spec.RunAt = *req.RunAt
+	}
+	if req.CallbackURL != "" {
+		spec.Callback = &jobs.Callback{URL: req.CallbackURL, Secret: req.CallbackSecret}
+	}
+	return spec
+}
+
+func (h *Handler) PostJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateJobRequest
+	if !httpkit.DecodeAndValidate(w, r, &req) {
+		return
+	}
+
+	job, reused, err := h.jobs.Create(ctx, req.Name, req.spec())
+	switch {
+	case errors.Is(err, jobs.ErrTemplateNotFound):
+		httpkit.WriteErr(w, r, 404, "TEMPLATE_NOT_FOUND", "template not found", map[string]any{"template_id": req.TemplateID})
+		return
+	case errors.Is(err, jobs.ErrQueuePush):
+		h.writeQueueErr(w, r, err, "jobs.create")
+		return
+	case errors.IsValidation(err):
+		httpkit.WriteError(w, r, err)
+		return
+	case err != nil:
+		h.writeDBErr(w, r, err, "jobs.create", "db insert failed")
+		return
+	}
+
+	resp := newJobResponse(job)
+	if reused {
+		// An identical job rendered recently: answer with it and its outputs
+		if msg, err := h.fillOutputs(ctx, &resp); err != nil {
+			h.writeDBErr(w, r, err, "jobs.create", msg)
 			return
 		}
+		httpkit.WriteJSON(w, 200, map[string]any{"job": resp, "deduplicated": true})
+		return
+	}
+	if req.CallbackURL != "" {
+		resp.Callback = &callbackResponse{URL: req.CallbackURL}
 	}
 
-	jobID := util.NewID("job")
+	httpkit.WriteJSON(w, 201, map[string]any{"job": resp})
+}
+
+// MaxBatchJobs bounds the jobs of POST /jobs/batch.
+const MaxBatchJobs = 100
+
+// CreateJobBatchRequest is the body of POST /jobs/batch: jobs as POST
+// /jobs takes them.
+type CreateJobBatchRequest struct {
+	Jobs []CreateJobRequest `json:"jobs"`
+}
 
-	var toStore any = req.Params
-	if req.TemplateID != "" {
-		toStore = map[string]any{
-			"template_id": req.TemplateID,
-			"inputs":      req.Inputs,
-			"params":      req.Params,
+func (req *CreateJobBatchRequest) Validate() error {
+	var v httpkit.Validator
+	v.Check(len(req.Jobs) > 0, "jobs", "jobs is required")
+	v.Check(len(req.Jobs) <= MaxBatchJobs, "jobs", "too many jobs")
+	if err := v.Err(); err != nil {
+		return err
+	}
+	var errs []error
+	for i := range req.Jobs {
+		if err := req.Jobs[i].Validate(); err != nil {
+			errs = append(errs, batchItemErr(i, err))
 		}
 	}
-	paramsBytes, _ := json.Marshal(toStore)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.Aggregate(errs...)
+}
 
-	createdAt := time.Now().UTC()
-	_, err := h.pool.Exec(ctx,
-		`INSERT INTO jobs (id, name, status, params_json, created_at)
-		 VALUES ($1,$2,'QUEUED',$3,$4)`,
-		jobID, nullIfEmpty(req.Name), string(paramsBytes), createdAt,
-	)
-	if err != nil {
-		httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "db insert failed", nil)
-		return
+// batchItemErr moves the field errors of err, the failure of the job at
+// index i of a batch, under jobs[i].
+func batchItemErr(i int, err error) error {
+	prefix := fmt.Sprintf("jobs[%d]", i)
+	if errors.Is(err, jobs.ErrTemplateNotFound) {
+		return errors.ValidationField(prefix+".template_id", "template not found")
+	}
+	e := errors.AsError(err)
+	if e == nil {
+		return err
+	}
+	causes := e.Causes
+	if len(causes) == 0 {
+		causes = []*errors.Error{e}
+	}
+	out := make([]error, 0, len(causes))
+	for _, c := range causes {
+		field, _ := c.Fields["field"].(string)
+		if field == "" {
+			field = prefix
+		} else {
+			field = prefix + "." + field
+		}
+		ne := errors.New(c.Code, c.Message).WithFields(c.Fields)
+		out = append(out, ne.WithField("field", field))
 	}
+	return errors.Aggregate(out...)
+}
+
+// batchJobResult is the outcome of a job of POST /jobs/batch.
+type batchJobResult struct {
+	Index        int         `json:"index"`
+	Job          jobResponse `json:"job"`
+	Deduplicated bool        `json:"deduplicated,omitempty"`
+}
+
+// PostJobBatch creates up to MaxBatchJobs jobs, all or none: every job is
+// validated first (a failure answers 400 with the errors of every job,
+// fields under jobs[i]), then they are inserted in one transaction and
+// pushed to the queue together. The results follow the order of the
+// request.
+func (h *Handler) PostJobBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-	if err := h.rdb.LPush(ctx, "gala:jobs", jobID).Err(); err != nil {
-		httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "queue push failed", nil)
+	var req CreateJobBatchRequest
+	if !httpkit.DecodeAndValidate(w, r, &req) {
 		return
 	}
 
-	respJob := map[string]any{
-		"id":         jobID,
-		"name":       req.Name,
-		"status":     "QUEUED",
-		"params":     req.Params,
-		"created_at": createdAt,
+	items := make([]jobs.BatchItem, len(req.Jobs))
+	for i := range req.Jobs {
+		items[i] = jobs.BatchItem{Name: req.Jobs[i].Name, Spec: req.Jobs[i].spec()}
 	}
-	if req.TemplateID != "" {
-		respJob["template_id"] = req.TemplateID
-		if len(req.Inputs) > 0 {
-			respJob["inputs"] = req.Inputs
+	results, err := h.jobs.CreateBatch(ctx, items)
+	switch {
+	case errors.Is(err, jobs.ErrBatchInvalid):
+		var errs []error
+		for i, res := range results {
+			if res.Err != nil {
+				errs = append(errs, batchItemErr(i, res.Err))
+			}
 		}
+		httpkit.WriteError(w, r, errors.Aggregate(errs...))
+		return
+	case errors.Is(err, jobs.ErrQueuePush):
+		h.writeQueueErr(w, r, err, "jobs.batch")
+		return
+	case err != nil:
+		h.writeDBErr(w, r, err, "jobs.batch", "db insert failed")
+		return
 	}
 
-	httpkit.WriteJSON(w, 201, map[string]any{"job": respJob})
+	out := make([]batchJobResult, 0, len(results))
+	for i, res := range results {
+		resp := newJobResponse(res.Job)
+		if res.Reused {
+			if msg, err := h.fillOutputs(ctx, &resp); err != nil {
+				h.writeDBErr(w, r, err, "jobs.batch", msg)
+				return
+			}
+		} else if req.Jobs[i].CallbackURL != "" {
+			resp.Callback = &callbackResponse{URL: req.Jobs[i].CallbackURL}
+		}
+		out = append(out, batchJobResult{Index: i, Job: resp, Deduplicated: res.Reused})
+	}
+	httpkit.WriteJSON(w, 201, map[string]any{"results": out})
 }
 
 func (h *Handler) ListJobs(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	status := strings.TrimSpace(r.URL.Query().Get("status"))
-	limitStr := strings.TrimSpace(r.URL.Query().Get("limit"))
-	limit := 50
-	if limitStr != "" {
-		if v, err := strconv.Atoi(limitStr); err == nil && v > 0 && v <= 200 {
-			limit = v
+	page, after, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	withCounts := false
+	for _, inc := range strings.Split(r.URL.Query().Get("include"), ",") {
+		switch inc = strings.TrimSpace(inc); inc {
+		case "":
+		case "counts":
+			withCounts = true
+		default:
+			httpkit.WriteError(w, r, errors.ValidationField("include", "unknown include "+inc))
+			return
 		}
 	}
 
-	var (
-		rows pgxRows
-		err  error
-	)
-
-	if status != "" {
-		rows, err = h.pool.Query(ctx,
-			`SELECT id, COALESCE(name,''), status, created_at
-			 FROM jobs WHERE status=$1
-			 ORDER BY created_at DESC
-			 LIMIT $2`,
-			status, limit,
-		)
-	} else {
-		rows, err = h.pool.Query(ctx,
-			`SELECT id, COALESCE(name,''), status, created_at
-			 FROM jobs
-			 ORDER BY created_at DESC
-			 LIMIT $1`,
-			limit,
-		)
-	}
+	// Fetch one extra row to know whether there is a next page.
+	f := store.JobFilter{Status: status}
+	jobs, err := store.ListJobs(ctx, h.reader(), f, after, page.Limit+1)
 	if err != nil {
-		httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "db query failed", nil)
+		h.writeDBErr(w, r, err, "jobs.list", "db query failed")
 		return
 	}
-	defer rows.Close()
 
-	type item struct {
-		ID        string    `json:"id"`
-		Name      string    `json:"name,omitempty"`
-		Status    string    `json:"status"`
-		CreatedAt time.Time `json:"created_at"`
+	jobs, next := nextCursor(jobs, page.Limit, func(j store.Job) (time.Time, string) { return j.CreatedAt, j.ID })
+	list := httpkit.List{
+		Items:      jobs,
+		NextCursor: next,
+		Filters:    map[string]string{"status": status},
+		Legacy:     "jobs",
 	}
-
-	out := make([]item, 0, limit)
-	for rows.Next() {
-		var it item
-		if err := rows.Scan(&it.ID, &it.Name, &it.Status, &it.CreatedAt); err != nil {
-			httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "row scan failed", nil)
+	if withCounts {
+		// Per status regardless of the status filter, for the tabs; total
+		// is what the filter matches, across all pages
+		byStatus, err := store.CountJobsByStatus(ctx, h.reader(), f)
+		if err != nil {
+			h.writeDBErr(w, r, err, "jobs.list", "db count query failed")
 			return
 		}
-		out = append(out, it)
+		counts := make(map[string]int64, len(store.JobStatuses))
+		var total int64
+		for _, s := range store.JobStatuses {
+			counts[s] = byStatus[s]
+			if status == "" || status == s {
+				total += byStatus[s]
+			}
+		}
+		list.Total = &total
+		list.Extra = map[string]any{"counts": counts}
+	}
+	httpkit.WriteList(w, r, list)
+}
+
+type jobExportItem struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name,omitempty"`
+	Status     string          `json:"status"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	ErrorText  string          `json:"error_text,omitempty"`
+	Error      *store.JobError `json:"error_detail,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+// ExportJobs streams every job (optionally filtered by status and a
+// created_at range) as NDJSON, oldest first.
+func (h *Handler) ExportJobs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	var v httpkit.Validator
+	f := store.JobFilter{Status: strings.TrimSpace(q.Get("status"))}
+	for _, p := range []struct {
+		param string
+		dst   *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		raw := strings.TrimSpace(q.Get(p.param))
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		v.Check(err == nil, p.param, p.param+" must be an RFC 3339 timestamp")
+		*p.dst = t
+	}
+	if err := v.Err(); err != nil {
+		httpkit.WriteError(w, r, err)
+		return
 	}
 
-	httpkit.WriteJSON(w, 200, map[string]any{"jobs": out})
+	jobs, err := store.StreamJobs(ctx, h.reader(), f)
+	if err != nil {
+		h.writeDBErr(w, r, err, "jobs.export", "db query failed")
+		return
+	}
+
+	err = httpkit.StreamNDJSON(w, r, mapSeq(jobs, func(j store.Job) jobExportItem {
+		it := jobExportItem{
+			ID:         j.ID,
+			Name:       j.Name,
+			Status:     j.Status,
+			ErrorText:  j.ErrorText,
+			Error:      j.Error,
+			CreatedAt:  j.CreatedAt,
+			UpdatedAt:  j.UpdatedAt,
+			StartedAt:  j.StartedAt,
+			FinishedAt: j.FinishedAt,
+		}
+		if json.Valid([]byte(j.ParamsJSON)) {
+			it.Params = json.RawMessage(j.ParamsJSON)
+		}
+		return it
+	}))
+	if err != nil && h.log != nil {
+		h.log.FromContext(ctx).Warn("jobs export aborted", "error", err.Error())
+	}
 }
 
 func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	jobID := chi.URLParam(r, "jobId")
 
-	var (
-		id, name, status, paramsJSON string
-		errorText                    *string
-		createdAt                    time.Time
-		startedAt, finishedAt        *time.Time
-	)
-
-	err := h.pool.QueryRow(ctx,
-		`SELECT id, COALESCE(name,''), status, params_json, error_text, created_at, started_at, finished_at
-		 FROM jobs WHERE id=$1`,
-		jobID,
-	).Scan(&id, &name, &status, &paramsJSON, &errorText, &createdAt, &startedAt, &finishedAt)
+	j, err := store.GetJob(ctx, h.pool, jobID)
 	if err != nil {
-		httpkit.WriteErr(w, 404, "JOB_NOT_FOUND", "job not found", map[string]any{"job_id": jobID})
+		if pgerr.IsNoRows(err) {
+			httpkit.WriteErr(w, r, 404, "JOB_NOT_FOUND", "job not found", map[string]any{"job_id": jobID})
+			return
+		}
+		h.writeDBErr(w, r, err, "jobs.get", "db query failed")
+		return
+	}
+
+	job := newJobResponse(j)
+	if msg, err := h.fillOutputs(ctx, &job); err != nil {
+		h.writeDBErr(w, r, err, "jobs.get", msg)
+		return
+	}
+
+	cb, err := store.GetJobCallback(ctx, h.pool, jobID)
+	switch {
+	case err == nil:
+		job.Callback = &callbackResponse{
+			URL:         cb.URL,
+			Attempts:    cb.Attempts,
+			DeliveredAt: cb.DeliveredAt,
+			LastError:   cb.LastError,
+		}
+	case !pgerr.IsNoRows(err) && !pgerr.IsUndefinedTable(err):
+		h.writeDBErr(w, r, err, "jobs.get", "db callback query failed")
 		return
 	}
 
-	var raw map[string]any
-	_ = json.Unmarshal([]byte(paramsJSON), &raw)
+	httpkit.WriteJSONWithETag(w, r, 200, map[string]any{"job": job})
+}
 
-	templateID := ""
-	params := map[string]any{}
-	inputs := map[string]string{}
+// ListCallbackDeliveries returns the delivery log of a job's callback,
+// one entry per attempt; a job without callback has none.
+func (h *Handler) ListCallbackDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	jobID := chi.URLParam(r, "jobId")
 
-	if v, ok := raw["template_id"].(string); ok && strings.TrimSpace(v) != "" {
-		templateID = strings.TrimSpace(v)
-		if m, ok := raw["params"].(map[string]any); ok && m != nil {
-			params = m
+	if _, err := store.GetJob(ctx, h.pool, jobID); err != nil {
+		if pgerr.IsNoRows(err) {
+			httpkit.WriteErr(w, r, 404, "JOB_NOT_FOUND", "job not found", map[string]any{"job_id": jobID})
+			return
 		}
-		if im, ok := raw["inputs"].(map[string]any); ok && im != nil {
-			for k, vv := range im {
-				if s, ok := vv.(string); ok && strings.TrimSpace(s) != "" {
-					inputs[k] = strings.TrimSpace(s)
-				}
+		h.writeDBErr(w, r, err, "jobs.callback_deliveries", "db query failed")
+		return
+	}
+	deliveries, err := store.ListCallbackDeliveries(ctx, h.pool, jobID)
+	if err != nil && !pgerr.IsUndefinedTable(err) {
+		h.writeDBErr(w, r, err, "jobs.callback_deliveries", "db query failed")
+		return
+	}
+	if deliveries == nil {
+		deliveries = []store.CallbackDelivery{}
+	}
+	httpkit.WriteJSON(w, 200, map[string]any{"deliveries": deliveries})
+}
+
+// JobProgressRequest is the progress the renderer reports for the render
+// of a job, 0-100 of the render itself.
+type JobProgressRequest struct {
+	Progress *int `json:"progress"`
+}
+
+func (req *JobProgressRequest) Validate() error {
+	var v httpkit.Validator
+	v.Check(req.Progress != nil && *req.Progress >= 0 && *req.Progress <= 100, "progress", "progress must be between 0 and 100")
+	return v.Err()
+}
+
+// maxProgressBody bounds the body of POST /jobs/{jobId}/progress, which is
+// read whole to verify its signature.
+const maxProgressBody = 1 << 10
+
+// PostJobProgress records the progress the renderer reports while it
+// renders a job, at the progress_url of the render spec. The job's
+// progress goes from its render stage to the next one (see
+// store.RenderProgress) and never back. With RENDERER_AUTH_MODE set the
+// report must be signed like the worker's requests to the renderer.
+func (h *Handler) PostJobProgress(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	jobID := chi.URLParam(r, "jobId")
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxProgressBody))
+	if err != nil {
+		httpkit.WriteErr(w, r, 400, string(errors.CodeValidation), "invalid json body", nil)
+		return
+	}
+	if h.rendererAuth != nil {
+		secret, err := h.rendererAuth.Current()
+		if err != nil {
+			if h.log != nil {
+				h.log.FromContext(ctx).Error("renderer auth secret unavailable", "error", err.Error())
 			}
+			httpkit.WriteErr(w, r, 503, string(errors.CodeUnavailable), "renderer auth unavailable", nil)
+			return
 		}
-	} else {
-		params = raw
-	}
-
-	type outItem struct {
-		Variant           int    `json:"variant"`
-		VideoAssetID      string `json:"video_asset_id"`
-		ThumbnailAssetID  string `json:"thumbnail_asset_id,omitempty"`
-		CaptionsAssetID   string `json:"captions_asset_id,omitempty"`
-		VideoObjectKey    string `json:"video_object_key,omitempty"`
-		ThumbObjectKey    string `json:"thumb_object_key,omitempty"`
-		CaptionsObjectKey string `json:"captions_object_key,omitempty"`
-	}
-
-	outs := []outItem{}
-	rows, err := h.pool.Query(ctx,
-		`SELECT variant, video_asset_id, COALESCE(thumbnail_asset_id,''), COALESCE(captions_asset_id,'')
-		 FROM job_outputs WHERE job_id=$1 ORDER BY variant ASC`,
-		jobID,
-	)
-	if err != nil {
-		if !httpkit.IsUndefinedTable(err) {
-			httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "db outputs query failed", nil)
+		if !renderer.Verify(r, body, renderer.MaxSkew, secret) {
+			httpkit.WriteErr(w, r, 401, string(errors.CodeUnauthorized), "authentication required", nil)
 			return
 		}
-	} else {
-		defer rows.Close()
-		for rows.Next() {
-			var it outItem
-			var thumbID, capID string
-			if err := rows.Scan(&it.Variant, &it.VideoAssetID, &thumbID, &capID); err != nil {
-				httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "outputs scan failed", nil)
-				return
-			}
-			if thumbID != "" {
-				it.ThumbnailAssetID = thumbID
-			}
-			if capID != "" {
-				it.CaptionsAssetID = capID
-			}
-
-			it.VideoObjectKey = lookupObjectKey(ctx, h.pool, it.VideoAssetID)
-			if it.ThumbnailAssetID != "" {
-				it.ThumbObjectKey = lookupObjectKey(ctx, h.pool, it.ThumbnailAssetID)
-			}
-			if it.CaptionsAssetID != "" {
-				it.CaptionsObjectKey = lookupObjectKey(ctx, h.pool, it.CaptionsAssetID)
-			}
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	var req JobProgressRequest
+	if !httpkit.DecodeAndValidate(w, r, &req) {
+		return
+	}
 
-			outs = append(outs, it)
+	progress, err := store.SetRenderProgress(ctx, h.pool, jobID, *req.Progress)
+	if pgerr.IsNoRows(err) {
+		j, err := store.GetJob(ctx, h.pool, jobID)
+		switch {
+		case pgerr.IsNoRows(err):
+			httpkit.WriteErr(w, r, 404, "JOB_NOT_FOUND", "job not found", map[string]any{"job_id": jobID})
+		case err != nil:
+			h.writeDBErr(w, r, err, "jobs.progress", "db query failed")
+		default:
+			// Ya no renderiza: terminó, se canceló o pasó a otra etapa
+			httpkit.WriteErr(w, r, 409, "JOB_INVALID_STATE", "job status does not allow this action",
+				map[string]any{"job_id": jobID, "status": j.Status, "stage": j.Stage})
 		}
+		return
 	}
+	if err != nil {
+		h.writeDBErr(w, r, err, "jobs.progress", "job update failed")
+		return
+	}
+	h.ev.Publish(ctx, events.JobProgress, jobID, map[string]any{
+		"status":   store.JobRunning,
+		"stage":    store.JobStageRender,
+		"progress": progress,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	job := map[string]any{
-		"id":          id,
-		"name":        name,
-		"status":      status,
-		"params":      params,
-		"created_at":  createdAt,
-		"started_at":  startedAt,
-		"finished_at": finishedAt,
-		"outputs":     outs,
+// fillOutputs adds the outputs of job, with their public URLs and HLS
+// paths. On failure it returns the message for the failed query.
+func (h *Handler) fillOutputs(ctx context.Context, job *jobResponse) (string, error) {
+	outputs, err := store.ListJobOutputs(ctx, h.pool, job.ID)
+	if err != nil && !pgerr.IsUndefinedTable(err) {
+		return "db outputs query failed", err
 	}
-	if errorText != nil && strings.TrimSpace(*errorText) != "" {
-		job["error"] = strings.TrimSpace(*errorText)
+	publicURLs := map[string]string{}
+	hls := map[string]bool{}
+	if len(outputs) > 0 {
+		pubs, err := store.ListJobPublications(ctx, h.pool, job.ID)
+		if err != nil && !pgerr.IsUndefinedTable(err) {
+			return "db publications query failed", err
+		}
+		for _, p := range pubs {
+			if p.Target == "s3" && p.Status == store.PublicationDone && publicURLs[p.OutputID] == "" {
+				publicURLs[p.OutputID] = p.ExternalURL
+			}
+		}
+
+		videos := make([]string, 0, len(outputs))
+		for _, o := range outputs {
+			videos = append(videos, o.VideoAssetID)
+		}
+		hls, err = store.VideosWithHLS(ctx, h.pool, videos)
+		if err != nil && !pgerr.IsUndefinedTable(err) {
+			return "db hls query failed", err
+		}
 	}
-	if templateID != "" {
-		job["template_id"] = templateID
-		if len(inputs) > 0 {
-			job["inputs"] = inputs
+	for _, o := range outputs {
+		o.PublicURL = publicURLs[o.ID]
+		if hls[o.VideoAssetID] {
+			o.HLSPath = httpkit.VersionedPath(ctx, "/assets/"+o.VideoAssetID+"/hls/"+store.HLSPlaylist)
 		}
+		job.Outputs = append(job.Outputs, o)
 	}
+	return "", nil
+}
 
-	httpkit.WriteJSON(w, 200, map[string]any{"job": job})
+// jobResponse is a job with its spec, outputs and callback, the body of
+// POST /jobs, GET /jobs/{jobId} and POST /render.
+type jobResponse struct {
+	store.Job
+	TemplateID string            `json:"template_id,omitempty"`
+	Inputs     map[string]string `json:"inputs,omitempty"`
+	Params     map[string]any    `json:"params"`
+	Watermark  *watermark.Config `json:"watermark,omitempty"`
+	Outputs    []store.JobOutput `json:"outputs"`
+	Callback   *callbackResponse `json:"callback,omitempty"`
 }
 
-func lookupObjectKey(ctx context.Context, pool *pgxpool.Pool, assetID string) string {
-	if assetID == "" {
-		return ""
-	}
-	var objectKey string
-	_ = pool.QueryRow(ctx, `SELECT object_key FROM assets WHERE id=$1`, assetID).Scan(&objectKey)
-	return objectKey
+// callbackResponse is a job's callback; the secret is write-only.
+type callbackResponse struct {
+	URL         string     `json:"url"`
+	Attempts    int        `json:"attempts"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
 }
 
-type pgxRows interface {
-	Close()
-	Next() bool
-	Scan(dest ...any) error
+// newJobResponse renders j with the parts of its stored spec and no
+// outputs or callback.
+func newJobResponse(j store.Job) jobResponse {
+	spec := jobs.ParseSpec(j.ParamsJSON)
+	resp := jobResponse{Job: j, Params: spec.Params, Outputs: []store.JobOutput{}}
+	if spec.TemplateID != "" {
+		resp.TemplateID = spec.TemplateID
+		if len(spec.Inputs) > 0 {
+			resp.Inputs = spec.Inputs
+		}
+		resp.Watermark = spec.Watermark
+	}
+	return resp
 }
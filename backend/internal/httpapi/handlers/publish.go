@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"gala/internal/httpkit"
+	"gala/internal/publish"
+	"gala/internal/store"
+)
+
+type PublishJobRequest struct {
+	Target string `json:"target"`
+	// Variant selects the output to publish; 0 or absent is the first.
+	Variant     int      `json:"variant,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Privacy     string   `json:"privacy,omitempty"`
+}
+
+func (req *PublishJobRequest) Validate() error {
+	req.Target = strings.ToLower(strings.TrimSpace(req.Target))
+	req.Title = strings.TrimSpace(req.Title)
+	req.Privacy = strings.ToLower(strings.TrimSpace(req.Privacy))
+
+	var v httpkit.Validator
+	v.Required("target", req.Target)
+	v.Check(req.Variant >= 0, "variant", "variant must not be negative")
+	v.Check(utf8.RuneCountInString(req.Title) <= publish.MaxTitle, "title", "title is too long")
+	v.Check(utf8.RuneCountInString(req.Description) <= publish.MaxDescription, "description", "description is too long")
+	v.Check(req.Privacy == "" || slices.Contains(publish.Privacies, req.Privacy), "privacy", "privacy must be private, unlisted or public")
+	return v.Err()
+}
+
+type publicationResponse struct {
+	ID          string          `json:"id"`
+	JobID       string          `json:"job_id"`
+	OutputID    string          `json:"output_id"`
+	Target      string          `json:"target"`
+	Status      string          `json:"status"`
+	Metadata    json.RawMessage `json:"metadata,omitempty"`
+	ExternalID  string          `json:"external_id,omitempty"`
+	URL         string          `json:"url,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	PublishedAt *time.Time      `json:"published_at,omitempty"`
+}
+
+func publicationJSON(p store.Publication) publicationResponse {
+	out := publicationResponse{
+		ID:          p.ID,
+		JobID:       p.JobID,
+		OutputID:    p.OutputID,
+		Target:      p.Target,
+		Status:      p.Status,
+		ExternalID:  p.ExternalID,
+		URL:         p.ExternalURL,
+		Error:       strings.TrimSpace(p.ErrorText),
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+		PublishedAt: p.PublishedAt,
+	}
+	if json.Valid(p.Metadata) {
+		out.Metadata = p.Metadata
+	}
+	return out
+}
+
+// PublishJob publishes an output of a DONE job to an external target. The
+// upload runs in the background: the response is the PENDING publication,
+// followed with ListJobPublications or the publication.* events.
+func (h *Handler) PublishJob(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobId")
+
+	var req PublishJobRequest
+	if !httpkit.DecodeAndValidate(w, r, &req) {
+		return
+	}
+
+	p, err := h.publish.Publish(r.Context(), jobID, publish.Request{
+		Target:      req.Target,
+		Variant:     req.Variant,
+		Title:       req.Title,
+		Description: req.Description,
+		Tags:        req.Tags,
+		Privacy:     req.Privacy,
+	})
+	switch {
+	case err == nil:
+		httpkit.WriteJSON(w, 202, map[string]any{"publication": publicationJSON(p)})
+	case errors.Is(err, publish.ErrTargetNotConfigured):
+		httpkit.WriteErr(w, r, 501, string(CodePublishTargetNotConfigured), "publish target is not configured",
+			map[string]any{"target": req.Target, "configured": h.publish.Targets()})
+	case errors.Is(err, publish.ErrJobNotFound):
+		httpkit.WriteErr(w, r, 404, "JOB_NOT_FOUND", "job not found", map[string]any{"job_id": jobID})
+	case errors.Is(err, publish.ErrJobState):
+		details := map[string]any{"job_id": jobID}
+		var se *publish.JobStateError
+		if errors.As(err, &se) {
+			details["status"] = se.Status
+		}
+		httpkit.WriteErr(w, r, 409, "JOB_INVALID_STATE", "job status does not allow this action", details)
+	case errors.Is(err, publish.ErrOutputNotFound):
+		httpkit.WriteErr(w, r, 404, string(CodeOutputNotFound), "job output not found",
+			map[string]any{"job_id": jobID, "variant": req.Variant})
+	case errors.Is(err, publish.ErrPublished):
+		httpkit.WriteErr(w, r, 409, string(CodePublicationExists), "output is already published to this target",
+			map[string]any{"job_id": jobID, "target": req.Target})
+	default:
+		h.writeDBErr(w, r, err, "jobs.publish", "publication failed")
+	}
+}
+
+// ListJobPublications lists the publications of a job, newest first.
+func (h *Handler) ListJobPublications(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobId")
+
+	pubs, err := h.publish.List(r.Context(), jobID)
+	switch {
+	case errors.Is(err, publish.ErrJobNotFound):
+		httpkit.WriteErr(w, r, 404, "JOB_NOT_FOUND", "job not found", map[string]any{"job_id": jobID})
+		return
+	case err != nil:
+		h.writeDBErr(w, r, err, "jobs.publications", "db query failed")
+		return
+	}
+
+	out := make([]publicationResponse, 0, len(pubs))
+	for _, p := range pubs {
+		out = append(out, publicationJSON(p))
+	}
+	httpkit.WriteJSON(w, 200, map[string]any{"publications": out})
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strings"
+
+	"gala/internal/httpkit"
+	"gala/internal/inputs"
+	"gala/internal/jobs"
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/fetch"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/store"
+)
+
+// MaxQuickRenderInputs bounds the URLs a quick render downloads while the
+// request waits.
+const MaxQuickRenderInputs = 10
+
+// QuickRenderRequest is the flat job request of POST /quick-render, for
+// no-code tools that can only send strings: the template goes by name and
+// inputs are URLs instead of asset ids.
+type QuickRenderRequest struct {
+	Template string            `json:"template"`
+	Name     string            `json:"name,omitempty"`
+	Params   map[string]string `json:"params,omitempty"`
+	Inputs   map[string]string `json:"inputs,omitempty"`
+}
+
+func (req *QuickRenderRequest) Validate() error {
+	req.Template = strings.TrimSpace(req.Template)
+	req.Name = strings.TrimSpace(req.Name)
+
+	var v httpkit.Validator
+	v.Required("template", req.Template)
+	v.Check(len(req.Inputs) <= MaxQuickRenderInputs, "inputs", "too many inputs")
+	for name, raw := range req.Inputs {
+		_, err := fetch.ParseURL(raw)
+		v.Check(err == nil, "inputs."+name, "input must be an https url")
+		req.Inputs[name] = strings.TrimSpace(raw)
+	}
+	return v.Err()
+}
+
+// QuickRender creates a job from a template name, string params and input
+// URLs. The inputs are downloaded into assets before the job is queued, so
+// the request can take as long as the downloads (HTTP_UPLOAD_TIMEOUT); if
+// one fails, the assets already imported are left to the asset GC.
+func (h *Handler) QuickRender(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req QuickRenderRequest
+	if !httpkit.DecodeAndValidate(w, r, &req) {
+		return
+	}
+
+	t, err := store.GetTemplateByName(ctx, h.pool, req.Template)
+	if err != nil {
+		if pgerr.IsNoRows(err) {
+			httpkit.WriteErr(w, r, 404, "TEMPLATE_NOT_FOUND", "template not found", map[string]any{"template": req.Template})
+			return
+		}
+		h.writeDBErr(w, r, err, "quick_render.template", "db query failed")
+		return
+	}
+
+	assetIDs := make(map[string]string, len(req.Inputs))
+	for name, u := range req.Inputs {
+		a, err := h.inputs.Import(ctx, u)
+		if err != nil {
+			var fe *inputs.FetchError
+			if stderrors.As(err, &fe) {
+				httpkit.WriteErr(w, r, 422, string(CodeInputFetchFailed), "input could not be downloaded",
+					map[string]any{"input": name, "url": u, "reason": fe.Err.Error()})
+				return
+			}
+			h.writeDBErr(w, r, err, "quick_render.input", "input import failed")
+			return
+		}
+		assetIDs[name] = a.ID
+	}
+
+	params := make(map[string]any, len(req.Params))
+	for k, v := range req.Params {
+		params[k] = v
+	}
+	job, _, err := h.jobs.Create(ctx, req.Name, jobs.Spec{TemplateID: t.ID, Inputs: assetIDs, Params: params})
+	switch {
+	case errors.Is(err, jobs.ErrTemplateNotFound):
+		// Deleted since the lookup
+		httpkit.WriteErr(w, r, 404, "TEMPLATE_NOT_FOUND", "template not found", map[string]any{"template": req.Template})
+		return
+	case errors.Is(err, jobs.ErrQueuePush):
+		h.writeQueueErr(w, r, err, "quick_render.create")
+		return
+	case errors.IsValidation(err):
+		httpkit.WriteError(w, r, err)
+		return
+	case err != nil:
+		h.writeDBErr(w, r, err, "quick_render.create", "db insert failed")
+		return
+	}
+
+	httpkit.WriteJSON(w, 201, map[string]any{
+		"job":        newJobResponse(job),
+		"status_url": httpkit.VersionedPath(ctx, "/jobs/"+job.ID),
+	})
+}
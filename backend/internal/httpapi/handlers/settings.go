@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"gala/internal/httpkit"
+	"gala/internal/pkg/fetch"
+	"gala/internal/settings"
+	"gala/internal/store"
+)
+
+// PutSettingsRequest replaces the workspace settings; what it leaves out
+// has no default. callback.secret is write-only, so it must be sent again
+// on every PUT to keep the callback signed.
+type PutSettingsRequest struct {
+	settings.Settings
+}
+
+func (req *PutSettingsRequest) Validate() error {
+	var v httpkit.Validator
+	if f := req.Format; f != nil {
+		v.Check(f.Width > 0, "format.width", "format.width must be positive")
+		v.Check(f.Height > 0, "format.height", "format.height must be positive")
+		v.Check(f.FPS > 0, "format.fps", "format.fps must be positive")
+	}
+	if c := req.Callback; c != nil {
+		c.URL = strings.TrimSpace(c.URL)
+		c.Signed = false
+		_, err := fetch.ParseURL(c.URL)
+		v.Check(err == nil, "callback.url", "callback.url must be an https url")
+		v.Check(len(c.Secret) <= MaxCallbackSecret, "callback.secret", "callback.secret is too long")
+	}
+	req.Priority = strings.TrimSpace(req.Priority)
+	v.Check(req.Priority == "" || slices.Contains(store.JobPriorities, req.Priority), "priority", "priority must be normal or high")
+	return v.Err()
+}
+
+func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	ws, err := settings.Load(r.Context(), h.pool)
+	if err != nil {
+		h.writeDBErr(w, r, err, "settings.get", "db query failed")
+		return
+	}
+	httpkit.WriteJSON(w, 200, map[string]any{"settings": ws.Redacted()})
+}
+
+func (h *Handler) PutSettings(w http.ResponseWriter, r *http.Request) {
+	var req PutSettingsRequest
+	if !httpkit.DecodeAndValidate(w, r, &req) {
+		return
+	}
+	ws, err := settings.Save(r.Context(), h.pool, req.Settings)
+	if err != nil {
+		h.writeDBErr(w, r, err, "settings.put", "db update failed")
+		return
+	}
+	httpkit.WriteJSON(w, 200, map[string]any{"settings": ws.Redacted()})
+}
+
+// DeleteSettings drops every workspace default.
+func (h *Handler) DeleteSettings(w http.ResponseWriter, r *http.Request) {
+	if err := settings.Reset(r.Context(), h.pool); err != nil {
+		h.writeDBErr(w, r, err, "settings.delete", "db delete failed")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
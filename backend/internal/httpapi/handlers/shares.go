@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"gala/internal/httpapi/util"
+	"gala/internal/httpkit"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/storage"
+	"gala/internal/store"
+)
+
+// MaxShareExpiry bounds expires_in; shares without it never expire.
+const MaxShareExpiry = 365 * 24 * time.Hour
+
+// shareTokenBytes is the entropy of a share token (hex encoded, so tokens
+// are twice as long).
+const shareTokenBytes = 16
+
+type CreateShareRequest struct {
+	// Variant selects the output to share; 0 or absent is the first.
+	Variant int `json:"variant,omitempty"`
+	// ExpiresIn is the lifetime of the link in seconds; 0 never expires.
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+}
+
+func (req *CreateShareRequest) Validate() error {
+	var v httpkit.Validator
+	v.Check(req.Variant >= 0, "variant", "variant must not be negative")
+	v.Check(req.ExpiresIn >= 0 && req.ExpiresIn <= int64(MaxShareExpiry/time.Second), "expires_in", "expires_in is out of range")
+	return v.Err()
+}
+
+type shareResponse struct {
+	Token     string     `json:"token"`
+	JobID     string     `json:"job_id"`
+	OutputID  string     `json:"output_id"`
+	Variant   int        `json:"variant"`
+	EmbedURL  string     `json:"embed_url"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func shareJSON(r *http.Request, s store.Share) shareResponse {
+	return shareResponse{
+		Token:     s.Token,
+		JobID:     s.JobID,
+		OutputID:  s.OutputID,
+		Variant:   s.Variant,
+		EmbedURL:  embedURL(r, s.Token, ""),
+		CreatedAt: s.CreatedAt,
+		ExpiresAt: s.ExpiresAt,
+		RevokedAt: s.RevokedAt,
+	}
+}
+
+// embedURL is the absolute URL of a share's embed route (suffix "" is the
+// player page). EMBED_BASE_URL sets the public origin when the API sits
+// behind a proxy; otherwise the request's host is used.
+func embedURL(r *http.Request, token, suffix string) string {
+	base := strings.TrimRight(util.Env("EMBED_BASE_URL", ""), "/")
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + "/embed/" + token + suffix
+}
+
+// PostShare creates a share link of an output of a DONE job. The link
+// works without API credentials until it expires or is revoked.
+func (h *Handler) PostShare(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	jobID := chi.URLParam(r, "jobId")
+
+	var req CreateShareRequest
+	if !httpkit.DecodeAndValidate(w, r, &req) {
+		return
+	}
+
+	job, err := store.GetJob(ctx, h.pool, jobID)
+	if err != nil {
+		if pgerr.IsNoRows(err) {
+			httpkit.WriteErr(w, r, 404, "JOB_NOT_FOUND", "job not found", map[string]any{"job_id": jobID})
+			return
+		}
+		h.writeDBErr(w, r, err, "shares.create", "db query failed")
+		return
+	}
+	if job.Status != store.JobDone {
+		httpkit.WriteErr(w, r, 409, "JOB_INVALID_STATE", "job status does not allow this action",
+			map[string]any{"job_id": jobID, "status": job.Status})
+		return
+	}
+
+	outs, err := store.ListJobOutputs(ctx, h.pool, jobID)
+	if err != nil {
+		h.writeDBErr(w, r, err, "shares.create", "db query failed")
+		return
+	}
+	var out *store.JobOutput
+	for i := range outs {
+		if req.Variant == 0 || outs[i].Variant == req.Variant {
+			out = &outs[i]
+			break
+		}
+	}
+	if out == nil {
+		httpkit.WriteErr(w, r, 404, string(CodeOutputNotFound), "job output not found",
+			map[string]any{"job_id": jobID, "variant": req.Variant})
+		return
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		httpkit.WriteErr(w, r, 500, "INTERNAL_ERROR", "share token generation failed", nil)
+		return
+	}
+	s := store.Share{
+		Token:            token,
+		JobID:            jobID,
+		OutputID:         out.ID,
+		Variant:          out.Variant,
+		VideoAssetID:     out.VideoAssetID,
+		ThumbnailAssetID: out.ThumbnailAssetID,
+		CreatedAt:        time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if req.ExpiresIn > 0 {
+		exp := s.CreatedAt.Add(time.Duration(req.ExpiresIn) * time.Second)
+		s.ExpiresAt = &exp
+	}
+	if err := store.InsertShare(ctx, h.pool, s); err != nil {
+		h.writeDBErr(w, r, err, "shares.create", "db insert failed")
+		return
+	}
+	httpkit.WriteJSON(w, 201, map[string]any{"share": shareJSON(r, s)})
+}
+
+// ListJobShares lists the share links of a job, revoked and expired ones
+// included, newest first.
+func (h *Handler) ListJobShares(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	jobID := chi.URLParam(r, "jobId")
+
+	if _, err := store.GetJob(ctx, h.pool, jobID); err != nil {
+		if pgerr.IsNoRows(err) {
+			httpkit.WriteErr(w, r, 404, "JOB_NOT_FOUND", "job not found", map[string]any{"job_id": jobID})
+			return
+		}
+		h.writeDBErr(w, r, err, "shares.list", "db query failed")
+		return
+	}
+	shares, err := store.ListJobShares(ctx, h.pool, jobID)
+	if err != nil {
+		h.writeDBErr(w, r, err, "shares.list", "db query failed")
+		return
+	}
+
+	out := make([]shareResponse, 0, len(shares))
+	for _, s := range shares {
+		out = append(out, shareJSON(r, s))
+	}
+	httpkit.WriteJSON(w, 200, map[string]any{"shares": out})
+}
+
+// RevokeShare disables a share link; its embed routes answer 404 from
+// then on.
+func (h *Handler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	if !validShareToken(token) {
+		writeShareNotFound(w, r, token)
+		return
+	}
+
+	found, err := store.RevokeShare(r.Context(), h.pool, token)
+	if err != nil {
+		h.writeDBErr(w, r, err, "shares.revoke", "db update failed")
+		return
+	}
+	if !found {
+		writeShareNotFound(w, r, token)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ---- EMBED (public) ----
+
+var embedPage = template.Must(template.New("embed").Parse(`<!doctype html>
+<html lang="es">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>html,body{margin:0;height:100%;background:#000}video{display:block;width:100%;height:100%;object-fit:contain}</style>
+</head>
+<body>
+<video controls playsinline preload="metadata" src="{{.VideoURL}}"{{if .PosterURL}} poster="{{.PosterURL}}"{{end}}></video>
+</body>
+</html>
+`))
+
+type embedMetadata struct {
+	Title        string     `json:"title"`
+	JobID        string     `json:"job_id"`
+	Variant      int        `json:"variant"`
+	VideoURL     string     `json:"video_url"`
+	ThumbnailURL string     `json:"thumbnail_url,omitempty"`
+	EmbedURL     string     `json:"embed_url"`
+	HTML         string     `json:"html"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// EmbedPlayer serves the player page of a share link, meant for an iframe
+// on any site (see EMBED_FRAME_ANCESTORS).
+func (h *Handler) EmbedPlayer(w http.ResponseWriter, r *http.Request) {
+	meta, ok := h.embedMetadata(w, r)
+	if !ok {
+		return
+	}
+
+	// The page uses relative media URLs so it works behind any host
+	base := "/embed/" + chi.URLParam(r, "token")
+	poster := ""
+	if meta.ThumbnailURL != "" {
+		poster = base + "/thumbnail"
+	}
+	ancestors := util.Env("EMBED_FRAME_ANCESTORS", "*")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy",
+		"default-src 'none'; media-src 'self'; img-src 'self'; style-src 'unsafe-inline'; frame-ancestors "+ancestors)
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = embedPage.Execute(w, map[string]string{
+		"Title":     meta.Title,
+		"VideoURL":  base + "/video",
+		"PosterURL": poster,
+	})
+}
+
+// EmbedMetadata returns what a site needs to embed a share link: the
+// media URLs and a ready-made iframe.
+func (h *Handler) EmbedMetadata(w http.ResponseWriter, r *http.Request) {
+	meta, ok := h.embedMetadata(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	httpkit.WriteJSON(w, 200, meta)
+}
+
+// EmbedVideo streams the video of a share link.
+func (h *Handler) EmbedVideo(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.liveShare(w, r)
+	if !ok {
+		return
+	}
+	h.streamSharedAsset(w, r, s.VideoAssetID)
+}
+
+// EmbedThumbnail streams the thumbnail of a share link.
+func (h *Handler) EmbedThumbnail(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.liveShare(w, r)
+	if !ok {
+		return
+	}
+	if s.ThumbnailAssetID == "" {
+		httpkit.WriteErr(w, r, 404, "ASSET_NOT_FOUND", "asset not found", map[string]any{"output_id": s.OutputID})
+		return
+	}
+	h.streamSharedAsset(w, r, s.ThumbnailAssetID)
+}
+
+func (h *Handler) embedMetadata(w http.ResponseWriter, r *http.Request) (embedMetadata, bool) {
+	s, ok := h.liveShare(w, r)
+	if !ok {
+		return embedMetadata{}, false
+	}
+
+	title := "GALA " + s.JobID
+	job, err := store.GetJob(r.Context(), h.pool, s.JobID)
+	if err != nil && !pgerr.IsNoRows(err) {
+		h.writeDBErr(w, r, err, "embed.get", "db query failed")
+		return embedMetadata{}, false
+	}
+	if strings.TrimSpace(job.Name) != "" {
+		title = job.Name
+	}
+
+	page := embedURL(r, s.Token, "")
+	meta := embedMetadata{
+		Title:     title,
+		JobID:     s.JobID,
+		Variant:   s.Variant,
+		VideoURL:  embedURL(r, s.Token, "/video"),
+		EmbedURL:  page,
+		HTML:      `<iframe src="` + template.HTMLEscapeString(page) + `" width="640" height="360" frameborder="0" allow="fullscreen" allowfullscreen></iframe>`,
+		CreatedAt: s.CreatedAt,
+		ExpiresAt: s.ExpiresAt,
+	}
+	if s.ThumbnailAssetID != "" {
+		meta.ThumbnailURL = embedURL(r, s.Token, "/thumbnail")
+	}
+	return meta, true
+}
+
+// liveShare loads the share of the {token} route. Unknown, revoked and
+// expired tokens are all SHARE_NOT_FOUND, so a link gives nothing away
+// once it stops working.
+func (h *Handler) liveShare(w http.ResponseWriter, r *http.Request) (store.Share, bool) {
+	token := chi.URLParam(r, "token")
+	if !validShareToken(token) {
+		writeShareNotFound(w, r, token)
+		return store.Share{}, false
+	}
+
+	s, err := store.GetShare(r.Context(), h.pool, token)
+	if err != nil {
+		if pgerr.IsNoRows(err) || pgerr.IsUndefinedTable(err) {
+			writeShareNotFound(w, r, token)
+			return store.Share{}, false
+		}
+		h.writeDBErr(w, r, err, "embed.get", "db query failed")
+		return store.Share{}, false
+	}
+	if !s.Live(time.Now()) {
+		writeShareNotFound(w, r, token)
+		return store.Share{}, false
+	}
+	return s, true
+}
+
+func (h *Handler) streamSharedAsset(w http.ResponseWriter, r *http.Request, assetID string) {
+	ctx := r.Context()
+	a, err := store.GetAsset(ctx, h.pool, assetID)
+	if err != nil {
+		if pgerr.IsNoRows(err) {
+			httpkit.WriteErr(w, r, 404, "ASSET_NOT_FOUND", "asset not found", map[string]any{"asset_id": assetID})
+			return
+		}
+		h.writeDBErr(w, r, err, "embed.stream", "db query failed")
+		return
+	}
+
+	// The link can be revoked, so shared caches must not keep the file
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if h.offload(w, a.ObjectKey, a.Mime) {
+		return
+	}
+	rc, ct, _, err := h.sp.GetObject(ctx, a.ObjectKey)
+	if err != nil {
+		httpkit.WriteErr(w, r, 404, "ASSET_FILE_MISSING", "asset file missing", map[string]any{"asset_id": assetID})
+		return
+	}
+	defer rc.Close()
+
+	if ct == "" {
+		ct = a.Mime
+	}
+	w.Header().Set("Content-Type", ct)
+	if a.SizeBytes > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(a.SizeBytes, 10))
+	}
+	_, _ = storage.Copy(ctx, w, rc)
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validShareToken rejects tokens that cannot exist without a query.
+func validShareToken(token string) bool {
+	if len(token) != 2*shareTokenBytes {
+		return false
+	}
+	_, err := hex.DecodeString(token)
+	return err == nil
+}
+
+func writeShareNotFound(w http.ResponseWriter, r *http.Request, token string) {
+	httpkit.WriteErr(w, r, 404, string(CodeShareNotFound), "share not found", map[string]any{"token": token})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Asset stream modes (ASSET_STREAM_MODE). In accel and sendfile mode the
+// API answers asset downloads with just the headers and a reverse proxy in
+// front sends the file itself, so big videos don't go through Go. Both
+// only apply to localfs storage; other providers are always proxied.
+const (
+	// StreamProxy copies the file through the API (default).
+	StreamProxy = "proxy"
+	// StreamAccel answers with X-Accel-Redirect: an nginx internal location
+	// (StreamConfig.AccelPrefix) aliased to STORAGE_LOCAL_ROOT.
+	StreamAccel = "accel"
+	// StreamSendfile answers with X-Sendfile and the file's path, for
+	// Apache mod_xsendfile, lighttpd and Caddy.
+	StreamSendfile = "sendfile"
+)
+
+// DefaultAccelPrefix is the nginx internal location of StreamAccel.
+const DefaultAccelPrefix = "/_gala_assets/"
+
+// StreamConfig sets how asset files are served.
+type StreamConfig struct {
+	// Mode is StreamProxy, StreamAccel or StreamSendfile; empty is
+	// StreamProxy.
+	Mode string
+	// AccelPrefix is where StreamAccel points (empty = DefaultAccelPrefix).
+	AccelPrefix string
+}
+
+// localPather is the storage provider of files on the API's own disk.
+type localPather interface {
+	Path(objectKey string) string
+}
+
+// offload hands the file of objectKey to the reverse proxy, if the stream
+// mode and the provider allow it, and reports whether it did. The proxy
+// answers 404 itself if the file is missing; it keeps Content-Type and
+// Cache-Control from the headers set here.
+func (h *Handler) offload(w http.ResponseWriter, objectKey, contentType string) bool {
+	lp, ok := h.sp.(localPather)
+	if !ok {
+		return false
+	}
+	switch h.stream.Mode {
+	case StreamAccel:
+		prefix := h.stream.AccelPrefix
+		if prefix == "" {
+			prefix = DefaultAccelPrefix
+		}
+		segs := strings.Split(objectKey, "/")
+		for i, s := range segs {
+			segs[i] = url.PathEscape(s)
+		}
+		w.Header().Set("X-Accel-Redirect", path.Join(prefix, strings.Join(segs, "/")))
+	case StreamSendfile:
+		w.Header().Set("X-Sendfile", lp.Path(objectKey))
+	default:
+		return false
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	return true
+}
@@ -2,16 +2,24 @@ package handlers
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5"
 
+	"gala/internal/assetslots"
+	"gala/internal/events"
 	"gala/internal/httpapi/util"
 	"gala/internal/httpkit"
+	"gala/internal/paramschema"
+	"gala/internal/pkg/db"
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/settings"
+	"gala/internal/store"
+	"gala/internal/watermark"
 )
 
 type TemplateFormat struct {
@@ -27,6 +35,16 @@ type CreateTemplateRequest struct {
 	Format       *TemplateFormat `json:"format,omitempty"`
 	ParamsSchema map[string]any  `json:"params_schema,omitempty"`
 	Defaults     map[string]any  `json:"defaults,omitempty"`
+	// Watermark is the branding overlay of the template's renders.
+	Watermark *watermark.Config `json:"watermark,omitempty"`
+	// StrictParams rejects jobs whose params are not declared in
+	// ParamsSchema or do not match it.
+	StrictParams bool `json:"strict_params,omitempty"`
+	// MaxConcurrency caps the jobs of the template that render at once.
+	MaxConcurrency *int `json:"max_concurrency,omitempty"`
+	// Assets are named slots whose asset fills the job input of the same
+	// name when the job does not set it.
+	Assets assetslots.Slots `json:"assets,omitempty"`
 }
 
 type UpdateTemplateRequest struct {
@@ -36,82 +54,147 @@ type UpdateTemplateRequest struct {
 	Format       *TemplateFormat `json:"format,omitempty"`
 	ParamsSchema *map[string]any `json:"params_schema,omitempty"`
 	Defaults     *map[string]any `json:"defaults,omitempty"`
+	// Watermark replaces the template's; {"disabled": true} removes it.
+	Watermark    *watermark.Config `json:"watermark,omitempty"`
+	StrictParams *bool             `json:"strict_params,omitempty"`
+	// MaxConcurrency replaces the template's cap; 0 removes it.
+	MaxConcurrency *int `json:"max_concurrency,omitempty"`
+	// Assets replaces the template's slots; {} removes them.
+	Assets *assetslots.Slots `json:"assets,omitempty"`
+}
+
+func (req *CreateTemplateRequest) Validate() error {
+	req.Type = strings.TrimSpace(req.Type)
+	req.Name = strings.TrimSpace(req.Name)
+
+	var v httpkit.Validator
+	v.Required("type", req.Type)
+	v.Required("name", req.Name)
+	if req.Watermark != nil {
+		req.Watermark.Normalize()
+		v.Check(!req.Watermark.Disabled, "watermark.disabled", "watermark.disabled is not valid here")
+		req.Watermark.Check("watermark", true, v.Check)
+	}
+	if req.StrictParams {
+		schema, _ := json.Marshal(req.ParamsSchema)
+		defaults, _ := json.Marshal(req.Defaults)
+		paramschema.CheckTemplate(true, schema, defaults, v.Check)
+	}
+	if req.MaxConcurrency != nil {
+		v.Check(*req.MaxConcurrency >= 1, "max_concurrency", "max_concurrency must be at least 1")
+	}
+	req.Assets.Normalize()
+	req.Assets.Check("assets", v.Check)
+	return v.Err()
+}
+
+func (req *UpdateTemplateRequest) Validate() error {
+	var v httpkit.Validator
+	v.NotBlank("type", req.Type)
+	v.NotBlank("name", req.Name)
+	if req.Watermark != nil {
+		req.Watermark.Normalize()
+		req.Watermark.Check("watermark", true, v.Check)
+	}
+	if req.MaxConcurrency != nil {
+		v.Check(*req.MaxConcurrency >= 0, "max_concurrency", "max_concurrency must be 0 or more")
+	}
+	if req.Assets != nil {
+		req.Assets.Normalize()
+		req.Assets.Check("assets", v.Check)
+	}
+	return v.Err()
 }
 
 func (h *Handler) PostTemplate(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var req CreateTemplateRequest
-	if err := httpkit.DecodeJSON(r, &req); err != nil {
-		httpkit.WriteErr(w, 400, "VALIDATION_ERROR", "invalid json body", nil)
+	if !httpkit.DecodeAndValidate(w, r, &req) {
 		return
 	}
 
-	req.Type = strings.TrimSpace(req.Type)
-	req.Name = strings.TrimSpace(req.Name)
-
-	if req.Type == "" {
-		httpkit.WriteErr(w, 400, "VALIDATION_ERROR", "type is required", map[string]any{"field": "type"})
-		return
+	if req.Watermark != nil {
+		if err := watermark.CheckAsset(ctx, h.pool, "watermark.asset_id", req.Watermark.AssetID); err != nil {
+			h.writeCheckErr(w, r, err, "templates.create")
+			return
+		}
 	}
-	if req.Name == "" {
-		httpkit.WriteErr(w, 400, "VALIDATION_ERROR", "name is required", map[string]any{"field": "name"})
+	if err := assetslots.CheckAssets(ctx, h.pool, "assets", req.Assets); err != nil {
+		h.writeCheckErr(w, r, err, "templates.create")
 		return
 	}
 
-	// JSONB payloads
-	var (
-		formatJSON, paramsSchemaJSON, defaultsJSON any
-	)
+	// Without a format the template takes the workspace's default
+	if req.Format == nil {
+		ws, err := settings.Load(ctx, h.pool)
+		if err != nil {
+			h.writeDBErr(w, r, err, "templates.create", "db query failed")
+			return
+		}
+		req.Format = (*TemplateFormat)(ws.Format)
+	}
 
+	// JSONB payloads
+	var formatJSON, paramsSchemaJSON, defaultsJSON, watermarkJSON, assetsJSON []byte
 	if req.Format != nil {
-		b, _ := json.Marshal(req.Format)
-		formatJSON = b
-	} else {
-		formatJSON = nil
+		formatJSON, _ = json.Marshal(req.Format)
 	}
-
 	if req.ParamsSchema != nil {
-		b, _ := json.Marshal(req.ParamsSchema)
-		paramsSchemaJSON = b
-	} else {
-		paramsSchemaJSON = nil
+		paramsSchemaJSON, _ = json.Marshal(req.ParamsSchema)
 	}
-
 	if req.Defaults != nil {
-		b, _ := json.Marshal(req.Defaults)
-		defaultsJSON = b
-	} else {
-		defaultsJSON = nil
+		defaultsJSON, _ = json.Marshal(req.Defaults)
+	}
+	if req.Watermark != nil {
+		watermarkJSON, _ = json.Marshal(req.Watermark)
+	}
+	if len(req.Assets) > 0 {
+		assetsJSON, _ = json.Marshal(req.Assets)
 	}
 
 	id := util.NewID("tpl")
-	createdAt := time.Now().UTC()
-
-	_, err := h.pool.Exec(ctx, `
-		INSERT INTO templates (id, type, name, duration_ms, format, params_schema, defaults, created_at)
-		VALUES ($1,$2,$3,$4,$5::jsonb,$6::jsonb,$7::jsonb,$8)
-	`, id, req.Type, req.Name, req.DurationMs, formatJSON, paramsSchemaJSON, defaultsJSON, createdAt)
-
+	createdAt := now()
+
+	err := store.InsertTemplate(ctx, h.pool, store.Template{
+		ID:             id,
+		Type:           req.Type,
+		Name:           req.Name,
+		DurationMs:     req.DurationMs,
+		Format:         formatJSON,
+		ParamsSchema:   paramsSchemaJSON,
+		Defaults:       defaultsJSON,
+		Watermark:      watermarkJSON,
+		StrictParams:   req.StrictParams,
+		MaxConcurrency: req.MaxConcurrency,
+		Assets:         assetsJSON,
+		CreatedAt:      createdAt,
+	})
 	if err != nil {
-		if isUniqueViolation(err) {
-			httpkit.WriteErr(w, 409, "TEMPLATE_NAME_EXISTS", "template name already exists", map[string]any{"field": "name"})
+		if pgerr.IsUniqueViolation(err) {
+			httpkit.WriteErr(w, r, 409, "TEMPLATE_NAME_EXISTS", "template name already exists", map[string]any{"field": "name"})
 			return
 		}
-		httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "db insert failed", nil)
+		h.writeDBErr(w, r, err, "templates.create", "db insert failed")
 		return
 	}
+	h.ev.Publish(ctx, events.TemplateCreated, id, map[string]any{"name": req.Name})
 
 	resp := map[string]any{
 		"template": map[string]any{
-			"id":            id,
-			"type":          req.Type,
-			"name":          req.Name,
-			"duration_ms":   req.DurationMs,
-			"format":        req.Format,
-			"params_schema": req.ParamsSchema,
-			"defaults":      req.Defaults,
-			"created_at":    createdAt,
+			"id":              id,
+			"type":            req.Type,
+			"name":            req.Name,
+			"duration_ms":     req.DurationMs,
+			"format":          req.Format,
+			"params_schema":   req.ParamsSchema,
+			"defaults":        req.Defaults,
+			"watermark":       req.Watermark,
+			"strict_params":   req.StrictParams,
+			"max_concurrency": req.MaxConcurrency,
+			"assets":          req.Assets,
+			"created_at":      createdAt,
+			"updated_at":      createdAt,
 		},
 	}
 	httpkit.WriteJSON(w, 201, resp)
@@ -120,218 +203,243 @@ func (h *Handler) PostTemplate(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) ListTemplates(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	rows, err := h.pool.Query(ctx, `
-		SELECT id, type, name, duration_ms, format, params_schema, defaults, created_at
-		FROM templates
-		WHERE deleted_at IS NULL
-		ORDER BY created_at DESC
-	`)
-	if err != nil {
-		httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "db query failed", nil)
+	page, after, ok := parsePage(w, r)
+	if !ok {
 		return
 	}
-	defer rows.Close()
-
-	templates := []map[string]any{}
-
-	for rows.Next() {
-		var (
-			id, typ, name                           string
-			durationMs                              *int
-			formatBytes, paramsBytes, defaultsBytes []byte
-			createdAt                               time.Time
-		)
 
-		if err := rows.Scan(&id, &typ, &name, &durationMs, &formatBytes, &paramsBytes, &defaultsBytes, &createdAt); err != nil {
-			httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "row scan failed", nil)
-			return
-		}
+	// Fetch one extra row to know whether there is a next page.
+	rows, err := store.ListTemplates(ctx, h.pool, after, page.Limit+1)
+	if err != nil {
+		h.writeDBErr(w, r, err, "templates.list", "db query failed")
+		return
+	}
 
-		var format any
-		var params any
-		var defaults any
-		_ = json.Unmarshal(formatBytes, &format)
-		_ = json.Unmarshal(paramsBytes, &params)
-		_ = json.Unmarshal(defaultsBytes, &defaults)
-
-		templates = append(templates, map[string]any{
-			"id":            id,
-			"type":          typ,
-			"name":          name,
-			"duration_ms":   durationMs,
-			"format":        format,
-			"params_schema": params,
-			"defaults":      defaults,
-			"created_at":    createdAt,
-		})
-	}
-
-	httpkit.WriteJSON(w, 200, map[string]any{"templates": templates})
+	rows, next := nextCursor(rows, page.Limit, func(t store.Template) (time.Time, string) { return t.CreatedAt, t.ID })
+	templates := make([]map[string]any, 0, len(rows))
+	for _, t := range rows {
+		templates = append(templates, templateJSON(t))
+	}
+	httpkit.WriteList(w, r, httpkit.List{Items: templates, NextCursor: next, Legacy: "templates"})
 }
 
 func (h *Handler) GetTemplate(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	templateID := chi.URLParam(r, "templateId")
 
-	var (
-		id, typ, name                           string
-		durationMs                              *int
-		formatBytes, paramsBytes, defaultsBytes []byte
-		createdAt                               time.Time
-	)
-
-	err := h.pool.QueryRow(ctx, `
-		SELECT id, type, name, duration_ms, format, params_schema, defaults, created_at
-		FROM templates
-		WHERE id=$1 AND deleted_at IS NULL
-	`, templateID).Scan(&id, &typ, &name, &durationMs, &formatBytes, &paramsBytes, &defaultsBytes, &createdAt)
-
+	t, err := store.GetTemplate(ctx, h.pool, templateID)
 	if err != nil {
-		httpkit.WriteErr(w, 404, "TEMPLATE_NOT_FOUND", "template not found", map[string]any{"template_id": templateID})
+		if pgerr.IsNoRows(err) {
+			httpkit.WriteErr(w, r, 404, "TEMPLATE_NOT_FOUND", "template not found", map[string]any{"template_id": templateID})
+			return
+		}
+		h.writeDBErr(w, r, err, "templates.get", "db query failed")
 		return
 	}
 
-	var format any
-	var params any
-	var defaults any
-	_ = json.Unmarshal(formatBytes, &format)
-	_ = json.Unmarshal(paramsBytes, &params)
-	_ = json.Unmarshal(defaultsBytes, &defaults)
+	httpkit.WriteJSONWithETag(w, r, 200, map[string]any{"template": templateJSON(t)})
+}
 
-	httpkit.WriteJSON(w, 200, map[string]any{
-		"template": map[string]any{
-			"id":            id,
-			"type":          typ,
-			"name":          name,
-			"duration_ms":   durationMs,
-			"format":        format,
-			"params_schema": params,
-			"defaults":      defaults,
-			"created_at":    createdAt,
-		},
-	})
+// templateJSON renders a template row, decoding its JSONB columns.
+func templateJSON(t store.Template) map[string]any {
+	var format, params, defaults, wm, assets any
+	_ = json.Unmarshal(t.Format, &format)
+	_ = json.Unmarshal(t.ParamsSchema, &params)
+	_ = json.Unmarshal(t.Defaults, &defaults)
+	_ = json.Unmarshal(t.Watermark, &wm)
+	_ = json.Unmarshal(t.Assets, &assets)
+
+	return map[string]any{
+		"id":              t.ID,
+		"type":            t.Type,
+		"name":            t.Name,
+		"duration_ms":     t.DurationMs,
+		"format":          format,
+		"params_schema":   params,
+		"defaults":        defaults,
+		"watermark":       wm,
+		"strict_params":   t.StrictParams,
+		"max_concurrency": t.MaxConcurrency,
+		"assets":          assets,
+		"created_at":      t.CreatedAt,
+		"updated_at":      t.UpdatedAt,
+	}
 }
 
 func (h *Handler) PatchTemplate(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	templateID := chi.URLParam(r, "templateId")
 
-	// read existing first
-	var (
-		id, typ, name                           string
-		durationMs                              *int
-		formatBytes, paramsBytes, defaultsBytes []byte
-		createdAt                               time.Time
-	)
-
-	err := h.pool.QueryRow(ctx, `
-		SELECT id, type, name, duration_ms, format, params_schema, defaults, created_at
-		FROM templates
-		WHERE id=$1 AND deleted_at IS NULL
-	`, templateID).Scan(&id, &typ, &name, &durationMs, &formatBytes, &paramsBytes, &defaultsBytes, &createdAt)
-
-	if err != nil {
-		httpkit.WriteErr(w, 404, "TEMPLATE_NOT_FOUND", "template not found", map[string]any{"template_id": templateID})
-		return
-	}
-
 	var req UpdateTemplateRequest
-	if err := httpkit.DecodeJSON(r, &req); err != nil {
-		httpkit.WriteErr(w, 400, "VALIDATION_ERROR", "invalid json body", nil)
+	if !httpkit.DecodeAndValidate(w, r, &req) {
 		return
 	}
-
-	if req.Type != nil {
-		typ = strings.TrimSpace(*req.Type)
-		if typ == "" {
-			httpkit.WriteErr(w, 400, "VALIDATION_ERROR", "type cannot be empty", map[string]any{"field": "type"})
+	if req.Watermark != nil && !req.Watermark.Disabled {
+		if err := watermark.CheckAsset(ctx, h.pool, "watermark.asset_id", req.Watermark.AssetID); err != nil {
+			h.writeCheckErr(w, r, err, "templates.patch")
 			return
 		}
 	}
-	if req.Name != nil {
-		name = strings.TrimSpace(*req.Name)
-		if name == "" {
-			httpkit.WriteErr(w, 400, "VALIDATION_ERROR", "name cannot be empty", map[string]any{"field": "name"})
+	if req.Assets != nil {
+		if err := assetslots.CheckAssets(ctx, h.pool, "assets", *req.Assets); err != nil {
+			h.writeCheckErr(w, r, err, "templates.patch")
 			return
 		}
 	}
-	if req.DurationMs != nil {
-		durationMs = req.DurationMs
-	}
 
-	// JSONB payloads
-	var formatJSON, paramsSchemaJSON, defaultsJSON any
+	// The row stays locked until the update commits, so concurrent
+	// patches to different fields do not overwrite each other.
+	err := db.WithTx(ctx, h.pool, func(tx pgx.Tx) error {
+		t, err := store.LockTemplate(ctx, tx, templateID)
+		if err != nil {
+			return err
+		}
 
-	if req.Format != nil {
-		b, _ := json.Marshal(req.Format)
-		formatJSON = b
-	} else {
-		// keep existing
-		formatJSON = formatBytes
-	}
+		if req.Type != nil {
+			t.Type = strings.TrimSpace(*req.Type)
+		}
+		if req.Name != nil {
+			t.Name = strings.TrimSpace(*req.Name)
+		}
+		if req.DurationMs != nil {
+			t.DurationMs = req.DurationMs
+		}
 
-	if req.ParamsSchema != nil {
-		b, _ := json.Marshal(*req.ParamsSchema)
-		paramsSchemaJSON = b
-	} else {
-		paramsSchemaJSON = paramsBytes
-	}
+		// JSONB payloads; omitted fields keep their value
+		if req.Format != nil {
+			t.Format, _ = json.Marshal(req.Format)
+		}
+		if req.ParamsSchema != nil {
+			t.ParamsSchema, _ = json.Marshal(*req.ParamsSchema)
+		}
+		if req.Defaults != nil {
+			t.Defaults, _ = json.Marshal(*req.Defaults)
+		}
+		switch {
+		case req.Watermark == nil:
+		case req.Watermark.Disabled:
+			t.Watermark = nil
+		default:
+			t.Watermark, _ = json.Marshal(req.Watermark)
+		}
+		if req.StrictParams != nil {
+			t.StrictParams = *req.StrictParams
+		}
+		switch {
+		case req.Assets == nil:
+		case len(*req.Assets) == 0:
+			t.Assets = nil
+		default:
+			t.Assets, _ = json.Marshal(*req.Assets)
+		}
+		switch {
+		case req.MaxConcurrency == nil:
+		case *req.MaxConcurrency == 0:
+			t.MaxConcurrency = nil
+		default:
+			t.MaxConcurrency = req.MaxConcurrency
+		}
 
-	if req.Defaults != nil {
-		b, _ := json.Marshal(*req.Defaults)
-		defaultsJSON = b
-	} else {
-		defaultsJSON = defaultsBytes
-	}
+		// The merged template must still hold when it is strict
+		var v httpkit.Validator
+		paramschema.CheckTemplate(t.StrictParams, t.ParamsSchema, t.Defaults, v.Check)
+		if err := v.Err(); err != nil {
+			return err
+		}
 
-	_, err = h.pool.Exec(ctx, `
-		UPDATE templates
-		SET type=$2, name=$3, duration_ms=$4, format=$5::jsonb, params_schema=$6::jsonb, defaults=$7::jsonb
-		WHERE id=$1 AND deleted_at IS NULL
-	`, templateID, typ, name, durationMs, formatJSON, paramsSchemaJSON, defaultsJSON)
+		return store.UpdateTemplate(ctx, tx, t)
+	})
 
 	if err != nil {
-		if isUniqueViolation(err) {
-			httpkit.WriteErr(w, 409, "TEMPLATE_NAME_EXISTS", "template name already exists", map[string]any{"field": "name"})
+		if pgerr.IsNoRows(err) {
+			httpkit.WriteErr(w, r, 404, "TEMPLATE_NOT_FOUND", "template not found", map[string]any{"template_id": templateID})
+			return
+		}
+		if pgerr.IsUniqueViolation(err) {
+			httpkit.WriteErr(w, r, 409, "TEMPLATE_NAME_EXISTS", "template name already exists", map[string]any{"field": "name"})
+			return
+		}
+		if errors.IsValidation(err) {
+			httpkit.WriteError(w, r, err)
 			return
 		}
-		httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "db update failed", nil)
+		h.writeDBErr(w, r, err, "templates.patch", "db update failed")
 		return
 	}
+	h.ev.Publish(ctx, events.TemplateUpdated, templateID, nil)
 
 	// return fresh
 	h.GetTemplate(w, r)
 }
 
-func (h *Handler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+// TemplateAssetSlot is a slot of GET /templates/{templateId}/assets, with
+// its asset; Asset is nil if the asset was deleted.
+type TemplateAssetSlot struct {
+	Name string `json:"name"`
+	assetslots.Slot
+	Asset *store.Asset `json:"asset"`
+}
+
+// GetTemplateAssets lists the asset slots of a template with their
+// assets, for editors.
+func (h *Handler) GetTemplateAssets(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	templateID := chi.URLParam(r, "templateId")
 
-	cmd, err := h.pool.Exec(ctx, `
-		UPDATE templates
-		SET deleted_at=NOW()
-		WHERE id=$1 AND deleted_at IS NULL
-	`, templateID)
+	t, err := store.GetTemplate(ctx, h.pool, templateID)
 	if err != nil {
-		httpkit.WriteErr(w, 500, "INTERNAL_ERROR", "db delete failed", nil)
+		if pgerr.IsNoRows(err) {
+			httpkit.WriteErr(w, r, 404, "TEMPLATE_NOT_FOUND", "template not found", map[string]any{"template_id": templateID})
+			return
+		}
+		h.writeDBErr(w, r, err, "templates.assets", "db query failed")
 		return
 	}
-	if cmd.RowsAffected() == 0 {
-		httpkit.WriteErr(w, 404, "TEMPLATE_NOT_FOUND", "template not found", map[string]any{"template_id": templateID})
+	slots, err := assetslots.Parse(t.Assets)
+	if err != nil {
+		h.writeDBErr(w, r, err, "templates.assets", "invalid template assets")
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-}
+	ids := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		ids = append(ids, slot.AssetID)
+	}
+	assets, err := store.GetAssets(ctx, h.pool, ids)
+	if err != nil {
+		h.writeDBErr(w, r, err, "templates.assets", "db query failed")
+		return
+	}
+	byID := make(map[string]store.Asset, len(assets))
+	for _, a := range assets {
+		byID[a.ID] = a
+	}
 
-func isUniqueViolation(err error) bool {
-	var pgErr *pgconn.PgError
-	if errors.As(err, &pgErr) {
-		// 23505 = unique_violation
-		return pgErr.Code == "23505"
+	out := make([]TemplateAssetSlot, 0, len(slots))
+	for _, name := range slots.Names() {
+		s := TemplateAssetSlot{Name: name, Slot: slots[name]}
+		if a, ok := byID[s.AssetID]; ok {
+			s.Asset = &a
+		}
+		out = append(out, s)
 	}
-	return false
+	httpkit.WriteJSON(w, 200, map[string]any{"assets": out})
 }
 
-// keep goimports from deleting util if your IDE complains in this file (rare)
-var _ = util.NewID
+func (h *Handler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	templateID := chi.URLParam(r, "templateId")
+
+	deleted, err := store.SoftDeleteTemplate(ctx, h.pool, templateID)
+	if err != nil {
+		h.writeDBErr(w, r, err, "templates.delete", "db delete failed")
+		return
+	}
+	if !deleted {
+		httpkit.WriteErr(w, r, 404, "TEMPLATE_NOT_FOUND", "template not found", map[string]any{"template_id": templateID})
+		return
+	}
+	h.ev.Publish(ctx, events.TemplateDeleted, templateID, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,35 @@
+// Package openapi serves the API's OpenAPI 3 document and a Swagger UI
+// page for it. openapi.json is maintained by hand next to the handlers;
+// the contract tests in package httpapi fail when routes, error codes or
+// responses drift from it.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"gala/internal/httpkit"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+//go:embed swagger.html
+var swaggerHTML []byte
+
+// Spec returns the OpenAPI document.
+func Spec() []byte { return spec }
+
+// ServeSpec serves the OpenAPI document (GET /openapi.json).
+func ServeSpec(w http.ResponseWriter, r *http.Request) {
+	httpkit.WriteJSONWithETag(w, r, http.StatusOK, json.RawMessage(spec))
+}
+
+// ServeUI serves a Swagger UI page for /openapi.json (GET /docs). The UI
+// assets come from the swagger-ui-dist CDN.
+func ServeUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(swaggerHTML)
+}
@@ -1,26 +1,87 @@
 package httpapi
 
 import (
+	"context"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 
+	"gala/internal/assetkind"
+	"gala/internal/events"
+	"gala/internal/graphapi"
+	"gala/internal/health"
 	"gala/internal/httpapi/handlers"
+	"gala/internal/httpapi/openapi"
 	"gala/internal/httpkit"
+	"gala/internal/pkg/dbpool"
+	"gala/internal/pkg/fetch"
+	"gala/internal/pkg/i18n"
 	"gala/internal/pkg/logger"
+	"gala/internal/pkg/metrics"
 	"gala/internal/pkg/middleware"
+	"gala/internal/pkg/reload"
 	"gala/internal/ports"
+	"gala/internal/publish"
+	"gala/internal/worker/renderer"
 )
 
 type Deps struct {
 	Pool *pgxpool.Pool
-	RDB  *redis.Client
-	SP   ports.StorageProvider
-	Log  *logger.Logger
+	// DB routes list/export reads to read replicas; nil uses Pool only.
+	DB  *dbpool.Router
+	RDB redis.UniversalClient
+	SP  ports.StorageProvider
+	Log *logger.Logger
+
+	// AccessLog receives the per-request lines; defaults to Log.
+	AccessLog *logger.Logger
+	// Metrics is served on /metrics; a new registry is used if nil.
+	Metrics *metrics.Registry
+	// Reload, if set, gets a handler that re-applies the CORS origins and
+	// backs POST /v1/admin/config/reload.
+	Reload *reload.Manager
+	// PanicReporter, if set, receives recovered panics with a request
+	// snapshot (e.g. to forward them to an error tracker).
+	PanicReporter middleware.PanicReporter
+	// Ready backs /readyz (e.g. shutdown.Manager's draining state); nil
+	// means always ready.
+	Ready func() bool
+	// QueueMode is the worker job source; in "postgres" mode new jobs are
+	// not pushed to Redis (see queue.ModePostgres).
+	QueueMode string
+	// AdminToken guards the /admin routes; empty reads ADMIN_TOKEN. The
+	// routes are not mounted when neither is set.
+	AdminToken string
+	// Events receives the lifecycle events and backs GET /v1/events; a
+	// bus on RDB with the default size is used if nil.
+	Events *events.Bus
+	// Publisher runs POST /v1/jobs/{jobId}/publish; nil publishes to no
+	// target.
+	Publisher *publish.Service
+	// Fetch downloads the inputs of POST /v1/quick-render; nil uses the
+	// fetch defaults.
+	Fetch *fetch.Client
+	// AssetStream sets how asset files are served (ASSET_STREAM_MODE);
+	// the zero value proxies them.
+	AssetStream handlers.StreamConfig
+	// Health, if set, serves the deep checks of GET /health from its
+	// cached snapshot.
+	Health *health.Monitor
+	// AssetKinds are the asset kinds POST /assets accepts; nil uses
+	// assetkind.Default.
+	AssetKinds *assetkind.Registry
+	// DedupWindow reuses DONE jobs identical to a new one for this long;
+	// 0 renders every job.
+	DedupWindow time.Duration
+	// RendererAuth verifies the progress the renderer reports; nil accepts
+	// it unauthenticated.
+	RendererAuth renderer.SecretSource
 }
 
 func NewRouter(d Deps) http.Handler {
@@ -29,70 +90,315 @@ func NewRouter(d Deps) http.Handler {
 	// ---- GLOBAL MIDDLEWARE ----
 	// Order matters: RequestID first, then Recovery, then Logging
 	r.Use(middleware.RequestID)
-	r.Use(middleware.Recovery(d.Log))
-	r.Use(middleware.Logging(d.Log))
+	r.Use(middleware.RecoveryWithOptions(d.Log, middleware.RecoveryOptions{
+		BodyLimit: envInt("RECOVERY_BODY_SNAPSHOT_BYTES", middleware.DefaultSnapshotBodyLimit),
+		Reporter:  d.PanicReporter,
+	}))
+	accessLog := d.AccessLog
+	if accessLog == nil {
+		accessLog = d.Log
+	}
+	r.Use(middleware.AccessLogging(d.Log, accessLog))
+
+	reg := d.Metrics
+	if reg == nil {
+		reg = metrics.NewRegistry()
+	}
+	r.Use(middleware.Metrics(middleware.NewHTTPMetrics(reg)))
+	r.Use(i18n.Middleware(i18n.Default()))
 
 	// ---- CORS (Swagger UI + Frontend) ----
-	allowedOrigins := envCSV("CORS_ALLOWED_ORIGINS", []string{
-		"http://localhost:8081",
-		"http://localhost:5173",
-	})
-	r.Use(httpkit.CORS(httpkit.CORSOptions{
-		AllowedOrigins:   allowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Request-ID"},
-		ExposedHeaders:   []string{"X-Request-ID"},
-		AllowCredentials: false,
-		MaxAgeSeconds:    600,
+	// Origins are re-read on config reload (see reload.Manager).
+	cors, err := httpkit.NewCORS(corsOptions(envValues()))
+	if err != nil {
+		panic(err)
+	}
+	r.Use(cors.Middleware)
+	if d.Reload != nil {
+		d.Reload.Register("cors", func(ctx context.Context, v reload.Values) error {
+			return cors.Update(corsOptions(v))
+		})
+	}
+
+	// ---- IDEMPOTENCY ----
+	// After CORS so replayed responses never carry another origin's headers.
+	r.Use(middleware.Idempotency(middleware.IdempotencyOptions{
+		Store:        middleware.NewRedisIdempotencyStore(d.RDB),
+		TTL:          envDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		MaxBodyBytes: int64(envInt("IDEMPOTENCY_MAX_BODY_BYTES", middleware.DefaultIdempotencyMaxBody)),
+		Log:          d.Log,
 	}))
 
+	ev := d.Events
+	if ev == nil {
+		ev = events.New(d.RDB, d.Log, 0)
+	}
 	h := handlers.New(handlers.Deps{
-		Pool: d.Pool,
-		RDB:  d.RDB,
-		SP:   d.SP,
-		Log:  d.Log,
+		Pool:      d.Pool,
+		DB:        d.DB,
+		RDB:       d.RDB,
+		SP:        d.SP,
+		Log:       d.Log,
+		Reload:    d.Reload,
+		Ready:     d.Ready,
+		QueueMode: d.QueueMode,
+		Events:    ev,
+		Publisher: d.Publisher,
+		Fetch:     d.Fetch,
+		Stream:    d.AssetStream,
+		Health:    d.Health,
+
+		AssetKinds:   d.AssetKinds,
+		DedupWindow:  d.DedupWindow,
+		RendererAuth: d.RendererAuth,
 	})
+	gql := graphapi.NewHandler(graphapi.Deps{Pool: d.Pool, DB: d.DB, Log: d.Log})
+
+	// ---- TIMEOUTS ----
+	// Responses are buffered by the timeout middleware, so streaming routes
+	// (asset content, asset listing, job export, events) run without one and rely
+	// on the client disconnecting.
+	rt := routeTimeouts{
+		request: middleware.Timeout(envDuration("HTTP_REQUEST_TIMEOUT", 30*time.Second)),
+		upload:  middleware.Timeout(envDuration("HTTP_UPLOAD_TIMEOUT", 5*time.Minute)),
+		audit:   middleware.Timeout(envDuration("HTTP_ADMIN_AUDIT_TIMEOUT", 10*time.Minute)),
+		drain:   middleware.Timeout(envDuration("HTTP_ADMIN_DRAIN_TIMEOUT", 15*time.Minute)),
+	}
 
 	// ---- HEALTH ----
-	r.Get("/health", h.Health)
+	// Service routes are not versioned.
+	r.With(rt.request).Get("/health", h.Health)
+	r.Get("/readyz", h.Ready)
+
+	// ---- METRICS (Prometheus) ----
+	r.Method(http.MethodGet, "/metrics", reg.Handler())
+
+	// ---- API DOCS ----
+	r.Get("/openapi.json", openapi.ServeSpec)
+	r.Get("/docs", openapi.ServeUI)
+
+	// ---- EMBED (public) ----
+	// Share links are the credentials, so the player works from any site.
+	// Media routes stream without a timeout.
+	r.Route("/embed", func(r chi.Router) {
+		r.With(rt.request).Get("/{token}", h.EmbedPlayer)
+		r.With(rt.request).Get("/{token}.json", h.EmbedMetadata)
+		r.Get("/{token}/video", h.EmbedVideo)
+		r.Get("/{token}/thumbnail", h.EmbedThumbnail)
+	})
+
+	token := d.AdminToken
+	if token == "" {
+		token = strings.TrimSpace(os.Getenv("ADMIN_TOKEN"))
+	}
+
+	// ---- API v1 ----
+	// A future /v2 gets its own Route and mount function, reusing the v1
+	// handlers that do not change; handlers can tell versions apart with
+	// httpkit.Version.
+	r.Route("/"+httpkit.V1, func(r chi.Router) {
+		r.Use(httpkit.WithVersion(httpkit.V1))
+		mountV1(r, h, gql, rt, token)
+	})
+
+	// ---- LEGACY ROOT ROUTES ----
+	// Deprecated aliases of the current version, kept for clients that
+	// predate /v1. They point at their /v1 successor.
+	r.Group(func(r chi.Router) {
+		r.Use(
+			httpkit.WithVersion(httpkit.CurrentVersion),
+			middleware.Deprecation(middleware.DeprecationOptions{
+				Successor: func(r *http.Request) string {
+					u := "/" + httpkit.CurrentVersion + r.URL.Path
+					if r.URL.RawQuery != "" {
+						u += "?" + r.URL.RawQuery
+					}
+					return u
+				},
+				Sunset: envTime("API_LEGACY_SUNSET"),
+			}),
+		)
+		mountV1(r, h, gql, rt, token)
+	})
+
+	return r
+}
+
+// routeTimeouts are the Timeout middlewares shared by the API versions.
+type routeTimeouts struct {
+	request, upload, audit, drain func(http.Handler) http.Handler
+}
+
+// mountV1 registers the v1 API routes on r. The admin routes are only
+// mounted when an admin token is set.
+func mountV1(r chi.Router, h *handlers.Handler, gql http.Handler, rt routeTimeouts, adminToken string) {
+	// ---- ERRORS ----
+	r.With(rt.request).Get("/errors/catalog", h.ErrorCatalog)
 
 	// ---- ASSETS ----
-	r.Post("/assets", h.PostAsset)
-	r.Get("/assets/{assetId}", h.GetAsset)
-	r.Get("/assets/{assetId}/url", h.GetAssetURL)
+	r.With(rt.upload).Post("/assets", h.PostAsset)
+	r.Get("/assets", h.ListAssets)
+	r.Get("/assets/kinds", h.ListAssetKinds)
+	r.With(rt.request).Post("/assets/uploads", h.CreateUpload)
+	r.With(rt.request).Get("/assets/uploads/{uploadId}", h.GetUpload)
+	r.With(rt.request).Get("/assets/{assetId}", h.GetAsset)
+	r.With(rt.request).Get("/assets/{assetId}/url", h.GetAssetURL)
 	r.Get("/assets/{assetId}/content", h.StreamAsset)
-	r.Delete("/assets/{assetId}", h.DeleteAsset)
+	r.Get("/assets/{assetId}/hls/{name}", h.StreamHLS)
+	r.With(rt.request).Delete("/assets/{assetId}", h.DeleteAsset)
 
-	// ---- TEMPLATES ----
-	r.Post("/templates", h.PostTemplate)
-	r.Get("/templates", h.ListTemplates)
-	r.Get("/templates/{templateId}", h.GetTemplate)
-	r.Patch("/templates/{templateId}", h.PatchTemplate)
-	r.Delete("/templates/{templateId}", h.DeleteTemplate)
+	// ---- QUICK RENDER ----
+	// Downloads its inputs before answering, so it gets the upload timeout
+	r.With(rt.upload).Post("/quick-render", h.QuickRender)
 
-	// ---- JOBS ----
-	r.Post("/jobs", h.PostJob)
-	r.Get("/jobs", h.ListJobs)
-	r.Get("/jobs/{jobId}", h.GetJob)
+	r.Group(func(r chi.Router) {
+		r.Use(rt.request)
 
-	return r
+		// ---- SETTINGS ----
+		r.Get("/settings", h.GetSettings)
+		r.Put("/settings", h.PutSettings)
+		r.Delete("/settings", h.DeleteSettings)
+
+		// ---- TEMPLATES ----
+		r.Post("/templates", h.PostTemplate)
+		r.Get("/templates", h.ListTemplates)
+		r.Get("/templates/{templateId}", h.GetTemplate)
+		r.Patch("/templates/{templateId}", h.PatchTemplate)
+		r.Get("/templates/{templateId}/assets", h.GetTemplateAssets)
+		r.Delete("/templates/{templateId}", h.DeleteTemplate)
+
+		// ---- JOBS ----
+		r.Post("/jobs", h.PostJob)
+		r.Post("/jobs/batch", h.PostJobBatch)
+		r.Get("/jobs", h.ListJobs)
+		r.Get("/jobs/{jobId}", h.GetJob)
+		r.Get("/jobs/{jobId}/callback/deliveries", h.ListCallbackDeliveries)
+		r.Post("/jobs/{jobId}/progress", h.PostJobProgress)
+		r.Post("/jobs/{jobId}/cancel", h.CancelJob)
+		r.Post("/jobs/{jobId}/retry", h.RequeueJob)
+		r.Post("/jobs/{jobId}/publish", h.PublishJob)
+		r.Get("/jobs/{jobId}/publications", h.ListJobPublications)
+		r.Post("/jobs/{jobId}/shares", h.PostShare)
+		r.Get("/jobs/{jobId}/shares", h.ListJobShares)
+		r.Delete("/shares/{token}", h.RevokeShare)
+	})
+
+	// ---- GRAPHQL (read-only, dashboard) ----
+	r.With(rt.request).Method(http.MethodPost, "/graphql", gql)
+
+	// ---- EXPORTS (NDJSON) ----
+	r.Get("/jobs/export", h.ExportJobs)
+
+	// ---- EVENTS (SSE) ----
+	// Long-lived: no timeout and no write deadline
+	r.With(noWriteDeadline).Get("/events", h.StreamEvents)
+	r.With(noWriteDeadline).Get("/jobs/{jobId}/events", h.StreamJobEvents)
+
+	// ---- ADMIN ----
+	if adminToken == "" {
+		return
+	}
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(middleware.RequireBearerToken(adminToken))
+		r.Group(func(r chi.Router) {
+			r.Use(rt.request)
+			r.Post("/config/reload", h.ReloadConfig)
+			r.Post("/jobs/{jobId}/requeue", h.RequeueJob)
+			r.Post("/jobs/{jobId}/cancel", h.CancelJob)
+			r.Patch("/jobs/{jobId}/params", h.EditJobParams)
+			r.Get("/queue/stats", h.QueueStats)
+			r.Post("/queue/resume", h.ResumeQueue)
+			r.Post("/assets/gc", h.GCAssets)
+			r.Get("/workers", h.ListWorkers)
+			r.Get("/reports/usage", h.UsageReport)
+		})
+		// A soft drain waits for the running jobs
+		r.With(noWriteDeadline, rt.drain).Post("/queue/drain", h.DrainQueue)
+		// The audit lists (and may read) every stored object
+		r.With(noWriteDeadline, rt.audit).Post("/storage/audit", h.AuditStorage)
+	})
 }
 
-func envCSV(key string, def []string) []string {
+// noWriteDeadline lifts the server's WriteTimeout for routes that allow
+// longer with their own Timeout.
+func noWriteDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsOptions builds the CORS policy from CORS_ALLOWED_ORIGINS and
+// CORS_ADMIN_ALLOWED_ORIGINS.
+func corsOptions(v reload.Values) httpkit.CORSOptions {
+	headers := []string{"Content-Type", "Authorization", "Accept-Language", "X-Request-ID", "Idempotency-Key", "If-None-Match"}
+	exposed := []string{"X-Request-ID", "Content-Language", "Idempotent-Replayed", "Link", "ETag", "Deprecation", "Sunset"}
+	admin := httpkit.CORSOptions{
+		AllowedOrigins: v.CSV("CORS_ADMIN_ALLOWED_ORIGINS", nil),
+		AllowedHeaders: headers,
+		ExposedHeaders: exposed,
+		MaxAgeSeconds:  600,
+	}
+	embed := httpkit.CORSOptions{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "OPTIONS"},
+		MaxAgeSeconds:  600,
+	}
+	return httpkit.CORSOptions{
+		AllowedOrigins: v.CSV("CORS_ALLOWED_ORIGINS", []string{
+			"http://localhost:8081",
+			"http://localhost:5173",
+		}),
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   headers,
+		ExposedHeaders:   exposed,
+		AllowCredentials: false,
+		MaxAgeSeconds:    600,
+		// Admin routes only accept their own origin list (none by default,
+		// i.e. same-origin only); preview wildcards do not apply there.
+		// Embed routes accept any origin.
+		Routes: []httpkit.CORSRoute{
+			{PathPrefix: "/" + httpkit.V1 + "/admin/", Options: admin},
+			{PathPrefix: "/admin/", Options: admin},
+			{PathPrefix: "/embed/", Options: embed},
+		},
+	}
+}
+
+// envValues returns the process environment as reload.Values.
+func envValues() reload.Values {
+	v, _ := reload.Load("")
+	return v
+}
+
+func envInt(key string, def int) int {
+	v, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key)))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
 	raw := strings.TrimSpace(os.Getenv(key))
 	if raw == "" {
 		return def
 	}
-	parts := strings.Split(raw, ",")
-	out := make([]string, 0, len(parts))
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if p != "" {
-			out = append(out, p)
-		}
-	}
-	if len(out) == 0 {
+	d, err := time.ParseDuration(raw)
+	if err != nil {
 		return def
 	}
-	return out
+	return d
+}
+
+// envTime reads an RFC 3339 timestamp or a YYYY-MM-DD date; unset or
+// invalid values give the zero time.
+func envTime(key string) time.Time {
+	raw := strings.TrimSpace(os.Getenv(key))
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
 }
@@ -1,11 +1,18 @@
 package httpkit
 
 import (
+	"fmt"
 	"net/http"
+	"regexp"
+	"sort"
 	"strings"
+	"sync/atomic"
 )
 
 type CORSOptions struct {
+	// AllowedOrigins accepts exact origins, "*", wildcard subdomain
+	// patterns ("https://*.preview.example.com") and regular expressions
+	// prefixed with "regex:" ("regex:^https://pr-[0-9]+\.example\.com$").
 	AllowedOrigins   []string
 	AllowedMethods   []string
 	AllowedHeaders   []string
@@ -13,71 +20,222 @@ type CORSOptions struct {
 	AllowCredentials bool
 	MaxAgeSeconds    int
 	DebugHeader      bool // agrega X-CORS-Debug para validar rápido en dev
+
+	// Routes overrides the policy for path prefixes, e.g. a stricter origin
+	// list for /admin. The longest matching prefix wins; overrides do not
+	// inherit from the parent options.
+	Routes []CORSRoute
+}
+
+// CORSRoute is a CORS policy for requests whose path starts with
+// PathPrefix.
+type CORSRoute struct {
+	PathPrefix string
+	Options    CORSOptions
 }
 
+// CORS returns the CORS middleware. It panics on an invalid "regex:"
+// origin, since origins are startup configuration.
 func CORS(opt CORSOptions) func(http.Handler) http.Handler {
-	if len(opt.AllowedMethods) == 0 {
-		opt.AllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	c, err := NewCORS(opt)
+	if err != nil {
+		panic(err)
 	}
-	if len(opt.AllowedHeaders) == 0 {
-		opt.AllowedHeaders = []string{"Content-Type", "Authorization", "Accept"}
+	return c.Middleware
+}
+
+// CORSHandler is the CORS middleware with options that can be replaced
+// while serving, e.g. on a configuration reload.
+type CORSHandler struct {
+	policies atomic.Pointer[corsPolicies]
+}
+
+type corsPolicies struct {
+	root   *corsPolicy
+	routes []corsRoute // longest prefix first
+}
+
+type corsRoute struct {
+	prefix string
+	policy *corsPolicy
+}
+
+// NewCORS builds a CORSHandler; it fails on an invalid "regex:" origin.
+func NewCORS(opt CORSOptions) (*CORSHandler, error) {
+	c := &CORSHandler{}
+	if err := c.Update(opt); err != nil {
+		return nil, err
 	}
-	if opt.MaxAgeSeconds == 0 {
-		opt.MaxAgeSeconds = 600
+	return c, nil
+}
+
+// Update replaces the options. On error the previous options stay in
+// effect. Requests already past the middleware are not affected.
+func (c *CORSHandler) Update(opt CORSOptions) error {
+	root, err := newCORSPolicy(opt)
+	if err != nil {
+		return err
+	}
+	ps := &corsPolicies{root: root, routes: make([]corsRoute, 0, len(opt.Routes))}
+	for _, rt := range opt.Routes {
+		p, err := newCORSPolicy(rt.Options)
+		if err != nil {
+			return fmt.Errorf("cors route %s: %w", rt.PathPrefix, err)
+		}
+		ps.routes = append(ps.routes, corsRoute{rt.PathPrefix, p})
 	}
+	sort.Slice(ps.routes, func(i, j int) bool { return len(ps.routes[i].prefix) > len(ps.routes[j].prefix) })
+	c.policies.Store(ps)
+	return nil
+}
 
-	allowedMethods := strings.Join(opt.AllowedMethods, ", ")
-	allowedHeaders := strings.Join(opt.AllowedHeaders, ", ")
-	exposedHeaders := strings.Join(opt.ExposedHeaders, ", ")
+func (ps *corsPolicies) policyFor(path string) *corsPolicy {
+	for _, rt := range ps.routes {
+		if strings.HasPrefix(path, rt.prefix) {
+			return rt.policy
+		}
+	}
+	return ps.root
+}
 
-	allowedOrigins := normalizeList(opt.AllowedOrigins)
+// Middleware applies the current options to each request.
+func (c *CORSHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := c.policies.Load().policyFor(r.URL.Path)
+		origin := r.Header.Get("Origin")
 
-	isAllowedOrigin := func(origin string) bool {
-		if origin == "" {
-			return false
+		allowed := p.isAllowedOrigin(origin)
+
+		if p.opt.DebugHeader {
+			w.Header().Set("X-CORS-Debug", "origin="+origin+" allowed="+boolToStr(allowed))
 		}
-		for _, o := range allowedOrigins {
-			if o == "*" || o == origin {
-				return true
-			}
+
+		if origin != "" {
+			// The response depends on Origin even when it is rejected.
+			w.Header().Add("Vary", "Origin")
 		}
-		return false
-	}
 
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := r.Header.Get("Origin")
+		if origin != "" && allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
 
-			allowed := isAllowedOrigin(origin)
+			w.Header().Set("Access-Control-Allow-Methods", p.allowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", p.allowedHeaders)
+			w.Header().Set("Access-Control-Max-Age", intToString(p.opt.MaxAgeSeconds))
 
-			if opt.DebugHeader {
-				w.Header().Set("X-CORS-Debug", "origin="+origin+" allowed="+boolToStr(allowed))
+			if p.exposedHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", p.exposedHeaders)
+			}
+			if p.opt.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
 			}
+		}
 
-			if origin != "" && allowed {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Add("Vary", "Origin")
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
 
-				w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
-				w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
-				w.Header().Set("Access-Control-Max-Age", intToString(opt.MaxAgeSeconds))
+		next.ServeHTTP(w, r)
+	})
+}
 
-				if exposedHeaders != "" {
-					w.Header().Set("Access-Control-Expose-Headers", exposedHeaders)
-				}
-				if opt.AllowCredentials {
-					w.Header().Set("Access-Control-Allow-Credentials", "true")
-				}
-			}
+type corsPolicy struct {
+	opt            CORSOptions
+	allowedMethods string
+	allowedHeaders string
+	exposedHeaders string
 
-			if r.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusNoContent)
-				return
+	any      bool
+	exact    map[string]bool
+	wildcard []wildcardOrigin
+	regexps  []*regexp.Regexp
+}
+
+func newCORSPolicy(opt CORSOptions) (*corsPolicy, error) {
+	if len(opt.AllowedMethods) == 0 {
+		opt.AllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	}
+	if len(opt.AllowedHeaders) == 0 {
+		opt.AllowedHeaders = []string{"Content-Type", "Authorization", "Accept"}
+	}
+	if opt.MaxAgeSeconds == 0 {
+		opt.MaxAgeSeconds = 600
+	}
+
+	p := &corsPolicy{
+		opt:            opt,
+		allowedMethods: strings.Join(opt.AllowedMethods, ", "),
+		allowedHeaders: strings.Join(opt.AllowedHeaders, ", "),
+		exposedHeaders: strings.Join(opt.ExposedHeaders, ", "),
+		exact:          map[string]bool{},
+	}
+
+	for _, o := range normalizeList(opt.AllowedOrigins) {
+		switch {
+		case o == "*":
+			p.any = true
+		case strings.HasPrefix(o, "regex:"):
+			re, err := regexp.Compile(strings.TrimPrefix(o, "regex:"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid cors origin %q: %w", o, err)
 			}
+			p.regexps = append(p.regexps, re)
+		case strings.Contains(o, "*"):
+			prefix, suffix, _ := strings.Cut(strings.ToLower(o), "*")
+			p.wildcard = append(p.wildcard, wildcardOrigin{prefix, suffix})
+		default:
+			p.exact[strings.ToLower(o)] = true
+		}
+	}
+	return p, nil
+}
 
-			next.ServeHTTP(w, r)
-		})
+func (p *corsPolicy) isAllowedOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.any {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if p.exact[lower] {
+		return true
+	}
+	for _, w := range p.wildcard {
+		if w.match(lower) {
+			return true
+		}
+	}
+	for _, re := range p.regexps {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardOrigin matches origins like "https://*.preview.example.com".
+// The "*" stands for one or more host labels, so it cannot match a scheme,
+// port or path and "https://evil.com/.preview.example.com" is rejected.
+type wildcardOrigin struct {
+	prefix, suffix string
+}
+
+func (w wildcardOrigin) match(origin string) bool {
+	if len(origin) <= len(w.prefix)+len(w.suffix) ||
+		!strings.HasPrefix(origin, w.prefix) || !strings.HasSuffix(origin, w.suffix) {
+		return false
+	}
+	host := origin[len(w.prefix) : len(origin)-len(w.suffix)]
+	if host[0] == '.' || host[len(host)-1] == '.' || strings.Contains(host, "..") {
+		return false
+	}
+	for _, c := range host {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.') {
+			return false
+		}
 	}
+	return true
 }
 
 func normalizeList(in []string) []string {
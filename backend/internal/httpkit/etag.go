@@ -0,0 +1,66 @@
+package httpkit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// WeakETag returns a weak entity tag for body. It is weak because the
+// same resource may be encoded differently (e.g. map key order, timestamp
+// precision) while meaning the same thing.
+func WeakETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// ETagMatches reports whether an If-None-Match header value matches etag
+// using the weak comparison from RFC 9110 §8.8.3.2: "*" matches anything
+// and the W/ prefix is ignored on both sides.
+func ETagMatches(ifNoneMatch, etag string) bool {
+	ifNoneMatch = strings.TrimSpace(ifNoneMatch)
+	if ifNoneMatch == "" {
+		return false
+	}
+	if ifNoneMatch == "*" {
+		return true
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteJSONWithETag is WriteJSON for cacheable resources: it sets a weak
+// ETag computed from the encoded body and, for GET and HEAD requests whose
+// If-None-Match matches it, answers 304 Not Modified without a body.
+// Clients polling a resource then only pay for the headers until it
+// changes.
+func WriteJSONWithETag(w http.ResponseWriter, r *http.Request, status int, body any) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	etag := WeakETag(buf.Bytes())
+	w.Header().Set("ETag", etag)
+	// Allow caching but make clients revalidate on every use.
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+		ETagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+}
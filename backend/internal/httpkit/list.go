@@ -0,0 +1,56 @@
+package httpkit
+
+import "net/http"
+
+// List is the response of the paginated list endpoints, the same for all
+// of them:
+//
+//	{"items": [...], "next_cursor": "...", "total": 42, "filters": {"status": "DONE"}}
+//
+// next_cursor is absent on the last page and total unless it was asked
+// for; filters echoes the filters applied, {} when none.
+type List struct {
+	Items      any
+	NextCursor string
+	// Total is the count of items matching the filters across pages; nil
+	// leaves it out.
+	Total *int64
+	// Filters are the applied filters by query parameter; empty values
+	// are left out.
+	Filters map[string]string
+	// Legacy is the key the endpoint used for its items before the
+	// envelope ("jobs", "assets"...). It still carries them for older
+	// clients and will be removed with the next API version.
+	Legacy string
+	// Extra holds endpoint-specific fields, e.g. the counts of GET /jobs.
+	Extra map[string]any
+}
+
+// WriteList writes l with status 200 and its Link header (see
+// WriteLinkHeader).
+func WriteList(w http.ResponseWriter, r *http.Request, l List) {
+	WriteLinkHeader(w, r, l.NextCursor)
+
+	filters := make(map[string]string, len(l.Filters))
+	for k, v := range l.Filters {
+		if v != "" {
+			filters[k] = v
+		}
+	}
+	resp := make(map[string]any, len(l.Extra)+5)
+	for k, v := range l.Extra {
+		resp[k] = v
+	}
+	resp["items"] = l.Items
+	resp["filters"] = filters
+	if l.NextCursor != "" {
+		resp["next_cursor"] = l.NextCursor
+	}
+	if l.Total != nil {
+		resp["total"] = *l.Total
+	}
+	if l.Legacy != "" {
+		resp[l.Legacy] = l.Items
+	}
+	WriteJSON(w, http.StatusOK, resp)
+}
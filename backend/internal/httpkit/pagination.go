@@ -0,0 +1,108 @@
+package httpkit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Default list bounds used by list endpoints.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// ErrInvalidCursor is returned for cursors that were not produced by
+// EncodeCursor or do not match the expected keyset.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrInvalidLimit is returned for a limit that is not a positive integer.
+var ErrInvalidLimit = errors.New("invalid limit")
+
+// Page is the pagination input of a list request.
+type Page struct {
+	Limit  int
+	Cursor string
+}
+
+// ParsePage reads `limit` and `cursor` from the query string. An empty
+// limit means def; limits above max are clamped to max.
+func ParsePage(r *http.Request, def, max int) (Page, error) {
+	q := r.URL.Query()
+	limit, err := ParseLimit(q.Get("limit"), def, max)
+	if err != nil {
+		return Page{}, err
+	}
+	return Page{Limit: limit, Cursor: strings.TrimSpace(q.Get("cursor"))}, nil
+}
+
+// ParseLimit parses a limit value with bounds (see ParsePage).
+func ParseLimit(raw string, def, max int) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 1 {
+		return 0, ErrInvalidLimit
+	}
+	if v > max {
+		v = max
+	}
+	return v, nil
+}
+
+// EncodeCursor returns an opaque cursor for the keyset values of the last
+// item of a page, e.g. EncodeCursor(createdAt, id).
+func EncodeCursor(values ...any) string {
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor decodes a cursor from EncodeCursor into dst, which must be
+// pointers matching the encoded values in number and order.
+func DecodeCursor(cursor string, dst ...any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	var parts []json.RawMessage
+	if err := json.Unmarshal(raw, &parts); err != nil || len(parts) != len(dst) {
+		return ErrInvalidCursor
+	}
+	for i, p := range parts {
+		if err := json.Unmarshal(p, dst[i]); err != nil {
+			return ErrInvalidCursor
+		}
+	}
+	return nil
+}
+
+// WriteLinkHeader sets an RFC 8288 Link header with rel="first" and, when
+// next is not empty, rel="next". Other query parameters (filters, limit)
+// are preserved.
+func WriteLinkHeader(w http.ResponseWriter, r *http.Request, next string) {
+	links := []string{`<` + pageURL(r, "") + `>; rel="first"`}
+	if next != "" {
+		links = append(links, `<`+pageURL(r, next)+`>; rel="next"`)
+	}
+	w.Header().Add("Link", strings.Join(links, ", "))
+}
+
+func pageURL(r *http.Request, cursor string) string {
+	q := r.URL.Query()
+	if cursor == "" {
+		q.Del("cursor")
+	} else {
+		q.Set("cursor", cursor)
+	}
+	u := *r.URL
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
@@ -3,8 +3,13 @@ package httpkit
 import (
 	"encoding/json"
 	"net/http"
+
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/i18n"
 )
 
+// ErrorEnvelope mirrors the JSON produced by *errors.Error; handy for
+// decoding error responses in clients and tests.
 type ErrorEnvelope struct {
 	Error struct {
 		Code    string         `json:"code"`
@@ -26,14 +31,31 @@ func WriteJSON(w http.ResponseWriter, status int, body any) {
 	_ = json.NewEncoder(w).Encode(body)
 }
 
-func WriteErr(w http.ResponseWriter, status int, code, msg string, details map[string]any) {
+// WriteErr writes the standard error envelope. msg is in English and is
+// translated to the request language (see i18n.Middleware).
+func WriteErr(w http.ResponseWriter, r *http.Request, status int, code, msg string, details map[string]any) {
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", i18n.Language(r.Context()))
 	w.WriteHeader(status)
 
-	var env ErrorEnvelope
-	env.Error.Code = code
-	env.Error.Message = msg
-	env.Error.Details = details
+	_ = json.NewEncoder(w).Encode(&errors.Error{
+		Code:    errors.Code(code),
+		Message: i18n.Localize(r.Context(), errors.Code(code), msg, details),
+		Fields:  details,
+	})
+}
 
-	_ = json.NewEncoder(w).Encode(env)
+// WriteError writes err as the standard error envelope, with the HTTP
+// status derived from its code. Non-*errors.Error values become 500s.
+// The message is translated to the request language.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	e := errors.AsError(err)
+	if e == nil {
+		e = errors.Internal("internal server error")
+	}
+	e = i18n.LocalizeError(r.Context(), e)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", i18n.Language(r.Context()))
+	w.WriteHeader(e.HTTPStatus())
+	_ = json.NewEncoder(w).Encode(e)
 }
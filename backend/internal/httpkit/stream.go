@@ -0,0 +1,82 @@
+package httpkit
+
+import (
+	"encoding/json"
+	"iter"
+	"net/http"
+	"strings"
+	"time"
+
+	"gala/internal/pkg/errors"
+)
+
+// NDJSONContentType is the media type of newline-delimited JSON.
+const NDJSONContentType = "application/x-ndjson"
+
+// ndjsonFlushInterval bounds how long encoded lines may sit in the
+// server's buffer before being pushed to the client.
+const ndjsonFlushInterval = 250 * time.Millisecond
+
+// WantsNDJSON reports whether the client asked for NDJSON via Accept.
+func WantsNDJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), NDJSONContentType)
+}
+
+// StreamNDJSON writes items as newline-delimited JSON, one value per line,
+// flushing periodically so clients see progress and nothing is buffered in
+// full. It stops when the request context is canceled.
+//
+// If items fails before the first value, a regular error response is
+// written. Once streaming has started the status can no longer change, so
+// a failure is reported as a final {"error":{...}} line. The error is
+// returned so the caller can log it.
+func StreamNDJSON[T any](w http.ResponseWriter, r *http.Request, items iter.Seq2[T, error]) error {
+	ctx := r.Context()
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+
+	started := false
+	lastFlush := time.Now()
+	start := func() {
+		w.Header().Set("Content-Type", NDJSONContentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+
+	for item, err := range items {
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			if !started {
+				WriteError(w, r, err)
+				return err
+			}
+			if ctx.Err() == nil {
+				_ = enc.Encode(errors.AsError(err))
+				_ = rc.Flush()
+			}
+			return err
+		}
+
+		if !started {
+			start()
+		}
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+		if time.Since(lastFlush) >= ndjsonFlushInterval {
+			// Not every writer can flush (e.g. in tests); lines still go out
+			// when the handler returns.
+			_ = rc.Flush()
+			lastFlush = time.Now()
+		}
+	}
+
+	if !started {
+		start()
+	}
+	_ = rc.Flush()
+	return nil
+}
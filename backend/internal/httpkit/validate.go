@@ -0,0 +1,72 @@
+package httpkit
+
+import (
+	"net/http"
+	"strings"
+
+	"gala/internal/pkg/errors"
+)
+
+// Validatable is implemented by request bodies that check themselves.
+// Validate may normalize fields (e.g. trim whitespace) before checking
+// them, so it is usually declared on a pointer receiver.
+type Validatable interface {
+	Validate() error
+}
+
+// Validator collects field errors so a request reports all of them at
+// once instead of one per round trip.
+//
+//	var v httpkit.Validator
+//	v.Required("name", req.Name)
+//	v.Check(req.Width > 0, "format.width", "format.width must be positive")
+//	return v.Err()
+type Validator struct {
+	errs []error
+}
+
+// Required adds "<field> is required" if value is blank.
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, field+" is required")
+}
+
+// NotBlank adds "<field> cannot be empty" if value is set but blank; used
+// for optional fields in partial updates.
+func (v *Validator) NotBlank(field string, value *string) {
+	if value != nil {
+		v.Check(strings.TrimSpace(*value) != "", field, field+" cannot be empty")
+	}
+}
+
+// Check adds message for field if ok is false.
+func (v *Validator) Check(ok bool, field, message string) {
+	if !ok {
+		v.errs = append(v.errs, errors.ValidationField(field, message))
+	}
+}
+
+// Err returns nil if all checks passed, the field error if one failed, or
+// an aggregate listing every failure under details.errors.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return errors.Aggregate(v.errs...)
+}
+
+// DecodeAndValidate decodes the JSON body into dst and, if dst is
+// Validatable, validates it. On failure it writes a 400 with the field
+// details and returns false.
+func DecodeAndValidate(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if err := DecodeJSON(r, dst); err != nil {
+		WriteErr(w, r, 400, string(errors.CodeValidation), "invalid json body", nil)
+		return false
+	}
+	if val, ok := dst.(Validatable); ok {
+		if err := val.Validate(); err != nil {
+			WriteError(w, r, err)
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+)
+
+// API versions. Each one is mounted under /<version>; handlers that build
+// URLs or change behavior between versions read it with Version.
+const (
+	V1 = "v1"
+
+	// CurrentVersion is the version the deprecated root aliases serve.
+	CurrentVersion = V1
+)
+
+type versionKey struct{}
+
+// WithVersion records the API version of the routes it wraps in the
+// request context.
+func WithVersion(version string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionKey{}, version)))
+		})
+	}
+}
+
+// Version returns the API version serving the request, or "" outside the
+// versioned routes (health, metrics, docs).
+func Version(ctx context.Context) string {
+	v, _ := ctx.Value(versionKey{}).(string)
+	return v
+}
+
+// VersionedPath prefixes path with the request's API version, e.g.
+// "/assets/x" -> "/v1/assets/x", so links point at the same version.
+func VersionedPath(ctx context.Context, path string) string {
+	if v := Version(ctx); v != "" {
+		return "/" + v + path
+	}
+	return path
+}
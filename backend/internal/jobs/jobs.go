@@ -0,0 +1,542 @@
+// Package jobs creates render jobs and follows their status. The REST
+// handlers and the gRPC services share it, so both queue jobs the same way.
+package jobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+
+	"gala/internal/events"
+	"gala/internal/httpapi/util"
+	"gala/internal/inputs"
+	"gala/internal/paramschema"
+	"gala/internal/pkg/db"
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/fetch"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/settings"
+	"gala/internal/store"
+	"gala/internal/watermark"
+	"gala/internal/worker/queue"
+)
+
+var (
+	ErrJobNotFound      = stderrors.New("job not found")
+	ErrTemplateNotFound = stderrors.New("template not found")
+	// ErrQueuePush: the job could not be pushed to Redis, so it was not
+	// created (its row is deleted again).
+	ErrQueuePush = stderrors.New("queue push failed")
+)
+
+// unqueueTimeout bounds the delete of a job whose push failed, which runs
+// even if the request's context is done.
+const unqueueTimeout = 5 * time.Second
+
+// Service creates and watches jobs.
+type Service struct {
+	pool *pgxpool.Pool
+	rdb  redis.UniversalClient
+	ev   *events.Bus
+
+	queueName string
+	// pushJobs is false in postgres queue mode, where the insert itself
+	// queues the job (the jobs trigger notifies the workers).
+	pushJobs bool
+	// dedupWindow is how long a DONE job is reused for identical ones
+	// (see WithDedup); 0 renders every job.
+	dedupWindow time.Duration
+}
+
+// New creates the service. ev receives job.created events (nil publishes
+// none); queueName is the Redis list the workers pop from; pushJobs is
+// false in postgres queue mode.
+func New(pool *pgxpool.Pool, rdb redis.UniversalClient, ev *events.Bus, queueName string, pushJobs bool) *Service {
+	return &Service{pool: pool, rdb: rdb, ev: ev, queueName: queueName, pushJobs: pushJobs}
+}
+
+// WithDedup makes Create return the last DONE job with the same template,
+// inputs, params and watermark if it finished within window, instead of
+// rendering it again. 0 turns it off.
+func (s *Service) WithDedup(window time.Duration) *Service {
+	s.dedupWindow = window
+	return s
+}
+
+// Spec is what a job renders: a template with its inputs and params, or
+// (without TemplateID) the legacy hello render driven by Params. Watermark
+// overrides the template's watermark (nil keeps it). Callback, if set, is
+// where the worker posts the job's completion; it is kept apart from the
+// params so its secret never shows up in them. Priority (store.JobPriority*,
+// empty is normal) is not part of the params either, so it does not keep
+// identical jobs from being deduplicated. RunAt, if in the future, creates
+// the job SCHEDULED until then (see queue.Scheduler).
+type Spec struct {
+	TemplateID string
+	Inputs     map[string]string
+	Params     map[string]any
+	Watermark  *watermark.Config
+	Callback   *Callback
+	Priority   string
+	RunAt      time.Time
+}
+
+// Callback is the completion callback of a job (see package callback).
+type Callback struct {
+	URL    string
+	Secret string
+}
+
+// ParseSpec decodes a job's params_json.
+func ParseSpec(paramsJSON string) Spec {
+	var raw map[string]any
+	_ = json.Unmarshal([]byte(paramsJSON), &raw)
+
+	s := Spec{Inputs: map[string]string{}, Params: map[string]any{}}
+	v, ok := raw["template_id"].(string)
+	if !ok || strings.TrimSpace(v) == "" {
+		if raw != nil {
+			s.Params = raw
+		}
+		return s
+	}
+	s.TemplateID = strings.TrimSpace(v)
+	if m, ok := raw["params"].(map[string]any); ok && m != nil {
+		s.Params = m
+	}
+	if im, ok := raw["inputs"].(map[string]any); ok {
+		for k, vv := range im {
+			if str, ok := vv.(string); ok && strings.TrimSpace(str) != "" {
+				s.Inputs[k] = strings.TrimSpace(str)
+			}
+		}
+	}
+	if wm, ok := raw["watermark"]; ok {
+		b, _ := json.Marshal(wm)
+		s.Watermark, _ = watermark.Parse(b)
+	}
+	return s
+}
+
+// Encode returns the params_json of spec (see ParseSpec) and, for a
+// template job, its params_hash; legacy jobs have no hash.
+func Encode(spec Spec) (paramsJSON []byte, paramsHash string, err error) {
+	var toStore any = spec.Params
+	if spec.TemplateID != "" {
+		envelope := map[string]any{
+			"template_id": spec.TemplateID,
+			"inputs":      spec.Inputs,
+			"params":      spec.Params,
+		}
+		if spec.Watermark != nil {
+			envelope["watermark"] = spec.Watermark
+		}
+		toStore = envelope
+	}
+	paramsJSON, err = json.Marshal(toStore)
+	if err != nil || spec.TemplateID == "" {
+		return paramsJSON, "", err
+	}
+	// Maps marshal with sorted keys, so identical specs hash the same
+	sum := sha256.Sum256(paramsJSON)
+	return paramsJSON, hex.EncodeToString(sum[:]), nil
+}
+
+// Create inserts a QUEUED job and queues it, or a SCHEDULED one if
+// spec.RunAt is in the future. The template must exist, the inputs given
+// by URL must be https URLs (the worker downloads them into assets), the
+// watermark the job resolves to (the template's with the job's overrides)
+// must be an image asset, and with a strict_params template the params
+// must match its params_schema; its failures are validation errors. The
+// workspace settings fill what the spec leaves out (see applySettings).
+// reused reports that an identical DONE job was returned instead (see
+// WithDedup); jobs with their own callback, and scheduled ones, are always
+// rendered.
+func (s *Service) Create(ctx context.Context, name string, spec Spec) (job store.Job, reused bool, err error) {
+	p, err := s.prepare(ctx, &workspace{pool: s.pool}, name, spec)
+	if err != nil || p.reused {
+		return p.job, p.reused, err
+	}
+	if err := s.enqueue(ctx, []prepared{p}); err != nil {
+		return store.Job{}, false, err
+	}
+	return p.job, false, nil
+}
+
+// BatchItem is a job of CreateBatch.
+type BatchItem struct {
+	Name string
+	Spec Spec
+}
+
+// BatchResult is the outcome of a BatchItem: the job, or Err if the item
+// failed its checks.
+type BatchResult struct {
+	Job    store.Job
+	Reused bool
+	Err    error
+}
+
+// ErrBatchInvalid: some item of a batch failed its checks (see the
+// BatchResult errors), so no job of the batch was created.
+var ErrBatchInvalid = stderrors.New("batch has invalid jobs")
+
+// CreateBatch creates the jobs of items as Create would, all or none: it
+// checks every item first and, if one fails, returns ErrBatchInvalid with
+// each item's error (nil for the valid ones). Otherwise the new jobs are
+// inserted in one transaction and pushed to the queue in one MULTI per
+// list, and the results are in the order of items.
+func (s *Service) CreateBatch(ctx context.Context, items []BatchItem) ([]BatchResult, error) {
+	ws := &workspace{pool: s.pool}
+	results := make([]BatchResult, len(items))
+	ps := make([]prepared, 0, len(items))
+	invalid := false
+	for i, it := range items {
+		p, err := s.prepare(ctx, ws, it.Name, it.Spec)
+		switch {
+		case err == nil:
+			results[i] = BatchResult{Job: p.job, Reused: p.reused}
+			if !p.reused {
+				ps = append(ps, p)
+			}
+		case stderrors.Is(err, ErrTemplateNotFound) || errors.IsValidation(err):
+			results[i].Err = err
+			invalid = true
+		default:
+			return nil, err
+		}
+	}
+	if invalid {
+		return results, ErrBatchInvalid
+	}
+	if err := s.enqueue(ctx, ps); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// workspace reads the workspace settings once, when the first job of a
+// request gets past its checks.
+type workspace struct {
+	pool     *pgxpool.Pool
+	settings *settings.Settings
+}
+
+func (w *workspace) get(ctx context.Context) (settings.Settings, error) {
+	if w.settings == nil {
+		ws, err := settings.Load(ctx, w.pool)
+		if err != nil {
+			return settings.Settings{}, err
+		}
+		w.settings = &ws
+	}
+	return *w.settings, nil
+}
+
+// prepared is a job checked and ready to insert, or the DONE job that
+// replaces it (reused).
+type prepared struct {
+	job      store.Job
+	callback *Callback
+	reused   bool
+	// templateID is for the job.created event.
+	templateID string
+}
+
+// prepare checks spec and builds its job with the workspace settings,
+// or finds the DONE job that replaces it; see Create.
+func (s *Service) prepare(ctx context.Context, w *workspace, name string, spec Spec) (prepared, error) {
+	var tmpl store.Template
+	if spec.TemplateID != "" {
+		for k, v := range spec.Inputs {
+			if !inputs.IsURL(v) {
+				continue
+			}
+			if _, err := fetch.ParseURL(v); err != nil {
+				return prepared{}, errors.ValidationField("inputs."+k, "input must be an https url")
+			}
+		}
+		t, err := store.GetTemplate(ctx, s.pool, spec.TemplateID)
+		if err != nil {
+			if pgerr.IsNoRows(err) {
+				return prepared{}, ErrTemplateNotFound
+			}
+			return prepared{}, err
+		}
+		if err := s.checkWatermark(ctx, t, spec.Watermark); err != nil {
+			return prepared{}, err
+		}
+		if err := CheckParams(t, spec.Params); err != nil {
+			return prepared{}, err
+		}
+		tmpl = t
+	}
+	now := time.Now()
+	scheduled := spec.RunAt.After(now)
+	ws, err := w.get(ctx)
+	if err != nil {
+		return prepared{}, err
+	}
+	spec = applySettings(ws, tmpl, spec)
+	dedup := s.dedupWindow > 0 && deduplicable(spec, now)
+
+	paramsBytes, paramsHash, err := Encode(spec)
+	if err != nil {
+		return prepared{}, err
+	}
+	if dedup {
+		prev, err := store.FindDoneJob(ctx, s.pool, paramsHash, now.Add(-s.dedupWindow))
+		if err == nil {
+			return prepared{job: prev, reused: true}, nil
+		}
+		if !pgerr.IsNoRows(err) {
+			return prepared{}, err
+		}
+	}
+
+	if spec.Priority == "" {
+		spec.Priority = store.JobPriorityNormal
+	}
+	createdAt := now.UTC().Truncate(time.Microsecond)
+	job := store.Job{
+		ID:         util.NewID("job"),
+		Name:       name,
+		Status:     store.JobQueued,
+		ParamsJSON: string(paramsBytes),
+		ParamsHash: paramsHash,
+		RequestID:  logger.RequestIDFromContext(ctx),
+		Priority:   spec.Priority,
+		CreatedAt:  createdAt,
+		UpdatedAt:  createdAt,
+	}
+	if scheduled {
+		runAt := spec.RunAt.UTC().Truncate(time.Microsecond)
+		job.Status, job.RunAt = store.JobScheduled, &runAt
+	}
+	return prepared{job: job, callback: spec.Callback, templateID: spec.TemplateID}, nil
+}
+
+// enqueue inserts the jobs of ps, with their callbacks, in one transaction
+// and pushes them to the queue. If the push fails, the jobs are deleted
+// again and it returns ErrQueuePush.
+func (s *Service) enqueue(ctx context.Context, ps []prepared) error {
+	if len(ps) == 0 {
+		return nil
+	}
+	err := db.WithTx(ctx, s.pool, func(tx pgx.Tx) error {
+		// Same transaction: in postgres queue mode a worker may take the
+		// jobs as soon as they are committed
+		for _, p := range ps {
+			if err := store.InsertJob(ctx, tx, p.job); err != nil {
+				return err
+			}
+			if p.callback == nil {
+				continue
+			}
+			err := store.InsertJobCallback(ctx, tx, store.JobCallback{
+				JobID:     p.job.ID,
+				URL:       p.callback.URL,
+				Secret:    p.callback.Secret,
+				CreatedAt: p.job.CreatedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, len(ps))
+	for i, p := range ps {
+		ids[i] = p.job.ID
+	}
+	if s.pushJobs {
+		if err := s.push(ctx, ps); err != nil {
+			return s.unqueue(ctx, ids, err)
+		}
+	}
+	for _, p := range ps {
+		data := map[string]any{
+			"status":      p.job.Status,
+			"name":        p.job.Name,
+			"template_id": p.templateID,
+		}
+		if p.job.RunAt != nil {
+			data["run_at"] = p.job.RunAt
+		}
+		s.ev.Publish(ctx, events.JobCreated, p.job.ID, data)
+	}
+	return nil
+}
+
+// push pushes the jobs of ps to their lists, and the scheduled ones to the
+// scheduled set, in one MULTI, so either all of them are queued or none.
+// The lists and the set share a Redis Cluster slot (see queue.ListFor).
+func (s *Service) push(ctx context.Context, ps []prepared) error {
+	pipe := s.rdb.TxPipeline()
+	for _, p := range ps {
+		if p.job.RunAt != nil {
+			pipe.ZAdd(ctx, queue.ScheduledSet(s.queueName), queue.Scheduled(p.job.ID, *p.job.RunAt))
+			continue
+		}
+		pipe.LPush(ctx, queue.ListFor(s.queueName, p.job.Priority), p.job.ID)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// unqueue deletes jobs whose push to Redis failed, with their callbacks,
+// so no QUEUED row is left that no worker will ever pop, and returns the
+// ErrQueuePush for the caller. The jobs cannot be pushed before the commit
+// instead: a worker popping one first would not find it.
+func (s *Service) unqueue(ctx context.Context, ids []string, pushErr error) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), unqueueTimeout)
+	defer cancel()
+	err := db.WithTx(ctx, s.pool, func(tx pgx.Tx) error {
+		for _, id := range ids {
+			if _, err := store.DeleteQueuedJob(ctx, tx, id); err != nil {
+				return err
+			}
+			if err := store.DeleteJobCallback(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v; jobs %s left QUEUED: %v", ErrQueuePush, pushErr, strings.Join(ids, ", "), err)
+	}
+	return fmt.Errorf("%w: %v", ErrQueuePush, pushErr)
+}
+
+// deduplicable reports whether the job of spec may be replaced by an
+// identical DONE one: a template job that is not scheduled and has no
+// callback, which the reused job would never send. spec must have the
+// workspace settings applied, since they may give it a callback.
+func deduplicable(spec Spec, now time.Time) bool {
+	return spec.TemplateID != "" && spec.Callback == nil && !spec.RunAt.After(now)
+}
+
+// applySettings fills spec with the workspace defaults: the callback and
+// the priority if the job has none, and params.captions for a template job
+// whose params and template defaults do not set it (strict templates are
+// left alone: their params are only the declared ones). It copies the
+// params before changing them.
+func applySettings(ws settings.Settings, t store.Template, spec Spec) Spec {
+	if spec.Callback == nil && ws.Callback != nil && ws.Callback.URL != "" {
+		spec.Callback = &Callback{URL: ws.Callback.URL, Secret: ws.Callback.Secret}
+	}
+	if spec.Priority == "" {
+		spec.Priority = ws.Priority
+	}
+	if ws.Captions == nil || spec.TemplateID == "" || t.StrictParams {
+		return spec
+	}
+	if _, ok := spec.Params["captions"]; ok {
+		return spec
+	}
+	var defaults map[string]any
+	_ = json.Unmarshal(t.Defaults, &defaults)
+	if _, ok := defaults["captions"]; ok {
+		return spec
+	}
+	params := make(map[string]any, len(spec.Params)+1)
+	for k, v := range spec.Params {
+		params[k] = v
+	}
+	params["captions"] = *ws.Captions
+	spec.Params = params
+	return spec
+}
+
+// checkWatermark resolves the job's watermark against the template's and
+// checks its asset. The worker resolves it again when rendering, so a
+// template change applies to the jobs still queued, like its defaults.
+func (s *Service) checkWatermark(ctx context.Context, t store.Template, override *watermark.Config) error {
+	tw, err := watermark.Parse(t.Watermark)
+	if err != nil {
+		return err
+	}
+	wm := watermark.Resolve(tw, override)
+	if wm == nil {
+		if override != nil && !override.Disabled {
+			return errors.ValidationField("watermark.asset_id", "the template has no watermark")
+		}
+		return nil
+	}
+	return watermark.CheckAsset(ctx, s.pool, "watermark.asset_id", wm.AssetID)
+}
+
+// CheckParams checks the job's params against the params_schema of a
+// strict template. The defaults are not checked again: the template was
+// validated when it was saved.
+func CheckParams(t store.Template, params map[string]any) error {
+	if !t.StrictParams {
+		return nil
+	}
+	schema, err := paramschema.Compile(t.ParamsSchema, true)
+	if err != nil {
+		return err
+	}
+	return schema.Validate("params", params)
+}
+
+// Final reports whether status is one a job does not leave on its own.
+func Final(status string) bool {
+	switch status {
+	case store.JobDone, store.JobFailed, store.JobCanceled:
+		return true
+	}
+	return false
+}
+
+// Watch polls the job every interval and calls fn with it first and then
+// on every change of status or updated_at. It returns nil after fn got a
+// final status, fn's error, or the context's.
+func (s *Service) Watch(ctx context.Context, id string, interval time.Duration, fn func(store.Job) error) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	var last store.Job
+	for first := true; ; first = false {
+		j, err := store.GetJob(ctx, s.pool, id)
+		if err != nil {
+			if pgerr.IsNoRows(err) {
+				if first {
+					return ErrJobNotFound
+				}
+				// Deleted (partition dropped) while watching
+				return nil
+			}
+			return err
+		}
+		if first || j.Status != last.Status || !j.UpdatedAt.Equal(last.UpdatedAt) {
+			if err := fn(j); err != nil {
+				return err
+			}
+			last = j
+		}
+		if Final(j.Status) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
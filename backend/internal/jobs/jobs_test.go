@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"gala/internal/settings"
+	"gala/internal/store"
+)
+
+func TestApplySettingsPriority(t *testing.T) {
+	ws := settings.Settings{Priority: store.JobPriorityHigh}
+	tests := []struct {
+		name string
+		ws   settings.Settings
+		spec Spec
+		want string
+	}{
+		{name: "default applied", ws: ws, spec: Spec{}, want: store.JobPriorityHigh},
+		{name: "job's own kept", ws: ws, spec: Spec{Priority: store.JobPriorityNormal}, want: store.JobPriorityNormal},
+		{name: "no default", spec: Spec{}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applySettings(tt.ws, store.Template{}, tt.spec).Priority; got != tt.want {
+				t.Errorf("Priority = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplySettingsPriorityKeepsHash(t *testing.T) {
+	spec := Spec{TemplateID: "tpl_1", Params: map[string]any{"text": "hola"}}
+	_, before, err := Encode(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, after, err := Encode(applySettings(settings.Settings{Priority: store.JobPriorityHigh}, store.Template{}, spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before != after {
+		t.Error("the default priority changed the params hash, which would keep the job from being deduplicated")
+	}
+}
+
+func TestDeduplicableAfterSettings(t *testing.T) {
+	now := time.Now()
+	spec := Spec{TemplateID: "tpl_1", Params: map[string]any{"text": "hola"}}
+	if !deduplicable(spec, now) {
+		t.Fatal("a plain template job is not deduplicable")
+	}
+
+	// The workspace callback must be sent: a reused job would not send it
+	ws := settings.Settings{Callback: &settings.Callback{URL: "https://example.com/hook"}}
+	if deduplicable(applySettings(ws, store.Template{}, spec), now) {
+		t.Error("a job given the workspace callback is deduplicable")
+	}
+
+	for name, s := range map[string]Spec{
+		"legacy":    {Params: map[string]any{"text": "hola"}},
+		"callback":  {TemplateID: "tpl_1", Callback: &Callback{URL: "https://example.com/hook"}},
+		"scheduled": {TemplateID: "tpl_1", RunAt: now.Add(time.Hour)},
+	} {
+		if deduplicable(s, now) {
+			t.Errorf("%s job is deduplicable", name)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package mockrenderer
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+// placeholderMP4 is a bare ftyp box: enough for content sniffing and size
+// checks, not a playable video.
+var placeholderMP4 = []byte{
+	0x00, 0x00, 0x00, 0x18, 'f', 't', 'y', 'p',
+	'i', 's', 'o', 'm', 0x00, 0x00, 0x02, 0x00,
+	'i', 's', 'o', 'm', 'm', 'p', '4', '1',
+}
+
+// placeholderVTT is a single-cue WebVTT file.
+var placeholderVTT = []byte("WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nmock render\n")
+
+// placeholderJPEG is a 16x16 gray JPEG generated at init.
+var placeholderJPEG = func() []byte {
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+	for i := range img.Pix {
+		img.Pix[i] = color.Gray{Y: 128}.Y
+	}
+	var buf bytes.Buffer
+	_ = jpeg.Encode(&buf, img, nil)
+	return buf.Bytes()
+}()
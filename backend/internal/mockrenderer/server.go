@@ -0,0 +1,211 @@
+// Package mockrenderer provides a stand-in for the renderer HTTP service.
+// It accepts the same /render and /render/v1 specs, writes placeholder
+// outputs into the shared storage root and can inject faults, so the full
+// API -> worker -> storage pipeline can run without FFmpeg/SadTalker.
+package mockrenderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gala/internal/pkg/logger"
+	"gala/internal/worker/renderer"
+)
+
+// Options controls the mock behavior, including fault injection.
+type Options struct {
+	// StorageRoot is the shared storage root where outputs are written.
+	StorageRoot string
+	// Delay is added before responding to each render request.
+	Delay time.Duration
+	// FailRate is the probability (0..1) of answering with FailStatus.
+	FailRate float64
+	// FailStatus is the HTTP status used for injected failures (default 500).
+	FailStatus int
+	// FailJobs lists job IDs that always fail.
+	FailJobs []string
+	// SkipOutputs answers 200 without writing any output file.
+	SkipOutputs bool
+	// SkipCaptions never writes the captions file even if requested.
+	SkipCaptions bool
+	// AuthSecrets, if set, are the accepted renderer secrets; requests
+	// without valid bearer or HMAC credentials get 401.
+	AuthSecrets []renderer.Secret
+	// Log is the logger used for request logging.
+	Log *logger.Logger
+}
+
+// Server implements http.Handler for the renderer contract.
+type Server struct {
+	opt Options
+	log *logger.Logger
+	mux *http.ServeMux
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// New creates a new mock renderer server.
+func New(opt Options) *Server {
+	if opt.FailStatus == 0 {
+		opt.FailStatus = http.StatusInternalServerError
+	}
+	log := opt.Log
+	if log == nil {
+		log = logger.NewDefault()
+	}
+
+	s := &Server{
+		opt: opt,
+		log: log.WithComponent("mock-renderer"),
+		mux: http.NewServeMux(),
+		rnd: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	s.mux.HandleFunc("/render", s.handleRender(false))
+	s.mux.HandleFunc("/render/v1", s.handleRender(true))
+	s.mux.HandleFunc("/health", s.handleHealth)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// renderSpec is the union of the v0 and v1 spec fields the mock cares about.
+type renderSpec struct {
+	JobID  string `json:"job_id"`
+	Output struct {
+		VideoObjectKey    string `json:"video_object_key"`
+		ThumbObjectKey    string `json:"thumb_object_key"`
+		CaptionsObjectKey string `json:"captions_object_key"`
+	} `json:"output"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "mock": true})
+}
+
+func (s *Server) handleRender(v1 bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "unreadable body"})
+			return
+		}
+		if len(s.opt.AuthSecrets) > 0 && !renderer.Verify(r, body, renderer.MaxSkew, s.opt.AuthSecrets...) {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+			return
+		}
+
+		var spec renderSpec
+		if err := json.Unmarshal(body, &spec); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid json"})
+			return
+		}
+		if strings.TrimSpace(spec.JobID) == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "job_id is required"})
+			return
+		}
+		if spec.Output.VideoObjectKey == "" || spec.Output.ThumbObjectKey == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "output object keys are required"})
+			return
+		}
+
+		log := s.log.WithJobID(spec.JobID)
+		log.Info("render requested", "v1", v1, "path", r.URL.Path)
+
+		if raw := r.Header.Get(renderer.HeaderDeadline); raw != "" {
+			if deadline, err := time.Parse(time.RFC3339, raw); err == nil && time.Now().Add(s.opt.Delay).After(deadline) {
+				log.Warn("refusing render past caller deadline", "deadline", raw)
+				writeJSON(w, http.StatusGatewayTimeout, map[string]any{"ok": false, "error": "deadline exceeded"})
+				return
+			}
+		}
+
+		if s.opt.Delay > 0 {
+			select {
+			case <-time.After(s.opt.Delay):
+			case <-r.Context().Done():
+				log.Warn("render canceled by client")
+				return
+			}
+		}
+
+		if s.shouldFail(spec.JobID) {
+			log.Warn("injecting render failure", "status", s.opt.FailStatus)
+			writeJSON(w, s.opt.FailStatus, map[string]any{"ok": false, "error": "injected failure"})
+			return
+		}
+
+		written := []string{}
+		if !s.opt.SkipOutputs {
+			files := map[string][]byte{
+				spec.Output.VideoObjectKey: placeholderMP4,
+				spec.Output.ThumbObjectKey: placeholderJPEG,
+			}
+			if v1 && spec.Output.CaptionsObjectKey != "" && !s.opt.SkipCaptions {
+				files[spec.Output.CaptionsObjectKey] = placeholderVTT
+			}
+			for key, data := range files {
+				if err := s.writeObject(key, data); err != nil {
+					log.Error("failed to write output", "object_key", key, "error", err.Error())
+					writeJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": err.Error()})
+					return
+				}
+				written = append(written, key)
+			}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"ok":      true,
+			"job_id":  spec.JobID,
+			"outputs": written,
+		})
+	}
+}
+
+func (s *Server) shouldFail(jobID string) bool {
+	for _, id := range s.opt.FailJobs {
+		if id == jobID {
+			return true
+		}
+	}
+	if s.opt.FailRate <= 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rnd.Float64() < s.opt.FailRate
+}
+
+func (s *Server) writeObject(objectKey string, data []byte) error {
+	clean := filepath.Clean(filepath.FromSlash(objectKey))
+	if filepath.IsAbs(clean) || strings.HasPrefix(clean, "..") {
+		return fmt.Errorf("invalid object key: %s", objectKey)
+	}
+	dst := filepath.Join(s.opt.StorageRoot, clean)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0o644)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Asset is a row of assets, with the JSON the API returns for it. Label
+// and Checksum are empty when NULL; Media is nil for assets that were not
+// probed.
+type Asset struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Provider  string `json:"provider"`
+	ObjectKey string `json:"object_key"`
+	Mime      string `json:"mime"`
+	SizeBytes int64  `json:"size_bytes"`
+	// Checksum is the hex MD5 of the content, recorded on upload.
+	Checksum  string      `json:"-"`
+	Label     string      `json:"label"`
+	Media     *AssetMedia `json:"media,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// AssetMedia are the specs of a video as probed by the worker, stored in
+// assets.media.
+type AssetMedia struct {
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	FPS        float64 `json:"fps"`
+	DurationMs int64   `json:"duration_ms"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Job is a row of jobs, with the JSON of its API summary (admin actions,
+// job lists). ErrorText is the trimmed human summary of a failure and
+// Error its structured form (nil for jobs that did not fail, or failed
+// before migration 012). ParamsJSON is the stored spec; responses that
+// show it parse it with jobs.ParseSpec. Attempts counts the runs of the
+// job.
+type Job struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name,omitempty"`
+	Status     string    `json:"status"`
+	ParamsJSON string    `json:"-"`
+	ErrorText  string    `json:"error,omitempty"`
+	Error      *JobError `json:"error_detail,omitempty"`
+	Attempts   int       `json:"attempts"`
+	// ParamsHash is the SHA-256 of ParamsJSON for template jobs, to find
+	// identical ones (jobs.Service.WithDedup).
+	ParamsHash string `json:"-"`
+	// RequestID is the X-Request-ID of the request that created the job.
+	RequestID string `json:"-"`
+	// Priority is normal or high; high jobs are taken first.
+	Priority string `json:"priority"`
+	// RunAt is when a SCHEDULED job is queued; nil for jobs queued on
+	// creation.
+	RunAt *time.Time `json:"run_at,omitempty"`
+	// Progress (0-100) and Stage follow a RUNNING job through its stages
+	// (store.JobStageParse and on); a DONE job is at 100, a FAILED or
+	// CANCELED one keeps the stage it stopped in.
+	Progress   int        `json:"progress"`
+	Stage      string     `json:"stage,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+// JobError is the structured failure of a job, stored in error_detail.
+type JobError struct {
+	Code      string `json:"code"`
+	Op        string `json:"op,omitempty"`
+	Message   string `json:"message"`
+	Retryable bool   `json:"retryable"`
+	// Attempt is the run of the job that failed, set by MarkJobFailed and
+	// FailStaleJobs.
+	Attempt int `json:"attempt"`
+}
+
+// JobOutput is a row of job_outputs with the object keys of its assets
+// (empty when the asset is gone). PublicURL and HLSPath are not stored:
+// the API fills them from the job's publications and HLS renditions.
+type JobOutput struct {
+	ID                string `json:"-"`
+	JobID             string `json:"-"`
+	Variant           int    `json:"variant"`
+	VideoAssetID      string `json:"video_asset_id"`
+	ThumbnailAssetID  string `json:"thumbnail_asset_id,omitempty"`
+	CaptionsAssetID   string `json:"captions_asset_id,omitempty"`
+	VideoObjectKey    string `json:"video_object_key,omitempty"`
+	ThumbObjectKey    string `json:"thumb_object_key,omitempty"`
+	CaptionsObjectKey string `json:"captions_object_key,omitempty"`
+	// PublicURL is where the s3 publish target serves the video.
+	PublicURL string `json:"public_url,omitempty"`
+	// HLSPath is the playlist of the video's HLS rendition, if packaged.
+	HLSPath string `json:"hls_path,omitempty"`
+}
@@ -0,0 +1,119 @@
+// Package paramschema checks a job's params against its template's
+// params_schema. Templates with strict_params reject, at submit time,
+// params the schema does not declare and params of the wrong type, instead
+// of letting the renderer ignore them or fail on them.
+package paramschema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/jsonschema"
+)
+
+// Schema is a compiled params_schema.
+type Schema struct {
+	s *jsonschema.Schema
+}
+
+// Compile parses a template's params_schema. It takes a JSON Schema of an
+// object ({"type": "object", "properties": {"text": {"type": "string"}}})
+// or the shorthand map of param name to schema ({"text": {"type":
+// "string", "nullable": true}}). strict forbids params the schema does not
+// declare. nil or JSON null has no schema: Compile returns nil.
+func Compile(raw []byte, strict bool) (*Schema, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil || doc == nil {
+		return nil, fmt.Errorf("params_schema must be an object")
+	}
+
+	if _, ok := doc["properties"]; !ok && doc["type"] != "object" {
+		doc = map[string]any{"type": "object", "properties": doc}
+	}
+	props, _ := doc["properties"].(map[string]any)
+	for name, p := range props {
+		prop, ok := p.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("params_schema.%s must be an object", name)
+		}
+		// OpenAPI-style nullable, as the shorthand examples use it
+		if prop["nullable"] == true {
+			if t, ok := prop["type"].(string); ok {
+				prop["type"] = []any{t, "null"}
+			}
+		}
+	}
+	if strict {
+		doc["additionalProperties"] = false
+	}
+
+	b, _ := json.Marshal(doc)
+	s, err := jsonschema.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{s: s}, nil
+}
+
+// Check validates params and reports each mismatch through check, with
+// field names under prefix (e.g. "params"). A nil Schema accepts
+// anything.
+func (s *Schema) Check(prefix string, params map[string]any, check func(ok bool, field, message string)) {
+	if s == nil {
+		return
+	}
+	if params == nil {
+		params = map[string]any{}
+	}
+	for _, e := range s.s.Validate(params) {
+		field := prefix
+		if e.Path != "" {
+			field = prefix + "." + e.Path
+		}
+		msg := e.Message
+		if msg == "is not allowed" {
+			msg = "is not a param of the template"
+		}
+		check(false, field, field+" "+msg)
+	}
+}
+
+// Validate is Check returning the mismatches as validation errors, nil if
+// there are none.
+func (s *Schema) Validate(prefix string, params map[string]any) error {
+	var errs []error
+	s.Check(prefix, params, func(ok bool, field, message string) {
+		if !ok {
+			errs = append(errs, errors.ValidationField(field, message))
+		}
+	})
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.Aggregate(errs...)
+}
+
+// CheckTemplate checks a strict template's params_schema and that its
+// defaults match it; non-strict templates are not checked. Field names are
+// "params_schema" and "defaults.<param>".
+func CheckTemplate(strict bool, rawSchema, rawDefaults []byte, check func(ok bool, field, message string)) {
+	if !strict {
+		return
+	}
+	if len(rawSchema) == 0 || string(rawSchema) == "null" {
+		check(false, "params_schema", "params_schema is required with strict_params")
+		return
+	}
+	s, err := Compile(rawSchema, true)
+	if err != nil {
+		check(false, "params_schema", err.Error())
+		return
+	}
+	var defaults map[string]any
+	_ = json.Unmarshal(rawDefaults, &defaults)
+	s.Check("defaults", defaults, check)
+}
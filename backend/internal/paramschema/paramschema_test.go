@@ -0,0 +1,113 @@
+package paramschema
+
+import (
+	"reflect"
+	"testing"
+
+	"gala/internal/pkg/errors"
+)
+
+// failures collects what a check reports, as "field: message".
+func failures(s *Schema, params map[string]any) []string {
+	var got []string
+	s.Check("params", params, func(ok bool, field, message string) {
+		if !ok {
+			got = append(got, field+": "+message)
+		}
+	})
+	return got
+}
+
+func TestCompileForms(t *testing.T) {
+	forms := map[string]string{
+		"json schema": `{"type": "object", "properties": {"text": {"type": "string"}, "captions": {"type": "boolean"}}}`,
+		"shorthand":   `{"text": {"type": "string"}, "captions": {"type": "boolean"}}`,
+	}
+	for name, raw := range forms {
+		s, err := Compile([]byte(raw), true)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := failures(s, map[string]any{"text": "hola", "captions": true}); got != nil {
+			t.Errorf("%s: valid params rejected: %v", name, got)
+		}
+		want := []string{
+			"params.captions: params.captions must be boolean",
+			"params.colour: params.colour is not a param of the template",
+		}
+		if got := failures(s, map[string]any{"captions": "yes", "colour": "red"}); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: failures = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCompileNotStrictAllowsUnknown(t *testing.T) {
+	s, err := Compile([]byte(`{"text": {"type": "string"}}`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := failures(s, map[string]any{"text": "x", "other": 1}); got != nil {
+		t.Errorf("failures = %v", got)
+	}
+}
+
+func TestNullable(t *testing.T) {
+	s, err := Compile([]byte(`{"bg_asset_id": {"type": "string", "nullable": true}}`), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := failures(s, map[string]any{"bg_asset_id": nil}); got != nil {
+		t.Errorf("null rejected: %v", got)
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, raw := range []string{`[]`, `"x"`, `{"text": "string"}`} {
+		if _, err := Compile([]byte(raw), true); err == nil {
+			t.Errorf("Compile(%s) succeeded", raw)
+		}
+	}
+	if s, err := Compile(nil, true); s != nil || err != nil {
+		t.Errorf("Compile(nil) = %v, %v", s, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var none *Schema
+	if err := none.Validate("params", map[string]any{"x": 1}); err != nil {
+		t.Errorf("nil schema: %v", err)
+	}
+
+	s, _ := Compile([]byte(`{"text": {"type": "string"}}`), true)
+	if err := s.Validate("params", map[string]any{"text": "x"}); err != nil {
+		t.Errorf("valid params: %v", err)
+	}
+	err := s.Validate("params", map[string]any{"text": 1, "x": 1})
+	if !errors.IsValidation(err) || len(errors.Causes(err)) != 2 {
+		t.Errorf("err = %v, want 2 validation errors", err)
+	}
+}
+
+func TestCheckTemplate(t *testing.T) {
+	run := func(strict bool, schema, defaults string) []string {
+		var got []string
+		CheckTemplate(strict, []byte(schema), []byte(defaults), func(ok bool, field, message string) {
+			if !ok {
+				got = append(got, field)
+			}
+		})
+		return got
+	}
+	if got := run(false, "", `{"x": 1}`); got != nil {
+		t.Errorf("non-strict checked: %v", got)
+	}
+	if got := run(true, "", ""); !reflect.DeepEqual(got, []string{"params_schema"}) {
+		t.Errorf("strict without schema: %v", got)
+	}
+	if got := run(true, `{"text": {"type": "string"}}`, `{"text": 1, "x": 1}`); !reflect.DeepEqual(got, []string{"defaults.text", "defaults.x"}) {
+		t.Errorf("bad defaults: %v", got)
+	}
+	if got := run(true, `{"text": {"type": "string"}}`, `{"text": "hola"}`); got != nil {
+		t.Errorf("valid template: %v", got)
+	}
+}
@@ -0,0 +1,41 @@
+package awsv4
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The example request of the AWS Signature Version 4 documentation.
+func TestSignDocumentationExample(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	creds := Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	Sign(req, creds, "us-east-1", "iam", EmptyPayload, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization\n got: %s\nwant: %s", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("X-Amz-Date: got %s", got)
+	}
+}
+
+func TestSignSessionToken(t *testing.T) {
+	req, _ := http.NewRequest("PUT", "https://bucket.s3.eu-west-1.amazonaws.com/renders/a.mp4", nil)
+	req.Header.Set("X-Amz-Content-Sha256", UnsignedPayload)
+	Sign(req, Credentials{AccessKeyID: "AK", SecretAccessKey: "SK", SessionToken: "tok"}, "eu-west-1", "s3", UnsignedPayload, time.Now())
+
+	if req.Header.Get("X-Amz-Security-Token") != "tok" {
+		t.Error("session token header not set")
+	}
+	auth := req.Header.Get("Authorization")
+	if !strings.Contains(auth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,") {
+		t.Errorf("unexpected signed headers: %s", auth)
+	}
+}
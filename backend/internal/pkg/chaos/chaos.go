@@ -0,0 +1,154 @@
+// Package chaos injects faults into the storage provider, the renderer
+// client and Redis at configurable rates, so that retries, the renderer
+// breaker and cleanup can be exercised in staging before production
+// depends on them. It is off unless CHAOS_ENABLED is set; a nil *Injector
+// injects nothing.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"gala/internal/pkg/logger"
+)
+
+// ErrInjected is the cause of every injected fault.
+var ErrInjected = errors.New("chaos: injected fault")
+
+// DefaultRendererHang bounds a renderer timeout when Config.RendererHang
+// is 0 and the call has no sooner deadline.
+const DefaultRendererHang = 30 * time.Second
+
+// Config sets the fault rates, each a probability between 0 and 1.
+type Config struct {
+	// StorageErrorRate fails storage provider calls.
+	StorageErrorRate float64
+	// RendererErrorRate answers render requests with a 500.
+	RendererErrorRate float64
+	// RendererTimeoutRate makes render requests hang until their deadline
+	// or RendererHang, then fail.
+	RendererTimeoutRate float64
+	RendererHang        time.Duration
+	// RedisDropRate fails Redis commands as if the connection dropped.
+	RedisDropRate float64
+}
+
+// Validate checks that the rates are probabilities.
+func (c Config) Validate() error {
+	for name, rate := range map[string]float64{
+		"storage error rate":    c.StorageErrorRate,
+		"renderer error rate":   c.RendererErrorRate,
+		"renderer timeout rate": c.RendererTimeoutRate,
+		"redis drop rate":       c.RedisDropRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("chaos: %s %v is not between 0 and 1", name, rate)
+		}
+	}
+	return nil
+}
+
+// Injector decides which calls fail.
+type Injector struct {
+	cfg Config
+	log *logger.Logger
+	// rand draws in [0, 1); tests replace it.
+	rand func() float64
+}
+
+// New creates an injector for cfg.
+func New(cfg Config, log *logger.Logger) *Injector {
+	if cfg.RendererHang <= 0 {
+		cfg.RendererHang = DefaultRendererHang
+	}
+	if log == nil {
+		log = logger.NewDefault()
+	}
+	return &Injector{cfg: cfg, log: log.WithComponent("chaos"), rand: rand.Float64}
+}
+
+// Config returns the rates of i.
+func (i *Injector) Config() Config {
+	return i.cfg
+}
+
+// roll reports whether a call of op on target fails at rate, and logs the
+// faults it injects. The public methods check for a nil i.
+func (i *Injector) roll(ctx context.Context, rate float64, target, op string) bool {
+	if rate <= 0 || i.rand() >= rate {
+		return false
+	}
+	i.log.FromContext(ctx).Warn("injecting fault", "target", target, "op", op)
+	return true
+}
+
+// StorageFault returns the error to fail the storage call op with, or nil.
+func (i *Injector) StorageFault(ctx context.Context, op string) error {
+	if i == nil || !i.roll(ctx, i.cfg.StorageErrorRate, "storage", op) {
+		return nil
+	}
+	return fmt.Errorf("storage %s: %w", op, ErrInjected)
+}
+
+// RendererFault reports whether a render should answer 500.
+func (i *Injector) RendererFault(ctx context.Context) bool {
+	return i != nil && i.roll(ctx, i.cfg.RendererErrorRate, "renderer", "error")
+}
+
+// RendererHang, if a render should time out, blocks until ctx ends or the
+// configured hang passes and returns the error to fail it with; it
+// returns nil at once otherwise.
+func (i *Injector) RendererHang(ctx context.Context) error {
+	if i == nil || !i.roll(ctx, i.cfg.RendererTimeoutRate, "renderer", "timeout") {
+		return nil
+	}
+	t := time.NewTimer(i.cfg.RendererHang)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("renderer timeout: %w: %w", ErrInjected, ctx.Err())
+	case <-t.C:
+		return fmt.Errorf("renderer timeout: %w", ErrInjected)
+	}
+}
+
+// RedisHook returns a hook that fails Redis commands and pipelines at the
+// drop rate, before they reach the server. i must not be nil.
+func (i *Injector) RedisHook() redis.Hook {
+	return redisHook{i}
+}
+
+type redisHook struct{ i *Injector }
+
+func (h redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if h.i.roll(ctx, h.i.cfg.RedisDropRate, "redis", cmd.Name()) {
+			err := fmt.Errorf("redis %s: %w", cmd.Name(), ErrInjected)
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if h.i.roll(ctx, h.i.cfg.RedisDropRate, "redis", "pipeline") {
+			err := fmt.Errorf("redis pipeline: %w", ErrInjected)
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		return next(ctx, cmds)
+	}
+}
@@ -0,0 +1,81 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fixed returns an injector whose draws are always v.
+func fixed(cfg Config, v float64) *Injector {
+	i := New(cfg, nil)
+	i.rand = func() float64 { return v }
+	return i
+}
+
+func TestNilInjectorInjectsNothing(t *testing.T) {
+	var i *Injector
+	ctx := context.Background()
+	if err := i.StorageFault(ctx, "put"); err != nil {
+		t.Errorf("StorageFault = %v", err)
+	}
+	if i.RendererFault(ctx) {
+		t.Error("RendererFault = true")
+	}
+	if err := i.RendererHang(ctx); err != nil {
+		t.Errorf("RendererHang = %v", err)
+	}
+}
+
+func TestRates(t *testing.T) {
+	ctx := context.Background()
+	cfg := Config{StorageErrorRate: 0.5, RendererErrorRate: 0}
+
+	if err := fixed(cfg, 0.49).StorageFault(ctx, "put"); !errors.Is(err, ErrInjected) {
+		t.Errorf("draw under the rate: StorageFault = %v, want ErrInjected", err)
+	}
+	if err := fixed(cfg, 0.5).StorageFault(ctx, "put"); err != nil {
+		t.Errorf("draw at the rate: StorageFault = %v, want nil", err)
+	}
+	if fixed(cfg, 0).RendererFault(ctx) {
+		t.Error("rate 0 injected a renderer fault")
+	}
+}
+
+func TestRendererHangEndsWithContext(t *testing.T) {
+	i := fixed(Config{RendererTimeoutRate: 1, RendererHang: time.Hour}, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := i.RendererHang(ctx)
+	if !errors.Is(err, ErrInjected) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RendererHang = %v, want ErrInjected and the deadline", err)
+	}
+}
+
+func TestRedisHookFailsCommands(t *testing.T) {
+	h := fixed(Config{RedisDropRate: 1}, 0).RedisHook()
+	called := false
+	process := h.ProcessHook(func(context.Context, redis.Cmder) error {
+		called = true
+		return nil
+	})
+	cmd := redis.NewStatusCmd(context.Background(), "ping")
+	if err := process(context.Background(), cmd); !errors.Is(err, ErrInjected) || !errors.Is(cmd.Err(), ErrInjected) {
+		t.Errorf("process = %v, cmd.Err = %v; want ErrInjected", err, cmd.Err())
+	}
+	if called {
+		t.Error("dropped command reached the next hook")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (Config{StorageErrorRate: 1, RedisDropRate: 0.1}).Validate(); err != nil {
+		t.Errorf("Validate = %v", err)
+	}
+	if err := (Config{RendererErrorRate: 1.5}).Validate(); err == nil {
+		t.Error("Validate accepted a rate above 1")
+	}
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// QueryTimeout is a pgx.QueryTracer that bounds every statement run on a
+// connection, so a stuck query gives its connection back to the pool
+// instead of holding it until the request's own deadline. Install it as
+// the ConnConfig.Tracer of the pool (dbpool.Config.QueryTimeout).
+//
+// The timeout covers the statement and, for Query, reading its rows until
+// they are closed. A query timing out fails with context.DeadlineExceeded,
+// which pgerr translates to CodeTimeout. Callers that legitimately run
+// longer (streams, migrations) set their own limit with WithQueryTimeout.
+type QueryTimeout struct {
+	// Default applies to statements whose context has no WithQueryTimeout.
+	Default time.Duration
+}
+
+// DefaultQueryTimeout is the per-statement limit used when none is
+// configured: well under the API's request timeouts.
+const DefaultQueryTimeout = 10 * time.Second
+
+type queryTimeoutKey struct{}
+
+type cancelKey struct{}
+
+// WithQueryTimeout overrides the QueryTimeout default for the statements
+// run with ctx; d <= 0 runs them without one (ctx's own deadline still
+// applies).
+func WithQueryTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, queryTimeoutKey{}, d)
+}
+
+// TraceQueryStart derives the statement's context with the timeout.
+func (t QueryTimeout) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	d := t.Default
+	if v, ok := ctx.Value(queryTimeoutKey{}).(time.Duration); ok {
+		d = v
+	}
+	if d <= 0 {
+		return ctx
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	return context.WithValue(ctx, cancelKey{}, cancel)
+}
+
+// TraceQueryEnd releases the timer once the statement (or its rows) is
+// done.
+func (t QueryTimeout) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryEndData) {
+	if cancel, ok := ctx.Value(cancelKey{}).(context.CancelFunc); ok {
+		cancel()
+	}
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestQueryTimeoutDefault(t *testing.T) {
+	tr := QueryTimeout{Default: time.Second}
+	ctx := tr.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{})
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) > time.Second {
+		t.Fatalf("deadline = %v, %v; want one within 1s", deadline, ok)
+	}
+
+	tr.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("ctx.Err() = %v after TraceQueryEnd, want context.Canceled", ctx.Err())
+	}
+}
+
+func TestQueryTimeoutOverride(t *testing.T) {
+	tr := QueryTimeout{Default: time.Second}
+
+	ctx := tr.TraceQueryStart(WithQueryTimeout(context.Background(), 0), nil, pgx.TraceQueryStartData{})
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("expected no deadline with WithQueryTimeout(ctx, 0)")
+	}
+	tr.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	ctx = tr.TraceQueryStart(WithQueryTimeout(context.Background(), time.Hour), nil, pgx.TraceQueryStartData{})
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) < 59*time.Minute {
+		t.Errorf("deadline = %v, %v; want the 1h override", deadline, ok)
+	}
+	tr.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+}
+
+func TestQueryTimeoutKeepsEarlierDeadline(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	ctx := QueryTimeout{Default: time.Hour}.TraceQueryStart(parent, nil, pgx.TraceQueryStartData{})
+	<-ctx.Done()
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("ctx.Err() = %v, want the parent's deadline", ctx.Err())
+	}
+}
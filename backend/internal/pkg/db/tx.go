@@ -0,0 +1,63 @@
+// Package db holds helpers shared by code that talks to Postgres.
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier runs statements. *pgxpool.Pool, *pgxpool.Conn, *pgx.Conn and
+// pgx.Tx all satisfy it, so a function taking a Querier works both on its
+// own and as part of a caller's transaction.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Beginner starts a transaction. On a pool or connection that is a real
+// transaction; on a pgx.Tx it is a savepoint.
+type Beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// rollbackTimeout bounds the rollback after fn fails, which runs even if
+// ctx is already canceled.
+const rollbackTimeout = 5 * time.Second
+
+// WithTx runs fn in a transaction begun on b and commits it if fn returns
+// nil. If fn returns an error or panics, the transaction is rolled back
+// (and the panic re-raised).
+//
+// Passing the tx received by an outer fn nests: the inner call runs in a
+// savepoint, so its failure rolls back only its own work and the outer fn
+// can decide whether to carry on.
+func WithTx(ctx context.Context, b Beginner, fn func(tx pgx.Tx) error) (err error) {
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		rbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+		defer cancel()
+		_ = tx.Rollback(rbCtx)
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	committed = true
+	return nil
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// fakeTx records how it ends; only Begin, Commit and Rollback are used.
+type fakeTx struct {
+	pgx.Tx
+	commitErr  error
+	committed  bool
+	rolledBack bool
+	children   []*fakeTx
+}
+
+func (t *fakeTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	child := &fakeTx{}
+	t.children = append(t.children, child)
+	return child, nil
+}
+
+func (t *fakeTx) Commit(ctx context.Context) error {
+	if t.commitErr != nil {
+		return t.commitErr
+	}
+	t.committed = true
+	return nil
+}
+
+func (t *fakeTx) Rollback(ctx context.Context) error {
+	t.rolledBack = true
+	return nil
+}
+
+type fakeBeginner struct {
+	tx  *fakeTx
+	err error
+}
+
+func (b *fakeBeginner) Begin(ctx context.Context) (pgx.Tx, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.tx, nil
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("commits on success", func(t *testing.T) {
+		b := &fakeBeginner{tx: &fakeTx{}}
+		if err := WithTx(ctx, b, func(tx pgx.Tx) error { return nil }); err != nil {
+			t.Fatalf("WithTx: %v", err)
+		}
+		if !b.tx.committed || b.tx.rolledBack {
+			t.Errorf("committed=%v rolledBack=%v, want commit only", b.tx.committed, b.tx.rolledBack)
+		}
+	})
+
+	t.Run("rolls back on error", func(t *testing.T) {
+		b := &fakeBeginner{tx: &fakeTx{}}
+		boom := errors.New("boom")
+		if err := WithTx(ctx, b, func(tx pgx.Tx) error { return boom }); !errors.Is(err, boom) {
+			t.Fatalf("WithTx error = %v, want %v", err, boom)
+		}
+		if b.tx.committed || !b.tx.rolledBack {
+			t.Errorf("committed=%v rolledBack=%v, want rollback only", b.tx.committed, b.tx.rolledBack)
+		}
+	})
+
+	t.Run("rolls back and re-panics", func(t *testing.T) {
+		b := &fakeBeginner{tx: &fakeTx{}}
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic to propagate")
+			}
+			if !b.tx.rolledBack {
+				t.Error("expected rollback after panic")
+			}
+		}()
+		_ = WithTx(ctx, b, func(tx pgx.Tx) error { panic("boom") })
+	})
+
+	t.Run("commit failure", func(t *testing.T) {
+		commitErr := errors.New("serialization failure")
+		b := &fakeBeginner{tx: &fakeTx{commitErr: commitErr}}
+		if err := WithTx(ctx, b, func(tx pgx.Tx) error { return nil }); !errors.Is(err, commitErr) {
+			t.Fatalf("WithTx error = %v, want %v", err, commitErr)
+		}
+	})
+
+	t.Run("begin failure", func(t *testing.T) {
+		beginErr := errors.New("no connection")
+		called := false
+		err := WithTx(ctx, &fakeBeginner{err: beginErr}, func(tx pgx.Tx) error {
+			called = true
+			return nil
+		})
+		if !errors.Is(err, beginErr) || called {
+			t.Errorf("err=%v called=%v, want begin error and fn not called", err, called)
+		}
+	})
+
+	t.Run("nested failure rolls back only the savepoint", func(t *testing.T) {
+		b := &fakeBeginner{tx: &fakeTx{}}
+		err := WithTx(ctx, b, func(tx pgx.Tx) error {
+			_ = WithTx(ctx, tx, func(tx pgx.Tx) error { return errors.New("inner") })
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithTx: %v", err)
+		}
+		inner := b.tx.children[0]
+		if !inner.rolledBack || inner.committed {
+			t.Errorf("savepoint committed=%v rolledBack=%v, want rollback", inner.committed, inner.rolledBack)
+		}
+		if !b.tx.committed {
+			t.Error("expected outer transaction to commit")
+		}
+	})
+}
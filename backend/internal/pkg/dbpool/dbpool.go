@@ -0,0 +1,160 @@
+// Package dbpool builds the pgx connection pool from configuration and
+// reports its saturation.
+package dbpool
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"gala/internal/pkg/db"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/metrics"
+)
+
+// Config overrides pgxpool defaults. Zero values keep the default (or the
+// pool_* parameter of the connection string, if present).
+type Config struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	// QueryTimeout bounds each statement (see db.QueryTimeout); 0 leaves
+	// statements bounded only by their context.
+	QueryTimeout time.Duration
+}
+
+// New parses dsn, applies cfg and creates the pool. Like pgxpool.New it
+// does not wait for a connection; ping the pool to check connectivity.
+func New(ctx context.Context, dsn string, cfg Config) (*pgxpool.Pool, error) {
+	pc, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parse database url: %w", err)
+	}
+	if err := cfg.apply(pc); err != nil {
+		return nil, err
+	}
+	return pgxpool.NewWithConfig(ctx, pc)
+}
+
+func (c Config) apply(pc *pgxpool.Config) error {
+	if c.MaxConns > 0 {
+		pc.MaxConns = c.MaxConns
+	}
+	if c.MinConns > 0 {
+		pc.MinConns = c.MinConns
+	}
+	if pc.MinConns > pc.MaxConns {
+		return fmt.Errorf("min conns (%d) exceeds max conns (%d)", pc.MinConns, pc.MaxConns)
+	}
+	if c.MaxConnLifetime > 0 {
+		pc.MaxConnLifetime = c.MaxConnLifetime
+	}
+	if c.MaxConnIdleTime > 0 {
+		pc.MaxConnIdleTime = c.MaxConnIdleTime
+	}
+	if c.HealthCheckPeriod > 0 {
+		pc.HealthCheckPeriod = c.HealthCheckPeriod
+	}
+	if c.QueryTimeout > 0 {
+		pc.ConnConfig.Tracer = db.QueryTimeout{Default: c.QueryTimeout}
+	}
+	return nil
+}
+
+// SaturationWarn is the acquired/max ratio from which Monitor warns.
+const SaturationWarn = 0.9
+
+// Monitor samples pool stats every interval until ctx is canceled. It
+// exports them as gauges when reg is not nil and logs a warning when the
+// pool is close to saturation or callers had to wait for a connection.
+// It blocks, so run it in its own goroutine.
+func Monitor(ctx context.Context, pool *pgxpool.Pool, log *logger.Logger, reg *metrics.Registry, interval time.Duration) {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	m := newMonitor(log.WithComponent("dbpool"), reg)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s := pool.Stat()
+			m.record(stats{
+				acquired:     s.AcquiredConns(),
+				idle:         s.IdleConns(),
+				total:        s.TotalConns(),
+				max:          s.MaxConns(),
+				emptyAcquire: s.EmptyAcquireCount(),
+			})
+		}
+	}
+}
+
+// stats is the subset of pgxpool.Stat that Monitor reports.
+type stats struct {
+	acquired, idle, total, max int32
+	emptyAcquire               int64
+}
+
+type monitor struct {
+	log *logger.Logger
+
+	conns      *metrics.GaugeVec
+	maxConns   *metrics.GaugeVec
+	saturation *metrics.GaugeVec
+	waits      *metrics.CounterVec
+
+	last stats
+}
+
+func newMonitor(log *logger.Logger, reg *metrics.Registry) *monitor {
+	m := &monitor{log: log}
+	if reg != nil {
+		m.conns = reg.NewGaugeVec("gala_db_pool_connections", "Database pool connections by state.", "state")
+		m.maxConns = reg.NewGaugeVec("gala_db_pool_max_connections", "Database pool size limit.")
+		m.saturation = reg.NewGaugeVec("gala_db_pool_saturation", "Acquired connections divided by the pool size limit.")
+		m.waits = reg.NewCounterVec("gala_db_pool_empty_acquires_total", "Acquires that waited because the pool was empty, since start.")
+	}
+	return m
+}
+
+func (m *monitor) record(s stats) {
+	saturation := 0.0
+	if s.max > 0 {
+		saturation = float64(s.acquired) / float64(s.max)
+	}
+
+	waited := s.emptyAcquire - m.last.emptyAcquire
+	if m.conns != nil {
+		m.conns.Set(float64(s.acquired), "acquired")
+		m.conns.Set(float64(s.idle), "idle")
+		m.conns.Set(float64(s.total), "total")
+		m.maxConns.Set(float64(s.max))
+		m.saturation.Set(saturation)
+		if waited > 0 {
+			m.waits.Add(float64(waited))
+		}
+	}
+
+	args := []any{
+		"acquired", s.acquired,
+		"idle", s.idle,
+		"total", s.total,
+		"max", s.max,
+		"saturation", saturation,
+		"empty_acquires", waited,
+	}
+	if saturation >= SaturationWarn || waited > 0 {
+		m.log.Warn("database pool saturated", args...)
+	} else {
+		m.log.Debug("database pool stats", args...)
+	}
+	m.last = s
+}
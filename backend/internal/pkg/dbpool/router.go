@@ -0,0 +1,106 @@
+package dbpool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"gala/internal/pkg/logger"
+)
+
+// Router sends reads that tolerate replication lag to read replicas and
+// everything else to the primary. Writes, job state transitions and reads
+// that must see the caller's own writes (e.g. GET /jobs/{id} right after
+// POST /jobs) use Primary; list and export endpoints use Reader.
+type Router struct {
+	primary  *pgxpool.Pool
+	replicas []*replica
+	next     atomic.Uint32
+}
+
+type replica struct {
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// NewRouter creates a router. With no replicas Reader returns the primary.
+// Replicas start healthy; Watch keeps that up to date.
+func NewRouter(primary *pgxpool.Pool, replicas ...*pgxpool.Pool) *Router {
+	r := &Router{primary: primary}
+	for _, p := range replicas {
+		rep := &replica{pool: p}
+		rep.healthy.Store(true)
+		r.replicas = append(r.replicas, rep)
+	}
+	return r
+}
+
+// Primary returns the primary pool.
+func (r *Router) Primary() *pgxpool.Pool {
+	return r.primary
+}
+
+// Reader returns a healthy replica (round-robin), or the primary if there
+// is none.
+func (r *Router) Reader() *pgxpool.Pool {
+	n := len(r.replicas)
+	if n == 0 {
+		return r.primary
+	}
+	start := int(r.next.Add(1))
+	for i := range n {
+		if rep := r.replicas[(start+i)%n]; rep.healthy.Load() {
+			return rep.pool
+		}
+	}
+	return r.primary
+}
+
+// Replicas returns the replica pools, e.g. to close them on shutdown.
+func (r *Router) Replicas() []*pgxpool.Pool {
+	out := make([]*pgxpool.Pool, len(r.replicas))
+	for i, rep := range r.replicas {
+		out[i] = rep.pool
+	}
+	return out
+}
+
+// Watch pings every replica each interval until ctx is canceled, taking
+// unreachable ones out of rotation and putting them back once they
+// answer. It blocks, so run it in its own goroutine.
+func (r *Router) Watch(ctx context.Context, log *logger.Logger, interval time.Duration) {
+	if len(r.replicas) == 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	log = log.WithComponent("dbpool")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for i, rep := range r.replicas {
+			pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			err := rep.pool.Ping(pingCtx)
+			cancel()
+
+			healthy := err == nil
+			if rep.healthy.Swap(healthy) == healthy {
+				continue
+			}
+			if healthy {
+				log.Info("read replica back in rotation", "replica", i)
+			} else {
+				log.Warn("read replica out of rotation", "replica", i, "error", err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Aggregate combines multiple errors into a single *Error.
+//
+// nil entries are skipped. It returns nil if no errors remain and the error
+// itself (converted to *Error) if only one remains. Otherwise the result:
+//   - has the shared code if all errors agree, else the code with the
+//     highest HTTP status (so one internal error beats many validation ones),
+//   - keeps every individual error in Causes,
+//   - exposes them under Fields["errors"] as code/message/details entries,
+//     ready to serialize in an HTTP error envelope,
+//   - unwraps to all originals, so errors.Is/As see each of them.
+//
+// Nested aggregates are flattened.
+func Aggregate(errs ...error) *Error {
+	causes := make([]*Error, 0, len(errs))
+	originals := make([]error, 0, len(errs))
+
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		var e *Error
+		if !errors.As(err, &e) {
+			e = &Error{Code: CodeInternal, Message: err.Error(), Err: err}
+		}
+		if len(e.Causes) > 0 {
+			causes = append(causes, e.Causes...)
+		} else {
+			causes = append(causes, e)
+		}
+		originals = append(originals, err)
+	}
+
+	switch len(causes) {
+	case 0:
+		return nil
+	case 1:
+		return causes[0]
+	}
+
+	code := causes[0].Code
+	for _, c := range causes[1:] {
+		if c.Code == code {
+			continue
+		}
+		if c.HTTPStatus() > (&Error{Code: code}).HTTPStatus() {
+			code = c.Code
+		}
+	}
+
+	details := make([]map[string]any, 0, len(causes))
+	for _, c := range causes {
+		item := map[string]any{
+			"code":    string(c.Code),
+			"message": c.Message,
+		}
+		if len(c.Fields) > 0 {
+			item["details"] = c.Fields
+		}
+		details = append(details, item)
+	}
+
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf("%d errors occurred", len(causes)),
+		Err:     errors.Join(originals...),
+		Fields:  map[string]any{"errors": details},
+		Causes:  causes,
+		pcs:     callers(code, 2),
+	}
+}
+
+// Causes returns the individual errors of an aggregate, or the error itself
+// as a single-element slice if it is not an aggregate.
+func Causes(err error) []*Error {
+	var e *Error
+	if !errors.As(err, &e) {
+		if err == nil {
+			return nil
+		}
+		return []*Error{{Code: CodeInternal, Message: err.Error(), Err: err}}
+	}
+	if len(e.Causes) > 0 {
+		return e.Causes
+	}
+	return []*Error{e}
+}
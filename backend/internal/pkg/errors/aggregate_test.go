@@ -0,0 +1,132 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAggregateEmpty(t *testing.T) {
+	if err := Aggregate(); err != nil {
+		t.Errorf("expected nil for no errors, got %v", err)
+	}
+	if err := Aggregate(nil, nil); err != nil {
+		t.Errorf("expected nil for only nil errors, got %v", err)
+	}
+}
+
+func TestAggregateSingle(t *testing.T) {
+	original := ValidationField("name", "name is required")
+	agg := Aggregate(nil, original)
+
+	if agg != original {
+		t.Error("expected single error to be returned as-is")
+	}
+}
+
+func TestAggregateSameCode(t *testing.T) {
+	agg := Aggregate(
+		ValidationField("name", "name is required"),
+		ValidationField("type", "type is required"),
+	)
+
+	if agg.Code != CodeValidation {
+		t.Errorf("expected code=%s, got %s", CodeValidation, agg.Code)
+	}
+	if agg.HTTPStatus() != 400 {
+		t.Errorf("expected status=400, got %d", agg.HTTPStatus())
+	}
+	if len(agg.Causes) != 2 {
+		t.Fatalf("expected 2 causes, got %d", len(agg.Causes))
+	}
+
+	items, ok := agg.Fields["errors"].([]map[string]any)
+	if !ok || len(items) != 2 {
+		t.Fatalf("expected 2 error details, got %#v", agg.Fields["errors"])
+	}
+	if items[1]["code"] != string(CodeValidation) {
+		t.Errorf("expected detail code=%s, got %v", CodeValidation, items[1]["code"])
+	}
+	details, _ := items[1]["details"].(map[string]any)
+	if details["field"] != "type" {
+		t.Errorf("expected detail field='type', got %v", details["field"])
+	}
+}
+
+func TestAggregateMixedCodes(t *testing.T) {
+	agg := Aggregate(
+		Validation("bad input"),
+		fmt.Errorf("upload failed"),
+		NotFound("asset", "ast_1"),
+	)
+
+	if agg.Code != CodeInternal {
+		t.Errorf("expected most severe code=%s, got %s", CodeInternal, agg.Code)
+	}
+
+	codes := []Code{}
+	for _, c := range agg.Causes {
+		codes = append(codes, c.Code)
+	}
+	want := []Code{CodeValidation, CodeInternal, CodeNotFound}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Errorf("expected cause %d code=%s, got %s", i, want[i], codes[i])
+		}
+	}
+}
+
+func TestAggregateFlattensAndUnwraps(t *testing.T) {
+	sentinel := fmt.Errorf("disk full")
+	inner := Aggregate(Validation("a"), Validation("b"))
+	agg := Aggregate(inner, Wrap(sentinel, "upload", "upload failed"))
+
+	if len(agg.Causes) != 3 {
+		t.Errorf("expected nested aggregate to be flattened to 3 causes, got %d", len(agg.Causes))
+	}
+	if !errors.Is(agg, sentinel) {
+		t.Error("expected errors.Is to find an aggregated error")
+	}
+}
+
+func TestCauses(t *testing.T) {
+	agg := Aggregate(Validation("a"), Conflict("b"))
+	wrapped := Wrap(agg, "handler", "request failed")
+
+	if got := len(Causes(wrapped)); got != 2 {
+		t.Errorf("expected causes to survive Wrap, got %d", got)
+	}
+	if got := len(Causes(Validation("x"))); got != 1 {
+		t.Errorf("expected single error to yield 1 cause, got %d", got)
+	}
+	if Causes(nil) != nil {
+		t.Error("expected nil causes for nil error")
+	}
+}
+
+func TestAggregateFieldsSerialize(t *testing.T) {
+	agg := Aggregate(
+		ValidationField("name", "required"),
+		ValidationField("params.text", "required"),
+	)
+
+	b, err := json.Marshal(agg.Fields)
+	if err != nil {
+		t.Fatalf("expected fields to marshal, got %v", err)
+	}
+
+	var decoded struct {
+		Errors []struct {
+			Code    string         `json:"code"`
+			Message string         `json:"message"`
+			Details map[string]any `json:"details"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if len(decoded.Errors) != 2 || decoded.Errors[1].Details["field"] != "params.text" {
+		t.Errorf("unexpected serialized aggregate: %s", b)
+	}
+}
@@ -0,0 +1,75 @@
+package errors
+
+import (
+	"sort"
+	"sync"
+)
+
+// CodeInfo describes an error code for the public catalog.
+type CodeInfo struct {
+	Code        Code   `json:"code"`
+	HTTPStatus  int    `json:"http_status"`
+	Description string `json:"description"`
+	// Retryable tells clients the same request may succeed later.
+	Retryable bool `json:"retryable"`
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[Code]CodeInfo{}
+)
+
+func init() {
+	for _, info := range []CodeInfo{
+		{CodeInternal, 500, "Unexpected server error.", false},
+		{CodeValidation, 400, "The request failed validation; see details for the offending field.", false},
+		{CodeNotFound, 404, "The requested resource does not exist.", false},
+		{CodeConflict, 409, "The request conflicts with the current state of the resource.", true},
+		{CodeUnauthorized, 401, "Authentication is required or has failed.", false},
+		{CodeForbidden, 403, "The caller is not allowed to perform this operation.", false},
+		{CodeTimeout, 504, "The operation did not complete in time.", true},
+		{CodeUnavailable, 503, "A dependency is temporarily unavailable.", true},
+		{CodeBadRequest, 400, "The request is malformed.", false},
+		{CodeAlreadyExists, 409, "A resource with the same identity already exists.", false},
+		{CodeFailedPrecond, 412, "The resource is not in a state that allows this operation.", false},
+		{CodeResourceExhaust, 429, "A quota or rate limit was exceeded.", true},
+		{CodePayloadTooLarge, 413, "The request body is larger than the server accepts.", false},
+	} {
+		Register(info)
+	}
+}
+
+// Register adds or replaces a code in the catalog. Services register their
+// resource-specific codes (e.g. TEMPLATE_NOT_FOUND) at init.
+func Register(info CodeInfo) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[info.Code] = info
+}
+
+// Lookup returns the catalog entry for a code.
+func Lookup(code Code) (CodeInfo, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	info, ok := registry[code]
+	return info, ok
+}
+
+// Catalog returns all registered codes sorted by code.
+func Catalog() []CodeInfo {
+	registryMu.RLock()
+	out := make([]CodeInfo, 0, len(registry))
+	for _, info := range registry {
+		out = append(out, info)
+	}
+	registryMu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
+
+// IsRetryable reports whether err carries a retryable code.
+func IsRetryable(err error) bool {
+	info, ok := Lookup(GetCode(err))
+	return ok && info.Retryable
+}
@@ -0,0 +1,71 @@
+package errors
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCatalogContainsBuiltins(t *testing.T) {
+	codes := map[Code]CodeInfo{}
+	for _, info := range Catalog() {
+		codes[info.Code] = info
+	}
+
+	for _, code := range []Code{CodeInternal, CodeValidation, CodeNotFound, CodeUnavailable} {
+		info, ok := codes[code]
+		if !ok {
+			t.Errorf("expected %s in catalog", code)
+			continue
+		}
+		if info.Description == "" {
+			t.Errorf("expected description for %s", code)
+		}
+	}
+
+	if !codes[CodeUnavailable].Retryable {
+		t.Error("expected UNAVAILABLE to be retryable")
+	}
+	if codes[CodeValidation].Retryable {
+		t.Error("expected VALIDATION_ERROR not to be retryable")
+	}
+}
+
+func TestCatalogSorted(t *testing.T) {
+	catalog := Catalog()
+	for i := 1; i < len(catalog); i++ {
+		if catalog[i-1].Code > catalog[i].Code {
+			t.Fatalf("expected catalog sorted by code, got %s before %s", catalog[i-1].Code, catalog[i].Code)
+		}
+	}
+}
+
+func TestRegisterCustomCode(t *testing.T) {
+	const code Code = "TEST_GONE"
+	Register(CodeInfo{Code: code, HTTPStatus: 410, Description: "gone"})
+
+	err := New(code, "resource gone")
+	if err.HTTPStatus() != 410 {
+		t.Errorf("expected registered status=410, got %d", err.HTTPStatus())
+	}
+	if _, ok := Lookup(code); !ok {
+		t.Error("expected registered code to be found")
+	}
+}
+
+func TestUnknownCodeStatus(t *testing.T) {
+	if status := New("NEVER_REGISTERED", "x").HTTPStatus(); status != 500 {
+		t.Errorf("expected unknown code to map to 500, got %d", status)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	if !IsRetryable(Unavailable("redis")) {
+		t.Error("expected unavailable error to be retryable")
+	}
+	if IsRetryable(Validation("bad")) {
+		t.Error("expected validation error not to be retryable")
+	}
+	if IsRetryable(fmt.Errorf("plain")) {
+		t.Error("expected plain (internal) error not to be retryable")
+	}
+}
@@ -5,7 +5,6 @@ package errors
 import (
 	"errors"
 	"fmt"
-	"runtime"
 	"strings"
 )
 
@@ -26,6 +25,7 @@ const (
 	CodeAlreadyExists  Code = "ALREADY_EXISTS"
 	CodeFailedPrecond  Code = "FAILED_PRECONDITION"
 	CodeResourceExhaust Code = "RESOURCE_EXHAUSTED"
+	CodePayloadTooLarge Code = "PAYLOAD_TOO_LARGE"
 )
 
 // Error is a custom error type with additional context.
@@ -40,8 +40,14 @@ type Error struct {
 	Err error
 	// Fields contains additional context fields.
 	Fields map[string]any
-	// Stack contains the stack trace at error creation.
+	// Stack holds explicitly set frames. Stacks captured by the constructors
+	// are kept as raw PCs and resolved on demand; use Frames to read them.
 	Stack []Frame
+	// Causes holds the individual errors of an aggregate (see Aggregate).
+	Causes []*Error
+
+	// pcs are the program counters captured at creation (see SetStackMode).
+	pcs []uintptr
 }
 
 // Frame represents a single stack frame.
@@ -109,40 +115,24 @@ func (e *Error) WithFields(fields map[string]any) *Error {
 	return e
 }
 
-// HTTPStatus returns the appropriate HTTP status code for this error.
+// HTTPStatus returns the appropriate HTTP status code for this error,
+// as registered in the catalog (see Register). Unknown codes map to 500.
 func (e *Error) HTTPStatus() int {
-	switch e.Code {
-	case CodeValidation, CodeBadRequest:
-		return 400
-	case CodeUnauthorized:
-		return 401
-	case CodeForbidden:
-		return 403
-	case CodeNotFound:
-		return 404
-	case CodeConflict, CodeAlreadyExists:
-		return 409
-	case CodeFailedPrecond:
-		return 412
-	case CodeResourceExhaust:
-		return 429
-	case CodeTimeout:
-		return 504
-	case CodeUnavailable:
-		return 503
-	default:
-		return 500
+	if info, ok := Lookup(e.Code); ok {
+		return info.HTTPStatus
 	}
+	return 500
 }
 
 // StackTrace returns the stack trace as a formatted string.
 func (e *Error) StackTrace() string {
-	if len(e.Stack) == 0 {
+	frames := e.Frames()
+	if len(frames) == 0 {
 		return ""
 	}
 
 	var b strings.Builder
-	for _, f := range e.Stack {
+	for _, f := range frames {
 		fmt.Fprintf(&b, "  %s:%d %s\n", f.File, f.Line, f.Function)
 	}
 	return b.String()
@@ -153,7 +143,7 @@ func New(code Code, message string) *Error {
 	return &Error{
 		Code:    code,
 		Message: message,
-		Stack:   captureStack(2),
+		pcs:     callers(code, 2),
 	}
 }
 
@@ -162,7 +152,7 @@ func Newf(code Code, format string, args ...any) *Error {
 	return &Error{
 		Code:    code,
 		Message: fmt.Sprintf(format, args...),
-		Stack:   captureStack(2),
+		pcs:     callers(code, 2),
 	}
 }
 
@@ -181,7 +171,8 @@ func Wrap(err error, op string, message string) *Error {
 			Op:      op,
 			Err:     err,
 			Fields:  e.Fields,
-			Stack:   captureStack(2),
+			Causes:  e.Causes,
+			pcs:     callers(e.Code, 2),
 		}
 	}
 
@@ -190,7 +181,7 @@ func Wrap(err error, op string, message string) *Error {
 		Message: message,
 		Op:      op,
 		Err:     err,
-		Stack:   captureStack(2),
+		pcs:     callers(CodeInternal, 2),
 	}
 }
 
@@ -210,7 +201,7 @@ func WrapWithCode(err error, code Code, op string, message string) *Error {
 		Message: message,
 		Op:      op,
 		Err:     err,
-		Stack:   captureStack(2),
+		pcs:     callers(code, 2),
 	}
 }
 
@@ -317,40 +308,6 @@ func IsConflict(err error) bool {
 	return IsCode(err, CodeConflict) || IsCode(err, CodeAlreadyExists)
 }
 
-// captureStack captures the current stack trace.
-func captureStack(skip int) []Frame {
-	const maxDepth = 32
-	var pcs [maxDepth]uintptr
-	n := runtime.Callers(skip+1, pcs[:])
-
-	frames := make([]Frame, 0, n)
-	callersFrames := runtime.CallersFrames(pcs[:n])
-
-	for {
-		frame, more := callersFrames.Next()
-		
-		// Skip runtime frames
-		if strings.Contains(frame.File, "runtime/") {
-			if !more {
-				break
-			}
-			continue
-		}
-
-		frames = append(frames, Frame{
-			File:     frame.File,
-			Line:     frame.Line,
-			Function: frame.Function,
-		})
-
-		if !more || len(frames) >= 10 {
-			break
-		}
-	}
-
-	return frames
-}
-
 // As is a convenience wrapper for errors.As.
 func As(err error, target any) bool {
 	return errors.As(err, target)
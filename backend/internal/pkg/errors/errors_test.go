@@ -16,7 +16,7 @@ func TestNew(t *testing.T) {
 	if err.Message != "invalid input" {
 		t.Errorf("expected message='invalid input', got %s", err.Message)
 	}
-	if len(err.Stack) == 0 {
+	if len(err.Frames()) == 0 {
 		t.Error("expected stack trace to be captured")
 	}
 }
@@ -161,6 +161,7 @@ func TestHTTPStatus(t *testing.T) {
 		{CodeConflict, 409},
 		{CodeAlreadyExists, 409},
 		{CodeFailedPrecond, 412},
+		{CodePayloadTooLarge, 413},
 		{CodeResourceExhaust, 429},
 		{CodeInternal, 500},
 		{CodeUnavailable, 503},
@@ -0,0 +1,83 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+)
+
+// debugJSON controls whether MarshalJSON includes stack traces.
+var debugJSON atomic.Bool
+
+// SetDebug enables or disables stack traces in JSON error output.
+// Keep it off in production: stacks leak file paths and internals.
+func SetDebug(enabled bool) {
+	debugJSON.Store(enabled)
+}
+
+// envelope is the standard API error envelope:
+//
+//	{"error":{"code":"...","message":"...","details":{...}}}
+type envelope struct {
+	Error envelopeBody `json:"error"`
+}
+
+type envelopeBody struct {
+	Code    Code           `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+	Stack   []Frame        `json:"stack,omitempty"`
+}
+
+// MarshalJSON renders the error as the standard API error envelope.
+// Fields become details; error-typed values are rendered as their message
+// so they do not serialize as {}.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	body := envelopeBody{
+		Code:    e.Code,
+		Message: e.Message,
+		Details: jsonDetails(e.Fields),
+	}
+	if body.Code == "" {
+		body.Code = CodeInternal
+	}
+	if debugJSON.Load() {
+		body.Stack = e.Frames()
+	}
+	return json.Marshal(envelope{Error: body})
+}
+
+// AsError returns err as an *Error suitable for client responses. Errors
+// that are not *Error become a generic internal error so raw messages from
+// drivers or the OS are not exposed. It returns nil for a nil err.
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{
+		Code:    CodeInternal,
+		Message: "internal server error",
+		Err:     err,
+	}
+}
+
+func jsonDetails(fields map[string]any) map[string]any {
+	if len(fields) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(fields))
+	for k, v := range fields {
+		if err, ok := v.(error); ok {
+			if _, isGala := v.(*Error); !isGala {
+				out[k] = err.Error()
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
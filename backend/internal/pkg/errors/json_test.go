@@ -0,0 +1,99 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+type decodedEnvelope struct {
+	Error struct {
+		Code    string         `json:"code"`
+		Message string         `json:"message"`
+		Details map[string]any `json:"details"`
+		Stack   []Frame        `json:"stack"`
+	} `json:"error"`
+}
+
+func decode(t *testing.T, e *Error) decodedEnvelope {
+	t.Helper()
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var env decodedEnvelope
+	if err := json.Unmarshal(b, &env); err != nil {
+		t.Fatalf("unmarshal failed: %v (%s)", err, b)
+	}
+	return env
+}
+
+func TestMarshalJSONEnvelope(t *testing.T) {
+	env := decode(t, ValidationField("email", "must be valid email").
+		WithField("max", 3).
+		WithField("allowed", []string{"a", "b"}))
+
+	if env.Error.Code != string(CodeValidation) {
+		t.Errorf("expected code=%s, got %s", CodeValidation, env.Error.Code)
+	}
+	if env.Error.Message != "must be valid email" {
+		t.Errorf("expected message only (no op/code prefix), got %q", env.Error.Message)
+	}
+	if env.Error.Details["field"] != "email" {
+		t.Errorf("expected field detail, got %v", env.Error.Details["field"])
+	}
+	if env.Error.Details["max"] != float64(3) {
+		t.Errorf("expected numeric detail to survive, got %v", env.Error.Details["max"])
+	}
+	if list, ok := env.Error.Details["allowed"].([]any); !ok || len(list) != 2 {
+		t.Errorf("expected slice detail to survive, got %v", env.Error.Details["allowed"])
+	}
+	if env.Error.Stack != nil {
+		t.Error("expected no stack outside debug mode")
+	}
+}
+
+func TestMarshalJSONErrorDetails(t *testing.T) {
+	env := decode(t, Internal("boom").WithField("cause", fmt.Errorf("disk full")))
+
+	if env.Error.Details["cause"] != "disk full" {
+		t.Errorf("expected error detail rendered as message, got %v", env.Error.Details["cause"])
+	}
+}
+
+func TestMarshalJSONDefaults(t *testing.T) {
+	env := decode(t, &Error{Message: "no code"})
+
+	if env.Error.Code != string(CodeInternal) {
+		t.Errorf("expected empty code to default to %s, got %s", CodeInternal, env.Error.Code)
+	}
+	if env.Error.Details != nil {
+		t.Errorf("expected details to be omitted, got %v", env.Error.Details)
+	}
+}
+
+func TestMarshalJSONDebugStack(t *testing.T) {
+	SetDebug(true)
+	defer SetDebug(false)
+
+	env := decode(t, New(CodeInternal, "boom"))
+	if len(env.Error.Stack) == 0 {
+		t.Error("expected stack in debug mode")
+	}
+}
+
+func TestAsError(t *testing.T) {
+	if AsError(nil) != nil {
+		t.Error("expected nil for nil error")
+	}
+
+	original := NotFound("job", "job_1")
+	if AsError(fmt.Errorf("ctx: %w", original)) != original {
+		t.Error("expected wrapped *Error to be found")
+	}
+
+	plain := AsError(fmt.Errorf("pq: secret detail"))
+	if plain.Code != CodeInternal || plain.Message != "internal server error" {
+		t.Errorf("expected generic internal error, got %s %q", plain.Code, plain.Message)
+	}
+}
@@ -0,0 +1,112 @@
+package errors
+
+import (
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// StackMode controls when constructors capture a stack trace.
+type StackMode int32
+
+const (
+	// StackAll captures a stack for every error (default).
+	StackAll StackMode = iota
+	// StackErrorsOnly captures stacks only for codes mapping to 5xx, so hot
+	// validation / not-found paths stay cheap.
+	StackErrorsOnly
+	// StackOff never captures stacks.
+	StackOff
+)
+
+const (
+	maxStackDepth  = 32
+	maxStackFrames = 10
+)
+
+var stackMode atomic.Int32
+
+// SetStackMode sets the global stack capture mode.
+func SetStackMode(mode StackMode) {
+	stackMode.Store(int32(mode))
+}
+
+// GetStackMode returns the current stack capture mode.
+func GetStackMode() StackMode {
+	return StackMode(stackMode.Load())
+}
+
+// ParseStackMode parses "all", "errors" or "off" (case-insensitive).
+// Unknown values return StackAll.
+func ParseStackMode(s string) StackMode {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "off", "none", "false":
+		return StackOff
+	case "errors", "errors_only", "5xx":
+		return StackErrorsOnly
+	default:
+		return StackAll
+	}
+}
+
+// String returns the mode name accepted by ParseStackMode.
+func (m StackMode) String() string {
+	switch m {
+	case StackOff:
+		return "off"
+	case StackErrorsOnly:
+		return "errors"
+	default:
+		return "all"
+	}
+}
+
+// Frames resolves the captured stack into frames. Resolution happens on each
+// call, so errors that are never logged never pay for symbolization.
+func (e *Error) Frames() []Frame {
+	if len(e.Stack) > 0 {
+		return e.Stack
+	}
+	if len(e.pcs) == 0 {
+		return nil
+	}
+
+	frames := make([]Frame, 0, len(e.pcs))
+	callersFrames := runtime.CallersFrames(e.pcs)
+	for {
+		frame, more := callersFrames.Next()
+
+		// Skip runtime frames
+		if !strings.Contains(frame.File, "runtime/") {
+			frames = append(frames, Frame{
+				File:     frame.File,
+				Line:     frame.Line,
+				Function: frame.Function,
+			})
+		}
+
+		if !more || len(frames) >= maxStackFrames {
+			break
+		}
+	}
+	return frames
+}
+
+// callers captures raw program counters for an error with the given code,
+// honoring the stack mode. skip is relative to the caller of callers.
+func callers(code Code, skip int) []uintptr {
+	switch GetStackMode() {
+	case StackOff:
+		return nil
+	case StackErrorsOnly:
+		if info, ok := Lookup(code); ok && info.HTTPStatus < 500 {
+			return nil
+		}
+	}
+
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(skip+1, pcs[:])
+	out := make([]uintptr, n)
+	copy(out, pcs[:n])
+	return out
+}
@@ -0,0 +1,89 @@
+package errors
+
+import (
+	"strings"
+	"testing"
+)
+
+func withStackMode(t *testing.T, mode StackMode) {
+	t.Helper()
+	prev := GetStackMode()
+	SetStackMode(mode)
+	t.Cleanup(func() { SetStackMode(prev) })
+}
+
+func TestStackModeAll(t *testing.T) {
+	withStackMode(t, StackAll)
+
+	frames := New(CodeValidation, "invalid").Frames()
+	if len(frames) == 0 {
+		t.Fatal("expected frames in StackAll mode")
+	}
+	if !strings.Contains(frames[0].Function, "TestStackModeAll") {
+		t.Errorf("expected first frame to be the caller, got %s", frames[0].Function)
+	}
+}
+
+func TestStackModeErrorsOnly(t *testing.T) {
+	withStackMode(t, StackErrorsOnly)
+
+	if frames := Validation("invalid").Frames(); len(frames) != 0 {
+		t.Errorf("expected no frames for 4xx codes, got %d", len(frames))
+	}
+	if frames := Internal("boom").Frames(); len(frames) == 0 {
+		t.Error("expected frames for 5xx codes")
+	}
+	if frames := Wrap(Validation("x"), "op", "wrapped").Frames(); len(frames) != 0 {
+		t.Errorf("expected wrapped 4xx error to skip capture, got %d", len(frames))
+	}
+}
+
+func TestStackModeOff(t *testing.T) {
+	withStackMode(t, StackOff)
+
+	err := Internal("boom")
+	if len(err.Frames()) != 0 {
+		t.Error("expected no frames in StackOff mode")
+	}
+	if err.StackTrace() != "" {
+		t.Error("expected empty stack trace in StackOff mode")
+	}
+}
+
+func TestExplicitStackWins(t *testing.T) {
+	err := &Error{Code: CodeInternal, Stack: []Frame{{File: "x.go", Line: 1, Function: "x"}}}
+	if frames := err.Frames(); len(frames) != 1 || frames[0].File != "x.go" {
+		t.Errorf("expected explicit Stack to be returned, got %v", frames)
+	}
+}
+
+func TestParseStackMode(t *testing.T) {
+	tests := map[string]StackMode{
+		"off":    StackOff,
+		"OFF":    StackOff,
+		"errors": StackErrorsOnly,
+		"all":    StackAll,
+		"":       StackAll,
+		"weird":  StackAll,
+	}
+	for in, want := range tests {
+		if got := ParseStackMode(in); got != want {
+			t.Errorf("ParseStackMode(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func BenchmarkNewValidation(b *testing.B) {
+	for _, mode := range []StackMode{StackAll, StackErrorsOnly, StackOff} {
+		b.Run(mode.String(), func(b *testing.B) {
+			prev := GetStackMode()
+			SetStackMode(mode)
+			defer SetStackMode(prev)
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = ValidationField("email", "invalid")
+			}
+		})
+	}
+}
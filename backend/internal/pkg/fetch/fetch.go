@@ -0,0 +1,251 @@
+// Package fetch downloads files given by URL, such as job inputs hosted on
+// other sites, with the limits a server fetching user-supplied URLs needs:
+// https only, no private or loopback addresses, a size cap and a list of
+// allowed content types.
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Defaults of Config.
+const (
+	DefaultMaxBytes = 100 << 20
+	DefaultTimeout  = time.Minute
+)
+
+// DefaultTypes are the content types accepted when Config.AllowedTypes is
+// empty: the media a render can take as input.
+var DefaultTypes = []string{"image/*", "video/*", "audio/*"}
+
+var (
+	// ErrURL: the URL is malformed or not https.
+	ErrURL = errors.New("url must be an absolute https url")
+	// ErrAddress: the host resolves to a private, loopback or otherwise
+	// internal address.
+	ErrAddress = errors.New("url points to an address that is not allowed")
+	// ErrStatus: the server did not answer 200.
+	ErrStatus = errors.New("unexpected response status")
+	// ErrTooLarge: the file is larger than Config.MaxBytes.
+	ErrTooLarge = errors.New("file is too large")
+	// ErrType: the content type is not in Config.AllowedTypes.
+	ErrType = errors.New("content type is not allowed")
+)
+
+// Config sets the limits of a Client.
+type Config struct {
+	// MaxBytes caps the size of a file (0 = DefaultMaxBytes).
+	MaxBytes int64
+	// AllowedTypes are content types ("image/png") or type wildcards
+	// ("image/*"); empty uses DefaultTypes.
+	AllowedTypes []string
+	// Timeout bounds a whole download (0 = DefaultTimeout).
+	Timeout time.Duration
+	// AllowPrivate lets URLs reach private and loopback addresses, for
+	// development setups that serve inputs locally.
+	AllowPrivate bool
+}
+
+// Client downloads files within its Config.
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+// New creates a client. Redirects are followed (up to 5) and checked like
+// the original URL.
+func New(cfg Config) *Client {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if len(cfg.AllowedTypes) == 0 {
+		cfg.AllowedTypes = DefaultTypes
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	c := &Client{cfg: cfg}
+	c.http = &http.Client{
+		Transport: Transport(cfg.AllowPrivate),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			return checkURL(req.URL)
+		},
+	}
+	return c
+}
+
+// Transport returns an HTTP transport for user-supplied URLs: unless
+// allowPrivate, dials to private, loopback and link-local addresses fail
+// with ErrAddress. Other packages that call URLs given by users (job
+// callbacks) share it.
+func Transport(allowPrivate bool) *http.Transport {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		// Checked on the resolved address, so DNS names that point inside
+		// are refused too
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return ErrAddress
+			}
+			return nil
+		}
+	}
+	return &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		MaxIdleConnsPerHost:   2,
+	}
+}
+
+// File is a downloaded file, kept in a temporary file positioned at its
+// start. Close removes it.
+type File struct {
+	*os.File
+	ContentType string
+	Size        int64
+	// Ext is the extension of the URL's path if it matches the content
+	// type, or else one of the content type; ".bin" if it has none.
+	Ext string
+}
+
+// Close closes and removes the temporary file.
+func (f *File) Close() error {
+	err := f.File.Close()
+	_ = os.Remove(f.File.Name())
+	return err
+}
+
+// ParseURL parses rawURL and checks it is an absolute https URL, so
+// requests can be validated before anything is downloaded.
+func ParseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, ErrURL
+	}
+	if err := checkURL(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Download fetches rawURL into a temporary file. Its errors wrap the Err*
+// values, or are the network or context error.
+func (c *Client) Download(ctx context.Context, rawURL string) (*File, error) {
+	u, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, ErrURL
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// Refused dials and redirects surface as the sentinel alone
+		for _, e := range []error{ErrAddress, ErrURL} {
+			if errors.Is(err, e) {
+				return nil, e
+			}
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %d", ErrStatus, resp.StatusCode)
+	}
+	if resp.ContentLength > c.cfg.MaxBytes {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLarge, resp.ContentLength)
+	}
+	ct, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !c.allowedType(ct) {
+		return nil, fmt.Errorf("%w: %q", ErrType, ct)
+	}
+
+	tmp, err := os.CreateTemp("", "gala-fetch-*")
+	if err != nil {
+		return nil, err
+	}
+	f := &File{File: tmp, ContentType: ct, Ext: extension(u, ct)}
+	// One byte over the cap tells a too large body from one of exactly
+	// MaxBytes
+	n, err := io.Copy(tmp, io.LimitReader(resp.Body, c.cfg.MaxBytes+1))
+	if err == nil && n > c.cfg.MaxBytes {
+		err = fmt.Errorf("%w: more than %d bytes", ErrTooLarge, c.cfg.MaxBytes)
+	}
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	f.Size = n
+	return f, nil
+}
+
+func (c *Client) allowedType(ct string) bool {
+	for _, t := range c.cfg.AllowedTypes {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(ct, prefix+"/") {
+				return true
+			}
+		} else if ct == t {
+			return true
+		}
+	}
+	return false
+}
+
+func checkURL(u *url.URL) error {
+	if u.Scheme != "https" || u.Host == "" || u.User != nil {
+		return ErrURL
+	}
+	return nil
+}
+
+// publicIP reports whether ip is a global unicast address outside the
+// private ranges.
+func publicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast()
+}
+
+func extension(u *url.URL, contentType string) string {
+	// The URL's extension only counts if it agrees with the content type
+	// (not for /image.php and the like)
+	if ext := strings.ToLower(path.Ext(u.Path)); ext != "" {
+		if t, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext)); t == contentType {
+			return ext
+		}
+	}
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
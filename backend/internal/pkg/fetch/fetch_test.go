@@ -0,0 +1,117 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestClient returns a client that trusts srv and may reach it on the
+// loopback address.
+func newTestClient(srv *httptest.Server, cfg Config) *Client {
+	cfg.AllowPrivate = true
+	c := New(cfg)
+	c.http.Transport.(*http.Transport).TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+	return c
+}
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = io.WriteString(w, "png-bytes")
+	})
+	mux.HandleFunc("/render.php", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png; charset=binary")
+		_, _ = io.WriteString(w, "png-bytes")
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = io.WriteString(w, "<html></html>")
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "video/mp4")
+		// Chunked, so only the body tells the size
+		for i := 0; i < 4; i++ {
+			_, _ = io.WriteString(w, strings.Repeat("x", 10))
+			w.(http.Flusher).Flush()
+		}
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://example.com/logo.png", http.StatusFound)
+	})
+	srv := httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDownload(t *testing.T) {
+	srv := newServer(t)
+	c := newTestClient(srv, Config{})
+
+	f, err := c.Download(context.Background(), srv.URL+"/logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	b, _ := io.ReadAll(f)
+	if string(b) != "png-bytes" || f.Size != int64(len(b)) {
+		t.Errorf("body = %q, size %d", b, f.Size)
+	}
+	if f.ContentType != "image/png" || f.Ext != ".png" {
+		t.Errorf("content type %q, ext %q", f.ContentType, f.Ext)
+	}
+
+	g, err := c.Download(context.Background(), srv.URL+"/render.php")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	if g.Ext != ".png" {
+		t.Errorf("ext of a dynamic url = %q, want the content type's", g.Ext)
+	}
+}
+
+func TestDownloadErrors(t *testing.T) {
+	srv := newServer(t)
+	c := newTestClient(srv, Config{MaxBytes: 32})
+
+	tests := []struct {
+		name string
+		url  string
+		want error
+	}{
+		{"http", strings.Replace(srv.URL, "https:", "http:", 1) + "/logo.png", ErrURL},
+		{"credentials", strings.Replace(srv.URL, "https://", "https://user:pw@", 1) + "/logo.png", ErrURL},
+		{"not found", srv.URL + "/missing", ErrStatus},
+		{"type", srv.URL + "/page", ErrType},
+		{"too large", srv.URL + "/big", ErrTooLarge},
+		{"redirect to http", srv.URL + "/redirect", ErrURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := c.Download(context.Background(), tt.url)
+			if err == nil {
+				f.Close()
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadRefusesPrivateAddresses(t *testing.T) {
+	srv := newServer(t)
+	c := New(Config{})
+
+	_, err := c.Download(context.Background(), srv.URL+"/logo.png")
+	if !errors.Is(err, ErrAddress) {
+		t.Errorf("err = %v, want ErrAddress", err)
+	}
+}
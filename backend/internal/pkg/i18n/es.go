@@ -0,0 +1,39 @@
+package i18n
+
+import "gala/internal/pkg/errors"
+
+var defaultBundle = NewBundle()
+
+func init() {
+	for code, tmpls := range map[errors.Code][]string{
+		errors.CodeInternal:        {"Error interno del servidor."},
+		errors.CodeValidation:      {"El campo {field} no es válido.", "La solicitud no superó la validación."},
+		errors.CodeNotFound:        {"No se encontró {resource}: {id}", "El recurso solicitado no existe."},
+		errors.CodeConflict:        {"La solicitud entra en conflicto con el estado actual del recurso."},
+		errors.CodeUnauthorized:    {"Se requiere autenticación."},
+		errors.CodeForbidden:       {"No tienes permiso para realizar esta operación."},
+		errors.CodeTimeout:         {"La operación excedió el tiempo de espera."},
+		errors.CodeUnavailable:     {"Servicio no disponible temporalmente; inténtalo de nuevo más tarde."},
+		errors.CodeBadRequest:      {"La solicitud está mal formada."},
+		errors.CodeAlreadyExists:   {"Ya existe {resource}: {id}", "El recurso ya existe."},
+		errors.CodeFailedPrecond:   {"El recurso no está en un estado que permita esta operación."},
+		errors.CodeResourceExhaust: {"Se superó una cuota o límite de solicitudes."},
+		errors.CodePayloadTooLarge: {"El cuerpo de la solicitud supera el tamaño máximo aceptado."},
+	} {
+		for _, t := range tmpls {
+			defaultBundle.AddCode("es", code, t)
+		}
+	}
+
+	for msg, t := range map[string]string{
+		"internal server error": "Error interno del servidor.",
+	} {
+		defaultBundle.AddMessage("es", msg, t)
+	}
+}
+
+// Default returns the process-wide bundle. It ships Spanish translations
+// for the built-in codes; services add their own at init.
+func Default() *Bundle {
+	return defaultBundle
+}
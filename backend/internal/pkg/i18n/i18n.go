@@ -0,0 +1,241 @@
+// Package i18n localizes client-facing error messages.
+//
+// Translations are keyed by error code (with {param} placeholders filled
+// from the error details) or by the exact English message. Only responses
+// are translated; errors and logs keep their English text.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gala/internal/pkg/errors"
+)
+
+// DefaultLanguage is the language messages are written in.
+const DefaultLanguage = "en"
+
+// Bundle holds translations per language.
+type Bundle struct {
+	mu       sync.RWMutex
+	codes    map[string]map[errors.Code][]string
+	messages map[string]map[string]string
+}
+
+// NewBundle creates an empty bundle. DefaultLanguage is always supported.
+func NewBundle() *Bundle {
+	return &Bundle{
+		codes:    map[string]map[errors.Code][]string{},
+		messages: map[string]map[string]string{},
+	}
+}
+
+// AddCode adds a template for code in lang. Templates may reference error
+// details as {name}; a template is only used if all of its params are
+// present. Templates added for the same code are tried in order.
+func (b *Bundle) AddCode(lang string, code errors.Code, template string) {
+	lang = normalize(lang)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.codes[lang] == nil {
+		b.codes[lang] = map[errors.Code][]string{}
+	}
+	b.codes[lang][code] = append(b.codes[lang][code], template)
+}
+
+// AddMessage adds a translation of an exact English message. Message
+// translations take precedence over code templates.
+func (b *Bundle) AddMessage(lang, message, translation string) {
+	lang = normalize(lang)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.messages[lang] == nil {
+		b.messages[lang] = map[string]string{}
+	}
+	b.messages[lang][message] = translation
+}
+
+// Languages returns the supported languages, DefaultLanguage first.
+func (b *Bundle) Languages() []string {
+	b.mu.RLock()
+	seen := map[string]bool{DefaultLanguage: true}
+	var out []string
+	for lang := range b.codes {
+		if !seen[lang] {
+			seen[lang] = true
+			out = append(out, lang)
+		}
+	}
+	for lang := range b.messages {
+		if !seen[lang] {
+			seen[lang] = true
+			out = append(out, lang)
+		}
+	}
+	b.mu.RUnlock()
+
+	sort.Strings(out)
+	return append([]string{DefaultLanguage}, out...)
+}
+
+// Translate returns message in lang. It falls back to message itself when
+// lang is the default language or no translation applies.
+func (b *Bundle) Translate(lang string, code errors.Code, message string, params map[string]any) string {
+	lang = normalize(lang)
+	if b == nil || lang == DefaultLanguage || lang == "" {
+		return message
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if t, ok := b.messages[lang][message]; ok {
+		return t
+	}
+	for _, tmpl := range b.codes[lang][code] {
+		if out, ok := expand(tmpl, params); ok {
+			return out
+		}
+	}
+	return message
+}
+
+// expand replaces {name} placeholders with params. It reports false if a
+// placeholder has no matching param.
+func expand(tmpl string, params map[string]any) (string, bool) {
+	var out strings.Builder
+	for {
+		start := strings.IndexByte(tmpl, '{')
+		if start < 0 {
+			out.WriteString(tmpl)
+			return out.String(), true
+		}
+		end := strings.IndexByte(tmpl[start:], '}')
+		if end < 0 {
+			out.WriteString(tmpl)
+			return out.String(), true
+		}
+		end += start
+
+		v, ok := params[tmpl[start+1:end]]
+		if !ok {
+			return "", false
+		}
+		out.WriteString(tmpl[:start])
+		out.WriteString(fmt.Sprint(v))
+		tmpl = tmpl[end+1:]
+	}
+}
+
+// Negotiate picks the best supported language for an Accept-Language
+// header. Region subtags fall back to their base language (es-MX -> es).
+// It returns DefaultLanguage when nothing matches.
+func Negotiate(header string, supported []string) string {
+	type pref struct {
+		tag string
+		q   float64
+	}
+	var prefs []pref
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if q <= 0 {
+			continue
+		}
+		prefs = append(prefs, pref{normalize(tag), q})
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+
+	for _, p := range prefs {
+		if p.tag == "*" {
+			return DefaultLanguage
+		}
+		base, _, _ := strings.Cut(p.tag, "-")
+		for _, s := range supported {
+			if s == p.tag || s == base {
+				return s
+			}
+		}
+	}
+	return DefaultLanguage
+}
+
+func normalize(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
+
+type ctxKey struct{}
+
+type locale struct {
+	lang   string
+	bundle *Bundle
+}
+
+// WithLanguage returns a context whose error messages are translated to
+// lang using b.
+func WithLanguage(ctx context.Context, b *Bundle, lang string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, locale{lang: lang, bundle: b})
+}
+
+// Language returns the negotiated language stored in ctx, or
+// DefaultLanguage if none.
+func Language(ctx context.Context) string {
+	if l, ok := ctx.Value(ctxKey{}).(locale); ok && l.lang != "" {
+		return l.lang
+	}
+	return DefaultLanguage
+}
+
+// Localize translates a client-facing message to the language in ctx.
+// Without a language in ctx it returns message unchanged.
+func Localize(ctx context.Context, code errors.Code, message string, params map[string]any) string {
+	l, ok := ctx.Value(ctxKey{}).(locale)
+	if !ok {
+		return message
+	}
+	return l.bundle.Translate(l.lang, code, message, params)
+}
+
+// LocalizeError returns a copy of e with its message translated to the
+// language in ctx. e itself is not modified, so logs keep the English text.
+func LocalizeError(ctx context.Context, e *errors.Error) *errors.Error {
+	if e == nil {
+		return nil
+	}
+	msg := Localize(ctx, e.Code, e.Message, e.Fields)
+	if msg == e.Message {
+		return e
+	}
+	out := *e
+	out.Message = msg
+	return &out
+}
+
+// Middleware negotiates the request language from Accept-Language and
+// stores it in the request context for Localize.
+func Middleware(b *Bundle) func(http.Handler) http.Handler {
+	supported := b.Languages()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Language")
+			lang := Negotiate(r.Header.Get("Accept-Language"), supported)
+			next.ServeHTTP(w, r.WithContext(WithLanguage(r.Context(), b, lang)))
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gala/internal/pkg/errors"
+)
+
+func TestNegotiate(t *testing.T) {
+	supported := []string{"en", "es"}
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"es", "es"},
+		{"es-MX,es;q=0.9,en;q=0.8", "es"},
+		{"fr, es;q=0.5", "es"},
+		{"en;q=0.4, es;q=0.9", "es"},
+		{"es;q=0, en", "en"},
+		{"de, fr", "en"},
+		{"*", "en"},
+		{"ES_mx", "es"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header, supported); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	b := NewBundle()
+	b.AddCode("es", errors.CodeNotFound, "No se encontró {resource}: {id}")
+	b.AddCode("es", errors.CodeNotFound, "El recurso no existe.")
+	b.AddMessage("es", "kind is required", "El campo kind es obligatorio.")
+
+	tests := []struct {
+		name    string
+		lang    string
+		code    errors.Code
+		message string
+		params  map[string]any
+		want    string
+	}{
+		{"default language", "en", errors.CodeNotFound, "job not found", nil, "job not found"},
+		{"params filled", "es", errors.CodeNotFound, "x", map[string]any{"resource": "job", "id": 7}, "No se encontró job: 7"},
+		{"missing params fall through", "es", errors.CodeNotFound, "x", map[string]any{"id": 7}, "El recurso no existe."},
+		{"message wins over code", "es", errors.CodeValidation, "kind is required", nil, "El campo kind es obligatorio."},
+		{"untranslated stays English", "es", errors.CodeTimeout, "render timed out", nil, "render timed out"},
+		{"unknown language", "fr", errors.CodeNotFound, "job not found", nil, "job not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.Translate(tt.lang, tt.code, tt.message, tt.params); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalizeErrorKeepsOriginal(t *testing.T) {
+	b := NewBundle()
+	b.AddCode("es", errors.CodeValidation, "Solicitud no válida.")
+	ctx := WithLanguage(context.Background(), b, "es")
+
+	orig := errors.Validation("bad input")
+	got := LocalizeError(ctx, orig)
+
+	if got.Message != "Solicitud no válida." {
+		t.Errorf("expected translated message, got %q", got.Message)
+	}
+	if orig.Message != "bad input" {
+		t.Errorf("expected original message untouched, got %q", orig.Message)
+	}
+	if got.Code != orig.Code {
+		t.Errorf("expected code %s, got %s", orig.Code, got.Code)
+	}
+}
+
+func TestLocalizeWithoutLanguage(t *testing.T) {
+	if got := Localize(context.Background(), errors.CodeInternal, "boom", nil); got != "boom" {
+		t.Errorf("expected message unchanged, got %q", got)
+	}
+	if got := Language(context.Background()); got != DefaultLanguage {
+		t.Errorf("expected %s, got %s", DefaultLanguage, got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	b := NewBundle()
+	b.AddCode("es", errors.CodeInternal, "Error interno.")
+
+	var lang, msg string
+	h := Middleware(b)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang = Language(r.Context())
+		msg = Localize(r.Context(), errors.CodeInternal, "internal error", nil)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "es-AR,es;q=0.9")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if lang != "es" {
+		t.Errorf("expected es, got %s", lang)
+	}
+	if msg != "Error interno." {
+		t.Errorf("expected translated message, got %q", msg)
+	}
+	if rec.Header().Get("Vary") != "Accept-Language" {
+		t.Errorf("expected Vary: Accept-Language, got %q", rec.Header().Get("Vary"))
+	}
+}
+
+func TestDefaultBundleHasSpanish(t *testing.T) {
+	got := Default().Translate("es", errors.CodeNotFound, "template not found: abc",
+		map[string]any{"resource": "template", "id": "abc"})
+	if got != "No se encontró template: abc" {
+		t.Errorf("unexpected translation %q", got)
+	}
+}
@@ -0,0 +1,272 @@
+// Package jsonschema validates decoded JSON values against the subset of
+// JSON Schema (draft 2020-12) the platform's contracts use: type, enum,
+// const, required, properties, additionalProperties, items, oneOf,
+// minLength, pattern, minimum, maximum, exclusiveMinimum and
+// exclusiveMaximum. Other keywords are ignored.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error is a value that does not match the schema.
+type Error struct {
+	// Path locates the value: "output.video_object_key", "items[2]"; empty
+	// for the root.
+	Path    string
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Schema is a parsed schema document.
+type Schema struct {
+	root map[string]any
+}
+
+// Parse decodes a schema document, which must be a JSON object.
+func Parse(data []byte) (*Schema, error) {
+	var root map[string]any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("jsonschema: %w", err)
+	}
+	if root == nil {
+		return nil, errors.New("jsonschema: schema is not an object")
+	}
+	return &Schema{root: root}, nil
+}
+
+// MustParse is Parse for schemas built into the binary; it panics on error.
+func MustParse(data []byte) *Schema {
+	s, err := Parse(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Validate checks v, a value as decoded by encoding/json into any
+// (map[string]any, []any, float64, string, bool or nil), and returns every
+// mismatch, sorted by path; none means v is valid.
+func (s *Schema) Validate(v any) []Error {
+	var errs []Error
+	validate(s.root, v, "", &errs)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return errs
+}
+
+// ValidateJSON decodes data and validates it. The error is for data that
+// is not JSON.
+func (s *Schema) ValidateJSON(data []byte) ([]Error, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return s.Validate(v), nil
+}
+
+// ValidateValue validates a Go value by its JSON encoding, e.g. a struct
+// with json tags.
+func (s *Schema) ValidateValue(v any) ([]Error, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return s.ValidateJSON(data)
+}
+
+// Join joins errs into one error, nil when there are none.
+func Join(errs []Error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+func validate(schema map[string]any, v any, path string, errs *[]Error) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, Error{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, v) {
+		fail("must be %s", typeNames(t))
+		return
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, v) {
+		fail("must be %s", encode(c))
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			vals := make([]string, len(enum))
+			for i, e := range enum {
+				vals[i] = encode(e)
+			}
+			fail("must be one of %s", strings.Join(vals, ", "))
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		matches := 0
+		for _, sub := range oneOf {
+			if m, ok := sub.(map[string]any); ok {
+				var subErrs []Error
+				validate(m, v, path, &subErrs)
+				if len(subErrs) == 0 {
+					matches++
+				}
+			}
+		}
+		if matches != 1 {
+			fail("must match exactly one schema of oneOf, matches %d", matches)
+		}
+	}
+
+	switch x := v.(type) {
+	case map[string]any:
+		validateObject(schema, x, path, errs)
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, e := range x {
+				validate(items, e, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case string:
+		if n, ok := schema["minLength"].(float64); ok && float64(utf8.RuneCountInString(x)) < n {
+			fail("must be at least %v characters", n)
+		}
+		if p, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				fail("invalid pattern %q in schema", p)
+			} else if !re.MatchString(x) {
+				fail("must match %q", p)
+			}
+		}
+	case float64:
+		if n, ok := schema["minimum"].(float64); ok && x < n {
+			fail("must be at least %v", n)
+		}
+		if n, ok := schema["maximum"].(float64); ok && x > n {
+			fail("must be at most %v", n)
+		}
+		if n, ok := schema["exclusiveMinimum"].(float64); ok && x <= n {
+			fail("must be greater than %v", n)
+		}
+		if n, ok := schema["exclusiveMaximum"].(float64); ok && x >= n {
+			fail("must be less than %v", n)
+		}
+	}
+}
+
+func validateObject(schema map[string]any, obj map[string]any, path string, errs *[]Error) {
+	if req, ok := schema["required"].([]any); ok {
+		for _, r := range req {
+			name, _ := r.(string)
+			if _, ok := obj[name]; !ok {
+				*errs = append(*errs, Error{Path: join(path, name), Message: "is required"})
+			}
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]any)
+	for k, val := range obj {
+		if p, ok := props[k].(map[string]any); ok {
+			validate(p, val, join(path, k), errs)
+			continue
+		}
+		switch add := schema["additionalProperties"].(type) {
+		case bool:
+			if !add {
+				*errs = append(*errs, Error{Path: join(path, k), Message: "is not allowed"})
+			}
+		case map[string]any:
+			validate(add, val, join(path, k), errs)
+		}
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// matchesType reports whether v is of type t, a type name or a list of
+// them.
+func matchesType(t any, v any) bool {
+	if list, ok := t.([]any); ok {
+		for _, e := range list {
+			if matchesType(e, v) {
+				return true
+			}
+		}
+		return false
+	}
+	switch t {
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		n, ok := v.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	}
+	return true
+}
+
+func typeNames(t any) string {
+	if list, ok := t.([]any); ok {
+		names := make([]string, len(list))
+		for i, e := range list {
+			names[i] = fmt.Sprint(e)
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func encode(v any) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	return strings.TrimSpace(buf.String())
+}
@@ -0,0 +1,104 @@
+package jsonschema
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["id", "output"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "string", "minLength": 1, "pattern": "^[a-z0-9-]+$"},
+		"kind": {"enum": ["a", "b"]},
+		"count": {"type": "integer", "minimum": 1, "maximum": 10},
+		"opacity": {"type": "number", "exclusiveMinimum": 0, "maximum": 1},
+		"tags": {"type": "array", "items": {"type": "string"}},
+		"note": {"type": ["string", "null"]},
+		"output": {
+			"type": "object",
+			"required": ["key"],
+			"properties": {"key": {"type": "string"}},
+			"additionalProperties": {"type": "string"}
+		},
+		"ref": {"oneOf": [{"type": "string"}, {"type": "integer"}]}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	s := MustParse([]byte(testSchema))
+
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{"valid", `{"id": "job-1", "kind": "a", "count": 3, "opacity": 1, "tags": ["x"], "note": null, "output": {"key": "k", "extra": "e"}, "ref": 2}`, nil},
+		{"missing required", `{"id": "job-1"}`, []string{"output: is required"}},
+		{"not an object", `[]`, []string{"must be object"}},
+		{"unknown property", `{"id": "x", "output": {"key": "k"}, "other": 1}`, []string{"other: is not allowed"}},
+		{"wrong types", `{"id": 1, "count": 1.5, "output": {"key": "k", "extra": 2}}`, []string{
+			"count: must be integer", "id: must be string", "output.extra: must be string",
+		}},
+		{"string rules", `{"id": "", "output": {"key": "k"}}`, []string{
+			`id: must be at least 1 characters`, `id: must match "^[a-z0-9-]+$"`,
+		}},
+		{"enum", `{"id": "x", "kind": "c", "output": {"key": "k"}}`, []string{`kind: must be one of "a", "b"`}},
+		{"number bounds", `{"id": "x", "count": 11, "opacity": 0, "output": {"key": "k"}}`, []string{
+			"count: must be at most 10", "opacity: must be greater than 0",
+		}},
+		{"items", `{"id": "x", "tags": ["a", 2], "output": {"key": "k"}}`, []string{"tags[1]: must be string"}},
+		{"type list", `{"id": "x", "note": 1, "output": {"key": "k"}}`, []string{"note: must be string or null"}},
+		{"oneOf", `{"id": "x", "ref": true, "output": {"key": "k"}}`, []string{"ref: must match exactly one schema of oneOf, matches 0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := s.ValidateJSON([]byte(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("errors = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	s := MustParse([]byte(testSchema))
+	type output struct {
+		Key string `json:"key"`
+	}
+	doc := struct {
+		ID     string `json:"id"`
+		Output output `json:"output"`
+	}{ID: "job-1", Output: output{Key: "k"}}
+
+	errs, err := s.ValidateValue(doc)
+	if err != nil || len(errs) != 0 {
+		t.Fatalf("ValidateValue = %v, %v; want valid", errs, err)
+	}
+}
+
+func TestParseRejectsNonObjects(t *testing.T) {
+	for _, doc := range []string{`[]`, `null`, `{`} {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("Parse(%s) succeeded", doc)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	if Join(nil) != nil {
+		t.Error("Join(nil) != nil")
+	}
+	err := Join([]Error{{Path: "a", Message: "is required"}, {Message: "must be object"}})
+	if err == nil || err.Error() != "a: is required; must be object" {
+		t.Errorf("Join = %v", err)
+	}
+}
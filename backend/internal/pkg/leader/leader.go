@@ -0,0 +1,153 @@
+// Package leader runs singleton tasks: work that must happen on exactly one
+// replica at a time (reapers, GC, schedulers), elected with Postgres
+// session advisory locks.
+//
+// The elected replica keeps the lock on a dedicated connection for as long
+// as it leads. If that connection drops, Postgres releases the lock and
+// another replica takes over on its next attempt, so a crashed leader is
+// replaced within one retry interval.
+package leader
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"gala/internal/pkg/logger"
+)
+
+// DefaultRetry is how often followers try to take the lock, and how often
+// the leader checks it still holds it.
+const DefaultRetry = 15 * time.Second
+
+// Key maps a task name to its advisory lock key.
+func Key(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("gala:leader:" + name))
+	return int64(h.Sum64())
+}
+
+// Run calls fn whenever this process is the leader for name, until ctx is
+// canceled. fn's context is canceled when leadership is lost; Run waits for
+// fn to return before trying again, so two replicas never run it at the
+// same time. retry <= 0 uses DefaultRetry. Run blocks, so start it in its
+// own goroutine.
+func Run(ctx context.Context, pool *pgxpool.Pool, log *logger.Logger, name string, retry time.Duration, fn func(ctx context.Context)) {
+	if retry <= 0 {
+		retry = DefaultRetry
+	}
+	log = log.WithComponent("leader").WithFields(map[string]any{"task": name})
+	key := Key(name)
+
+	for {
+		led, err := lead(ctx, pool, key, retry, log, fn)
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case err != nil && led:
+			log.Warn("leadership lost", "error", err.Error())
+		case err != nil:
+			log.Warn("leader election failed", "error", err.Error())
+		case led:
+			log.Info("stepped down as leader")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+// lead tries the lock once and, if it gets it, runs fn until ctx ends or
+// the lock connection fails. It reports whether it led.
+func lead(ctx context.Context, pool *pgxpool.Pool, key int64, retry time.Duration, log *logger.Logger, fn func(ctx context.Context)) (bool, error) {
+	pc, err := pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var acquired bool
+	if err := pc.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&acquired); err != nil {
+		pc.Release()
+		return false, err
+	}
+	if !acquired {
+		pc.Release()
+		return false, nil
+	}
+
+	// The lock belongs to this session: keep the connection out of the pool
+	// until we step down, and close it afterwards so the lock goes with it.
+	conn := pc.Hijack()
+	defer closeConn(conn)
+
+	log.Info("acquired leadership")
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(leaderCtx)
+	}()
+
+	err = holdLock(leaderCtx, conn, retry, done)
+	cancel()
+	<-done
+	return true, err
+}
+
+// holdLock pings the lock connection every retry until ctx ends, fn
+// returns (done) or the connection fails.
+func holdLock(ctx context.Context, conn *pgx.Conn, retry time.Duration, done <-chan struct{}) error {
+	ticker := time.NewTicker(retry)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-done:
+			return nil
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil && ctx.Err() == nil {
+				return err
+			}
+		}
+	}
+}
+
+func closeConn(conn *pgx.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = conn.Close(ctx)
+}
+
+// Every turns a periodic task into a Run callback: it calls task right away
+// and then every interval while leading. Errors are logged and the task
+// keeps its schedule.
+func Every(log *logger.Logger, interval time.Duration, task func(ctx context.Context) error) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := task(ctx); err != nil && ctx.Err() == nil {
+				log.Error("singleton task failed", "error", err.Error())
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gala/internal/pkg/logger"
+)
+
+func TestKey(t *testing.T) {
+	if Key("reaper") != Key("reaper") {
+		t.Error("expected Key to be stable")
+	}
+	if Key("reaper") == Key("gc") {
+		t.Error("expected different names to get different keys")
+	}
+}
+
+func TestEvery(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(logger.Config{Level: "debug", Output: &buf})
+
+	var calls atomic.Int32
+	fn := Every(log, 10*time.Millisecond, func(ctx context.Context) error {
+		calls.Add(1)
+		return errors.New("transient")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		fn(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for calls.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Every did not return after cancel")
+	}
+	if n := calls.Load(); n < 3 {
+		t.Errorf("task ran %d times, want at least 3 despite errors", n)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("singleton task failed")) {
+		t.Error("expected task errors to be logged")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,6 +24,10 @@ const (
 // Logger wraps slog.Logger with GALA-specific functionality.
 type Logger struct {
 	*slog.Logger
+
+	// level is shared by every logger derived from the same New call, so
+	// SetLevel applies to all of them.
+	level *slog.LevelVar
 }
 
 // Config holds logger configuration.
@@ -56,7 +61,8 @@ func New(cfg Config) *Logger {
 		cfg.Output = os.Stdout
 	}
 
-	level := parseLevel(cfg.Level)
+	level := new(slog.LevelVar)
+	level.Set(parseLevel(cfg.Level))
 
 	opts := &slog.HandlerOptions{
 		Level:     level,
@@ -88,6 +94,7 @@ func New(cfg Config) *Logger {
 
 	return &Logger{
 		Logger: slog.New(handler),
+		level:  level,
 	}
 }
 
@@ -100,6 +107,7 @@ func NewDefault() *Logger {
 func (l *Logger) WithRequestID(requestID string) *Logger {
 	return &Logger{
 		Logger: l.Logger.With(slog.String("request_id", requestID)),
+		level:  l.level,
 	}
 }
 
@@ -107,6 +115,7 @@ func (l *Logger) WithRequestID(requestID string) *Logger {
 func (l *Logger) WithJobID(jobID string) *Logger {
 	return &Logger{
 		Logger: l.Logger.With(slog.String("job_id", jobID)),
+		level:  l.level,
 	}
 }
 
@@ -114,6 +123,7 @@ func (l *Logger) WithJobID(jobID string) *Logger {
 func (l *Logger) WithComponent(component string) *Logger {
 	return &Logger{
 		Logger: l.Logger.With(slog.String("component", component)),
+		level:  l.level,
 	}
 }
 
@@ -124,6 +134,7 @@ func (l *Logger) WithError(err error) *Logger {
 	}
 	return &Logger{
 		Logger: l.Logger.With(slog.String("error", err.Error())),
+		level:  l.level,
 	}
 }
 
@@ -135,9 +146,26 @@ func (l *Logger) WithFields(fields map[string]any) *Logger {
 	}
 	return &Logger{
 		Logger: l.Logger.With(attrs...),
+		level:  l.level,
 	}
 }
 
+// SetLevel changes the minimum level at runtime (debug, info, warn,
+// error). Unknown values fall back to info, as in New.
+func (l *Logger) SetLevel(level string) {
+	if l.level != nil {
+		l.level.Set(parseLevel(level))
+	}
+}
+
+// Level returns the current minimum level.
+func (l *Logger) Level() slog.Level {
+	if l.level == nil {
+		return slog.LevelInfo
+	}
+	return l.level.Level()
+}
+
 // FromContext extracts logger context values and returns an enriched logger.
 func (l *Logger) FromContext(ctx context.Context) *Logger {
 	result := l
@@ -169,13 +197,34 @@ func (l *Logger) LogError(ctx context.Context, msg string, err error, args ...an
 	l.FromContext(ctx).Error(msg, args...)
 }
 
-// LogFatal logs a fatal error and exits.
+// exitHook runs once before LogFatal exits; see SetExitHook.
+var exitHook atomic.Pointer[func()]
+
+// osExit is replaced in tests.
+var osExit = os.Exit
+
+// SetExitHook registers fn to run before LogFatal exits the process, e.g.
+// to close pools and finish in-flight work (shutdown.Manager.Fatal). It
+// replaces any previous hook; nil removes it. The hook runs at most once,
+// so a LogFatal from inside it exits immediately.
+func SetExitHook(fn func()) {
+	if fn == nil {
+		exitHook.Store(nil)
+		return
+	}
+	exitHook.Store(&fn)
+}
+
+// LogFatal logs a fatal error, runs the exit hook (if any) and exits.
 func (l *Logger) LogFatal(msg string, err error, args ...any) {
 	if err != nil {
 		args = append(args, "error", err.Error())
 	}
 	l.Error(msg, args...)
-	os.Exit(1)
+	if hook := exitHook.Swap(nil); hook != nil {
+		(*hook)()
+	}
+	osExit(1)
 }
 
 // ContextWithRequestID adds a request ID to the context.
@@ -183,6 +232,12 @@ func ContextWithRequestID(ctx context.Context, requestID string) context.Context
 	return context.WithValue(ctx, RequestIDKey, requestID)
 }
 
+// RequestIDFromContext returns the request ID in ctx, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
+
 // ContextWithJobID adds a job ID to the context.
 func ContextWithJobID(ctx context.Context, jobID string) context.Context {
 	return context.WithValue(ctx, JobIDKey, jobID)
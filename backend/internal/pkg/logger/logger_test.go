@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"os"
 	"strings"
 	"testing"
 )
@@ -143,6 +144,28 @@ func TestLoggerLevels(t *testing.T) {
 	}
 }
 
+func TestSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := New(Config{
+		Level:  "info",
+		Format: "json",
+		Output: &buf,
+	})
+	child := log.WithComponent("worker")
+
+	child.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("expected debug to be filtered at info, got: %s", buf.String())
+	}
+
+	log.SetLevel("debug")
+	child.Debug("visible")
+	if !strings.Contains(buf.String(), "visible") {
+		t.Errorf("expected derived logger to follow SetLevel, got: %s", buf.String())
+	}
+}
+
 func TestWithRequestID(t *testing.T) {
 	var buf bytes.Buffer
 
@@ -253,6 +276,12 @@ func TestContextWithRequestID(t *testing.T) {
 	if val != "req-789" {
 		t.Errorf("expected request_id='req-789', got %v", val)
 	}
+	if got := RequestIDFromContext(ctx); got != "req-789" {
+		t.Errorf("RequestIDFromContext() = %q, want 'req-789'", got)
+	}
+	if got := RequestIDFromContext(context.Background()); got != "" {
+		t.Errorf("RequestIDFromContext() without one = %q, want empty", got)
+	}
 }
 
 func TestContextWithJobID(t *testing.T) {
@@ -316,3 +345,23 @@ func TestParseLevel(t *testing.T) {
 		})
 	}
 }
+
+func TestLogFatalRunsExitHook(t *testing.T) {
+	var code, calls int
+	osExit = func(c int) { code = c }
+	defer func() { osExit = os.Exit }()
+
+	SetExitHook(func() { calls++ })
+	defer SetExitHook(nil)
+
+	log := New(Config{Level: "info", Output: &bytes.Buffer{}})
+	log.LogFatal("boom", nil)
+	log.LogFatal("boom again", nil)
+
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if calls != 1 {
+		t.Errorf("exit hook ran %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	f, err := NewRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	read := func(p string) string {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatalf("read %s: %v", p, err)
+		}
+		return string(b)
+	}
+	if got := read(path); got != "dddddd\n" {
+		t.Errorf("current file = %q", got)
+	}
+	if got := read(path + ".1"); got != "cccccc\n" {
+		t.Errorf("backup 1 = %q", got)
+	}
+	if got := read(path + ".2"); got != "bbbbbb\n" {
+		t.Errorf("backup 2 = %q", got)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, stat .3: %v", err)
+	}
+}
+
+func TestRotatingFileAppendsToExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "app.log")
+	f, err := NewRotatingFile(path, 0, 0)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+	f.Write([]byte("one\n"))
+	f.Close()
+
+	if _, err := f.Write([]byte("closed\n")); err == nil {
+		t.Error("expected write after close to fail")
+	}
+
+	f, err = NewRotatingFile(path, 0, 0)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	f.Write([]byte("two\n"))
+	f.Close()
+
+	b, _ := os.ReadFile(path)
+	if !strings.Contains(string(b), "one\ntwo\n") {
+		t.Errorf("expected appended content, got %q", b)
+	}
+}
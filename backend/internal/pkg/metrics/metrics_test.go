@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, reg *Registry) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	return rec.Body.String()
+}
+
+func TestHistogramExposition(t *testing.T) {
+	reg := NewRegistry()
+	h := reg.NewHistogramVec("req_seconds", "Request latency.", []float64{0.1, 1}, "route")
+
+	h.Observe(0.05, "/jobs")
+	h.Observe(0.1, "/jobs")
+	h.Observe(0.5, "/jobs")
+	h.Observe(3, "/jobs")
+
+	out := scrape(t, reg)
+	for _, want := range []string{
+		"# HELP req_seconds Request latency.",
+		"# TYPE req_seconds histogram",
+		`req_seconds_bucket{route="/jobs",le="0.1"} 2`,
+		`req_seconds_bucket{route="/jobs",le="1"} 3`,
+		`req_seconds_bucket{route="/jobs",le="+Inf"} 4`,
+		`req_seconds_sum{route="/jobs"} 3.65`,
+		`req_seconds_count{route="/jobs"} 4`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestGaugeExposition(t *testing.T) {
+	reg := NewRegistry()
+	g := reg.NewGaugeVec("in_flight", "In flight.", "method")
+
+	g.Add(1, "GET")
+	g.Add(1, "GET")
+	g.Add(-1, "GET")
+	g.Set(4, `PO"ST`)
+
+	out := scrape(t, reg)
+	if !strings.Contains(out, `in_flight{method="GET"} 1`+"\n") {
+		t.Errorf("expected GET gauge, got:\n%s", out)
+	}
+	if !strings.Contains(out, `in_flight{method="PO\"ST"} 4`+"\n") {
+		t.Errorf("expected escaped label, got:\n%s", out)
+	}
+}
+
+func TestCounterExposition(t *testing.T) {
+	reg := NewRegistry()
+	c := reg.NewCounterVec("bytes_total", "Bytes moved.", "op")
+
+	c.Add(512, "get")
+	c.Add(0, "get")
+	c.Inc("get")
+	c.Inc("put")
+
+	out := scrape(t, reg)
+	for _, want := range []string{
+		"# TYPE bytes_total counter",
+		`bytes_total{op="get"} 513`,
+		`bytes_total{op="put"} 1`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestCounterDecreasePanics(t *testing.T) {
+	c := NewRegistry().NewCounterVec("c_total", "c", "a")
+	c.Add(2, "x")
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic on a negative delta")
+			}
+		}()
+		c.Add(-1, "x")
+	}()
+	if c.series["x"] != 2 {
+		t.Errorf("counter = %v after the rejected delta, want 2", c.series["x"])
+	}
+}
+
+func TestLabelCountMismatchPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on wrong number of label values")
+		}
+	}()
+	NewRegistry().NewGaugeVec("g", "g", "a", "b").Set(1, "only-one")
+}
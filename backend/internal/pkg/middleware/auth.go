@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"gala/internal/pkg/errors"
+)
+
+// RequireBearerToken rejects requests whose Authorization header is not
+// "Bearer <token>" with 401. It guards operator-only routes such as
+// /admin; an empty token rejects every request.
+func RequireBearerToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="gala-admin"`)
+				WriteErrorResponse(w, errors.CodeUnauthorized, "authentication required", nil)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
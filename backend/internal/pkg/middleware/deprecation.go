@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// DeprecationOptions configures Deprecation.
+type DeprecationOptions struct {
+	// Successor returns the URL that replaces the requested one; empty
+	// omits the Link header.
+	Successor func(r *http.Request) string
+	// Sunset, if set, is announced as the date the routes go away.
+	Sunset time.Time
+}
+
+// Deprecation marks the routes it wraps as deprecated: responses carry
+// "Deprecation: true", a Link to the successor (rel="successor-version")
+// and, if configured, a Sunset date. Requests are served as usual.
+func Deprecation(opt DeprecationOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Deprecation", "true")
+			if opt.Successor != nil {
+				if u := opt.Successor(r); u != "" {
+					h.Add("Link", "<"+u+`>; rel="successor-version"`)
+				}
+			}
+			if !opt.Sunset.IsZero() {
+				h.Set("Sunset", opt.Sunset.UTC().Format(http.TimeFormat))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,468 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/logger"
+)
+
+// Idempotency headers.
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLen      = 255
+	maxFingerprintBytes       = 1 << 20
+	maxIdempotentResponseSize = 1 << 20
+)
+
+// DefaultIdempotencyMaxBody is the default IdempotencyOptions.MaxBodyBytes:
+// the largest asset kind (2 GiB) plus room for the multipart framing.
+const DefaultIdempotencyMaxBody = 2<<30 + 1<<20
+
+// IdempotentResponse is a cached response, or an in-flight reservation when
+// InProgress is set.
+type IdempotentResponse struct {
+	Fingerprint string      `json:"fingerprint"`
+	InProgress  bool        `json:"in_progress,omitempty"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// IdempotencyStore persists idempotent responses.
+type IdempotencyStore interface {
+	// Get returns the stored entry for key, or nil if there is none.
+	Get(ctx context.Context, key string) (*IdempotentResponse, error)
+	// Reserve marks key as in progress. It reports false if key exists.
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (bool, error)
+	// Save stores the final response for key.
+	Save(ctx context.Context, key string, resp *IdempotentResponse, ttl time.Duration) error
+	// Release drops key so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyOptions configures the Idempotency middleware.
+type IdempotencyOptions struct {
+	Store IdempotencyStore
+	// TTL is how long completed responses are replayed (default 24h).
+	TTL time.Duration
+	// LockTTL bounds how long a request may hold its key while running
+	// (default 5m), so a crashed request does not block retries forever.
+	LockTTL time.Duration
+	// Methods are the methods the middleware applies to
+	// (default POST, PUT, PATCH, DELETE).
+	Methods []string
+	// MaxBodyBytes caps the body read to fingerprint a request; a larger
+	// one is rejected with PAYLOAD_TOO_LARGE (default
+	// DefaultIdempotencyMaxBody).
+	MaxBodyBytes int64
+	// Principal identifies the caller; keys are scoped per principal.
+	// Default: a hash of the Authorization header.
+	Principal func(r *http.Request) string
+	Log       *logger.Logger
+}
+
+// Idempotency replays the stored response for requests that repeat an
+// Idempotency-Key, scoped to principal, method and path. Only non-5xx
+// responses are stored, so failed requests can be retried.
+//
+// Reusing a key with a different request body yields VALIDATION_ERROR,
+// a body over MaxBodyBytes yields PAYLOAD_TOO_LARGE, and a key whose
+// first request is still running yields CONFLICT. A
+// handler that panics releases its key before the panic goes on. If the
+// store is unavailable requests are served without idempotency.
+func Idempotency(opt IdempotencyOptions) func(http.Handler) http.Handler {
+	if opt.TTL <= 0 {
+		opt.TTL = 24 * time.Hour
+	}
+	if opt.LockTTL <= 0 {
+		opt.LockTTL = 5 * time.Minute
+	}
+	if opt.MaxBodyBytes <= 0 {
+		opt.MaxBodyBytes = DefaultIdempotencyMaxBody
+	}
+	if len(opt.Methods) == 0 {
+		opt.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	if opt.Principal == nil {
+		opt.Principal = authorizationPrincipal
+	}
+	methods := make(map[string]bool, len(opt.Methods))
+	for _, m := range opt.Methods {
+		methods[strings.ToUpper(m)] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idemKey := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))
+			if idemKey == "" || !methods[r.Method] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(idemKey) > maxIdempotencyKeyLen {
+				WriteErrorResponse(w, errors.CodeValidation, "idempotency key too long",
+					map[string]any{"max_length": maxIdempotencyKeyLen})
+				return
+			}
+
+			ctx := r.Context()
+			key := idempotencyStoreKey(opt.Principal(r), r.Method, r.URL.Path, idemKey)
+			r.Body = http.MaxBytesReader(w, r.Body, opt.MaxBodyBytes)
+			fingerprint, cleanup, err := requestFingerprint(r)
+			var tooLarge *http.MaxBytesError
+			if stderrors.As(err, &tooLarge) {
+				WriteErrorResponse(w, errors.CodePayloadTooLarge, "request body too large",
+					map[string]any{"max_bytes": tooLarge.Limit})
+				return
+			}
+			if err != nil {
+				WriteErrorResponse(w, errors.CodeBadRequest, "failed to read request body", nil)
+				return
+			}
+			defer cleanup()
+
+			stored, err := opt.Store.Get(ctx, key)
+			if err != nil {
+				opt.warn(ctx, "idempotency lookup failed", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if stored == nil {
+				ok, err := opt.Store.Reserve(ctx, key, fingerprint, opt.LockTTL)
+				if err != nil {
+					opt.warn(ctx, "idempotency reserve failed", err)
+					next.ServeHTTP(w, r)
+					return
+				}
+				if !ok {
+					// Lost the race to a concurrent request with the same key.
+					stored = &IdempotentResponse{Fingerprint: fingerprint, InProgress: true}
+				}
+			}
+
+			if stored != nil {
+				switch {
+				case stored.Fingerprint != fingerprint:
+					WriteErrorResponse(w, errors.CodeValidation,
+						"idempotency key was used with a different request", nil)
+				case stored.InProgress:
+					WriteErrorResponse(w, errors.CodeConflict,
+						"a request with this idempotency key is in progress", nil)
+				default:
+					replayIdempotentResponse(w, stored)
+				}
+				return
+			}
+
+			rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				// Otherwise the key stays in progress until LockTTL
+				if p := recover(); p != nil {
+					opt.release(ctx, key)
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+			if !rec.wroteHeader {
+				rec.header = w.Header().Clone()
+			}
+
+			if rec.status >= 500 || rec.overflow {
+				opt.release(ctx, key)
+				return
+			}
+			// Store even if the client went away: the retry is what we serve.
+			storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			err = opt.Store.Save(storeCtx, key, &IdempotentResponse{
+				Fingerprint: fingerprint,
+				Status:      rec.status,
+				Header:      rec.header,
+				Body:        rec.body.Bytes(),
+			}, opt.TTL)
+			if err != nil {
+				opt.warn(ctx, "idempotency save failed", err)
+			}
+		})
+	}
+}
+
+// release drops key so the request can be retried, even if the client
+// went away.
+func (opt IdempotencyOptions) release(ctx context.Context, key string) {
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := opt.Store.Release(storeCtx, key); err != nil {
+		opt.warn(ctx, "idempotency release failed", err)
+	}
+}
+
+func (opt IdempotencyOptions) warn(ctx context.Context, msg string, err error) {
+	if opt.Log != nil {
+		opt.Log.FromContext(ctx).Warn(msg, "error", err.Error())
+	}
+}
+
+func replayIdempotentResponse(w http.ResponseWriter, stored *IdempotentResponse) {
+	for k, vv := range stored.Header {
+		if k == RequestIDHeader {
+			continue // keep this request's ID
+		}
+		w.Header()[k] = vv
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(stored.Status)
+	_, _ = w.Write(stored.Body)
+}
+
+func authorizationPrincipal(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return "anonymous"
+	}
+	sum := sha256.Sum256([]byte(auth))
+	return hex.EncodeToString(sum[:8])
+}
+
+func idempotencyStoreKey(principal, method, path, key string) string {
+	sum := sha256.Sum256([]byte(principal + "\x00" + method + "\x00" + path + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// requestFingerprint hashes the request so a key cannot be reused for a
+// different payload. The whole body is hashed, as it is or part by part
+// for multipart bodies (see multipartFingerprint). The body is restored
+// for the handler: a small one from memory, a larger one from a
+// temporary file; cleanup releases what that took and must be called
+// once the handler is done.
+func requestFingerprint(r *http.Request) (fingerprint string, cleanup func(), err error) {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	cleanup = func() {}
+
+	if r.Body == nil || r.Body == http.NoBody {
+		return hex.EncodeToString(h.Sum(nil)), cleanup, nil
+	}
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		if cleanup, err = multipartFingerprint(h, r, params["boundary"]); err != nil {
+			return "", nil, err
+		}
+		return hex.EncodeToString(h.Sum(nil)), cleanup, nil
+	}
+
+	prefix, err := io.ReadAll(io.LimitReader(r.Body, maxFingerprintBytes+1))
+	if err != nil {
+		return "", nil, err
+	}
+	h.Write(prefix)
+	if len(prefix) <= maxFingerprintBytes {
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(prefix))
+		return hex.EncodeToString(h.Sum(nil)), cleanup, nil
+	}
+
+	spool, cleanup, err := newSpool()
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := spool.Write(prefix); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	if _, err := io.Copy(io.MultiWriter(h, spool), r.Body); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	_ = r.Body.Close()
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	r.Body = io.NopCloser(spool)
+	return hex.EncodeToString(h.Sum(nil)), cleanup, nil
+}
+
+// multipartFingerprint hashes the parts of a multipart body into h: each
+// form field's name and value, each file's field name, filename and full
+// contents. Clients pick a new boundary on every attempt, so the raw bytes
+// would never match. The body is read to the end into a temporary file,
+// which replaces it for the handler and is removed by cleanup. A body that
+// does not parse is hashed as it is, and the handler rejects it.
+func multipartFingerprint(h io.Writer, r *http.Request, boundary string) (cleanup func(), err error) {
+	spool, cleanup, err := newSpool()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			cleanup()
+		}
+	}()
+
+	body := io.TeeReader(r.Body, spool)
+	var parts []string
+	malformed := false
+	mr := multipart.NewReader(body, boundary)
+	for {
+		p, perr := mr.NextPart()
+		if perr == io.EOF {
+			break
+		}
+		if perr != nil {
+			malformed = true
+			break
+		}
+		ph := sha256.New()
+		ph.Write([]byte(p.FormName() + "\x00" + p.FileName() + "\x00"))
+		_, err = io.Copy(ph, p)
+		_ = p.Close()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, hex.EncodeToString(ph.Sum(nil)))
+	}
+	// The epilogue, or what follows a malformed part
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		return nil, err
+	}
+	_ = r.Body.Close()
+
+	if malformed {
+		parts = nil
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(h, spool); err != nil {
+			return nil, err
+		}
+	}
+	// Fields are matched by content, whatever their order
+	slices.Sort(parts)
+	for _, p := range parts {
+		_, _ = io.WriteString(h, p+"\n")
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(spool)
+	return cleanup, nil
+}
+
+// newSpool creates the temporary file a body is read into; cleanup
+// closes and removes it.
+func newSpool() (spool *os.File, cleanup func(), err error) {
+	spool, err = os.CreateTemp("", "gala-idempotency-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	return spool, func() {
+		_ = spool.Close()
+		_ = os.Remove(spool.Name())
+	}, nil
+}
+
+// recordingWriter passes the response through while keeping a copy for
+// the idempotency store.
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+	overflow    bool
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+	rw.status = code
+	rw.header = rw.ResponseWriter.Header().Clone()
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if !rw.overflow {
+		if rw.body.Len()+len(b) > maxIdempotentResponseSize {
+			rw.overflow = true
+			rw.body.Reset()
+		} else {
+			rw.body.Write(b)
+		}
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// RedisIdempotencyStore is an IdempotencyStore backed by Redis.
+type RedisIdempotencyStore struct {
+	rdb    redis.UniversalClient
+	prefix string
+}
+
+// NewRedisIdempotencyStore creates a store that keeps entries under
+// "gala:idem:<hash>".
+func NewRedisIdempotencyStore(rdb redis.UniversalClient) *RedisIdempotencyStore {
+	return &RedisIdempotencyStore{rdb: rdb, prefix: "gala:idem:"}
+}
+
+func (s *RedisIdempotencyStore) Get(ctx context.Context, key string) (*IdempotentResponse, error) {
+	raw, err := s.rdb.Get(ctx, s.prefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var resp IdempotentResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (s *RedisIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (bool, error) {
+	raw, err := json.Marshal(IdempotentResponse{Fingerprint: fingerprint, InProgress: true})
+	if err != nil {
+		return false, err
+	}
+	return s.rdb.SetNX(ctx, s.prefix+key, raw, ttl).Result()
+}
+
+func (s *RedisIdempotencyStore) Save(ctx context.Context, key string, resp *IdempotentResponse, ttl time.Duration) error {
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, s.prefix+key, raw, ttl).Err()
+}
+
+func (s *RedisIdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.rdb.Del(ctx, s.prefix+key).Err()
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"gala/internal/pkg/metrics"
+)
+
+// HTTPMetrics are the request metrics recorded by Metrics.
+type HTTPMetrics struct {
+	duration *metrics.HistogramVec
+	inFlight *metrics.GaugeVec
+}
+
+// NewHTTPMetrics registers the HTTP metrics in reg.
+func NewHTTPMetrics(reg *metrics.Registry) *HTTPMetrics {
+	return &HTTPMetrics{
+		duration: reg.NewHistogramVec("gala_http_request_duration_seconds",
+			"HTTP request latency by route pattern.", nil, "method", "route", "status"),
+		inFlight: reg.NewGaugeVec("gala_http_requests_in_flight",
+			"HTTP requests currently being served.", "method"),
+	}
+}
+
+// Metrics records latency and in-flight requests. Requests are labeled
+// with the chi route pattern (/jobs/{jobId}), never the raw path, to keep
+// cardinality bounded; unmatched requests use "unmatched".
+//
+// It must run inside a chi router so the route context is available.
+func Metrics(m *HTTPMetrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			m.inFlight.Add(1, r.Method)
+			defer m.inFlight.Add(-1, r.Method)
+
+			wrapped := wrapResponseWriter(w)
+			next.ServeHTTP(wrapped, r)
+
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if p := rctx.RoutePattern(); p != "" {
+					route = p
+				}
+			}
+			m.duration.Observe(time.Since(start).Seconds(), r.Method, route, strconv.Itoa(wrapped.status))
+		})
+	}
+}
@@ -2,14 +2,14 @@
 package middleware
 
 import (
-	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"net/http"
-	"runtime/debug"
 	"time"
 
 	"gala/internal/pkg/errors"
+	"gala/internal/pkg/i18n"
 	"gala/internal/pkg/logger"
 )
 
@@ -46,6 +46,12 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer (for
+// Flush in streaming responses).
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // RequestID adds a unique request ID to each request.
 func RequestID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -65,6 +71,14 @@ func RequestID(next http.Handler) http.Handler {
 
 // Logging logs HTTP requests with structured logging.
 func Logging(log *logger.Logger) func(http.Handler) http.Handler {
+	return AccessLogging(log, log)
+}
+
+// AccessLogging is Logging with the "request completed" lines sent to a
+// dedicated access logger, so they can have their own output, level and
+// format and be shipped separately. Debug "request started" lines stay in
+// the application log.
+func AccessLogging(log, access *logger.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -88,11 +102,12 @@ func Logging(log *logger.Logger) func(http.Handler) http.Handler {
 			duration := time.Since(start)
 
 			// Determine log level based on status
-			logFn := reqLog.Info
+			accessLog := access.FromContext(r.Context())
+			logFn := accessLog.Info
 			if wrapped.status >= 500 {
-				logFn = reqLog.Error
+				logFn = accessLog.Error
 			} else if wrapped.status >= 400 {
-				logFn = reqLog.Warn
+				logFn = accessLog.Warn
 			}
 
 			// Log request completion
@@ -102,71 +117,13 @@ func Logging(log *logger.Logger) func(http.Handler) http.Handler {
 				"status", wrapped.status,
 				"size", wrapped.size,
 				"duration_ms", duration.Milliseconds(),
+				"remote_addr", r.RemoteAddr,
+				"user_agent", r.UserAgent(),
 			)
 		})
 	}
 }
 
-// Recovery recovers from panics and logs them.
-func Recovery(log *logger.Logger) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			defer func() {
-				if rec := recover(); rec != nil {
-					// Get stack trace
-					stack := debug.Stack()
-
-					// Log the panic
-					reqLog := log.FromContext(r.Context())
-					reqLog.Error("panic recovered",
-						"panic", rec,
-						"stack", string(stack),
-						"method", r.Method,
-						"path", r.URL.Path,
-					)
-
-					// Return 500 error
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusInternalServerError)
-					_, _ = w.Write([]byte(`{"error":{"code":"INTERNAL_ERROR","message":"internal server error"}}`))
-				}
-			}()
-
-			next.ServeHTTP(w, r)
-		})
-	}
-}
-
-// Timeout adds a timeout to requests.
-func Timeout(duration time.Duration) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx, cancel := context.WithTimeout(r.Context(), duration)
-			defer cancel()
-
-			// Create a channel to signal completion
-			done := make(chan struct{})
-			
-			go func() {
-				next.ServeHTTP(w, r.WithContext(ctx))
-				close(done)
-			}()
-
-			select {
-			case <-done:
-				// Request completed normally
-			case <-ctx.Done():
-				// Timeout occurred
-				if ctx.Err() == context.DeadlineExceeded {
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusGatewayTimeout)
-					_, _ = w.Write([]byte(`{"error":{"code":"TIMEOUT","message":"request timeout"}}`))
-				}
-			}
-		})
-	}
-}
-
 // ErrorHandler creates a middleware that handles errors from handlers.
 // It expects handlers to return errors via context.
 type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request) error
@@ -204,42 +161,33 @@ func HandleError(w http.ResponseWriter, r *http.Request, log *logger.Logger, err
 	if status >= 500 {
 		// Include stack trace for server errors
 		var galaErr *errors.Error
-		if errors.As(err, &galaErr) && len(galaErr.Stack) > 0 {
-			logFields = append(logFields, "stack", galaErr.StackTrace())
+		if errors.As(err, &galaErr) {
+			if stack := galaErr.StackTrace(); stack != "" {
+				logFields = append(logFields, "stack", stack)
+			}
 		}
 		reqLog.Error("request failed", logFields...)
 	} else {
 		reqLog.Warn("request error", logFields...)
 	}
 
-	// Write error response
-	WriteErrorResponse(w, code, err.Error(), fields)
+	// Write error response using the *Error JSON envelope; only the
+	// response message is localized, the log above stays in English.
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", i18n.Language(r.Context()))
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(i18n.LocalizeError(r.Context(), errors.AsError(err)))
 }
 
-// WriteErrorResponse writes a JSON error response.
+// WriteErrorResponse writes a JSON error response in the standard
+// envelope. Detail values keep their JSON types; error values are written
+// as their message.
 func WriteErrorResponse(w http.ResponseWriter, code errors.Code, message string, details map[string]any) {
-	status := (&errors.Error{Code: code}).HTTPStatus()
+	e := &errors.Error{Code: code, Message: message, Fields: details}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-
-	// Build response
-	response := `{"error":{"code":"` + string(code) + `","message":"` + escapeJSON(message) + `"`
-	if len(details) > 0 {
-		response += `,"details":{`
-		first := true
-		for k, v := range details {
-			if !first {
-				response += ","
-			}
-			response += `"` + escapeJSON(k) + `":"` + escapeJSON(toString(v)) + `"`
-			first = false
-		}
-		response += "}"
-	}
-	response += "}}"
-
-	_, _ = w.Write([]byte(response))
+	w.WriteHeader(e.HTTPStatus())
+	_ = json.NewEncoder(w).Encode(e)
 }
 
 // generateRequestID generates a unique request ID.
@@ -248,37 +196,3 @@ func generateRequestID() string {
 	_, _ = rand.Read(b)
 	return hex.EncodeToString(b)
 }
-
-// escapeJSON escapes a string for JSON output.
-func escapeJSON(s string) string {
-	result := ""
-	for _, c := range s {
-		switch c {
-		case '"':
-			result += `\"`
-		case '\\':
-			result += `\\`
-		case '\n':
-			result += `\n`
-		case '\r':
-			result += `\r`
-		case '\t':
-			result += `\t`
-		default:
-			result += string(c)
-		}
-	}
-	return result
-}
-
-// toString converts a value to string.
-func toString(v any) string {
-	switch val := v.(type) {
-	case string:
-		return val
-	case error:
-		return val.Error()
-	default:
-		return ""
-	}
-}
@@ -2,14 +2,24 @@ package middleware
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 
 	"gala/internal/pkg/errors"
+	"gala/internal/pkg/i18n"
 	"gala/internal/pkg/logger"
+	"gala/internal/pkg/metrics"
 )
 
 func TestRequestID(t *testing.T) {
@@ -135,6 +145,64 @@ func TestLoggingLevels(t *testing.T) {
 	}
 }
 
+func TestAccessLogging(t *testing.T) {
+	var appBuf, accessBuf bytes.Buffer
+	app := logger.New(logger.Config{Level: "debug", Format: "json", Output: &appBuf})
+	access := logger.New(logger.Config{Level: "info", Format: "json", Output: &accessBuf})
+
+	handler := AccessLogging(app, access)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	req := httptest.NewRequest("POST", "/jobs", nil)
+	req.Header.Set("User-Agent", "gala-test")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if strings.Contains(appBuf.String(), "request completed") {
+		t.Errorf("expected completion line only in access log, app log: %s", appBuf.String())
+	}
+	if !strings.Contains(appBuf.String(), "request started") {
+		t.Errorf("expected debug start line in app log, got: %s", appBuf.String())
+	}
+	out := accessBuf.String()
+	for _, want := range []string{"request completed", `"status":202`, `"user_agent":"gala-test"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in access log, got: %s", want, out)
+		}
+	}
+}
+
+func TestMetricsUsesRoutePattern(t *testing.T) {
+	reg := metrics.NewRegistry()
+	r := chi.NewRouter()
+	r.Use(Metrics(NewHTTPMetrics(reg)))
+	r.Get("/jobs/{jobId}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, id := range []string{"job_1", "job_2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/jobs/"+id, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope", nil))
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+
+	if !strings.Contains(out, `gala_http_request_duration_seconds_count{method="GET",route="/jobs/{jobId}",status="200"} 2`) {
+		t.Errorf("expected requests grouped by route pattern, got:\n%s", out)
+	}
+	if !strings.Contains(out, `route="unmatched",status="404"`) {
+		t.Errorf("expected unmatched route label, got:\n%s", out)
+	}
+	if strings.Contains(out, "job_1") {
+		t.Errorf("raw path leaked into labels:\n%s", out)
+	}
+	if !strings.Contains(out, `gala_http_requests_in_flight{method="GET"} 0`) {
+		t.Errorf("expected in-flight gauge back at 0, got:\n%s", out)
+	}
+}
+
 func TestRecovery(t *testing.T) {
 	var logBuf bytes.Buffer
 	log := logger.New(logger.Config{
@@ -174,6 +242,96 @@ func TestRecovery(t *testing.T) {
 	}
 }
 
+func TestRecoverySnapshot(t *testing.T) {
+	var logBuf bytes.Buffer
+	log := logger.New(logger.Config{Level: "info", Format: "json", Output: &logBuf})
+
+	var report PanicReport
+	reporter := PanicReporterFunc(func(ctx context.Context, r PanicReport) { report = r })
+
+	r := chi.NewRouter()
+	r.Use(RecoveryWithOptions(log, RecoveryOptions{BodyLimit: 8, Reporter: reporter}))
+	r.Post("/jobs/{jobId}", func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		panic("kaboom")
+	})
+
+	req := httptest.NewRequest("POST", "/jobs/job_1?dry=1", strings.NewReader(`{"text":"hello world"}`))
+	req.Header.Set("Authorization", "Bearer s3cr3t")
+	req.Header.Set("Cookie", "session=abc")
+	req.Header.Set("X-Refresh-Token", "tok")
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	if report.Value != "kaboom" || len(report.Stack) == 0 {
+		t.Errorf("expected panic value and stack in report, got %v", report.Value)
+	}
+
+	snap := report.Request
+	if snap.Route != "/jobs/{jobId}" || snap.Path != "/jobs/job_1" || snap.Query != "dry=1" {
+		t.Errorf("unexpected route/path/query: %+v", snap)
+	}
+	if snap.Body != `{"text":` || !snap.BodyTruncated {
+		t.Errorf("expected body truncated to 8 bytes, got %q (truncated=%v)", snap.Body, snap.BodyTruncated)
+	}
+	if snap.Headers["Content-Type"] != "application/json" {
+		t.Errorf("expected Content-Type in snapshot, got %v", snap.Headers)
+	}
+	for _, h := range []string{"Authorization", "Cookie", "X-Refresh-Token"} {
+		if _, ok := snap.Headers[h]; ok {
+			t.Errorf("expected %s to be removed from snapshot", h)
+		}
+	}
+	if strings.Contains(logBuf.String(), "s3cr3t") {
+		t.Errorf("credentials leaked into log: %s", logBuf.String())
+	}
+	if !strings.Contains(logBuf.String(), `"route":"/jobs/{jobId}"`) {
+		t.Errorf("expected snapshot in log, got: %s", logBuf.String())
+	}
+}
+
+func TestRequireBearerToken(t *testing.T) {
+	handler := RequireBearerToken("s3cr3t")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"valid token", "Bearer s3cr3t", http.StatusNoContent},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"missing header", "", http.StatusUnauthorized},
+		{"wrong scheme", "Basic s3cr3t", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/config/reload", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/admin/config/reload", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	RequireBearerToken("")(handler).ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected empty token to reject every request, got %d", rec.Code)
+	}
+}
+
 func TestResponseWriter(t *testing.T) {
 	t.Run("captures status code", func(t *testing.T) {
 		rec := httptest.NewRecorder()
@@ -267,6 +425,65 @@ func TestWrapHandler(t *testing.T) {
 			t.Errorf("expected NOT_FOUND in body, got: %s", body)
 		}
 	})
+
+	t.Run("non-string details survive", func(t *testing.T) {
+		handler := WrapHandler(log, func(w http.ResponseWriter, r *http.Request) error {
+			return errors.Validation("too many items").
+				WithField("max", 10).
+				WithField("fields", []string{"a", "b"})
+		})
+
+		req := httptest.NewRequest("POST", "/test", nil)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", rec.Code)
+		}
+
+		body := rec.Body.String()
+		if !strings.Contains(body, `"max":10`) || !strings.Contains(body, `"fields":["a","b"]`) {
+			t.Errorf("expected typed details in body, got: %s", body)
+		}
+	})
+
+	t.Run("plain errors are not leaked", func(t *testing.T) {
+		handler := WrapHandler(log, func(w http.ResponseWriter, r *http.Request) error {
+			return io.ErrUnexpectedEOF
+		})
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d", rec.Code)
+		}
+		if strings.Contains(rec.Body.String(), "unexpected EOF") {
+			t.Errorf("expected raw error message to be hidden, got: %s", rec.Body.String())
+		}
+	})
+
+	t.Run("message is localized", func(t *testing.T) {
+		handler := i18n.Middleware(i18n.Default())(WrapHandler(log, func(w http.ResponseWriter, r *http.Request) error {
+			return errors.NotFound("job", "123")
+		}))
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Accept-Language", "es-MX")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if !strings.Contains(rec.Body.String(), "No se encontró job: 123") {
+			t.Errorf("expected Spanish message, got: %s", rec.Body.String())
+		}
+		if rec.Header().Get("Content-Language") != "es" {
+			t.Errorf("expected Content-Language es, got %q", rec.Header().Get("Content-Language"))
+		}
+	})
 }
 
 func TestWriteErrorResponse(t *testing.T) {
@@ -334,25 +551,419 @@ func TestGenerateRequestID(t *testing.T) {
 	}
 }
 
-func TestEscapeJSON(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected string
-	}{
-		{`hello`, `hello`},
-		{`hello "world"`, `hello \"world\"`},
-		{"hello\nworld", `hello\nworld`},
-		{"hello\tworld", `hello\tworld`},
-		{`back\slash`, `back\\slash`},
-	}
+func TestWriteErrorResponseEncoding(t *testing.T) {
+	t.Run("envelope shape without details", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		WriteErrorResponse(rec, errors.CodeNotFound, "user not found", nil)
 
-	for _, tt := range tests {
-		t.Run(tt.input, func(t *testing.T) {
-			result := escapeJSON(tt.input)
-			if result != tt.expected {
-				t.Errorf("expected %q, got %q", tt.expected, result)
-			}
+		want := `{"error":{"code":"NOT_FOUND","message":"user not found"}}` + "\n"
+		if rec.Body.String() != want {
+			t.Errorf("expected %s, got %s", want, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected application/json, got %s", ct)
+		}
+	})
+
+	t.Run("special characters are escaped", func(t *testing.T) {
+		msg := "bad \"name\"\n\tback\\slash <script>"
+		rec := httptest.NewRecorder()
+		WriteErrorResponse(rec, errors.CodeValidation, msg, map[string]any{"quote\"key": "line\nbreak"})
+
+		var env httpEnvelope
+		if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+			t.Fatalf("invalid JSON %q: %v", rec.Body.String(), err)
+		}
+		if env.Error.Message != msg {
+			t.Errorf("expected message %q, got %q", msg, env.Error.Message)
+		}
+		if env.Error.Details["quote\"key"] != "line\nbreak" {
+			t.Errorf("expected detail to round-trip, got %v", env.Error.Details)
+		}
+	})
+
+	t.Run("typed details are kept", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		WriteErrorResponse(rec, errors.CodeValidation, "too many items", map[string]any{
+			"max":    10,
+			"strict": true,
+			"fields": []string{"a", "b"},
+			"limits": map[string]int{"min": 1},
+			"cause":  io.ErrUnexpectedEOF,
+			"none":   nil,
 		})
+
+		var env httpEnvelope
+		if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+			t.Fatalf("invalid JSON %q: %v", rec.Body.String(), err)
+		}
+		d := env.Error.Details
+		if d["max"] != float64(10) {
+			t.Errorf("expected max 10, got %v", d["max"])
+		}
+		if d["strict"] != true {
+			t.Errorf("expected strict true, got %v", d["strict"])
+		}
+		if fields, ok := d["fields"].([]any); !ok || len(fields) != 2 {
+			t.Errorf("expected fields array, got %v", d["fields"])
+		}
+		if limits, ok := d["limits"].(map[string]any); !ok || limits["min"] != float64(1) {
+			t.Errorf("expected limits object, got %v", d["limits"])
+		}
+		if d["cause"] != "unexpected EOF" {
+			t.Errorf("expected error detail as message, got %v", d["cause"])
+		}
+		if v, ok := d["none"]; !ok || v != nil {
+			t.Errorf("expected null detail, got %v (present=%v)", v, ok)
+		}
+	})
+}
+
+type httpEnvelope struct {
+	Error struct {
+		Code    string         `json:"code"`
+		Message string         `json:"message"`
+		Details map[string]any `json:"details"`
+	} `json:"error"`
+}
+
+func TestTimeout(t *testing.T) {
+	t.Run("fast handler passes through", func(t *testing.T) {
+		handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Test", "yes")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("created"))
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/test", nil))
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("expected status 201, got %d", rec.Code)
+		}
+		if rec.Header().Get("X-Test") != "yes" {
+			t.Error("expected handler header to be copied")
+		}
+		if rec.Body.String() != "created" {
+			t.Errorf("expected body 'created', got %q", rec.Body.String())
+		}
+	})
+
+	t.Run("slow handler gets 504 and late writes fail", func(t *testing.T) {
+		lateErr := make(chan error, 1)
+		handler := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			time.Sleep(10 * time.Millisecond)
+			w.Header().Set("X-Late", "yes")
+			_, err := w.Write([]byte("too late"))
+			lateErr <- err
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/test", nil))
+
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("expected status 504, got %d", rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), `"code":"TIMEOUT"`) {
+			t.Errorf("expected TIMEOUT envelope, got: %s", rec.Body.String())
+		}
+		if err := <-lateErr; err != http.ErrHandlerTimeout {
+			t.Errorf("expected ErrHandlerTimeout for late write, got %v", err)
+		}
+		if strings.Contains(rec.Body.String(), "too late") || rec.Header().Get("X-Late") != "" {
+			t.Error("expected late output to be discarded")
+		}
+	})
+
+	t.Run("panic reaches the caller goroutine", func(t *testing.T) {
+		handler := Recovery(logger.New(logger.Config{Output: io.Discard}))(
+			Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			})))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/test", nil))
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d", rec.Code)
+		}
+	})
+
+	t.Run("zero duration disables timeout", func(t *testing.T) {
+		var hadDeadline bool
+		handler := Timeout(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, hadDeadline = r.Context().Deadline()
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+		if hadDeadline {
+			t.Error("expected no deadline when duration is zero")
+		}
+	})
+}
+
+type memIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*IdempotentResponse
+}
+
+func newMemIdempotencyStore() *memIdempotencyStore {
+	return &memIdempotencyStore{entries: map[string]*IdempotentResponse{}}
+}
+
+func (s *memIdempotencyStore) Get(ctx context.Context, key string) (*IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[key], nil
+}
+
+func (s *memIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[key]; ok {
+		return false, nil
+	}
+	s.entries[key] = &IdempotentResponse{Fingerprint: fingerprint, InProgress: true}
+	return true, nil
+}
+
+func (s *memIdempotencyStore) Save(ctx context.Context, key string, resp *IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = resp
+	return nil
+}
+
+func (s *memIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	newHandler := func(store IdempotencyStore, status int, calls *int) http.Handler {
+		return Idempotency(IdempotencyOptions{Store: store})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls++
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"call":%d,"body":%q}`, *calls, body)
+		}))
+	}
+	do := func(h http.Handler, method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("replays stored response", func(t *testing.T) {
+		calls := 0
+		h := newHandler(newMemIdempotencyStore(), http.StatusCreated, &calls)
+
+		first := do(h, "POST", "/jobs", "k1", `{"a":1}`)
+		second := do(h, "POST", "/jobs", "k1", `{"a":1}`)
+
+		if calls != 1 {
+			t.Fatalf("expected handler called once, got %d", calls)
+		}
+		if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+			t.Errorf("expected replay of %d %s, got %d %s", first.Code, first.Body, second.Code, second.Body)
+		}
+		if second.Header().Get(IdempotentReplayedHeader) != "true" {
+			t.Error("expected replayed header")
+		}
+		if first.Body.String() != `{"call":1,"body":"{\"a\":1}"}` {
+			t.Errorf("expected handler to see the full body, got %s", first.Body)
+		}
+	})
+
+	t.Run("keys are scoped by route", func(t *testing.T) {
+		calls := 0
+		h := newHandler(newMemIdempotencyStore(), http.StatusOK, &calls)
+
+		do(h, "POST", "/jobs", "k1", "")
+		do(h, "POST", "/templates", "k1", "")
+
+		if calls != 2 {
+			t.Errorf("expected handler called twice, got %d", calls)
+		}
+	})
+
+	t.Run("different body is rejected", func(t *testing.T) {
+		calls := 0
+		h := newHandler(newMemIdempotencyStore(), http.StatusOK, &calls)
+
+		do(h, "POST", "/jobs", "k1", `{"a":1}`)
+		rec := do(h, "POST", "/jobs", "k1", `{"a":2}`)
+
+		if rec.Code != http.StatusBadRequest || calls != 1 {
+			t.Errorf("expected 400 without calling handler, got %d (calls=%d)", rec.Code, calls)
+		}
+	})
+
+	t.Run("in-flight key conflicts", func(t *testing.T) {
+		fp, _, _ := requestFingerprint(httptest.NewRequest("POST", "/jobs", strings.NewReader("")))
+		store := newMemIdempotencyStore()
+		store.entries[idempotencyStoreKey("anonymous", "POST", "/jobs", "k1")] =
+			&IdempotentResponse{Fingerprint: fp, InProgress: true}
+		calls := 0
+		h := newHandler(store, http.StatusOK, &calls)
+
+		rec := do(h, "POST", "/jobs", "k1", "")
+		if rec.Code != http.StatusConflict || calls != 0 {
+			t.Errorf("expected 409 without calling handler, got %d (calls=%d)", rec.Code, calls)
+		}
+	})
+
+	t.Run("server errors are not stored", func(t *testing.T) {
+		calls := 0
+		h := newHandler(newMemIdempotencyStore(), http.StatusInternalServerError, &calls)
+
+		do(h, "POST", "/jobs", "k1", "")
+		do(h, "POST", "/jobs", "k1", "")
+
+		if calls != 2 {
+			t.Errorf("expected retry after 5xx to run handler, got %d calls", calls)
+		}
+	})
+
+	t.Run("multipart matches by content, not boundary", func(t *testing.T) {
+		calls := 0
+		h := newHandler(newMemIdempotencyStore(), http.StatusCreated, &calls)
+		upload := func(boundary, file string) *httptest.ResponseRecorder {
+			var buf bytes.Buffer
+			mw := multipart.NewWriter(&buf)
+			_ = mw.SetBoundary(boundary)
+			_ = mw.WriteField("kind", "image")
+			fw, _ := mw.CreateFormFile("file", "a.png")
+			_, _ = io.WriteString(fw, file)
+			_ = mw.Close()
+			req := httptest.NewRequest("POST", "/assets", &buf)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			req.Header.Set(IdempotencyKeyHeader, "k1")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			return rec
+		}
+
+		first := upload("boundary-one", "png bytes")
+		if !strings.Contains(first.Body.String(), "png bytes") {
+			t.Errorf("handler did not get the full body: %s", first.Body)
+		}
+		second := upload("boundary-two", "png bytes")
+		if calls != 1 || second.Header().Get(IdempotentReplayedHeader) != "true" {
+			t.Errorf("same upload with a new boundary: calls=%d, replayed=%q; want a replay",
+				calls, second.Header().Get(IdempotentReplayedHeader))
+		}
+		if rec := upload("boundary-three", "other bytes"); rec.Code != http.StatusBadRequest || calls != 1 {
+			t.Errorf("another file with the same key = %d (calls=%d), want 400 without calling handler", rec.Code, calls)
+		}
+	})
+
+	t.Run("large bodies are compared in full", func(t *testing.T) {
+		calls := 0
+		h := Idempotency(IdempotencyOptions{Store: newMemIdempotencyStore()})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			n, _ := io.Copy(io.Discard, r.Body)
+			fmt.Fprintf(w, `{"read":%d}`, n)
+		}))
+		prefix := strings.Repeat("a", maxFingerprintBytes+10)
+
+		first := do(h, "POST", "/jobs", "k1", prefix+"one")
+		if want := fmt.Sprintf(`{"read":%d}`, len(prefix)+3); first.Body.String() != want {
+			t.Errorf("handler read %s, want %s", first.Body, want)
+		}
+		if rec := do(h, "POST", "/jobs", "k1", prefix+"two"); rec.Code != http.StatusBadRequest || calls != 1 {
+			t.Errorf("body differing past the first MiB = %d (calls=%d), want 400 without calling handler", rec.Code, calls)
+		}
+		if rec := do(h, "POST", "/jobs", "k1", prefix+"one"); rec.Header().Get(IdempotentReplayedHeader) != "true" {
+			t.Errorf("same large body was not replayed: %d %s", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("body over the limit is rejected", func(t *testing.T) {
+		calls := 0
+		h := Idempotency(IdempotencyOptions{Store: newMemIdempotencyStore(), MaxBodyBytes: 16})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+		}))
+
+		rec := do(h, "POST", "/jobs", "k1", strings.Repeat("a", 17))
+		if rec.Code != http.StatusRequestEntityTooLarge || calls != 0 {
+			t.Errorf("expected 413 without calling handler, got %d (calls=%d)", rec.Code, calls)
+		}
+		if !strings.Contains(rec.Body.String(), `"code":"PAYLOAD_TOO_LARGE"`) {
+			t.Errorf("unexpected body %s", rec.Body)
+		}
+		if rec := do(h, "POST", "/jobs", "k2", strings.Repeat("a", 16)); rec.Code != http.StatusOK || calls != 1 {
+			t.Errorf("body at the limit = %d (calls=%d), want it served", rec.Code, calls)
+		}
+	})
+
+	t.Run("panicking handler releases its key", func(t *testing.T) {
+		store := newMemIdempotencyStore()
+		h := Idempotency(IdempotencyOptions{Store: store})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))
+		func() {
+			defer func() {
+				if p := recover(); p != "boom" {
+					t.Errorf("recovered %v, want the handler's panic", p)
+				}
+			}()
+			do(h, "POST", "/jobs", "k1", `{"a":1}`)
+		}()
+		if len(store.entries) != 0 {
+			t.Errorf("store holds %d entries after a panic, want the key released", len(store.entries))
+		}
+	})
+
+	t.Run("ignored without key or for GET", func(t *testing.T) {
+		calls := 0
+		h := newHandler(newMemIdempotencyStore(), http.StatusOK, &calls)
+
+		do(h, "POST", "/jobs", "", "")
+		do(h, "POST", "/jobs", "", "")
+		do(h, "GET", "/jobs", "k1", "")
+		do(h, "GET", "/jobs", "k1", "")
+
+		if calls != 4 {
+			t.Errorf("expected every request to reach handler, got %d calls", calls)
+		}
+	})
+}
+
+func TestDeprecation(t *testing.T) {
+	sunset := time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC)
+	h := Deprecation(DeprecationOptions{
+		Successor: func(r *http.Request) string { return "/v1" + r.URL.Path },
+		Sunset:    sunset,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", `</jobs?cursor=x>; rel="next"`)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/jobs", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("expected Deprecation true, got %q", got)
+	}
+	links := rec.Header().Values("Link")
+	if len(links) != 2 || links[0] != `</v1/jobs>; rel="successor-version"` {
+		t.Errorf("expected successor and handler links, got %q", links)
+	}
+	if got := rec.Header().Get("Sunset"); got != "Sun, 31 Jan 2027 00:00:00 GMT" {
+		t.Errorf("unexpected Sunset %q", got)
 	}
 }
 
@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"gala/internal/pkg/logger"
+)
+
+// DefaultSnapshotBodyLimit is how much of the request body Recovery keeps.
+const DefaultSnapshotBodyLimit = 4 << 10
+
+// maxSnapshotHeaderLen caps each header value in a snapshot.
+const maxSnapshotHeaderLen = 512
+
+// RequestSnapshot is a bounded, credential-free view of a request taken
+// when a handler panics.
+type RequestSnapshot struct {
+	RequestID  string            `json:"request_id,omitempty"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Route      string            `json:"route,omitempty"`
+	Query      string            `json:"query,omitempty"`
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	// Body holds the start of what the handler had read before panicking.
+	Body          string `json:"body,omitempty"`
+	BodyTruncated bool   `json:"body_truncated,omitempty"`
+}
+
+// PanicReport describes a recovered panic.
+type PanicReport struct {
+	Value   any
+	Stack   []byte
+	Request RequestSnapshot
+}
+
+// PanicReporter forwards panics to an external error tracker.
+type PanicReporter interface {
+	ReportPanic(ctx context.Context, report PanicReport)
+}
+
+// PanicReporterFunc adapts a function to PanicReporter.
+type PanicReporterFunc func(ctx context.Context, report PanicReport)
+
+// ReportPanic calls f.
+func (f PanicReporterFunc) ReportPanic(ctx context.Context, report PanicReport) {
+	f(ctx, report)
+}
+
+// RecoveryOptions configures RecoveryWithOptions.
+type RecoveryOptions struct {
+	// BodyLimit is how many body bytes to keep (default
+	// DefaultSnapshotBodyLimit); negative disables body capture.
+	BodyLimit int
+	// Reporter, if set, receives every panic after it is logged.
+	Reporter PanicReporter
+}
+
+// Recovery recovers from panics and logs them.
+func Recovery(log *logger.Logger) func(http.Handler) http.Handler {
+	return RecoveryWithOptions(log, RecoveryOptions{})
+}
+
+// RecoveryWithOptions recovers from panics, logs the stack with a snapshot
+// of the request (sensitive headers removed, body bounded) and optionally
+// forwards both to a reporter.
+func RecoveryWithOptions(log *logger.Logger, opt RecoveryOptions) func(http.Handler) http.Handler {
+	if opt.BodyLimit == 0 {
+		opt.BodyLimit = DefaultSnapshotBodyLimit
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body *capturingBody
+			if opt.BodyLimit > 0 && r.Body != nil && r.Body != http.NoBody {
+				body = &capturingBody{ReadCloser: r.Body, limit: opt.BodyLimit}
+				r.Body = body
+			}
+
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					// Deliberate abort; let net/http handle it quietly.
+					panic(rec)
+				}
+
+				stack := debug.Stack()
+				snap := snapshotRequest(r, body)
+
+				log.FromContext(r.Context()).Error("panic recovered",
+					"panic", rec,
+					"stack", string(stack),
+					"method", r.Method,
+					"path", r.URL.Path,
+					"request", snap,
+				)
+				if opt.Reporter != nil {
+					opt.Reporter.ReportPanic(r.Context(), PanicReport{Value: rec, Stack: stack, Request: snap})
+				}
+
+				// Return 500 error
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"error":{"code":"INTERNAL_ERROR","message":"internal server error"}}`))
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// sensitiveHeaders are never included in snapshots.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+	"X-Gala-Signature":    true,
+}
+
+func isSensitiveHeader(name string) bool {
+	if sensitiveHeaders[name] {
+		return true
+	}
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "token") || strings.Contains(lower, "secret")
+}
+
+func snapshotRequest(r *http.Request, body *capturingBody) RequestSnapshot {
+	snap := RequestSnapshot{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      r.URL.RawQuery,
+		RemoteAddr: r.RemoteAddr,
+		Headers:    make(map[string]string, len(r.Header)),
+	}
+	if id, ok := r.Context().Value(logger.RequestIDKey).(string); ok {
+		snap.RequestID = id
+	}
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		snap.Route = rctx.RoutePattern()
+	}
+	for name, values := range r.Header {
+		if isSensitiveHeader(name) {
+			continue
+		}
+		v := strings.Join(values, ", ")
+		if len(v) > maxSnapshotHeaderLen {
+			v = v[:maxSnapshotHeaderLen] + "..."
+		}
+		snap.Headers[name] = v
+	}
+	if body != nil {
+		snap.Body = body.buf.String()
+		snap.BodyTruncated = body.truncated
+	}
+	return snap
+}
+
+// capturingBody keeps the first limit bytes the handler reads, so a panic
+// snapshot can include the body without buffering it up front.
+type capturingBody struct {
+	io.ReadCloser
+	limit     int
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (b *capturingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if room := b.limit - b.buf.Len(); room > 0 {
+			if n > room {
+				b.buf.Write(p[:room])
+				b.truncated = true
+			} else {
+				b.buf.Write(p[:n])
+			}
+		} else {
+			b.truncated = true
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/i18n"
+)
+
+// Timeout bounds request handling to duration. Like http.TimeoutHandler,
+// the handler writes into a buffer that is only copied to the client if it
+// finishes in time; otherwise a 504 TIMEOUT envelope is sent and later
+// writes from the handler fail with http.ErrHandlerTimeout.
+//
+// Because responses are buffered, do not use it on streaming routes. A
+// duration <= 0 disables the timeout.
+func Timeout(duration time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if duration <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), duration)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{h: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				// Re-panic on the request goroutine so Recovery sees it.
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				dst := w.Header()
+				for k, vv := range tw.h {
+					dst[k] = vv
+				}
+				if !tw.wroteHeader {
+					tw.code = http.StatusOK
+				}
+				w.WriteHeader(tw.code)
+				_, _ = w.Write(tw.buf.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if ctx.Err() == context.DeadlineExceeded {
+					w.Header().Set("Content-Language", i18n.Language(r.Context()))
+					WriteErrorResponse(w, errors.CodeTimeout,
+						i18n.Localize(r.Context(), errors.CodeTimeout, "request timeout", nil), nil)
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers a response until Timeout decides whether to send
+// it. All access is guarded by mu since the handler runs on its own
+// goroutine.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	h           http.Header
+	buf         bytes.Buffer
+	code        int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.h }
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	tw.wroteHeader = true
+	tw.code = code
+}
@@ -0,0 +1,286 @@
+// Package migrate applies versioned SQL migrations to Postgres and records
+// them in the schema_migrations table.
+//
+// Every migration runs in its own transaction, and a session advisory lock
+// serializes runners, so several instances auto-migrating at startup apply
+// each version once.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"gala/internal/pkg/db"
+	"gala/internal/pkg/logger"
+)
+
+// lockID is the advisory lock key held while migrating.
+const lockID = 7_256_010_686
+
+// fileRe matches NNN_name.up.sql and NNN_name.down.sql.
+var fileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one schema version.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	// Down is empty when the migration cannot be reverted.
+	Down string
+}
+
+// Status is a migration and when it was applied (nil if pending).
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Load reads the migrations in the root of fsys, sorted by version. Files
+// not named like migrations are ignored; a version without an up file, or
+// with two names, is an error.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		m := fileRe.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", e.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, path.Join(".", e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", e.Name(), err)
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration version %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+	log        *logger.Logger
+}
+
+// New loads the migrations from fsys (see Load).
+func New(pool *pgxpool.Pool, fsys fs.FS, log *logger.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{pool: pool, migrations: migrations, log: log.WithComponent("migrate")}, nil
+}
+
+// Up applies every pending migration in order and returns how many ran.
+// It stops at the first failure; earlier migrations stay applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	n := 0
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig, true); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and
+// returns how many were reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	n := 0
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && n < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted: no down file", mig.Version, mig.Name)
+			}
+			if err := m.apply(ctx, conn, mig, false); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Status lists every known migration with its applied time.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		st := Status{Migration: mig}
+		if at, ok := applied[mig.Version]; ok {
+			st.AppliedAt = &at
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// withLock runs fn on one connection holding the migration advisory lock.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	// Waiting for the lock and running migrations may take longer than the
+	// pool's per-statement timeout
+	ctx = db.WithQueryTimeout(ctx, 0)
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Use a fresh context: ctx may be canceled, and a held session lock
+		// would block every later runner until the connection closes.
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.Exec(unlockCtx, `SELECT pg_advisory_unlock($1)`, lockID); err != nil {
+			m.log.Warn("failed to release migration lock", "error", err.Error())
+		}
+	}()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// apply runs one migration up or down and records it, in one transaction.
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, mig Migration, up bool) error {
+	direction, body := "up", mig.Up
+	if !up {
+		direction, body = "down", mig.Down
+	}
+	start := time.Now()
+
+	err := db.WithTx(ctx, conn, func(tx pgx.Tx) error {
+		// No arguments: pgx uses the simple protocol, which allows several
+		// statements per file.
+		if _, err := tx.Exec(ctx, body); err != nil {
+			return err
+		}
+		if up {
+			_, err := tx.Exec(ctx,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+				mig.Version, mig.Name,
+			)
+			return err
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version=$1`, mig.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s (%s): %w", mig.Version, mig.Name, direction, err)
+	}
+
+	m.log.Info("migration applied",
+		"version", mig.Version,
+		"name", mig.Name,
+		"direction", direction,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return nil
+}
+
+func ensureTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		  version    BIGINT PRIMARY KEY,
+		  name       TEXT NOT NULL,
+		  applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[int64]time.Time{}
+	for rows.Next() {
+		var v int64
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, fmt.Errorf("read schema_migrations: %w", err)
+		}
+		out[v] = at
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"gala/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_add_index.up.sql": {Data: []byte("CREATE INDEX i ON t (c);")},
+		"001_init.up.sql":      {Data: []byte("CREATE TABLE t (c INT);")},
+		"001_init.down.sql":    {Data: []byte("DROP TABLE t;")},
+		"README.md":            {Data: []byte("ignored")},
+	}
+
+	got, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d migrations, want 2", len(got))
+	}
+	if got[0].Version != 1 || got[0].Name != "init" || got[0].Down != "DROP TABLE t;" {
+		t.Errorf("first migration = %+v", got[0])
+	}
+	if got[1].Version != 2 || got[1].Down != "" {
+		t.Errorf("second migration = %+v", got[1])
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			name: "down without up",
+			fsys: fstest.MapFS{"001_init.down.sql": {Data: []byte("DROP TABLE t;")}},
+			want: "no up file",
+		},
+		{
+			name: "two names for one version",
+			fsys: fstest.MapFS{
+				"001_init.up.sql":  {Data: []byte("SELECT 1;")},
+				"001_other.up.sql": {Data: []byte("SELECT 1;")},
+			},
+			want: "two names",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	got, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load embedded migrations: %v", err)
+	}
+	if len(got) == 0 || got[0].Version != 1 {
+		t.Fatalf("expected migrations starting at version 1, got %+v", got)
+	}
+	for i, m := range got {
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+		if i > 0 && m.Version == got[i-1].Version {
+			t.Errorf("duplicate version %d", m.Version)
+		}
+	}
+}
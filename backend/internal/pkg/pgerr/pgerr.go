@@ -0,0 +1,142 @@
+// Package pgerr translates pgx/pgconn errors into GALA errors.
+// Callers get a coded *errors.Error instead of checking SQLSTATEs inline.
+package pgerr
+
+import (
+	"context"
+	stderrors "errors"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"gala/internal/pkg/errors"
+)
+
+// PostgreSQL SQLSTATE codes used for translation.
+const (
+	UniqueViolation      = "23505"
+	ForeignKeyViolation  = "23503"
+	NotNullViolation     = "23502"
+	CheckViolation       = "23514"
+	InvalidTextRep       = "22P02"
+	UndefinedTable       = "42P01"
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
+	QueryCanceled        = "57014"
+	AdminShutdown        = "57P01"
+	CannotConnectNow     = "57P03"
+	TooManyConnections   = "53300"
+)
+
+// Translate maps a database error to an error code:
+//
+//	pgx.ErrNoRows                       -> CodeNotFound
+//	unique_violation                    -> CodeAlreadyExists
+//	foreign_key_violation               -> CodeFailedPrecond
+//	not_null / check / invalid text     -> CodeValidation
+//	serialization / deadlock            -> CodeConflict (retryable)
+//	query canceled, context deadline    -> CodeTimeout
+//	connection failures, shutdown, 08*  -> CodeUnavailable
+//	anything else                       -> CodeInternal
+func Translate(err error) errors.Code {
+	switch {
+	case err == nil:
+		return ""
+	case stderrors.Is(err, pgx.ErrNoRows):
+		return errors.CodeNotFound
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return errors.CodeTimeout
+	case stderrors.Is(err, context.Canceled):
+		return errors.CodeTimeout
+	}
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case UniqueViolation:
+			return errors.CodeAlreadyExists
+		case ForeignKeyViolation:
+			return errors.CodeFailedPrecond
+		case NotNullViolation, CheckViolation, InvalidTextRep:
+			return errors.CodeValidation
+		case SerializationFailure, DeadlockDetected:
+			return errors.CodeConflict
+		case QueryCanceled:
+			return errors.CodeTimeout
+		case AdminShutdown, CannotConnectNow, TooManyConnections:
+			return errors.CodeUnavailable
+		}
+		// Class 08 = connection exception
+		if strings.HasPrefix(pgErr.Code, "08") {
+			return errors.CodeUnavailable
+		}
+		return errors.CodeInternal
+	}
+
+	var connErr *pgconn.ConnectError
+	if stderrors.As(err, &connErr) {
+		return errors.CodeUnavailable
+	}
+	var netErr net.Error
+	if stderrors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return errors.CodeTimeout
+		}
+		return errors.CodeUnavailable
+	}
+	if pgconn.SafeToRetry(err) {
+		return errors.CodeUnavailable
+	}
+
+	return errors.CodeInternal
+}
+
+// Wrap wraps a database error as *errors.Error with the translated code.
+// Constraint names and SQLSTATE are kept as fields for logging. It returns
+// nil for a nil err.
+func Wrap(err error, op string, message string) *errors.Error {
+	if err == nil {
+		return nil
+	}
+	e := errors.WrapWithCode(err, Translate(err), op, message)
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) {
+		e.WithField("sqlstate", pgErr.Code)
+		if pgErr.ConstraintName != "" {
+			e.WithField("constraint", pgErr.ConstraintName)
+		}
+	}
+	return e
+}
+
+// IsNoRows reports whether err is pgx.ErrNoRows.
+func IsNoRows(err error) bool {
+	return stderrors.Is(err, pgx.ErrNoRows)
+}
+
+// IsUniqueViolation reports whether err is a unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	return hasCode(err, UniqueViolation)
+}
+
+// IsForeignKeyViolation reports whether err is a foreign key violation.
+func IsForeignKeyViolation(err error) bool {
+	return hasCode(err, ForeignKeyViolation)
+}
+
+// IsUndefinedTable reports whether err is an undefined table error
+// (used to tolerate optional tables that are not migrated yet).
+func IsUndefinedTable(err error) bool {
+	return hasCode(err, UndefinedTable)
+}
+
+func hasCode(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) {
+		return pgErr.Code == code
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package pgerr
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"gala/internal/pkg/errors"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code errors.Code
+	}{
+		{"nil", nil, ""},
+		{"no rows", pgx.ErrNoRows, errors.CodeNotFound},
+		{"wrapped no rows", fmt.Errorf("scan: %w", pgx.ErrNoRows), errors.CodeNotFound},
+		{"unique violation", &pgconn.PgError{Code: UniqueViolation}, errors.CodeAlreadyExists},
+		{"foreign key violation", &pgconn.PgError{Code: ForeignKeyViolation}, errors.CodeFailedPrecond},
+		{"not null violation", &pgconn.PgError{Code: NotNullViolation}, errors.CodeValidation},
+		{"invalid text", &pgconn.PgError{Code: InvalidTextRep}, errors.CodeValidation},
+		{"deadlock", &pgconn.PgError{Code: DeadlockDetected}, errors.CodeConflict},
+		{"query canceled", &pgconn.PgError{Code: QueryCanceled}, errors.CodeTimeout},
+		{"admin shutdown", &pgconn.PgError{Code: AdminShutdown}, errors.CodeUnavailable},
+		{"connection exception class", &pgconn.PgError{Code: "08006"}, errors.CodeUnavailable},
+		{"deadline exceeded", context.DeadlineExceeded, errors.CodeTimeout},
+		{"undefined table", &pgconn.PgError{Code: UndefinedTable}, errors.CodeInternal},
+		{"unknown", fmt.Errorf("boom"), errors.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Translate(tt.err); got != tt.code {
+				t.Errorf("expected code=%q, got %q", tt.code, got)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	if Wrap(nil, "op", "msg") != nil {
+		t.Error("Wrap(nil) should return nil")
+	}
+
+	pgErr := &pgconn.PgError{Code: UniqueViolation, ConstraintName: "templates_name_key"}
+	err := Wrap(pgErr, "templates.create", "insert failed")
+
+	if err.Code != errors.CodeAlreadyExists {
+		t.Errorf("expected code=%s, got %s", errors.CodeAlreadyExists, err.Code)
+	}
+	if err.HTTPStatus() != 409 {
+		t.Errorf("expected status=409, got %d", err.HTTPStatus())
+	}
+	if err.Fields["sqlstate"] != UniqueViolation {
+		t.Errorf("expected sqlstate field, got %v", err.Fields["sqlstate"])
+	}
+	if err.Fields["constraint"] != "templates_name_key" {
+		t.Errorf("expected constraint field, got %v", err.Fields["constraint"])
+	}
+	if !errors.Is(err, pgErr) {
+		t.Error("expected underlying pg error to be preserved")
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	if !IsNoRows(fmt.Errorf("x: %w", pgx.ErrNoRows)) {
+		t.Error("expected IsNoRows to match wrapped ErrNoRows")
+	}
+	if !IsUniqueViolation(&pgconn.PgError{Code: UniqueViolation}) {
+		t.Error("expected IsUniqueViolation to match")
+	}
+	if !IsForeignKeyViolation(&pgconn.PgError{Code: ForeignKeyViolation}) {
+		t.Error("expected IsForeignKeyViolation to match")
+	}
+	if !IsUndefinedTable(&pgconn.PgError{Code: UndefinedTable}) {
+		t.Error("expected IsUndefinedTable to match")
+	}
+	if IsUniqueViolation(fmt.Errorf("boom")) {
+		t.Error("expected plain error not to match")
+	}
+}
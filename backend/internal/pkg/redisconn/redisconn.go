@@ -0,0 +1,152 @@
+// Package redisconn builds the Redis client from configuration for the
+// supported topologies: a single node, Sentinel failover or Cluster.
+package redisconn
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Topologies accepted by Config.Mode.
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel   = "sentinel"
+	ModeCluster    = "cluster"
+)
+
+// Config describes how to reach Redis.
+type Config struct {
+	// Mode is standalone (default), sentinel or cluster.
+	Mode string
+	// Addrs are host:port pairs: the server, the sentinels or the cluster
+	// seed nodes depending on Mode.
+	Addrs []string
+
+	Username string
+	Password string
+	// PasswordFile, if set, takes precedence over Password (e.g. a mounted
+	// secret).
+	PasswordFile string
+	// DB is the database index; Cluster only supports 0.
+	DB int
+
+	// MasterName is the Sentinel master set name (sentinel mode only).
+	MasterName       string
+	SentinelUsername string
+	SentinelPassword string
+
+	TLS bool
+	// TLSServerName overrides the name checked against the certificate.
+	TLSServerName string
+	// TLSCAFile is a PEM bundle of CAs to trust instead of the system pool.
+	TLSCAFile string
+	// TLSInsecureSkipVerify disables certificate checks; only for local
+	// testing against self-signed servers.
+	TLSInsecureSkipVerify bool
+}
+
+// New validates cfg and creates the client. Like redis.NewClient it does
+// not connect; ping the client to check connectivity.
+func New(cfg Config) (redis.UniversalClient, error) {
+	opt, err := cfg.options()
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.mode() {
+	case ModeSentinel:
+		return redis.NewFailoverClient(opt.Failover()), nil
+	case ModeCluster:
+		return redis.NewClusterClient(opt.Cluster()), nil
+	default:
+		return redis.NewClient(opt.Simple()), nil
+	}
+}
+
+func (c Config) mode() string {
+	m := strings.ToLower(strings.TrimSpace(c.Mode))
+	if m == "" {
+		return ModeStandalone
+	}
+	return m
+}
+
+func (c Config) options() (*redis.UniversalOptions, error) {
+	addrs := make([]string, 0, len(c.Addrs))
+	for _, a := range c.Addrs {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("redis: at least one address is required")
+	}
+
+	opt := &redis.UniversalOptions{
+		Addrs:            addrs,
+		Username:         c.Username,
+		Password:         c.Password,
+		DB:               c.DB,
+		SentinelUsername: c.SentinelUsername,
+		SentinelPassword: c.SentinelPassword,
+	}
+
+	switch c.mode() {
+	case ModeStandalone:
+		if len(addrs) > 1 {
+			return nil, fmt.Errorf("redis: standalone mode takes one address, got %d", len(addrs))
+		}
+	case ModeSentinel:
+		if c.MasterName == "" {
+			return nil, fmt.Errorf("redis: sentinel mode requires a master name")
+		}
+		opt.MasterName = c.MasterName
+	case ModeCluster:
+		if c.DB != 0 {
+			return nil, fmt.Errorf("redis: cluster mode only supports DB 0")
+		}
+	default:
+		return nil, fmt.Errorf("redis: unknown mode %q (want standalone, sentinel or cluster)", c.Mode)
+	}
+
+	if c.PasswordFile != "" {
+		b, err := os.ReadFile(c.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis: read password file: %w", err)
+		}
+		opt.Password = strings.TrimSpace(string(b))
+	}
+
+	if c.TLS {
+		tc, err := c.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		opt.TLSConfig = tc
+	}
+	return opt, nil
+}
+
+func (c Config) tlsConfig() (*tls.Config, error) {
+	tc := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.TLSServerName,
+		InsecureSkipVerify: c.TLSInsecureSkipVerify,
+	}
+	if c.TLSCAFile != "" {
+		pem, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis: read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis: no certificates found in %s", c.TLSCAFile)
+		}
+		tc.RootCAs = pool
+	}
+	return tc, nil
+}
@@ -0,0 +1,89 @@
+package redisconn
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNewTopologies(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		cluster bool
+	}{
+		{"standalone default", Config{Addrs: []string{"localhost:6379"}, DB: 2}, false},
+		{"sentinel", Config{Mode: "sentinel", Addrs: []string{"s1:26379", "s2:26379"}, MasterName: "mymaster"}, false},
+		{"cluster", Config{Mode: "CLUSTER", Addrs: []string{"n1:6379"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb, err := New(tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer rdb.Close()
+			_, isCluster := rdb.(*redis.ClusterClient)
+			if isCluster != tt.cluster {
+				t.Errorf("expected cluster=%v, got %T", tt.cluster, rdb)
+			}
+		})
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"no address", Config{Addrs: []string{" "}}},
+		{"standalone with many addresses", Config{Addrs: []string{"a:1", "b:1"}}},
+		{"sentinel without master", Config{Mode: ModeSentinel, Addrs: []string{"s1:26379"}}},
+		{"cluster with db", Config{Mode: ModeCluster, Addrs: []string{"n1:6379"}, DB: 1}},
+		{"unknown mode", Config{Mode: "ring", Addrs: []string{"a:1"}}},
+		{"missing password file", Config{Addrs: []string{"a:1"}, PasswordFile: "/nonexistent/redis-pass"}},
+		{"missing CA file", Config{Addrs: []string{"a:1"}, TLS: true, TLSCAFile: "/nonexistent/ca.pem"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestOptionsCredentialsAndTLS(t *testing.T) {
+	passFile := filepath.Join(t.TempDir(), "redis-pass")
+	if err := os.WriteFile(passFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	opt, err := Config{
+		Addrs:         []string{"redis.example.com:6380"},
+		Username:      "gala",
+		Password:      "ignored",
+		PasswordFile:  passFile,
+		TLS:           true,
+		TLSServerName: "redis.example.com",
+	}.options()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opt.Username != "gala" || opt.Password != "from-file" {
+		t.Errorf("expected file password to win, got user=%q pass=%q", opt.Username, opt.Password)
+	}
+	if opt.TLSConfig == nil || opt.TLSConfig.ServerName != "redis.example.com" {
+		t.Errorf("expected TLS config with server name, got %+v", opt.TLSConfig)
+	}
+
+	opt, err = Config{Addrs: []string{"localhost:6379"}}.options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opt.TLSConfig != nil {
+		t.Error("expected no TLS config when TLS is off")
+	}
+}
@@ -0,0 +1,209 @@
+// Package reload re-applies a subset of configuration to a running process
+// on SIGHUP or on demand (e.g. from an admin endpoint).
+//
+// Configuration comes from the environment, which cannot change after the
+// process starts, so reloadable values are read from an optional env file
+// (KEY=VALUE lines, as mounted from a ConfigMap or secret) layered on top
+// of it. Keys absent from the file keep their environment value.
+package reload
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/logger"
+)
+
+// Values is a configuration snapshot: the environment overlaid with the
+// config file.
+type Values map[string]string
+
+// Get returns the value for key, or def if it is unset or blank.
+func (v Values) Get(key, def string) string {
+	if s := strings.TrimSpace(v[key]); s != "" {
+		return s
+	}
+	return def
+}
+
+// Bool returns the value for key parsed as a boolean (1/true/yes/on), or
+// def if it is unset.
+func (v Values) Bool(key string, def bool) bool {
+	s := strings.ToLower(v.Get(key, ""))
+	if s == "" {
+		return def
+	}
+	return s == "1" || s == "true" || s == "yes" || s == "on"
+}
+
+// Int returns the value for key parsed as an integer, or def if it is
+// unset or invalid.
+func (v Values) Int(key string, def int) int {
+	n, err := strconv.Atoi(v.Get(key, ""))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// CSV returns the comma-separated value for key with blanks dropped, or
+// def if nothing is left.
+func (v Values) CSV(key string, def []string) []string {
+	var out []string
+	for _, p := range strings.Split(v.Get(key, ""), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	if len(out) == 0 {
+		return def
+	}
+	return out
+}
+
+// Load reads the environment and, if path is not empty, overlays the env
+// file at path.
+func Load(path string) (Values, error) {
+	v := Values{}
+	for _, kv := range os.Environ() {
+		if k, val, ok := strings.Cut(kv, "="); ok {
+			v[k] = val
+		}
+	}
+	if path == "" {
+		return v, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open config file: %w", err)
+	}
+	defer f.Close()
+
+	file, err := ParseEnvFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	for k, val := range file {
+		v[k] = val
+	}
+	return v, nil
+}
+
+// ParseEnvFile parses KEY=VALUE lines. Blank lines and lines starting
+// with # are skipped, an "export " prefix is allowed, and values may be
+// wrapped in single or double quotes.
+func ParseEnvFile(r io.Reader) (map[string]string, error) {
+	out := map[string]string{}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		k, val, ok := strings.Cut(line, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", n)
+		}
+		val = strings.TrimSpace(val)
+		if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+			val = val[1 : len(val)-1]
+		}
+		out[k] = val
+	}
+	return out, sc.Err()
+}
+
+// Handler applies new values to one component.
+type Handler struct {
+	Name  string
+	Apply func(ctx context.Context, v Values) error
+}
+
+// Manager runs the registered handlers on reload.
+type Manager struct {
+	log  *logger.Logger
+	path string
+
+	mu       sync.Mutex // serializes reloads
+	handlers []Handler
+}
+
+// NewManager creates a manager reading the config file at path (may be
+// empty to reload from the environment only).
+func NewManager(log *logger.Logger, path string) *Manager {
+	return &Manager{log: log.WithComponent("reload"), path: path}
+}
+
+// Register adds a handler. Handlers run in registration order.
+func (m *Manager) Register(name string, apply func(ctx context.Context, v Values) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, Handler{Name: name, Apply: apply})
+}
+
+// Reload loads the configuration and applies it. Every handler runs even
+// if an earlier one fails; a handler that fails keeps its previous
+// configuration. It returns the names of the handlers that applied.
+func (m *Manager) Reload(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	start := time.Now()
+	v, err := Load(m.path)
+	if err != nil {
+		m.log.Error("config reload failed", "error", err.Error())
+		return nil, errors.WrapWithCode(err, errors.CodeFailedPrecond, "reload.load", err.Error())
+	}
+
+	applied := make([]string, 0, len(m.handlers))
+	var errs []error
+	for _, h := range m.handlers {
+		if err := h.Apply(ctx, v); err != nil {
+			m.log.Error("config reload handler failed", "name", h.Name, "error", err.Error())
+			errs = append(errs, errors.WrapWithCode(err, errors.CodeFailedPrecond, "reload."+h.Name, h.Name+": "+err.Error()))
+			continue
+		}
+		applied = append(applied, h.Name)
+	}
+
+	m.log.Info("configuration reloaded",
+		"applied", applied,
+		"failed", len(errs),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	if len(errs) > 0 {
+		return applied, errors.Aggregate(errs...)
+	}
+	return applied, nil
+}
+
+// Watch reloads on every SIGHUP until ctx is canceled. It blocks, so run
+// it in its own goroutine.
+func (m *Manager) Watch(ctx context.Context) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigChan:
+			m.log.Info("reload signal received", "signal", "SIGHUP")
+			_, _ = m.Reload(ctx)
+		}
+	}
+}
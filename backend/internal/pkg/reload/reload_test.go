@@ -0,0 +1,148 @@
+package reload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/logger"
+)
+
+func newTestLogger() *logger.Logger {
+	var buf bytes.Buffer
+	return logger.New(logger.Config{Level: "debug", Format: "json", Output: &buf})
+}
+
+func TestParseEnvFile(t *testing.T) {
+	in := `
+# comment
+LOG_LEVEL=debug
+export CORS_ALLOWED_ORIGINS = "https://a.example.com, https://b.example.com"
+EMPTY=
+QUOTED='x y'
+`
+	got, err := ParseEnvFile(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"LOG_LEVEL":            "debug",
+		"CORS_ALLOWED_ORIGINS": "https://a.example.com, https://b.example.com",
+		"EMPTY":                "",
+		"QUOTED":               "x y",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d keys, got %v", len(want), got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: expected %q, got %q", k, v, got[k])
+		}
+	}
+
+	if _, err := ParseEnvFile(strings.NewReader("NOT A PAIR")); err == nil {
+		t.Error("expected error for line without '='")
+	}
+}
+
+func TestLoadOverlaysFile(t *testing.T) {
+	t.Setenv("GALA_RELOAD_TEST_A", "env")
+	t.Setenv("GALA_RELOAD_TEST_B", "env")
+
+	path := filepath.Join(t.TempDir(), "gala.env")
+	if err := os.WriteFile(path, []byte("GALA_RELOAD_TEST_B=file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.Get("GALA_RELOAD_TEST_A", "") != "env" {
+		t.Errorf("expected env value to be kept, got %q", v["GALA_RELOAD_TEST_A"])
+	}
+	if v.Get("GALA_RELOAD_TEST_B", "") != "file" {
+		t.Errorf("expected file value to win, got %q", v["GALA_RELOAD_TEST_B"])
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestValues(t *testing.T) {
+	v := Values{"B": "yes", "N": "12", "BAD": "x", "L": " a, ,b "}
+
+	if !v.Bool("B", false) || v.Bool("MISSING", false) || !v.Bool("MISSING", true) {
+		t.Error("unexpected Bool result")
+	}
+	if v.Int("N", 0) != 12 || v.Int("BAD", 3) != 3 {
+		t.Error("unexpected Int result")
+	}
+	if got := v.CSV("L", nil); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("unexpected CSV result: %v", got)
+	}
+	if got := v.CSV("MISSING", []string{"def"}); len(got) != 1 || got[0] != "def" {
+		t.Errorf("expected default, got %v", got)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gala.env")
+	if err := os.WriteFile(path, []byte("LOG_LEVEL=warn\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewManager(newTestLogger(), path)
+
+	var order []string
+	var level string
+	m.Register("log", func(ctx context.Context, v Values) error {
+		order = append(order, "log")
+		level = v.Get("LOG_LEVEL", "info")
+		return nil
+	})
+	m.Register("broken", func(ctx context.Context, v Values) error {
+		order = append(order, "broken")
+		return fmt.Errorf("bad value")
+	})
+	m.Register("cors", func(ctx context.Context, v Values) error {
+		order = append(order, "cors")
+		return nil
+	})
+
+	applied, err := m.Reload(context.Background())
+	if err == nil || !errors.IsCode(err, errors.CodeFailedPrecond) {
+		t.Fatalf("expected FAILED_PRECONDITION, got %v", err)
+	}
+	if strings.Join(order, ",") != "log,broken,cors" {
+		t.Errorf("expected every handler to run in order, got %v", order)
+	}
+	if strings.Join(applied, ",") != "log,cors" {
+		t.Errorf("expected applied=[log cors], got %v", applied)
+	}
+	if level != "warn" {
+		t.Errorf("expected value from file, got %q", level)
+	}
+}
+
+func TestReloadLoadError(t *testing.T) {
+	m := NewManager(newTestLogger(), filepath.Join(t.TempDir(), "missing.env"))
+	called := false
+	m.Register("log", func(ctx context.Context, v Values) error {
+		called = true
+		return nil
+	})
+
+	if _, err := m.Reload(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+	if called {
+		t.Error("handlers must not run when the configuration cannot be loaded")
+	}
+}
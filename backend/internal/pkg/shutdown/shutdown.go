@@ -6,24 +6,57 @@ import (
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"gala/internal/pkg/logger"
 )
 
+// Stage orders shutdown work. Stages run one after another; the handlers
+// of a stage run concurrently (registered last, started first).
+type Stage int
+
+const (
+	// StageDrain stops taking new work and finishes what is in flight:
+	// the HTTP server stops accepting and waits for active requests, the
+	// worker stops popping jobs and finishes the current one.
+	StageDrain Stage = iota
+	// StageClose releases what in-flight work was using (database pools,
+	// Redis). It only starts once every drain handler has returned.
+	StageClose
+)
+
+func (s Stage) String() string {
+	switch s {
+	case StageDrain:
+		return "drain"
+	case StageClose:
+		return "close"
+	}
+	return "unknown"
+}
+
 // Manager handles graceful shutdown of services.
+//
+// Shutdown first marks the process as draining (see Draining, used by
+// /readyz), waits the pre-stop delay so load balancers stop routing to it,
+// then runs the drain stage and finally the close stage.
 type Manager struct {
-	log      *logger.Logger
-	timeout  time.Duration
-	handlers []Handler
-	mu       sync.Mutex
-	done     chan struct{}
+	log          *logger.Logger
+	timeout      time.Duration
+	preStopDelay time.Duration
+	handlers     []Handler
+	mu           sync.Mutex
+	done         chan struct{}
+	draining     atomic.Bool
+	once         sync.Once
 }
 
 // Handler is a function that performs cleanup during shutdown.
 type Handler struct {
 	Name    string
+	Stage   Stage
 	Cleanup func(ctx context.Context) error
 }
 
@@ -40,12 +73,31 @@ func NewManager(log *logger.Logger, timeout time.Duration) *Manager {
 	}
 }
 
-// Register adds a cleanup handler.
+// SetPreStopDelay sets how long Shutdown waits after flipping readiness
+// and before draining, so a Kubernetes endpoint (or any load balancer)
+// removes the instance before it stops accepting connections. It is not
+// counted against the shutdown timeout.
+func (m *Manager) SetPreStopDelay(d time.Duration) {
+	m.preStopDelay = d
+}
+
+// Draining reports whether shutdown has started; readiness checks should
+// fail from then on.
+func (m *Manager) Draining() bool {
+	return m.draining.Load()
+}
+
+// Register adds a cleanup handler to the drain stage.
 func (m *Manager) Register(name string, cleanup func(ctx context.Context) error) {
+	m.RegisterStage(StageDrain, name, cleanup)
+}
+
+// RegisterStage adds a cleanup handler to the given stage.
+func (m *Manager) RegisterStage(stage Stage, name string, cleanup func(ctx context.Context) error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.handlers = append(m.handlers, Handler{Name: name, Cleanup: cleanup})
-	m.log.Debug("registered shutdown handler", "name", name)
+	m.handlers = append(m.handlers, Handler{Name: name, Stage: stage, Cleanup: cleanup})
+	m.log.Debug("registered shutdown handler", "name", name, "stage", stage.String())
 }
 
 // RegisterSimple adds a simple cleanup handler without context.
@@ -56,11 +108,12 @@ func (m *Manager) RegisterSimple(name string, cleanup func()) {
 	})
 }
 
-// Wait blocks until shutdown signal is received, then runs cleanup.
+// Wait blocks until SIGINT or SIGTERM is received, then runs cleanup.
+// SIGHUP is left to reload.Manager.Watch.
 func (m *Manager) Wait() {
 	// Listen for shutdown signals
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	// Wait for signal
 	sig := <-sigChan
@@ -70,40 +123,96 @@ func (m *Manager) Wait() {
 	m.Shutdown()
 }
 
-// Shutdown runs all cleanup handlers.
+// Shutdown runs all cleanup handlers, stage by stage. If the timeout
+// expires, the remaining stages are skipped: closing pools under requests
+// that are still running would only turn a slow shutdown into errors.
+// Only the first call (of Shutdown or Fatal) runs the handlers; later ones
+// wait for it to finish.
 func (m *Manager) Shutdown() {
+	m.once.Do(func() { m.run(m.preStopDelay, m.timeout) })
+	<-m.done
+}
+
+// Fatal runs the cleanup handlers for a process about to exit on a fatal
+// error: without the pre-stop delay, and within timeout instead of the
+// configured one. If a shutdown is already running it waits up to timeout
+// for it. Use it as the logger exit hook:
+//
+//	logger.SetExitHook(func() { shutdownMgr.Fatal(5 * time.Second) })
+func (m *Manager) Fatal(timeout time.Duration) {
+	ran := false
+	m.once.Do(func() {
+		ran = true
+		m.run(0, timeout)
+	})
+	if ran {
+		return
+	}
+	// A handler may itself be the caller, so never wait unbounded.
+	select {
+	case <-m.done:
+	case <-time.After(timeout):
+	}
+}
+
+func (m *Manager) run(preStopDelay, timeout time.Duration) {
+	m.draining.Store(true)
+
 	m.mu.Lock()
 	handlers := make([]Handler, len(m.handlers))
 	copy(handlers, m.handlers)
 	m.mu.Unlock()
 
+	if preStopDelay > 0 {
+		m.log.Info("draining, waiting before shutdown", "pre_stop_delay", preStopDelay.String())
+		time.Sleep(preStopDelay)
+	}
+
 	// Create timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	m.log.Info("starting graceful shutdown", "handlers", len(handlers), "timeout", m.timeout.String())
+	m.log.Info("starting graceful shutdown", "handlers", len(handlers), "timeout", timeout.String())
 
-	// Run handlers in reverse order (LIFO)
-	var wg sync.WaitGroup
-	errors := make(chan error, len(handlers))
+	completed := true
+	for _, stage := range []Stage{StageDrain, StageClose} {
+		if !m.runStage(ctx, stage, handlers) {
+			m.log.Warn("shutdown timeout exceeded, forcing exit", "stage", stage.String())
+			completed = false
+			break
+		}
+	}
+	if completed {
+		m.log.Info("graceful shutdown completed")
+	}
 
+	close(m.done)
+}
+
+// runStage runs the handlers of stage concurrently, in reverse
+// registration order, and reports whether they all returned before ctx
+// expired.
+func (m *Manager) runStage(ctx context.Context, stage Stage, handlers []Handler) bool {
+	var wg sync.WaitGroup
 	for i := len(handlers) - 1; i >= 0; i-- {
 		h := handlers[i]
+		if h.Stage != stage {
+			continue
+		}
 		wg.Add(1)
 		go func(h Handler) {
 			defer wg.Done()
-			m.log.Debug("running shutdown handler", "name", h.Name)
+			m.log.Debug("running shutdown handler", "name", h.Name, "stage", stage.String())
 			start := time.Now()
-			
+
 			if err := h.Cleanup(ctx); err != nil {
-				m.log.Error("shutdown handler failed", 
-					"name", h.Name, 
+				m.log.Error("shutdown handler failed",
+					"name", h.Name,
 					"error", err.Error(),
 					"duration_ms", time.Since(start).Milliseconds(),
 				)
-				errors <- err
 			} else {
-				m.log.Debug("shutdown handler completed", 
+				m.log.Debug("shutdown handler completed",
 					"name", h.Name,
 					"duration_ms", time.Since(start).Milliseconds(),
 				)
@@ -120,12 +229,10 @@ func (m *Manager) Shutdown() {
 
 	select {
 	case <-done:
-		m.log.Info("graceful shutdown completed")
+		return true
 	case <-ctx.Done():
-		m.log.Warn("shutdown timeout exceeded, forcing exit")
+		return false
 	}
-
-	close(m.done)
 }
 
 // Done returns a channel that is closed when shutdown is complete.
@@ -146,7 +253,7 @@ func (m *Manager) Context() context.Context {
 // WaitWithContext waits for shutdown signal with a custom context.
 func (m *Manager) WaitWithContext(ctx context.Context) {
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	select {
 	case sig := <-sigChan:
@@ -234,3 +234,136 @@ func TestConcurrentHandlers(t *testing.T) {
 		t.Errorf("expected 10 handlers to run, got %d", counter.Load())
 	}
 }
+
+func TestStages(t *testing.T) {
+	log := newTestLogger()
+	mgr := NewManager(log, 5*time.Second)
+
+	var drained atomic.Bool
+	var closedAfterDrain atomic.Bool
+	mgr.RegisterStage(StageClose, "postgres", func(ctx context.Context) error {
+		closedAfterDrain.Store(drained.Load())
+		return nil
+	})
+	mgr.Register("http-server", func(ctx context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		drained.Store(true)
+		return nil
+	})
+
+	mgr.Shutdown()
+
+	if !closedAfterDrain.Load() {
+		t.Error("expected close stage to start only after the drain stage finished")
+	}
+}
+
+func TestTimeoutSkipsCloseStage(t *testing.T) {
+	log := newTestLogger()
+	mgr := NewManager(log, 50*time.Millisecond)
+
+	var closed atomic.Bool
+	mgr.RegisterStage(StageClose, "postgres", func(ctx context.Context) error {
+		closed.Store(true)
+		return nil
+	})
+	mgr.Register("stuck", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(100 * time.Millisecond)
+		return ctx.Err()
+	})
+
+	mgr.Shutdown()
+
+	if closed.Load() {
+		t.Error("expected close stage to be skipped after the timeout")
+	}
+}
+
+func TestDraining(t *testing.T) {
+	log := newTestLogger()
+	mgr := NewManager(log, 5*time.Second)
+	mgr.SetPreStopDelay(50 * time.Millisecond)
+
+	var drainingDuringHandler atomic.Bool
+	mgr.Register("http-server", func(ctx context.Context) error {
+		drainingDuringHandler.Store(mgr.Draining())
+		return nil
+	})
+
+	if mgr.Draining() {
+		t.Fatal("expected not draining before shutdown")
+	}
+
+	start := time.Now()
+	mgr.Shutdown()
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected shutdown to wait the pre-stop delay, took %v", elapsed)
+	}
+	if !drainingDuringHandler.Load() {
+		t.Error("expected Draining to be true while handlers run")
+	}
+}
+
+func TestFatal(t *testing.T) {
+	t.Run("skips pre-stop delay and uses its own timeout", func(t *testing.T) {
+		mgr := NewManager(newTestLogger(), time.Minute)
+		mgr.SetPreStopDelay(time.Minute)
+
+		var closed atomic.Bool
+		mgr.Register("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		mgr.RegisterStage(StageClose, "pool", func(ctx context.Context) error {
+			closed.Store(true)
+			return nil
+		})
+
+		start := time.Now()
+		mgr.Fatal(50 * time.Millisecond)
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("Fatal took %v, want about 50ms", elapsed)
+		}
+		if closed.Load() {
+			t.Error("close stage ran after drain timed out")
+		}
+		if !mgr.Draining() {
+			t.Error("expected Draining after Fatal")
+		}
+	})
+
+	t.Run("runs handlers once", func(t *testing.T) {
+		mgr := NewManager(newTestLogger(), time.Second)
+		var calls atomic.Int32
+		mgr.RegisterSimple("h", func() { calls.Add(1) })
+
+		mgr.Fatal(time.Second)
+		mgr.Shutdown()
+		mgr.Fatal(time.Second)
+
+		if n := calls.Load(); n != 1 {
+			t.Errorf("handler ran %d times, want 1", n)
+		}
+	})
+
+	t.Run("from inside a handler", func(t *testing.T) {
+		mgr := NewManager(newTestLogger(), time.Second)
+		mgr.Register("fatal", func(ctx context.Context) error {
+			mgr.Fatal(20 * time.Millisecond)
+			return nil
+		})
+
+		finished := make(chan struct{})
+		go func() {
+			mgr.Shutdown()
+			close(finished)
+		}()
+		select {
+		case <-finished:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Shutdown deadlocked on a nested Fatal")
+		}
+	})
+}
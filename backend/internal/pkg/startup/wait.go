@@ -0,0 +1,73 @@
+// Package startup holds helpers for bringing a service up.
+package startup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gala/internal/pkg/logger"
+)
+
+// Backoff bounds the delay between attempts.
+type Backoff struct {
+	// Initial is the delay after the first failure (default 500ms).
+	Initial time.Duration
+	// Max caps the delay; it doubles up to this value (default 10s).
+	Max time.Duration
+}
+
+func (b Backoff) withDefaults() Backoff {
+	if b.Initial <= 0 {
+		b.Initial = 500 * time.Millisecond
+	}
+	if b.Max <= 0 {
+		b.Max = 10 * time.Second
+	}
+	return b
+}
+
+// WaitFor calls check until it succeeds, retrying with exponential backoff
+// for at most maxWait (0 = a single attempt). Each failed attempt is
+// logged; the last error is returned once maxWait is exhausted or ctx is
+// canceled.
+//
+// It is meant for dependencies that may still be starting (Postgres and
+// Redis on a cold compose/Kubernetes start), so the process waits instead
+// of crash-looping.
+func WaitFor(ctx context.Context, log *logger.Logger, name string, maxWait time.Duration, b Backoff, check func(ctx context.Context) error) error {
+	b = b.withDefaults()
+	deadline := time.Now().Add(maxWait)
+	delay := b.Initial
+
+	for attempt := 1; ; attempt++ {
+		err := check(ctx)
+		if err == nil {
+			if attempt > 1 {
+				log.Info("dependency ready", "dependency", name, "attempts", attempt)
+			}
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%s not ready after %d attempts: %w", name, attempt, err)
+		}
+		if delay > remaining {
+			delay = remaining
+		}
+		log.Warn("dependency not ready, retrying",
+			"dependency", name,
+			"attempt", attempt,
+			"retry_in", delay.String(),
+			"error", err.Error(),
+		)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s not ready: %w", name, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, b.Max)
+	}
+}
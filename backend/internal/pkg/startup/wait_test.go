@@ -0,0 +1,78 @@
+package startup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gala/internal/pkg/logger"
+)
+
+func newTestLogger(buf *bytes.Buffer) *logger.Logger {
+	return logger.New(logger.Config{Level: "debug", Format: "json", Output: buf})
+}
+
+var fastBackoff = Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond}
+
+func TestWaitForEventuallyReady(t *testing.T) {
+	var buf bytes.Buffer
+	calls := 0
+	err := WaitFor(context.Background(), newTestLogger(&buf), "postgres", time.Second, fastBackoff, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+	if got := strings.Count(buf.String(), "dependency not ready"); got != 2 {
+		t.Errorf("expected 2 retry logs, got %d: %s", got, buf.String())
+	}
+}
+
+func TestWaitForGivesUp(t *testing.T) {
+	var buf bytes.Buffer
+	refused := errors.New("connection refused")
+	start := time.Now()
+	err := WaitFor(context.Background(), newTestLogger(&buf), "redis", 20*time.Millisecond, fastBackoff, func(ctx context.Context) error {
+		return refused
+	})
+	if !errors.Is(err, refused) {
+		t.Fatalf("expected wrapped check error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to stop near maxWait, took %v", elapsed)
+	}
+}
+
+func TestWaitForSingleAttempt(t *testing.T) {
+	var buf bytes.Buffer
+	calls := 0
+	err := WaitFor(context.Background(), newTestLogger(&buf), "redis", 0, fastBackoff, func(ctx context.Context) error {
+		calls++
+		return errors.New("down")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expected one failed attempt, got calls=%d err=%v", calls, err)
+	}
+}
+
+func TestWaitForContextCanceled(t *testing.T) {
+	var buf bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := WaitFor(ctx, newTestLogger(&buf), "postgres", time.Minute, Backoff{Initial: time.Minute}, func(ctx context.Context) error {
+		return errors.New("down")
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package ports
+
+import (
+	"context"
+	"io"
+)
+
+// PublishInput is a rendered video and the metadata to publish it with.
+type PublishInput struct {
+	Reader      io.Reader
+	Size        int64
+	ContentType string
+	// Name is a stable file name for the output, "<job_id>/<variant><ext>",
+	// for targets that store files.
+	Name string
+
+	Title       string
+	Description string
+	Tags        []string
+	// Privacy is target specific (YouTube: private, unlisted, public);
+	// empty uses the target's default.
+	Privacy string
+}
+
+// PublishOutput identifies the published video on the target.
+type PublishOutput struct {
+	ExternalID string
+	URL        string
+}
+
+// PublishTarget: implementaciones (youtube, s3, ...)
+type PublishTarget interface {
+	Target() string
+	Publish(ctx context.Context, in PublishInput) (PublishOutput, error)
+}
@@ -11,6 +11,9 @@ type PutObjectInput struct {
 	ContentType string
 	Reader      io.Reader
 	Size        int64
+	// Kind es el kind del asset (p. ej. "thumbnail"), para providers que
+	// guardan cada tipo aparte (gdrive con GDRIVE_FOLDERS). Opcional.
+	Kind string
 }
 
 type PutObjectOutput struct {
@@ -36,3 +39,25 @@ type StorageProvider interface {
 	// v0: opcional. (API hoy puede seguir usando /assets/{id}/content)
 	GetSignedURL(ctx context.Context, objectKey string, expiresIn time.Duration) (SignedURLOutput, error)
 }
+
+// ObjectInfo describe un objeto guardado.
+type ObjectInfo struct {
+	// ObjectKey es la key que reciben Get/DeleteObject (el fileId en gdrive).
+	ObjectKey string
+	// Name es la key con la que se escribió el objeto (igual a ObjectKey
+	// salvo en gdrive).
+	Name    string
+	Size    int64
+	ModTime time.Time
+	// MD5 es el MD5 en hex del contenido cuando el provider lo conoce sin
+	// leer el objeto (gdrive); si no, vacío.
+	MD5 string
+}
+
+// ObjectLister lo implementan los providers que pueden listar sus objetos
+// (lo usa la auditoría del storage).
+type ObjectLister interface {
+	// ListObjects llama a fn por cada objeto cuyo Name empieza con
+	// prefix, y se detiene en el primer error que devuelva fn.
+	ListObjects(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+}
@@ -0,0 +1,471 @@
+// Package publish uploads rendered job outputs to external targets
+// (YouTube, a public S3 bucket behind a CDN) and tracks each attempt in
+// job_publications.
+//
+// Uploads run in the background of the process that accepted them, a few
+// at a time. A publication is PENDING until an uploader claims it
+// (UPLOADING), then DONE with the target's video ID or FAILED. Pending
+// publications left by a restart are picked up again by Resume; the ones
+// interrupted by a shutdown go back to PENDING, and those whose uploader
+// died are failed once they have been UPLOADING for twice the upload
+// timeout.
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"gala/internal/events"
+	"gala/internal/httpapi/util"
+	"gala/internal/jobs"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/ports"
+	"gala/internal/store"
+)
+
+var (
+	ErrJobNotFound    = errors.New("job not found")
+	ErrJobState       = errors.New("job is not done")
+	ErrOutputNotFound = errors.New("job output not found")
+	// ErrTargetNotConfigured: the target has no credentials in this
+	// deployment.
+	ErrTargetNotConfigured = errors.New("publish target not configured")
+	// ErrPublished: the output is already published (or being published)
+	// to the target.
+	ErrPublished = errors.New("output already published to this target")
+)
+
+// JobStateError is returned (matching ErrJobState) when the job is not
+// DONE.
+type JobStateError struct {
+	Status string
+}
+
+func (e *JobStateError) Error() string { return "job is " + e.Status }
+
+func (e *JobStateError) Is(target error) bool { return target == ErrJobState }
+
+// Metadata limits, the strictest of the supported targets (YouTube).
+const (
+	MaxTitle       = 100
+	MaxDescription = 5000
+	MaxTagsLength  = 500
+)
+
+// Privacy values accepted by the targets.
+var Privacies = []string{"private", "unlisted", "public"}
+
+const (
+	// DefaultUploadTimeout bounds one upload.
+	DefaultUploadTimeout = 30 * time.Minute
+	// DefaultConcurrency is how many uploads run at once.
+	DefaultConcurrency = 2
+)
+
+// Deps are the service dependencies. Zero values use the defaults.
+type Deps struct {
+	Pool *pgxpool.Pool
+	SP   ports.StorageProvider
+	// Targets are the configured targets; publishing to any other fails
+	// with ErrTargetNotConfigured.
+	Targets []ports.PublishTarget
+	// Events receives publication.* events; nil publishes none.
+	Events        *events.Bus
+	Log           *logger.Logger
+	UploadTimeout time.Duration
+	Concurrency   int
+}
+
+// Service accepts publications and runs their uploads.
+type Service struct {
+	pool    *pgxpool.Pool
+	sp      ports.StorageProvider
+	targets map[string]ports.PublishTarget
+	ev      *events.Bus
+	log     *logger.Logger
+	timeout time.Duration
+
+	// ctx is canceled by Shutdown and bounds every upload.
+	ctx    context.Context
+	cancel context.CancelFunc
+	sem    chan struct{}
+	wg     sync.WaitGroup
+}
+
+// New creates the service.
+func New(d Deps) *Service {
+	if d.Log == nil {
+		d.Log = logger.NewDefault()
+	}
+	if d.UploadTimeout <= 0 {
+		d.UploadTimeout = DefaultUploadTimeout
+	}
+	if d.Concurrency <= 0 {
+		d.Concurrency = DefaultConcurrency
+	}
+	s := &Service{
+		pool:    d.Pool,
+		sp:      d.SP,
+		targets: map[string]ports.PublishTarget{},
+		ev:      d.Events,
+		log:     d.Log.WithComponent("publish"),
+		timeout: d.UploadTimeout,
+		sem:     make(chan struct{}, d.Concurrency),
+	}
+	for _, t := range d.Targets {
+		s.targets[t.Target()] = t
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+// Targets returns the names of the configured targets.
+func (s *Service) Targets() []string {
+	out := make([]string, 0, len(s.targets))
+	for name := range s.targets {
+		out = append(out, name)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Request asks to publish one output of a job. Empty metadata fields come
+// from the job params (title, description, tags), see Metadata.
+type Request struct {
+	Target string
+	// Variant selects the output; 0 is the first one.
+	Variant     int
+	Title       string
+	Description string
+	Tags        []string
+	Privacy     string
+}
+
+// Metadata is what a publication sends to its target, stored with it.
+type Metadata struct {
+	Variant     int      `json:"variant"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Privacy     string   `json:"privacy,omitempty"`
+}
+
+// Publish records a PENDING publication of a DONE job's output and starts
+// its upload in the background.
+func (s *Service) Publish(ctx context.Context, jobID string, req Request) (store.Publication, error) {
+	if _, ok := s.targets[req.Target]; !ok {
+		return store.Publication{}, ErrTargetNotConfigured
+	}
+
+	job, err := store.GetJob(ctx, s.pool, jobID)
+	if pgerr.IsNoRows(err) {
+		return store.Publication{}, ErrJobNotFound
+	}
+	if err != nil {
+		return store.Publication{}, err
+	}
+	if job.Status != store.JobDone {
+		return store.Publication{}, &JobStateError{Status: job.Status}
+	}
+
+	outs, err := store.ListJobOutputs(ctx, s.pool, jobID)
+	if err != nil {
+		return store.Publication{}, err
+	}
+	var out *store.JobOutput
+	for i := range outs {
+		if req.Variant == 0 || outs[i].Variant == req.Variant {
+			out = &outs[i]
+			break
+		}
+	}
+	if out == nil {
+		return store.Publication{}, ErrOutputNotFound
+	}
+
+	meta := BuildMetadata(job, req)
+	meta.Variant = out.Variant
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return store.Publication{}, err
+	}
+
+	// Free the slot of uploads whose uploader died, so they can be retried
+	if _, err := store.FailStalePublications(ctx, s.pool, 2*s.timeout, "upload interrupted"); err != nil {
+		return store.Publication{}, err
+	}
+
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	p := store.Publication{
+		ID:        util.NewID("pub"),
+		JobID:     jobID,
+		OutputID:  out.ID,
+		Target:    req.Target,
+		Status:    store.PublicationPending,
+		Metadata:  raw,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+	if err := store.InsertPublication(ctx, s.pool, p); err != nil {
+		if pgerr.IsUniqueViolation(err) {
+			return store.Publication{}, ErrPublished
+		}
+		return store.Publication{}, err
+	}
+	s.start(p)
+	return p, nil
+}
+
+// List returns the publications of a job, newest first.
+func (s *Service) List(ctx context.Context, jobID string) ([]store.Publication, error) {
+	if _, err := store.GetJob(ctx, s.pool, jobID); err != nil {
+		if pgerr.IsNoRows(err) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return store.ListJobPublications(ctx, s.pool, jobID)
+}
+
+// Resume fails the stale uploads and starts the pending publications,
+// e.g. those queued when the previous process stopped.
+func (s *Service) Resume(ctx context.Context) error {
+	n, err := store.FailStalePublications(ctx, s.pool, 2*s.timeout, "upload interrupted")
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.log.Warn("failed stale publications", "count", n)
+	}
+	pending, err := store.ListPendingPublications(ctx, s.pool)
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		s.start(p)
+	}
+	if len(pending) > 0 {
+		s.log.Info("resumed pending publications", "count", len(pending))
+	}
+	return nil
+}
+
+// Shutdown interrupts the running uploads, which go back to PENDING, and
+// waits for them to stop.
+func (s *Service) Shutdown(ctx context.Context) error {
+	s.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// start uploads p in the background once a slot is free. Publications
+// still waiting at shutdown stay PENDING.
+func (s *Service) start(p store.Publication) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		select {
+		case s.sem <- struct{}{}:
+		case <-s.ctx.Done():
+			return
+		}
+		defer func() { <-s.sem }()
+		s.run(p)
+	}()
+}
+
+// run claims p and uploads it, recording the outcome.
+func (s *Service) run(p store.Publication) {
+	log := s.log.WithJobID(p.JobID).WithFields(map[string]any{"publication_id": p.ID, "target": p.Target})
+	// Status updates must land even when the upload was interrupted
+	dbCtx := context.WithoutCancel(s.ctx)
+
+	claimed, err := store.ClaimPublication(s.ctx, s.pool, p.ID)
+	if err != nil || !claimed {
+		if err != nil && s.ctx.Err() == nil {
+			log.Error("publication claim failed", "error", err.Error())
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, s.timeout)
+	defer cancel()
+	start := time.Now()
+	out, err := s.upload(ctx, p)
+	if err != nil {
+		if s.ctx.Err() != nil {
+			// Shutting down: the next start resumes it
+			if err := store.RequeuePublication(dbCtx, s.pool, p.ID); err != nil {
+				log.Error("publication requeue failed", "error", err.Error())
+			}
+			return
+		}
+		log.Error("publication failed", "error", err.Error())
+		if err := store.MarkPublicationFailed(dbCtx, s.pool, p.ID, err.Error()); err != nil {
+			log.Error("publication update failed", "error", err.Error())
+			return
+		}
+		s.ev.Publish(dbCtx, events.PublicationFailed, p.ID, map[string]any{"job_id": p.JobID, "target": p.Target})
+		return
+	}
+
+	if _, err := store.MarkPublicationDone(dbCtx, s.pool, p.ID, out.ExternalID, out.URL); err != nil {
+		log.Error("publication update failed", "external_id", out.ExternalID, "error", err.Error())
+		return
+	}
+	log.Info("publication done", "external_id", out.ExternalID, "duration_ms", time.Since(start).Milliseconds())
+	s.ev.Publish(dbCtx, events.PublicationDone, p.ID, map[string]any{
+		"job_id":      p.JobID,
+		"target":      p.Target,
+		"external_id": out.ExternalID,
+		"url":         out.URL,
+	})
+}
+
+// upload sends the output's video to the target.
+func (s *Service) upload(ctx context.Context, p store.Publication) (ports.PublishOutput, error) {
+	target, ok := s.targets[p.Target]
+	if !ok {
+		return ports.PublishOutput{}, ErrTargetNotConfigured
+	}
+	var meta Metadata
+	if err := json.Unmarshal(p.Metadata, &meta); err != nil {
+		return ports.PublishOutput{}, fmt.Errorf("invalid metadata: %w", err)
+	}
+
+	outs, err := store.ListJobOutputs(ctx, s.pool, p.JobID)
+	if err != nil {
+		return ports.PublishOutput{}, err
+	}
+	i := slices.IndexFunc(outs, func(o store.JobOutput) bool { return o.ID == p.OutputID })
+	if i < 0 || outs[i].VideoObjectKey == "" {
+		return ports.PublishOutput{}, ErrOutputNotFound
+	}
+	asset, err := store.GetAsset(ctx, s.pool, outs[i].VideoAssetID)
+	if err != nil {
+		return ports.PublishOutput{}, fmt.Errorf("video asset: %w", err)
+	}
+
+	rc, contentType, size, err := s.sp.GetObject(ctx, asset.ObjectKey)
+	if err != nil {
+		return ports.PublishOutput{}, fmt.Errorf("read video: %w", err)
+	}
+	defer rc.Close()
+	if asset.Mime != "" {
+		contentType = asset.Mime
+	}
+	if size <= 0 {
+		size = asset.SizeBytes
+	}
+	ext := path.Ext(asset.ObjectKey)
+	if ext == "" {
+		// gdrive keys are file IDs
+		ext = ".mp4"
+	}
+	return target.Publish(ctx, ports.PublishInput{
+		Reader:      rc,
+		Size:        size,
+		ContentType: contentType,
+		Name:        fmt.Sprintf("%s/%d%s", p.JobID, outs[i].Variant, ext),
+		Title:       meta.Title,
+		Description: meta.Description,
+		Tags:        meta.Tags,
+		Privacy:     meta.Privacy,
+	})
+}
+
+// BuildMetadata fills the request's empty fields from the job params
+// (title, description, and tags as a list or a comma-separated string).
+// The title falls back to the job name and then its ID. Values are cut to
+// the metadata limits; angle brackets, which YouTube rejects, are dropped.
+func BuildMetadata(job store.Job, req Request) Metadata {
+	params := jobs.ParseSpec(job.ParamsJSON).Params
+	m := Metadata{
+		Variant:     req.Variant,
+		Title:       firstNonEmpty(req.Title, stringParam(params, "title"), job.Name, job.ID),
+		Description: firstNonEmpty(req.Description, stringParam(params, "description")),
+		Tags:        req.Tags,
+		Privacy:     req.Privacy,
+	}
+	if len(m.Tags) == 0 {
+		m.Tags = tagsParam(params["tags"])
+	}
+	m.Title = truncate(clean(m.Title), MaxTitle)
+	m.Description = truncate(clean(m.Description), MaxDescription)
+
+	var tags []string
+	total := 0
+	for _, t := range m.Tags {
+		t = clean(t)
+		if t == "" || slices.Contains(tags, t) {
+			continue
+		}
+		if total+utf8.RuneCountInString(t) > MaxTagsLength {
+			break
+		}
+		total += utf8.RuneCountInString(t)
+		tags = append(tags, t)
+	}
+	m.Tags = tags
+	return m
+}
+
+func stringParam(params map[string]any, key string) string {
+	s, _ := params[key].(string)
+	return s
+}
+
+func tagsParam(v any) []string {
+	var out []string
+	switch t := v.(type) {
+	case string:
+		out = strings.Split(t, ",")
+	case []any:
+		for _, it := range t {
+			if s, ok := it.(string); ok {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func clean(s string) string {
+	return strings.TrimSpace(strings.NewReplacer("<", "", ">", "").Replace(s))
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return strings.TrimSpace(string([]rune(s)[:n]))
+}
@@ -0,0 +1,65 @@
+package publish
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"gala/internal/store"
+)
+
+func TestBuildMetadataFromParams(t *testing.T) {
+	job := store.Job{
+		ID:         "job_1",
+		Name:       "promo",
+		ParamsJSON: `{"template_id":"tpl_1","params":{"title":"Launch <day>","description":"All about it","tags":"launch, promo,launch,"}}`,
+	}
+	m := BuildMetadata(job, Request{Target: "youtube"})
+	if m.Title != "Launch day" {
+		t.Errorf("title: got %q", m.Title)
+	}
+	if m.Description != "All about it" {
+		t.Errorf("description: got %q", m.Description)
+	}
+	if !slices.Equal(m.Tags, []string{"launch", "promo"}) {
+		t.Errorf("tags: got %q", m.Tags)
+	}
+
+	// The request wins over the params
+	m = BuildMetadata(job, Request{Title: "Custom", Tags: []string{"a"}, Privacy: "unlisted"})
+	if m.Title != "Custom" || !slices.Equal(m.Tags, []string{"a"}) || m.Privacy != "unlisted" {
+		t.Errorf("request overrides: got %+v", m)
+	}
+}
+
+func TestBuildMetadataFallbacks(t *testing.T) {
+	// Legacy job without name nor title: the ID is the title
+	m := BuildMetadata(store.Job{ID: "job_2", ParamsJSON: `{"text":"hi","tags":["x","y"]}`}, Request{})
+	if m.Title != "job_2" || !slices.Equal(m.Tags, []string{"x", "y"}) {
+		t.Errorf("got %+v", m)
+	}
+	m = BuildMetadata(store.Job{ID: "job_3", Name: "named"}, Request{})
+	if m.Title != "named" {
+		t.Errorf("title: got %q", m.Title)
+	}
+}
+
+func TestBuildMetadataLimits(t *testing.T) {
+	long := strings.Repeat("é", MaxTitle+10)
+	var tags []string
+	for i := range 100 {
+		tags = append(tags, fmt.Sprintf("tag-%026d", i))
+	}
+	m := BuildMetadata(store.Job{ID: "job_4"}, Request{Title: long, Tags: tags})
+	if n := len([]rune(m.Title)); n != MaxTitle {
+		t.Errorf("title has %d runes", n)
+	}
+	total := 0
+	for _, tag := range m.Tags {
+		total += len(tag)
+	}
+	if total > MaxTagsLength || len(m.Tags) != MaxTagsLength/30 {
+		t.Errorf("tags total %d (%d tags)", total, len(m.Tags))
+	}
+}
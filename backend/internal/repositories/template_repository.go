@@ -2,16 +2,17 @@ package repositories
 
 import (
 	"context"
-	"errors"
 
-	"gala/internal/httpkit"
 	"gala/internal/models"
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/pgerr"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-var ErrTemplateNotFound = errors.New("template not found")
-var ErrTemplateNameExists = errors.New("template name already exists")
+// Sentinel errors; match with errors.Is (codes compare equal).
+var ErrTemplateNotFound = errors.New(errors.CodeNotFound, "template not found")
+var ErrTemplateNameExists = errors.New(errors.CodeAlreadyExists, "template name already exists")
 
 type TemplateRepository struct {
 	db *pgxpool.Pool
@@ -29,10 +30,10 @@ func (r *TemplateRepository) Create(ctx context.Context, t *models.Template) err
 	`, t.ID, t.Name, t.Description, t.Definition).Scan(&t.CreatedAt)
 
 	if err != nil {
-		if httpkit.IsUniqueViolation(err) {
+		if pgerr.IsUniqueViolation(err) {
 			return ErrTemplateNameExists
 		}
-		return err
+		return pgerr.Wrap(err, "templates.create", "insert template failed")
 	}
 	return nil
 }
@@ -45,7 +46,7 @@ func (r *TemplateRepository) List(ctx context.Context) ([]models.Template, error
 		ORDER BY created_at DESC
 	`)
 	if err != nil {
-		return nil, err
+		return nil, pgerr.Wrap(err, "templates.list", "list templates failed")
 	}
 	defer rows.Close()
 
@@ -53,7 +54,7 @@ func (r *TemplateRepository) List(ctx context.Context) ([]models.Template, error
 	for rows.Next() {
 		var t models.Template
 		if err := rows.Scan(&t.ID, &t.Name, &t.Description, &t.CreatedAt); err != nil {
-			return nil, err
+			return nil, pgerr.Wrap(err, "templates.list", "scan template failed")
 		}
 		out = append(out, t)
 	}
@@ -75,7 +76,10 @@ func (r *TemplateRepository) Get(ctx context.Context, id string) (*models.Templa
 		&t.DeletedAt,
 	)
 	if err != nil {
-		return nil, ErrTemplateNotFound
+		if pgerr.IsNoRows(err) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, pgerr.Wrap(err, "templates.get", "get template failed")
 	}
 	return &t, nil
 }
@@ -85,9 +89,9 @@ func (r *TemplateRepository) Delete(ctx context.Context, id string) error {
 		UPDATE templates
 		SET deleted_at=now()
 		WHERE id=$1 AND deleted_at IS NULL
-	`)
+	`, id)
 	if err != nil {
-		return err
+		return pgerr.Wrap(err, "templates.delete", "delete template failed")
 	}
 	if cmd.RowsAffected() == 0 {
 		return ErrTemplateNotFound
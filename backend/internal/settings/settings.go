@@ -0,0 +1,111 @@
+// Package settings holds the workspace settings: defaults applied to the
+// templates and jobs created without them. The platform serves a single
+// workspace per deployment for now, so there is one set of settings
+// (workspace Default); the API edits it in /settings.
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"gala/internal/pkg/db"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/store"
+)
+
+// Default is the workspace of the deployment.
+const Default = "default"
+
+// Settings are a workspace's defaults. Nil or empty fields set nothing.
+type Settings struct {
+	// Captions sets params.captions of the jobs that do not set it and
+	// whose template has no default for it (strict templates are left
+	// alone: their params are only the declared ones).
+	Captions *bool `json:"captions,omitempty"`
+	// Format is the format of the templates created without one.
+	Format *Format `json:"format,omitempty"`
+	// Callback is the completion callback of the jobs created without
+	// callback_url.
+	Callback *Callback `json:"callback,omitempty"`
+	// Priority is the priority (store.JobPriority*) of the jobs created
+	// without one.
+	Priority string `json:"priority,omitempty"`
+	// UpdatedAt is when the settings were saved; nil if they never were.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Format is a template's output format.
+type Format struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	FPS    int `json:"fps"`
+}
+
+// Callback is the default completion callback of jobs. Secret is
+// write-only: Redacted clears it and reports it in Signed.
+type Callback struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+	Signed bool   `json:"signed,omitempty"`
+}
+
+// Redacted returns s without the callback secret, for responses.
+func (s Settings) Redacted() Settings {
+	if s.Callback != nil {
+		c := *s.Callback
+		c.Signed, c.Secret = c.Secret != "", ""
+		s.Callback = &c
+	}
+	return s
+}
+
+// FormatJSON returns format, or the default format as JSONB if format is
+// empty and there is one.
+func (s Settings) FormatJSON(format []byte) []byte {
+	if len(format) > 0 || s.Format == nil {
+		return format
+	}
+	b, _ := json.Marshal(s.Format)
+	return b
+}
+
+// Load returns the settings of the deployment's workspace; the zero
+// Settings if none were saved (or the table is not migrated yet).
+func Load(ctx context.Context, q db.Querier) (Settings, error) {
+	raw, updatedAt, err := store.GetWorkspaceSettings(ctx, q, Default)
+	if pgerr.IsNoRows(err) || pgerr.IsUndefinedTable(err) {
+		return Settings{}, nil
+	}
+	if err != nil {
+		return Settings{}, err
+	}
+	var s Settings
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return Settings{}, err
+	}
+	s.UpdatedAt = &updatedAt
+	return s, nil
+}
+
+// Save replaces the settings of the deployment's workspace and returns
+// them as stored.
+func Save(ctx context.Context, q db.Querier, s Settings) (Settings, error) {
+	s.UpdatedAt = nil
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return Settings{}, err
+	}
+	updatedAt, err := store.PutWorkspaceSettings(ctx, q, Default, raw)
+	if err != nil {
+		return Settings{}, err
+	}
+	s.UpdatedAt = &updatedAt
+	return s, nil
+}
+
+// Reset deletes the settings of the deployment's workspace, so nothing
+// gets a default.
+func Reset(ctx context.Context, q db.Querier) error {
+	return store.DeleteWorkspaceSettings(ctx, q, Default)
+}
@@ -0,0 +1,31 @@
+package settings
+
+import "testing"
+
+func TestRedacted(t *testing.T) {
+	s := Settings{Callback: &Callback{URL: "https://example.com/hook", Secret: "s3cret"}}
+	r := s.Redacted()
+	if r.Callback.Secret != "" || !r.Callback.Signed {
+		t.Errorf("Redacted().Callback = %+v, want no secret and signed", r.Callback)
+	}
+	if s.Callback.Secret != "s3cret" {
+		t.Error("Redacted() changed the original settings")
+	}
+	if r := (Settings{Callback: &Callback{URL: "https://example.com/hook"}}).Redacted(); r.Callback.Signed {
+		t.Error("Redacted() reports signed without a secret")
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	s := Settings{Format: &Format{Width: 1080, Height: 1920, FPS: 30}}
+	if got := string(s.FormatJSON(nil)); got != `{"width":1080,"height":1920,"fps":30}` {
+		t.Errorf("FormatJSON(nil) = %s", got)
+	}
+	own := []byte(`{"width":720,"height":1280,"fps":25}`)
+	if got := string(s.FormatJSON(own)); got != string(own) {
+		t.Errorf("FormatJSON(own) = %s, want the template's", got)
+	}
+	if got := (Settings{}).FormatJSON(nil); got != nil {
+		t.Errorf("FormatJSON() without a default = %s, want nil", got)
+	}
+}
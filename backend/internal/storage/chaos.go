@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"gala/internal/pkg/chaos"
+	"gala/internal/ports"
+)
+
+// faulty fails provider calls as its injector decides, before they reach
+// the provider.
+type faulty struct {
+	sp  Provider
+	inj *chaos.Injector
+}
+
+// WithChaos wraps sp so that inj fails some of its calls; a nil inj
+// returns sp as it is. Like Instrument, the wrapper keeps the optional
+// ports.ObjectLister and Path methods of sp.
+func WithChaos(sp Provider, inj *chaos.Injector) Provider {
+	if inj == nil {
+		return sp
+	}
+	w := &faulty{sp: sp, inj: inj}
+
+	lister, isLister := sp.(ports.ObjectLister)
+	p, isPather := sp.(pather)
+	switch {
+	case isLister && isPather:
+		return struct {
+			faultyLister
+			pather
+		}{faultyLister{w, lister}, p}
+	case isLister:
+		return faultyLister{w, lister}
+	case isPather:
+		return struct {
+			*faulty
+			pather
+		}{w, p}
+	}
+	return w
+}
+
+func (w *faulty) Provider() string { return w.sp.Provider() }
+
+func (w *faulty) PutObject(ctx context.Context, in ports.PutObjectInput) (ports.PutObjectOutput, error) {
+	if err := w.inj.StorageFault(ctx, "put"); err != nil {
+		return ports.PutObjectOutput{}, err
+	}
+	return w.sp.PutObject(ctx, in)
+}
+
+func (w *faulty) GetObject(ctx context.Context, objectKey string) (io.ReadCloser, string, int64, error) {
+	if err := w.inj.StorageFault(ctx, "get"); err != nil {
+		return nil, "", 0, err
+	}
+	return w.sp.GetObject(ctx, objectKey)
+}
+
+func (w *faulty) DeleteObject(ctx context.Context, objectKey string) error {
+	if err := w.inj.StorageFault(ctx, "delete"); err != nil {
+		return err
+	}
+	return w.sp.DeleteObject(ctx, objectKey)
+}
+
+func (w *faulty) GetSignedURL(ctx context.Context, objectKey string, expiresIn time.Duration) (ports.SignedURLOutput, error) {
+	if err := w.inj.StorageFault(ctx, "signed_url"); err != nil {
+		return ports.SignedURLOutput{}, err
+	}
+	return w.sp.GetSignedURL(ctx, objectKey, expiresIn)
+}
+
+// faultyLister is a faulty provider that can list objects.
+type faultyLister struct {
+	*faulty
+	l ports.ObjectLister
+}
+
+func (w faultyLister) ListObjects(ctx context.Context, prefix string, fn func(ports.ObjectInfo) error) error {
+	if err := w.inj.StorageFault(ctx, "list"); err != nil {
+		return err
+	}
+	return w.l.ListObjects(ctx, prefix, fn)
+}
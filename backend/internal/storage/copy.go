@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// Copy copies src to dst like io.Copy, but stops with ctx.Err() before the
+// next read once ctx is done. Streams to an HTTP client use it with the
+// request context, so a client that disconnects stops the read of the
+// object instead of leaving it to the next write error (or to the end of
+// the object, while the writes are buffered).
+func Copy(ctx context.Context, dst io.Writer, src io.Reader) (int64, error) {
+	return io.Copy(dst, WithContext(ctx, src))
+}
+
+// WithContext returns a reader of r whose reads fail with ctx.Err() once
+// ctx is done. Providers that ignore the context of PutObject (localfs
+// copying a temporary file) stop reading with it.
+func WithContext(ctx context.Context, r io.Reader) io.Reader {
+	if ctx.Done() == nil {
+		return r
+	}
+	return &ctxReader{ctx: ctx, r: r}
+}
+
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
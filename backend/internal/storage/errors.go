@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+)
+
+// IsNotFound reports whether err, returned by a provider, means the object
+// does not exist (a missing file in localfs, a 404 from Drive).
+func IsNotFound(err error) bool {
+	if errors.Is(err, fs.ErrNotExist) {
+		return true
+	}
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == http.StatusNotFound
+}
+
+// pingKey names an object that does not exist, which Ping reads.
+const pingKey = "gala-readiness-probe"
+
+// Ping checks that sp can be reached by reading an object that does not
+// exist: a not-found answer means the provider is up and authorized.
+func Ping(ctx context.Context, sp Provider) error {
+	rc, _, _, err := sp.GetObject(ctx, pingKey)
+	if err == nil {
+		return rc.Close()
+	}
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
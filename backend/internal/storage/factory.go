@@ -3,10 +3,14 @@ package storage
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"slices"
+	"strings"
 
 	"gala/internal/adapters/storage/gdrive"
 	"gala/internal/adapters/storage/localfs"
+	"gala/internal/pkg/logger"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
@@ -38,7 +42,10 @@ func newGDriveProvider() (Provider, error) {
 
 	clientID := mustEnv("GDRIVE_CLIENT_ID")
 	clientSecret := mustEnv("GDRIVE_CLIENT_SECRET")
-	refreshToken := mustEnv("GDRIVE_REFRESH_TOKEN")
+	refreshToken, err := gdriveRefreshToken()
+	if err != nil {
+		return nil, err
+	}
 	folderID := os.Getenv("GDRIVE_FOLDER_ID")
 
 	conf := &oauth2.Config{
@@ -50,13 +57,83 @@ func newGDriveProvider() (Provider, error) {
 
 	tok := &oauth2.Token{RefreshToken: refreshToken}
 	httpClient := conf.Client(ctx, tok)
+	httpClient.Transport = requestIDTransport{base: httpClient.Transport}
 
 	srv, err := drive.NewService(ctx, option.WithHTTPClient(httpClient))
 	if err != nil {
 		return nil, err
 	}
 
-	return gdrive.NewClient(srv, folderID), nil
+	folders, err := gdriveFolders(os.Getenv("GDRIVE_FOLDERS"))
+	if err != nil {
+		return nil, err
+	}
+
+	return gdrive.NewClient(srv, folderID).
+		WithFolders(folders).
+		WithSharedDrive(strings.TrimSpace(os.Getenv("GDRIVE_SHARED_DRIVE_ID"))), nil
+}
+
+// requestIDTransport sends the request ID of the call's context (the
+// job's, in the worker) in X-Request-ID, so the Drive calls can be matched
+// with the API and worker logs in proxies and egress logs. Google does not
+// log the header; quotaUser is not used for this because Drive buckets its
+// quota by it.
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if id := logger.RequestIDFromContext(req.Context()); id != "" {
+		// A RoundTripper must not modify the request it is given
+		req = req.Clone(req.Context())
+		req.Header.Set("X-Request-ID", id)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// gdriveFolders parses GDRIVE_FOLDERS, e.g.
+// "inputs=<folderId>,renders=<folderId>,thumbnails=<folderId>".
+func gdriveFolders(v string) (map[string]string, error) {
+	folders := map[string]string{}
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		cat, id, ok := strings.Cut(pair, "=")
+		cat, id = strings.TrimSpace(cat), strings.TrimSpace(id)
+		if !ok || id == "" {
+			return nil, fmt.Errorf("GDRIVE_FOLDERS: %q is not category=folderId", pair)
+		}
+		if !slices.Contains(gdrive.Categories, cat) {
+			return nil, fmt.Errorf("GDRIVE_FOLDERS: unknown category %q (want one of %s)",
+				cat, strings.Join(gdrive.Categories, ", "))
+		}
+		folders[cat] = id
+	}
+	return folders, nil
+}
+
+// gdriveRefreshToken reads GDRIVE_REFRESH_TOKEN or, if unset, the file at
+// GDRIVE_REFRESH_TOKEN_FILE (as written by gdrive-auth -out).
+func gdriveRefreshToken() (string, error) {
+	if v := os.Getenv("GDRIVE_REFRESH_TOKEN"); v != "" {
+		return v, nil
+	}
+	path := os.Getenv("GDRIVE_REFRESH_TOKEN_FILE")
+	if path == "" {
+		panic("missing env: GDRIVE_REFRESH_TOKEN")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("gdrive refresh token file: %w", err)
+	}
+	tok := strings.TrimSpace(string(b))
+	if tok == "" {
+		return "", fmt.Errorf("gdrive refresh token file is empty: %s", path)
+	}
+	return tok, nil
 }
 
 func mustEnv(k string) string {
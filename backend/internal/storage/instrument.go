@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/metrics"
+	"gala/internal/ports"
+)
+
+// storageBuckets are the operation latency buckets in seconds: uploads of
+// rendered videos to Drive take tens of seconds.
+var storageBuckets = []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// pather is the provider of files on the local disk (localfs), which the
+// stream handler asks for the path of an object.
+type pather interface {
+	Path(objectKey string) string
+}
+
+// instrumented times the calls to a provider: per operation latency
+// histograms (whose count by result gives the call and error rates), the
+// bytes moved, the transfers cut short, and a warning for calls slower
+// than slow.
+type instrumented struct {
+	sp   Provider
+	log  *logger.Logger
+	slow time.Duration
+
+	// nil without a metrics registry
+	duration *metrics.HistogramVec
+	bytes    *metrics.CounterVec
+	partial  *metrics.CounterVec
+	partialN *metrics.CounterVec
+}
+
+// Instrument wraps sp with operation metrics, registered in reg if not nil,
+// and logs operations that take longer than slow (0 logs none). The
+// wrapper keeps the optional ports.ObjectLister and Path methods of sp.
+//
+// GetObject is timed until the object is open; the bytes read are counted
+// when the reader is closed. A reader closed before the end of the object
+// (a client that disconnected mid-stream) and a PutObject that fails after
+// reading part of its input count as partial transfers, by reason:
+// canceled if the context of the call was done, error otherwise.
+func Instrument(sp Provider, reg *metrics.Registry, log *logger.Logger, slow time.Duration) Provider {
+	if log == nil {
+		log = logger.NewDefault()
+	}
+	w := &instrumented{sp: sp, log: log.WithComponent("storage"), slow: slow}
+	if reg != nil {
+		w.duration = reg.NewHistogramVec("gala_storage_operation_duration_seconds",
+			"Storage provider call latency by operation and result (ok or error).", storageBuckets, "provider", "op", "result")
+		w.bytes = reg.NewCounterVec("gala_storage_bytes_total",
+			"Bytes written (put) and read (get) through the storage provider, since start.", "provider", "op")
+		w.partial = reg.NewCounterVec("gala_storage_partial_transfers_total",
+			"Gets closed before the end of the object and puts failed midway, by reason (canceled or error), since start.", "provider", "op", "reason")
+		w.partialN = reg.NewCounterVec("gala_storage_partial_bytes_total",
+			"Bytes moved by partial transfers, since start.", "provider", "op")
+	}
+
+	lister, isLister := sp.(ports.ObjectLister)
+	p, isPather := sp.(pather)
+	switch {
+	case isLister && isPather:
+		return struct {
+			instrumentedLister
+			pather
+		}{instrumentedLister{w, lister}, p}
+	case isLister:
+		return instrumentedLister{w, lister}
+	case isPather:
+		return struct {
+			*instrumented
+			pather
+		}{w, p}
+	}
+	return w
+}
+
+// observe records a call of op that started at start.
+func (w *instrumented) observe(ctx context.Context, op, key string, start time.Time, size int64, err error) {
+	elapsed := time.Since(start)
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	if w.duration != nil {
+		w.duration.Observe(elapsed.Seconds(), w.sp.Provider(), op, result)
+	}
+	if w.slow > 0 && elapsed >= w.slow {
+		args := []any{"provider", w.sp.Provider(), "op", op, "object_key", key, "duration_ms", elapsed.Milliseconds()}
+		if size > 0 {
+			args = append(args, "bytes", size)
+		}
+		if err != nil {
+			args = append(args, "error", err.Error())
+		}
+		w.log.FromContext(ctx).Warn("slow storage operation", args...)
+	}
+}
+
+func (w *instrumented) addBytes(op string, n int64) {
+	if w.bytes != nil && n > 0 {
+		w.bytes.Add(float64(n), w.sp.Provider(), op)
+	}
+}
+
+// addPartial records a transfer of op cut short after n bytes.
+func (w *instrumented) addPartial(ctx context.Context, op string, n int64) {
+	if w.partial == nil {
+		return
+	}
+	reason := "error"
+	if ctx.Err() != nil {
+		reason = "canceled"
+	}
+	w.partial.Add(1, w.sp.Provider(), op, reason)
+	if n > 0 {
+		w.partialN.Add(float64(n), w.sp.Provider(), op)
+	}
+}
+
+func (w *instrumented) Provider() string { return w.sp.Provider() }
+
+func (w *instrumented) PutObject(ctx context.Context, in ports.PutObjectInput) (ports.PutObjectOutput, error) {
+	start := time.Now()
+	var read int64
+	if in.Reader != nil {
+		in.Reader = &countingReader{ReadCloser: io.NopCloser(in.Reader), n: &read}
+	}
+	out, err := w.sp.PutObject(ctx, in)
+	w.observe(ctx, "put", in.ObjectKey, start, out.Size, err)
+	switch {
+	case err == nil:
+		w.addBytes("put", out.Size)
+	case read > 0 || ctx.Err() != nil:
+		w.addPartial(ctx, "put", read)
+	}
+	return out, err
+}
+
+func (w *instrumented) GetObject(ctx context.Context, objectKey string) (io.ReadCloser, string, int64, error) {
+	start := time.Now()
+	rc, contentType, size, err := w.sp.GetObject(ctx, objectKey)
+	w.observe(ctx, "get", objectKey, start, size, err)
+	if err != nil {
+		return rc, contentType, size, err
+	}
+	var read int64
+	return &countingReader{ReadCloser: rc, n: &read, done: func(eof bool) {
+		w.addBytes("get", read)
+		// Closing unread (a size check) is not a transfer
+		complete := eof || (size >= 0 && read >= size)
+		if !complete && (read > 0 || ctx.Err() != nil) {
+			w.addPartial(ctx, "get", read)
+		}
+	}}, contentType, size, nil
+}
+
+func (w *instrumented) DeleteObject(ctx context.Context, objectKey string) error {
+	start := time.Now()
+	err := w.sp.DeleteObject(ctx, objectKey)
+	w.observe(ctx, "delete", objectKey, start, 0, err)
+	return err
+}
+
+func (w *instrumented) GetSignedURL(ctx context.Context, objectKey string, expiresIn time.Duration) (ports.SignedURLOutput, error) {
+	start := time.Now()
+	out, err := w.sp.GetSignedURL(ctx, objectKey, expiresIn)
+	w.observe(ctx, "signed_url", objectKey, start, 0, err)
+	return out, err
+}
+
+// instrumentedLister is an instrumented provider that can list objects;
+// a listing is timed as a whole, including the calls to fn.
+type instrumentedLister struct {
+	*instrumented
+	l ports.ObjectLister
+}
+
+func (w instrumentedLister) ListObjects(ctx context.Context, prefix string, fn func(ports.ObjectInfo) error) error {
+	start := time.Now()
+	err := w.l.ListObjects(ctx, prefix, fn)
+	w.observe(ctx, "list", prefix, start, 0, err)
+	return err
+}
+
+// countingReader counts the bytes read into n and calls done once, on
+// Close, with whether the reads got to the end.
+type countingReader struct {
+	io.ReadCloser
+	n    *int64
+	eof  bool
+	done func(eof bool)
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	*r.n += int64(n)
+	if errors.Is(err, io.EOF) {
+		r.eof = true
+	}
+	return n, err
+}
+
+func (r *countingReader) Close() error {
+	if r.done != nil {
+		r.done(r.eof)
+		r.done = nil
+	}
+	return r.ReadCloser.Close()
+}
@@ -0,0 +1,140 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/metrics"
+	"gala/internal/ports"
+	"gala/internal/storage"
+	"gala/internal/testsupport"
+)
+
+// cancelReader cancels its context once n bytes were read.
+type cancelReader struct {
+	r      io.Reader
+	n      int
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p[:min(len(p), r.n)])
+	if r.n -= n; r.n == 0 {
+		r.cancel()
+	}
+	return n, err
+}
+
+func TestCopy(t *testing.T) {
+	t.Run("to the end", func(t *testing.T) {
+		var dst strings.Builder
+		n, err := storage.Copy(context.Background(), &dst, strings.NewReader("0123456789"))
+		if err != nil || n != 10 || dst.String() != "0123456789" {
+			t.Errorf("Copy = %d, %v (%q); want all 10 bytes", n, err, dst.String())
+		}
+	})
+
+	t.Run("stops once ctx is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var dst strings.Builder
+		src := &cancelReader{r: strings.NewReader("0123456789"), n: 4, cancel: cancel}
+		n, err := storage.Copy(ctx, &dst, src)
+		if !errors.Is(err, context.Canceled) || n != 4 || dst.String() != "0123" {
+			t.Errorf("Copy = %d, %v (%q); want the 4 bytes read before the cancel and context.Canceled", n, err, dst.String())
+		}
+	})
+
+	t.Run("done before the first read", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		n, err := storage.Copy(ctx, io.Discard, strings.NewReader("0123456789"))
+		if !errors.Is(err, context.Canceled) || n != 0 {
+			t.Errorf("Copy = %d, %v; want 0, context.Canceled", n, err)
+		}
+	})
+}
+
+func TestWithContextWithoutDone(t *testing.T) {
+	r := strings.NewReader("x")
+	if got := storage.WithContext(context.Background(), r); got != r {
+		t.Error("WithContext wrapped a reader for a context that is never done")
+	}
+}
+
+// failingReader returns n bytes and then err.
+type failingReader struct {
+	n   int
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, r.err
+	}
+	n := min(len(p), r.n)
+	r.n -= n
+	return n, nil
+}
+
+func TestInstrumentTransfers(t *testing.T) {
+	ctx := context.Background()
+	mem := testsupport.NewStorage()
+	reg := metrics.NewRegistry()
+	sp := storage.Instrument(mem, reg, logger.New(logger.Config{Level: "error", Output: io.Discard}), 0)
+
+	if _, err := sp.PutObject(ctx, ports.PutObjectInput{ObjectKey: "a", Reader: strings.NewReader("0123456789")}); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(ctx context.Context, read int64) {
+		t.Helper()
+		rc, _, _, err := sp.GetObject(ctx, "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.CopyN(io.Discard, rc, read); err != nil && !errors.Is(err, io.EOF) {
+			t.Fatal(err)
+		}
+		_ = rc.Close()
+	}
+	get(ctx, 100) // read to the end
+	get(ctx, 10)  // every byte, without seeing EOF
+	get(ctx, 0)   // opened and closed: a size check
+	get(ctx, 3)   // given up midway
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	get(canceled, 2) // client gone
+
+	// Fails before reading anything: not a transfer
+	mem.Fail = testsupport.FailFirst(1, testsupport.OpPut, "b")
+	_, _ = sp.PutObject(ctx, ports.PutObjectInput{ObjectKey: "b", Reader: strings.NewReader("xyz")})
+	// Fails after reading 4 bytes
+	_, err := sp.PutObject(ctx, ports.PutObjectInput{ObjectKey: "c", Reader: &failingReader{n: 4, err: errors.New("disk gone")}})
+	if err == nil {
+		t.Fatal("PutObject of a failing reader succeeded")
+	}
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+	for _, want := range []string{
+		`gala_storage_bytes_total{provider="memory",op="put"} 10`,
+		`gala_storage_bytes_total{provider="memory",op="get"} 25`,
+		`gala_storage_partial_transfers_total{provider="memory",op="get",reason="error"} 1`,
+		`gala_storage_partial_transfers_total{provider="memory",op="get",reason="canceled"} 1`,
+		`gala_storage_partial_transfers_total{provider="memory",op="put",reason="error"} 1`,
+		`gala_storage_partial_bytes_total{provider="memory",op="get"} 5`,
+		`gala_storage_partial_bytes_total{provider="memory",op="put"} 4`,
+		`gala_storage_operation_duration_seconds_count{provider="memory",op="put",result="error"} 2`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
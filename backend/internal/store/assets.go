@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"iter"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"gala/internal/models"
+	"gala/internal/pkg/db"
+)
+
+// Asset is a row of assets (see models.Asset).
+type Asset = models.Asset
+
+// AssetMedia are the probed specs of a video, stored in assets.media.
+type AssetMedia = models.AssetMedia
+
+const assetColumns = `id, kind, provider, object_key, mime, size_bytes, checksum, label, media, created_at, updated_at`
+
+func scanAsset(row pgx.Row) (Asset, error) {
+	var (
+		a               Asset
+		checksum, label sql.NullString
+		media           []byte
+	)
+	err := row.Scan(&a.ID, &a.Kind, &a.Provider, &a.ObjectKey, &a.Mime, &a.SizeBytes, &checksum, &label, &media, &a.CreatedAt, &a.UpdatedAt)
+	a.Checksum, a.Label = checksum.String, label.String
+	if err == nil && media != nil {
+		a.Media = new(AssetMedia)
+		err = json.Unmarshal(media, a.Media)
+	}
+	a.CreatedAt, a.UpdatedAt = a.CreatedAt.UTC(), a.UpdatedAt.UTC()
+	return a, err
+}
+
+// InsertAsset inserts a; updated_at starts as created_at.
+func InsertAsset(ctx context.Context, q db.Querier, a Asset) error {
+	_, err := q.Exec(ctx,
+		`INSERT INTO assets (id, kind, provider, object_key, mime, size_bytes, checksum, label, media, created_at, updated_at)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$10)`,
+		a.ID, a.Kind, a.Provider, a.ObjectKey, a.Mime, a.SizeBytes, nullIfEmpty(a.Checksum), nullIfEmpty(a.Label), a.Media, a.CreatedAt,
+	)
+	return err
+}
+
+// GetAsset returns the asset with id, or pgx.ErrNoRows.
+func GetAsset(ctx context.Context, q db.Querier, id string) (Asset, error) {
+	return scanAsset(q.QueryRow(ctx, `SELECT `+assetColumns+` FROM assets WHERE id=$1`, id))
+}
+
+// GetAssets returns the assets whose id is in ids, in no particular order;
+// missing ids are skipped.
+func GetAssets(ctx context.Context, q db.Querier, ids []string) ([]Asset, error) {
+	rows, err := q.Query(ctx, `SELECT `+assetColumns+` FROM assets WHERE id = ANY($1)`, ids)
+	return collect(rows, err, scanAsset)
+}
+
+// AssetFilter selects assets. Zero fields do not filter.
+type AssetFilter struct {
+	Kind     string
+	Provider string
+	// Search matches label, case-insensitively, anywhere.
+	Search string
+}
+
+func (f AssetFilter) build() *filter {
+	var w filter
+	if f.Kind != "" {
+		w.add("kind=%s", f.Kind)
+	}
+	if f.Provider != "" {
+		w.add("provider=%s", f.Provider)
+	}
+	if f.Search != "" {
+		w.add("label ILIKE %s", "%"+f.Search+"%")
+	}
+	return &w
+}
+
+// ListAssets returns up to limit assets matching f after the keyset,
+// newest first.
+func ListAssets(ctx context.Context, q db.Querier, f AssetFilter, after *Keyset, limit int) ([]Asset, error) {
+	w := f.build()
+	w.afterKeyset(after)
+	lim := w.limit(limit)
+	rows, err := q.Query(ctx,
+		`SELECT `+assetColumns+` FROM assets WHERE `+w.where()+`
+		 ORDER BY created_at DESC, id DESC`+lim,
+		w.args...,
+	)
+	return collect(rows, err, scanAsset)
+}
+
+// StreamAssets iterates over every asset matching f, newest first.
+func StreamAssets(ctx context.Context, q db.Querier, f AssetFilter) (iter.Seq2[Asset, error], error) {
+	// The rows are read as the caller consumes them, for as long as ctx
+	// allows, so the per-statement timeout does not apply
+	ctx = db.WithQueryTimeout(ctx, 0)
+	w := f.build()
+	rows, err := q.Query(ctx,
+		`SELECT `+assetColumns+` FROM assets WHERE `+w.where()+`
+		 ORDER BY created_at DESC, id DESC`,
+		w.args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return seq(rows, scanAsset), nil
+}
+
+// LockAsset returns the asset with id locked FOR UPDATE until q's
+// transaction ends, or pgx.ErrNoRows.
+func LockAsset(ctx context.Context, q db.Querier, id string) (Asset, error) {
+	return scanAsset(q.QueryRow(ctx, `SELECT `+assetColumns+` FROM assets WHERE id=$1 FOR UPDATE`, id))
+}
+
+// CountAssetRefs returns how many job outputs and HLS renditions reference
+// an asset.
+func CountAssetRefs(ctx context.Context, q db.Querier, id string) (int, error) {
+	var n int
+	err := q.QueryRow(ctx,
+		`SELECT (SELECT COUNT(1)
+		         FROM job_outputs
+		         WHERE video_asset_id=$1 OR thumbnail_asset_id=$1 OR captions_asset_id=$1)
+		      + (SELECT COUNT(1) FROM asset_hls_files WHERE asset_id=$1)`,
+		id,
+	).Scan(&n)
+	return n, err
+}
+
+// SetAssetSize sets an asset's size_bytes.
+func SetAssetSize(ctx context.Context, q db.Querier, id string, size int64) error {
+	_, err := q.Exec(ctx, `UPDATE assets SET size_bytes=$2 WHERE id=$1`, id, size)
+	return err
+}
+
+// SampleOutputAssets returns up to limit assets of provider that are
+// outputs of jobs finished since since: the never checked first, then the
+// longest unchecked, in random order among equals.
+func SampleOutputAssets(ctx context.Context, q db.Querier, provider string, since time.Time, limit int) ([]Asset, error) {
+	rows, err := q.Query(ctx,
+		`SELECT `+assetColumns+` FROM assets
+		 WHERE provider=$1 AND id IN (
+		   SELECT unnest(ARRAY[video_asset_id, thumbnail_asset_id, captions_asset_id])
+		   FROM job_outputs WHERE created_at >= $2)
+		 ORDER BY (SELECT checked_at FROM asset_integrity i WHERE i.asset_id = assets.id) NULLS FIRST, random()
+		 LIMIT $3`,
+		provider, since, limit,
+	)
+	return collect(rows, err, scanAsset)
+}
+
+// SetAssetIntegrity records the outcome of an integrity check of an
+// asset; errText is empty when it passed.
+func SetAssetIntegrity(ctx context.Context, q db.Querier, id, errText string) error {
+	_, err := q.Exec(ctx,
+		`INSERT INTO asset_integrity (asset_id, checked_at, error) VALUES ($1, NOW(), $2)
+		 ON CONFLICT (asset_id) DO UPDATE SET checked_at=EXCLUDED.checked_at, error=EXCLUDED.error`,
+		id, nullIfEmpty(errText),
+	)
+	return err
+}
+
+// DeleteAsset deletes the asset row (not its storage object).
+func DeleteAsset(ctx context.Context, q db.Querier, id string) error {
+	_, err := q.Exec(ctx, `DELETE FROM assets WHERE id=$1`, id)
+	return err
+}
+
+// UnreferencedAssets returns up to limit assets created before before that
+// nothing points to: no job output, HLS rendition, job params or template
+// defaults or watermark mention their id. It scans jobs and templates, so
+// it is meant for occasional garbage collection, not request paths.
+func UnreferencedAssets(ctx context.Context, q db.Querier, before time.Time, limit int) ([]Asset, error) {
+	rows, err := q.Query(ctx,
+		`SELECT `+assetColumns+` FROM assets a
+		 WHERE a.created_at < $1
+		   AND NOT EXISTS (
+		     SELECT 1 FROM job_outputs o
+		     WHERE o.video_asset_id=a.id OR o.thumbnail_asset_id=a.id OR o.captions_asset_id=a.id)
+		   AND NOT EXISTS (SELECT 1 FROM asset_hls_files h WHERE h.asset_id=a.id)
+		   AND NOT EXISTS (SELECT 1 FROM jobs j WHERE strpos(j.params_json, a.id) > 0)
+		   AND NOT EXISTS (
+		     SELECT 1 FROM templates t
+		     WHERE strpos(t.defaults::text, a.id) > 0 OR strpos(t.watermark::text, a.id) > 0)
+		 ORDER BY a.created_at, a.id
+		 LIMIT $2`,
+		before, limit,
+	)
+	return collect(rows, err, scanAsset)
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"gala/internal/pkg/db"
+)
+
+// JobCallback is a row of job_callbacks: where to post a job's completion
+// and how the delivery went. DeliveredAt is set once an attempt got a 2xx
+// answer; LastError holds the failure of the last attempt otherwise.
+type JobCallback struct {
+	JobID       string
+	URL         string
+	Secret      string
+	Attempts    int
+	LastError   string
+	DeliveredAt *time.Time
+	CreatedAt   time.Time
+}
+
+// InsertJobCallback records the callback of a job.
+func InsertJobCallback(ctx context.Context, q db.Querier, c JobCallback) error {
+	_, err := q.Exec(ctx,
+		`INSERT INTO job_callbacks (job_id, url, secret, created_at) VALUES ($1,$2,$3,$4)`,
+		c.JobID, c.URL, c.Secret, c.CreatedAt,
+	)
+	return err
+}
+
+// DeleteJobCallback deletes the callback of a job, if it has one.
+func DeleteJobCallback(ctx context.Context, q db.Querier, jobID string) error {
+	_, err := q.Exec(ctx, `DELETE FROM job_callbacks WHERE job_id=$1`, jobID)
+	return err
+}
+
+// GetJobCallback returns the callback of a job, or pgx.ErrNoRows if it has
+// none.
+func GetJobCallback(ctx context.Context, q db.Querier, jobID string) (JobCallback, error) {
+	var c JobCallback
+	err := q.QueryRow(ctx,
+		`SELECT job_id, url, secret, attempts, COALESCE(last_error,''), delivered_at, created_at
+		 FROM job_callbacks WHERE job_id=$1`,
+		jobID,
+	).Scan(&c.JobID, &c.URL, &c.Secret, &c.Attempts, &c.LastError, &c.DeliveredAt, &c.CreatedAt)
+	c.CreatedAt = c.CreatedAt.UTC()
+	c.DeliveredAt = utcPtr(c.DeliveredAt)
+	return c, err
+}
+
+// CallbackDelivery is a row of job_callback_deliveries: an attempt to
+// deliver a job's callback. Attempt counts the callback's attempts from 1,
+// across the worker runs that tried it; StatusCode is 0 if the receiver
+// did not answer, and Error is empty once it answered 2xx.
+type CallbackDelivery struct {
+	ID         int64     `json:"id"`
+	JobID      string    `json:"job_id"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// RecordCallbackAttempt counts a delivery attempt and logs it in
+// job_callback_deliveries; an empty d.Error marks the callback delivered.
+// d.JobID names the callback and d.Attempt is ignored: it is the new count.
+func RecordCallbackAttempt(ctx context.Context, q db.Querier, d CallbackDelivery) error {
+	if len(d.Error) > 500 {
+		d.Error = d.Error[:500]
+	}
+	_, err := q.Exec(ctx,
+		`WITH cb AS (
+		   UPDATE job_callbacks
+		   SET attempts=attempts+1, last_error=$2::text,
+		       delivered_at=CASE WHEN $2::text IS NULL THEN NOW() ELSE delivered_at END
+		   WHERE job_id=$1
+		   RETURNING job_id, attempts
+		 )
+		 INSERT INTO job_callback_deliveries (job_id, attempt, status_code, error, duration_ms)
+		 SELECT job_id, attempts, $3, $2::text, $4 FROM cb`,
+		d.JobID, nullIfEmpty(d.Error), nullIfZero(d.StatusCode), d.DurationMS,
+	)
+	return err
+}
+
+// ListCallbackDeliveries returns the delivery log of a job's callback,
+// oldest attempt first.
+func ListCallbackDeliveries(ctx context.Context, q db.Querier, jobID string) ([]CallbackDelivery, error) {
+	rows, err := q.Query(ctx,
+		`SELECT id, job_id, attempt, COALESCE(status_code,0), COALESCE(error,''), duration_ms, created_at
+		 FROM job_callback_deliveries WHERE job_id=$1 ORDER BY id`,
+		jobID,
+	)
+	return collect(rows, err, func(row pgx.Row) (CallbackDelivery, error) {
+		var d CallbackDelivery
+		err := row.Scan(&d.ID, &d.JobID, &d.Attempt, &d.StatusCode, &d.Error, &d.DurationMS, &d.CreatedAt)
+		d.CreatedAt = d.CreatedAt.UTC()
+		return d, err
+	})
+}
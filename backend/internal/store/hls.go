@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"gala/internal/pkg/db"
+)
+
+// HLSFile is a file of the HLS rendition of a video asset: Name is the
+// playlist-relative name (index.m3u8, seg_0000.ts, ...) and AssetID the
+// asset holding it.
+type HLSFile struct {
+	VideoAssetID string
+	Name         string
+	AssetID      string
+}
+
+// HLSPlaylist is the name of the master playlist of a rendition.
+const HLSPlaylist = "index.m3u8"
+
+// InsertHLSFile records a file of a video's HLS rendition.
+func InsertHLSFile(ctx context.Context, q db.Querier, f HLSFile) error {
+	_, err := q.Exec(ctx,
+		`INSERT INTO asset_hls_files (video_asset_id, name, asset_id) VALUES ($1,$2,$3)`,
+		f.VideoAssetID, f.Name, f.AssetID,
+	)
+	if err != nil {
+		return fmt.Errorf("insert hls file: %w", err)
+	}
+	return nil
+}
+
+// GetHLSFile returns the asset holding file name of the HLS rendition of
+// videoAssetID; pgx.ErrNoRows if the video has no such file.
+func GetHLSFile(ctx context.Context, q db.Querier, videoAssetID, name string) (Asset, error) {
+	return scanAsset(q.QueryRow(ctx,
+		`SELECT `+assetColumns+` FROM assets
+		 WHERE id = (SELECT asset_id FROM asset_hls_files WHERE video_asset_id=$1 AND name=$2)`,
+		videoAssetID, name,
+	))
+}
+
+// VideosWithHLS returns which of videoAssetIDs have an HLS rendition.
+func VideosWithHLS(ctx context.Context, q db.Querier, videoAssetIDs []string) (map[string]bool, error) {
+	rows, err := q.Query(ctx,
+		`SELECT video_asset_id FROM asset_hls_files
+		 WHERE video_asset_id = ANY($1) AND name=$2`,
+		videoAssetIDs, HLSPlaylist,
+	)
+	ids, err := collect(rows, err, func(row pgx.Row) (string, error) {
+		var id string
+		err := row.Scan(&id)
+		return id, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		out[id] = true
+	}
+	return out, nil
+}
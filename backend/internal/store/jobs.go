@@ -0,0 +1,641 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"gala/internal/models"
+	"gala/internal/pkg/db"
+)
+
+// Job statuses.
+const (
+	// JobScheduled jobs wait for their run_at to be queued.
+	JobScheduled = "SCHEDULED"
+	JobQueued    = "QUEUED"
+	JobRunning   = "RUNNING"
+	JobDone      = "DONE"
+	JobFailed    = "FAILED"
+	// JobCanceled jobs were canceled: while QUEUED, and workers skip them,
+	// or while RUNNING, and the worker stopped them.
+	JobCanceled = "CANCELED"
+)
+
+// JobStatuses lists every job status, in lifecycle order.
+var JobStatuses = []string{JobScheduled, JobQueued, JobRunning, JobDone, JobFailed, JobCanceled}
+
+// Job stages, the steps a RUNNING job goes through (jobs.stage), in order.
+// JobStageHLS only runs with HLS packaging on.
+const (
+	JobStageParse    = "parse"
+	JobStageInputs   = "inputs"
+	JobStageRender   = "render"
+	JobStageHLS      = "hls"
+	JobStageUpload   = "upload"
+	JobStageRegister = "register"
+)
+
+// stageProgress is the progress of a job as it enters each stage. The
+// render takes most of the run: the renderer's own progress fills the range
+// from JobStageRender to the next stage (see RenderProgress).
+var stageProgress = map[string]int{
+	JobStageParse:    0,
+	JobStageInputs:   5,
+	JobStageRender:   15,
+	JobStageHLS:      80,
+	JobStageUpload:   85,
+	JobStageRegister: 95,
+}
+
+// StageProgress is the progress of a job entering stage; 0 for unknown
+// stages.
+func StageProgress(stage string) int {
+	return stageProgress[stage]
+}
+
+// RenderProgress maps the progress the renderer reports for its render
+// (0-100, clamped) to the progress of the job.
+func RenderProgress(percent int) int {
+	percent = min(max(percent, 0), 100)
+	from, to := stageProgress[JobStageRender], stageProgress[JobStageHLS]
+	return from + percent*(to-from)/100
+}
+
+// Job priorities. jobs.priority stores them as 0 and 1, so queued jobs
+// sort by it.
+const (
+	JobPriorityNormal = "normal"
+	JobPriorityHigh   = "high"
+)
+
+// JobPriorities lists every job priority, lowest first.
+var JobPriorities = []string{JobPriorityNormal, JobPriorityHigh}
+
+// priorityLevel is the jobs.priority of priority; unknown ones are normal.
+func priorityLevel(priority string) int {
+	if priority == JobPriorityHigh {
+		return 1
+	}
+	return 0
+}
+
+// Job is a row of jobs (see models.Job).
+type Job = models.Job
+
+// JobError is the structured failure of a job, stored in error_detail.
+type JobError = models.JobError
+
+const jobColumns = `id, COALESCE(name,''), status, params_json, error_text, error_detail, attempts, COALESCE(params_hash,''), COALESCE(request_id,''), CASE WHEN priority > 0 THEN 'high' ELSE 'normal' END, run_at, progress, stage, created_at, updated_at, started_at, finished_at`
+
+func scanJob(row pgx.Row) (Job, error) {
+	var (
+		j       Job
+		errText sql.NullString
+		detail  []byte
+	)
+	err := row.Scan(&j.ID, &j.Name, &j.Status, &j.ParamsJSON, &errText, &detail, &j.Attempts, &j.ParamsHash, &j.RequestID, &j.Priority, &j.RunAt, &j.Progress, &j.Stage, &j.CreatedAt, &j.UpdatedAt, &j.StartedAt, &j.FinishedAt)
+	if err == nil && detail != nil {
+		j.Error = new(JobError)
+		err = json.Unmarshal(detail, j.Error)
+	}
+	j.ErrorText = strings.TrimSpace(errText.String)
+	j.CreatedAt, j.UpdatedAt = j.CreatedAt.UTC(), j.UpdatedAt.UTC()
+	j.StartedAt, j.FinishedAt = utcPtr(j.StartedAt), utcPtr(j.FinishedAt)
+	j.RunAt = utcPtr(j.RunAt)
+	return j, err
+}
+
+// InsertJob inserts j with status QUEUED, or SCHEDULED if j.Status says
+// so; updated_at starts as created_at.
+func InsertJob(ctx context.Context, q db.Querier, j Job) error {
+	status := JobQueued
+	if j.Status == JobScheduled {
+		status = JobScheduled
+	}
+	_, err := q.Exec(ctx,
+		`INSERT INTO jobs (id, name, status, params_json, params_hash, request_id, priority, run_at, created_at, updated_at)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$9)`,
+		j.ID, nullIfEmpty(j.Name), status, j.ParamsJSON, nullIfEmpty(j.ParamsHash), nullIfEmpty(j.RequestID), priorityLevel(j.Priority), j.RunAt, j.CreatedAt,
+	)
+	return err
+}
+
+// DeleteQueuedJob deletes a job that is still QUEUED or SCHEDULED and
+// reports whether it did.
+func DeleteQueuedJob(ctx context.Context, q db.Querier, id string) (bool, error) {
+	tag, err := q.Exec(ctx, `DELETE FROM jobs WHERE id=$1 AND status IN ('QUEUED','SCHEDULED')`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetJob returns the job with id, or pgx.ErrNoRows.
+func GetJob(ctx context.Context, q db.Querier, id string) (Job, error) {
+	return scanJob(q.QueryRow(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id=$1`, id))
+}
+
+// FindDoneJob returns the last job with paramsHash that finished DONE at
+// or after since, or pgx.ErrNoRows.
+func FindDoneJob(ctx context.Context, q db.Querier, paramsHash string, since time.Time) (Job, error) {
+	return scanJob(q.QueryRow(ctx,
+		`SELECT `+jobColumns+` FROM jobs
+		 WHERE params_hash=$1 AND status='DONE' AND finished_at >= $2
+		 ORDER BY finished_at DESC LIMIT 1`,
+		paramsHash, since,
+	))
+}
+
+// JobFilter selects jobs. Zero fields do not filter.
+type JobFilter struct {
+	Status string
+	// Since and Until bound created_at: Since <= created_at < Until.
+	Since, Until time.Time
+}
+
+func (f JobFilter) build() *filter {
+	var w filter
+	if f.Status != "" {
+		w.add("status=%s", f.Status)
+	}
+	if !f.Since.IsZero() {
+		w.add("created_at >= %s", f.Since)
+	}
+	if !f.Until.IsZero() {
+		w.add("created_at < %s", f.Until)
+	}
+	return &w
+}
+
+// ListJobs returns up to limit jobs matching f after the keyset, newest
+// first.
+func ListJobs(ctx context.Context, q db.Querier, f JobFilter, after *Keyset, limit int) ([]Job, error) {
+	w := f.build()
+	w.afterKeyset(after)
+	lim := w.limit(limit)
+	rows, err := q.Query(ctx,
+		`SELECT `+jobColumns+` FROM jobs WHERE `+w.where()+`
+		 ORDER BY created_at DESC, id DESC`+lim,
+		w.args...,
+	)
+	return collect(rows, err, scanJob)
+}
+
+// StreamJobs iterates over every job matching f, oldest first.
+func StreamJobs(ctx context.Context, q db.Querier, f JobFilter) (iter.Seq2[Job, error], error) {
+	// The rows are read as the caller consumes them, for as long as ctx
+	// allows, so the per-statement timeout does not apply
+	ctx = db.WithQueryTimeout(ctx, 0)
+	w := f.build()
+	rows, err := q.Query(ctx,
+		`SELECT `+jobColumns+` FROM jobs WHERE `+w.where()+`
+		 ORDER BY created_at, id`,
+		w.args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return seq(rows, scanJob), nil
+}
+
+// ClaimNextJob moves the oldest QUEUED job of the highest priority to
+// RUNNING and returns its id, or "" if none is queued. Jobs whose run_at
+// is still ahead (deferred by DeferClaimedJob) wait. SKIP LOCKED keeps
+// concurrent callers from claiming the same job.
+func ClaimNextJob(ctx context.Context, q db.Querier) (string, error) {
+	var id string
+	err := q.QueryRow(ctx, `
+		UPDATE jobs SET status='RUNNING', started_at=NOW()
+		WHERE id = (
+		  SELECT id FROM jobs
+		  WHERE status='QUEUED' AND (run_at IS NULL OR run_at <= NOW())
+		  ORDER BY priority DESC, created_at
+		  LIMIT 1
+		  FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id`,
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return id, err
+}
+
+// UnclaimJob sets a job claimed by ClaimNextJob that the worker did not
+// start back to QUEUED, and reports whether it did.
+func UnclaimJob(ctx context.Context, q db.Querier, id string) (bool, error) {
+	tag, err := q.Exec(ctx,
+		`UPDATE jobs SET status='QUEUED', started_at=NULL
+		 WHERE id=$1 AND status='RUNNING'`,
+		id,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeferClaimedJob sets a job claimed by ClaimNextJob that the worker did
+// not start back to QUEUED, not to be claimed again before until, and
+// reports whether it did.
+func DeferClaimedJob(ctx context.Context, q db.Querier, id string, until time.Time) (bool, error) {
+	tag, err := q.Exec(ctx,
+		`UPDATE jobs SET status='QUEUED', started_at=NULL, run_at=$2
+		 WHERE id=$1 AND status='RUNNING'`,
+		id, until,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MarkJobRunning sets a QUEUED job RUNNING, clearing any previous result
+// and progress, and counts the attempt. claimed is set when the job was
+// already set RUNNING by ClaimNextJob (postgres queue mode): only then is a
+// RUNNING job taken, since in Redis mode its id may be in the list twice
+// and another worker is already running it. It returns the params_json the
+// job runs with, which an admin may have edited since the worker read the
+// job, and reports false if the job is in another state, e.g. canceled
+// since it was popped.
+func MarkJobRunning(ctx context.Context, q db.Querier, id string, claimed bool) (string, bool, error) {
+	from := JobQueued
+	if claimed {
+		from = JobRunning
+	}
+	var params string
+	err := q.QueryRow(ctx,
+		`UPDATE jobs SET status='RUNNING', started_at=NOW(), finished_at=NULL, error_text=NULL, error_detail=NULL,
+		   attempts=attempts+1, progress=0, stage=''
+		 WHERE id=$1 AND status=$2
+		 RETURNING params_json`,
+		id, from,
+	).Scan(&params)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	return params, err == nil, err
+}
+
+// MarkJobDone sets a RUNNING job DONE, with progress 100. It reports
+// false if the job was no longer RUNNING, e.g. reaped or canceled while
+// its outputs were saved.
+func MarkJobDone(ctx context.Context, q db.Querier, id string) (bool, error) {
+	tag, err := q.Exec(ctx,
+		`UPDATE jobs SET status='DONE', finished_at=NOW(), progress=100 WHERE id=$1 AND status='RUNNING'`,
+		id,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// SetJobStage records that a RUNNING job entered stage, with its
+// StageProgress. It reports false if the job is not RUNNING.
+func SetJobStage(ctx context.Context, q db.Querier, id, stage string) (bool, error) {
+	tag, err := q.Exec(ctx,
+		`UPDATE jobs SET stage=$2, progress=$3 WHERE id=$1 AND status='RUNNING'`,
+		id, stage, StageProgress(stage),
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// SetRenderProgress records the progress the renderer reports for the
+// render of a job (see RenderProgress) and returns the job's progress,
+// which never goes back. It returns pgx.ErrNoRows if the job does not exist
+// or is not RUNNING its render.
+func SetRenderProgress(ctx context.Context, q db.Querier, id string, percent int) (int, error) {
+	var progress int
+	err := q.QueryRow(ctx,
+		`UPDATE jobs SET progress=GREATEST(progress, $2)
+		 WHERE id=$1 AND status='RUNNING' AND stage='render'
+		 RETURNING progress`,
+		id, RenderProgress(percent),
+	).Scan(&progress)
+	return progress, err
+}
+
+// SetJobParams replaces the params_json of a job, as the worker does once
+// it has imported the inputs given by URL.
+func SetJobParams(ctx context.Context, q db.Querier, id, paramsJSON string) error {
+	_, err := q.Exec(ctx, `UPDATE jobs SET params_json=$2 WHERE id=$1`, id, paramsJSON)
+	return err
+}
+
+// SetJobRenderSpec stores the renderer spec (JSON) sent for a job.
+func SetJobRenderSpec(ctx context.Context, q db.Querier, id string, spec []byte) error {
+	_, err := q.Exec(ctx, `UPDATE jobs SET render_spec=$2 WHERE id=$1`, id, spec)
+	return err
+}
+
+// JobRenderSpec returns the renderer spec stored for a job, nil if it has
+// none, or pgx.ErrNoRows if the job does not exist.
+func JobRenderSpec(ctx context.Context, q db.Querier, id string) ([]byte, error) {
+	var spec []byte
+	err := q.QueryRow(ctx, `SELECT render_spec FROM jobs WHERE id=$1`, id).Scan(&spec)
+	return spec, err
+}
+
+// StoredRenderSpec is the renderer spec stored for a job.
+type StoredRenderSpec struct {
+	JobID string
+	Spec  []byte
+}
+
+// RecentRenderSpecs returns the renderer specs of the last limit jobs that
+// have one, newest first.
+func RecentRenderSpecs(ctx context.Context, q db.Querier, limit int) ([]StoredRenderSpec, error) {
+	rows, err := q.Query(ctx,
+		`SELECT id, render_spec FROM jobs WHERE render_spec IS NOT NULL ORDER BY created_at DESC LIMIT $1`,
+		limit,
+	)
+	return collect(rows, err, func(r pgx.Row) (StoredRenderSpec, error) {
+		var s StoredRenderSpec
+		err := r.Scan(&s.JobID, &s.Spec)
+		return s, err
+	})
+}
+
+// MarkJobFailed sets a job FAILED with errorText and detail, unless it was
+// canceled. detail.Attempt is set to the job's current attempt.
+func MarkJobFailed(ctx context.Context, q db.Querier, id, errorText string, detail JobError) error {
+	raw, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx,
+		`UPDATE jobs SET status='FAILED', finished_at=NOW(), error_text=$2,
+		   error_detail=$3::jsonb || jsonb_build_object('attempt', attempts)
+		 WHERE id=$1 AND status<>'CANCELED'`,
+		id, errorText, string(raw),
+	)
+	return err
+}
+
+// MarkJobCanceled sets a RUNNING job CANCELED, once its worker stopped it.
+// It reports false if the job was no longer RUNNING.
+func MarkJobCanceled(ctx context.Context, q db.Querier, id string) (bool, error) {
+	tag, err := q.Exec(ctx,
+		`UPDATE jobs SET status='CANCELED', finished_at=NOW() WHERE id=$1 AND status='RUNNING'`,
+		id,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// UpdateQueuedJobParams replaces the params_json (and params_hash) of a
+// QUEUED job whose updated_at is still updatedAt, and returns it. It
+// returns pgx.ErrNoRows if the job does not exist, is in another state or
+// changed since.
+func UpdateQueuedJobParams(ctx context.Context, q db.Querier, id, paramsJSON, paramsHash string, updatedAt time.Time) (Job, error) {
+	return scanJob(q.QueryRow(ctx,
+		`UPDATE jobs SET params_json=$2, params_hash=$3
+		 WHERE id=$1 AND status='QUEUED' AND updated_at=$4
+		 RETURNING `+jobColumns,
+		id, paramsJSON, nullIfEmpty(paramsHash), updatedAt,
+	))
+}
+
+// RequeueJob moves a FAILED or CANCELED job back to QUEUED, clearing its
+// previous run, and returns it. run_at is cleared too: the job runs now,
+// even if it was canceled while still SCHEDULED. It returns pgx.ErrNoRows
+// if the job does not exist or is in another state.
+func RequeueJob(ctx context.Context, q db.Querier, id string) (Job, error) {
+	return scanJob(q.QueryRow(ctx,
+		`UPDATE jobs SET status='QUEUED', started_at=NULL, finished_at=NULL, error_text=NULL, error_detail=NULL,
+		   progress=0, stage='', run_at=NULL
+		 WHERE id=$1 AND status IN ('FAILED','CANCELED')
+		 RETURNING `+jobColumns,
+		id,
+	))
+}
+
+// JobRun is the result of the last run of a job, which RequeueJob clears:
+// LockJobRun saves it and RestoreJobRun puts it back.
+type JobRun struct {
+	ID          string
+	Status      string
+	ErrorText   sql.NullString
+	ErrorDetail []byte
+	Progress    int
+	Stage       string
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+	RunAt       *time.Time
+}
+
+// LockJobRun returns the run of the job with id, locked FOR UPDATE until
+// q's transaction ends, or pgx.ErrNoRows.
+func LockJobRun(ctx context.Context, q db.Querier, id string) (JobRun, error) {
+	r := JobRun{ID: id}
+	err := q.QueryRow(ctx,
+		`SELECT status, error_text, error_detail, progress, stage, started_at, finished_at, run_at
+		 FROM jobs WHERE id=$1 FOR UPDATE`,
+		id,
+	).Scan(&r.Status, &r.ErrorText, &r.ErrorDetail, &r.Progress, &r.Stage, &r.StartedAt, &r.FinishedAt, &r.RunAt)
+	return r, err
+}
+
+// RestoreJobRun puts r back on a job that RequeueJob set QUEUED, if it is
+// still QUEUED, and reports whether it did.
+func RestoreJobRun(ctx context.Context, q db.Querier, r JobRun) (bool, error) {
+	tag, err := q.Exec(ctx,
+		`UPDATE jobs SET status=$2, error_text=$3, error_detail=$4, progress=$5, stage=$6, started_at=$7, finished_at=$8,
+		   run_at=$9
+		 WHERE id=$1 AND status='QUEUED'`,
+		r.ID, r.Status, r.ErrorText, r.ErrorDetail, r.Progress, r.Stage, r.StartedAt, r.FinishedAt, r.RunAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// CancelQueuedJob sets a QUEUED or SCHEDULED job CANCELED and returns
+// it. It returns pgx.ErrNoRows if the job does not exist or is in another
+// status.
+func CancelQueuedJob(ctx context.Context, q db.Querier, id string) (Job, error) {
+	return scanJob(q.QueryRow(ctx,
+		`UPDATE jobs SET status='CANCELED', finished_at=NOW()
+		 WHERE id=$1 AND status IN ('QUEUED','SCHEDULED')
+		 RETURNING `+jobColumns,
+		id,
+	))
+}
+
+// QueueScheduledJob sets a SCHEDULED job QUEUED and returns it. It returns
+// pgx.ErrNoRows if the job does not exist or is no longer SCHEDULED
+// (canceled, or queued by another worker).
+func QueueScheduledJob(ctx context.Context, q db.Querier, id string) (Job, error) {
+	return scanJob(q.QueryRow(ctx,
+		`UPDATE jobs SET status='QUEUED', updated_at=NOW()
+		 WHERE id=$1 AND status='SCHEDULED'
+		 RETURNING `+jobColumns,
+		id,
+	))
+}
+
+// UnqueueScheduledJob sets a job that QueueScheduledJob queued back to
+// SCHEDULED, if it is still QUEUED.
+func UnqueueScheduledJob(ctx context.Context, q db.Querier, id string) error {
+	_, err := q.Exec(ctx,
+		`UPDATE jobs SET status='SCHEDULED', updated_at=NOW()
+		 WHERE id=$1 AND status='QUEUED' AND run_at IS NOT NULL`,
+		id,
+	)
+	return err
+}
+
+// QueueDueJobs sets every SCHEDULED job whose run_at is not after now
+// QUEUED (the jobs trigger notifies the workers in postgres queue mode),
+// and returns them.
+func QueueDueJobs(ctx context.Context, q db.Querier, now time.Time) ([]Job, error) {
+	rows, err := q.Query(ctx,
+		`UPDATE jobs SET status='QUEUED', updated_at=NOW()
+		 WHERE status='SCHEDULED' AND run_at <= $1
+		 RETURNING `+jobColumns,
+		now,
+	)
+	return collect(rows, err, scanJob)
+}
+
+// CancelQueuedJobs sets every QUEUED job CANCELED and returns how many
+// there were.
+func CancelQueuedJobs(ctx context.Context, q db.Querier) (int64, error) {
+	tag, err := q.Exec(ctx, `UPDATE jobs SET status='CANCELED', finished_at=NOW() WHERE status='QUEUED'`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// CountJobsByStatus returns the number of jobs matching f in each status,
+// in one aggregate query. f.Status is ignored, so the counts of the other
+// statuses come along; statuses without jobs are missing.
+func CountJobsByStatus(ctx context.Context, q db.Querier, f JobFilter) (map[string]int64, error) {
+	f.Status = ""
+	w := f.build()
+	rows, err := q.Query(ctx,
+		`SELECT status, COUNT(1) FROM jobs WHERE `+w.where()+` GROUP BY status`,
+		w.args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]int64{}
+	for rows.Next() {
+		var (
+			status string
+			n      int64
+		)
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		out[status] = n
+	}
+	return out, rows.Err()
+}
+
+// OldestQueuedJob returns when the oldest QUEUED job was created, or nil
+// if none is queued.
+func OldestQueuedJob(ctx context.Context, q db.Querier) (*time.Time, error) {
+	var oldest *time.Time
+	err := q.QueryRow(ctx, `SELECT MIN(created_at) FROM jobs WHERE status='QUEUED'`).Scan(&oldest)
+	return utcPtr(oldest), err
+}
+
+// FailStaleJobs sets FAILED, with errorText and detail, the jobs RUNNING
+// for longer than staleAfter, and returns how many there were.
+func FailStaleJobs(ctx context.Context, q db.Querier, staleAfter time.Duration, errorText string, detail JobError) (int64, error) {
+	raw, err := json.Marshal(detail)
+	if err != nil {
+		return 0, err
+	}
+	tag, err := q.Exec(ctx,
+		`UPDATE jobs
+		 SET status='FAILED', finished_at=NOW(), error_text=$2,
+		     error_detail=$3::jsonb || jsonb_build_object('attempt', attempts)
+		 WHERE status='RUNNING' AND started_at < NOW() - make_interval(secs => $1)`,
+		staleAfter.Seconds(), errorText, string(raw),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// EnsureJobPartitions creates the monthly jobs partitions from the current
+// month through monthsAhead months ahead and returns how many it created
+// (see migration 004).
+func EnsureJobPartitions(ctx context.Context, q db.Querier, monthsAhead int) (int, error) {
+	var created int
+	err := q.QueryRow(ctx,
+		`SELECT ensure_job_partitions((NOW() AT TIME ZONE 'UTC')::date, $1)`,
+		monthsAhead,
+	).Scan(&created)
+	return created, err
+}
+
+// JobOutput is a row of job_outputs (see models.JobOutput).
+type JobOutput = models.JobOutput
+
+// InsertJobOutput inserts o; the object keys are ignored.
+func InsertJobOutput(ctx context.Context, q db.Querier, o JobOutput) error {
+	_, err := q.Exec(ctx,
+		`INSERT INTO job_outputs (id, job_id, variant, video_asset_id, thumbnail_asset_id, captions_asset_id)
+		 VALUES ($1,$2,$3,$4,$5,$6)`,
+		o.ID, o.JobID, o.Variant, o.VideoAssetID, nullIfEmpty(o.ThumbnailAssetID), nullIfEmpty(o.CaptionsAssetID),
+	)
+	if err != nil {
+		return fmt.Errorf("insert job output: %w", err)
+	}
+	return nil
+}
+
+// ListJobOutputs returns the outputs of a job by variant.
+func ListJobOutputs(ctx context.Context, q db.Querier, jobID string) ([]JobOutput, error) {
+	return listJobOutputs(ctx, q, `o.job_id=$1`, jobID)
+}
+
+// ListOutputsOfJobs returns the outputs of the jobs in jobIDs, by job and
+// variant.
+func ListOutputsOfJobs(ctx context.Context, q db.Querier, jobIDs []string) ([]JobOutput, error) {
+	return listJobOutputs(ctx, q, `o.job_id = ANY($1)`, jobIDs)
+}
+
+func listJobOutputs(ctx context.Context, q db.Querier, where string, arg any) ([]JobOutput, error) {
+	rows, err := q.Query(ctx,
+		`SELECT o.id, o.job_id, o.variant, o.video_asset_id,
+		        COALESCE(o.thumbnail_asset_id,''), COALESCE(o.captions_asset_id,''),
+		        COALESCE(v.object_key,''), COALESCE(t.object_key,''), COALESCE(c.object_key,'')
+		 FROM job_outputs o
+		 LEFT JOIN assets v ON v.id = o.video_asset_id
+		 LEFT JOIN assets t ON t.id = o.thumbnail_asset_id
+		 LEFT JOIN assets c ON c.id = o.captions_asset_id
+		 WHERE `+where+`
+		 ORDER BY o.job_id ASC, o.variant ASC`,
+		arg,
+	)
+	return collect(rows, err, func(row pgx.Row) (JobOutput, error) {
+		var o JobOutput
+		err := row.Scan(&o.ID, &o.JobID, &o.Variant, &o.VideoAssetID, &o.ThumbnailAssetID, &o.CaptionsAssetID,
+			&o.VideoObjectKey, &o.ThumbObjectKey, &o.CaptionsObjectKey)
+		return o, err
+	})
+}
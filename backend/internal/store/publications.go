@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"gala/internal/pkg/db"
+)
+
+// Publication statuses.
+const (
+	PublicationPending   = "PENDING"
+	PublicationUploading = "UPLOADING"
+	PublicationDone      = "DONE"
+	// PublicationFailed publications stay for the record; the output can
+	// be published again.
+	PublicationFailed = "FAILED"
+)
+
+// Publication is a row of job_publications. ExternalID, ExternalURL and
+// ErrorText are empty when NULL.
+type Publication struct {
+	ID       string
+	JobID    string
+	OutputID string
+	Target   string
+	Status   string
+	// Metadata is the JSON the target got (title, description, tags, ...).
+	Metadata    []byte
+	ExternalID  string
+	ExternalURL string
+	ErrorText   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	PublishedAt *time.Time
+}
+
+const publicationColumns = `id, job_id, output_id, target, status, metadata, external_id, external_url, error_text, created_at, updated_at, published_at`
+
+func scanPublication(row pgx.Row) (Publication, error) {
+	var (
+		p                    Publication
+		extID, extURL, errTx sql.NullString
+	)
+	err := row.Scan(&p.ID, &p.JobID, &p.OutputID, &p.Target, &p.Status, &p.Metadata,
+		&extID, &extURL, &errTx, &p.CreatedAt, &p.UpdatedAt, &p.PublishedAt)
+	p.ExternalID, p.ExternalURL, p.ErrorText = extID.String, extURL.String, errTx.String
+	p.CreatedAt, p.UpdatedAt = p.CreatedAt.UTC(), p.UpdatedAt.UTC()
+	p.PublishedAt = utcPtr(p.PublishedAt)
+	return p, err
+}
+
+// InsertPublication inserts p with status PENDING; updated_at starts as
+// created_at. A live publication of the same output and target makes it
+// fail with a unique violation.
+func InsertPublication(ctx context.Context, q db.Querier, p Publication) error {
+	_, err := q.Exec(ctx,
+		`INSERT INTO job_publications (id, job_id, output_id, target, status, metadata, created_at, updated_at)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$7)`,
+		p.ID, p.JobID, p.OutputID, p.Target, PublicationPending, p.Metadata, p.CreatedAt,
+	)
+	return err
+}
+
+// GetPublication returns the publication with id, or pgx.ErrNoRows.
+func GetPublication(ctx context.Context, q db.Querier, id string) (Publication, error) {
+	return scanPublication(q.QueryRow(ctx, `SELECT `+publicationColumns+` FROM job_publications WHERE id=$1`, id))
+}
+
+// ListJobPublications returns the publications of a job, newest first.
+func ListJobPublications(ctx context.Context, q db.Querier, jobID string) ([]Publication, error) {
+	rows, err := q.Query(ctx,
+		`SELECT `+publicationColumns+` FROM job_publications WHERE job_id=$1
+		 ORDER BY created_at DESC, id DESC`,
+		jobID,
+	)
+	return collect(rows, err, scanPublication)
+}
+
+// ListPendingPublications returns the PENDING publications, oldest first.
+func ListPendingPublications(ctx context.Context, q db.Querier) ([]Publication, error) {
+	rows, err := q.Query(ctx,
+		`SELECT `+publicationColumns+` FROM job_publications WHERE status='PENDING'
+		 ORDER BY created_at, id`,
+	)
+	return collect(rows, err, scanPublication)
+}
+
+// ClaimPublication moves a PENDING publication to UPLOADING and reports
+// whether it did; false means someone else claimed it first.
+func ClaimPublication(ctx context.Context, q db.Querier, id string) (bool, error) {
+	tag, err := q.Exec(ctx,
+		`UPDATE job_publications SET status='UPLOADING' WHERE id=$1 AND status='PENDING'`,
+		id,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MarkPublicationDone sets a publication DONE with the target's ID and URL
+// for the published video, and returns it.
+func MarkPublicationDone(ctx context.Context, q db.Querier, id, externalID, externalURL string) (Publication, error) {
+	return scanPublication(q.QueryRow(ctx,
+		`UPDATE job_publications
+		 SET status='DONE', external_id=$2, external_url=$3, error_text=NULL, published_at=NOW()
+		 WHERE id=$1
+		 RETURNING `+publicationColumns,
+		id, externalID, nullIfEmpty(externalURL),
+	))
+}
+
+// MarkPublicationFailed sets a publication FAILED with errorText.
+func MarkPublicationFailed(ctx context.Context, q db.Querier, id, errorText string) error {
+	_, err := q.Exec(ctx,
+		`UPDATE job_publications SET status='FAILED', error_text=$2 WHERE id=$1`,
+		id, errorText,
+	)
+	return err
+}
+
+// FailStalePublications sets FAILED, with errorText, the publications
+// UPLOADING for longer than staleAfter (their uploader is gone), and
+// returns how many there were.
+func FailStalePublications(ctx context.Context, q db.Querier, staleAfter time.Duration, errorText string) (int64, error) {
+	tag, err := q.Exec(ctx,
+		`UPDATE job_publications
+		 SET status='FAILED', error_text=$2
+		 WHERE status='UPLOADING' AND updated_at < NOW() - make_interval(secs => $1)`,
+		staleAfter.Seconds(), errorText,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// RequeuePublication moves an UPLOADING publication back to PENDING, for
+// uploads interrupted by a shutdown.
+func RequeuePublication(ctx context.Context, q db.Querier, id string) error {
+	_, err := q.Exec(ctx,
+		`UPDATE job_publications SET status='PENDING' WHERE id=$1 AND status='UPLOADING'`,
+		id,
+	)
+	return err
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"gala/internal/pkg/db"
+)
+
+// GetWorkspaceSettings returns the settings document of a workspace and
+// when it was saved, or pgx.ErrNoRows if it has none.
+func GetWorkspaceSettings(ctx context.Context, q db.Querier, workspace string) ([]byte, time.Time, error) {
+	var (
+		raw       []byte
+		updatedAt time.Time
+	)
+	err := q.QueryRow(ctx,
+		`SELECT settings, updated_at FROM workspace_settings WHERE workspace=$1`,
+		workspace,
+	).Scan(&raw, &updatedAt)
+	return raw, updatedAt.UTC(), err
+}
+
+// PutWorkspaceSettings replaces the settings document of a workspace and
+// returns when it was saved.
+func PutWorkspaceSettings(ctx context.Context, q db.Querier, workspace string, settings []byte) (time.Time, error) {
+	var updatedAt time.Time
+	err := q.QueryRow(ctx,
+		`INSERT INTO workspace_settings (workspace, settings, updated_at) VALUES ($1, $2::jsonb, NOW())
+		 ON CONFLICT (workspace) DO UPDATE SET settings=EXCLUDED.settings, updated_at=EXCLUDED.updated_at
+		 RETURNING updated_at`,
+		workspace, settings,
+	).Scan(&updatedAt)
+	return updatedAt.UTC(), err
+}
+
+// DeleteWorkspaceSettings deletes the settings of a workspace, if it has
+// any.
+func DeleteWorkspaceSettings(ctx context.Context, q db.Querier, workspace string) error {
+	_, err := q.Exec(ctx, `DELETE FROM workspace_settings WHERE workspace=$1`, workspace)
+	return err
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"gala/internal/pkg/db"
+)
+
+// Share is a row of job_shares with the output it points at. Variant,
+// VideoAssetID and ThumbnailAssetID come from job_outputs and are ignored
+// on insert; ThumbnailAssetID is empty when the output has none.
+type Share struct {
+	Token            string
+	JobID            string
+	OutputID         string
+	Variant          int
+	VideoAssetID     string
+	ThumbnailAssetID string
+	CreatedAt        time.Time
+	ExpiresAt        *time.Time
+	RevokedAt        *time.Time
+}
+
+// Live reports whether the share can still be watched at now.
+func (s Share) Live(now time.Time) bool {
+	return s.RevokedAt == nil && (s.ExpiresAt == nil || now.Before(*s.ExpiresAt))
+}
+
+const shareSelect = `SELECT s.token, s.job_id, s.output_id, o.variant, o.video_asset_id,
+	COALESCE(o.thumbnail_asset_id,''), s.created_at, s.expires_at, s.revoked_at
+	FROM job_shares s JOIN job_outputs o ON o.id = s.output_id`
+
+func scanShare(row pgx.Row) (Share, error) {
+	var s Share
+	err := row.Scan(&s.Token, &s.JobID, &s.OutputID, &s.Variant, &s.VideoAssetID,
+		&s.ThumbnailAssetID, &s.CreatedAt, &s.ExpiresAt, &s.RevokedAt)
+	s.CreatedAt = s.CreatedAt.UTC()
+	s.ExpiresAt, s.RevokedAt = utcPtr(s.ExpiresAt), utcPtr(s.RevokedAt)
+	return s, err
+}
+
+// InsertShare inserts s.
+func InsertShare(ctx context.Context, q db.Querier, s Share) error {
+	_, err := q.Exec(ctx,
+		`INSERT INTO job_shares (token, job_id, output_id, created_at, expires_at) VALUES ($1,$2,$3,$4,$5)`,
+		s.Token, s.JobID, s.OutputID, s.CreatedAt, s.ExpiresAt,
+	)
+	return err
+}
+
+// GetShare returns the share with token, live or not, or pgx.ErrNoRows.
+func GetShare(ctx context.Context, q db.Querier, token string) (Share, error) {
+	return scanShare(q.QueryRow(ctx, shareSelect+` WHERE s.token=$1`, token))
+}
+
+// ListJobShares returns the shares of a job, newest first.
+func ListJobShares(ctx context.Context, q db.Querier, jobID string) ([]Share, error) {
+	rows, err := q.Query(ctx,
+		shareSelect+` WHERE s.job_id=$1 ORDER BY s.created_at DESC, s.token`,
+		jobID,
+	)
+	return collect(rows, err, scanShare)
+}
+
+// RevokeShare marks the share with token revoked. It reports false when
+// there is no such share; revoking twice keeps the first revocation time.
+func RevokeShare(ctx context.Context, q db.Querier, token string) (bool, error) {
+	tag, err := q.Exec(ctx,
+		`UPDATE job_shares SET revoked_at = COALESCE(revoked_at, NOW()) WHERE token=$1`,
+		token,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,131 @@
+// Package store is the query layer over the gala schema: every statement
+// the API and the worker run against templates, jobs, job_outputs and
+// assets, as typed functions scanning into fixed row structs. A column
+// added or reordered is fixed here once, and callers get compile errors
+// instead of runtime scan mismatches.
+//
+// Every function takes a db.Querier, so it runs the same on the pool, a
+// read replica or inside db.WithTx. pgx prepares and caches each statement
+// per connection. Errors are returned as pgx reports them; callers classify
+// them with pgerr (IsNoRows, IsUniqueViolation, ...).
+package store
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Keyset is the position of the last item of a page ordered by
+// created_at DESC, id DESC; the next page starts after it.
+type Keyset struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// filter builds a WHERE clause with positional arguments.
+type filter struct {
+	conds []string
+	args  []any
+}
+
+// add appends a condition; each %s in cond becomes the placeholder of the
+// matching arg.
+func (f *filter) add(cond string, args ...any) {
+	ph := make([]any, len(args))
+	for i, a := range args {
+		ph[i] = f.arg(a)
+	}
+	f.conds = append(f.conds, fmt.Sprintf(cond, ph...))
+}
+
+// arg appends a value and returns its placeholder.
+func (f *filter) arg(v any) string {
+	f.args = append(f.args, v)
+	return fmt.Sprintf("$%d", len(f.args))
+}
+
+func (f *filter) where() string {
+	if len(f.conds) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(f.conds, " AND ")
+}
+
+// afterKeyset and limit add the usual keyset pagination clauses.
+func (f *filter) afterKeyset(k *Keyset) {
+	if k != nil {
+		f.add("(created_at, id) < (%s, %s)", k.CreatedAt, k.ID)
+	}
+}
+
+func (f *filter) limit(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return " LIMIT " + f.arg(n)
+}
+
+// collect scans every row with scan and closes rows.
+func collect[T any](rows pgx.Rows, err error, scan func(pgx.Row) (T, error)) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []T
+	for rows.Next() {
+		it, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+// seq adapts rows to an iterator, so results can be encoded as they are
+// read instead of collected first. rows is closed when iteration ends.
+func seq[T any](rows pgx.Rows, scan func(pgx.Row) (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer rows.Close()
+		for rows.Next() {
+			item, err := scan(rows)
+			if !yield(item, err) || err != nil {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// utcPtr returns t in UTC; pgx scans timestamptz in the local zone.
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// nullIfEmpty stores blank strings as NULL.
+func nullIfEmpty(s string) any {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return s
+}
+
+// nullIfZero stores 0 as NULL.
+func nullIfZero(n int) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
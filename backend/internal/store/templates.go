@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"gala/internal/pkg/db"
+)
+
+// Template is a row of templates. Format, ParamsSchema, Defaults and
+// Watermark hold the raw JSONB, nil when NULL.
+type Template struct {
+	ID           string
+	Type         string
+	Name         string
+	DurationMs   *int
+	Format       []byte
+	ParamsSchema []byte
+	Defaults     []byte
+	Watermark    []byte
+	// StrictParams rejects job params that do not match ParamsSchema.
+	StrictParams bool
+	// MaxConcurrency caps how many jobs of the template render at once;
+	// nil has no cap.
+	MaxConcurrency *int
+	// Assets are the template's asset slots (assetslots.Slots as JSON).
+	Assets    []byte
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+const templateColumns = `id, type, name, duration_ms, format, params_schema, defaults, watermark, strict_params, max_concurrency, assets, created_at, updated_at`
+
+func scanTemplate(row pgx.Row) (Template, error) {
+	var t Template
+	err := row.Scan(&t.ID, &t.Type, &t.Name, &t.DurationMs, &t.Format, &t.ParamsSchema, &t.Defaults, &t.Watermark, &t.StrictParams, &t.MaxConcurrency, &t.Assets, &t.CreatedAt, &t.UpdatedAt)
+	t.CreatedAt, t.UpdatedAt = t.CreatedAt.UTC(), t.UpdatedAt.UTC()
+	return t, err
+}
+
+// InsertTemplate inserts t; updated_at starts as created_at.
+func InsertTemplate(ctx context.Context, q db.Querier, t Template) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO templates (id, type, name, duration_ms, format, params_schema, defaults, watermark, strict_params, max_concurrency, assets, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5::jsonb,$6::jsonb,$7::jsonb,$8::jsonb,$9,$10,$11::jsonb,$12,$12)
+	`, t.ID, t.Type, t.Name, t.DurationMs, t.Format, t.ParamsSchema, t.Defaults, t.Watermark, t.StrictParams, t.MaxConcurrency, t.Assets, t.CreatedAt)
+	return err
+}
+
+// GetTemplate returns the template with id unless it was deleted, or
+// pgx.ErrNoRows.
+func GetTemplate(ctx context.Context, q db.Querier, id string) (Template, error) {
+	return scanTemplate(q.QueryRow(ctx, `
+		SELECT `+templateColumns+`
+		FROM templates
+		WHERE id=$1 AND deleted_at IS NULL
+	`, id))
+}
+
+// GetTemplateByName returns the live template named name, or
+// pgx.ErrNoRows.
+func GetTemplateByName(ctx context.Context, q db.Querier, name string) (Template, error) {
+	return scanTemplate(q.QueryRow(ctx, `
+		SELECT `+templateColumns+`
+		FROM templates
+		WHERE name=$1 AND deleted_at IS NULL
+	`, name))
+}
+
+// GetTemplates returns the templates whose id is in ids, skipping missing
+// and deleted ones, in no particular order.
+func GetTemplates(ctx context.Context, q db.Querier, ids []string) ([]Template, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+templateColumns+`
+		FROM templates
+		WHERE id = ANY($1) AND deleted_at IS NULL
+	`, ids)
+	return collect(rows, err, scanTemplate)
+}
+
+// LockTemplate is GetTemplate with the row locked FOR UPDATE until q's
+// transaction ends.
+func LockTemplate(ctx context.Context, q db.Querier, id string) (Template, error) {
+	return scanTemplate(q.QueryRow(ctx, `
+		SELECT `+templateColumns+`
+		FROM templates
+		WHERE id=$1 AND deleted_at IS NULL
+		FOR UPDATE
+	`, id))
+}
+
+// UpdateTemplate writes every editable column of t.
+func UpdateTemplate(ctx context.Context, q db.Querier, t Template) error {
+	_, err := q.Exec(ctx, `
+		UPDATE templates
+		SET type=$2, name=$3, duration_ms=$4, format=$5::jsonb, params_schema=$6::jsonb, defaults=$7::jsonb,
+		    watermark=$8::jsonb, strict_params=$9, max_concurrency=$10, assets=$11::jsonb
+		WHERE id=$1
+	`, t.ID, t.Type, t.Name, t.DurationMs, t.Format, t.ParamsSchema, t.Defaults, t.Watermark, t.StrictParams, t.MaxConcurrency, t.Assets)
+	return err
+}
+
+// SoftDeleteTemplate marks a template deleted and reports whether it
+// existed (and was not deleted already).
+func SoftDeleteTemplate(ctx context.Context, q db.Querier, id string) (bool, error) {
+	tag, err := q.Exec(ctx, `
+		UPDATE templates
+		SET deleted_at=NOW()
+		WHERE id=$1 AND deleted_at IS NULL
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListTemplates returns up to limit templates that were not deleted,
+// after the keyset, newest first.
+func ListTemplates(ctx context.Context, q db.Querier, after *Keyset, limit int) ([]Template, error) {
+	w := filter{conds: []string{"deleted_at IS NULL"}}
+	w.afterKeyset(after)
+	lim := w.limit(limit)
+	rows, err := q.Query(ctx, `
+		SELECT `+templateColumns+`
+		FROM templates
+		WHERE `+w.where()+`
+		ORDER BY created_at DESC, id DESC`+lim,
+		w.args...,
+	)
+	return collect(rows, err, scanTemplate)
+}
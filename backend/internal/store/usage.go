@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"gala/internal/pkg/db"
+)
+
+// UsageDay is the usage of one UTC day. Renders count the jobs that
+// finished DONE that day and RenderSeconds the run time (started_at to
+// finished_at) of the DONE and FAILED ones; Publications count the
+// publications completed that day.
+type UsageDay struct {
+	Date              time.Time
+	Renders           int64
+	FailedRenders     int64
+	RenderSeconds     float64
+	StorageBytesAdded int64
+	Publications      int64
+}
+
+// UsageByDay returns the usage of every UTC day in [from, to), days
+// without activity included. from and to must be UTC midnights.
+func UsageByDay(ctx context.Context, q db.Querier, from, to time.Time) ([]UsageDay, error) {
+	rows, err := q.Query(ctx,
+		`WITH days AS (
+		   SELECT d::date AS day
+		   FROM generate_series(($1::timestamptz AT TIME ZONE 'UTC')::date,
+		                        ($2::timestamptz AT TIME ZONE 'UTC')::date - 1, interval '1 day') d
+		 ), renders AS (
+		   SELECT (finished_at AT TIME ZONE 'UTC')::date AS day,
+		          COUNT(1) FILTER (WHERE status='DONE') AS done,
+		          COUNT(1) FILTER (WHERE status='FAILED') AS failed,
+		          COALESCE(SUM(EXTRACT(EPOCH FROM finished_at - started_at)) FILTER (WHERE started_at IS NOT NULL), 0) AS secs
+		   FROM jobs
+		   WHERE status IN ('DONE','FAILED') AND finished_at >= $1 AND finished_at < $2
+		   GROUP BY 1
+		 ), storage AS (
+		   SELECT (created_at AT TIME ZONE 'UTC')::date AS day, SUM(size_bytes) AS bytes
+		   FROM assets
+		   WHERE created_at >= $1 AND created_at < $2
+		   GROUP BY 1
+		 ), pubs AS (
+		   SELECT (published_at AT TIME ZONE 'UTC')::date AS day, COUNT(1) AS n
+		   FROM job_publications
+		   WHERE status='DONE' AND published_at >= $1 AND published_at < $2
+		   GROUP BY 1
+		 )
+		 SELECT days.day, COALESCE(r.done,0), COALESCE(r.failed,0), COALESCE(r.secs,0)::float8,
+		        COALESCE(s.bytes,0)::bigint, COALESCE(p.n,0)
+		 FROM days
+		 LEFT JOIN renders r ON r.day = days.day
+		 LEFT JOIN storage s ON s.day = days.day
+		 LEFT JOIN pubs p ON p.day = days.day
+		 ORDER BY days.day`,
+		from, to,
+	)
+	return collect(rows, err, func(row pgx.Row) (UsageDay, error) {
+		var d UsageDay
+		err := row.Scan(&d.Date, &d.Renders, &d.FailedRenders, &d.RenderSeconds, &d.StorageBytesAdded, &d.Publications)
+		d.Date = time.Date(d.Date.Year(), d.Date.Month(), d.Date.Day(), 0, 0, 0, 0, time.UTC)
+		return d, err
+	})
+}
+
+// StoredBytes returns the size of the assets created before t that still
+// exist. Deleted assets leave no trace, so for a past t it is a lower
+// bound.
+func StoredBytes(ctx context.Context, q db.Querier, t time.Time) (int64, error) {
+	var n int64
+	err := q.QueryRow(ctx,
+		`SELECT COALESCE(SUM(size_bytes),0)::bigint FROM assets WHERE created_at < $1`,
+		t,
+	).Scan(&n)
+	return n, err
+}
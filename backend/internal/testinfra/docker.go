@@ -0,0 +1,62 @@
+package testinfra
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// container is a throwaway container started with the docker CLI.
+type container struct {
+	id string
+	// addr is the host:port the container port is published on.
+	addr string
+}
+
+// runContainer starts image detached with port published on a random
+// host port. The container is removed when it stops.
+func runContainer(ctx context.Context, image, port string, env ...string) (*container, error) {
+	args := []string{"run", "-d", "--rm", "-p", "127.0.0.1::" + port}
+	for _, e := range env {
+		args = append(args, "-e", e)
+	}
+	out, err := docker(ctx, append(args, image)...)
+	if err != nil {
+		return nil, err
+	}
+	c := &container{id: out}
+
+	out, err = docker(ctx, "port", c.id, port+"/tcp")
+	if err != nil {
+		c.stop()
+		return nil, err
+	}
+	// One line per published address; they all share the host port.
+	_, hostPort, err := net.SplitHostPort(strings.SplitN(out, "\n", 2)[0])
+	if err != nil {
+		c.stop()
+		return nil, fmt.Errorf("docker port %s: %q: %w", image, out, err)
+	}
+	c.addr = net.JoinHostPort("127.0.0.1", hostPort)
+	return c, nil
+}
+
+// stop removes the container; errors are ignored, the container is gone
+// or docker is.
+func (c *container) stop() {
+	_, _ = docker(context.Background(), "rm", "-f", c.id)
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,349 @@
+// Package testinfra runs integration tests against real Postgres and
+// Redis. It starts both in throwaway containers (the images of
+// infra/docker-compose.yml) through the docker CLI, migrates a template
+// database once per test binary and gives each test a fresh copy of it,
+// with ready-made Deps for the handlers, the processor and the worker.
+//
+// TEST_DATABASE_URL and TEST_REDIS_ADDR point it at running servers
+// instead of containers: the role must be allowed to create databases,
+// and every test flushes the Redis database, so run the packages that use
+// it one at a time (go test -p 1). Tests skip when neither docker nor
+// those variables are available, unless GALA_REQUIRE_INTEGRATION=1: CI
+// sets it so a runner without them fails instead of passing with every
+// integration test skipped.
+//
+// testinfra imports handlers and processor, so use it from external test
+// packages (package foo_test), and stop the containers from TestMain:
+//
+//	func TestMain(m *testing.M) { testinfra.Main(m) }
+//
+//	func TestCreateJob(t *testing.T) {
+//		env := testinfra.New(t)
+//		h := handlers.New(env.HandlerDeps())
+//		// ...
+//	}
+package testinfra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+
+	"gala/internal/adapters/storage/localfs"
+	"gala/internal/events"
+	"gala/internal/httpapi/handlers"
+	"gala/internal/mockrenderer"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/migrate"
+	"gala/internal/ports"
+	"gala/internal/worker"
+	"gala/internal/worker/processor"
+	"gala/internal/worker/queue"
+	"gala/internal/worker/renderer"
+	"gala/migrations"
+)
+
+// Images started when TEST_DATABASE_URL or TEST_REDIS_ADDR is not set.
+const (
+	PostgresImage = "postgres:15"
+	RedisImage    = "redis:7"
+)
+
+// startTimeout bounds starting the containers and migrating the template.
+const startTimeout = 2 * time.Minute
+
+// RequireEnv set to 1 makes New fail instead of skip when there are no
+// servers to run against.
+const RequireEnv = "GALA_REQUIRE_INTEGRATION"
+
+// errUnavailable makes New skip instead of fail.
+var errUnavailable = errors.New("testinfra: no postgres/redis available")
+
+var (
+	once     sync.Once
+	shared   *infra
+	startErr error
+	// seq numbers the databases of a test binary.
+	seq atomic.Int64
+)
+
+// Env is what a test gets from New. Everything in it is cleaned up when
+// the test ends.
+type Env struct {
+	// Pool is connected to a database of its own, migrated to the latest
+	// version.
+	Pool *pgxpool.Pool
+	// RDB is flushed before the test.
+	RDB redis.UniversalClient
+	// StorageRoot is a temporary directory backing SP, where the mock
+	// renderer writes its outputs.
+	StorageRoot string
+	SP          ports.StorageProvider
+	Events      *events.Bus
+	// RendererURL is a mock renderer (see package mockrenderer).
+	RendererURL string
+	Log         *logger.Logger
+}
+
+// New returns a fresh Env for t, starting the shared servers on first
+// use. It skips t when there is no docker and no TEST_DATABASE_URL and
+// TEST_REDIS_ADDR to use instead, and fails it then if RequireEnv is 1.
+func New(t testing.TB) *Env {
+	t.Helper()
+	once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+		defer cancel()
+		shared, startErr = start(ctx)
+	})
+	if errors.Is(startErr, errUnavailable) && os.Getenv(RequireEnv) != "1" {
+		t.Skip(startErr)
+	}
+	if startErr != nil {
+		t.Fatal(startErr)
+	}
+	ctx := context.Background()
+
+	name := fmt.Sprintf("gala_test_%d_%d", os.Getpid(), seq.Add(1))
+	if err := shared.exec(ctx, "CREATE DATABASE "+name+" TEMPLATE "+shared.template); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := shared.exec(ctx, "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)"); err != nil {
+			t.Log(err)
+		}
+	})
+	pool, err := shared.pool(ctx, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	rdb := redis.NewClient(&redis.Options{Addr: shared.redisAddr})
+	t.Cleanup(func() { _ = rdb.Close() })
+	if err := rdb.FlushDB(ctx).Err(); err != nil {
+		t.Fatalf("testinfra: flush redis: %v", err)
+	}
+
+	log := newLogger()
+	root := t.TempDir()
+	srv := httptest.NewServer(mockrenderer.New(mockrenderer.Options{StorageRoot: root, Log: log}))
+	t.Cleanup(srv.Close)
+
+	return &Env{
+		Pool:        pool,
+		RDB:         rdb,
+		StorageRoot: root,
+		SP:          localfs.New(root),
+		Events:      events.New(rdb, log, 0),
+		RendererURL: srv.URL,
+		Log:         log,
+	}
+}
+
+// HandlerDeps returns the Deps of handlers.New wired to e.
+func (e *Env) HandlerDeps() handlers.Deps {
+	return handlers.Deps{
+		Pool:   e.Pool,
+		RDB:    e.RDB,
+		SP:     e.SP,
+		Log:    e.Log,
+		Events: e.Events,
+	}
+}
+
+// ProcessorDeps returns the Deps of processor.New wired to e and its mock
+// renderer.
+func (e *Env) ProcessorDeps() processor.Deps {
+	return processor.Deps{
+		Pool:        e.Pool,
+		Renderer:    renderer.NewHTTPClient(e.RendererURL),
+		StorageRoot: e.StorageRoot,
+		SP:          e.SP,
+		Events:      e.Events,
+		Log:         e.Log,
+	}
+}
+
+// WorkerDeps returns the Deps of worker.Run wired to e and its mock
+// renderer, popping the queue the handlers push to.
+func (e *Env) WorkerDeps() worker.Deps {
+	return worker.Deps{
+		Pool:            e.Pool,
+		RDB:             e.RDB,
+		RendererBaseURL: e.RendererURL,
+		StorageRoot:     e.StorageRoot,
+		QueueName:       queue.DefaultName,
+		QueuePopTimeout: time.Second,
+		Events:          e.Events,
+		SP:              e.SP,
+		Log:             e.Log,
+	}
+}
+
+// Main runs the tests of a package and then stops the containers New
+// started. Call it from TestMain.
+func Main(m *testing.M) {
+	code := m.Run()
+	if shared != nil {
+		shared.close()
+	}
+	os.Exit(code)
+}
+
+// infra is what the tests of a binary share: the servers and the migrated
+// template database.
+type infra struct {
+	// dsn connects to the maintenance database, where databases are
+	// created and dropped.
+	dsn        string
+	redisAddr  string
+	template   string
+	containers []*container
+	// ownsPostgres is set when dsn is a container of ours.
+	ownsPostgres bool
+}
+
+func start(ctx context.Context) (*infra, error) {
+	in := &infra{
+		dsn:       os.Getenv("TEST_DATABASE_URL"),
+		redisAddr: os.Getenv("TEST_REDIS_ADDR"),
+		template:  fmt.Sprintf("gala_template_%d", os.Getpid()),
+	}
+	if in.dsn == "" || in.redisAddr == "" {
+		if _, err := exec.LookPath("docker"); err != nil {
+			return nil, fmt.Errorf("%w: docker: %v", errUnavailable, err)
+		}
+	}
+	if in.dsn == "" {
+		c, err := runContainer(ctx, PostgresImage, "5432",
+			"POSTGRES_USER=gala", "POSTGRES_PASSWORD=gala", "POSTGRES_DB=gala")
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errUnavailable, err)
+		}
+		in.containers = append(in.containers, c)
+		in.ownsPostgres = true
+		in.dsn = "postgres://gala:gala@" + c.addr + "/gala?sslmode=disable"
+	}
+	if in.redisAddr == "" {
+		c, err := runContainer(ctx, RedisImage, "6379")
+		if err != nil {
+			in.close()
+			return nil, fmt.Errorf("%w: %v", errUnavailable, err)
+		}
+		in.containers = append(in.containers, c)
+		in.redisAddr = c.addr
+	}
+
+	if err := in.prepare(ctx); err != nil {
+		in.close()
+		return nil, err
+	}
+	return in, nil
+}
+
+// prepare waits for the servers and migrates the template database.
+func (in *infra) prepare(ctx context.Context) error {
+	rdb := redis.NewClient(&redis.Options{Addr: in.redisAddr})
+	defer rdb.Close()
+	if err := retry(ctx, func() error { return rdb.Ping(ctx).Err() }); err != nil {
+		return fmt.Errorf("testinfra: redis: %w", err)
+	}
+	// The postgres image only listens on TCP once it is initialized.
+	if err := retry(ctx, func() error {
+		return in.exec(ctx, "CREATE DATABASE "+in.template)
+	}); err != nil {
+		return err
+	}
+
+	pool, err := in.pool(ctx, in.template)
+	if err != nil {
+		return err
+	}
+	// CREATE DATABASE ... TEMPLATE needs the template without
+	// connections.
+	defer pool.Close()
+	m, err := migrate.New(pool, migrations.FS, newLogger())
+	if err != nil {
+		return fmt.Errorf("testinfra: %w", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		return fmt.Errorf("testinfra: migrate template: %w", err)
+	}
+	return nil
+}
+
+// exec runs stmt on the maintenance database.
+func (in *infra) exec(ctx context.Context, stmt string) error {
+	conn, err := pgx.Connect(ctx, in.dsn)
+	if err != nil {
+		return fmt.Errorf("testinfra: connect: %w", err)
+	}
+	defer conn.Close(ctx)
+	if _, err := conn.Exec(ctx, stmt); err != nil {
+		return fmt.Errorf("testinfra: %s: %w", stmt, err)
+	}
+	return nil
+}
+
+// pool connects to the database name of the server.
+func (in *infra) pool(ctx context.Context, name string) (*pgxpool.Pool, error) {
+	cfg, err := pgxpool.ParseConfig(in.dsn)
+	if err != nil {
+		return nil, fmt.Errorf("testinfra: parse TEST_DATABASE_URL: %w", err)
+	}
+	cfg.ConnConfig.Database = name
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("testinfra: connect %s: %w", name, err)
+	}
+	return pool, nil
+}
+
+// close drops the template database and stops the containers.
+func (in *infra) close() {
+	if !in.ownsPostgres {
+		// The server outlives the tests: leave it clean.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = in.exec(ctx, "DROP DATABASE IF EXISTS "+in.template+" WITH (FORCE)")
+	}
+	for _, c := range in.containers {
+		c.stop()
+	}
+}
+
+// newLogger logs at LOG_LEVEL, error by default, so that passing tests
+// stay quiet.
+func newLogger() *logger.Logger {
+	level := os.Getenv("LOG_LEVEL")
+	if level == "" {
+		level = "error"
+	}
+	return logger.New(logger.Config{Level: level, Format: "text", Output: os.Stderr})
+}
+
+// retry calls fn every half second until it succeeds or ctx ends.
+func retry(ctx context.Context, fn func() error) error {
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package testinfra
+
+import (
+	"context"
+	"testing"
+
+	"gala/internal/pkg/migrate"
+	"gala/migrations"
+)
+
+func TestMain(m *testing.M) { Main(m) }
+
+func TestNewMigratesEachDatabase(t *testing.T) {
+	ctx := context.Background()
+	a, b := New(t), New(t)
+
+	m, err := migrate.New(a.Pool, migrations.FS, a.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			t.Errorf("migration %d_%s not applied", s.Version, s.Name)
+		}
+	}
+
+	// The databases are copies, not the same one.
+	if _, err := a.Pool.Exec(ctx, `CREATE TABLE testinfra_probe (id INT)`); err != nil {
+		t.Fatal(err)
+	}
+	var exists bool
+	if err := b.Pool.QueryRow(ctx, `SELECT to_regclass('testinfra_probe') IS NOT NULL`).Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Error("a table created in one Env is visible in another")
+	}
+}
+
+func TestNewFlushesRedis(t *testing.T) {
+	ctx := context.Background()
+	if err := New(t).RDB.Set(ctx, "testinfra:probe", "1", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	n, err := New(t).RDB.Exists(ctx, "testinfra:probe").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Error("a key set in one Env survives into the next")
+	}
+}
@@ -0,0 +1,97 @@
+package testsupport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"gala/internal/mockrenderer"
+	"gala/internal/pkg/logger"
+	"gala/internal/worker/renderer"
+)
+
+// Renderer is a renderer.Client that runs a mockrenderer.Server in
+// process: each render writes the placeholder outputs of its spec under
+// Options.StorageRoot (the processor's StorageRoot) and fails as the
+// Options say, without a listener. Non-2xx answers are returned as
+// *renderer.StatusError, like the HTTP client does.
+type Renderer struct {
+	// Fail, if set, is called with the job ID of every render before it
+	// runs; the error it returns fails the render.
+	Fail func(jobID string) error
+
+	srv *mockrenderer.Server
+
+	mu   sync.Mutex
+	jobs []string
+}
+
+var _ renderer.Client = (*Renderer)(nil)
+
+// NewRenderer creates a Renderer with opt. A nil opt.Log discards the
+// mock's logs.
+func NewRenderer(opt mockrenderer.Options) *Renderer {
+	if opt.Log == nil {
+		opt.Log = logger.New(logger.Config{Level: "error", Output: io.Discard})
+	}
+	return &Renderer{srv: mockrenderer.New(opt)}
+}
+
+func (r *Renderer) Render(ctx context.Context, spec any) error {
+	return r.render(ctx, "/render", spec)
+}
+
+func (r *Renderer) RenderV1(ctx context.Context, spec any) error {
+	return r.render(ctx, "/render/v1", spec)
+}
+
+// Jobs returns the job IDs of the renders requested so far, failed ones
+// included, in order.
+func (r *Renderer) Jobs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.jobs...)
+}
+
+func (r *Renderer) render(ctx context.Context, path string, spec any) error {
+	body, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	var job struct {
+		JobID string `json:"job_id"`
+	}
+	_ = json.Unmarshal(body, &job)
+	r.mu.Lock()
+	r.jobs = append(r.jobs, job.JobID)
+	r.mu.Unlock()
+
+	if r.Fail != nil {
+		if err := r.Fail(job.JobID); err != nil {
+			return err
+		}
+	}
+
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set(renderer.HeaderDeadline, deadline.UTC().Format(time.RFC3339))
+	}
+	rec := httptest.NewRecorder()
+	r.srv.ServeHTTP(rec, req)
+
+	// The mock stops answering when the request is canceled.
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("renderer request abandoned: %w", err)
+	}
+	if rec.Code < 200 || rec.Code >= 300 {
+		return &renderer.StatusError{Code: rec.Code}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// Package testsupport holds test doubles of the worker's dependencies: an
+// in-memory storage provider and a renderer client that writes placeholder
+// outputs, both with hooks to fail calls. Tests of this module and of
+// services built on it share them instead of writing their own stubs.
+package testsupport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gala/internal/ports"
+)
+
+// Storage operations, as passed to Storage.Fail.
+const (
+	OpPut       = "put"
+	OpGet       = "get"
+	OpDelete    = "delete"
+	OpSignedURL = "signed_url"
+	OpList      = "list"
+)
+
+// Storage is an in-memory ports.StorageProvider that can list its objects
+// (ports.ObjectLister). The zero value is ready to use. Missing objects
+// fail with an error wrapping fs.ErrNotExist, as in localfs, so
+// storage.IsNotFound recognizes them.
+type Storage struct {
+	// Name is what Provider returns; empty is "memory". Code that treats
+	// localfs or gdrive differently can be tested with either name.
+	Name string
+	// Fail, if set, is called before every operation with its Op* name
+	// and object key (the prefix for OpList); the error it returns fails
+	// the call. See FailFirst.
+	Fail func(op, key string) error
+	// KeyFunc maps the key of a put to the ObjectKey it is stored and
+	// returned under, as gdrive returns file IDs; nil keeps the key.
+	KeyFunc func(key string) string
+
+	mu      sync.Mutex
+	objects map[string]object
+}
+
+type object struct {
+	name        string
+	contentType string
+	data        []byte
+	modTime     time.Time
+}
+
+var _ ports.ObjectLister = (*Storage)(nil)
+
+// NewStorage returns an empty Storage.
+func NewStorage() *Storage {
+	return &Storage{}
+}
+
+func (s *Storage) Provider() string {
+	if s.Name == "" {
+		return "memory"
+	}
+	return s.Name
+}
+
+func (s *Storage) PutObject(_ context.Context, in ports.PutObjectInput) (ports.PutObjectOutput, error) {
+	if err := s.fail(OpPut, in.ObjectKey); err != nil {
+		return ports.PutObjectOutput{}, err
+	}
+	data, err := io.ReadAll(in.Reader)
+	if err != nil {
+		return ports.PutObjectOutput{}, err
+	}
+	key := in.ObjectKey
+	if s.KeyFunc != nil {
+		key = s.KeyFunc(key)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.objects == nil {
+		s.objects = map[string]object{}
+	}
+	s.objects[key] = object{name: in.ObjectKey, contentType: in.ContentType, data: data, modTime: time.Now()}
+	return ports.PutObjectOutput{ObjectKey: key, Size: int64(len(data))}, nil
+}
+
+func (s *Storage) GetObject(_ context.Context, objectKey string) (io.ReadCloser, string, int64, error) {
+	if err := s.fail(OpGet, objectKey); err != nil {
+		return nil, "", 0, err
+	}
+	o, ok := s.object(objectKey)
+	if !ok {
+		return nil, "", 0, notFound(objectKey)
+	}
+	contentType := o.contentType
+	if contentType == "" {
+		contentType = http.DetectContentType(o.data)
+	}
+	return io.NopCloser(bytes.NewReader(o.data)), contentType, int64(len(o.data)), nil
+}
+
+func (s *Storage) DeleteObject(_ context.Context, objectKey string) error {
+	if err := s.fail(OpDelete, objectKey); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[objectKey]; !ok {
+		return notFound(objectKey)
+	}
+	delete(s.objects, objectKey)
+	return nil
+}
+
+func (s *Storage) GetSignedURL(_ context.Context, objectKey string, expiresIn time.Duration) (ports.SignedURLOutput, error) {
+	if err := s.fail(OpSignedURL, objectKey); err != nil {
+		return ports.SignedURLOutput{}, err
+	}
+	return ports.SignedURLOutput{
+		URL:       "memory://" + objectKey,
+		ExpiresAt: time.Now().UTC().Add(expiresIn),
+	}, nil
+}
+
+// ListObjects calls fn for the objects whose name starts with prefix, in
+// name order.
+func (s *Storage) ListObjects(_ context.Context, prefix string, fn func(ports.ObjectInfo) error) error {
+	if err := s.fail(OpList, prefix); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	var infos []ports.ObjectInfo
+	for key, o := range s.objects {
+		if strings.HasPrefix(o.name, prefix) {
+			infos = append(infos, ports.ObjectInfo{ObjectKey: key, Name: o.name, Size: int64(len(o.data)), ModTime: o.modTime})
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	for _, info := range infos {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Object returns the content stored under objectKey.
+func (s *Storage) Object(objectKey string) ([]byte, bool) {
+	o, ok := s.object(objectKey)
+	return o.data, ok
+}
+
+// Keys returns the keys of the stored objects, sorted.
+func (s *Storage) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.objects))
+	for k := range s.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Storage) object(key string) (object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.objects[key]
+	return o, ok
+}
+
+func (s *Storage) fail(op, key string) error {
+	if s.Fail == nil {
+		return nil
+	}
+	return s.Fail(op, key)
+}
+
+func notFound(key string) error {
+	return fmt.Errorf("object %s: %w", key, fs.ErrNotExist)
+}
+
+// ErrInjected is the error of the calls failed by FailFirst.
+var ErrInjected = errors.New("testsupport: injected failure")
+
+// FailFirst returns a Storage.Fail hook that fails the first n calls of op
+// on key with ErrInjected, and all of them if n < 0. An empty op or key
+// matches any.
+func FailFirst(n int, op, key string) func(op, key string) error {
+	var mu sync.Mutex
+	return func(gotOp, gotKey string) error {
+		if (op != "" && gotOp != op) || (key != "" && gotKey != key) {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		if n > 0 {
+			n--
+		}
+		return fmt.Errorf("%s %s: %w", gotOp, gotKey, ErrInjected)
+	}
+}
@@ -0,0 +1,124 @@
+package testsupport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gala/internal/mockrenderer"
+	"gala/internal/ports"
+	"gala/internal/storage"
+)
+
+func put(t *testing.T, s *Storage, key, data string) string {
+	t.Helper()
+	out, err := s.PutObject(context.Background(), ports.PutObjectInput{ObjectKey: key, Reader: strings.NewReader(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.ObjectKey
+}
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+	s := NewStorage()
+	key := put(t, s, "renders/job_1/hello.mp4", "video")
+
+	rc, _, size, err := s.GetObject(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(rc)
+	if string(data) != "video" || size != 5 {
+		t.Errorf("GetObject = %q (%d bytes), want video", data, size)
+	}
+
+	put(t, s, "renders/job_2/hello.mp4", "other")
+	var names []string
+	err = s.ListObjects(ctx, "renders/job_1/", func(o ports.ObjectInfo) error {
+		names = append(names, o.Name)
+		return nil
+	})
+	if err != nil || len(names) != 1 || names[0] != key {
+		t.Errorf("ListObjects = %v, %v; want [%s]", names, err, key)
+	}
+
+	if err := s.DeleteObject(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := s.GetObject(ctx, key); !storage.IsNotFound(err) {
+		t.Errorf("GetObject after delete = %v, want not found", err)
+	}
+	if err := storage.Ping(ctx, s); err != nil {
+		t.Errorf("Ping = %v", err)
+	}
+}
+
+func TestStorageKeyFunc(t *testing.T) {
+	s := &Storage{KeyFunc: func(key string) string { return "file_" + filepath.Base(key) }}
+	if got := put(t, s, "renders/job_1/hello.mp4", "video"); got != "file_hello.mp4" {
+		t.Errorf("ObjectKey = %q, want file_hello.mp4", got)
+	}
+	if _, ok := s.Object("file_hello.mp4"); !ok {
+		t.Error("object not stored under its returned key")
+	}
+}
+
+func TestFailFirst(t *testing.T) {
+	ctx := context.Background()
+	s := &Storage{Fail: FailFirst(2, OpPut, "a")}
+	in := func(key string) ports.PutObjectInput {
+		return ports.PutObjectInput{ObjectKey: key, Reader: strings.NewReader("x")}
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.PutObject(ctx, in("a")); !errors.Is(err, ErrInjected) {
+			t.Fatalf("put %d = %v, want ErrInjected", i, err)
+		}
+	}
+	if _, err := s.PutObject(ctx, in("a")); err != nil {
+		t.Errorf("third put = %v, want success", err)
+	}
+	if _, err := s.PutObject(ctx, in("b")); err != nil {
+		t.Errorf("put of another key = %v, want success", err)
+	}
+	if _, _, _, err := s.GetObject(ctx, "a"); err != nil {
+		t.Errorf("get = %v, want success", err)
+	}
+}
+
+func TestRenderer(t *testing.T) {
+	root := t.TempDir()
+	r := NewRenderer(mockrenderer.Options{StorageRoot: root})
+	spec := map[string]any{
+		"job_id": "job_1",
+		"output": map[string]any{
+			"video_object_key": "renders/job_1/hello.mp4",
+			"thumb_object_key": "renders/job_1/hello.jpg",
+		},
+	}
+	if err := r.Render(context.Background(), spec); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "renders/job_1/hello.mp4")); err != nil {
+		t.Errorf("video not written: %v", err)
+	}
+
+	injected := errors.New("down")
+	r.Fail = func(string) error { return injected }
+	if err := r.RenderV1(context.Background(), spec); !errors.Is(err, injected) {
+		t.Errorf("RenderV1 with Fail = %v, want %v", err, injected)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	slow := NewRenderer(mockrenderer.Options{StorageRoot: root, Delay: time.Minute})
+	if err := slow.Render(ctx, spec); !errors.Is(err, context.Canceled) {
+		t.Errorf("Render canceled = %v, want context.Canceled", err)
+	}
+}
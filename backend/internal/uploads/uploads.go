@@ -0,0 +1,212 @@
+// Package uploads tracks the progress of asset uploads, so clients can show
+// how far a large upload got. A client creates an upload session, sends the
+// file to POST /assets with its ID, and polls GET /assets/uploads/{id}
+// meanwhile. Sessions live in Redis, shared by the API replicas, and expire
+// after a day.
+package uploads
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"gala/internal/httpapi/util"
+)
+
+// Statuses of an upload.
+const (
+	// StatusPending is a session whose file has not been sent yet.
+	StatusPending = "pending"
+	// StatusReceiving is reading the request body from the client.
+	StatusReceiving = "receiving"
+	// StatusStoring is writing the received file to storage.
+	StatusStoring = "storing"
+	// StatusDone created the asset (Upload.AssetID).
+	StatusDone = "done"
+	// StatusFailed ended without an asset (Upload.Error).
+	StatusFailed = "failed"
+)
+
+const (
+	keyPrefix = "gala:upload:"
+	// DefaultTTL is how long a session is kept after its last change.
+	DefaultTTL = 24 * time.Hour
+	// reportEvery throttles the progress writes of a transfer.
+	reportEvery = 500 * time.Millisecond
+)
+
+var (
+	ErrNotFound = stderrors.New("upload not found")
+	// ErrStarted means the session's file was already sent: a session
+	// tracks a single upload.
+	ErrStarted = stderrors.New("upload already started")
+)
+
+// Upload is the progress of an upload session.
+type Upload struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	// BytesReceived counts the request body read so far; BytesTotal is its
+	// Content-Length, 0 if the client did not send one. Both include the
+	// multipart framing, so they reach the total a bit past the file size.
+	BytesReceived int64 `json:"bytes_received"`
+	BytesTotal    int64 `json:"bytes_total,omitempty"`
+	// FileSize and BytesStored track the copy to storage, once the file
+	// was received.
+	FileSize    int64     `json:"file_size,omitempty"`
+	BytesStored int64     `json:"bytes_stored"`
+	AssetID     string    `json:"asset_id,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// startScript moves the pending session KEYS[1] to receiving with a total
+// of ARGV[1] bytes at ARGV[2]. It returns -1 if there is no session and 0
+// if it is not pending.
+var startScript = redis.NewScript(`
+local status = redis.call('HGET', KEYS[1], 'status')
+if not status then return -1 end
+if status ~= 'pending' then return 0 end
+redis.call('HSET', KEYS[1], 'status', 'receiving', 'bytes_total', ARGV[1], 'updated_at', ARGV[2])
+return 1
+`)
+
+// Tracker stores the upload sessions.
+type Tracker struct {
+	rdb redis.UniversalClient
+	ttl time.Duration
+}
+
+// New creates a tracker on rdb; ttl 0 uses DefaultTTL.
+func New(rdb redis.UniversalClient, ttl time.Duration) *Tracker {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Tracker{rdb: rdb, ttl: ttl}
+}
+
+// Create opens a pending session.
+func (t *Tracker) Create(ctx context.Context) (*Upload, error) {
+	now := time.Now().UTC()
+	u := &Upload{ID: util.NewID("upl"), Status: StatusPending, CreatedAt: now, UpdatedAt: now}
+	if err := t.set(ctx, u.ID, "status", u.Status, "created_at", stamp(now), "updated_at", stamp(now)); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Get returns the session id, or ErrNotFound.
+func (t *Tracker) Get(ctx context.Context, id string) (*Upload, error) {
+	m, err := t.rdb.HGetAll(ctx, keyPrefix+id).Result()
+	if err != nil {
+		return nil, err
+	}
+	if m["status"] == "" {
+		return nil, ErrNotFound
+	}
+	u := &Upload{ID: id, Status: m["status"], AssetID: m["asset_id"], Error: m["error"]}
+	u.BytesReceived, _ = strconv.ParseInt(m["bytes_received"], 10, 64)
+	u.BytesTotal, _ = strconv.ParseInt(m["bytes_total"], 10, 64)
+	u.FileSize, _ = strconv.ParseInt(m["file_size"], 10, 64)
+	u.BytesStored, _ = strconv.ParseInt(m["bytes_stored"], 10, 64)
+	u.CreatedAt, _ = time.Parse(time.RFC3339Nano, m["created_at"])
+	u.UpdatedAt, _ = time.Parse(time.RFC3339Nano, m["updated_at"])
+	return u, nil
+}
+
+// Start marks the pending session id as receiving a body of total bytes
+// (negative if unknown). It returns ErrNotFound or ErrStarted if the
+// session cannot take the upload.
+func (t *Tracker) Start(ctx context.Context, id string, total int64) error {
+	if total < 0 {
+		total = 0
+	}
+	key := keyPrefix + id
+	n, err := startScript.Run(ctx, t.rdb, []string{key}, total, stamp(time.Now())).Int()
+	if err != nil {
+		return err
+	}
+	switch n {
+	case -1:
+		return ErrNotFound
+	case 0:
+		return ErrStarted
+	}
+	return t.rdb.Expire(ctx, key, t.ttl).Err()
+}
+
+// Receive wraps the request body of the session id to record the bytes
+// read from it.
+func (t *Tracker) Receive(ctx context.Context, id string, body io.ReadCloser) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{t.track(ctx, id, "bytes_received", body), body}
+}
+
+// Store marks the session id as storing a file of size bytes and wraps
+// the file to record the bytes copied from it.
+func (t *Tracker) Store(ctx context.Context, id string, size int64, file io.Reader) io.Reader {
+	_ = t.set(ctx, id, "status", StatusStoring, "file_size", size, "updated_at", stamp(time.Now()))
+	return t.track(ctx, id, "bytes_stored", file)
+}
+
+// Finish ends the session id: done with assetID, or failed with errText
+// if assetID is empty.
+func (t *Tracker) Finish(ctx context.Context, id, assetID, errText string) error {
+	if assetID != "" {
+		return t.set(ctx, id, "status", StatusDone, "asset_id", assetID, "updated_at", stamp(time.Now()))
+	}
+	return t.set(ctx, id, "status", StatusFailed, "error", errText, "updated_at", stamp(time.Now()))
+}
+
+func (t *Tracker) set(ctx context.Context, id string, values ...any) error {
+	key := keyPrefix + id
+	pipe := t.rdb.TxPipeline()
+	pipe.HSet(ctx, key, values...)
+	pipe.Expire(ctx, key, t.ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// track returns r recording the bytes read in field of the session id.
+// Progress is best effort: a failed write is dropped, not the upload.
+func (t *Tracker) track(ctx context.Context, id, field string, r io.Reader) io.Reader {
+	return &progressReader{r: r, every: reportEvery, now: time.Now, report: func(n int64) {
+		_ = t.rdb.HSet(ctx, keyPrefix+id, field, n, "updated_at", stamp(time.Now())).Err()
+	}}
+}
+
+// progressReader counts the bytes read and reports the count at most once
+// per every, and at the end of r.
+type progressReader struct {
+	r      io.Reader
+	n      int64
+	every  time.Duration
+	last   time.Time
+	now    func() time.Time
+	report func(n int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.n += int64(n)
+	if err != nil {
+		p.report(p.n)
+		return n, err
+	}
+	if now := p.now(); now.Sub(p.last) >= p.every {
+		p.last = now
+		p.report(p.n)
+	}
+	return n, err
+}
+
+func stamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
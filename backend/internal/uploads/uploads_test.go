@@ -0,0 +1,43 @@
+package uploads
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestProgressReaderThrottles(t *testing.T) {
+	clock := time.Unix(0, 0)
+	var reports []int64
+	p := &progressReader{
+		r:      iotest.OneByteReader(strings.NewReader("abcdef")),
+		every:  time.Second,
+		now:    func() time.Time { return clock },
+		report: func(n int64) { reports = append(reports, n) },
+	}
+	buf := make([]byte, 1)
+	for i := 0; i < 6; i++ {
+		if _, err := p.Read(buf); err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if i == 2 {
+			clock = clock.Add(time.Second)
+		}
+	}
+	if _, err := p.Read(buf); err != io.EOF {
+		t.Fatalf("Read at end = %v, want EOF", err)
+	}
+	// The first read reports (last is zero), the fourth after the clock
+	// moved, and the end the total.
+	want := []int64{1, 4, 6}
+	if len(reports) != len(want) {
+		t.Fatalf("reports = %v, want %v", reports, want)
+	}
+	for i := range want {
+		if reports[i] != want[i] {
+			t.Fatalf("reports = %v, want %v", reports, want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package watermark is the branding overlay of v1 renders: a template sets
+// one, a job can override its fields or disable it, and the worker passes
+// the resolved watermark to the renderer with the image materialized like
+// any other input.
+package watermark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gala/internal/pkg/db"
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/store"
+)
+
+// Positions are the corners (and center) the renderer can place the
+// watermark at.
+var Positions = []string{"top-left", "top-right", "bottom-left", "bottom-right", "center"}
+
+const (
+	DefaultPosition = "bottom-right"
+	DefaultOpacity  = 0.8
+)
+
+// Config is a watermark as templates and jobs set it. In a job every field
+// is optional and overrides the template's; Disabled drops the template's
+// watermark for that job.
+type Config struct {
+	AssetID  string   `json:"asset_id,omitempty"`
+	Position string   `json:"position,omitempty"`
+	Opacity  *float64 `json:"opacity,omitempty"`
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+// Normalize trims and lowercases the fields.
+func (c *Config) Normalize() {
+	c.AssetID = strings.TrimSpace(c.AssetID)
+	c.Position = strings.ToLower(strings.TrimSpace(c.Position))
+}
+
+// Check reports the field errors of c through check, with field names
+// under prefix (e.g. "watermark"). requireAsset is set for templates,
+// whose watermark has nothing to inherit the asset from.
+func (c Config) Check(prefix string, requireAsset bool, check func(ok bool, field, message string)) {
+	if c.Disabled {
+		return
+	}
+	if requireAsset {
+		check(c.AssetID != "", prefix+".asset_id", prefix+".asset_id is required")
+	}
+	check(c.Position == "" || slices.Contains(Positions, c.Position), prefix+".position", prefix+".position is invalid")
+	check(c.Opacity == nil || (*c.Opacity > 0 && *c.Opacity <= 1), prefix+".opacity", prefix+".opacity must be in (0, 1]")
+}
+
+// Resolve merges a job's override onto the template's watermark and fills
+// the defaults. It returns nil when the job has no watermark: neither sets
+// one, or the job disabled it.
+func Resolve(template, job *Config) *Config {
+	if job != nil && job.Disabled {
+		return nil
+	}
+	var out Config
+	if template != nil && !template.Disabled {
+		out = *template
+	}
+	if job != nil {
+		if job.AssetID != "" {
+			out.AssetID = job.AssetID
+		}
+		if job.Position != "" {
+			out.Position = job.Position
+		}
+		if job.Opacity != nil {
+			out.Opacity = job.Opacity
+		}
+	}
+	if out.AssetID == "" {
+		return nil
+	}
+	if out.Position == "" {
+		out.Position = DefaultPosition
+	}
+	if out.Opacity == nil {
+		o := DefaultOpacity
+		out.Opacity = &o
+	}
+	return &out
+}
+
+// Parse decodes a stored watermark (templates.watermark, the watermark of
+// a job's params); nil or JSON null is no watermark.
+func Parse(raw []byte) (*Config, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var c Config
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, fmt.Errorf("invalid watermark: %w", err)
+	}
+	return &c, nil
+}
+
+// CheckAsset verifies that the watermark image exists and is an image. Its
+// failures are validation errors on field, so handlers can return them as
+// they are.
+func CheckAsset(ctx context.Context, q db.Querier, field, assetID string) error {
+	a, err := store.GetAsset(ctx, q, assetID)
+	if err != nil {
+		if pgerr.IsNoRows(err) {
+			return errors.ValidationField(field, "watermark asset not found").WithField("asset_id", assetID)
+		}
+		return err
+	}
+	if !strings.HasPrefix(strings.ToLower(a.Mime), "image/") {
+		return errors.ValidationField(field, "watermark asset must be an image").
+			WithFields(map[string]any{"asset_id": assetID, "mime": a.Mime})
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package watermark
+
+import "testing"
+
+func TestResolve(t *testing.T) {
+	half, full := 0.5, 1.0
+	tpl := &Config{AssetID: "ast_logo", Position: "top-left", Opacity: &half}
+
+	cases := []struct {
+		name     string
+		template *Config
+		job      *Config
+		want     *Config
+	}{
+		{"none", nil, nil, nil},
+		{"template only", tpl, nil, &Config{AssetID: "ast_logo", Position: "top-left", Opacity: &half}},
+		{"job field override", tpl, &Config{Opacity: &full}, &Config{AssetID: "ast_logo", Position: "top-left", Opacity: &full}},
+		{"job asset override", tpl, &Config{AssetID: "ast_other"}, &Config{AssetID: "ast_other", Position: "top-left", Opacity: &half}},
+		{"job disables", tpl, &Config{Disabled: true}, nil},
+		{"job only, defaults", nil, &Config{AssetID: "ast_job"}, &Config{AssetID: "ast_job", Position: DefaultPosition}},
+		{"job without asset", nil, &Config{Position: "center"}, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := Resolve(c.template, c.job)
+			if (got == nil) != (c.want == nil) {
+				t.Fatalf("got %+v, want %+v", got, c.want)
+			}
+			if got == nil {
+				return
+			}
+			wantOpacity := DefaultOpacity
+			if c.want.Opacity != nil {
+				wantOpacity = *c.want.Opacity
+			}
+			if got.AssetID != c.want.AssetID || got.Position != c.want.Position || got.Opacity == nil || *got.Opacity != wantOpacity {
+				t.Errorf("got %+v (opacity %v), want %+v (opacity %v)", got, got.Opacity, c.want, wantOpacity)
+			}
+		})
+	}
+
+	// Resolving does not modify the template
+	if tpl.Opacity != &half || *tpl.Opacity != 0.5 {
+		t.Errorf("template modified: %+v", tpl)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	zero, over := 0.0, 1.5
+	cases := []struct {
+		name         string
+		c            Config
+		requireAsset bool
+		fields       []string
+	}{
+		{"valid", Config{AssetID: "ast_1", Position: "center"}, true, nil},
+		{"missing asset in template", Config{Position: "center"}, true, []string{"w.asset_id"}},
+		{"missing asset in job", Config{Position: "center"}, false, nil},
+		{"bad position", Config{AssetID: "ast_1", Position: "middle"}, true, []string{"w.position"}},
+		{"zero opacity", Config{AssetID: "ast_1", Opacity: &zero}, false, []string{"w.opacity"}},
+		{"opacity over 1", Config{Opacity: &over}, true, []string{"w.asset_id", "w.opacity"}},
+		{"disabled skips checks", Config{Disabled: true, Position: "middle"}, true, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var fields []string
+			c.c.Check("w", c.requireAsset, func(ok bool, field, _ string) {
+				if !ok {
+					fields = append(fields, field)
+				}
+			})
+			if len(fields) != len(c.fields) {
+				t.Fatalf("got %v, want %v", fields, c.fields)
+			}
+			for i := range fields {
+				if fields[i] != c.fields[i] {
+					t.Errorf("got %v, want %v", fields, c.fields)
+				}
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, raw := range []string{"", "null"} {
+		if c, err := Parse([]byte(raw)); c != nil || err != nil {
+			t.Errorf("Parse(%q) = %+v, %v", raw, c, err)
+		}
+	}
+	c, err := Parse([]byte(`{"asset_id":"ast_1","opacity":0.3}`))
+	if err != nil || c.AssetID != "ast_1" || c.Opacity == nil || *c.Opacity != 0.3 {
+		t.Errorf("got %+v, %v", c, err)
+	}
+	if _, err := Parse([]byte(`{"opacity":"high"}`)); err == nil {
+		t.Error("expected an error for a bad opacity")
+	}
+}
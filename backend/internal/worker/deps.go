@@ -1,24 +1,124 @@
 package worker
 
 import (
+	"time"
+
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 
+	"gala/internal/callback"
+	"gala/internal/events"
+	"gala/internal/pkg/chaos"
+	"gala/internal/pkg/fetch"
 	"gala/internal/pkg/logger"
+	"gala/internal/pkg/reload"
 	"gala/internal/ports"
+	"gala/internal/worker/processor"
+	"gala/internal/worker/renderer"
+	"gala/internal/worker/staging"
 )
 
 type Deps struct {
 	Pool            *pgxpool.Pool
-	RDB             *redis.Client
+	RDB             redis.UniversalClient
 	RendererBaseURL string
 	StorageRoot     string
 	QueueName       string
 
+	// QueueMode selects the job source: queue.ModeRedis (default) or
+	// queue.ModePostgres. QueuePollInterval is the postgres mode fallback
+	// when no notification arrives (0 = 5s).
+	QueueMode         string
+	QueuePollInterval time.Duration
+
+	// QueuePopTimeout is how long a pop waits for a job (0 =
+	// queue.DefaultPopTimeout); when it runs out the worker is idle and
+	// pauses about QueueIdleBackoff (0 = DefaultIdleBackoff, jittered)
+	// before popping again.
+	QueuePopTimeout  time.Duration
+	QueueIdleBackoff time.Duration
+
+	// WorkerID names this worker in the registry (GET /admin/workers);
+	// empty uses hostname-pid. HeartbeatInterval is how often the entry is
+	// refreshed (0 = registry.DefaultInterval).
+	WorkerID          string
+	HeartbeatInterval time.Duration
+
 	// Feature flag: if true, the worker will delete local render staging under StorageRoot
 	// after (1) upload OK and (2) DB insert OK. See README Punto 3.
 	CleanupLocal bool
 
+	// JobTimeout bounds a single job end to end, from when it holds its
+	// template's concurrency slot (0 = no limit). When it expires the
+	// in-flight render request is abandoned.
+	JobTimeout time.Duration
+
+	// RendererAuth authenticates requests to the renderer; nil means none.
+	RendererAuth renderer.Authenticator
+
+	// ProgressBaseURL is the API base URL the renderer reports the progress
+	// of each render to (RENDERER_PROGRESS_BASEURL); empty asks for no
+	// reports.
+	ProgressBaseURL string
+
+	// RendererBreakerThreshold is how many renders in a row must fail with
+	// the renderer down before the worker stops taking jobs (0 =
+	// renderer.DefaultBreakerThreshold). ReadyInterval is how often it
+	// pings the renderer and the storage meanwhile, and at startup until
+	// they answer (0 = DefaultReadyInterval).
+	RendererBreakerThreshold int
+	ReadyInterval            time.Duration
+
+	// Chaos, if set, fails some renders (CHAOS_RENDERER_ERROR_RATE,
+	// CHAOS_RENDERER_TIMEOUT_RATE); see package chaos.
+	Chaos *chaos.Injector
+
+	// Reload, if set, gets handlers that re-apply RENDERER_HTTP_BASEURL and
+	// WORKER_CLEANUP_LOCAL without restarting the worker.
+	Reload *reload.Manager
+
+	// HLS configures the optional HLS packaging of renders (ffmpeg) for
+	// in-browser preview; zero value disables it.
+	HLS processor.HLSConfig
+
+	// FormatCheck configures the ffprobe check of renders against their
+	// template's format; the zero value warns on mismatches.
+	FormatCheck processor.FormatCheckConfig
+
+	// Fetch downloads the job inputs given by URL; nil uses the fetch
+	// defaults.
+	Fetch *fetch.Client
+
+	// Callbacks posts the completion of the jobs created with a
+	// callback_url; nil uses the callback defaults.
+	Callbacks *callback.Sender
+
+	// InputConcurrency bounds how many inputs of a job are downloaded at
+	// once (0 = processor.DefaultInputConcurrency).
+	InputConcurrency int
+
+	// UploadConcurrency bounds how many outputs of a job are uploaded at
+	// once and UploadAttempts how many times each is tried (0 =
+	// processor.DefaultUploadConcurrency and DefaultUploadAttempts).
+	UploadConcurrency int
+	UploadAttempts    int
+
+	// TemplateCacheTTL is how long the template of a job (defaults,
+	// watermark, params_schema) is reused for other jobs; template change
+	// events invalidate it sooner. 0 reads it for every job.
+	TemplateCacheTTL time.Duration
+
+	// Staging checks the free disk space before a job uses it and evicts
+	// old jobs/{id} directories; nil checks nothing. It is swept every
+	// StagingSweep while Run runs (0 = only when a job is short of
+	// space).
+	Staging      *staging.Manager
+	StagingSweep time.Duration
+
+	// Events receives the job.running/done/failed and asset.created
+	// events; nil publishes none.
+	Events *events.Bus
+
 	SP  ports.StorageProvider
 	Log *logger.Logger
 }
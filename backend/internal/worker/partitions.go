@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"gala/internal/pkg/logger"
+	"gala/internal/store"
+)
+
+// EnsureJobPartitions creates the monthly jobs partitions (migration 004)
+// from the current month through monthsAhead months ahead, so inserts never
+// land in jobs_default. It returns how many partitions it created.
+//
+// It is meant to run as a singleton task (see leader.Run).
+func EnsureJobPartitions(ctx context.Context, pool *pgxpool.Pool, log *logger.Logger, monthsAhead int) (int, error) {
+	created, err := store.EnsureJobPartitions(ctx, pool, monthsAhead)
+	if err != nil {
+		return 0, fmt.Errorf("ensure job partitions: %w", err)
+	}
+	if created > 0 {
+		log.Info("created job partitions", "count", created, "months_ahead", monthsAhead)
+	}
+	return created, nil
+}
@@ -0,0 +1,149 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"gala/internal/callback"
+	"gala/internal/events"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/store"
+)
+
+// CallbackHandler avisa al callback_url del job cuando este termina.
+type CallbackHandler struct {
+	pool   *pgxpool.Pool
+	sender *callback.Sender
+	log    *logger.Logger
+
+	// stop corta los envíos en curso (Close)
+	stop     context.Context
+	stopSend context.CancelFunc
+	sending  sync.WaitGroup
+}
+
+func NewCallbackHandler(pool *pgxpool.Pool, sender *callback.Sender, log *logger.Logger) *CallbackHandler {
+	if sender == nil {
+		sender = callback.New(callback.Config{})
+	}
+	stop, stopSend := context.WithCancel(context.Background())
+	return &CallbackHandler{pool: pool, sender: sender, log: log, stop: stop, stopSend: stopSend}
+}
+
+// Notify envía en segundo plano el callback del job, si tiene, con su
+// estado final, y vuelve enseguida: un receptor caído no demora al worker
+// con los reintentos (ver callback.Config). El envío tiene su propio
+// plazo, el máximo de callback.Sender.MaxDuration, y sigue aunque el ctx
+// del job haya expirado; solo Close lo corta. Cada intento queda
+// registrado en job_callback_deliveries; un fallo no cambia el job.
+func (ch *CallbackHandler) Notify(ctx context.Context, jobID string) {
+	// El job ya terminó: el aviso sale aunque el ctx del job haya expirado
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), ch.sender.MaxDuration())
+	stop := context.AfterFunc(ch.stop, cancel)
+	ch.sending.Add(1)
+	go func() {
+		defer ch.sending.Done()
+		defer cancel()
+		defer stop()
+		ch.send(ctx, jobID)
+	}()
+}
+
+// Close espera los envíos en curso hasta que ctx termina y corta los que
+// queden; los intentos cortados quedan registrados como fallidos.
+func (ch *CallbackHandler) Close(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		ch.sending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+	ch.stopSend()
+	<-done
+}
+
+func (ch *CallbackHandler) send(ctx context.Context, jobID string) {
+	log := ch.log.FromContext(ctx).WithJobID(jobID)
+
+	cb, err := store.GetJobCallback(ctx, ch.pool, jobID)
+	if err != nil {
+		if !pgerr.IsNoRows(err) && !pgerr.IsUndefinedTable(err) {
+			log.Warn("failed to load job callback", "error", err.Error())
+		}
+		return
+	}
+	if cb.DeliveredAt != nil {
+		return
+	}
+
+	payload, err := ch.payload(ctx, jobID)
+	if err != nil {
+		log.Warn("failed to build job callback", "error", err.Error())
+		return
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Warn("failed to encode job callback", "error", err.Error())
+		return
+	}
+
+	err = ch.sender.Send(ctx, cb.URL, cb.Secret, payload.Event, body, func(a callback.Attempt) {
+		d := store.CallbackDelivery{JobID: jobID, StatusCode: a.StatusCode, DurationMS: a.Duration.Milliseconds()}
+		if a.Err != nil {
+			d.Error = a.Err.Error()
+		}
+		// Se registra también el intento que cortó Close
+		rctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if rerr := store.RecordCallbackAttempt(rctx, ch.pool, d); rerr != nil {
+			log.Warn("failed to record callback attempt", "error", rerr.Error())
+		}
+	})
+	if err != nil {
+		log.Warn("job callback failed", "error", err.Error())
+		return
+	}
+	log.Debug("job callback delivered")
+}
+
+func (ch *CallbackHandler) payload(ctx context.Context, jobID string) (callback.Payload, error) {
+	j, err := store.GetJob(ctx, ch.pool, jobID)
+	if err != nil {
+		return callback.Payload{}, err
+	}
+	p := callback.Payload{
+		Event:      events.JobFailed,
+		JobID:      j.ID,
+		Status:     j.Status,
+		Outputs:    []callback.Output{},
+		Error:      j.ErrorText,
+		FinishedAt: j.FinishedAt,
+	}
+	if j.Status != store.JobDone {
+		return p, nil
+	}
+
+	p.Event = events.JobDone
+	outputs, err := store.ListJobOutputs(ctx, ch.pool, jobID)
+	if err != nil {
+		return callback.Payload{}, err
+	}
+	for _, o := range outputs {
+		p.Outputs = append(p.Outputs, callback.Output{
+			Variant:          o.Variant,
+			VideoAssetID:     o.VideoAssetID,
+			ThumbnailAssetID: o.ThumbnailAssetID,
+			CaptionsAssetID:  o.CaptionsAssetID,
+		})
+	}
+	return p, nil
+}
@@ -0,0 +1,136 @@
+package processor_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gala/internal/store"
+	"gala/internal/testinfra"
+	"gala/internal/worker/processor"
+	"gala/internal/worker/queue"
+)
+
+func TestMain(m *testing.M) { testinfra.Main(m) }
+
+func insertJob(t *testing.T, env *testinfra.Env, id string) {
+	t.Helper()
+	err := store.InsertJob(context.Background(), env.Pool, store.Job{
+		ID:         id,
+		ParamsJSON: `{"text":"hola"}`,
+		CreatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// In Redis mode a job id can be in the list twice; the worker that pops
+// the second copy must leave the job to the one running it.
+func TestProcessJobPoppedTwice(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	insertJob(t, env, "job_1")
+
+	// The first copy: its worker set the job RUNNING
+	if _, ok, err := store.MarkJobRunning(ctx, env.Pool, "job_1", false); err != nil || !ok {
+		t.Fatalf("first MarkJobRunning = %v, %v; want true", ok, err)
+	}
+
+	p := processor.New(env.ProcessorDeps())
+	if err := p.ProcessJob(ctx, "job_1"); err != nil {
+		t.Fatalf("ProcessJob of the second copy = %v", err)
+	}
+	job, err := store.GetJob(ctx, env.Pool, "job_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != store.JobRunning || job.Attempts != 1 {
+		t.Errorf("job = %s with %d attempts, want RUNNING with 1", job.Status, job.Attempts)
+	}
+}
+
+func TestMarkJobRunningClaimed(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	insertJob(t, env, "job_1")
+
+	id, err := store.ClaimNextJob(ctx, env.Pool)
+	if err != nil || id != "job_1" {
+		t.Fatalf("ClaimNextJob = %q, %v; want job_1", id, err)
+	}
+	if _, ok, _ := store.MarkJobRunning(ctx, env.Pool, id, false); ok {
+		t.Error("MarkJobRunning took a RUNNING job without claimed")
+	}
+	if _, ok, err := store.MarkJobRunning(ctx, env.Pool, id, true); err != nil || !ok {
+		t.Errorf("MarkJobRunning of the claimed job = %v, %v; want true", ok, err)
+	}
+}
+
+// A job whose template has no free slot is left QUEUED, untouched, for
+// the worker to give back; its timeout has not started.
+func TestProcessJobSlotBusy(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	limit := 1
+	err := store.InsertTemplate(ctx, env.Pool, store.Template{
+		ID: "tpl_1", Type: "avatar_v1", Name: "promo", MaxConcurrency: &limit, CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.InsertJob(ctx, env.Pool, store.Job{
+		ID:         "job_1",
+		ParamsJSON: `{"template_id":"tpl_1","inputs":{"avatar_image_asset_id":"asset_1"}}`,
+		CreatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sem := queue.NewSemaphore(env.RDB, time.Minute)
+	if ok, err := sem.TryAcquire(ctx, "template:tpl_1", limit, "job_other"); err != nil || !ok {
+		t.Fatalf("TryAcquire = %v, %v", ok, err)
+	}
+	d := env.ProcessorDeps()
+	d.Semaphore = sem
+	d.RDB = env.RDB
+	// Would fail the job at once if it ran while waiting
+	d.JobTimeout = time.Nanosecond
+
+	if err := processor.New(d).ProcessJob(ctx, "job_1"); !errors.Is(err, processor.ErrSlotBusy) {
+		t.Fatalf("ProcessJob = %v, want ErrSlotBusy", err)
+	}
+	job, err := store.GetJob(ctx, env.Pool, "job_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != store.JobQueued || job.Attempts != 0 {
+		t.Errorf("job = %s with %d attempts, want QUEUED and never started", job.Status, job.Attempts)
+	}
+}
+
+// Only a RUNNING job is set DONE: one reaped while its outputs were
+// saved stays FAILED.
+func TestMarkJobDoneOnlyRunning(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	insertJob(t, env, "job_1")
+
+	if ok, err := store.MarkJobDone(ctx, env.Pool, "job_1"); err != nil || ok {
+		t.Errorf("MarkJobDone on a QUEUED job = %v, %v; want false", ok, err)
+	}
+	if _, _, err := store.MarkJobRunning(ctx, env.Pool, "job_1", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.MarkJobFailed(ctx, env.Pool, "job_1", "reaped", store.JobError{Code: "TIMEOUT", Message: "reaped"}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := store.MarkJobDone(ctx, env.Pool, "job_1"); err != nil || ok {
+		t.Errorf("MarkJobDone on a FAILED job = %v, %v; want false", ok, err)
+	}
+	if job, _ := store.GetJob(ctx, env.Pool, "job_1"); job.Status != store.JobFailed {
+		t.Errorf("job is %s, want FAILED", job.Status)
+	}
+}
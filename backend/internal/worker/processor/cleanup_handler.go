@@ -2,20 +2,21 @@ package processor
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
-	"syscall"
+	"sync/atomic"
 
 	"gala/internal/ports"
 )
 
 type Cleanup struct {
 	storageRoot  string
-	cleanupLocal bool
+	cleanupLocal *atomic.Bool
 	sp           ports.StorageProvider
 }
 
-func NewCleanup(storageRoot string, cleanupLocal bool, sp ports.StorageProvider) *Cleanup {
+func NewCleanup(storageRoot string, cleanupLocal *atomic.Bool, sp ports.StorageProvider) *Cleanup {
 	return &Cleanup{
 		storageRoot:  storageRoot,
 		cleanupLocal: cleanupLocal,
@@ -23,26 +24,35 @@ func NewCleanup(storageRoot string, cleanupLocal bool, sp ports.StorageProvider)
 	}
 }
 
-// CleanupJob limpia los archivos temporales del job
-func (c *Cleanup) CleanupJob(jobID string) {
-	if !c.shouldCleanup() {
-		return
+// CleanupJob borra el staging local del job, con WORKER_CLEANUP_LOCAL
+// activo y una vez registrados sus outputs: jobs/{id} (los inputs
+// materializados) con cualquier provider, y renders/{id} salvo con
+// localfs, donde renders/ es el propio almacenamiento de los outputs.
+func (c *Cleanup) CleanupJob(jobID string) error {
+	if !c.cleanupLocal.Load() {
+		return nil
 	}
-
-	// Solo limpiar la carpeta de renders, no otras carpetas del job
-	jobDir := filepath.Join(c.storageRoot, "renders", jobID)
-	
-	err := os.Remove(jobDir)
-	if err == nil || os.IsNotExist(err) {
-		return
+	// Un ID vacío o con separadores borraría fuera del job
+	if jobID == "" || jobID != filepath.Base(jobID) || jobID == "." || jobID == ".." {
+		return fmt.Errorf("invalid job id %q", jobID)
 	}
 
-	// Ignorar errores de directorio no vacío
-	if errors.Is(err, syscall.ENOTEMPTY) || errors.Is(err, syscall.EEXIST) {
-		return
+	var errs []error
+	for _, dir := range c.stagingDirs(jobID) {
+		// RemoveAll no falla si el directorio ya no existe
+		if err := os.RemoveAll(dir); err != nil {
+			errs = append(errs, err)
+		}
 	}
+	return errors.Join(errs...)
 }
 
-func (c *Cleanup) shouldCleanup() bool {
-	return c.cleanupLocal && c.sp.Provider() == "gdrive"
+// stagingDirs son los directorios locales del job que no son
+// almacenamiento con el provider actual.
+func (c *Cleanup) stagingDirs(jobID string) []string {
+	dirs := []string{filepath.Join(c.storageRoot, "jobs", jobID)}
+	if c.sp.Provider() != "localfs" {
+		dirs = append(dirs, filepath.Join(c.storageRoot, "renders", jobID))
+	}
+	return dirs
 }
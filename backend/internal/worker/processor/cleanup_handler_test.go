@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"gala/internal/testsupport"
+)
+
+// stageJob creates the local staging of a job as the worker leaves it
+// after uploading its outputs.
+func stageJob(t *testing.T, root, jobID string) {
+	t.Helper()
+	for _, f := range []string{
+		filepath.Join("jobs", jobID, "inputs", "avatar.jpg"),
+		filepath.Join("renders", jobID, "video.mp4"),
+		filepath.Join("renders", jobID, "hls", "index.m3u8"),
+	} {
+		p := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func newTestCleanup(root, provider string, enabled bool) *Cleanup {
+	flag := new(atomic.Bool)
+	flag.Store(enabled)
+	return NewCleanup(root, flag, &testsupport.Storage{Name: provider})
+}
+
+func TestCleanupJob(t *testing.T) {
+	tests := []struct {
+		provider    string
+		enabled     bool
+		wantInputs  bool
+		wantRenders bool
+	}{
+		{provider: "gdrive", enabled: true, wantInputs: false, wantRenders: false},
+		// renders/ is the storage of localfs outputs
+		{provider: "localfs", enabled: true, wantInputs: false, wantRenders: true},
+		{provider: "gdrive", enabled: false, wantInputs: true, wantRenders: true},
+		{provider: "localfs", enabled: false, wantInputs: true, wantRenders: true},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		stageJob(t, root, "job_1")
+		stageJob(t, root, "job_2")
+
+		if err := newTestCleanup(root, tt.provider, tt.enabled).CleanupJob("job_1"); err != nil {
+			t.Fatalf("%s enabled=%v: CleanupJob() = %v", tt.provider, tt.enabled, err)
+		}
+		if got := exists(filepath.Join(root, "jobs", "job_1")); got != tt.wantInputs {
+			t.Errorf("%s enabled=%v: jobs/job_1 exists = %v, want %v", tt.provider, tt.enabled, got, tt.wantInputs)
+		}
+		if got := exists(filepath.Join(root, "renders", "job_1")); got != tt.wantRenders {
+			t.Errorf("%s enabled=%v: renders/job_1 exists = %v, want %v", tt.provider, tt.enabled, got, tt.wantRenders)
+		}
+		if !exists(filepath.Join(root, "jobs", "job_2")) || !exists(filepath.Join(root, "renders", "job_2")) {
+			t.Errorf("%s enabled=%v: another job's staging was removed", tt.provider, tt.enabled)
+		}
+	}
+}
+
+func TestCleanupJobMissingDirs(t *testing.T) {
+	if err := newTestCleanup(t.TempDir(), "gdrive", true).CleanupJob("job_1"); err != nil {
+		t.Errorf("CleanupJob() = %v, want nil without staging", err)
+	}
+}
+
+func TestCleanupJobRejectsUnsafeIDs(t *testing.T) {
+	root := t.TempDir()
+	stageJob(t, root, "job_1")
+	c := newTestCleanup(root, "gdrive", true)
+	for _, id := range []string{"", ".", "..", "../renders", "job_1/inputs"} {
+		if err := c.CleanupJob(id); err == nil {
+			t.Errorf("CleanupJob(%q) = nil, want an error", id)
+		}
+	}
+	if !exists(filepath.Join(root, "jobs", "job_1", "inputs", "avatar.jpg")) {
+		t.Error("an unsafe job id removed files")
+	}
+}
@@ -0,0 +1,224 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/logger"
+	"gala/internal/store"
+)
+
+// Modos de la verificación de formato (FormatCheckConfig.Mode).
+const (
+	FormatCheckOff  = "off"
+	FormatCheckWarn = "warn"
+	FormatCheckFail = "fail"
+)
+
+// FormatCheckConfig configura la verificación del formato de los renders:
+// tras el render se mide el video con ffprobe, se compara con el format y
+// duration_ms del template y lo medido queda en el asset del video.
+type FormatCheckConfig struct {
+	// Mode es FormatCheckOff, FormatCheckWarn (registra las diferencias en
+	// el log; el default) o FormatCheckFail (falla el job, también si el
+	// video no se puede medir).
+	Mode string
+	// FFprobePath es el binario de ffprobe; vacío usa "ffprobe" del PATH.
+	FFprobePath string
+	// SizeTolerance son los píxeles que puede diferir cada dimensión; 0
+	// exige la medida exacta.
+	SizeTolerance int
+	// FPSTolerance es la diferencia de fps admitida (0 = 0.5).
+	FPSTolerance float64
+	// DurationTolerance es la diferencia de duración admitida (0 = 1s).
+	DurationTolerance time.Duration
+	// Timeout acota la ejecución de ffprobe (0 = 30s).
+	Timeout time.Duration
+}
+
+// OutputFormat es el formato que un template pide a sus renders; los
+// campos en cero no se verifican.
+type OutputFormat struct {
+	Width      int
+	Height     int
+	FPS        float64
+	DurationMs int
+}
+
+// parseOutputFormat lee el format (JSONB) y duration_ms de un template.
+func parseOutputFormat(raw []byte, durationMs *int) (OutputFormat, error) {
+	var f OutputFormat
+	if len(raw) > 0 {
+		var v struct {
+			Width  int     `json:"width"`
+			Height int     `json:"height"`
+			FPS    float64 `json:"fps"`
+		}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return f, fmt.Errorf("invalid template format: %w", err)
+		}
+		f.Width, f.Height, f.FPS = v.Width, v.Height, v.FPS
+	}
+	if durationMs != nil {
+		f.DurationMs = *durationMs
+	}
+	return f, nil
+}
+
+// FormatChecker mide los renders y los compara con su OutputFormat.
+type FormatChecker struct {
+	cfg FormatCheckConfig
+}
+
+func NewFormatChecker(cfg FormatCheckConfig) *FormatChecker {
+	if cfg.Mode == "" {
+		cfg.Mode = FormatCheckWarn
+	}
+	if cfg.FFprobePath == "" {
+		cfg.FFprobePath = "ffprobe"
+	}
+	if cfg.FPSTolerance <= 0 {
+		cfg.FPSTolerance = 0.5
+	}
+	if cfg.DurationTolerance <= 0 {
+		cfg.DurationTolerance = time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &FormatChecker{cfg: cfg}
+}
+
+// Enabled indica si el paso está activo.
+func (fc *FormatChecker) Enabled() bool {
+	return fc.cfg.Mode != FormatCheckOff
+}
+
+// Probe mide el video en file con ffprobe.
+func (fc *FormatChecker) Probe(ctx context.Context, file string) (store.AssetMedia, error) {
+	runCtx, cancel := context.WithTimeout(ctx, fc.cfg.Timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(runCtx, fc.cfg.FFprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height,avg_frame_rate:format=duration",
+		"-of", "json",
+		file,
+	)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 500 {
+			msg = msg[len(msg)-500:]
+		}
+		return store.AssetMedia{}, fmt.Errorf("ffprobe failed: %w: %s", err, msg)
+	}
+	return parseProbe(stdout.Bytes())
+}
+
+// parseProbe lee la salida JSON de ffprobe.
+func parseProbe(out []byte) (store.AssetMedia, error) {
+	var v struct {
+		Streams []struct {
+			Width        int    `json:"width"`
+			Height       int    `json:"height"`
+			AvgFrameRate string `json:"avg_frame_rate"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &v); err != nil {
+		return store.AssetMedia{}, fmt.Errorf("invalid ffprobe output: %w", err)
+	}
+	if len(v.Streams) == 0 {
+		return store.AssetMedia{}, fmt.Errorf("ffprobe found no video stream")
+	}
+	st := v.Streams[0]
+	m := store.AssetMedia{Width: st.Width, Height: st.Height, FPS: frameRate(st.AvgFrameRate)}
+	if d, err := strconv.ParseFloat(v.Format.Duration, 64); err == nil {
+		m.DurationMs = int64(math.Round(d * 1000))
+	}
+	return m, nil
+}
+
+// frameRate convierte una fracción de ffprobe ("30000/1001") en fps,
+// redondeados a centésimas; 0 si no hay dato ("0/0").
+func frameRate(s string) float64 {
+	num, den, ok := strings.Cut(s, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if ok {
+		d, err := strconv.ParseFloat(den, 64)
+		if err != nil || d == 0 {
+			return 0
+		}
+		n /= d
+	}
+	return math.Round(n*100) / 100
+}
+
+// Deviations lista en qué se aparta got de want más allá de las
+// tolerancias; vacío si cumple.
+func (fc *FormatChecker) Deviations(want OutputFormat, got store.AssetMedia) []string {
+	var devs []string
+	if want.Width > 0 && want.Height > 0 &&
+		(abs(got.Width-want.Width) > fc.cfg.SizeTolerance || abs(got.Height-want.Height) > fc.cfg.SizeTolerance) {
+		devs = append(devs, fmt.Sprintf("size is %dx%d, template wants %dx%d", got.Width, got.Height, want.Width, want.Height))
+	}
+	if want.FPS > 0 && math.Abs(got.FPS-want.FPS) > fc.cfg.FPSTolerance {
+		devs = append(devs, fmt.Sprintf("fps is %g, template wants %g", got.FPS, want.FPS))
+	}
+	if want.DurationMs > 0 {
+		diff := time.Duration(got.DurationMs-int64(want.DurationMs)) * time.Millisecond
+		if diff.Abs() > fc.cfg.DurationTolerance {
+			devs = append(devs, fmt.Sprintf("duration is %dms, template wants %dms", got.DurationMs, want.DurationMs))
+		}
+	}
+	return devs
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// checkFormat mide el video renderizado y lo compara con el formato del
+// template. Devuelve lo medido para guardarlo en el asset (nil si no se
+// pudo medir) y, en modo fail, el error con que falla el job.
+func (p *Processor) checkFormat(ctx context.Context, log *logger.Logger, want OutputFormat, videoKey string) (*store.AssetMedia, error) {
+	fc := p.formatChecker
+	media, err := fc.Probe(ctx, filepath.Join(p.storageRoot, videoKey))
+	if err != nil {
+		if fc.cfg.Mode == FormatCheckFail {
+			return nil, errors.Wrap(err, "processor.format", "failed to probe render")
+		}
+		log.Warn("render probe failed, skipping format check", "error", err.Error())
+		return nil, nil
+	}
+	devs := fc.Deviations(want, media)
+	if len(devs) == 0 {
+		log.Debug("render format checked", "width", media.Width, "height", media.Height, "fps", media.FPS, "duration_ms", media.DurationMs)
+		return &media, nil
+	}
+	if fc.cfg.Mode == FormatCheckFail {
+		return nil, errors.WrapWithCode(fmt.Errorf("%s", strings.Join(devs, "; ")),
+			errors.CodeFailedPrecond, "processor.format", "render does not match the template format")
+	}
+	log.Warn("render does not match the template format", "deviations", strings.Join(devs, "; "))
+	return &media, nil
+}
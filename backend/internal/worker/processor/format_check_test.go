@@ -0,0 +1,82 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+
+	"gala/internal/store"
+)
+
+func TestParseProbe(t *testing.T) {
+	out := `{"programs": [], "streams": [{"width": 1080, "height": 1920, "avg_frame_rate": "30000/1001"}],
+		"format": {"duration": "12.345678"}}`
+	got, err := parseProbe([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := store.AssetMedia{Width: 1080, Height: 1920, FPS: 29.97, DurationMs: 12346}
+	if got != want {
+		t.Errorf("parseProbe() = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseProbe([]byte(`{"streams": [], "format": {}}`)); err == nil {
+		t.Error("expected an error without a video stream")
+	}
+}
+
+func TestFrameRate(t *testing.T) {
+	for in, want := range map[string]float64{"30/1": 30, "25": 25, "0/0": 0, "": 0, "24000/1001": 23.98} {
+		if got := frameRate(in); got != want {
+			t.Errorf("frameRate(%q) = %g, want %g", in, got, want)
+		}
+	}
+}
+
+func TestDeviations(t *testing.T) {
+	want := OutputFormat{Width: 1080, Height: 1920, FPS: 30, DurationMs: 10000}
+	fc := NewFormatChecker(FormatCheckConfig{SizeTolerance: 2})
+	tests := []struct {
+		name string
+		got  store.AssetMedia
+		devs []string // substrings, one per deviation
+	}{
+		{"match", store.AssetMedia{Width: 1080, Height: 1920, FPS: 29.97, DurationMs: 10400}, nil},
+		{"within size tolerance", store.AssetMedia{Width: 1078, Height: 1920, FPS: 30, DurationMs: 10000}, nil},
+		{"landscape", store.AssetMedia{Width: 1920, Height: 1080, FPS: 30, DurationMs: 10000}, []string{"size is 1920x1080"}},
+		{"slow and short", store.AssetMedia{Width: 1080, Height: 1920, FPS: 24, DurationMs: 8000}, []string{"fps is 24", "duration is 8000ms"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devs := fc.Deviations(want, tt.got)
+			if len(devs) != len(tt.devs) {
+				t.Fatalf("Deviations() = %q, want %d", devs, len(tt.devs))
+			}
+			for i, d := range tt.devs {
+				if !strings.Contains(devs[i], d) {
+					t.Errorf("deviation %d = %q, want it to mention %q", i, devs[i], d)
+				}
+			}
+		})
+	}
+
+	if devs := fc.Deviations(OutputFormat{}, store.AssetMedia{Width: 1, Height: 1}); len(devs) != 0 {
+		t.Errorf("Deviations() without a format = %q, want none", devs)
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	ms := 15000
+	f, err := parseOutputFormat([]byte(`{"width": 720, "height": 1280, "fps": 25}`), &ms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (OutputFormat{Width: 720, Height: 1280, FPS: 25, DurationMs: 15000}); f != want {
+		t.Errorf("parseOutputFormat() = %+v, want %+v", f, want)
+	}
+	if f, err := parseOutputFormat(nil, nil); err != nil || f != (OutputFormat{}) {
+		t.Errorf("parseOutputFormat(nil) = %+v, %v", f, err)
+	}
+	if _, err := parseOutputFormat([]byte(`"9:16"`), nil); err == nil {
+		t.Error("expected an error for a format that is not an object")
+	}
+}
@@ -45,14 +45,6 @@ func IsTruthy(v any) bool {
 	}
 }
 
-// NullIfEmpty retorna nil si el string está vacío, útil para campos nullable en DB
-func NullIfEmpty(s string) any {
-	if strings.TrimSpace(s) == "" {
-		return nil
-	}
-	return s
-}
-
 // SanitizeFilename limpia un nombre de archivo de caracteres peligrosos
 func SanitizeFilename(s string) string {
 	s = strings.TrimSpace(s)
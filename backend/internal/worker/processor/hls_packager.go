@@ -0,0 +1,126 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gala/internal/store"
+)
+
+// HLSConfig configura el empaquetado HLS opcional de los renders.
+type HLSConfig struct {
+	// Enabled activa el paso; sin él los jobs solo producen el mp4.
+	Enabled bool
+	// FFmpegPath es el binario de ffmpeg; vacío usa "ffmpeg" del PATH.
+	FFmpegPath string
+	// SegmentSeconds es la duración objetivo de cada segmento (0 = 6s).
+	SegmentSeconds int
+	// Transcode recodifica a H.264/AAC en vez de copiar los streams; solo
+	// hace falta si el renderer no entrega H.264.
+	Transcode bool
+	// Timeout acota la ejecución de ffmpeg (0 = 10m).
+	Timeout time.Duration
+}
+
+// HLSPackager convierte el mp4 renderizado en un playlist HLS con sus
+// segmentos, junto al video en el staging local.
+type HLSPackager struct {
+	cfg         HLSConfig
+	storageRoot string
+}
+
+func NewHLSPackager(cfg HLSConfig, storageRoot string) *HLSPackager {
+	if cfg.FFmpegPath == "" {
+		cfg.FFmpegPath = "ffmpeg"
+	}
+	if cfg.SegmentSeconds <= 0 {
+		cfg.SegmentSeconds = 6
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Minute
+	}
+	return &HLSPackager{cfg: cfg, storageRoot: storageRoot}
+}
+
+// Enabled indica si el paso está activo.
+func (hp *HLSPackager) Enabled() bool {
+	return hp.cfg.Enabled
+}
+
+// HLSKey devuelve la clave de objeto de un archivo HLS del job.
+func HLSKey(jobID, name string) string {
+	return path.Join("renders", jobID, "hls", name)
+}
+
+// Package empaqueta videoKey en renders/<job>/hls y devuelve las claves de
+// objeto generadas, el playlist primero. El playlist referencia los
+// segmentos por nombre relativo, así que se sirve tal cual desde
+// /assets/{id}/hls/.
+func (hp *HLSPackager) Package(ctx context.Context, jobID, videoKey string) ([]string, error) {
+	dir := filepath.Join(hp.storageRoot, "renders", jobID, "hls")
+	// Un intento anterior del mismo job puede haber dejado segmentos
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to reset hls dir: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create hls dir: %w", err)
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error", "-y",
+		"-i", filepath.Join(hp.storageRoot, videoKey)}
+	if hp.cfg.Transcode {
+		args = append(args, "-c:v", "libx264", "-preset", "veryfast", "-c:a", "aac")
+	} else {
+		args = append(args, "-c", "copy")
+	}
+	args = append(args,
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(hp.cfg.SegmentSeconds),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(dir, "seg_%04d.ts"),
+		filepath.Join(dir, store.HLSPlaylist),
+	)
+
+	runCtx, cancel := context.WithTimeout(ctx, hp.cfg.Timeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(runCtx, hp.cfg.FFmpegPath, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 500 {
+			msg = msg[len(msg)-500:]
+		}
+		return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, msg)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hls dir: %w", err)
+	}
+	var segments []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".ts") {
+			segments = append(segments, e.Name())
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("ffmpeg produced no segments")
+	}
+	sort.Strings(segments)
+
+	keys := []string{HLSKey(jobID, store.HLSPlaylist)}
+	for _, s := range segments {
+		keys = append(keys, HLSKey(jobID, s))
+	}
+	return keys, nil
+}
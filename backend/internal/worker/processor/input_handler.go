@@ -2,21 +2,35 @@ package processor
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"mime"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/sync/errgroup"
 
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/pgerr"
 	"gala/internal/ports"
+	"gala/internal/store"
 )
 
+// DefaultInputConcurrency es cuántos inputs de un job se descargan a la
+// vez si no se configura otro límite.
+const DefaultInputConcurrency = 4
+
 type InputHandler struct {
 	pool        *pgxpool.Pool
 	sp          ports.StorageProvider
 	storageRoot string
+	concurrency int
 }
 
 func NewInputHandler(pool *pgxpool.Pool, sp ports.StorageProvider, storageRoot string) *InputHandler {
@@ -24,40 +38,75 @@ func NewInputHandler(pool *pgxpool.Pool, sp ports.StorageProvider, storageRoot s
 		pool:        pool,
 		sp:          sp,
 		storageRoot: storageRoot,
+		concurrency: DefaultInputConcurrency,
 	}
 }
 
-// Materialize descarga y guarda todos los inputs localmente
+// WithConcurrency limita cuántos inputs se descargan a la vez; n <= 0
+// deja DefaultInputConcurrency.
+func (ih *InputHandler) WithConcurrency(n int) *InputHandler {
+	if n > 0 {
+		ih.concurrency = n
+	}
+	return ih
+}
+
+// Materialize descarga y guarda todos los inputs localmente, varios a la
+// vez. El primer input que falla cancela los demás; en ese caso no deja
+// archivos a medias ni los inputs que ya se habían descargado.
 func (ih *InputHandler) Materialize(ctx context.Context, jobID string, inputs map[string]string) (map[string]string, error) {
 	baseDir := filepath.Join(ih.storageRoot, "jobs", jobID, "inputs")
 	if err := os.MkdirAll(baseDir, 0o755); err != nil {
 		return nil, fmt.Errorf("failed to create inputs directory: %w", err)
 	}
 
+	var mu sync.Mutex
 	materializedPaths := make(map[string]string)
 
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(ih.concurrency)
 	for inputName, assetID := range inputs {
 		assetID = strings.TrimSpace(assetID)
 		if assetID == "" {
 			continue
 		}
 
-		localPath, err := ih.materializeInput(ctx, baseDir, inputName, assetID)
-		if err != nil {
-			return nil, err
-		}
+		g.Go(func() error {
+			// Otro input ya falló: no empezar más descargas
+			if err := gctx.Err(); err != nil {
+				return err
+			}
+			localPath, err := ih.materializeInput(gctx, baseDir, inputName, assetID)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			materializedPaths[inputName] = localPath
+			mu.Unlock()
+			return nil
+		})
+	}
 
-		materializedPaths[inputName] = localPath
+	if err := g.Wait(); err != nil {
+		// saveToLocal ya borra su archivo si falla; quedan los completos
+		for _, path := range materializedPaths {
+			_ = os.Remove(path)
+		}
+		return nil, err
 	}
 
 	return materializedPaths, nil
 }
 
 func (ih *InputHandler) materializeInput(ctx context.Context, baseDir, inputName, assetID string) (string, error) {
-	// Obtener metadata del asset
+	// Obtener metadata del asset; si no existe es un input inválido
 	asset, err := ih.fetchAsset(ctx, assetID)
 	if err != nil {
-		return "", fmt.Errorf("input asset not found input=%s asset_id=%s: %w", inputName, assetID, err)
+		if pgerr.IsNoRows(err) {
+			return "", errors.ValidationField("inputs."+inputName, "input asset not found").
+				WithFields(map[string]any{"input": inputName, "asset_id": assetID})
+		}
+		return "", fmt.Errorf("fetch input asset input=%s asset_id=%s: %w", inputName, assetID, err)
 	}
 
 	// Descargar del storage
@@ -67,34 +116,35 @@ func (ih *InputHandler) materializeInput(ctx context.Context, baseDir, inputName
 	}
 	defer rc.Close()
 
-	// Guardar localmente
-	localPath, err := ih.saveToLocal(baseDir, inputName, asset.Mime, rc)
+	// Guardar localmente y verificar contra el registro del asset
+	localPath, err := ih.saveToLocal(baseDir, inputName, asset, rc)
 	if err != nil {
-		return "", fmt.Errorf("failed to save input locally input=%s: %w", inputName, err)
+		return "", err
 	}
 
 	return localPath, nil
 }
 
 type assetMetadata struct {
+	ID        string
 	ObjectKey string
 	Mime      string
+	SizeBytes int64
+	Checksum  string
 }
 
 func (ih *InputHandler) fetchAsset(ctx context.Context, assetID string) (*assetMetadata, error) {
-	var objectKey, mime string
-	err := ih.pool.QueryRow(ctx, 
-		`SELECT object_key, mime FROM assets WHERE id=$1`, 
-		assetID,
-	).Scan(&objectKey, &mime)
-
+	a, err := store.GetAsset(ctx, ih.pool, assetID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &assetMetadata{
-		ObjectKey: objectKey,
-		Mime:      mime,
+		ID:        a.ID,
+		ObjectKey: a.ObjectKey,
+		Mime:      a.Mime,
+		SizeBytes: a.SizeBytes,
+		Checksum:  a.Checksum,
 	}, nil
 }
 
@@ -106,20 +156,103 @@ func (ih *InputHandler) downloadAsset(ctx context.Context, objectKey, inputName,
 	return rc, nil
 }
 
-func (ih *InputHandler) saveToLocal(baseDir, inputName, mime string, rc io.Reader) (string, error) {
-	ext := ExtFromMime(mime)
+// saveToLocal guarda el contenido en baseDir y lo verifica con
+// verifyInput; si no pasa, borra el archivo.
+func (ih *InputHandler) saveToLocal(baseDir, inputName string, asset *assetMetadata, rc io.Reader) (string, error) {
+	ext := ExtFromMime(asset.Mime)
 	filename := SanitizeFilename(inputName) + ext
 	localPath := filepath.Join(baseDir, filename)
 
 	f, err := os.Create(localPath)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to save input locally input=%s: %w", inputName, err)
+	}
+
+	sum := md5.New()
+	head := &headBuffer{}
+	size, err := io.Copy(f, io.TeeReader(rc, io.MultiWriter(sum, head)))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(localPath)
+		return "", fmt.Errorf("failed to save input locally input=%s: %w", inputName, err)
 	}
-	defer f.Close()
 
-	if _, err := io.Copy(f, rc); err != nil {
+	if err := verifyInput(inputName, asset, size, hex.EncodeToString(sum.Sum(nil)), http.DetectContentType(head.buf)); err != nil {
+		_ = os.Remove(localPath)
 		return "", err
 	}
 
 	return localPath, nil
 }
+
+// verifyInput compara un input descargado con el registro de su asset. Un
+// tamaño o checksum distinto es corrupción del storage (INTERNAL, no es
+// culpa del usuario); un contenido que no es del tipo que declara el asset
+// es un input inválido (VALIDATION). Los assets sin tamaño o checksum
+// registrado no se comparan en eso.
+func verifyInput(inputName string, a *assetMetadata, size int64, checksum, sniffed string) error {
+	fields := map[string]any{"input": inputName, "asset_id": a.ID}
+
+	if a.SizeBytes > 0 && size != a.SizeBytes {
+		return errors.Newf(errors.CodeInternal, "input %s: downloaded %d bytes but asset %s records %d (storage corruption)",
+			inputName, size, a.ID, a.SizeBytes).WithFields(fields)
+	}
+	if a.Checksum != "" && !strings.EqualFold(checksum, a.Checksum) {
+		return errors.Newf(errors.CodeInternal, "input %s: md5 %s does not match asset %s checksum %s (storage corruption)",
+			inputName, checksum, a.ID, a.Checksum).WithFields(fields)
+	}
+	if !mimeMatches(a.Mime, sniffed) {
+		fields["mime"], fields["detected_mime"] = a.Mime, sniffed
+		return errors.ValidationField("inputs."+inputName,
+			fmt.Sprintf("input %s content is %s but asset %s declares %s", inputName, sniffed, a.ID, a.Mime)).WithFields(fields)
+	}
+	return nil
+}
+
+// mimeMatches dice si el tipo detectado del contenido
+// (http.DetectContentType) es compatible con el MIME declarado. Sólo se
+// compara el tipo de media: imagen, audio, video o texto. Si la detección
+// no concluye (application/octet-stream) se acepta.
+func mimeMatches(declared, sniffed string) bool {
+	declared = strings.ToLower(strings.TrimSpace(declared))
+	if mt, _, err := mime.ParseMediaType(sniffed); err == nil {
+		sniffed = mt
+	}
+	major := func(t string) string {
+		m, _, _ := strings.Cut(t, "/")
+		return m
+	}
+
+	switch major(declared) {
+	case "image":
+		if declared == "image/svg+xml" {
+			return sniffed == "text/xml" || sniffed == "text/plain"
+		}
+		return sniffed == "application/octet-stream" || major(sniffed) == "image"
+	case "audio", "video":
+		switch sniffed {
+		// Contenedores que llevan audio o video (m4a se detecta como mp4)
+		case "application/octet-stream", "application/ogg", "video/mp4", "video/webm":
+			return true
+		}
+		return major(sniffed) == major(declared)
+	case "text":
+		return major(sniffed) == "text"
+	}
+	return true
+}
+
+// headBuffer guarda los primeros 512 bytes escritos, los que usa
+// http.DetectContentType.
+type headBuffer struct {
+	buf []byte
+}
+
+func (h *headBuffer) Write(p []byte) (int, error) {
+	if n := 512 - len(h.buf); n > 0 {
+		h.buf = append(h.buf, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
@@ -5,8 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"gala/internal/assetslots"
+	"gala/internal/paramschema"
+	"gala/internal/store"
+	"gala/internal/watermark"
 )
 
 type ParsedJob struct {
@@ -15,6 +21,15 @@ type ParsedJob struct {
 	Params       map[string]any
 	MergedParams map[string]any
 	HasEnvelope  bool
+	// Watermark es el del template con los overrides del job; nil si el
+	// render va sin watermark.
+	Watermark *watermark.Config
+	// Format es el formato que el template pide al render (cero en jobs
+	// legacy).
+	Format OutputFormat
+	// MaxConcurrency es el tope de renders simultáneos del template; 0 sin
+	// tope.
+	MaxConcurrency int
 }
 
 func (j *ParsedJob) UsedV1() bool {
@@ -38,13 +53,23 @@ func (j *ParsedJob) HasAudio() bool {
 }
 
 type JobParser struct {
-	pool *pgxpool.Pool
+	pool  *pgxpool.Pool
+	cache *templateCache // nil: cada job lee su template
 }
 
 func NewJobParser(pool *pgxpool.Pool) *JobParser {
 	return &JobParser{pool: pool}
 }
 
+// WithTemplateCache reusa lo leído de cada template durante ttl (ver
+// templateCache); ttl <= 0 lo deja sin cache.
+func (jp *JobParser) WithTemplateCache(ttl time.Duration) *JobParser {
+	if ttl > 0 {
+		jp.cache = newTemplateCache(ttl)
+	}
+	return jp
+}
+
 func (jp *JobParser) Parse(ctx context.Context, paramsJSON string) (*ParsedJob, error) {
 	var raw map[string]any
 	if err := json.Unmarshal([]byte(paramsJSON), &raw); err != nil {
@@ -83,14 +108,38 @@ func (jp *JobParser) parseEnvelopeFormat(ctx context.Context, raw map[string]any
 		}
 	}
 
-	// Obtener defaults del template
-	defaults, err := jp.fetchTemplateDefaults(ctx, templateID)
+	// Obtener defaults, watermark, formato y schema (si es strict) del
+	// template
+	t, err := jp.fetchTemplate(ctx, templateID)
 	if err != nil {
 		return nil, err
 	}
+	j.Format = t.format
+	j.MaxConcurrency = t.maxConcurrency
+
+	// Los inputs que el job no manda salen de los slots de assets del
+	// template (música de fondo, logo...)
+	t.assets.Fill(j.Inputs)
+
+	// Template strict: los params deben coincidir con su params_schema. El
+	// API ya lo valida al crear el job; esto cubre los jobs en cola de un
+	// template que pasó a strict después
+	if err := t.schema.Validate("params", j.Params); err != nil {
+		return nil, err
+	}
+
+	// Watermark: el del template con los overrides del job
+	var jobWM *watermark.Config
+	if wm, ok := raw["watermark"]; ok {
+		b, _ := json.Marshal(wm)
+		if jobWM, err = watermark.Parse(b); err != nil {
+			return nil, err
+		}
+	}
+	j.Watermark = watermark.Resolve(t.watermark, jobWM)
 
 	// Merge: defaults -> params del job
-	j.MergedParams = mergeMaps(defaults, j.Params)
+	j.MergedParams = mergeMaps(t.defaults, j.Params)
 
 	// Validar campo text según contexto:
 	// - Si hay audio + captions: text es opcional (se transcribe del audio)
@@ -131,22 +180,50 @@ func (jp *JobParser) parseLegacyFormat(raw map[string]any, j *ParsedJob) (*Parse
 	return j, nil
 }
 
-func (jp *JobParser) fetchTemplateDefaults(ctx context.Context, templateID string) (map[string]any, error) {
-	var defaultsBytes []byte
-	err := jp.pool.QueryRow(ctx,
-		`SELECT COALESCE(defaults, '{}'::jsonb) FROM templates WHERE id=$1 AND deleted_at IS NULL`,
-		templateID,
-	).Scan(&defaultsBytes)
+// fetchTemplate devuelve los defaults, el watermark, los slots de assets y,
+// si el template es strict, su params_schema (nil si no lo es).
+func (jp *JobParser) fetchTemplate(ctx context.Context, templateID string) (cachedTemplate, error) {
+	if jp.cache != nil {
+		if t, ok := jp.cache.get(templateID); ok {
+			return t, nil
+		}
+	}
+
+	t, err := store.GetTemplate(ctx, jp.pool, templateID)
 	if err != nil {
-		return nil, fmt.Errorf("template not found: %s", templateID)
+		return cachedTemplate{}, fmt.Errorf("template not found: %s", templateID)
+	}
+
+	ct := cachedTemplate{defaults: make(map[string]any)}
+	if len(t.Defaults) > 0 {
+		if err := json.Unmarshal(t.Defaults, &ct.defaults); err != nil {
+			return cachedTemplate{}, fmt.Errorf("invalid template defaults: %w", err)
+		}
+	}
+
+	if ct.watermark, err = watermark.Parse(t.Watermark); err != nil {
+		return cachedTemplate{}, err
+	}
+	if ct.format, err = parseOutputFormat(t.Format, t.DurationMs); err != nil {
+		return cachedTemplate{}, err
 	}
 
-	defaults := make(map[string]any)
-	if err := json.Unmarshal(defaultsBytes, &defaults); err != nil {
-		return nil, fmt.Errorf("invalid template defaults: %w", err)
+	if t.MaxConcurrency != nil {
+		ct.maxConcurrency = *t.MaxConcurrency
+	}
+	if ct.assets, err = assetslots.Parse(t.Assets); err != nil {
+		return cachedTemplate{}, err
 	}
 
-	return defaults, nil
+	if t.StrictParams {
+		if ct.schema, err = paramschema.Compile(t.ParamsSchema, true); err != nil {
+			return cachedTemplate{}, err
+		}
+	}
+	if jp.cache != nil {
+		jp.cache.put(templateID, ct)
+	}
+	return ct, nil
 }
 
 func hasValidText(params map[string]any) bool {
@@ -2,37 +2,95 @@ package processor
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/sync/errgroup"
 
+	"gala/internal/pkg/db"
 	"gala/internal/ports"
+	"gala/internal/store"
 	"gala/internal/worker/util"
 )
 
+// Subida de outputs: cuántos a la vez y cuántos intentos por objeto
+// (1s, 2s, 4s... entre intentos) si no se configura otra cosa.
+const (
+	DefaultUploadConcurrency = 4
+	DefaultUploadAttempts    = 3
+)
+
 type OutputHandler struct {
 	pool         *pgxpool.Pool
 	sp           ports.StorageProvider
 	storageRoot  string
-	cleanupLocal bool
+	cleanupLocal *atomic.Bool
+	concurrency  int
+	attempts     int
+	// sleep espera entre intentos; los tests la reemplazan.
+	sleep func(context.Context, time.Duration) error
 }
 
-func NewOutputHandler(pool *pgxpool.Pool, sp ports.StorageProvider, storageRoot string, cleanupLocal bool) *OutputHandler {
+func NewOutputHandler(pool *pgxpool.Pool, sp ports.StorageProvider, storageRoot string, cleanupLocal *atomic.Bool) *OutputHandler {
 	return &OutputHandler{
 		pool:         pool,
 		sp:           sp,
 		storageRoot:  storageRoot,
 		cleanupLocal: cleanupLocal,
+		concurrency:  DefaultUploadConcurrency,
+		attempts:     DefaultUploadAttempts,
+		sleep:        sleepCtx,
 	}
 }
 
+// WithUploads limita cuántos outputs se suben a la vez y cuántas veces se
+// intenta cada uno; <= 0 deja los defaults.
+func (oh *OutputHandler) WithUploads(concurrency, attempts int) *OutputHandler {
+	if concurrency > 0 {
+		oh.concurrency = concurrency
+	}
+	if attempts > 0 {
+		oh.attempts = attempts
+	}
+	return oh
+}
+
 type RegisterOutputsRequest struct {
 	JobID           string
 	OutputKeys      *OutputKeys
 	UsedV1          bool
 	CaptionsEnabled bool
+	// HLSKeys son los archivos del empaquetado HLS (playlist primero);
+	// vacío si el paso no está activo o falló.
+	HLSKeys []string
+	// VideoMedia es lo que midió la verificación de formato del video; se
+	// guarda en su asset (nil si no se midió).
+	VideoMedia *store.AssetMedia
+	// Report, si no es nil, recibe el resultado de cada subida al
+	// terminar; se llama desde varias goroutines.
+	Report func(UploadStatus)
+}
+
+// UploadStatus es el resultado de la subida de un output.
+type UploadStatus struct {
+	// Output es "video", "thumbnail", "captions" o "hls/<archivo>".
+	Output    string
+	ObjectKey string
+	// AssetID es el asset que se registrará; vacío si la subida falló.
+	AssetID  string
+	Attempts int
+	Err      error
 }
 
 type OutputResult struct {
@@ -40,93 +98,251 @@ type OutputResult struct {
 	VideoAssetID    string
 	ThumbAssetID    string
 	CaptionsAssetID string
+
+	// assets subidos, pendientes de registrar en DB
+	assets []uploadedAsset
+	// archivos HLS del video, pendientes de registrar en DB
+	hls []store.HLSFile
+}
+
+type uploadedAsset struct {
+	id        string
+	kind      string
+	mime      string
+	objectKey string
+	size      int64
+	checksum  string
+	media     *store.AssetMedia
 }
 
-// RegisterOutputs sube y registra todos los outputs generados
-func (oh *OutputHandler) RegisterOutputs(ctx context.Context, req RegisterOutputsRequest) (*OutputResult, error) {
+// UploadOutputs sube todos los outputs generados al storage. No toca la DB:
+// los assets se registran con RegisterOutputs, dentro de la transacción que
+// cierra el job. Sube varios outputs a la vez y reintenta cada uno; si
+// alguno falla, borra los que sí subieron.
+func (oh *OutputHandler) UploadOutputs(ctx context.Context, req RegisterOutputsRequest) (_ *OutputResult, err error) {
 	result := &OutputResult{
 		OutputID: util.NewID("out"),
 	}
+	defer func() {
+		if err != nil {
+			if derr := oh.DiscardOutputs(ctx, result); derr != nil {
+				err = fmt.Errorf("%w (%v)", err, derr)
+			}
+		}
+	}()
 
-	// Registrar video
-	videoAssetID, _, err := oh.registerAsset(ctx, "render_output", "video/mp4", req.OutputKeys.Video)
-	if err != nil {
-		return nil, fmt.Errorf("failed to register video: %w", err)
+	uploads := []pendingUpload{
+		{output: "video", kind: "render_output", mime: "video/mp4", key: req.OutputKeys.Video},
+		{output: "thumbnail", kind: "thumbnail", mime: "image/jpeg", key: req.OutputKeys.Thumb},
 	}
-	result.VideoAssetID = videoAssetID
-
-	// Registrar thumbnail
-	thumbAssetID, _, err := oh.registerAsset(ctx, "thumbnail", "image/jpeg", req.OutputKeys.Thumb)
-	if err != nil {
-		return nil, fmt.Errorf("failed to register thumbnail: %w", err)
+	if req.UsedV1 && req.CaptionsEnabled && req.OutputKeys.Captions != "" && oh.captionsFileExists(req.OutputKeys.Captions) {
+		uploads = append(uploads, pendingUpload{output: "captions", kind: "captions", mime: "text/vtt", key: req.OutputKeys.Captions})
+	}
+	for _, key := range req.HLSKeys {
+		u := pendingUpload{output: "hls/" + path.Base(key), kind: "hls_segment", mime: "video/mp2t", key: key}
+		if strings.HasSuffix(key, ".m3u8") {
+			u.kind, u.mime = "hls_playlist", "application/vnd.apple.mpegurl"
+		}
+		uploads = append(uploads, u)
 	}
-	result.ThumbAssetID = thumbAssetID
 
-	// Registrar captions si aplica
-	if req.UsedV1 && req.CaptionsEnabled && req.OutputKeys.Captions != "" {
-		if oh.captionsFileExists(req.OutputKeys.Captions) {
-			captionsAssetID, _, err := oh.registerAsset(ctx, "captions", "text/vtt", req.OutputKeys.Captions)
+	// Se suben todos aunque alguno falle, para reportar el estado de cada
+	// uno; los errores se devuelven juntos
+	assets := make([]uploadedAsset, len(uploads))
+	errs := make([]error, len(uploads))
+	var g errgroup.Group
+	g.SetLimit(oh.concurrency)
+	for i, u := range uploads {
+		g.Go(func() error {
+			a, attempts, err := oh.uploadWithRetry(ctx, u)
 			if err != nil {
-				return nil, fmt.Errorf("failed to register captions: %w", err)
+				errs[i] = fmt.Errorf("failed to upload %s: %w", u.output, err)
 			}
-			result.CaptionsAssetID = captionsAssetID
-		}
+			assets[i] = a
+			if req.Report != nil {
+				req.Report(UploadStatus{Output: u.output, ObjectKey: u.key, AssetID: a.id, Attempts: attempts, Err: err})
+			}
+			return nil
+		})
 	}
+	_ = g.Wait()
 
+	for i, u := range uploads {
+		if errs[i] != nil {
+			continue
+		}
+		a := assets[i]
+		if u.output == "video" {
+			a.media = req.VideoMedia
+		}
+		result.assets = append(result.assets, a)
+		switch u.output {
+		case "video":
+			result.VideoAssetID = a.id
+		case "thumbnail":
+			result.ThumbAssetID = a.id
+		case "captions":
+			result.CaptionsAssetID = a.id
+		default:
+			result.hls = append(result.hls, store.HLSFile{
+				VideoAssetID: result.VideoAssetID,
+				Name:         path.Base(u.key),
+				AssetID:      a.id,
+			})
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
+// pendingUpload es un output por subir.
+type pendingUpload struct {
+	output, kind, mime, key string
+}
+
+// uploadWithRetry sube un output, reintentando los fallos del storage. Un
+// archivo local que falta no se reintenta. Devuelve los intentos hechos.
+func (oh *OutputHandler) uploadWithRetry(ctx context.Context, u pendingUpload) (uploadedAsset, int, error) {
+	var err error
+	for i := 0; i < oh.attempts; i++ {
+		if i > 0 {
+			if serr := oh.sleep(ctx, time.Duration(1<<(i-1))*time.Second); serr != nil {
+				return uploadedAsset{}, i, err
+			}
+		}
+		var a uploadedAsset
+		a, err = oh.uploadAsset(ctx, u.kind, u.mime, u.key)
+		if err == nil {
+			return a, i + 1, nil
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			return uploadedAsset{}, i + 1, err
+		}
+	}
+	return uploadedAsset{}, oh.attempts, err
+}
+
+// RegisterOutputs registra en DB los assets subidos por UploadOutputs y la
+// fila de job_outputs que los une al job. Pasar una transacción (q) hace que
+// outputs y estado del job se guarden juntos o no se guarden.
+func (oh *OutputHandler) RegisterOutputs(ctx context.Context, q db.Querier, jobID string, result *OutputResult) error {
+	createdAt := time.Now().UTC()
+	for _, a := range result.assets {
+		err := store.InsertAsset(ctx, q, store.Asset{
+			ID:        a.id,
+			Kind:      a.kind,
+			Provider:  oh.sp.Provider(),
+			ObjectKey: a.objectKey,
+			Mime:      a.mime,
+			SizeBytes: a.size,
+			Checksum:  a.checksum,
+			Media:     a.media,
+			CreatedAt: createdAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register %s asset in DB: %w", a.kind, err)
+		}
+	}
+
+	err := store.InsertJobOutput(ctx, q, store.JobOutput{
+		ID:               result.OutputID,
+		JobID:            jobID,
+		Variant:          1,
+		VideoAssetID:     result.VideoAssetID,
+		ThumbnailAssetID: result.ThumbAssetID,
+		CaptionsAssetID:  result.CaptionsAssetID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save job output: %w", err)
+	}
+
+	for _, f := range result.hls {
+		if err := store.InsertHLSFile(ctx, q, f); err != nil {
+			return fmt.Errorf("failed to register hls file: %w", err)
+		}
+	}
+	return nil
+}
+
+// DiscardOutputs borra del storage los objetos subidos por UploadOutputs
+// que no llegan a registrarse: compensa la transacción de RegisterOutputs
+// cuando falla, para no dejar objetos sin asset.
+func (oh *OutputHandler) DiscardOutputs(ctx context.Context, result *OutputResult) error {
+	// El ctx del job puede estar cancelado (timeout); el borrado va igual
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+
+	var errs []error
+	for _, a := range result.assets {
+		if err := oh.sp.DeleteObject(ctx, a.objectKey); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete uploaded %s %s: %w", a.kind, a.objectKey, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (oh *OutputHandler) captionsFileExists(captionsKey string) bool {
 	localPath := filepath.Join(oh.storageRoot, captionsKey)
 	_, err := os.Stat(localPath)
 	return err == nil
 }
 
-func (oh *OutputHandler) registerAsset(ctx context.Context, kind, mime, objectKey string) (assetID string, size int64, err error) {
+func (oh *OutputHandler) uploadAsset(ctx context.Context, kind, mime, objectKey string) (uploadedAsset, error) {
 	// Obtener archivo local
 	localPath := filepath.Join(oh.storageRoot, objectKey)
 	st, err := os.Stat(localPath)
 	if err != nil {
-		return "", 0, fmt.Errorf("asset file not found: %w", err)
+		return uploadedAsset{}, fmt.Errorf("asset file not found: %w", err)
 	}
 
 	f, err := os.Open(localPath)
 	if err != nil {
-		return "", 0, fmt.Errorf("failed to open asset: %w", err)
+		return uploadedAsset{}, fmt.Errorf("failed to open asset: %w", err)
 	}
 	defer f.Close()
 
-	// Subir a storage
+	// Subir a storage, calculando el MD5 al vuelo para la auditoría de storage
+	sum := md5.New()
 	uploadResult, err := oh.sp.PutObject(ctx, ports.PutObjectInput{
 		ObjectKey:   objectKey,
 		ContentType: mime,
-		Reader:      f,
+		Reader:      io.TeeReader(f, sum),
 		Size:        st.Size(),
+		Kind:        kind,
 	})
 	if err != nil {
-		return "", 0, fmt.Errorf("failed to upload asset: %w", err)
-	}
-
-	// Registrar en DB
-	assetID = util.NewID("ast")
-	_, err = oh.pool.Exec(ctx,
-		`INSERT INTO assets (id, kind, provider, object_key, mime, size_bytes)
-		 VALUES ($1,$2,$3,$4,$5,$6)`,
-		assetID, kind, oh.sp.Provider(), uploadResult.ObjectKey, mime, uploadResult.Size,
-	)
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to register asset in DB: %w", err)
+		return uploadedAsset{}, fmt.Errorf("failed to upload asset: %w", err)
 	}
 
 	// Limpiar archivo local si corresponde
 	oh.maybeCleanupFile(objectKey)
 
-	return assetID, uploadResult.Size, nil
+	return uploadedAsset{
+		id:        util.NewID("ast"),
+		kind:      kind,
+		mime:      mime,
+		objectKey: uploadResult.ObjectKey,
+		size:      uploadResult.Size,
+		checksum:  hex.EncodeToString(sum.Sum(nil)),
+	}, nil
 }
 
 func (oh *OutputHandler) maybeCleanupFile(objectKey string) {
-	if !oh.cleanupLocal || oh.sp.Provider() != "gdrive" {
+	if !oh.cleanupLocal.Load() || oh.sp.Provider() != "gdrive" {
 		return
 	}
 	_ = os.Remove(filepath.Join(oh.storageRoot, objectKey))
 }
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gala/internal/testsupport"
+)
+
+// newStorage stores objects by fileId, as gdrive does.
+func newStorage() *testsupport.Storage {
+	return &testsupport.Storage{
+		Name:    "gdrive",
+		KeyFunc: func(key string) string { return "file_" + filepath.Base(key) },
+	}
+}
+
+// newUploadTest renders the outputs of job_1 under a temp root.
+func newUploadTest(t *testing.T, sp *testsupport.Storage) (*OutputHandler, *OutputKeys) {
+	t.Helper()
+	root := t.TempDir()
+	keys := GenerateOutputKeys("job_1", false)
+	for _, k := range []string{keys.Video, keys.Thumb} {
+		p := filepath.Join(root, k)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oh := NewOutputHandler(nil, sp, root, new(atomic.Bool))
+	oh.sleep = func(context.Context, time.Duration) error { return nil }
+	return oh, keys
+}
+
+func TestUploadOutputsRetries(t *testing.T) {
+	sp := newStorage()
+	oh, keys := newUploadTest(t, sp)
+	sp.Fail = testsupport.FailFirst(2, testsupport.OpPut, keys.Video)
+
+	var mu sync.Mutex
+	reports := map[string]UploadStatus{}
+	result, err := oh.UploadOutputs(context.Background(), RegisterOutputsRequest{
+		JobID:      "job_1",
+		OutputKeys: keys,
+		Report: func(st UploadStatus) {
+			mu.Lock()
+			defer mu.Unlock()
+			reports[st.Output] = st
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := reports["video"]; v.Attempts != 3 || v.Err != nil || v.AssetID != result.VideoAssetID {
+		t.Errorf("video report = %+v, want success on the third attempt", v)
+	}
+	if th := reports["thumbnail"]; th.Attempts != 1 || th.AssetID != result.ThumbAssetID {
+		t.Errorf("thumbnail report = %+v, want success on the first attempt", th)
+	}
+}
+
+func TestUploadOutputsDiscardsUploadsOnFailure(t *testing.T) {
+	sp := newStorage()
+	oh, keys := newUploadTest(t, sp)
+	sp.Fail = testsupport.FailFirst(-1, testsupport.OpPut, keys.Thumb)
+
+	_, err := oh.UploadOutputs(context.Background(), RegisterOutputsRequest{JobID: "job_1", OutputKeys: keys})
+	if err == nil {
+		t.Fatal("UploadOutputs() = nil, want the thumbnail upload error")
+	}
+	if keys := sp.Keys(); len(keys) != 0 {
+		t.Errorf("objects left in storage after a failed upload: %v", keys)
+	}
+
+	sp.Fail = nil
+	result, err := oh.UploadOutputs(context.Background(), RegisterOutputsRequest{JobID: "job_1", OutputKeys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := sp.Keys(); len(keys) != 2 {
+		t.Fatalf("objects = %v, want video and thumbnail", keys)
+	}
+	if err := oh.DiscardOutputs(context.Background(), result); err != nil || len(sp.Keys()) != 0 {
+		t.Errorf("DiscardOutputs() = %v, objects left %v", err, sp.Keys())
+	}
+}
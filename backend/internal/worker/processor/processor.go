@@ -2,15 +2,28 @@ package processor
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
+	stderrors "errors"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
 
+	"gala/internal/callback"
+	"gala/internal/events"
+	"gala/internal/inputs"
+	"gala/internal/pkg/db"
 	"gala/internal/pkg/errors"
+	"gala/internal/pkg/fetch"
 	"gala/internal/pkg/logger"
 	"gala/internal/ports"
+	"gala/internal/store"
+	"gala/internal/worker/queue"
 	"gala/internal/worker/renderer"
+	"gala/internal/worker/staging"
 )
 
 type Deps struct {
@@ -19,22 +32,78 @@ type Deps struct {
 	StorageRoot  string
 	CleanupLocal bool
 	SP           ports.StorageProvider
+	Events       *events.Bus
 	Log          *logger.Logger
+	// HLS configura el empaquetado HLS tras el render (opcional).
+	HLS HLSConfig
+	// FormatCheck configura la verificación del formato de los renders
+	// contra el del template (el Mode vacío es FormatCheckWarn).
+	FormatCheck FormatCheckConfig
+	// Fetch descarga los inputs dados por URL (nil usa los límites por
+	// defecto de fetch).
+	Fetch *fetch.Client
+	// Callbacks envía los callbacks de los jobs que los piden (nil usa la
+	// configuración por defecto de callback).
+	Callbacks *callback.Sender
+	// InputConcurrency limita las descargas de inputs simultáneas de un
+	// job (0 = DefaultInputConcurrency).
+	InputConcurrency int
+	// UploadConcurrency y UploadAttempts limitan las subidas de outputs
+	// simultáneas y los intentos de cada una (0 = DefaultUploadConcurrency
+	// y DefaultUploadAttempts).
+	UploadConcurrency int
+	UploadAttempts    int
+	// TemplateCacheTTL es cuánto se reusa lo leído de un template entre
+	// jobs (0 = sin cache). Ver WatchTemplates.
+	TemplateCacheTTL time.Duration
+	// Staging comprueba el espacio libre antes de materializar inputs y
+	// de renderizar, y protege el directorio del job mientras corre (nil
+	// no comprueba nada).
+	Staging *staging.Manager
+	// Semaphore limita los renders simultáneos de los templates con
+	// max_concurrency, entre todos los workers (nil no limita).
+	Semaphore *queue.Semaphore
+	// RDB recibe los pedidos de cancelación de los jobs en curso (nil no
+	// los atiende).
+	RDB redis.UniversalClient
+	// ProgressBaseURL es la URL base de la API a la que el renderer
+	// reporta el progreso de cada render (RENDERER_PROGRESS_BASEURL);
+	// vacía, el spec no lleva progress_url.
+	ProgressBaseURL string
+	// QueueMode es el modo de la cola de la que llegan los jobs (vacío es
+	// queue.ModeRedis). En queue.ModePostgres llegan ya RUNNING,
+	// reclamados por la cola; en Redis solo se corren los QUEUED.
+	QueueMode string
+	// JobTimeout limita cada job de punta a punta desde que tiene su slot
+	// (0 = sin límite); al vencer se abandona el render en curso.
+	JobTimeout time.Duration
 }
 
 type Processor struct {
 	pool         *pgxpool.Pool
 	renderer     renderer.Client
 	storageRoot  string
-	cleanupLocal bool
+	cleanupLocal *atomic.Bool // compartido con outputHandler y cleanup
 	sp           ports.StorageProvider
+	ev           *events.Bus
 	log          *logger.Logger
+	staging      *staging.Manager
+	sem          *queue.Semaphore
+	rdb          redis.UniversalClient
+	progressBase string
+	// claimed: los jobs llegan RUNNING (cola postgres)
+	claimed    bool
+	jobTimeout time.Duration
 
 	// Componentes internos
 	jobParser       *JobParser
 	inputHandler    *InputHandler
 	outputHandler   *OutputHandler
 	rendererAdapter *RendererAdapter
+	hlsPackager     *HLSPackager
+	formatChecker   *FormatChecker
+	importer        *inputs.Importer
+	callbackHandler *CallbackHandler
 	cleanup         *Cleanup
 }
 
@@ -49,34 +118,97 @@ func New(d Deps) *Processor {
 		pool:         d.Pool,
 		renderer:     d.Renderer,
 		storageRoot:  d.StorageRoot,
-		cleanupLocal: d.CleanupLocal,
+		cleanupLocal: new(atomic.Bool),
 		sp:           d.SP,
+		ev:           d.Events,
 		log:          log,
+		staging:      d.Staging,
+		sem:          d.Semaphore,
+		rdb:          d.RDB,
+		progressBase: strings.TrimRight(d.ProgressBaseURL, "/"),
+		claimed:      d.QueueMode == queue.ModePostgres,
+		jobTimeout:   d.JobTimeout,
 	}
+	p.cleanupLocal.Store(d.CleanupLocal)
 
 	// Inicializar componentes
-	p.jobParser = NewJobParser(d.Pool)
-	p.inputHandler = NewInputHandler(d.Pool, d.SP, d.StorageRoot)
-	p.outputHandler = NewOutputHandler(d.Pool, d.SP, d.StorageRoot, d.CleanupLocal)
+	p.jobParser = NewJobParser(d.Pool).WithTemplateCache(d.TemplateCacheTTL)
+	p.inputHandler = NewInputHandler(d.Pool, d.SP, d.StorageRoot).WithConcurrency(d.InputConcurrency)
+	p.outputHandler = NewOutputHandler(d.Pool, d.SP, d.StorageRoot, p.cleanupLocal).WithUploads(d.UploadConcurrency, d.UploadAttempts)
 	p.rendererAdapter = NewRendererAdapter(d.Renderer)
-	p.cleanup = NewCleanup(d.StorageRoot, d.CleanupLocal, d.SP)
+	p.hlsPackager = NewHLSPackager(d.HLS, d.StorageRoot)
+	p.formatChecker = NewFormatChecker(d.FormatCheck)
+	fc := d.Fetch
+	if fc == nil {
+		fc = fetch.New(fetch.Config{})
+	}
+	p.importer = inputs.NewImporter(d.Pool, d.SP, d.Events, fc)
+	p.callbackHandler = NewCallbackHandler(d.Pool, d.Callbacks, log)
+	p.cleanup = NewCleanup(d.StorageRoot, p.cleanupLocal, d.SP)
 
 	return p
 }
 
+// SetCleanupLocal activa o desactiva la limpieza del staging local; rige
+// desde el próximo paso de outputs o de limpieza.
+func (p *Processor) SetCleanupLocal(v bool) {
+	p.cleanupLocal.Store(v)
+}
+
+// Close espera los callbacks que se están enviando hasta que ctx termina
+// y corta los que queden (ver CallbackHandler.Close).
+func (p *Processor) Close(ctx context.Context) {
+	p.callbackHandler.Close(ctx)
+}
+
+// WatchTemplates invalida el cache de templates con los eventos de cambio
+// de templates hasta que ctx termina; sin cache o sin eventos no hace nada
+// y los cambios se ven al vencer el TTL.
+func (p *Processor) WatchTemplates(ctx context.Context) {
+	if p.jobParser.cache == nil || p.ev == nil {
+		return
+	}
+	p.jobParser.cache.watch(ctx, p.ev, p.log)
+}
+
+// ErrJobCanceled es lo que devuelve ProcessJob cuando el job se canceló
+// mientras corría.
+var ErrJobCanceled = stderrors.New("job canceled")
+
+// ErrSlotBusy es lo que devuelve ProcessJob cuando el template del job
+// tiene todos sus slots ocupados: el job no empezó y sigue QUEUED, y quien
+// lo sacó de la cola lo devuelve para más tarde (queue.Queue.Defer).
+var ErrSlotBusy = stderrors.New("template concurrency slots busy")
+
 // ProcessJob orquesta el flujo completo del job
 func (p *Processor) ProcessJob(ctx context.Context, jobID string) error {
 	log := p.log.FromContext(ctx).WithJobID(jobID)
 
 	// 1. Obtener y parsear el job
-	log.Debug("fetching job params")
-	paramsJSON, err := p.fetchJobParams(ctx, jobID)
+	log.Debug("fetching job")
+	job, err := store.GetJob(ctx, p.pool, jobID)
 	if err != nil {
 		return p.failJob(ctx, jobID, errors.Wrap(err, "processor.fetch", "failed to fetch job params"))
 	}
+	// El job corre bajo el request ID de quien lo creó (o su ID, en los
+	// jobs anteriores a la migración 015): va en los logs y en las
+	// llamadas al renderer y al storage, para correlacionarlas.
+	requestID := job.RequestID
+	if requestID == "" {
+		requestID = jobID
+	}
+	ctx = logger.ContextWithRequestID(ctx, requestID)
+	log = log.WithRequestID(requestID)
+	// Cancelado (o ya terminado) desde que se encoló: nada que hacer. En
+	// Redis un job RUNNING lo corre otro worker: su id estaba dos veces
+	// en la lista
+	if job.Status != store.JobQueued && (job.Status != store.JobRunning || !p.claimed) {
+		log.Info("skipping job", "status", job.Status)
+		return nil
+	}
 
 	log.Debug("parsing job params")
-	parsedJob, err := p.jobParser.Parse(ctx, paramsJSON)
+	parsedJob, err := p.jobParser.Parse(ctx, job.ParamsJSON)
 	if err != nil {
 		return p.failJob(ctx, jobID, errors.WrapWithCode(err, errors.CodeValidation, "processor.parse", "failed to parse job params"))
 	}
@@ -90,11 +222,57 @@ func (p *Processor) ProcessJob(ctx context.Context, jobID string) error {
 		log.Debug("v1 job validated", "template_id", parsedJob.TemplateID)
 	}
 
+	// 1b. Tomar un slot del template si limita sus renders simultáneos;
+	// sin slot libre el job vuelve a la cola
+	release, err := p.acquireSlot(ctx, log, jobID, parsedJob)
+	if stderrors.Is(err, ErrSlotBusy) {
+		return err
+	}
+	if err != nil {
+		return p.failJob(ctx, jobID, err)
+	}
+	defer release()
+
+	// El timeout del job corre desde que tiene su slot
+	if p.jobTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.jobTimeout)
+		defer cancel()
+	}
+
 	// 2. Marcar como running
 	log.Debug("marking job as running")
-	if err := p.markJobRunning(ctx, jobID); err != nil {
+	paramsJSON, running, err := store.MarkJobRunning(ctx, p.pool, jobID, p.claimed)
+	if err != nil {
 		return p.failJob(ctx, jobID, errors.Wrap(err, "processor.status", "failed to mark job as running"))
 	}
+	if !running {
+		log.Info("skipping job", "reason", "canceled or started by another worker")
+		return nil
+	}
+	p.ev.Publish(ctx, events.JobRunning, jobID, map[string]any{"status": store.JobRunning})
+	p.progress(ctx, jobID, store.JobStageParse)
+
+	// Un admin editó los params mientras estaba QUEUED: se corre con los
+	// nuevos
+	if paramsJSON != job.ParamsJSON {
+		log.Info("job params edited before it started, parsing them again")
+		job.ParamsJSON = paramsJSON
+		if parsedJob, err = p.jobParser.Parse(ctx, job.ParamsJSON); err != nil {
+			return p.failJob(ctx, jobID, errors.WrapWithCode(err, errors.CodeValidation, "processor.parse", "failed to parse job params"))
+		}
+	}
+	defer p.staging.Acquire(jobID)()
+
+	// Un pedido de cancelación cancela ctx, lo que corta la etapa en curso
+	// (el render, una subida); entre etapas además se mira la key, por si
+	// el aviso se perdió
+	ctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	defer p.watchCancel(ctx, jobID, abort)()
+	if err := p.checkCanceled(ctx, jobID); err != nil {
+		return p.failJob(ctx, jobID, err)
+	}
 
 	// 3. Preparar keys de salida
 	outputKeys := GenerateOutputKeys(jobID, parsedJob.CaptionsEnabled())
@@ -104,6 +282,19 @@ func (p *Processor) ProcessJob(ctx context.Context, jobID string) error {
 		"captions", outputKeys.Captions,
 	)
 
+	// 4a. Importar los inputs dados por URL como assets
+	p.progress(ctx, jobID, store.JobStageInputs)
+	if err := p.ensureDisk("materialize inputs"); err != nil {
+		return p.failJob(ctx, jobID, err)
+	}
+	if err := p.importURLInputs(ctx, jobID, job.ParamsJSON, parsedJob.Inputs); err != nil {
+		return p.failJob(ctx, jobID, err)
+	}
+
+	if err := p.checkCanceled(ctx, jobID); err != nil {
+		return p.failJob(ctx, jobID, err)
+	}
+
 	// 4. Procesar inputs si es necesario
 	var inputPaths map[string]string
 	if parsedJob.NeedsInputMaterialization() {
@@ -115,102 +306,313 @@ func (p *Processor) ProcessJob(ctx context.Context, jobID string) error {
 		log.Debug("inputs materialized", "count", len(inputPaths))
 	}
 
+	// 4b. Materializar el watermark, si el job lleva
+	var watermarkPath string
+	if wm := parsedJob.Watermark; wm != nil {
+		paths, err := p.inputHandler.Materialize(ctx, jobID, map[string]string{"watermark": wm.AssetID})
+		if err != nil {
+			return p.failJob(ctx, jobID, errors.Wrap(err, "processor.watermark", "failed to materialize watermark"))
+		}
+		watermarkPath = paths["watermark"]
+		log.Debug("watermark materialized", "asset_id", wm.AssetID, "position", wm.Position)
+	}
+
 	// 5. Renderizar
+	if err := p.checkCanceled(ctx, jobID); err != nil {
+		return p.failJob(ctx, jobID, err)
+	}
+	if err := p.ensureDisk("render"); err != nil {
+		return p.failJob(ctx, jobID, err)
+	}
+	p.progress(ctx, jobID, store.JobStageRender)
 	log.Info("starting render",
 		"v1", parsedJob.UsedV1(),
 		"captions", parsedJob.CaptionsEnabled(),
 	)
-	err = p.rendererAdapter.Render(ctx, RenderRequest{
-		JobID:      jobID,
-		ParsedJob:  parsedJob,
-		InputPaths: inputPaths,
-		OutputKeys: outputKeys,
+	spec := p.rendererAdapter.Spec(RenderRequest{
+		JobID:         jobID,
+		ParsedJob:     parsedJob,
+		InputPaths:    inputPaths,
+		OutputKeys:    outputKeys,
+		WatermarkPath: watermarkPath,
+		ProgressURL:   p.progressURL(jobID),
 	})
+	// Guardar el spec para poder repetir el render; no es motivo para
+	// fallar el job
+	if err := p.saveRenderSpec(ctx, jobID, spec); err != nil {
+		log.Warn("failed to store render spec", "error", err.Error())
+	}
+	err = p.rendererAdapter.Render(ctx, spec)
 	if err != nil {
+		if ctx.Err() != nil {
+			// Job cancelado o vencido: se cortó la conexión con el renderer,
+			// que deja de trabajar en este job (ver renderer.Client).
+			return p.failJob(ctx, jobID, errors.WrapWithCode(err, errors.CodeTimeout, "processor.render", "render abandoned"))
+		}
 		return p.failJob(ctx, jobID, errors.Wrap(err, "processor.render", "render failed"))
 	}
 	log.Debug("render completed")
 
-	// 6. Registrar outputs
-	log.Debug("registering outputs")
-	outputResult, err := p.outputHandler.RegisterOutputs(ctx, RegisterOutputsRequest{
+	// 5a. Verificar el formato del render contra el del template; lo
+	// medido queda en el asset del video
+	var videoMedia *store.AssetMedia
+	if p.formatChecker.Enabled() {
+		videoMedia, err = p.checkFormat(ctx, log, parsedJob.Format, outputKeys.Video)
+		if err != nil {
+			return p.failJob(ctx, jobID, err)
+		}
+	}
+
+	// 5b. Empaquetar HLS (opcional). Es solo para la vista previa en el
+	// navegador: si falla, el job termina igual con su mp4
+	var hlsKeys []string
+	if p.hlsPackager.Enabled() {
+		p.progress(ctx, jobID, store.JobStageHLS)
+		hlsKeys, err = p.hlsPackager.Package(ctx, jobID, outputKeys.Video)
+		if err != nil {
+			log.Warn("hls packaging failed, skipping", "error", err.Error())
+			hlsKeys = nil
+		} else {
+			log.Debug("hls packaged", "files", len(hlsKeys))
+		}
+	}
+
+	// 6. Subir outputs
+	if err := p.checkCanceled(ctx, jobID); err != nil {
+		return p.failJob(ctx, jobID, err)
+	}
+	p.progress(ctx, jobID, store.JobStageUpload)
+	log.Debug("uploading outputs")
+	outputResult, err := p.outputHandler.UploadOutputs(ctx, RegisterOutputsRequest{
 		JobID:           jobID,
 		OutputKeys:      outputKeys,
 		UsedV1:          parsedJob.UsedV1(),
 		CaptionsEnabled: parsedJob.CaptionsEnabled(),
+		HLSKeys:         hlsKeys,
+		VideoMedia:      videoMedia,
+		Report: func(st UploadStatus) {
+			data := map[string]any{"output": st.Output, "status": "uploaded", "attempts": st.Attempts}
+			if st.Err != nil {
+				data["status"], data["error"] = "failed", st.Err.Error()
+			} else {
+				data["asset_id"] = st.AssetID
+			}
+			p.ev.Publish(ctx, events.JobOutput, jobID, data)
+		},
 	})
 	if err != nil {
-		return p.failJob(ctx, jobID, errors.Wrap(err, "processor.outputs", "failed to register outputs"))
+		return p.failJob(ctx, jobID, errors.Wrap(err, "processor.outputs", "failed to upload outputs"))
 	}
-	log.Debug("outputs registered",
+	log.Debug("outputs uploaded",
 		"video_asset", outputResult.VideoAssetID,
 		"thumb_asset", outputResult.ThumbAssetID,
 	)
 
-	// 7. Guardar resultado en DB
+	// 7. Registrar outputs y marcar como completado, en una transacción:
+	// un job DONE siempre tiene sus outputs
+	p.progress(ctx, jobID, store.JobStageRegister)
 	log.Debug("saving job output")
-	if err := p.saveJobOutput(ctx, jobID, outputResult); err != nil {
+	err = db.WithTx(ctx, p.pool, func(tx pgx.Tx) error {
+		if err := p.outputHandler.RegisterOutputs(ctx, tx, jobID, outputResult); err != nil {
+			return err
+		}
+		return p.markJobDone(ctx, tx, jobID)
+	})
+	if err != nil {
+		// Nada quedó registrado: los objetos subidos sobran
+		if derr := p.outputHandler.DiscardOutputs(ctx, outputResult); derr != nil {
+			log.Warn("failed to discard uploaded outputs", "error", derr.Error())
+		}
+		if stderrors.Is(err, errNotRunning) {
+			log.Warn("job left RUNNING before its outputs were saved, discarding them")
+			return nil
+		}
 		return p.failJob(ctx, jobID, errors.Wrap(err, "processor.save", "failed to save job output"))
 	}
+	for _, a := range outputResult.assets {
+		p.ev.Publish(ctx, events.AssetCreated, a.id, map[string]any{"kind": a.kind, "mime": a.mime, "job_id": jobID})
+	}
+	p.ev.Publish(ctx, events.JobDone, jobID, map[string]any{"status": store.JobDone})
+	p.callbackHandler.Notify(ctx, jobID)
 
 	// 8. Limpiar archivos temporales
-	p.cleanup.CleanupJob(jobID)
-	log.Debug("cleanup completed")
+	if err := p.cleanup.CleanupJob(jobID); err != nil {
+		// El job ya está DONE; lo que quede lo desaloja el barrido del staging
+		log.Warn("cleanup failed", "error", err.Error())
+	} else {
+		log.Debug("cleanup completed")
+	}
 
-	// 9. Marcar como completado
-	return p.markJobDone(ctx, jobID)
+	return nil
 }
 
-func (p *Processor) fetchJobParams(ctx context.Context, jobID string) (string, error) {
-	var paramsJSON string
-	err := p.pool.QueryRow(ctx,
-		`SELECT params_json FROM jobs WHERE id=$1`,
-		jobID,
-	).Scan(&paramsJSON)
+// progress registra la etapa en la que entra el job, con su progreso
+// (store.StageProgress), y la publica (events.JobProgress). Es solo
+// informativo: si no se puede guardar, el job sigue.
+func (p *Processor) progress(ctx context.Context, jobID, stage string) {
+	if _, err := store.SetJobStage(ctx, p.pool, jobID, stage); err != nil {
+		p.log.FromContext(ctx).WithJobID(jobID).Warn("failed to save job progress", "stage", stage, "error", err.Error())
+	}
+	p.ev.Publish(ctx, events.JobProgress, jobID, map[string]any{
+		"status":   store.JobRunning,
+		"stage":    stage,
+		"progress": store.StageProgress(stage),
+	})
+}
+
+// progressURL es el progress_url del spec del job; vacío sin
+// ProgressBaseURL.
+func (p *Processor) progressURL(jobID string) string {
+	if p.progressBase == "" {
+		return ""
+	}
+	return p.progressBase + "/v1/jobs/" + jobID + "/progress"
+}
+
+// importURLInputs descarga los inputs que son URLs, los registra como
+// assets y los sustituye en inputs por sus asset IDs. El params_json del
+// job se reescribe con los IDs (las URLs quedan en "input_urls"), para que
+// un reintento no vuelva a descargarlas y el GC vea los assets en uso.
+func (p *Processor) importURLInputs(ctx context.Context, jobID, paramsJSON string, in map[string]string) error {
+	urls := map[string]string{}
+	for name, v := range in {
+		if inputs.IsURL(v) {
+			urls[name] = v
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	log := p.log.FromContext(ctx).WithJobID(jobID)
+	for name, u := range urls {
+		a, err := p.importer.Import(ctx, u)
+		if err != nil {
+			var fe *inputs.FetchError
+			if errors.As(err, &fe) {
+				return errors.WrapWithCode(err, errors.CodeValidation, "processor.inputs", "input "+name+" could not be downloaded")
+			}
+			return errors.Wrap(err, "processor.inputs", "failed to import input "+name)
+		}
+		in[name] = a.ID
+		log.Debug("input imported", "input", name, "asset_id", a.ID, "bytes", a.SizeBytes)
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(paramsJSON), &raw); err != nil {
+		return errors.Wrap(err, "processor.inputs", "failed to decode job params")
+	}
+	raw["inputs"] = in
+	raw["input_urls"] = urls
+	b, err := json.Marshal(raw)
 	if err != nil {
-		return "", fmt.Errorf("job not found: %w", err)
+		return errors.Wrap(err, "processor.inputs", "failed to encode job params")
+	}
+	if err := store.SetJobParams(ctx, p.pool, jobID, string(b)); err != nil {
+		return errors.Wrap(err, "processor.inputs", "failed to store imported inputs")
 	}
-	return paramsJSON, nil
+	return nil
 }
 
-func (p *Processor) markJobRunning(ctx context.Context, jobID string) error {
-	_, err := p.pool.Exec(ctx,
-		`UPDATE jobs SET status='RUNNING', started_at=NOW(), finished_at=NULL, error_text=NULL WHERE id=$1`,
-		jobID,
-	)
-	return err
+// ensureDisk falla con RESOURCE_EXHAUSTED si el staging no tiene el
+// espacio libre mínimo ni lo recupera desalojando jobs viejos.
+func (p *Processor) ensureDisk(step string) error {
+	if err := p.staging.Ensure(); err != nil {
+		return errors.WrapWithCode(err, errors.CodeResourceExhaust, "processor.disk", "not enough disk space to "+step)
+	}
+	return nil
 }
 
-func (p *Processor) markJobDone(ctx context.Context, jobID string) error {
-	_, err := p.pool.Exec(ctx,
-		`UPDATE jobs SET status='DONE', finished_at=NOW() WHERE id=$1`,
-		jobID,
-	)
-	return err
-}
-
-func (p *Processor) saveJobOutput(ctx context.Context, jobID string, result *OutputResult) error {
-	_, err := p.pool.Exec(ctx,
-		`INSERT INTO job_outputs (id, job_id, variant, video_asset_id, thumbnail_asset_id, captions_asset_id)
-         VALUES ($1,$2,1,$3,$4,$5)`,
-		result.OutputID,
-		jobID,
-		result.VideoAssetID,
-		result.ThumbAssetID,
-		NullIfEmpty(result.CaptionsAssetID),
-	)
-	return err
+func (p *Processor) saveRenderSpec(ctx context.Context, jobID string, spec RenderSpec) error {
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	return store.SetJobRenderSpec(ctx, p.pool, jobID, raw)
+}
+
+// errNotRunning: el job dejó de estar RUNNING antes de quedar DONE (lo
+// cosechó el reaper o se canceló); su estado no se toca.
+var errNotRunning = stderrors.New("job is no longer running")
+
+func (p *Processor) markJobDone(ctx context.Context, q db.Querier, jobID string) error {
+	ok, err := store.MarkJobDone(ctx, q, jobID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errNotRunning
+	}
+	return nil
+}
+
+// acquireSlot toma un slot libre del template del job (su
+// max_concurrency); la función devuelta lo libera. Sin límite o sin
+// semáforo no hace nada. Si están todos ocupados devuelve ErrSlotBusy sin
+// esperar, así el worker sigue con jobs de otros templates.
+func (p *Processor) acquireSlot(ctx context.Context, log *logger.Logger, jobID string, j *ParsedJob) (release func(), err error) {
+	if p.sem == nil || j.MaxConcurrency <= 0 {
+		return func() {}, nil
+	}
+	name := "template:" + j.TemplateID
+	ok, err := p.sem.TryAcquire(ctx, name, j.MaxConcurrency, jobID)
+	if err != nil {
+		return nil, errors.Wrap(err, "processor.slot", "failed to acquire a template concurrency slot")
+	}
+	if !ok {
+		log.Info("template concurrency slots busy, returning the job to the queue",
+			"template_id", j.TemplateID, "max_concurrency", j.MaxConcurrency)
+		return nil, ErrSlotBusy
+	}
+	return p.sem.Hold(ctx, name, jobID), nil
+}
+
+// watchCancel cancela ctx con ErrJobCanceled (vía abort) cuando llega un
+// pedido de cancelación del job, hasta que se llama a la función
+// devuelta, que además borra el pedido.
+func (p *Processor) watchCancel(ctx context.Context, jobID string, abort context.CancelCauseFunc) (stop func()) {
+	if p.rdb == nil {
+		return func() {}
+	}
+	unwatch := queue.WatchCancel(ctx, p.rdb, jobID, func() { abort(ErrJobCanceled) })
+	return func() {
+		unwatch()
+		dctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		_ = queue.ClearCancel(dctx, p.rdb, jobID)
+	}
+}
+
+// checkCanceled devuelve ErrJobCanceled si se pidió cancelar el job: por
+// el aviso, que ya canceló ctx, o por la key del pedido. Si Redis no
+// responde sigue.
+func (p *Processor) checkCanceled(ctx context.Context, jobID string) error {
+	if stderrors.Is(context.Cause(ctx), ErrJobCanceled) {
+		return ErrJobCanceled
+	}
+	if p.rdb == nil {
+		return nil
+	}
+	if ok, err := queue.CancelRequested(ctx, p.rdb, jobID); err == nil && ok {
+		return ErrJobCanceled
+	}
+	return nil
 }
 
 func (p *Processor) failJob(ctx context.Context, jobID string, cause error) error {
 	log := p.log.FromContext(ctx).WithJobID(jobID)
 
+	// Cancelado mientras corría: la falla de la etapa es por el corte, el
+	// job queda CANCELED
+	if stderrors.Is(cause, ErrJobCanceled) || stderrors.Is(context.Cause(ctx), ErrJobCanceled) {
+		return p.cancelJob(ctx, jobID)
+	}
+
 	msg := ""
+	detail := store.JobError{Code: string(errors.CodeInternal)}
 	if cause != nil {
-		msg = cause.Error()
-		if len(msg) > 2000 {
-			msg = msg[:2000]
-		}
+		msg = truncate(cause.Error(), 2000)
+		detail = jobError(cause)
 
 		// Log with error details
 		var galaErr *errors.Error
@@ -225,10 +627,64 @@ func (p *Processor) failJob(ctx context.Context, jobID string, cause error) erro
 		}
 	}
 
-	_, _ = p.pool.Exec(ctx,
-		`UPDATE jobs SET status='FAILED', finished_at=NOW(), error_text=$2 WHERE id=$1`,
-		jobID, msg,
-	)
+	// El ctx del job puede estar ya cancelado (shutdown / timeout); la
+	// falla se registra igual.
+	dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	if err := store.MarkJobFailed(dbCtx, p.pool, jobID, msg, detail); err == nil {
+		p.ev.Publish(dbCtx, events.JobFailed, jobID, map[string]any{"status": store.JobFailed, "error": msg})
+		p.callbackHandler.Notify(ctx, jobID)
+	}
 
 	return cause
 }
+
+// cancelJob deja CANCELED un job que se detuvo por un pedido de
+// cancelación. No manda callback (solo van los de DONE y FAILED) y, como
+// en una falla, lo que quede en el staging lo desaloja el barrido.
+func (p *Processor) cancelJob(ctx context.Context, jobID string) error {
+	log := p.log.FromContext(ctx).WithJobID(jobID)
+	dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	ok, err := store.MarkJobCanceled(dbCtx, p.pool, jobID)
+	if err != nil {
+		log.Error("failed to mark job as canceled", "error", err.Error())
+		return ErrJobCanceled
+	}
+	if ok {
+		log.Info("job canceled while running")
+		p.ev.Publish(dbCtx, events.JobCanceled, jobID, map[string]any{"status": store.JobCanceled})
+	}
+	return ErrJobCanceled
+}
+
+// jobError es la forma estructurada de la falla de un job: código,
+// operación y retryable salen del *errors.Error más externo; el mensaje es
+// el suyo con sus causas, sin el prefijo de op y código de Error().
+func jobError(cause error) store.JobError {
+	code := errors.GetCode(cause)
+	detail := store.JobError{
+		Code:      string(code),
+		Message:   cause.Error(),
+		Retryable: errors.IsRetryable(cause),
+	}
+	var galaErr *errors.Error
+	if errors.As(cause, &galaErr) {
+		detail.Op = galaErr.Op
+		detail.Message = galaErr.Message
+		if galaErr.Err != nil {
+			detail.Message += ": " + galaErr.Err.Error()
+		}
+	}
+	detail.Message = truncate(detail.Message, 2000)
+	return detail
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
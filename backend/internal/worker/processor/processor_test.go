@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"gala/internal/pkg/errors"
+	"gala/internal/store"
+)
+
+func TestJobError(t *testing.T) {
+	tests := []struct {
+		cause error
+		want  store.JobError
+	}{
+		{
+			cause: errors.Wrap(fmt.Errorf("connection reset"), "processor.inputs", "failed to materialize inputs"),
+			want:  store.JobError{Code: "INTERNAL_ERROR", Op: "processor.inputs", Message: "failed to materialize inputs: connection reset"},
+		},
+		{
+			cause: errors.WrapWithCode(fmt.Errorf("deadline exceeded"), errors.CodeTimeout, "processor.render", "render abandoned"),
+			want:  store.JobError{Code: "TIMEOUT", Op: "processor.render", Message: "render abandoned: deadline exceeded", Retryable: true},
+		},
+		{
+			cause: fmt.Errorf("plain failure"),
+			want:  store.JobError{Code: "INTERNAL_ERROR", Message: "plain failure"},
+		},
+	}
+	for _, tt := range tests {
+		if got := jobError(tt.cause); got != tt.want {
+			t.Errorf("jobError(%v) = %+v, want %+v", tt.cause, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCanceled(t *testing.T) {
+	p := &Processor{}
+	if err := p.checkCanceled(context.Background(), "job_1"); err != nil {
+		t.Errorf("checkCanceled() without a request = %v, want nil", err)
+	}
+
+	ctx, abort := context.WithCancelCause(context.Background())
+	abort(ErrJobCanceled)
+	if err := p.checkCanceled(ctx, "job_1"); !stderrors.Is(err, ErrJobCanceled) {
+		t.Errorf("checkCanceled() after a cancel request = %v, want ErrJobCanceled", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.checkCanceled(ctx, "job_1"); err != nil {
+		t.Errorf("checkCanceled() with a plain canceled ctx = %v, want nil", err)
+	}
+}
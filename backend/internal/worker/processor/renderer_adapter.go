@@ -7,6 +7,12 @@ import (
 	"gala/internal/worker/renderer"
 )
 
+// Versiones del endpoint del renderer
+const (
+	RenderV0 = "v0"
+	RenderV1 = "v1"
+)
+
 type RendererAdapter struct {
 	client renderer.Client
 }
@@ -20,17 +26,44 @@ type RenderRequest struct {
 	ParsedJob  *ParsedJob
 	InputPaths map[string]string
 	OutputKeys *OutputKeys
+	// WatermarkPath es la imagen del watermark materializada; vacío sin
+	// watermark.
+	WatermarkPath string
+	// ProgressURL es donde el renderer reporta el progreso del render
+	// (POST /v1/jobs/{jobId}/progress de la API); vacío no lo pide.
+	ProgressURL string
+}
+
+// RenderSpec es lo que recibe el renderer para un job: el spec y la
+// versión del endpoint. Se guarda con el job (jobs.render_spec) para poder
+// repetir el render.
+type RenderSpec struct {
+	Version string `json:"version"`
+	Spec    any    `json:"spec"`
 }
 
-// Render adapta entre v0 y v1 del renderer según el tipo de job
-func (ra *RendererAdapter) Render(ctx context.Context, req RenderRequest) error {
+// Spec arma el spec de v0 o v1 según el tipo de job
+func (ra *RendererAdapter) Spec(req RenderRequest) RenderSpec {
 	if req.ParsedJob.UsedV1() {
-		return ra.renderV1(req)
+		return RenderSpec{Version: RenderV1, Spec: specV1(req)}
 	}
-	return ra.renderV0(req)
+	return RenderSpec{Version: RenderV0, Spec: specV0(req)}
 }
 
-func (ra *RendererAdapter) renderV1(req RenderRequest) error {
+// Render envía el spec al endpoint de su versión
+func (ra *RendererAdapter) Render(ctx context.Context, spec RenderSpec) error {
+	return RenderWith(ctx, ra.client, spec)
+}
+
+// RenderWith envía el spec al endpoint de su versión usando client
+func RenderWith(ctx context.Context, client renderer.Client, spec RenderSpec) error {
+	if spec.Version == RenderV1 {
+		return client.RenderV1(ctx, spec.Spec)
+	}
+	return client.Render(ctx, spec.Spec)
+}
+
+func specV1(req RenderRequest) map[string]any {
 	outBlock := map[string]any{
 		"video_object_key": req.OutputKeys.Video,
 		"thumb_object_key": req.OutputKeys.Thumb,
@@ -40,24 +73,38 @@ func (ra *RendererAdapter) renderV1(req RenderRequest) error {
 		outBlock["captions_object_key"] = req.OutputKeys.Captions
 	}
 
-	specV1 := map[string]any{
+	spec := map[string]any{
 		"job_id":      req.JobID,
 		"template_id": req.ParsedJob.TemplateID,
 		"inputs":      req.InputPaths,
 		"params":      req.ParsedJob.MergedParams,
 		"output":      outBlock,
 	}
+	if req.ProgressURL != "" {
+		spec["progress_url"] = req.ProgressURL
+	}
 
-	return ra.client.RenderV1(specV1)
+	// Watermark: ruta local de la imagen, como los inputs. asset_id no lo
+	// usa el renderer; permite volver a materializarla en un replay
+	if wm := req.ParsedJob.Watermark; wm != nil && req.WatermarkPath != "" {
+		spec["watermark"] = map[string]any{
+			"asset_id": wm.AssetID,
+			"path":     req.WatermarkPath,
+			"position": wm.Position,
+			"opacity":  *wm.Opacity,
+		}
+	}
+
+	return spec
 }
 
-func (ra *RendererAdapter) renderV0(req RenderRequest) error {
+func specV0(req RenderRequest) contracts.RendererSpec {
 	spec := contracts.RendererSpec{
 		JobID:  req.JobID,
 		Params: req.ParsedJob.MergedParams,
 	}
 	spec.Output.VideoObjectKey = req.OutputKeys.Video
 	spec.Output.ThumbObjectKey = req.OutputKeys.Thumb
-
-	return ra.client.Render(spec)
+	spec.ProgressURL = req.ProgressURL
+	return spec
 }
@@ -0,0 +1,89 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	contracts "gala/internal/contracts/renderer/v0"
+	"gala/internal/mockrenderer"
+	"gala/internal/testsupport"
+	"gala/internal/worker/renderer"
+)
+
+func TestRendererAdapterWritesOutputs(t *testing.T) {
+	tests := []struct {
+		name         string
+		job          *ParsedJob
+		wantCaptions bool
+	}{
+		{name: "v0", job: &ParsedJob{MergedParams: map[string]any{}}},
+		{name: "v1 with captions", job: &ParsedJob{
+			TemplateID:   "tpl_1",
+			HasEnvelope:  true,
+			MergedParams: map[string]any{"captions": true},
+		}, wantCaptions: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			ra := NewRendererAdapter(testsupport.NewRenderer(mockrenderer.Options{StorageRoot: root}))
+			keys := GenerateOutputKeys("job_1", tt.job.CaptionsEnabled())
+
+			spec := ra.Spec(RenderRequest{JobID: "job_1", ParsedJob: tt.job, OutputKeys: keys})
+			if err := ra.Render(context.Background(), spec); err != nil {
+				t.Fatal(err)
+			}
+			for _, k := range []string{keys.Video, keys.Thumb} {
+				if !exists(filepath.Join(root, k)) {
+					t.Errorf("output %s not written", k)
+				}
+			}
+			if tt.wantCaptions && !exists(filepath.Join(root, keys.Captions)) {
+				t.Errorf("captions %s not written", keys.Captions)
+			}
+		})
+	}
+}
+
+func TestRendererAdapterFailure(t *testing.T) {
+	root := t.TempDir()
+	rc := testsupport.NewRenderer(mockrenderer.Options{StorageRoot: root, FailJobs: []string{"job_1"}})
+	ra := NewRendererAdapter(rc)
+	keys := GenerateOutputKeys("job_1", false)
+
+	spec := ra.Spec(RenderRequest{JobID: "job_1", ParsedJob: &ParsedJob{}, OutputKeys: keys})
+	err := ra.Render(context.Background(), spec)
+	var se *renderer.StatusError
+	if !errors.As(err, &se) || se.Code != 500 {
+		t.Fatalf("Render() = %v, want a renderer 500", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, keys.Video)); err == nil {
+		t.Error("a failed render wrote its video")
+	}
+	if got := rc.Jobs(); len(got) != 1 || got[0] != "job_1" {
+		t.Errorf("Jobs() = %v, want [job_1]", got)
+	}
+}
+
+func TestRendererAdapterProgressURL(t *testing.T) {
+	ra := NewRendererAdapter(nil)
+	keys := GenerateOutputKeys("job_1", false)
+	url := "http://api:8080/v1/jobs/job_1/progress"
+
+	v0 := ra.Spec(RenderRequest{JobID: "job_1", ParsedJob: &ParsedJob{}, OutputKeys: keys, ProgressURL: url})
+	if got := v0.Spec.(contracts.RendererSpec).ProgressURL; got != url {
+		t.Errorf("v0 progress_url = %q, want %q", got, url)
+	}
+	v1 := ra.Spec(RenderRequest{JobID: "job_1", ParsedJob: &ParsedJob{TemplateID: "tpl_1", HasEnvelope: true}, OutputKeys: keys, ProgressURL: url})
+	if got := v1.Spec.(map[string]any)["progress_url"]; got != url {
+		t.Errorf("v1 progress_url = %v, want %q", got, url)
+	}
+	v1 = ra.Spec(RenderRequest{JobID: "job_1", ParsedJob: &ParsedJob{TemplateID: "tpl_1", HasEnvelope: true}, OutputKeys: keys})
+	if _, ok := v1.Spec.(map[string]any)["progress_url"]; ok {
+		t.Error("v1 spec has a progress_url without ProgressURL")
+	}
+}
@@ -0,0 +1,120 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gala/internal/assetslots"
+	"gala/internal/events"
+	"gala/internal/paramschema"
+	"gala/internal/pkg/logger"
+	"gala/internal/watermark"
+)
+
+// DefaultTemplateCacheTTL es cuánto se reusa lo leído de un template si no
+// se configura otro valor.
+const DefaultTemplateCacheTTL = time.Minute
+
+// templateCacheMax acota las entradas: al llenarse se vacía entera
+const templateCacheMax = 1000
+
+// cachedTemplate es lo que el parser usa de un template. Se comparte entre
+// jobs: nadie lo modifica (mergeMaps y watermark.Resolve copian).
+type cachedTemplate struct {
+	defaults  map[string]any
+	watermark *watermark.Config
+	schema    *paramschema.Schema
+	format    OutputFormat
+	// maxConcurrency es el tope de renders simultáneos; 0 sin tope.
+	maxConcurrency int
+	// assets son los slots de assets del template; nil si no tiene.
+	assets  assetslots.Slots
+	expires time.Time
+}
+
+// templateCache guarda por ID los templates leídos por el parser, para que
+// miles de jobs del mismo template no vayan cada uno a Postgres. Las
+// entradas vencen a los ttl y se invalidan antes con los eventos
+// template.updated y template.deleted (ver watch).
+type templateCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedTemplate
+}
+
+func newTemplateCache(ttl time.Duration) *templateCache {
+	return &templateCache{ttl: ttl, now: time.Now, entries: map[string]cachedTemplate{}}
+}
+
+func (c *templateCache) get(id string) (cachedTemplate, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.entries[id]
+	if ok && !c.now().Before(t.expires) {
+		delete(c.entries, id)
+		return cachedTemplate{}, false
+	}
+	return t, ok
+}
+
+func (c *templateCache) put(id string, t cachedTemplate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= templateCacheMax {
+		clear(c.entries)
+	}
+	t.expires = c.now().Add(c.ttl)
+	c.entries[id] = t
+}
+
+func (c *templateCache) invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, id)
+}
+
+func (c *templateCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// watch invalida las entradas de los templates que cambian, leyendo el
+// stream de eventos hasta que ctx termina. Si la lectura falla pudo
+// perderse un cambio: vacía el cache antes de reintentar.
+func (c *templateCache) watch(ctx context.Context, bus *events.Bus, log *logger.Logger) {
+	last := ""
+	for ctx.Err() == nil {
+		var (
+			evs []events.Event
+			err error
+		)
+		if last == "" {
+			last, err = bus.LastID(ctx)
+		} else {
+			evs, err = bus.Read(ctx, last, 100, 5*time.Second)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warn("template cache watch failed, retrying", "error", err.Error())
+			c.clear()
+			last = ""
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		for _, ev := range evs {
+			last = ev.ID
+			if ev.Type == events.TemplateUpdated || ev.Type == events.TemplateDeleted {
+				c.invalidate(ev.Subject)
+			}
+		}
+	}
+}
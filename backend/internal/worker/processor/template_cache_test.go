@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTemplateCache(t *testing.T) {
+	now := time.Now()
+	c := newTemplateCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.put("tpl_1", cachedTemplate{defaults: map[string]any{"text": "hola"}})
+	got, ok := c.get("tpl_1")
+	if !ok || got.defaults["text"] != "hola" {
+		t.Fatalf("get() = %+v, %v; want the cached defaults", got, ok)
+	}
+
+	c.invalidate("tpl_1")
+	if _, ok := c.get("tpl_1"); ok {
+		t.Error("get() found an invalidated template")
+	}
+
+	c.put("tpl_1", cachedTemplate{})
+	now = now.Add(time.Minute)
+	if _, ok := c.get("tpl_1"); ok {
+		t.Error("get() found an expired template")
+	}
+}
+
+func TestTemplateCacheBounded(t *testing.T) {
+	c := newTemplateCache(time.Minute)
+	for i := 0; i <= templateCacheMax; i++ {
+		c.put(fmt.Sprintf("tpl_%d", i), cachedTemplate{})
+	}
+	if n := len(c.entries); n > templateCacheMax {
+		t.Errorf("cache holds %d entries, want at most %d", n, templateCacheMax)
+	}
+	if _, ok := c.get(fmt.Sprintf("tpl_%d", templateCacheMax)); !ok {
+		t.Error("the newest template is not cached")
+	}
+}
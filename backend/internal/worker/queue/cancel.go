@@ -0,0 +1,80 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// cancelKeyPrefix prefixes the keys that mark a RUNNING job to be
+	// canceled; the worker checks it between the stages of the job.
+	cancelKeyPrefix = "gala:job:cancel:"
+	// CancelChannel is the pub/sub channel a cancel request is announced
+	// on (the message is the job ID), so the worker aborts the stage in
+	// flight, such as a render, without waiting for it to end.
+	CancelChannel = "gala:jobs:cancel"
+	// cancelTTL bounds how long a request outlives a job that ended
+	// before the worker saw it.
+	cancelTTL = 24 * time.Hour
+)
+
+// RequestCancel asks the worker running jobID to cancel it. Both queue
+// modes use it.
+func RequestCancel(ctx context.Context, rdb redis.UniversalClient, jobID string) error {
+	if err := rdb.Set(ctx, cancelKeyPrefix+jobID, time.Now().UTC().Format(time.RFC3339Nano), cancelTTL).Err(); err != nil {
+		return err
+	}
+	return rdb.Publish(ctx, CancelChannel, jobID).Err()
+}
+
+// CancelRequested reports whether jobID was asked to be canceled.
+func CancelRequested(ctx context.Context, rdb redis.UniversalClient, jobID string) (bool, error) {
+	n, err := rdb.Exists(ctx, cancelKeyPrefix+jobID).Result()
+	return n > 0, err
+}
+
+// ClearCancel forgets a cancel request of jobID, once the job ended or
+// before it runs again.
+func ClearCancel(ctx context.Context, rdb redis.UniversalClient, jobID string) error {
+	return rdb.Del(ctx, cancelKeyPrefix+jobID).Err()
+}
+
+// WatchCancel calls fn, at most once, when a cancel request for jobID is
+// announced, until the returned function is called. It returns once the
+// subscription is active; requests made before are missed, so check
+// CancelRequested after. If Redis is unreachable it watches nothing.
+func WatchCancel(ctx context.Context, rdb redis.UniversalClient, jobID string, fn func()) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	sub := rdb.Subscribe(ctx, CancelChannel)
+	if _, err := sub.Receive(ctx); err != nil {
+		cancel()
+		_ = sub.Close()
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				if msg.Payload == jobID {
+					fn()
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		_ = sub.Close()
+		<-done
+	}
+}
@@ -0,0 +1,43 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PauseKey holds, while set, the time the queue was paused: workers take no
+// new jobs until it is deleted, and finish the ones in flight. It lives in
+// Redis in both queue modes.
+const PauseKey = "gala:queue:paused"
+
+// Pause stops the workers from taking new jobs. Pausing a paused queue
+// keeps its original time.
+func Pause(ctx context.Context, rdb redis.UniversalClient) error {
+	return rdb.SetNX(ctx, PauseKey, time.Now().UTC().Format(time.RFC3339Nano), 0).Err()
+}
+
+// Resume lets the workers take jobs again.
+func Resume(ctx context.Context, rdb redis.UniversalClient) error {
+	return rdb.Del(ctx, PauseKey).Err()
+}
+
+// PausedSince returns when the queue was paused, or the zero time if it is
+// not.
+func PausedSince(ctx context.Context, rdb redis.UniversalClient) (time.Time, error) {
+	v, err := rdb.Get(ctx, PauseKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		// Set by hand: paused all the same
+		return time.Unix(0, 0).UTC(), nil
+	}
+	return t, nil
+}
@@ -0,0 +1,49 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gala/internal/testinfra"
+	"gala/internal/worker/queue"
+)
+
+func TestPause(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+
+	if since, err := queue.PausedSince(ctx, env.RDB); err != nil || !since.IsZero() {
+		t.Fatalf("PausedSince before pausing = %v, %v; want zero", since, err)
+	}
+	if err := queue.Pause(ctx, env.RDB); err != nil {
+		t.Fatal(err)
+	}
+	first, err := queue.PausedSince(ctx, env.RDB)
+	if err != nil || first.IsZero() {
+		t.Fatalf("PausedSince after Pause = %v, %v", first, err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := queue.Pause(ctx, env.RDB); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := queue.PausedSince(ctx, env.RDB); !again.Equal(first) {
+		t.Errorf("pausing again moved the time to %v, want %v", again, first)
+	}
+
+	if err := queue.Resume(ctx, env.RDB); err != nil {
+		t.Fatal(err)
+	}
+	if since, _ := queue.PausedSince(ctx, env.RDB); !since.IsZero() {
+		t.Errorf("PausedSince after Resume = %v, want zero", since)
+	}
+
+	// Set by hand, without a time
+	if err := env.RDB.Set(ctx, queue.PauseKey, "1", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if since, err := queue.PausedSince(ctx, env.RDB); err != nil || since.IsZero() {
+		t.Errorf("PausedSince of a hand-set key = %v, %v; want paused", since, err)
+	}
+}
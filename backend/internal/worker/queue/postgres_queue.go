@@ -0,0 +1,152 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"gala/internal/pkg/logger"
+	"gala/internal/store"
+)
+
+// NotifyChannel is the channel the jobs trigger (migration 002) notifies
+// on every queued job.
+const NotifyChannel = "gala_jobs"
+
+// PostgresQueue consumes jobs straight from the jobs table
+// (QUEUE_MODE=postgres): no Redis involved. Workers claim the oldest
+// QUEUED job with FOR UPDATE SKIP LOCKED, so concurrent workers never get
+// the same one.
+//
+// Listen wakes Pop as soon as a job is queued; PollInterval is only a
+// fallback for notifications lost while reconnecting.
+type PostgresQueue struct {
+	pool         *pgxpool.Pool
+	log          *logger.Logger
+	pollInterval time.Duration
+	wakeCh       chan struct{}
+}
+
+func NewPostgresQueue(pool *pgxpool.Pool, log *logger.Logger, pollInterval time.Duration) *PostgresQueue {
+	if pollInterval <= 0 {
+		pollInterval = 5 * time.Second
+	}
+	return &PostgresQueue{
+		pool:         pool,
+		log:          log.WithComponent("queue"),
+		pollInterval: pollInterval,
+		wakeCh:       make(chan struct{}, 1),
+	}
+}
+
+// Pop bloquea hasta reclamar un job (lo marca RUNNING) o hasta que ctx
+// termine.
+func (q *PostgresQueue) Pop(ctx context.Context) (string, error) {
+	for {
+		jobID, err := q.claim(ctx)
+		if err != nil || jobID != "" {
+			return jobID, err
+		}
+
+		timer := time.NewTimer(q.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		case <-q.wakeCh:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+func (q *PostgresQueue) claim(ctx context.Context) (string, error) {
+	return store.ClaimNextJob(ctx, q.pool)
+}
+
+// Return sets the claimed job jobID back to QUEUED; it keeps its place,
+// which is by priority and age.
+func (q *PostgresQueue) Return(ctx context.Context, jobID, _ string) error {
+	_, err := store.UnclaimJob(ctx, q.pool, jobID)
+	return err
+}
+
+// Defer sets the claimed job jobID back to QUEUED with run_at delay from
+// now, which ClaimNextJob waits for. No notification marks the end of the
+// delay: a waiting Pop finds the job on its next poll.
+func (q *PostgresQueue) Defer(ctx context.Context, jobID, _ string, delay time.Duration) error {
+	_, err := store.DeferClaimedJob(ctx, q.pool, jobID, time.Now().Add(delay))
+	return err
+}
+
+// Wake makes a waiting Pop check the table right away.
+func (q *PostgresQueue) Wake() {
+	select {
+	case q.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// Listen holds a dedicated connection LISTENing on NotifyChannel and wakes
+// Pop on every notification until ctx is canceled, reconnecting with
+// backoff if the connection drops. It blocks, so run it in its own
+// goroutine.
+func (q *PostgresQueue) Listen(ctx context.Context) {
+	const maxBackoff = 30 * time.Second
+	backoff := time.Second
+
+	for {
+		connected, err := q.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = time.Second
+		}
+		q.log.Warn("job notification listener failed, reconnecting",
+			"error", err.Error(),
+			"retry_in", backoff.String(),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// listen runs one LISTEN session and reports whether it got as far as
+// listening.
+func (q *PostgresQueue) listen(ctx context.Context) (bool, error) {
+	pc, err := q.pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	// Take the connection out of the pool: it stays LISTENing, and is
+	// closed rather than handed to other queries when the session ends.
+	conn := pc.Hijack()
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{NotifyChannel}.Sanitize()); err != nil {
+		return false, err
+	}
+	q.log.Debug("listening for job notifications", "channel", NotifyChannel)
+
+	// Catch up on jobs queued while no session was listening
+	q.Wake()
+
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return true, err
+		}
+		q.Wake()
+	}
+}
@@ -0,0 +1,198 @@
+package queue_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gala/internal/store"
+	"gala/internal/testinfra"
+	"gala/internal/worker/queue"
+)
+
+func TestMain(m *testing.M) { testinfra.Main(m) }
+
+func insertJob(t *testing.T, env *testinfra.Env, id, priority string, createdAt time.Time) {
+	t.Helper()
+	err := store.InsertJob(context.Background(), env.Pool, store.Job{
+		ID:         id,
+		ParamsJSON: `{"text":"hola"}`,
+		Priority:   priority,
+		CreatedAt:  createdAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClaimNextJobOrder(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	insertJob(t, env, "job_old", store.JobPriorityNormal, now.Add(-2*time.Minute))
+	insertJob(t, env, "job_new", store.JobPriorityNormal, now.Add(-time.Minute))
+	insertJob(t, env, "job_high", store.JobPriorityHigh, now)
+
+	for _, want := range []string{"job_high", "job_old", "job_new", ""} {
+		id, err := store.ClaimNextJob(ctx, env.Pool)
+		if err != nil || id != want {
+			t.Fatalf("ClaimNextJob = %q, %v; want %q", id, err, want)
+		}
+	}
+	job, err := store.GetJob(ctx, env.Pool, "job_old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != store.JobRunning || job.StartedAt == nil {
+		t.Errorf("claimed job = %s, started_at %v; want RUNNING with started_at", job.Status, job.StartedAt)
+	}
+}
+
+func TestClaimNextJobSkipsOtherStates(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	insertJob(t, env, "job_1", store.JobPriorityNormal, time.Now().UTC())
+	if _, err := store.CancelQueuedJob(ctx, env.Pool, "job_1"); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := store.ClaimNextJob(ctx, env.Pool); err != nil || id != "" {
+		t.Errorf("ClaimNextJob with only a canceled job = %q, %v; want none", id, err)
+	}
+}
+
+// Concurrent claims split the queued jobs: SKIP LOCKED never hands the
+// same job to two callers.
+func TestClaimNextJobConcurrent(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	const jobs = 20
+	now := time.Now().UTC()
+	for i := range jobs {
+		insertJob(t, env, fmt.Sprintf("job_%02d", i), store.JobPriorityNormal, now.Add(time.Duration(i)*time.Millisecond))
+	}
+
+	var (
+		mu      sync.Mutex
+		claimed = map[string]int{}
+		wg      sync.WaitGroup
+	)
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				id, err := store.ClaimNextJob(ctx, env.Pool)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if id == "" {
+					return
+				}
+				mu.Lock()
+				claimed[id]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimed) != jobs {
+		t.Errorf("claimed %d distinct jobs, want %d", len(claimed), jobs)
+	}
+	for id, n := range claimed {
+		if n != 1 {
+			t.Errorf("%s claimed %d times", id, n)
+		}
+	}
+}
+
+func TestPostgresQueuePop(t *testing.T) {
+	env := testinfra.New(t)
+	// A poll interval longer than the test: only Wake or a notification
+	// gets Pop to look again.
+	q := queue.NewPostgresQueue(env.Pool, env.Log, time.Hour)
+
+	t.Run("canceled while empty", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if id, err := q.Pop(ctx); err != context.DeadlineExceeded || id != "" {
+			t.Errorf("Pop = %q, %v; want DeadlineExceeded", id, err)
+		}
+	})
+
+	t.Run("woken by Wake", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		got := make(chan string, 1)
+		go func() {
+			id, _ := q.Pop(ctx)
+			got <- id
+		}()
+		time.Sleep(50 * time.Millisecond)
+		insertJob(t, env, "job_wake", store.JobPriorityNormal, time.Now().UTC())
+		q.Wake()
+		if id := <-got; id != "job_wake" {
+			t.Errorf("Pop = %q, want job_wake", id)
+		}
+	})
+
+	t.Run("woken by a notification", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		lctx, stop := context.WithCancel(ctx)
+		defer stop()
+		go q.Listen(lctx)
+
+		got := make(chan string, 1)
+		go func() {
+			id, _ := q.Pop(ctx)
+			got <- id
+		}()
+		time.Sleep(50 * time.Millisecond)
+		insertJob(t, env, "job_notify", store.JobPriorityNormal, time.Now().UTC())
+		select {
+		case id := <-got:
+			if id != "job_notify" {
+				t.Errorf("Pop = %q, want job_notify", id)
+			}
+		case <-ctx.Done():
+			t.Fatal("Pop was not woken by the notification")
+		}
+	})
+}
+
+func TestPostgresQueueDefer(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	q := queue.NewPostgresQueue(env.Pool, env.Log, time.Hour)
+	insertJob(t, env, "job_1", store.JobPriorityNormal, time.Now().UTC())
+
+	id, err := store.ClaimNextJob(ctx, env.Pool)
+	if err != nil || id != "job_1" {
+		t.Fatalf("ClaimNextJob = %q, %v; want job_1", id, err)
+	}
+	if err := q.Defer(ctx, id, store.JobPriorityNormal, 300*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := store.GetJob(ctx, env.Pool, id); job.Status != store.JobQueued {
+		t.Errorf("deferred job is %s, want QUEUED", job.Status)
+	}
+	if id, err := store.ClaimNextJob(ctx, env.Pool); err != nil || id != "" {
+		t.Errorf("ClaimNextJob during the delay = %q, %v; want none", id, err)
+	}
+	time.Sleep(400 * time.Millisecond)
+	if id, err := store.ClaimNextJob(ctx, env.Pool); err != nil || id != "job_1" {
+		t.Errorf("ClaimNextJob after the delay = %q, %v; want job_1", id, err)
+	}
+
+	// Return puts it back with no delay
+	if err := q.Return(ctx, "job_1", store.JobPriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := store.ClaimNextJob(ctx, env.Pool); err != nil || id != "job_1" {
+		t.Errorf("ClaimNextJob after Return = %q, %v; want job_1", id, err)
+	}
+}
@@ -0,0 +1,61 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"gala/internal/store"
+)
+
+// Queue hands out job ids to the worker loop.
+type Queue interface {
+	// Pop blocks until a job id is available or ctx is done. An empty id
+	// with a nil error means nothing arrived within the queue's own pop
+	// timeout.
+	Pop(ctx context.Context) (string, error)
+	// Return gives back jobID, which Pop handed out and the worker did
+	// not start, ahead of the other queued jobs; the job stays QUEUED.
+	// priority is the job's.
+	Return(ctx context.Context, jobID, priority string) error
+	// Defer gives back jobID like Return, but it is not handed out again
+	// for about delay, and other queued jobs go first meanwhile.
+	Defer(ctx context.Context, jobID, priority string, delay time.Duration) error
+}
+
+// DefaultPopTimeout is how long a pop waits for a job before the worker
+// counts itself idle (QUEUE_POP_TIMEOUT).
+const DefaultPopTimeout = 30 * time.Second
+
+// DefaultName is the Redis list job ids are pushed to (JOB_QUEUE_NAME).
+const DefaultName = "gala:jobs"
+
+// highList names the list of the high priority jobs after the queue's:
+// {gala:jobs}:high. The hash tag puts both lists in the same Redis Cluster
+// slot, so one BRPOP or MULTI can take both.
+func highList(name string) string {
+	return "{" + name + "}:high"
+}
+
+// ListFor returns the Redis list of the queue name that jobs of priority
+// are pushed to.
+func ListFor(name, priority string) string {
+	if priority == store.JobPriorityHigh {
+		return highList(name)
+	}
+	return name
+}
+
+// Lists returns every Redis list of the queue name, in the order workers
+// pop them: high priority first.
+func Lists(name string) []string {
+	return []string{highList(name), name}
+}
+
+// Queue modes (QUEUE_MODE).
+const (
+	// ModeRedis pops ids pushed by the API to a Redis list (default).
+	ModeRedis = "redis"
+	// ModePostgres claims QUEUED rows from the jobs table, woken by
+	// LISTEN/NOTIFY; Redis is not used for jobs.
+	ModePostgres = "postgres"
+)
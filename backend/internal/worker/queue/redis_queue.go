@@ -2,22 +2,39 @@ package queue
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 type RedisQueue struct {
-	rdb       *redis.Client
-	queueName string
+	rdb        redis.UniversalClient
+	queueName  string
+	popTimeout time.Duration
 }
 
-func NewRedisQueue(rdb *redis.Client, queueName string) *RedisQueue {
-	return &RedisQueue{rdb: rdb, queueName: queueName}
+func NewRedisQueue(rdb redis.UniversalClient, queueName string) *RedisQueue {
+	return &RedisQueue{rdb: rdb, queueName: queueName, popTimeout: DefaultPopTimeout}
 }
 
-// Pop bloquea hasta que exista un elemento (BRPOP)
+// WithPopTimeout fija cuánto espera BRPOP un job; con d <= 0 queda
+// DefaultPopTimeout.
+func (q *RedisQueue) WithPopTimeout(d time.Duration) *RedisQueue {
+	if d > 0 {
+		q.popTimeout = d
+	}
+	return q
+}
+
+// Pop bloquea hasta que exista un elemento (BRPOP) o venza el pop timeout;
+// en ese caso devuelve "" sin error. BRPOP mira las listas en orden, así
+// que los jobs de prioridad alta salen antes que cualquier job normal.
 func (q *RedisQueue) Pop(ctx context.Context) (string, error) {
-	res, err := q.rdb.BRPop(ctx, 0, q.queueName).Result()
+	res, err := q.rdb.BRPop(ctx, q.popTimeout, Lists(q.queueName)...).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
 	if err != nil {
 		return "", err
 	}
@@ -26,3 +43,16 @@ func (q *RedisQueue) Pop(ctx context.Context) (string, error) {
 	}
 	return res[1], nil
 }
+
+// Return vuelve a poner jobID en su lista, del lado por el que sale
+// (RPUSH): es el próximo job que se toma.
+func (q *RedisQueue) Return(ctx context.Context, jobID, priority string) error {
+	return q.rdb.RPush(ctx, ListFor(q.queueName, priority), jobID).Err()
+}
+
+// Defer pone jobID en el sorted set de los programados, a delay de ahora:
+// el job sigue QUEUED y el scheduler lo vuelve a poner en su lista cuando
+// vence (ver Scheduler.move).
+func (q *RedisQueue) Defer(ctx context.Context, jobID, _ string, delay time.Duration) error {
+	return q.rdb.ZAdd(ctx, ScheduledSet(q.queueName), Scheduled(jobID, time.Now().Add(delay))).Err()
+}
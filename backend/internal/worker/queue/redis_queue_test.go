@@ -0,0 +1,82 @@
+package queue_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"gala/internal/store"
+	"gala/internal/testinfra"
+	"gala/internal/worker/queue"
+)
+
+func TestRedisQueueReturn(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	q := queue.NewRedisQueue(env.RDB, queue.DefaultName).WithPopTimeout(time.Second)
+	if err := env.RDB.LPush(ctx, queue.DefaultName, "job_1", "job_2").Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := q.Pop(ctx)
+	if err != nil || id != "job_1" {
+		t.Fatalf("Pop = %q, %v; want job_1", id, err)
+	}
+	if err := q.Return(ctx, id, store.JobPriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := q.Pop(ctx); err != nil || id != "job_1" {
+		t.Errorf("Pop after Return = %q, %v; want job_1 first in line again", id, err)
+	}
+}
+
+// A deferred job stays QUEUED and out of the list until the scheduler
+// moves it back after its delay.
+func TestRedisQueueDefer(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+	q := queue.NewRedisQueue(env.RDB, queue.DefaultName)
+	s := queue.NewScheduler(env.Pool, env.RDB, env.Events, env.Log, queue.DefaultName, queue.ModeRedis, 0)
+	insertJob(t, env, "job_1", store.JobPriorityNormal, time.Now().UTC())
+	insertJob(t, env, "job_2", store.JobPriorityNormal, time.Now().UTC())
+
+	list := func() []string {
+		t.Helper()
+		ids, err := env.RDB.LRange(ctx, queue.DefaultName, 0, -1).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ids
+	}
+
+	for _, id := range []string{"job_1", "job_2"} {
+		if err := q.Defer(ctx, id, store.JobPriorityNormal, 300*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Canceled meanwhile: dropped
+	if _, err := store.CancelQueuedJob(ctx, env.Pool, "job_2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ids := list(); len(ids) != 0 {
+		t.Fatalf("list during the delay = %v, want empty", ids)
+	}
+	time.Sleep(400 * time.Millisecond)
+	if _, err := s.Tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ids := list(); !slices.Equal(ids, []string{"job_1"}) {
+		t.Errorf("list after the delay = %v, want [job_1]", ids)
+	}
+	if n, _ := env.RDB.ZCard(ctx, queue.ScheduledSet(queue.DefaultName)).Result(); n != 0 {
+		t.Errorf("scheduled set holds %d ids, want none", n)
+	}
+	if job, _ := store.GetJob(ctx, env.Pool, "job_1"); job.Status != store.JobQueued {
+		t.Errorf("deferred job is %s, want QUEUED", job.Status)
+	}
+}
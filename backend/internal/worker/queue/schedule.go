@@ -0,0 +1,185 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+
+	"gala/internal/events"
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/pgerr"
+	"gala/internal/store"
+)
+
+// DefaultScheduleInterval is how often the scheduler looks for scheduled
+// jobs that are due (JOB_SCHEDULE_INTERVAL).
+const DefaultScheduleInterval = time.Second
+
+// scheduleBatch bounds the jobs a tick queues from the Redis set; the
+// rest wait for the next tick.
+const scheduleBatch = 100
+
+// ScheduledSet names the sorted set of the scheduled jobs of the queue
+// name, {gala:jobs}:scheduled. It shares the Redis Cluster slot of the
+// lists (see highList), so a job moves to its list in one MULTI.
+func ScheduledSet(name string) string {
+	return "{" + name + "}:scheduled"
+}
+
+// Scheduled returns the member of ScheduledSet for the job id that runs
+// at runAt: scored by runAt in Unix milliseconds.
+func Scheduled(id string, runAt time.Time) redis.Z {
+	return redis.Z{Score: float64(runAt.UnixMilli()), Member: id}
+}
+
+// Scheduler queues the SCHEDULED jobs whose run_at has come. It runs on
+// one worker replica (the job-scheduler singleton); should two overlap
+// while the lock changes hands, a job is queued by whichever sets its row
+// QUEUED first.
+type Scheduler struct {
+	pool *pgxpool.Pool
+	rdb  redis.UniversalClient
+	ev   *events.Bus
+	log  *logger.Logger
+	name string
+	// push is false in postgres queue mode, where setting the rows
+	// QUEUED is enough.
+	push     bool
+	interval time.Duration
+}
+
+// NewScheduler creates the scheduler of the queue name in mode. interval
+// <= 0 uses DefaultScheduleInterval; ev receives job.queued events (nil
+// publishes none).
+func NewScheduler(pool *pgxpool.Pool, rdb redis.UniversalClient, ev *events.Bus, log *logger.Logger, name, mode string, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = DefaultScheduleInterval
+	}
+	return &Scheduler{
+		pool:     pool,
+		rdb:      rdb,
+		ev:       ev,
+		log:      log.WithComponent("scheduler"),
+		name:     name,
+		push:     mode != ModePostgres,
+		interval: interval,
+	}
+}
+
+// Run queues the due jobs every interval until ctx is canceled.
+func (s *Scheduler) Run(ctx context.Context) {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		n, err := s.Tick(ctx)
+		if n > 0 {
+			s.log.Info("scheduled jobs queued", "count", n)
+		}
+		if err != nil && ctx.Err() == nil {
+			s.log.Warn("queueing scheduled jobs failed", "error", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Tick queues the jobs due now and returns how many it queued.
+func (s *Scheduler) Tick(ctx context.Context) (int, error) {
+	now := time.Now()
+	if !s.push {
+		jobs, err := store.QueueDueJobs(ctx, s.pool, now)
+		for _, j := range jobs {
+			s.queued(ctx, j)
+		}
+		return len(jobs), err
+	}
+
+	set := ScheduledSet(s.name)
+	ids, err := s.rdb.ZRangeArgs(ctx, redis.ZRangeArgs{
+		Key:     set,
+		Start:   "-inf",
+		Stop:    strconv.FormatInt(now.UnixMilli(), 10),
+		ByScore: true,
+		Count:   scheduleBatch,
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, id := range ids {
+		ok, err := s.move(ctx, set, id)
+		if err != nil {
+			return n, fmt.Errorf("job %s: %w", id, err)
+		}
+		if ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// move queues the scheduled job id and reports whether it did. The row
+// goes first, so a worker that pops the id finds it QUEUED; then the id
+// moves from set to its list in one MULTI. If that fails the row goes back
+// to SCHEDULED for the next tick. A QUEUED row is a job a worker deferred
+// (RedisQueue.Defer): only its id moves. An id whose row is in any other
+// status (canceled, or queued by another worker) is just dropped from set.
+func (s *Scheduler) move(ctx context.Context, set, id string) (bool, error) {
+	job, err := store.QueueScheduledJob(ctx, s.pool, id)
+	if pgerr.IsNoRows(err) {
+		return false, s.moveDeferred(ctx, set, id)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	pipe := s.rdb.TxPipeline()
+	pipe.LPush(ctx, ListFor(s.name, job.Priority), id)
+	pipe.ZRem(ctx, set, id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		ctx := context.WithoutCancel(ctx)
+		if uerr := store.UnqueueScheduledJob(ctx, s.pool, id); uerr != nil {
+			return false, fmt.Errorf("%w; job left QUEUED: %v", err, uerr)
+		}
+		// Another worker may have dropped the id meanwhile
+		if job.RunAt != nil {
+			_ = s.rdb.ZAdd(ctx, set, Scheduled(id, *job.RunAt)).Err()
+		}
+		return false, err
+	}
+	s.queued(ctx, job)
+	return true, nil
+}
+
+// moveDeferred moves the id of a deferred job from set to its list, or
+// drops it if the job is no longer QUEUED.
+func (s *Scheduler) moveDeferred(ctx context.Context, set, id string) error {
+	job, err := store.GetJob(ctx, s.pool, id)
+	if err != nil && !pgerr.IsNoRows(err) {
+		return err
+	}
+	if err != nil || job.Status != store.JobQueued {
+		return s.rdb.ZRem(ctx, set, id).Err()
+	}
+	// Whoever removes the id pushes it, so it never lands twice in the list
+	n, err := s.rdb.ZRem(ctx, set, id).Result()
+	if err != nil || n == 0 {
+		return err
+	}
+	if err := s.rdb.LPush(ctx, ListFor(s.name, job.Priority), id).Err(); err != nil {
+		_ = s.rdb.ZAdd(context.WithoutCancel(ctx), set, Scheduled(id, time.Now())).Err()
+		return err
+	}
+	return nil
+}
+
+func (s *Scheduler) queued(ctx context.Context, j store.Job) {
+	s.ev.Publish(ctx, events.JobQueued, j.ID, map[string]any{"status": j.Status, "run_at": j.RunAt})
+}
@@ -0,0 +1,96 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// semaphorePrefix prefixes the Redis sorted sets of the semaphores: the
+// holders, scored by when their lease expires.
+const semaphorePrefix = "gala:semaphore:"
+
+// DefaultSemaphoreLease is how long a slot is held without a refresh; a
+// worker that dies frees its slots once their lease runs out.
+const DefaultSemaphoreLease = 2 * time.Minute
+
+// acquireScript takes a slot of KEYS[1] for ARGV[2] if fewer than ARGV[1]
+// holders have a live lease (or ARGV[2] already holds one), with a lease
+// of ARGV[3] ms. Leases use the Redis clock, so worker clocks do not
+// matter.
+var acquireScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+if redis.call('ZSCORE', KEYS[1], ARGV[2]) or redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[1]) then
+  redis.call('ZADD', KEYS[1], now + tonumber(ARGV[3]), ARGV[2])
+  redis.call('PEXPIRE', KEYS[1], ARGV[3])
+  return 1
+end
+return 0
+`)
+
+// refreshScript extends the lease of ARGV[1] on KEYS[1] to ARGV[2] ms, if
+// it still holds a slot: a lease that expired meanwhile is not taken back.
+var refreshScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+if redis.call('ZADD', KEYS[1], 'XX', 'CH', now + tonumber(ARGV[2]), ARGV[1]) == 1 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Semaphore limits how many holders (jobs) run at once per name, across
+// workers. Slots are leases in Redis: Hold refreshes them while the job
+// runs.
+type Semaphore struct {
+	rdb   redis.UniversalClient
+	lease time.Duration
+}
+
+// NewSemaphore creates a semaphore on rdb; lease 0 uses
+// DefaultSemaphoreLease.
+func NewSemaphore(rdb redis.UniversalClient, lease time.Duration) *Semaphore {
+	if lease <= 0 {
+		lease = DefaultSemaphoreLease
+	}
+	return &Semaphore{rdb: rdb, lease: lease}
+}
+
+// TryAcquire takes a slot of name for holder if fewer than limit are
+// taken, and reports whether it did.
+func (s *Semaphore) TryAcquire(ctx context.Context, name string, limit int, holder string) (bool, error) {
+	n, err := acquireScript.Run(ctx, s.rdb, []string{semaphorePrefix + name}, limit, holder, s.lease.Milliseconds()).Int()
+	return n == 1, err
+}
+
+// Hold keeps the slot of holder alive until the returned function is
+// called, which releases it. The refreshes and the release run even if
+// ctx is done, so a canceled job still frees its slot.
+func (s *Semaphore) Hold(ctx context.Context, name, holder string) (release func()) {
+	key := semaphorePrefix + name
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(s.lease / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				refreshScript.Run(ctx, s.rdb, []string{key}, holder, s.lease.Milliseconds())
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+		rctx, rcancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer rcancel()
+		s.rdb.ZRem(rctx, key, holder)
+	}
+}
@@ -0,0 +1,87 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gala/internal/testinfra"
+	"gala/internal/worker/queue"
+)
+
+func TestSemaphore(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+
+	acquire := func(t *testing.T, s *queue.Semaphore, holder string) bool {
+		t.Helper()
+		ok, err := s.TryAcquire(ctx, "tpl_1", 2, holder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	t.Run("limit", func(t *testing.T) {
+		s := queue.NewSemaphore(env.RDB, time.Minute)
+		if !acquire(t, s, "job_1") || !acquire(t, s, "job_2") {
+			t.Fatal("the first two holders did not get a slot")
+		}
+		if acquire(t, s, "job_3") {
+			t.Error("a third holder got a slot over the limit")
+		}
+		if !acquire(t, s, "job_1") {
+			t.Error("a holder lost its own slot on acquiring it again")
+		}
+		if ok, _ := s.TryAcquire(ctx, "tpl_2", 2, "job_3"); !ok {
+			t.Error("the slots of another name were taken")
+		}
+		s.Hold(ctx, "tpl_1", "job_1")()
+		s.Hold(ctx, "tpl_1", "job_2")()
+	})
+
+	t.Run("release frees the slot", func(t *testing.T) {
+		s := queue.NewSemaphore(env.RDB, time.Minute)
+		acquire(t, s, "job_1")
+		acquire(t, s, "job_2")
+		release := s.Hold(ctx, "tpl_1", "job_1")
+		release()
+		if !acquire(t, s, "job_3") {
+			t.Error("the released slot was not free")
+		}
+		s.Hold(ctx, "tpl_1", "job_2")()
+		s.Hold(ctx, "tpl_1", "job_3")()
+	})
+
+	t.Run("lease expires", func(t *testing.T) {
+		s := queue.NewSemaphore(env.RDB, 100*time.Millisecond)
+		acquire(t, s, "job_1")
+		acquire(t, s, "job_2")
+		time.Sleep(200 * time.Millisecond)
+		if !acquire(t, s, "job_3") {
+			t.Error("the slots of holders that stopped refreshing were not freed")
+		}
+		s.Hold(ctx, "tpl_1", "job_3")()
+	})
+
+	t.Run("hold refreshes the lease", func(t *testing.T) {
+		s := queue.NewSemaphore(env.RDB, 150*time.Millisecond)
+		acquire(t, s, "job_1")
+		acquire(t, s, "job_2")
+		hctx, cancel := context.WithCancel(ctx)
+		release1 := s.Hold(hctx, "tpl_1", "job_1")
+		release2 := s.Hold(hctx, "tpl_1", "job_2")
+		// A canceled ctx does not stop the refreshes: only release does.
+		cancel()
+		time.Sleep(400 * time.Millisecond)
+		if acquire(t, s, "job_3") {
+			t.Error("a held slot expired")
+		}
+		release1()
+		release2()
+		if !acquire(t, s, "job_3") {
+			t.Error("no slot free after releasing both")
+		}
+		s.Hold(ctx, "tpl_1", "job_3")()
+	})
+}
@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gala/internal/pkg/logger"
+	"gala/internal/ports"
+	"gala/internal/storage"
+	"gala/internal/worker/renderer"
+)
+
+// DefaultReadyInterval is about how often a worker waiting for its
+// dependencies pings them again (WORKER_READY_INTERVAL).
+const DefaultReadyInterval = 5 * time.Second
+
+// readiness gates the job loop on the renderer and the storage: Run takes
+// its first job once both answer, and takes none while the renderer's
+// breaker is open, until the renderer answers again. Otherwise a worker
+// started before the renderer, or left running after it died, pops job
+// after job only to fail them.
+type readiness struct {
+	rc       *renderer.HTTPClient
+	sp       ports.StorageProvider
+	log      *logger.Logger
+	interval time.Duration
+}
+
+// check pings the renderer and the storage.
+func (g *readiness) check(ctx context.Context) error {
+	if err := g.rc.Ping(ctx); err != nil {
+		return fmt.Errorf("renderer: %w", err)
+	}
+	if g.sp != nil {
+		if err := storage.Ping(ctx, g.sp); err != nil {
+			return fmt.Errorf("storage: %w", err)
+		}
+	}
+	return nil
+}
+
+// wait blocks until check passes, and reports false if ctx ended first.
+// reason says in the logs why the worker waits.
+func (g *readiness) wait(ctx context.Context, reason string) bool {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := g.check(ctx)
+		if ctx.Err() != nil {
+			return false
+		}
+		if err == nil {
+			if attempt > 1 {
+				g.log.Info("dependencies ready, taking jobs",
+					"reason", reason,
+					"waited_ms", time.Since(start).Milliseconds(),
+				)
+			}
+			return true
+		}
+		// Warn once; the retries only matter when debugging
+		if attempt == 1 {
+			g.log.Warn("dependencies not ready, not taking jobs",
+				"reason", reason,
+				"error", err.Error(),
+				"retry_every", g.interval.String(),
+			)
+		} else {
+			g.log.Debug("dependencies still not ready", "reason", reason, "error", err.Error())
+		}
+		sleepCtx(ctx, jitter(g.interval))
+	}
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gala/internal/pkg/logger"
+	"gala/internal/testsupport"
+	"gala/internal/worker/renderer"
+)
+
+// newReadiness returns a readiness on a renderer whose /health answers
+// while up is set, and on sp.
+func newReadiness(t *testing.T, up *atomic.Bool, sp *testsupport.Storage) *readiness {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return &readiness{
+		rc:       renderer.NewHTTPClient(srv.URL),
+		sp:       sp,
+		log:      logger.New(logger.Config{Level: "error", Output: io.Discard}),
+		interval: 10 * time.Millisecond,
+	}
+}
+
+func TestReadinessCheck(t *testing.T) {
+	ctx := context.Background()
+	var up atomic.Bool
+	sp := testsupport.NewStorage()
+	g := newReadiness(t, &up, sp)
+
+	if err := g.check(ctx); err == nil {
+		t.Error("check passed with the renderer down")
+	}
+	up.Store(true)
+	if err := g.check(ctx); err != nil {
+		t.Errorf("check = %v, want nil", err)
+	}
+
+	// A missing object means the storage answered; other errors do not
+	sp.Fail = testsupport.FailFirst(1, testsupport.OpGet, "")
+	if err := g.check(ctx); err == nil {
+		t.Error("check passed with the storage failing")
+	}
+	if err := g.check(ctx); err != nil {
+		t.Errorf("check after the storage recovered = %v, want nil", err)
+	}
+
+	g.sp = nil
+	if err := g.check(ctx); err != nil {
+		t.Errorf("check without storage = %v, want nil", err)
+	}
+}
+
+func TestReadinessWait(t *testing.T) {
+	var up atomic.Bool
+	g := newReadiness(t, &up, testsupport.NewStorage())
+
+	t.Run("until ready", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			up.Store(true)
+		}()
+		if !g.wait(ctx, "startup") {
+			t.Error("wait gave up before the renderer came up")
+		}
+	})
+
+	t.Run("ctx ends first", func(t *testing.T) {
+		up.Store(false)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if g.wait(ctx, "breaker open") {
+			t.Error("wait reported ready with the renderer down")
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"gala/internal/pkg/errors"
+	"gala/internal/pkg/logger"
+	"gala/internal/store"
+)
+
+// ReapStaleJobs marks FAILED the jobs that have been RUNNING for longer
+// than staleAfter: their worker died or lost the database mid-job, so
+// nobody will ever finish them. It returns how many were reaped.
+//
+// It is meant to run as a singleton task (see leader.Run); staleAfter must
+// be longer than any job can legitimately run (WORKER_JOB_TIMEOUT).
+func ReapStaleJobs(ctx context.Context, pool *pgxpool.Pool, log *logger.Logger, staleAfter time.Duration) (int64, error) {
+	msg := fmt.Sprintf("job abandoned: still running after %s, worker presumed dead", staleAfter)
+	timeout, _ := errors.Lookup(errors.CodeTimeout)
+	n, err := store.FailStaleJobs(ctx, pool, staleAfter, msg, store.JobError{
+		Code:      string(errors.CodeTimeout),
+		Op:        "worker.reaper",
+		Message:   msg,
+		Retryable: timeout.Retryable,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("reap stale jobs: %w", err)
+	}
+	if n > 0 {
+		log.Warn("reaped stale jobs", "count", n, "stale_after", staleAfter.String())
+	}
+	return n, nil
+}
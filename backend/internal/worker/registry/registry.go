@@ -0,0 +1,172 @@
+// Package registry keeps the list of live workers in Redis: each worker
+// refreshes its entry every heartbeat interval, and entries not refreshed
+// within three intervals drop out. The API reads it for GET /admin/workers.
+//
+// Entries are one key per worker plus a sorted set of ids by last beat, so
+// listing needs no SCAN and works on Redis Cluster.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"gala/internal/pkg/logger"
+)
+
+const (
+	indexKey  = "gala:workers"
+	keyPrefix = "gala:workers:"
+)
+
+// DefaultInterval is the heartbeat interval when none is given.
+const DefaultInterval = 10 * time.Second
+
+// Worker is a registry entry.
+type Worker struct {
+	ID        string    `json:"id"`
+	Hostname  string    `json:"hostname"`
+	PID       int       `json:"pid"`
+	QueueMode string    `json:"queue_mode"`
+	StartedAt time.Time `json:"started_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	// CurrentJob is the job being processed, empty when idle.
+	CurrentJob string `json:"current_job,omitempty"`
+	// Paused is set while the queue is paused (see queue.Pause) and the
+	// worker takes no new jobs.
+	Paused bool `json:"paused,omitempty"`
+}
+
+// Heartbeat publishes one worker's entry.
+type Heartbeat struct {
+	rdb      redis.UniversalClient
+	log      *logger.Logger
+	interval time.Duration
+
+	mu     sync.Mutex
+	worker Worker
+	// pub serializes the writes of the entry, so a stale beat never lands
+	// after a newer one.
+	pub sync.Mutex
+}
+
+// NewHeartbeat creates the heartbeat for w; interval <= 0 uses
+// DefaultInterval.
+func NewHeartbeat(rdb redis.UniversalClient, log *logger.Logger, w Worker, interval time.Duration) *Heartbeat {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if w.StartedAt.IsZero() {
+		w.StartedAt = time.Now().UTC()
+	}
+	return &Heartbeat{rdb: rdb, log: log.WithComponent("registry"), interval: interval, worker: w}
+}
+
+// SetJob records the job in progress ("" when idle) and publishes the
+// entry right away, so that a soft drain sees a job the worker just took
+// before it is RUNNING.
+func (h *Heartbeat) SetJob(ctx context.Context, jobID string) {
+	h.mu.Lock()
+	h.worker.CurrentJob = jobID
+	h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := h.beat(ctx); err != nil {
+		h.log.Warn("worker heartbeat failed", "error", err.Error())
+	}
+}
+
+// SetPaused records whether the worker is holding off new jobs.
+func (h *Heartbeat) SetPaused(paused bool) {
+	h.mu.Lock()
+	h.worker.Paused = paused
+	h.mu.Unlock()
+}
+
+// Run beats every interval until ctx is canceled, then removes the entry.
+// It blocks, so run it in its own goroutine.
+func (h *Heartbeat) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := h.beat(ctx); err != nil && ctx.Err() == nil {
+			h.log.Warn("worker heartbeat failed", "error", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			h.remove()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Heartbeat) beat(ctx context.Context) error {
+	h.pub.Lock()
+	defer h.pub.Unlock()
+	h.mu.Lock()
+	h.worker.LastSeen = time.Now().UTC()
+	w := h.worker
+	h.mu.Unlock()
+
+	raw, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	_, err = h.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, keyPrefix+w.ID, raw, 3*h.interval)
+		p.ZAdd(ctx, indexKey, redis.Z{Score: float64(w.LastSeen.Unix()), Member: w.ID})
+		return nil
+	})
+	return err
+}
+
+func (h *Heartbeat) remove() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _ = h.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Del(ctx, keyPrefix+h.worker.ID)
+		p.ZRem(ctx, indexKey, h.worker.ID)
+		return nil
+	})
+}
+
+// List returns the live workers by id. Ids left in the index by workers
+// that died without deregistering are pruned once their entry expires.
+func List(ctx context.Context, rdb redis.UniversalClient) ([]Worker, error) {
+	ids, err := rdb.ZRange(ctx, indexKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	out := []Worker{}
+	var gone []any
+	for _, id := range ids {
+		raw, err := rdb.Get(ctx, keyPrefix+id).Bytes()
+		if errors.Is(err, redis.Nil) {
+			gone = append(gone, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var w Worker
+		if err := json.Unmarshal(raw, &w); err != nil {
+			continue
+		}
+		out = append(out, w)
+	}
+	if len(gone) > 0 {
+		_ = rdb.ZRem(ctx, indexKey, gone...).Err()
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
@@ -0,0 +1,278 @@
+package renderer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Auth header names used between the worker and the renderer.
+const (
+	HeaderAuthorization = "Authorization"
+	HeaderKeyID         = "X-Gala-Key-Id"
+	HeaderTimestamp     = "X-Gala-Timestamp"
+	HeaderSignature     = "X-Gala-Signature"
+)
+
+// Auth modes accepted by AuthConfig.Mode.
+const (
+	AuthModeNone   = "none"
+	AuthModeBearer = "bearer"
+	AuthModeHMAC   = "hmac"
+)
+
+// Authenticator decorates outgoing renderer requests with credentials.
+// body is the exact payload being sent, for signing schemes.
+type Authenticator interface {
+	Authenticate(req *http.Request, body []byte) error
+}
+
+// Secret is a shared secret with an identifier so the renderer can tell
+// which key signed a request while both old and new keys are valid.
+type Secret struct {
+	ID    string
+	Value string
+}
+
+// SecretSource returns the secret to use for the next request.
+// Implementations must be safe for concurrent use.
+type SecretSource interface {
+	Current() (Secret, error)
+}
+
+// StaticSecret is an in-memory SecretSource that can be rotated at runtime.
+type StaticSecret struct {
+	mu     sync.RWMutex
+	secret Secret
+}
+
+// NewStaticSecret creates a rotatable in-memory secret.
+func NewStaticSecret(id, value string) *StaticSecret {
+	return &StaticSecret{secret: Secret{ID: id, Value: value}}
+}
+
+// Current returns the active secret.
+func (s *StaticSecret) Current() (Secret, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.secret.Value == "" {
+		return Secret{}, fmt.Errorf("renderer auth secret is empty")
+	}
+	return s.secret, nil
+}
+
+// Rotate replaces the active secret. In-flight requests keep the old one.
+func (s *StaticSecret) Rotate(id, value string) {
+	s.mu.Lock()
+	s.secret = Secret{ID: id, Value: value}
+	s.mu.Unlock()
+}
+
+// FileSecret reads the secret from a file and reloads it when the file's
+// modification time changes, so mounted secrets can be rotated in place.
+// The file holds either "value" or "id:value".
+type FileSecret struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	secret  Secret
+}
+
+// NewFileSecret creates a file-backed SecretSource.
+func NewFileSecret(path string) *FileSecret {
+	return &FileSecret{path: path}
+}
+
+// Current returns the secret, reloading the file if it changed.
+func (f *FileSecret) Current() (Secret, error) {
+	st, err := os.Stat(f.path)
+	if err != nil {
+		return Secret{}, fmt.Errorf("renderer auth secret file: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.secret.Value != "" && st.ModTime().Equal(f.modTime) {
+		return f.secret, nil
+	}
+
+	raw, err := os.ReadFile(f.path)
+	if err != nil {
+		return Secret{}, fmt.Errorf("renderer auth secret file: %w", err)
+	}
+	secret := parseSecret(strings.TrimSpace(string(raw)))
+	if secret.Value == "" {
+		return Secret{}, fmt.Errorf("renderer auth secret file is empty: %s", f.path)
+	}
+
+	f.secret = secret
+	f.modTime = st.ModTime()
+	return f.secret, nil
+}
+
+// BearerAuth sends the secret as an Authorization: Bearer token.
+type BearerAuth struct {
+	src SecretSource
+}
+
+// NewBearerAuth creates a bearer-token Authenticator.
+func NewBearerAuth(src SecretSource) *BearerAuth {
+	return &BearerAuth{src: src}
+}
+
+// Authenticate implements Authenticator.
+func (a *BearerAuth) Authenticate(req *http.Request, _ []byte) error {
+	secret, err := a.src.Current()
+	if err != nil {
+		return err
+	}
+	req.Header.Set(HeaderAuthorization, "Bearer "+secret.Value)
+	if secret.ID != "" {
+		req.Header.Set(HeaderKeyID, secret.ID)
+	}
+	return nil
+}
+
+// HMACAuth signs each request with HMAC-SHA256 over timestamp, method,
+// path and body. The secret itself never travels on the wire.
+type HMACAuth struct {
+	src SecretSource
+	now func() time.Time
+}
+
+// NewHMACAuth creates an HMAC-signing Authenticator.
+func NewHMACAuth(src SecretSource) *HMACAuth {
+	return &HMACAuth{src: src, now: time.Now}
+}
+
+// Authenticate implements Authenticator.
+func (a *HMACAuth) Authenticate(req *http.Request, body []byte) error {
+	secret, err := a.src.Current()
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(a.now().Unix(), 10)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, Sign(secret.Value, ts, req.Method, req.URL.Path, body))
+	if secret.ID != "" {
+		req.Header.Set(HeaderKeyID, secret.ID)
+	}
+	return nil
+}
+
+// Sign computes the hex HMAC-SHA256 signature for a renderer request.
+func Sign(secret, timestamp, method, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(method))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(path))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// MaxSkew is how old an HMAC timestamp the receivers of signed requests
+// (the renderer, and the API for its progress reports) accept.
+const MaxSkew = 5 * time.Minute
+
+// Verify checks a request produced by BearerAuth or HMACAuth against any of
+// the accepted secrets (pass both old and new during a rotation window).
+// maxSkew bounds how old an HMAC timestamp may be.
+func Verify(req *http.Request, body []byte, maxSkew time.Duration, accepted ...Secret) bool {
+	if sig := req.Header.Get(HeaderSignature); sig != "" {
+		ts := req.Header.Get(HeaderTimestamp)
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return false
+		}
+		if maxSkew > 0 {
+			skew := time.Since(time.Unix(sec, 0))
+			if skew > maxSkew || skew < -maxSkew {
+				return false
+			}
+		}
+		for _, s := range accepted {
+			expected := Sign(s.Value, ts, req.Method, req.URL.Path, body)
+			if hmac.Equal([]byte(expected), []byte(sig)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	token, ok := strings.CutPrefix(req.Header.Get(HeaderAuthorization), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	for _, s := range accepted {
+		if hmac.Equal([]byte(token), []byte(s.Value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthConfig describes how the worker authenticates against the renderer.
+type AuthConfig struct {
+	// Mode is one of none, bearer, hmac.
+	Mode string
+	// Secret is the shared secret ("value" or "id:value").
+	Secret string
+	// SecretFile, if set, takes precedence over Secret and is reloaded on change.
+	SecretFile string
+}
+
+// NewAuthenticator builds an Authenticator from config. It returns nil for
+// mode "none" (or empty), meaning requests are sent unauthenticated.
+func NewAuthenticator(cfg AuthConfig) (Authenticator, error) {
+	src, err := NewSecretSource(cfg)
+	if src == nil || err != nil {
+		return nil, err
+	}
+
+	switch mode := strings.ToLower(strings.TrimSpace(cfg.Mode)); mode {
+	case AuthModeBearer:
+		return NewBearerAuth(src), nil
+	case AuthModeHMAC:
+		return NewHMACAuth(src), nil
+	default:
+		return nil, fmt.Errorf("unknown renderer auth mode: %s", cfg.Mode)
+	}
+}
+
+// NewSecretSource returns the shared secret of config, which the API also
+// uses to verify the progress the renderer reports. It returns nil for
+// mode "none" (or empty).
+func NewSecretSource(cfg AuthConfig) (SecretSource, error) {
+	mode := strings.ToLower(strings.TrimSpace(cfg.Mode))
+	if mode == "" || mode == AuthModeNone {
+		return nil, nil
+	}
+	switch {
+	case cfg.SecretFile != "":
+		return NewFileSecret(cfg.SecretFile), nil
+	case cfg.Secret != "":
+		s := parseSecret(cfg.Secret)
+		return NewStaticSecret(s.ID, s.Value), nil
+	default:
+		return nil, fmt.Errorf("renderer auth mode %q requires a secret", mode)
+	}
+}
+
+func parseSecret(raw string) Secret {
+	if id, value, ok := strings.Cut(raw, ":"); ok && id != "" && value != "" {
+		return Secret{ID: id, Value: value}
+	}
+	return Secret{Value: raw}
+}
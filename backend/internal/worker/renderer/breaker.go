@@ -0,0 +1,88 @@
+package renderer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultBreakerThreshold is how many renders in a row must fail with the
+// renderer down before the breaker opens (RENDERER_BREAKER_THRESHOLD).
+const DefaultBreakerThreshold = 5
+
+// Breaker is a circuit breaker around a Client. After threshold renders in
+// a row fail because the renderer is unreachable or broken (a connection
+// error or a 5xx answer) it opens, and stays open until Reset: the worker
+// takes no jobs meanwhile and pings the renderer until it answers.
+// Renders abandoned by their caller's context and 4xx answers (a spec the
+// renderer refused) do not count.
+type Breaker struct {
+	Client
+	threshold int
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+}
+
+// NewBreaker wraps c; threshold <= 0 uses DefaultBreakerThreshold.
+func NewBreaker(c Client, threshold int) *Breaker {
+	if threshold <= 0 {
+		threshold = DefaultBreakerThreshold
+	}
+	return &Breaker{Client: c, threshold: threshold}
+}
+
+func (b *Breaker) Render(ctx context.Context, spec any) error {
+	err := b.Client.Render(ctx, spec)
+	b.record(ctx, err)
+	return err
+}
+
+func (b *Breaker) RenderV1(ctx context.Context, spec any) error {
+	err := b.Client.RenderV1(ctx, spec)
+	b.record(ctx, err)
+	return err
+}
+
+func (b *Breaker) record(ctx context.Context, err error) {
+	if err != nil && (ctx.Err() != nil || !rendererDown(err)) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold && b.openedAt.IsZero() {
+		b.openedAt = time.Now()
+	}
+}
+
+// OpenSince returns when the breaker opened, or the zero time if it is
+// closed.
+func (b *Breaker) OpenSince() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.openedAt
+}
+
+// Reset closes the breaker, once the renderer answers again.
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures, b.openedAt = 0, time.Time{}
+}
+
+// rendererDown reports whether err means the renderer could not render
+// anything, rather than this spec.
+func rendererDown(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code >= 500
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package renderer_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"gala/internal/mockrenderer"
+	"gala/internal/testsupport"
+	"gala/internal/worker/renderer"
+)
+
+func TestBreaker(t *testing.T) {
+	var fail error
+	r := testsupport.NewRenderer(mockrenderer.Options{StorageRoot: t.TempDir(), SkipOutputs: true})
+	r.Fail = func(string) error { return fail }
+	b := renderer.NewBreaker(r, 3)
+	ctx := context.Background()
+	spec := map[string]any{
+		"job_id": "job_1",
+		"output": map[string]string{"video_object_key": "v.mp4", "thumb_object_key": "t.jpg"},
+	}
+
+	render := func(err error, n int) {
+		t.Helper()
+		fail = err
+		for range n {
+			_ = b.Render(ctx, spec)
+		}
+	}
+
+	down := errors.New("connection refused")
+	render(down, 2)
+	if !b.OpenSince().IsZero() {
+		t.Fatal("breaker opened before the threshold")
+	}
+	// A success starts the count again
+	render(nil, 1)
+	render(down, 2)
+	if !b.OpenSince().IsZero() {
+		t.Fatal("breaker counted failures from before a success")
+	}
+
+	// Refused specs say nothing about the renderer
+	render(&renderer.StatusError{Code: http.StatusBadRequest}, 5)
+	if !b.OpenSince().IsZero() {
+		t.Fatal("breaker opened on 4xx answers")
+	}
+
+	// Neither do renders abandoned by their caller
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	fail = down
+	for range 5 {
+		_ = b.RenderV1(canceled, spec)
+	}
+	if !b.OpenSince().IsZero() {
+		t.Fatal("breaker opened on canceled renders")
+	}
+
+	render(&renderer.StatusError{Code: http.StatusBadGateway}, 3)
+	opened := b.OpenSince()
+	if opened.IsZero() {
+		t.Fatal("breaker did not open after 3 failures in a row")
+	}
+	render(down, 1)
+	if !b.OpenSince().Equal(opened) {
+		t.Error("more failures moved the time the breaker opened")
+	}
+
+	b.Reset()
+	if !b.OpenSince().IsZero() {
+		t.Error("breaker still open after Reset")
+	}
+	render(down, 2)
+	if !b.OpenSince().IsZero() {
+		t.Error("Reset did not clear the failure count")
+	}
+}
@@ -0,0 +1,46 @@
+package renderer
+
+import (
+	"context"
+	"net/http"
+
+	"gala/internal/pkg/chaos"
+)
+
+// faulty fails renders as its injector decides: with a 500 before calling
+// the renderer, or after hanging like a renderer that stopped answering.
+type faulty struct {
+	Client
+	inj *chaos.Injector
+}
+
+// WithChaos wraps c so that inj fails some of its renders; a nil inj
+// returns c as it is. Wrap the client inside the Breaker, so injected
+// faults open it like real ones.
+func WithChaos(c Client, inj *chaos.Injector) Client {
+	if inj == nil {
+		return c
+	}
+	return &faulty{Client: c, inj: inj}
+}
+
+func (f *faulty) Render(ctx context.Context, spec any) error {
+	if err := f.fault(ctx); err != nil {
+		return err
+	}
+	return f.Client.Render(ctx, spec)
+}
+
+func (f *faulty) RenderV1(ctx context.Context, spec any) error {
+	if err := f.fault(ctx); err != nil {
+		return err
+	}
+	return f.Client.RenderV1(ctx, spec)
+}
+
+func (f *faulty) fault(ctx context.Context) error {
+	if f.inj.RendererFault(ctx) {
+		return &StatusError{Code: http.StatusInternalServerError}
+	}
+	return f.inj.RendererHang(ctx)
+}
@@ -2,57 +2,148 @@ package renderer
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
+
+	"gala/internal/pkg/logger"
 )
 
+// HeaderDeadline carries the caller's deadline (RFC3339) so the renderer can
+// give up on work nobody is waiting for.
+const HeaderDeadline = "X-Gala-Deadline"
+
+// HeaderRequestID carries the request ID of the job (see
+// logger.RequestIDFromContext), so the renderer's logs correlate with the
+// API's and the worker's.
+const HeaderRequestID = "X-Request-ID"
+
+// Client renders specs on the renderer service.
+//
+// Cancel contract: the request is bound to ctx. When ctx is canceled or its
+// deadline passes, the HTTP connection is closed and the call returns an
+// error wrapping ctx.Err(); the renderer must treat a closed connection as
+// "abandon this job" and stop work. If ctx has a deadline it is also sent in
+// X-Gala-Deadline so the renderer can refuse or abort work that cannot finish.
+// The request ID in ctx, if any, goes in X-Request-ID.
 type Client interface {
-	Render(spec any) error
-	RenderV1(spec any) error
+	Render(ctx context.Context, spec any) error
+	RenderV1(ctx context.Context, spec any) error
 }
 
 type HTTPClient struct {
-	baseURL string
+	baseURL atomic.Pointer[string]
 	client  *http.Client
+	auth    Authenticator
 }
 
 func NewHTTPClient(baseURL string) *HTTPClient {
-	return &HTTPClient{
-		baseURL: baseURL,
-		client:  &http.Client{Timeout: 10 * time.Minute},
+	c := &HTTPClient{
+		client: &http.Client{Timeout: 10 * time.Minute},
 	}
+	c.SetBaseURL(baseURL)
+	return c
+}
+
+// SetBaseURL points the client at another renderer. Requests already in
+// flight keep the previous URL.
+func (c *HTTPClient) SetBaseURL(baseURL string) {
+	c.baseURL.Store(&baseURL)
+}
+
+// BaseURL returns the renderer base URL currently in use.
+func (c *HTTPClient) BaseURL() string {
+	return *c.baseURL.Load()
 }
 
-func (c *HTTPClient) Render(spec any) error {
-	return c.post("/render", spec)
+// WithAuth sets the Authenticator applied to every request.
+// A nil Authenticator sends requests unauthenticated.
+func (c *HTTPClient) WithAuth(auth Authenticator) *HTTPClient {
+	c.auth = auth
+	return c
 }
 
-func (c *HTTPClient) RenderV1(spec any) error {
-	return c.post("/render/v1", spec)
+func (c *HTTPClient) Render(ctx context.Context, spec any) error {
+	return c.post(ctx, "/render", spec)
 }
 
-func (c *HTTPClient) post(path string, spec any) error {
+func (c *HTTPClient) RenderV1(ctx context.Context, spec any) error {
+	return c.post(ctx, "/render/v1", spec)
+}
+
+func (c *HTTPClient) post(ctx context.Context, path string, spec any) error {
 	body, err := json.Marshal(spec)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+path, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL()+path, bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set(HeaderDeadline, deadline.UTC().Format(time.RFC3339))
+	}
+	if id := logger.RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(HeaderRequestID, id)
+	}
+	if c.auth != nil {
+		if err := c.auth.Authenticate(req, body); err != nil {
+			return fmt.Errorf("renderer auth: %w", err)
+		}
+	}
 
 	res, err := c.client.Do(req)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("renderer request abandoned: %w", ctxErr)
+		}
 		return err
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		return fmt.Errorf("renderer http %d", res.StatusCode)
+		return &StatusError{Code: res.StatusCode}
+	}
+	return nil
+}
+
+// pingTimeout bounds a Ping that ctx does not bound sooner.
+const pingTimeout = 5 * time.Second
+
+// Ping checks that the renderer answers GET /health.
+func (c *HTTPClient) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL()+"/health", nil)
+	if err != nil {
+		return err
+	}
+	if c.auth != nil {
+		if err := c.auth.Authenticate(req, nil); err != nil {
+			return fmt.Errorf("renderer auth: %w", err)
+		}
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return &StatusError{Code: res.StatusCode}
 	}
 	return nil
 }
+
+// StatusError is a non-2xx answer of the renderer.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("renderer http %d", e.Code)
+}
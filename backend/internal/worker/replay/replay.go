@@ -0,0 +1,295 @@
+// Package replay re-runs a job's stored renderer spec and compares the new
+// outputs with the ones the job produced, to debug renderer regressions.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"gala/internal/pkg/pgerr"
+	"gala/internal/ports"
+	"gala/internal/store"
+	"gala/internal/worker/processor"
+	"gala/internal/worker/renderer"
+	"gala/internal/worker/util"
+)
+
+// Output comparison results.
+const (
+	Same    = "same"
+	Changed = "changed"
+	// Missing outputs are in the spec but the replay did not produce them.
+	Missing = "missing"
+	// New outputs were produced by the replay only.
+	New = "new"
+)
+
+// ErrNoSpec is returned for jobs without a stored renderer spec (rendered
+// before specs were stored, or failed before reaching the renderer).
+var ErrNoSpec = errors.New("job has no stored renderer spec")
+
+type Deps struct {
+	Pool *pgxpool.Pool
+	SP   ports.StorageProvider
+	// Renderer receives the spec; it must share StorageRoot with this
+	// process, as the worker does.
+	Renderer    renderer.Client
+	StorageRoot string
+	// Keep leaves the replay inputs and outputs under StorageRoot.
+	Keep bool
+}
+
+// Result is the outcome of a replay.
+type Result struct {
+	JobID    string        `json:"job_id"`
+	ReplayID string        `json:"replay_id"`
+	Version  string        `json:"version"`
+	Duration time.Duration `json:"duration_ns"`
+	Outputs  []OutputDiff  `json:"outputs"`
+	// Dir holds the replay files when Deps.Keep is set.
+	Dir string `json:"dir,omitempty"`
+}
+
+// Identical reports whether every output matched the job's.
+func (r *Result) Identical() bool {
+	for _, o := range r.Outputs {
+		if o.Status != Same {
+			return false
+		}
+	}
+	return true
+}
+
+// OutputDiff compares one output (video, thumb, captions) of the job with
+// the replay's.
+type OutputDiff struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	OriginalKey    string `json:"original_key,omitempty"`
+	ReplayKey      string `json:"replay_key,omitempty"`
+	OriginalSize   int64  `json:"original_size"`
+	ReplaySize     int64  `json:"replay_size"`
+	OriginalSHA256 string `json:"original_sha256,omitempty"`
+	ReplaySHA256   string `json:"replay_sha256,omitempty"`
+	// Detail is the first differing line of text outputs.
+	Detail string `json:"detail,omitempty"`
+}
+
+// Run sends the renderer spec stored for jobID again and compares the
+// outputs. The spec is sent as stored except for file locations: inputs
+// are downloaded again from their assets, and job_id and the output keys
+// point at a separate replay id so the job's files are never overwritten.
+func Run(ctx context.Context, d Deps, jobID string) (*Result, error) {
+	job, err := store.GetJob(ctx, d.Pool, jobID)
+	if err != nil {
+		if pgerr.IsNoRows(err) {
+			return nil, fmt.Errorf("job %s not found", jobID)
+		}
+		return nil, err
+	}
+	raw, err := store.JobRenderSpec(ctx, d.Pool, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, ErrNoSpec
+	}
+	var stored struct {
+		Version string         `json:"version"`
+		Spec    map[string]any `json:"spec"`
+	}
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		return nil, fmt.Errorf("invalid stored renderer spec: %w", err)
+	}
+	spec := stored.Spec
+
+	replayID := util.NewID("rpl")
+	res := &Result{JobID: jobID, ReplayID: replayID, Version: stored.Version}
+	if d.Keep {
+		res.Dir = filepath.Join(d.StorageRoot, "renders", replayID)
+	} else {
+		defer cleanup(d.StorageRoot, replayID)
+	}
+	spec["job_id"] = replayID
+
+	if _, ok := spec["inputs"]; ok {
+		inputs, err := jobInputs(job.ParamsJSON)
+		if err != nil {
+			return nil, err
+		}
+		paths, err := processor.NewInputHandler(d.Pool, d.SP, d.StorageRoot).Materialize(ctx, replayID, inputs)
+		if err != nil {
+			return nil, err
+		}
+		spec["inputs"] = paths
+	}
+	if wm, ok := spec["watermark"].(map[string]any); ok {
+		if id, _ := wm["asset_id"].(string); id != "" {
+			paths, err := processor.NewInputHandler(d.Pool, d.SP, d.StorageRoot).Materialize(ctx, replayID, map[string]string{"watermark": id})
+			if err != nil {
+				return nil, err
+			}
+			wm["path"] = paths["watermark"]
+		}
+	}
+
+	// Replay output keys, by spec field
+	replayed := map[string]string{}
+	if out, ok := spec["output"].(map[string]any); ok {
+		for field, v := range out {
+			key, ok := v.(string)
+			if !ok || !strings.HasSuffix(field, "_object_key") || key == "" {
+				continue
+			}
+			replayed[field] = path.Join("renders", replayID, path.Base(key))
+			out[field] = replayed[field]
+		}
+	}
+
+	start := time.Now()
+	err = processor.RenderWith(ctx, d.Renderer, processor.RenderSpec{Version: stored.Version, Spec: spec})
+	res.Duration = time.Since(start)
+	if err != nil {
+		return res, fmt.Errorf("render: %w", err)
+	}
+
+	// Compare with the assets the job registered; a job that failed has
+	// none, so every replay output is new
+	outputs, err := store.ListJobOutputs(ctx, d.Pool, jobID)
+	if err != nil {
+		return res, err
+	}
+	original := map[string]string{}
+	if len(outputs) > 0 {
+		o := outputs[0]
+		for field, key := range map[string]string{
+			"video_object_key":    o.VideoObjectKey,
+			"thumb_object_key":    o.ThumbObjectKey,
+			"captions_object_key": o.CaptionsObjectKey,
+		} {
+			if key != "" {
+				original[field] = key
+			}
+		}
+	}
+
+	fields := make([]string, 0, len(replayed))
+	for f := range replayed {
+		fields = append(fields, f)
+	}
+	for f := range original {
+		if _, ok := replayed[f]; !ok {
+			fields = append(fields, f)
+		}
+	}
+	sort.Strings(fields)
+	for _, f := range fields {
+		diff, err := compare(ctx, d, strings.TrimSuffix(f, "_object_key"), original[f], replayed[f])
+		if err != nil {
+			return res, err
+		}
+		res.Outputs = append(res.Outputs, diff)
+	}
+	return res, nil
+}
+
+// jobInputs returns the input asset ids of a job's params.
+func jobInputs(paramsJSON string) (map[string]string, error) {
+	var params struct {
+		Inputs map[string]any `json:"inputs"`
+	}
+	if err := json.Unmarshal([]byte(paramsJSON), &params); err != nil {
+		return nil, fmt.Errorf("invalid params_json: %w", err)
+	}
+	inputs := map[string]string{}
+	for k, v := range params.Inputs {
+		if s, ok := v.(string); ok && strings.TrimSpace(s) != "" {
+			inputs[k] = strings.TrimSpace(s)
+		}
+	}
+	return inputs, nil
+}
+
+func compare(ctx context.Context, d Deps, name, originalKey, replayKey string) (OutputDiff, error) {
+	diff := OutputDiff{Name: name, OriginalKey: originalKey, ReplayKey: replayKey}
+
+	var orig, replay []byte
+	if originalKey != "" {
+		rc, _, _, err := d.SP.GetObject(ctx, originalKey)
+		if err != nil {
+			return diff, fmt.Errorf("read %s output %s: %w", name, originalKey, err)
+		}
+		orig, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return diff, fmt.Errorf("read %s output %s: %w", name, originalKey, err)
+		}
+		diff.OriginalSize, diff.OriginalSHA256 = int64(len(orig)), digest(orig)
+	}
+	replayOK := false
+	if replayKey != "" {
+		b, err := os.ReadFile(filepath.Join(d.StorageRoot, filepath.FromSlash(replayKey)))
+		switch {
+		case err == nil:
+			replay, replayOK = b, true
+			diff.ReplaySize, diff.ReplaySHA256 = int64(len(replay)), digest(replay)
+		case !os.IsNotExist(err):
+			return diff, err
+		}
+	}
+
+	switch {
+	case !replayOK:
+		diff.Status = Missing
+	case originalKey == "":
+		diff.Status = New
+	case diff.OriginalSHA256 == diff.ReplaySHA256:
+		diff.Status = Same
+	default:
+		diff.Status = Changed
+		if strings.HasSuffix(originalKey, ".vtt") {
+			diff.Detail = firstDiffLine(orig, replay)
+		}
+	}
+	return diff, nil
+}
+
+func digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// firstDiffLine describes the first line that differs between two texts.
+func firstDiffLine(a, b []byte) string {
+	sa, sb := bufio.NewScanner(bytes.NewReader(a)), bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; ; line++ {
+		okA, okB := sa.Scan(), sb.Scan()
+		if !okA && !okB {
+			return ""
+		}
+		if okA != okB || sa.Text() != sb.Text() {
+			return fmt.Sprintf("line %d: %q -> %q", line, sa.Text(), sb.Text())
+		}
+	}
+}
+
+// cleanup removes the replay inputs and outputs.
+func cleanup(storageRoot, replayID string) {
+	_ = os.RemoveAll(filepath.Join(storageRoot, "jobs", replayID))
+	_ = os.RemoveAll(filepath.Join(storageRoot, "renders", replayID))
+}
@@ -2,72 +2,278 @@ package worker
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
 	"time"
 
 	"gala/internal/pkg/logger"
+	"gala/internal/pkg/reload"
+	"gala/internal/store"
 	"gala/internal/worker/processor"
 	"gala/internal/worker/queue"
+	"gala/internal/worker/registry"
 	"gala/internal/worker/renderer"
 )
 
-func Run(ctx context.Context, d Deps) error {
+// DefaultIdleBackoff is about how long the worker pauses after a pop that
+// found nothing, before popping again (QUEUE_IDLE_BACKOFF).
+const DefaultIdleBackoff = time.Second
+
+const (
+	// popMargin is how much longer than the pop timeout a pop may take
+	popMargin = 5 * time.Second
+	// maxErrBackoff caps the wait between failed pops, which doubles from
+	// 1s
+	maxErrBackoff = 30 * time.Second
+	// pauseCheck is about how often a paused worker checks whether the
+	// queue was resumed
+	pauseCheck = 2 * time.Second
+	// callbackGrace is how long a stopping worker waits for the job
+	// callbacks still being sent
+	callbackGrace = 10 * time.Second
+	// slotRetry is how long a job whose template had no free slot stays
+	// out of the queue before a worker takes it again
+	slotRetry = 2 * time.Second
+)
+
+// Run processes jobs from the queue until stop is closed, then returns nil
+// once the job in flight (if any) has finished and the job callbacks being
+// sent were delivered or given up on, waiting callbackGrace for them at
+// most. Canceling ctx also abandons the job in flight and the callbacks
+// and returns ctx.Err().
+func Run(ctx context.Context, stop <-chan struct{}, d Deps) error {
 	log := d.Log
 	if log == nil {
 		log = logger.NewDefault()
 	}
 	log = log.WithComponent("worker")
 
-	q := queue.NewRedisQueue(d.RDB, d.QueueName)
-	rc := renderer.NewHTTPClient(d.RendererBaseURL)
+	rc := renderer.NewHTTPClient(d.RendererBaseURL).WithAuth(d.RendererAuth)
+	breaker := renderer.NewBreaker(renderer.WithChaos(rc, d.Chaos), d.RendererBreakerThreshold)
 
 	p := processor.New(processor.Deps{
-		Pool:         d.Pool,
-		Renderer:     rc,
-		StorageRoot:  d.StorageRoot,
-		CleanupLocal: d.CleanupLocal,
-		SP:           d.SP,
-		Log:          log,
+		Pool:              d.Pool,
+		Renderer:          breaker,
+		StorageRoot:       d.StorageRoot,
+		CleanupLocal:      d.CleanupLocal,
+		SP:                d.SP,
+		Events:            d.Events,
+		Log:               log,
+		HLS:               d.HLS,
+		FormatCheck:       d.FormatCheck,
+		Fetch:             d.Fetch,
+		Callbacks:         d.Callbacks,
+		InputConcurrency:  d.InputConcurrency,
+		UploadConcurrency: d.UploadConcurrency,
+		UploadAttempts:    d.UploadAttempts,
+		TemplateCacheTTL:  d.TemplateCacheTTL,
+		Staging:           d.Staging,
+		Semaphore:         queue.NewSemaphore(d.RDB, 0),
+		RDB:               d.RDB,
+		ProgressBaseURL:   d.ProgressBaseURL,
+		QueueMode:         d.QueueMode,
+		JobTimeout:        d.JobTimeout,
 	})
 
-	for {
+	defer func() {
+		// A canceled ctx cuts the callbacks at once
+		cctx, cancel := context.WithTimeout(ctx, callbackGrace)
+		defer cancel()
+		p.Close(cctx)
+	}()
+
+	if d.Reload != nil {
+		d.Reload.Register("renderer", func(ctx context.Context, v reload.Values) error {
+			baseURL := v.Get("RENDERER_HTTP_BASEURL", "")
+			if baseURL == "" {
+				return fmt.Errorf("RENDERER_HTTP_BASEURL is empty")
+			}
+			if baseURL != rc.BaseURL() {
+				log.Info("renderer base URL changed", "from", rc.BaseURL(), "to", baseURL)
+				rc.SetBaseURL(baseURL)
+			}
+			return nil
+		})
+		d.Reload.Register("cleanup", func(ctx context.Context, v reload.Values) error {
+			p.SetCleanupLocal(v.Bool("WORKER_CLEANUP_LOCAL", false))
+			return nil
+		})
+	}
+
+	// popCtx is canceled on stop so a blocking pop returns right away;
+	// jobs run under ctx and are not interrupted by stop.
+	popCtx, cancelPops := context.WithCancel(ctx)
+	defer cancelPops()
+	go func() {
 		select {
-		case <-ctx.Done():
-			log.Info("worker context canceled, stopping")
-			return ctx.Err()
-		default:
+		case <-stop:
+			cancelPops()
+		case <-popCtx.Done():
 		}
+	}()
 
-		// Use a separate context with timeout for queue operations
-		popCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-		jobID, err := q.Pop(popCtx)
-		cancel()
+	go p.WatchTemplates(popCtx)
+	if d.Staging != nil && d.StagingSweep > 0 {
+		go d.Staging.Run(popCtx.Done(), d.StagingSweep)
+	}
 
-		if err != nil {
-			// Check if it's a context cancellation
+	// Registry entry: lives until Run returns, including the job in flight
+	// while stopping
+	hb := registry.NewHeartbeat(d.RDB, log, workerInfo(d), d.HeartbeatInterval)
+	hbCtx, stopHeartbeat := context.WithCancel(context.WithoutCancel(ctx))
+	hbDone := make(chan struct{})
+	go func() {
+		defer close(hbDone)
+		hb.Run(hbCtx)
+	}()
+	defer func() {
+		stopHeartbeat()
+		<-hbDone
+	}()
+
+	popTimeout := d.QueuePopTimeout
+	if popTimeout <= 0 {
+		popTimeout = queue.DefaultPopTimeout
+	}
+	idleBackoff := d.QueueIdleBackoff
+	if idleBackoff <= 0 {
+		idleBackoff = DefaultIdleBackoff
+	}
+
+	var q queue.Queue
+	switch d.QueueMode {
+	case "", queue.ModeRedis:
+		q = queue.NewRedisQueue(d.RDB, d.QueueName).WithPopTimeout(popTimeout)
+	case queue.ModePostgres:
+		pq := queue.NewPostgresQueue(d.Pool, log, d.QueuePollInterval)
+		go pq.Listen(popCtx)
+		q = pq
+	default:
+		return fmt.Errorf("unknown queue mode %q", d.QueueMode)
+	}
+
+	readyInterval := d.ReadyInterval
+	if readyInterval <= 0 {
+		readyInterval = DefaultReadyInterval
+	}
+	gate := &readiness{rc: rc, sp: d.SP, log: log, interval: readyInterval}
+	// Stopping while waiting is handled at the top of the loop
+	gate.wait(popCtx, "startup")
+
+	var (
+		errBackoff time.Duration
+		paused     bool
+	)
+	for {
+		select {
+		case <-popCtx.Done():
 			if ctx.Err() != nil {
-				log.Info("worker stopping due to context cancellation")
+				log.Info("worker context canceled, stopping")
 				return ctx.Err()
 			}
+			log.Info("worker stopped taking jobs")
+			return nil
+		default:
+		}
+
+		// Paused by a soft drain: finish nothing new until resumed. If
+		// Redis cannot tell, keep taking jobs
+		if d.RDB != nil {
+			since, err := queue.PausedSince(popCtx, d.RDB)
+			if err == nil && paused != !since.IsZero() {
+				paused = !paused
+				hb.SetPaused(paused)
+				if paused {
+					log.Info("queue paused, not taking new jobs", "paused_at", since)
+				} else {
+					log.Info("queue resumed")
+				}
+			}
+			if err == nil && paused {
+				sleepCtx(popCtx, jitter(pauseCheck))
+				continue
+			}
+		}
+
+		// Renders keep failing with the renderer down: take no jobs until
+		// it answers again
+		if opened := breaker.OpenSince(); !opened.IsZero() {
+			log.Warn("renderer circuit open, not taking jobs", "opened_at", opened)
+			if gate.wait(popCtx, "renderer circuit open") {
+				breaker.Reset()
+				log.Info("renderer circuit closed", "open_ms", time.Since(opened).Milliseconds())
+			}
+			continue
+		}
+
+		// The redis queue returns on its own at popTimeout; the margin
+		// keeps the context from cutting the BRPOP first
+		opCtx, cancel := context.WithTimeout(popCtx, popTimeout+popMargin)
+		jobID, err := q.Pop(opCtx)
+		cancel()
 
+		// Stopping or canceled: handled at the top of the loop
+		if popCtx.Err() != nil {
+			continue
+		}
+		// Nothing queued within the pop timeout: idle, not an error
+		if (err == nil && jobID == "") || errors.Is(err, context.DeadlineExceeded) {
+			errBackoff = 0
+			sleepCtx(popCtx, jitter(idleBackoff))
+			continue
+		}
+		if err != nil {
+			errBackoff = min(max(2*errBackoff, time.Second), maxErrBackoff)
+			wait := jitter(errBackoff)
 			log.Warn("queue pop error, retrying",
 				"error", err.Error(),
+				"retry_in", wait.String(),
 			)
-			time.Sleep(1 * time.Second)
+			sleepCtx(popCtx, wait)
 			continue
 		}
+		errBackoff = 0
 
-		if jobID == "" {
-			continue
+		// The job is published as this worker's before the pause is
+		// checked again: a drain that paused the queue while the pop was
+		// blocked either sees it here or in the registry
+		hb.SetJob(ctx, jobID)
+		if d.RDB != nil {
+			if since, err := queue.PausedSince(popCtx, d.RDB); err == nil && !since.IsZero() {
+				if giveBack(ctx, q, d, jobID, 0, log) {
+					log.Info("queue paused while popping, job returned to the queue", "job_id", jobID)
+					hb.SetJob(ctx, "")
+					continue
+				}
+			}
 		}
 
-		// Create a context for this job
+		// The job timeout starts in ProcessJob, once the job holds its
+		// template's slot
 		jobCtx := logger.ContextWithJobID(ctx, jobID)
 		jobLog := log.WithJobID(jobID)
 
 		jobLog.Info("processing job")
 		startTime := time.Now()
 
-		if err := p.ProcessJob(jobCtx, jobID); err != nil {
+		if err := p.ProcessJob(jobCtx, jobID); errors.Is(err, processor.ErrSlotBusy) {
+			// Other jobs go first; this one is back in the queue after
+			// slotRetry. If it cannot be deferred it goes back first in
+			// line, and the worker waits out slotRetry itself
+			if !giveBack(ctx, q, d, jobID, slotRetry, log) {
+				if !giveBack(ctx, q, d, jobID, 0, log) {
+					jobLog.Error("job left out of the queue: its template had no free slot and it could not be given back")
+				}
+				hb.SetJob(ctx, "")
+				sleepCtx(popCtx, jitter(slotRetry))
+			}
+		} else if errors.Is(err, processor.ErrJobCanceled) {
+			jobLog.Info("job canceled",
+				"duration_ms", time.Since(startTime).Milliseconds(),
+			)
+		} else if err != nil {
 			jobLog.Error("job failed",
 				"error", err.Error(),
 				"duration_ms", time.Since(startTime).Milliseconds(),
@@ -77,5 +283,62 @@ func Run(ctx context.Context, d Deps) error {
 				"duration_ms", time.Since(startTime).Milliseconds(),
 			)
 		}
+		hb.SetJob(ctx, "")
+	}
+}
+
+// giveBack returns jobID, which the worker popped and did not start, to q:
+// first in line, or deferred for delay if delay > 0. It reports whether it
+// did; when the queue was paused the worker runs a job it could not give
+// back rather than leave it QUEUED and out of the list.
+func giveBack(ctx context.Context, q queue.Queue, d Deps, jobID string, delay time.Duration, log *logger.Logger) bool {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	job, err := store.GetJob(ctx, d.Pool, jobID)
+	if err == nil {
+		if delay > 0 {
+			err = q.Defer(ctx, jobID, job.Priority, delay)
+		} else {
+			err = q.Return(ctx, jobID, job.Priority)
+		}
+	}
+	if err != nil {
+		log.Warn("could not give a job back to the queue",
+			"job_id", jobID,
+			"delay", delay.String(),
+			"error", err.Error(),
+		)
+		return false
+	}
+	return true
+}
+
+// workerInfo describes this worker for the registry.
+func workerInfo(d Deps) registry.Worker {
+	hostname, _ := os.Hostname()
+	mode := d.QueueMode
+	if mode == "" {
+		mode = queue.ModeRedis
+	}
+	id := d.WorkerID
+	if id == "" {
+		id = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	return registry.Worker{ID: id, Hostname: hostname, PID: os.Getpid(), QueueMode: mode}
+}
+
+// jitter returns a random duration in [d/2, d), so idle workers do not
+// hit the queue in lockstep.
+func jitter(d time.Duration) time.Duration {
+	return d/2 + rand.N(d/2+1)
+}
+
+// sleepCtx waits d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
 	}
 }
@@ -0,0 +1,77 @@
+package worker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gala/internal/store"
+	"gala/internal/testinfra"
+	"gala/internal/worker"
+	"gala/internal/worker/queue"
+)
+
+func TestMain(m *testing.M) { testinfra.Main(m) }
+
+// A job popped by a worker that was already blocked in BRPOP when the
+// queue was paused goes back to the queue, not to the renderer.
+func TestPauseWhilePopping(t *testing.T) {
+	env := testinfra.New(t)
+	ctx := context.Background()
+
+	d := env.WorkerDeps()
+	// Long enough for the pop to be in flight when the queue is paused
+	d.QueuePopTimeout = 30 * time.Second
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- worker.Run(ctx, stop, d) }()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	// Let the worker get past its readiness check and block in BRPOP
+	time.Sleep(500 * time.Millisecond)
+	if err := queue.Pause(ctx, env.RDB); err != nil {
+		t.Fatal(err)
+	}
+	err := store.InsertJob(ctx, env.Pool, store.Job{ID: "job_1", ParamsJSON: `{"text":"hola"}`, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.RDB.LPush(ctx, queue.DefaultName, "job_1").Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The blocked BRPOP takes the job at once; give the worker time to
+	// hand it back
+	time.Sleep(time.Second)
+	ids, err := env.RDB.LRange(ctx, queue.DefaultName, 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "job_1" {
+		t.Fatalf("queue holds %v, want job_1 back in it", ids)
+	}
+	job, err := store.GetJob(ctx, env.Pool, "job_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != store.JobQueued || job.Attempts != 0 {
+		t.Fatalf("job = %s with %d attempts, want QUEUED and never started", job.Status, job.Attempts)
+	}
+
+	if err := queue.Resume(ctx, env.RDB); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for job.Status == store.JobQueued {
+		if time.Now().After(deadline) {
+			t.Fatal("job still QUEUED after resuming")
+		}
+		time.Sleep(100 * time.Millisecond)
+		if job, err = store.GetJob(ctx, env.Pool, "job_1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
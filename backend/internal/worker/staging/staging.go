@@ -0,0 +1,286 @@
+// Package staging manages the worker's staging area: the jobs/{id}
+// directories under the storage root where inputs are materialized for
+// the renderer. It checks the free disk space before a job uses it, evicts
+// the least recently used job directories when the area is over its budget
+// or the disk runs low, and exports the usage as gauges.
+package staging
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/metrics"
+)
+
+// Defaults of Config.
+const (
+	DefaultMinAge   = time.Hour
+	DefaultInterval = 10 * time.Minute
+)
+
+// ErrLowDisk: the filesystem of the staging area has less free space than
+// Config.MinFreeBytes, even after evicting old job directories.
+var ErrLowDisk = errors.New("not enough free disk space in the staging area")
+
+// Config configures a Manager.
+type Config struct {
+	// Root is the storage root; the job directories are Root/jobs/{id}.
+	Root string
+	// MinFreeBytes is the free space a job needs before it materializes
+	// its inputs or renders; 0 disables the check.
+	MinFreeBytes int64
+	// MaxBytes is the budget of Root/jobs: sweeps evict job directories
+	// above it. 0 means no budget.
+	MaxBytes int64
+	// MinAge protects the job directories modified more recently than
+	// this from eviction, such as those of jobs running on other workers
+	// that share Root; 0 uses DefaultMinAge.
+	MinAge time.Duration
+}
+
+// Usage is the state of the staging area after a sweep.
+type Usage struct {
+	// Bytes and Jobs are the size and number of the job directories left.
+	Bytes int64
+	Jobs  int
+	// FreeBytes is the free space of the filesystem, -1 if unknown.
+	FreeBytes int64
+	// Evicted and EvictedBytes are what the sweep removed.
+	Evicted      int
+	EvictedBytes int64
+}
+
+// Manager checks and cleans the staging area. A nil *Manager checks
+// nothing.
+type Manager struct {
+	cfg Config
+	log *logger.Logger
+	now func() time.Time
+
+	mu     sync.Mutex
+	active map[string]int
+	// sweeping serializes sweeps: Run and Ensure may overlap
+	sweeping sync.Mutex
+
+	// nil without a metrics registry
+	bytes, jobs, free *metrics.GaugeVec
+	evictions         *metrics.CounterVec
+}
+
+// New creates a manager. reg, if not nil, receives the usage gauges.
+func New(cfg Config, log *logger.Logger, reg *metrics.Registry) *Manager {
+	if cfg.MinAge <= 0 {
+		cfg.MinAge = DefaultMinAge
+	}
+	m := &Manager{
+		cfg:    cfg,
+		log:    log.WithComponent("staging"),
+		now:    time.Now,
+		active: map[string]int{},
+	}
+	if reg != nil {
+		m.bytes = reg.NewGaugeVec("gala_worker_staging_bytes", "Size of the job directories in the worker staging area.")
+		m.jobs = reg.NewGaugeVec("gala_worker_staging_jobs", "Job directories in the worker staging area.")
+		m.free = reg.NewGaugeVec("gala_worker_staging_free_bytes", "Free space of the staging filesystem.")
+		m.evictions = reg.NewCounterVec("gala_worker_staging_evictions_total", "Job directories evicted from the staging area, since start.")
+	}
+	return m
+}
+
+// Acquire marks a job's directory in use, so sweeps do not evict it, until
+// the returned function is called.
+func (m *Manager) Acquire(jobID string) (release func()) {
+	if m == nil {
+		return func() {}
+	}
+	m.mu.Lock()
+	m.active[jobID]++
+	m.mu.Unlock()
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.active[jobID]--; m.active[jobID] <= 0 {
+			delete(m.active, jobID)
+		}
+	}
+}
+
+// Ensure checks that the filesystem has MinFreeBytes free, sweeping first
+// when it does not. Its error wraps ErrLowDisk. Where the free space
+// cannot be read the check passes.
+func (m *Manager) Ensure() error {
+	if m == nil || m.cfg.MinFreeBytes <= 0 {
+		return nil
+	}
+	free, err := freeBytes(m.cfg.Root)
+	if err != nil || free >= m.cfg.MinFreeBytes {
+		return nil
+	}
+
+	u, err := m.Sweep()
+	if err != nil {
+		return err
+	}
+	if u.FreeBytes >= 0 && u.FreeBytes < m.cfg.MinFreeBytes {
+		return fmt.Errorf("%w: %d MiB free in %s, %d MiB required",
+			ErrLowDisk, u.FreeBytes>>20, m.cfg.Root, m.cfg.MinFreeBytes>>20)
+	}
+	return nil
+}
+
+// jobDir is a job directory of the staging area.
+type jobDir struct {
+	path     string
+	id       string
+	size     int64
+	lastUsed time.Time
+}
+
+// Sweep evicts job directories, least recently modified first, while the
+// area is over MaxBytes or the disk has less than MinFreeBytes free. It
+// skips the directories in use and those younger than MinAge.
+func (m *Manager) Sweep() (Usage, error) {
+	m.sweeping.Lock()
+	defer m.sweeping.Unlock()
+
+	dirs, err := m.scan()
+	if err != nil {
+		return Usage{}, err
+	}
+	u := Usage{Jobs: len(dirs), FreeBytes: -1}
+	for _, d := range dirs {
+		u.Bytes += d.size
+	}
+	if free, err := freeBytes(m.cfg.Root); err == nil {
+		u.FreeBytes = free
+	}
+
+	overBudget := func() bool { return m.cfg.MaxBytes > 0 && u.Bytes > m.cfg.MaxBytes }
+	lowDisk := func() bool {
+		return m.cfg.MinFreeBytes > 0 && u.FreeBytes >= 0 && u.FreeBytes < m.cfg.MinFreeBytes
+	}
+
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].lastUsed.Before(dirs[j].lastUsed) })
+	now := m.now()
+	for _, d := range dirs {
+		if !overBudget() && !lowDisk() {
+			break
+		}
+		if m.inUse(d.id) || now.Sub(d.lastUsed) < m.cfg.MinAge {
+			continue
+		}
+		if err := os.RemoveAll(d.path); err != nil {
+			m.log.Warn("staging eviction failed", "job_id", d.id, "error", err.Error())
+			continue
+		}
+		u.Bytes -= d.size
+		u.Jobs--
+		if u.FreeBytes >= 0 {
+			u.FreeBytes += d.size
+		}
+		u.Evicted++
+		u.EvictedBytes += d.size
+	}
+
+	m.record(u)
+	args := []any{
+		"bytes", u.Bytes,
+		"jobs", u.Jobs,
+		"free_bytes", u.FreeBytes,
+		"evicted", u.Evicted,
+		"evicted_bytes", u.EvictedBytes,
+	}
+	switch {
+	case overBudget() || lowDisk():
+		m.log.Warn("staging area over its limits", args...)
+	case u.Evicted > 0:
+		m.log.Info("staging area cleaned", args...)
+	default:
+		m.log.Debug("staging area usage", args...)
+	}
+	return u, nil
+}
+
+// Run sweeps every interval (DefaultInterval if <= 0) until done is
+// closed.
+func (m *Manager) Run(done <-chan struct{}, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if _, err := m.Sweep(); err != nil {
+			m.log.Warn("staging sweep failed", "error", err.Error())
+		}
+		select {
+		case <-done:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (m *Manager) inUse(jobID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active[jobID] > 0
+}
+
+// scan measures every directory under Root/jobs.
+func (m *Manager) scan() ([]jobDir, error) {
+	base := filepath.Join(m.cfg.Root, "jobs")
+	entries, err := os.ReadDir(base)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]jobDir, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		d := jobDir{path: filepath.Join(base, e.Name()), id: e.Name()}
+		// Files may go away while walking (a job finishing); skip them
+		_ = filepath.WalkDir(d.path, func(_ string, de fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			info, err := de.Info()
+			if err != nil {
+				return nil
+			}
+			if !de.IsDir() {
+				d.size += info.Size()
+			}
+			if info.ModTime().After(d.lastUsed) {
+				d.lastUsed = info.ModTime()
+			}
+			return nil
+		})
+		dirs = append(dirs, d)
+	}
+	return dirs, nil
+}
+
+func (m *Manager) record(u Usage) {
+	if m.bytes == nil {
+		return
+	}
+	m.bytes.Set(float64(u.Bytes))
+	m.jobs.Set(float64(u.Jobs))
+	if u.FreeBytes >= 0 {
+		m.free.Set(float64(u.FreeBytes))
+	}
+	m.evictions.Add(float64(u.Evicted))
+}
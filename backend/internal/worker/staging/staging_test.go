@@ -0,0 +1,141 @@
+package staging
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gala/internal/pkg/logger"
+	"gala/internal/pkg/metrics"
+)
+
+// writeJob creates root/jobs/{id} with a file of size bytes, last modified
+// age ago.
+func writeJob(t *testing.T, root, id string, size int, age time.Duration) {
+	t.Helper()
+	dir := filepath.Join(root, "jobs", id, "inputs")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "avatar.jpg")
+	if err := os.WriteFile(file, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(-age)
+	for _, p := range []string{file, dir, filepath.Dir(dir)} {
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func exists(root, id string) bool {
+	_, err := os.Stat(filepath.Join(root, "jobs", id))
+	return err == nil
+}
+
+func TestSweepEvictsOldestOverBudget(t *testing.T) {
+	root := t.TempDir()
+	writeJob(t, root, "oldest", 1000, 3*time.Hour)
+	writeJob(t, root, "old", 1000, 2*time.Hour)
+	writeJob(t, root, "recent", 1000, 90*time.Minute)
+
+	m := New(Config{Root: root, MaxBytes: 2000}, logger.NewDefault(), nil)
+	u, err := m.Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Evicted != 1 || u.EvictedBytes != 1000 || u.Bytes != 2000 || u.Jobs != 2 {
+		t.Errorf("usage = %+v, want one eviction down to 2000 bytes", u)
+	}
+	if exists(root, "oldest") || !exists(root, "old") || !exists(root, "recent") {
+		t.Error("expected only the least recently used job to be evicted")
+	}
+}
+
+func TestSweepKeepsRecentAndActiveJobs(t *testing.T) {
+	root := t.TempDir()
+	writeJob(t, root, "active", 1000, 3*time.Hour)
+	writeJob(t, root, "fresh", 1000, time.Minute)
+
+	m := New(Config{Root: root, MaxBytes: 1}, logger.NewDefault(), nil)
+	release := m.Acquire("active")
+	u, err := m.Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Evicted != 0 || !exists(root, "active") || !exists(root, "fresh") {
+		t.Errorf("usage = %+v, want no eviction of an active or fresh job", u)
+	}
+
+	release()
+	if u, _ = m.Sweep(); u.Evicted != 1 || exists(root, "active") {
+		t.Errorf("usage = %+v, want the released job evicted", u)
+	}
+}
+
+func TestSweepWithoutJobsDir(t *testing.T) {
+	m := New(Config{Root: t.TempDir(), MaxBytes: 1}, logger.NewDefault(), nil)
+	if u, err := m.Sweep(); err != nil || u.Jobs != 0 {
+		t.Errorf("Sweep() = %+v, %v; want an empty area", u, err)
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	root := t.TempDir()
+	free, err := freeBytes(root)
+	if err != nil {
+		t.Skip("free space not available:", err)
+	}
+
+	if err := New(Config{Root: root, MinFreeBytes: 1 << 20}, logger.NewDefault(), nil).Ensure(); err != nil {
+		t.Errorf("Ensure() = %v with enough space", err)
+	}
+
+	err = New(Config{Root: root, MinFreeBytes: free * 4}, logger.NewDefault(), nil).Ensure()
+	if !errors.Is(err, ErrLowDisk) {
+		t.Errorf("Ensure() = %v, want ErrLowDisk", err)
+	}
+
+	var m *Manager
+	if err := m.Ensure(); err != nil {
+		t.Errorf("nil Manager Ensure() = %v", err)
+	}
+	m.Acquire("job")()
+}
+
+func TestSweepMetrics(t *testing.T) {
+	root := t.TempDir()
+	writeJob(t, root, "a", 300, 3*time.Hour)
+	writeJob(t, root, "b", 200, 2*time.Hour)
+
+	var buf bytes.Buffer
+	log := logger.New(logger.Config{Level: "debug", Format: "json", Output: &buf})
+	reg := metrics.NewRegistry()
+	m := New(Config{Root: root, MaxBytes: 250}, log, reg)
+	if _, err := m.Sweep(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"msg":"staging area cleaned"`) {
+		t.Errorf("expected a cleanup log, got: %s", buf.String())
+	}
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"gala_worker_staging_bytes 200",
+		"gala_worker_staging_jobs 1",
+		"gala_worker_staging_evictions_total 1",
+		"gala_worker_staging_free_bytes",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in metrics output:\n%s", want, body)
+		}
+	}
+}
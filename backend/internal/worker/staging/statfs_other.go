@@ -0,0 +1,10 @@
+//go:build !unix
+
+package staging
+
+import "errors"
+
+// freeBytes is not implemented here: the free space checks are skipped.
+func freeBytes(string) (int64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS job_outputs;
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS templates;
+DROP TABLE IF EXISTS assets;
@@ -1,4 +1,5 @@
--- infra/postgres/init.sql
+-- Initial schema. IF NOT EXISTS so databases created by the old
+-- infra/postgres/init.sql adopt it without changes.
 
 CREATE TABLE IF NOT EXISTS assets (
   id           TEXT PRIMARY KEY,
@@ -33,7 +34,7 @@ CREATE TABLE IF NOT EXISTS job_outputs (
   created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
--- ✅ TEMPLATES (Punto 4.1)
+-- Templates (soft-deleted via deleted_at)
 CREATE TABLE IF NOT EXISTS templates (
   id           TEXT PRIMARY KEY,
   type         TEXT NOT NULL,
@@ -0,0 +1,22 @@
+-- Wake workers (QUEUE_MODE=postgres) as soon as a job is queued. The
+-- payload is the job id; NOTIFY is delivered on commit, so listeners never
+-- see a job they cannot read yet.
+
+CREATE OR REPLACE FUNCTION notify_job_queued() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('gala_jobs', NEW.id);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS jobs_notify_queued ON jobs;
+CREATE TRIGGER jobs_notify_queued
+  AFTER INSERT OR UPDATE OF status ON jobs
+  FOR EACH ROW
+  WHEN (NEW.status = 'QUEUED')
+  EXECUTE FUNCTION notify_job_queued();
+
+-- Oldest-first claim of queued jobs
+CREATE INDEX IF NOT EXISTS idx_jobs_queued
+  ON jobs (created_at)
+  WHERE status = 'QUEUED';
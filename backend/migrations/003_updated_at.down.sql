@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS assets_set_updated_at ON assets;
+DROP TRIGGER IF EXISTS jobs_set_updated_at ON jobs;
+DROP TRIGGER IF EXISTS templates_set_updated_at ON templates;
+DROP FUNCTION IF EXISTS set_updated_at();
+
+ALTER TABLE assets DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE jobs DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE templates DROP COLUMN IF EXISTS updated_at;
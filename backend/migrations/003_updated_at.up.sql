@@ -0,0 +1,50 @@
+-- updated_at on templates, jobs and assets, maintained by a trigger so
+-- every writer (API, worker, reaper, manual SQL) keeps it right. Existing
+-- rows get their latest known timestamp.
+
+ALTER TABLE templates ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE templates SET updated_at = GREATEST(created_at, deleted_at) WHERE updated_at IS NULL;
+ALTER TABLE templates
+  ALTER COLUMN updated_at SET DEFAULT NOW(),
+  ALTER COLUMN updated_at SET NOT NULL;
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE jobs SET updated_at = GREATEST(created_at, started_at, finished_at) WHERE updated_at IS NULL;
+ALTER TABLE jobs
+  ALTER COLUMN updated_at SET DEFAULT NOW(),
+  ALTER COLUMN updated_at SET NOT NULL;
+
+ALTER TABLE assets ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE assets SET updated_at = created_at WHERE updated_at IS NULL;
+ALTER TABLE assets
+  ALTER COLUMN updated_at SET DEFAULT NOW(),
+  ALTER COLUMN updated_at SET NOT NULL;
+
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at = NOW();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Only real changes bump updated_at
+DROP TRIGGER IF EXISTS templates_set_updated_at ON templates;
+CREATE TRIGGER templates_set_updated_at
+  BEFORE UPDATE ON templates
+  FOR EACH ROW
+  WHEN (OLD.* IS DISTINCT FROM NEW.*)
+  EXECUTE FUNCTION set_updated_at();
+
+DROP TRIGGER IF EXISTS jobs_set_updated_at ON jobs;
+CREATE TRIGGER jobs_set_updated_at
+  BEFORE UPDATE ON jobs
+  FOR EACH ROW
+  WHEN (OLD.* IS DISTINCT FROM NEW.*)
+  EXECUTE FUNCTION set_updated_at();
+
+DROP TRIGGER IF EXISTS assets_set_updated_at ON assets;
+CREATE TRIGGER assets_set_updated_at
+  BEFORE UPDATE ON assets
+  FOR EACH ROW
+  WHEN (OLD.* IS DISTINCT FROM NEW.*)
+  EXECUTE FUNCTION set_updated_at();
@@ -0,0 +1,51 @@
+DROP INDEX IF EXISTS idx_job_outputs_captions;
+DROP INDEX IF EXISTS idx_job_outputs_thumbnail;
+DROP INDEX IF EXISTS idx_job_outputs_video;
+
+DROP INDEX IF EXISTS idx_assets_kind_created;
+DROP INDEX IF EXISTS idx_assets_created;
+CREATE INDEX IF NOT EXISTS idx_assets_kind ON assets(kind);
+
+DROP INDEX IF EXISTS idx_templates_active;
+CREATE INDEX idx_templates_active ON templates (created_at) WHERE deleted_at IS NULL;
+
+ALTER TABLE jobs RENAME TO jobs_partitioned;
+
+CREATE TABLE jobs (
+  id           TEXT PRIMARY KEY,
+  name         TEXT NULL,
+  status       TEXT NOT NULL,
+  params_json  TEXT NOT NULL,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  started_at   TIMESTAMPTZ NULL,
+  finished_at  TIMESTAMPTZ NULL,
+  error_text   TEXT NULL,
+  updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO jobs (id, name, status, params_json, created_at, started_at, finished_at, error_text, updated_at)
+SELECT id, name, status, params_json, created_at, started_at, finished_at, error_text, updated_at
+FROM jobs_partitioned;
+
+DROP TABLE jobs_partitioned;
+DROP FUNCTION IF EXISTS ensure_job_partitions(DATE, INT);
+
+CREATE INDEX idx_jobs_status ON jobs(status);
+CREATE INDEX idx_jobs_queued ON jobs (created_at) WHERE status = 'QUEUED';
+
+CREATE TRIGGER jobs_notify_queued
+  AFTER INSERT OR UPDATE OF status ON jobs
+  FOR EACH ROW
+  WHEN (NEW.status = 'QUEUED')
+  EXECUTE FUNCTION notify_job_queued();
+
+CREATE TRIGGER jobs_set_updated_at
+  BEFORE UPDATE ON jobs
+  FOR EACH ROW
+  WHEN (OLD.* IS DISTINCT FROM NEW.*)
+  EXECUTE FUNCTION set_updated_at();
+
+-- Outputs of jobs that no longer exist would fail the constraint
+DELETE FROM job_outputs o WHERE NOT EXISTS (SELECT 1 FROM jobs j WHERE j.id = o.job_id);
+ALTER TABLE job_outputs
+  ADD CONSTRAINT job_outputs_job_id_fkey FOREIGN KEY (job_id) REFERENCES jobs(id) ON DELETE CASCADE;
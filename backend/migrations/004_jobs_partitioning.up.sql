@@ -0,0 +1,107 @@
+-- Monthly range partitioning of jobs by created_at, plus keyset indexes
+-- for the list/export queries (ORDER BY created_at DESC, id DESC).
+--
+-- Partitions are named jobs_YYYY_MM (UTC months). ensure_job_partitions()
+-- creates the missing ones; the worker runs it daily as a singleton task
+-- so upcoming months always exist. jobs_default only catches rows outside
+-- every partition and should stay empty.
+--
+-- The primary key must include the partition key, so it becomes
+-- (id, created_at), and job_outputs can no longer reference jobs(id) with
+-- a foreign key (it would also block detaching old partitions for
+-- archival). job_outputs.job_id keeps its index.
+
+ALTER TABLE job_outputs DROP CONSTRAINT IF EXISTS job_outputs_job_id_fkey;
+
+ALTER TABLE jobs RENAME TO jobs_unpartitioned;
+ALTER TABLE jobs_unpartitioned RENAME CONSTRAINT jobs_pkey TO jobs_unpartitioned_pkey;
+DROP TRIGGER IF EXISTS jobs_notify_queued ON jobs_unpartitioned;
+DROP TRIGGER IF EXISTS jobs_set_updated_at ON jobs_unpartitioned;
+DROP INDEX IF EXISTS idx_jobs_status;
+DROP INDEX IF EXISTS idx_jobs_queued;
+
+CREATE TABLE jobs (
+  id           TEXT NOT NULL,
+  name         TEXT NULL,
+  status       TEXT NOT NULL,
+  params_json  TEXT NOT NULL,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  started_at   TIMESTAMPTZ NULL,
+  finished_at  TIMESTAMPTZ NULL,
+  error_text   TEXT NULL,
+  updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+CREATE TABLE jobs_default PARTITION OF jobs DEFAULT;
+
+-- Creates the monthly partitions from from_month through months_ahead
+-- months after the current one; returns how many it created.
+CREATE OR REPLACE FUNCTION ensure_job_partitions(from_month DATE, months_ahead INT)
+RETURNS INT AS $$
+DECLARE
+  m       DATE := date_trunc('month', from_month)::date;
+  last    DATE := (date_trunc('month', NOW() AT TIME ZONE 'UTC') + make_interval(months => months_ahead))::date;
+  part    TEXT;
+  created INT := 0;
+BEGIN
+  WHILE m <= last LOOP
+    part := format('jobs_%s', to_char(m, 'YYYY_MM'));
+    IF to_regclass(part) IS NULL THEN
+      EXECUTE format(
+        'CREATE TABLE %I PARTITION OF jobs FOR VALUES FROM (%L) TO (%L)',
+        part,
+        m::timestamp AT TIME ZONE 'UTC',
+        (m + INTERVAL '1 month')::timestamp AT TIME ZONE 'UTC'
+      );
+      created := created + 1;
+    END IF;
+    m := (m + INTERVAL '1 month')::date;
+  END LOOP;
+  RETURN created;
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT ensure_job_partitions(
+  COALESCE(
+    (SELECT (MIN(created_at) AT TIME ZONE 'UTC')::date FROM jobs_unpartitioned),
+    (NOW() AT TIME ZONE 'UTC')::date
+  ),
+  3
+);
+
+INSERT INTO jobs (id, name, status, params_json, created_at, started_at, finished_at, error_text, updated_at)
+SELECT id, name, status, params_json, created_at, started_at, finished_at, error_text, updated_at
+FROM jobs_unpartitioned;
+
+DROP TABLE jobs_unpartitioned;
+
+-- Keyset pagination and filters
+CREATE INDEX idx_jobs_created ON jobs (created_at DESC, id DESC);
+CREATE INDEX idx_jobs_status_created ON jobs (status, created_at DESC, id DESC);
+CREATE INDEX idx_jobs_queued ON jobs (created_at) WHERE status = 'QUEUED';
+
+CREATE TRIGGER jobs_notify_queued
+  AFTER INSERT OR UPDATE OF status ON jobs
+  FOR EACH ROW
+  WHEN (NEW.status = 'QUEUED')
+  EXECUTE FUNCTION notify_job_queued();
+
+CREATE TRIGGER jobs_set_updated_at
+  BEFORE UPDATE ON jobs
+  FOR EACH ROW
+  WHEN (OLD.* IS DISTINCT FROM NEW.*)
+  EXECUTE FUNCTION set_updated_at();
+
+-- Templates and assets: same keyset order
+DROP INDEX IF EXISTS idx_templates_active;
+CREATE INDEX idx_templates_active ON templates (created_at DESC, id DESC) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_assets_kind;
+CREATE INDEX idx_assets_created ON assets (created_at DESC, id DESC);
+CREATE INDEX idx_assets_kind_created ON assets (kind, created_at DESC, id DESC);
+
+-- "Is this asset used by an output?" checks on asset delete
+CREATE INDEX IF NOT EXISTS idx_job_outputs_video ON job_outputs (video_asset_id);
+CREATE INDEX IF NOT EXISTS idx_job_outputs_thumbnail ON job_outputs (thumbnail_asset_id) WHERE thumbnail_asset_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_job_outputs_captions ON job_outputs (captions_asset_id) WHERE captions_asset_id IS NOT NULL;
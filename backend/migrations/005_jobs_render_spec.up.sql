@@ -0,0 +1,6 @@
+-- The renderer spec sent for each job (with the endpoint version), stored
+-- by the worker right before it calls the renderer so the render can be
+-- replayed later (galactl jobs replay). NULL for jobs rendered before this
+-- migration or that never reached the renderer.
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS render_spec JSONB NULL;
@@ -0,0 +1,35 @@
+-- Publications of job outputs to external targets (YouTube, ...). One row
+-- per attempt: a failed publication stays for the record and a new one can
+-- be requested; at most one live (PENDING, UPLOADING or DONE) publication
+-- per output and target. jobs is partitioned, so job_id has no foreign key
+-- (same as job_outputs after 004).
+
+CREATE TABLE IF NOT EXISTS job_publications (
+  id             TEXT PRIMARY KEY,
+  job_id         TEXT NOT NULL,
+  output_id      TEXT NOT NULL REFERENCES job_outputs(id) ON DELETE CASCADE,
+  target         TEXT NOT NULL,
+  status         TEXT NOT NULL DEFAULT 'PENDING',
+  metadata       JSONB NOT NULL DEFAULT '{}'::jsonb,
+  external_id    TEXT NULL,
+  external_url   TEXT NULL,
+  error_text     TEXT NULL,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  published_at   TIMESTAMPTZ NULL,
+  CONSTRAINT job_publications_status_check
+    CHECK (status IN ('PENDING','UPLOADING','DONE','FAILED'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_publications_job_id ON job_publications(job_id);
+CREATE INDEX IF NOT EXISTS idx_job_publications_status ON job_publications(status);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_job_publications_live
+  ON job_publications(output_id, target)
+  WHERE status <> 'FAILED';
+
+DROP TRIGGER IF EXISTS job_publications_set_updated_at ON job_publications;
+CREATE TRIGGER job_publications_set_updated_at
+  BEFORE UPDATE ON job_publications
+  FOR EACH ROW
+  WHEN (OLD.* IS DISTINCT FROM NEW.*)
+  EXECUTE FUNCTION set_updated_at();
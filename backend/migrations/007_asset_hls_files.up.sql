@@ -0,0 +1,14 @@
+-- HLS renditions of video assets: the playlist and segments packaged after
+-- the render are assets of their own (kinds hls_playlist and hls_segment);
+-- this table maps each file name of a video's rendition to its asset, so
+-- /assets/{id}/hls/{name} can resolve the relative URIs of the playlist.
+
+CREATE TABLE IF NOT EXISTS asset_hls_files (
+  video_asset_id  TEXT NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+  name            TEXT NOT NULL,
+  asset_id        TEXT NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (video_asset_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_asset_hls_files_asset_id ON asset_hls_files(asset_id);
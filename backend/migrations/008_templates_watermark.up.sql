@@ -0,0 +1,5 @@
+-- Template-level watermark (branding overlay) of v1 renders:
+-- {"asset_id": "...", "position": "bottom-right", "opacity": 0.8}. Jobs
+-- override it in their params envelope. NULL means no watermark.
+
+ALTER TABLE templates ADD COLUMN IF NOT EXISTS watermark JSONB NULL;
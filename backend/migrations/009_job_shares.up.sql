@@ -0,0 +1,16 @@
+-- Share links of job outputs: a token that lets anyone without API
+-- credentials watch one output through the /embed/{token} player. A share
+-- is revoked (not deleted) so its token can never be reissued, and may
+-- expire. jobs is partitioned, so job_id has no foreign key (same as
+-- job_outputs after 004).
+
+CREATE TABLE IF NOT EXISTS job_shares (
+  token       TEXT PRIMARY KEY,
+  job_id      TEXT NOT NULL,
+  output_id   TEXT NOT NULL REFERENCES job_outputs(id) ON DELETE CASCADE,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at  TIMESTAMPTZ NULL,
+  revoked_at  TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_shares_job_id ON job_shares(job_id);
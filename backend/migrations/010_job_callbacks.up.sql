@@ -0,0 +1,14 @@
+-- Per-job completion callbacks: the URL (and optional HMAC secret) given
+-- when the job was created, and how its delivery went. The worker posts
+-- once the job is DONE or FAILED. jobs is partitioned, so job_id has no
+-- foreign key (same as job_outputs after 004).
+
+CREATE TABLE IF NOT EXISTS job_callbacks (
+  job_id        TEXT PRIMARY KEY,
+  url           TEXT NOT NULL,
+  secret        TEXT NOT NULL DEFAULT '',
+  attempts      INT NOT NULL DEFAULT 0,
+  last_error    TEXT NULL,
+  delivered_at  TIMESTAMPTZ NULL,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+-- Strict params: jobs of the template are rejected at submit time when
+-- their params are not declared in params_schema or do not match its types.
+
+ALTER TABLE templates ADD COLUMN IF NOT EXISTS strict_params BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+ALTER TABLE jobs DROP COLUMN IF EXISTS attempts;
+ALTER TABLE jobs DROP COLUMN IF EXISTS error_detail;
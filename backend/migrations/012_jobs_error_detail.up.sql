@@ -0,0 +1,7 @@
+-- Structured failure of a job, {"code", "op", "message", "retryable",
+-- "attempt"}; error_text stays as its human summary. attempts counts the
+-- runs of the job: each time a worker starts it (after a requeue or a
+-- worker restart too).
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS error_detail JSONB NULL;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE assets DROP COLUMN IF EXISTS media;
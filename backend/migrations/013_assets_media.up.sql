@@ -0,0 +1,5 @@
+-- Specs of a media file as probed by the worker (ffprobe), {"width",
+-- "height", "fps", "duration_ms"}; NULL for assets that were not probed.
+-- Rendered videos get them when the worker checks the template format.
+
+ALTER TABLE assets ADD COLUMN IF NOT EXISTS media JSONB NULL;
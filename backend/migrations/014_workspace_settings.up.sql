@@ -0,0 +1,9 @@
+-- Workspace settings: defaults for the templates and jobs created without
+-- them (see package settings). One row per workspace; the platform has a
+-- single workspace for now, "default".
+
+CREATE TABLE IF NOT EXISTS workspace_settings (
+  workspace  TEXT PRIMARY KEY,
+  settings   JSONB NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+-- X-Request-ID of the API request that created the job. The worker runs
+-- the job under it and forwards it to the renderer and the storage, so
+-- their logs correlate with the API's; NULL for older jobs.
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS request_id TEXT NULL;
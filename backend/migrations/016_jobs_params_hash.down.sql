@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_jobs_params_hash_done;
+ALTER TABLE jobs DROP COLUMN IF EXISTS params_hash;
@@ -0,0 +1,9 @@
+-- SHA-256 of the params_json of template jobs. With JOB_DEDUP_WINDOW the
+-- API returns a recent DONE job with the same hash instead of rendering an
+-- identical one again; NULL for legacy and older jobs.
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS params_hash TEXT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_params_hash_done
+  ON jobs (params_hash, finished_at DESC)
+  WHERE status = 'DONE' AND params_hash IS NOT NULL;
@@ -0,0 +1,4 @@
+-- Per-template concurrency: the worker renders at most max_concurrency jobs
+-- of the template at once, across all workers; NULL has no limit.
+
+ALTER TABLE templates ADD COLUMN IF NOT EXISTS max_concurrency INT NULL;
@@ -0,0 +1,13 @@
+-- Outcome of the last check of an asset by the worker's output verifier
+-- (WORKER_VERIFY_INTERVAL): error is NULL when the object was intact. Kept
+-- apart from assets so that checks do not bump assets.updated_at.
+
+CREATE TABLE IF NOT EXISTS asset_integrity (
+  asset_id   TEXT PRIMARY KEY REFERENCES assets(id) ON DELETE CASCADE,
+  checked_at TIMESTAMPTZ NOT NULL,
+  error      TEXT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_asset_integrity_failed
+  ON asset_integrity (checked_at DESC)
+  WHERE error IS NOT NULL;
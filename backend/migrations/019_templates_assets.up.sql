@@ -0,0 +1,6 @@
+-- Template asset library: named slots bound to a default asset, e.g.
+-- {"background_music": {"asset_id": "...", "kind": "music"}}. A job input
+-- of the same name that the job leaves unset takes the slot's asset. NULL
+-- means no slots.
+
+ALTER TABLE templates ADD COLUMN IF NOT EXISTS assets JSONB NULL;
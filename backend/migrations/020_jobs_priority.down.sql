@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_jobs_queued;
+CREATE INDEX IF NOT EXISTS idx_jobs_queued ON jobs (created_at) WHERE status = 'QUEUED';
+
+ALTER TABLE jobs DROP COLUMN IF EXISTS priority;
@@ -0,0 +1,9 @@
+-- Job priority: 1 (high) jobs are taken before 0 (normal) ones. In Redis
+-- mode they go to their own list ({<JOB_QUEUE_NAME>}:high), popped first; in
+-- postgres mode the claim orders by priority, so the queued index leads
+-- with it.
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 0;
+
+DROP INDEX IF EXISTS idx_jobs_queued;
+CREATE INDEX IF NOT EXISTS idx_jobs_queued ON jobs (priority DESC, created_at) WHERE status = 'QUEUED';
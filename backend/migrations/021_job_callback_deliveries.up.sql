@@ -0,0 +1,17 @@
+-- Delivery log of job callbacks: one row per attempt, with the receiver's
+-- status code (NULL if it did not answer), the error and how long it took.
+-- job_callbacks keeps the summary (attempts, last_error, delivered_at);
+-- the rows go with their callback.
+
+CREATE TABLE IF NOT EXISTS job_callback_deliveries (
+  id           BIGSERIAL PRIMARY KEY,
+  job_id       TEXT NOT NULL REFERENCES job_callbacks(job_id) ON DELETE CASCADE,
+  attempt      INT NOT NULL,
+  status_code  INT NULL,
+  error        TEXT NULL,
+  duration_ms  INT NOT NULL DEFAULT 0,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_callback_deliveries_job
+  ON job_callback_deliveries (job_id, id);
@@ -0,0 +1,6 @@
+-- Without run_at nothing would queue the scheduled jobs: queue them now.
+UPDATE jobs SET status = 'QUEUED', updated_at = NOW() WHERE status = 'SCHEDULED';
+
+DROP INDEX IF EXISTS idx_jobs_scheduled;
+
+ALTER TABLE jobs DROP COLUMN IF EXISTS run_at;
@@ -0,0 +1,8 @@
+-- Scheduled jobs: a job created with a future run_at is SCHEDULED until the
+-- worker scheduler queues it. In Redis mode its id waits in the sorted set
+-- {<JOB_QUEUE_NAME>}:scheduled; in postgres mode the scheduler finds it with
+-- the index below. run_at stays set once the job is queued.
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS run_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_scheduled ON jobs (run_at) WHERE status = 'SCHEDULED';
@@ -0,0 +1,10 @@
+-- Job progress: the stage a RUNNING job is in and its progress (0-100). The
+-- worker sets both as the job enters each stage; the renderer reports the
+-- progress of the render through POST /v1/jobs/{jobId}/progress. A FAILED or
+-- CANCELED job keeps the stage it stopped in.
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS progress SMALLINT NOT NULL DEFAULT 0
+  CHECK (progress BETWEEN 0 AND 100);
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS stage TEXT NOT NULL DEFAULT '';
+
+UPDATE jobs SET progress = 100 WHERE status = 'DONE';
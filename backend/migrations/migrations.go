@@ -0,0 +1,14 @@
+// Package migrations embeds the SQL schema migrations applied by
+// internal/pkg/migrate.
+//
+// Each version has a NNN_name.up.sql file and, optionally, a matching
+// NNN_name.down.sql. Versions are applied in ascending order and must never
+// be edited once released; add a new version instead.
+package migrations
+
+import "embed"
+
+// FS holds the migration files.
+//
+//go:embed *.sql
+var FS embed.FS
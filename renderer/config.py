@@ -10,6 +10,14 @@ RENDERER_PORT = int(os.environ.get("RENDERER_PORT", "9000"))
 DATA_ROOT = os.environ.get("STORAGE_LOCAL_ROOT", "/data")
 SPECS_DIR = os.path.join(DATA_ROOT, "specs")
 
+# Progreso: se reporta al progress_url del spec. Con RENDERER_AUTH_SECRET
+# ("valor" o "id:valor", el mismo secreto que usan los workers) va como
+# bearer token
+_auth_secret = os.environ.get("RENDERER_AUTH_SECRET", "")
+_auth_id, _, _auth_value = _auth_secret.partition(":")
+PROGRESS_TOKEN = _auth_value if _auth_id and _auth_value else _auth_secret
+PROGRESS_TIMEOUT = 2
+
 # Video defaults
 DEFAULT_DURATION = 3.0
 VIDEO_WIDTH = 1280
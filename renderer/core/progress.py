@@ -0,0 +1,25 @@
+"""
+Reporte del progreso del render a la API
+El spec trae progress_url si el worker lo pide (RENDERER_PROGRESS_BASEURL)
+"""
+import requests
+
+from config import PROGRESS_TIMEOUT, PROGRESS_TOKEN
+
+
+def report_progress(spec: dict, percent: int) -> None:
+    """
+    Reporta el avance del render (0-100) al progress_url del spec.
+    Es informativo: sin progress_url no hace nada y un fallo no corta el render.
+    """
+    url = spec.get("progress_url") if isinstance(spec, dict) else None
+    if not url:
+        return
+
+    headers = {"Content-Type": "application/json"}
+    if PROGRESS_TOKEN:
+        headers["Authorization"] = f"Bearer {PROGRESS_TOKEN}"
+    try:
+        requests.post(url, json={"progress": percent}, headers=headers, timeout=PROGRESS_TIMEOUT)
+    except Exception as e:
+        print(f"[progress] report failed: {e}")
@@ -6,6 +6,10 @@ from typing import Dict, Optional, Tuple
 from config import DATA_ROOT
 
 
+# Posiciones del watermark (mismas que el API)
+WATERMARK_POSITIONS = ("top-left", "top-right", "bottom-left", "bottom-right", "center")
+
+
 class ValidationError(Exception):
     """Error de validación de spec"""
     pass
@@ -79,6 +83,9 @@ class V1Spec:
         params = spec.get("params", {}) or {}
         self.text = params.get("text", "")
         self.captions_enabled = is_truthy(params.get("captions"))
+        
+        # Watermark (opcional)
+        self.watermark = self._extract_watermark(spec.get("watermark"))
     
     @property
     def has_external_captions(self) -> bool:
@@ -135,6 +142,33 @@ class V1Spec:
         
         return captions
     
+    def _extract_watermark(self, watermark) -> Optional[dict]:
+        """Extrae y valida el watermark (opcional): path, position, opacity"""
+        if not watermark:
+            return None
+        if not isinstance(watermark, dict):
+            raise ValidationError("watermark must be an object")
+        
+        path = watermark.get("path")
+        if not path or not isinstance(path, str):
+            raise ValidationError("watermark.path is required")
+        path = path.strip()
+        validate_path_under_data(path, "watermark")
+        validate_file_exists(path, "watermark")
+        
+        position = watermark.get("position") or "bottom-right"
+        if position not in WATERMARK_POSITIONS:
+            raise ValidationError(f"watermark.position must be one of {', '.join(WATERMARK_POSITIONS)}")
+        
+        try:
+            opacity = float(watermark.get("opacity", 0.8))
+        except (TypeError, ValueError):
+            raise ValidationError("watermark.opacity must be a number")
+        if not 0 < opacity <= 1:
+            raise ValidationError("watermark.opacity must be greater than 0 and at most 1")
+        
+        return {"path": path, "position": position, "opacity": opacity}
+    
     def _extract_video_output(self, output: dict) -> str:
         """Extrae y valida video_object_key"""
         key = output.get("video_object_key", "")
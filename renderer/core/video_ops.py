@@ -182,6 +182,70 @@ def burn_subtitles(video_path: str, vtt_path: str, output_path: str) -> None:
         raise FFmpegError(f"subtitle burn-in failed: {proc.stderr[-2000:]}")
 
 
+# Margen del watermark respecto al borde, en pixeles
+WATERMARK_MARGIN = 24
+
+# Coordenadas de overlay por posición (W/H: video, w/h: watermark)
+_WATERMARK_XY = {
+    "top-left": (f"{WATERMARK_MARGIN}", f"{WATERMARK_MARGIN}"),
+    "top-right": (f"W-w-{WATERMARK_MARGIN}", f"{WATERMARK_MARGIN}"),
+    "bottom-left": (f"{WATERMARK_MARGIN}", f"H-h-{WATERMARK_MARGIN}"),
+    "bottom-right": (f"W-w-{WATERMARK_MARGIN}", f"H-h-{WATERMARK_MARGIN}"),
+    "center": ("(W-w)/2", "(H-h)/2"),
+}
+
+
+def apply_watermark(
+    video_path: str,
+    image_path: str,
+    output_path: str,
+    position: str = "bottom-right",
+    opacity: float = 0.8
+) -> None:
+    """
+    Superpone una imagen (logo) sobre el video
+    
+    Args:
+        video_path: Video de entrada
+        image_path: Imagen del watermark
+        output_path: Video de salida con el watermark
+        position: Esquina (o centro) donde va el watermark
+        opacity: Opacidad del watermark, de 0 a 1
+    
+    El watermark se escala al 20% del ancho del video, conservando su
+    proporción.
+    """
+    ensure_dir(os.path.dirname(output_path))
+    
+    x, y = _WATERMARK_XY.get(position, _WATERMARK_XY["bottom-right"])
+    filter_complex = (
+        "[1:v][0:v]scale2ref=w=main_w*0.2:h=ow/dar[wm][base];"
+        f"[wm]format=rgba,colorchannelmixer=aa={opacity:.3f}[wma];"
+        f"[base][wma]overlay=x={x}:y={y}"
+    )
+    
+    proc = subprocess.run(
+        [
+            "ffmpeg", "-y",
+            "-i", video_path,
+            "-i", image_path,
+            "-filter_complex", filter_complex,
+            "-c:v", "libx264",
+            "-pix_fmt", "yuv420p",
+            "-c:a", "copy",
+            output_path,
+        ],
+        stdout=subprocess.PIPE,
+        stderr=subprocess.PIPE,
+        text=True,
+        check=False,
+        timeout=FFMPEG_TIMEOUT,
+    )
+    
+    if proc.returncode != 0:
+        raise FFmpegError(f"watermark overlay failed: {proc.stderr[-2000:]}")
+
+
 def render_legacy_video(output_path: str, text: str, duration: float = 7.0) -> None:
     """
     Renderiza video legacy (v0) - video vertical negro con texto
@@ -7,6 +7,7 @@ import os
 from core.spec_parser import V0Spec, ValidationError
 from core.video_ops import render_legacy_video, extract_first_frame, FFmpegError
 from core.file_utils import save_json, sanitize_filename
+from core.progress import report_progress
 from config import SPECS_DIR
 
 
@@ -33,6 +34,7 @@ def handle_render_v0(spec: dict) -> dict:
             text=parsed.text,
             duration=7.0
         )
+        report_progress(spec, 80)
         
         # 4. Generar thumbnail
         extract_first_frame(
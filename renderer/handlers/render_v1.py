@@ -13,10 +13,12 @@ from core.video_ops import (
     probe_audio_duration,
     mux_audio_to_video,
     burn_subtitles,
+    apply_watermark,
     FFmpegError
 )
 from core.captions import generate_vtt_file, generate_vtt_from_transcription
 from core.file_utils import save_json, sanitize_filename, safe_remove, safe_replace, ensure_dir
+from core.progress import report_progress
 from config import SPECS_DIR, DEFAULT_DURATION
 
 
@@ -77,6 +79,7 @@ def handle_render_v1(spec: dict) -> dict:
        Si no: renderizar video estático
     5. Generar captions (transcripción o texto estático)
     6. Quemar captions en el video
+    7. Superponer el watermark, si el spec lo trae
     
     Args:
         spec: Diccionario con el spec de render
@@ -95,6 +98,7 @@ def handle_render_v1(spec: dict) -> dict:
         
         # 3. Generar thumbnail desde avatar
         create_thumbnail_from_image(parsed.avatar_path, parsed.thumb_dest)
+        report_progress(spec, 10)
         
         # 4. Determinar duración
         duration = DEFAULT_DURATION
@@ -152,6 +156,8 @@ def handle_render_v1(spec: dict) -> dict:
             )
             final_video = temp_video
         
+        report_progress(spec, 60)
+
         # 6. Captions
         used_transcription = False
         used_external_captions = False
@@ -188,6 +194,22 @@ def handle_render_v1(spec: dict) -> dict:
             if final_video in temp_files:
                 temp_files.remove(final_video)
         
+        report_progress(spec, 85)
+
+        # 7. Watermark
+        if parsed.watermark:
+            watermarked = parsed.video_dest + ".watermarked.mp4"
+            temp_files.append(watermarked)
+            apply_watermark(
+                video_path=parsed.video_dest,
+                image_path=parsed.watermark["path"],
+                output_path=watermarked,
+                position=parsed.watermark["position"],
+                opacity=parsed.watermark["opacity"]
+            )
+            safe_replace(watermarked, parsed.video_dest)
+            temp_files.remove(watermarked)
+        
         _cleanup_temp_files(temp_files)
         
         return {
@@ -202,6 +224,7 @@ def handle_render_v1(spec: dict) -> dict:
                 "used_transcription": used_transcription,
                 "used_external_captions": used_external_captions,
                 "used_animation": used_animation,
+                "has_watermark": parsed.watermark is not None,
             }
         }
     
@@ -4,7 +4,8 @@ GALA Renderer - HTTP Server
 Responsabilidad: Solo routing HTTP, delega la lógica a handlers
 """
 import json
-from http.server import BaseHTTPRequestHandler, HTTPServer
+import threading
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
 
 from config import RENDERER_PORT
 from handlers.render_v0 import handle_render_v0
@@ -31,7 +32,20 @@ def write_json(handler: BaseHTTPRequestHandler, code: int, obj: dict):
     handler.wfile.write(body)
 
 
+# Los renders corren de a uno, como cuando el servidor atendía un request
+# por vez; el hilo por request deja que /health conteste durante un render.
+render_lock = threading.Lock()
+
+
 class Handler(BaseHTTPRequestHandler):
+    def do_GET(self):
+        # Los workers lo consultan antes de tomar jobs
+        if self.path == "/health":
+            write_json(self, 200, {"status": "ok", "rendering": render_lock.locked()})
+        else:
+            self.send_response(404)
+            self.end_headers()
+
     def do_POST(self):
         # Routing
         if self.path == "/render":
@@ -48,7 +62,8 @@ class Handler(BaseHTTPRequestHandler):
             write_json(self, 400, {"error": "invalid json"})
             return
 
-        result = handle_render_v0(spec)
+        with render_lock:
+            result = handle_render_v0(spec)
         write_json(self, result["status_code"], result["body"])
 
     def _handle_v1(self):
@@ -57,7 +72,8 @@ class Handler(BaseHTTPRequestHandler):
             write_json(self, 400, {"error": "invalid json"})
             return
 
-        result = handle_render_v1(spec)
+        with render_lock:
+            result = handle_render_v1(spec)
         write_json(self, result["status_code"], result["body"])
 
     def log_message(self, format, *args):
@@ -66,7 +82,7 @@ class Handler(BaseHTTPRequestHandler):
 
 
 def main():
-    server = HTTPServer(("0.0.0.0", RENDERER_PORT), Handler)
+    server = ThreadingHTTPServer(("0.0.0.0", RENDERER_PORT), Handler)
     print(f"🎬 Renderer listening on :{RENDERER_PORT}")
     server.serve_forever()
 
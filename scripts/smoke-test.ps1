@@ -4,6 +4,9 @@ param(
 
 $ErrorActionPreference = "Stop"
 
+# Rutas versionadas de la API (health no lleva versión)
+$Api = "$ApiBase/v1"
+
 function Assert-Ok($cond, $msg) {
   if (-not $cond) { throw $msg }
 }
@@ -23,7 +26,7 @@ $body = @{
   params=@{ text="GALA E2E" }
 } | ConvertTo-Json -Depth 5
 
-$created = Invoke-RestMethod "$ApiBase/jobs" -Method POST -ContentType "application/json" -Body $body
+$created = Invoke-RestMethod "$Api/jobs" -Method POST -ContentType "application/json" -Body $body
 $jobId = $created.job.id
 Assert-Ok ($jobId -ne $null -and $jobId.Length -gt 0) "Job id missing"
 Write-Host "   jobId=$jobId"
@@ -34,7 +37,7 @@ $max = 60
 $job = $null
 for ($i=0; $i -lt $max; $i++) {
   Start-Sleep -Seconds 1
-  $job = Invoke-RestMethod "$ApiBase/jobs/$jobId" -Method GET
+  $job = Invoke-RestMethod "$Api/jobs/$jobId" -Method GET
   $status = $job.job.status
   Write-Host "   status=$status"
   if ($status -eq "DONE" -or $status -eq "FAILED") { break }
@@ -52,8 +55,8 @@ Assert-Ok ($videoAssetId) "video_asset_id missing"
 Assert-Ok ($thumbAssetId) "thumbnail_asset_id missing"
 
 Write-Host "4) Download assets..."
-Invoke-WebRequest "$ApiBase/assets/$videoAssetId/content" -OutFile "hello.mp4" | Out-Null
-Invoke-WebRequest "$ApiBase/assets/$thumbAssetId/content" -OutFile "hello.jpg" | Out-Null
+Invoke-WebRequest "$Api/assets/$videoAssetId/content" -OutFile "hello.mp4" | Out-Null
+Invoke-WebRequest "$Api/assets/$thumbAssetId/content" -OutFile "hello.jpg" | Out-Null
 
 $videoSize = (Get-Item "hello.mp4").Length
 $thumbSize = (Get-Item "hello.jpg").Length